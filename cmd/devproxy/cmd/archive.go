@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/munichmade/devproxy/internal/archive"
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/daemon"
+)
+
+var (
+	importYes   bool
+	importTrust bool
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export <file.tar>",
+	Short: "Export CA, certificates, config and routes to an archive",
+	Long: `Export bundles the CA, generated certificates, config file and route state
+into a tar archive, e.g. to move your setup to a new machine.
+
+The archive contains private keys, including the CA key. Keep it safe.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.OpenFile(args[0], os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to create archive: %w", err)
+		}
+		defer f.Close()
+
+		names, err := archive.Write(f)
+		if err != nil {
+			return fmt.Errorf("failed to export: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to write archive: %w", err)
+		}
+
+		for _, name := range names {
+			fmt.Printf("  %s\n", name)
+		}
+		fmt.Printf("Exported %d files to %s\n", len(names), args[0])
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import <file.tar>",
+	Short: "Restore CA, certificates, config and routes from an archive",
+	Long: `Import restores an archive created with 'devproxy export', replacing the
+existing CA, certificates, config file and route state.
+
+Only import archives you created yourself: anyone holding a CA key can issue
+certificates your machine trusts once that CA is installed.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer f.Close()
+
+		a, err := archive.Read(f)
+		if err != nil {
+			return err
+		}
+
+		out := cmd.OutOrStdout()
+		if a.HasCAKey() {
+			fmt.Fprintln(out, "warning: this archive contains a CA private key. Anyone holding it can issue")
+			fmt.Fprintln(out, "certificates your machine trusts. Only import archives you created yourself.")
+		}
+		if a.ReplacesCA() {
+			fmt.Fprintln(out, "warning: your existing CA will be replaced; certificates it issued stop being trusted.")
+		}
+
+		if !importYes && !confirm(cmd.InOrStdin(), out, fmt.Sprintf("Restore %d files from %s?", len(a.Files), args[0])) {
+			return fmt.Errorf("import cancelled")
+		}
+
+		written, err := a.Restore()
+		if err != nil {
+			return fmt.Errorf("failed to import: %w", err)
+		}
+		fmt.Fprintf(out, "Imported %d files\n", len(written))
+
+		if importTrust {
+			configureTrust()
+			if err := ca.InstallTrust(); err != nil {
+				return fmt.Errorf("failed to install CA trust (try 'sudo devproxy setup'): %w", err)
+			}
+			fmt.Fprintln(out, "CA installed into trust store")
+		} else if a.HasCAKey() && !ca.IsTrusted() {
+			fmt.Fprintln(out, "Run 'devproxy setup' to trust the imported CA")
+		}
+
+		if daemon.New().IsRunning() {
+			fmt.Fprintln(out, "Restart the daemon to use the imported state: devproxy restart")
+		}
+		return nil
+	},
+}
+
+// confirm asks a yes/no question and reports whether the answer was yes.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func init() {
+	importCmd.Flags().BoolVarP(&importYes, "yes", "y", false, "Do not ask for confirmation")
+	importCmd.Flags().BoolVar(&importTrust, "trust", false, "Install the imported CA into the system trust store (requires root)")
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
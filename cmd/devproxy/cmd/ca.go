@@ -0,0 +1,386 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/config"
+	"github.com/munichmade/devproxy/internal/daemon"
+	"github.com/munichmade/devproxy/internal/paths"
+	"github.com/munichmade/devproxy/internal/privilege"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+// configureTrust applies the trust store settings of the config, so commands
+// changing the trust stores honor cert.trust_timeout.
+func configureTrust() {
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+	// Validated when the config was loaded; empty selects the default
+	timeout, _ := time.ParseDuration(cfg.Cert.TrustTimeout)
+	ca.SetTrustTimeout(timeout)
+}
+
+var caCmd = &cobra.Command{
+	Use:   "ca",
+	Short: "Manage the local Certificate Authority",
+}
+
+var caTrustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Install the CA into the system trust store",
+	Long: `Install the CA into the system trust store, replacing an outdated
+devproxy CA left over from before the CA was regenerated. A CA replaced by
+'devproxy ca rotate' is removed once its grace period has passed.
+
+With --check, only report whether the trusted CA is the current one and
+exit non-zero if it is not.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		check, _ := cmd.Flags().GetBool("check")
+
+		if check {
+			trust := ca.CheckTrust()
+			fmt.Println(trustMessage(trust))
+			if trust != ca.TrustCurrent {
+				os.Exit(1)
+			}
+			return
+		}
+
+		if !ca.Exists() {
+			fmt.Fprintln(os.Stderr, "CA not found, run 'devproxy setup' first")
+			os.Exit(1)
+		}
+		previous, _ := ca.LoadPrevious()
+		retire := previous != nil && !time.Now().Before(previous.RetireAfter)
+		trusted := ca.IsTrusted()
+		if trusted && !retire {
+			fmt.Println("CA already trusted")
+			return
+		}
+
+		if err := privilege.RequireRoot("installing CA certificate"); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to elevate privileges: %v\n", err)
+			os.Exit(1)
+		}
+		configureTrust()
+		if !trusted {
+			if err := ca.InstallTrust(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to install CA trust: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Printf("CA installed into %s\n", ca.TrustStoreName())
+		}
+		retired, err := ca.RetirePrevious(false)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to remove the previous CA: %v\n", err)
+			os.Exit(1)
+		}
+		if retired {
+			fmt.Println("Previous CA removed from the trust stores")
+		}
+	},
+}
+
+var caRotateCmd = &cobra.Command{
+	Use:   "rotate",
+	Short: "Replace the CA with a new one, keeping the old one trusted for a while",
+	Long: `Generate a new CA and install it into the trust stores. The previous CA
+stays trusted alongside it for the grace period, so clients that cached a
+certificate it issued, or that pinned it, keep working; cached certificates
+are reissued by the new CA right away.
+
+After the grace period, 'sudo devproxy ca trust' removes the previous CA.
+With --retire, it is removed now without rotating again. An imported CA
+cannot be rotated; import its successor with 'devproxy ca import' instead.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		retire, _ := cmd.Flags().GetBool("retire")
+		grace, _ := cmd.Flags().GetDuration("grace")
+
+		if err := privilege.RequireRoot("rotating the CA"); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to elevate privileges: %v\n", err)
+			os.Exit(1)
+		}
+		configureTrust()
+
+		if retire {
+			retired, err := ca.RetirePrevious(true)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to remove the previous CA: %v\n", err)
+				os.Exit(1)
+			}
+			if retired {
+				fmt.Println("Previous CA removed from the trust stores")
+			} else {
+				fmt.Println("No previous CA to remove")
+			}
+			return
+		}
+
+		rotated, err := ca.Rotate(grace)
+		if err != nil && rotated == nil {
+			fmt.Fprintf(os.Stderr, "Failed to rotate CA: %v\n", err)
+			os.Exit(1)
+		}
+		chownDataDir()
+		if err != nil {
+			// The new CA is in place; trusting it can be retried
+			fmt.Fprintf(os.Stderr, "CA rotated, but installing trust failed: %v\n", err)
+			fmt.Fprintln(os.Stderr, "Retry with: sudo devproxy ca trust")
+		} else {
+			fmt.Printf("CA rotated, new SHA-256 fingerprint %s\n", rotated.Fingerprint())
+		}
+		if previous, err := ca.LoadPrevious(); err == nil {
+			fmt.Printf("The previous CA stays trusted until %s; then run: sudo devproxy ca trust\n",
+				previous.RetireAfter.Local().Format("2006-01-02 15:04"))
+		}
+
+		if err := reissueCertificates(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to reissue certificates: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// reissueCertificates makes the running daemon load the rotated CA and
+// reissue its certificates, or reissues the certificates on disk if the
+// daemon is not running.
+func reissueCertificates() error {
+	d := daemon.New()
+	if !d.IsRunning() {
+		certManager, err := cert.NewManager()
+		if err != nil {
+			return err
+		}
+		n, err := certManager.RenewExpiring()
+		chownDataDir()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Reissued %d cached certificates\n", n)
+		return nil
+	}
+
+	resp, err := proxy.Control(proxy.QueryRequest{Cmd: proxy.ControlCAReload})
+	if err != nil {
+		return fmt.Errorf("daemon failed to reload the CA: %w", err)
+	}
+	fmt.Printf("Daemon reloaded the CA and reissued %d cached certificates\n", resp.Reissued)
+	return nil
+}
+
+// chownDataDir gives files written as root in the data directory back to the
+// user that invoked sudo, so the daemon can still read them after dropping
+// privileges.
+func chownDataDir() {
+	originalUser, err := privilege.GetOriginalUser()
+	if err != nil || originalUser == nil {
+		return
+	}
+	if err := chownRecursive(paths.DataDir(), originalUser.UID, originalUser.GID); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to chown data directory: %v\n", err)
+	}
+}
+
+// caExpiryWarning is how long before the CA expires 'ca status' warns.
+const caExpiryWarning = 30 * 24 * time.Hour
+
+var caStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the CA certificate and where it is trusted",
+	Long: `Show the subject, SHA-256 fingerprint and validity of the CA certificate,
+and whether each detected trust store trusts it. Warns when the CA expires
+within 30 days.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !ca.Exists() {
+			fmt.Fprintln(os.Stderr, "CA not found, run 'devproxy setup' first")
+			os.Exit(1)
+		}
+		current, err := ca.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to load CA: %v\n", err)
+			os.Exit(1)
+		}
+		previous, _ := ca.LoadPrevious()
+		printCAStatus(os.Stdout, current, previous, ca.IsImported(), ca.CheckStores(), time.Now())
+	},
+}
+
+// printCAStatus writes the details of current and its trust in stores to w,
+// and the CA replaced by the last rotation if previous is not nil.
+func printCAStatus(w io.Writer, current *ca.CA, previous *ca.Previous, imported bool, stores []ca.StoreTrust, now time.Time) {
+	c := current.Certificate
+	origin := "generated"
+	if imported {
+		origin = "imported"
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Subject:\t%s (%s)\n", c.Subject, origin)
+	fmt.Fprintf(tw, "SHA-256:\t%s\n", current.Fingerprint())
+	fmt.Fprintf(tw, "Not before:\t%s\n", c.NotBefore.Format(time.RFC3339))
+	fmt.Fprintf(tw, "Not after:\t%s\n", c.NotAfter.Format(time.RFC3339))
+	remaining := c.NotAfter.Sub(now)
+	if remaining > 0 {
+		fmt.Fprintf(tw, "Expires in:\t%d days\n", int(remaining.Hours()/24))
+	} else {
+		fmt.Fprintf(tw, "Expires in:\texpired\n")
+	}
+	if previous != nil {
+		state := "retire with: sudo devproxy ca trust"
+		if now.Before(previous.RetireAfter) {
+			state = "trusted until " + previous.RetireAfter.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "Previous CA:\t%s (%s)\n", previous.Fingerprint(), state)
+	}
+	tw.Flush()
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Trust stores:")
+	tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, store := range stores {
+		fmt.Fprintf(tw, "  %s\t%s\n", store.Name, store.State)
+	}
+	tw.Flush()
+
+	if remaining < caExpiryWarning {
+		fmt.Fprintln(w)
+		if remaining > 0 {
+			fmt.Fprintf(w, "Warning: the CA expires in %d days. Rotate it before browsers reject its certificates:\n", int(remaining.Hours()/24))
+		} else {
+			fmt.Fprintln(w, "Warning: the CA has expired and browsers reject its certificates. Rotate it:")
+		}
+		if imported {
+			fmt.Fprintln(w, "  devproxy ca import <cert.pem> <key.pem>")
+		} else {
+			fmt.Fprintln(w, "  sudo devproxy ca rotate")
+		}
+	}
+}
+
+var caImportCmd = &cobra.Command{
+	Use:   "import <cert.pem> <key.pem>",
+	Short: "Use an existing CA instead of the generated one",
+	Long: `Replace the local CA with an existing CA certificate and private key,
+e.g. a root shared by a team so browsers only trust one CA for everyone.
+
+The certificate must be a CA certificate and the key must match it. Cached
+certificates issued by the previous CA are removed, and a running daemon
+loads the imported CA without a restart. An imported CA is never replaced by
+a generated one.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		imported, err := ca.Import(args[0], args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to import CA: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("CA imported: %s (expires %s)\n",
+			imported.Certificate.Subject.CommonName, imported.Certificate.NotAfter.Format("2006-01-02"))
+
+		// Certificates signed by the previous CA are not trusted anymore
+		certManager, err := cert.NewManager()
+		if err == nil {
+			err = certManager.ClearCache()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to clear certificate cache: %v\n", err)
+			os.Exit(1)
+		}
+
+		if !ca.IsTrusted() {
+			fmt.Println("Trust the imported CA: sudo devproxy ca trust")
+		}
+		if !daemon.New().IsRunning() {
+			return
+		}
+		resp, err := proxy.Control(proxy.QueryRequest{Cmd: proxy.ControlCAReload})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Daemon failed to load the imported CA: %v\n", err)
+			fmt.Println("Restart the daemon to use the imported CA: devproxy restart")
+			return
+		}
+		fmt.Printf("Daemon loaded the imported CA and reissued %d cached certificates\n", resp.Reissued)
+	},
+}
+
+var caExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Write the CA certificate for manual installation",
+	Long: `Write the CA certificate to stdout or a file, e.g. to hand it to IT on
+machines where 'devproxy ca trust' cannot install it.
+
+Use --format der for tools that expect a binary certificate, such as the
+Windows certificate import wizard.
+
+Examples:
+  devproxy ca export > devproxy-ca.crt
+  devproxy ca export --format der --out devproxy-ca.cer`,
+	Run: func(cmd *cobra.Command, args []string) {
+		out, _ := cmd.Flags().GetString("out")
+		format, _ := cmd.Flags().GetString("format")
+
+		if !ca.Exists() {
+			fmt.Fprintln(os.Stderr, "CA not found, run 'devproxy setup' first")
+			os.Exit(1)
+		}
+
+		if out == "" {
+			if err := ca.ExportCert(os.Stdout, format); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to export CA: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := ca.ExportCert(&buf, format); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to export CA: %v\n", err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", out, err)
+			os.Exit(1)
+		}
+		fmt.Printf("CA certificate written to %s\n", out)
+	},
+}
+
+// trustMessage describes a trust state for the user.
+func trustMessage(trust ca.TrustState) string {
+	switch trust {
+	case ca.TrustCurrent:
+		return "CA is trusted and current"
+	case ca.TrustStale:
+		return "CA is trusted but stale (re-run setup: sudo devproxy setup)"
+	default:
+		return "CA is not trusted (run: sudo devproxy setup)"
+	}
+}
+
+func init() {
+	caTrustCmd.Flags().Bool("check", false, "Report whether the current CA is trusted without changing anything")
+
+	caCmd.AddCommand(caTrustCmd)
+	caCmd.AddCommand(caStatusCmd)
+	caCmd.AddCommand(caImportCmd)
+
+	caRotateCmd.Flags().Duration("grace", ca.DefaultRotationGrace, "How long the previous CA stays trusted")
+	caRotateCmd.Flags().Bool("retire", false, "Remove the previous CA from the trust stores now instead of rotating")
+	caCmd.AddCommand(caRotateCmd)
+
+	caExportCmd.Flags().StringP("out", "o", "", "Write to this file instead of stdout")
+	caExportCmd.Flags().String("format", "pem", "Certificate encoding: pem or der")
+	caCmd.AddCommand(caExportCmd)
+	rootCmd.AddCommand(caCmd)
+}
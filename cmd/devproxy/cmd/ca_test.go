@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+func TestTrustMessage(t *testing.T) {
+	tests := []struct {
+		trust ca.TrustState
+		want  string
+	}{
+		{ca.TrustCurrent, "trusted and current"},
+		{ca.TrustStale, "trusted but stale (re-run setup"},
+		{ca.TrustNone, "not trusted"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.trust.String(), func(t *testing.T) {
+			if got := trustMessage(tt.trust); !strings.Contains(got, tt.want) {
+				t.Errorf("trustMessage() = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrintCAStatus(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	current, err := ca.Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	stores := []ca.StoreTrust{
+		{Name: "system trust store", State: ca.TrustCurrent},
+		{Name: "NSS database /home/dev/.pki/nssdb", State: ca.TrustNone},
+	}
+	notAfter := current.Certificate.NotAfter
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		previous *ca.Previous
+		imported bool
+		want     []string
+		wantNot  []string
+	}{
+		{
+			name:    "valid",
+			now:     notAfter.Add(-100 * 24 * time.Hour),
+			want:    []string{current.Fingerprint(), "(generated)", "Expires in:  100 days", "trusted and current", "nssdb  not trusted"},
+			wantNot: []string{"Warning"},
+		},
+		{
+			name: "expiring soon",
+			now:  notAfter.Add(-10 * 24 * time.Hour),
+			want: []string{"Warning: the CA expires in 10 days", "sudo devproxy ca rotate"},
+		},
+		{
+			name:     "rotated",
+			now:      notAfter.Add(-100 * 24 * time.Hour),
+			previous: &ca.Previous{Certificate: current.Certificate, RetireAfter: notAfter.Add(-99 * 24 * time.Hour)},
+			want:     []string{"Previous CA:", "trusted until " + notAfter.Add(-99*24*time.Hour).Format(time.RFC3339)},
+		},
+		{
+			name:     "previous due for retirement",
+			now:      notAfter.Add(-100 * 24 * time.Hour),
+			previous: &ca.Previous{Certificate: current.Certificate, RetireAfter: notAfter.Add(-101 * 24 * time.Hour)},
+			want:     []string{"retire with: sudo devproxy ca trust"},
+		},
+		{
+			name:     "imported and expired",
+			now:      notAfter.Add(time.Hour),
+			imported: true,
+			want:     []string{"(imported)", "expired", "devproxy ca import"},
+			wantNot:  []string{"devproxy ca rotate"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			printCAStatus(&out, current, tt.previous, tt.imported, stores, tt.now)
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("expected output to contain %q:\n%s", want, out.String())
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(out.String(), unwanted) {
+					t.Errorf("expected output not to contain %q:\n%s", unwanted, out.String())
+				}
+			}
+		})
+	}
+}
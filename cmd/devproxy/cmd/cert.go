@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/daemon"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Inspect and manage certificates",
+}
+
+var certCacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the daemon's certificate cache",
+}
+
+var certCacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List certificates cached by the running daemon",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !daemon.New().IsRunning() {
+			return daemon.ErrNotRunning
+		}
+
+		resp, err := proxy.Control(proxy.QueryRequest{Cmd: proxy.ControlCertCache})
+		if err != nil {
+			return fmt.Errorf("failed to list certificate cache: %w", err)
+		}
+
+		writeCacheEntries(os.Stdout, resp.CertCache)
+		return nil
+	},
+}
+
+var certCacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Clear the running daemon's certificate cache",
+	Long: `Clear the running daemon's certificate cache, both in memory and on disk.
+Certificates are regenerated on the next TLS handshake.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !daemon.New().IsRunning() {
+			return daemon.ErrNotRunning
+		}
+
+		if _, err := proxy.Control(proxy.QueryRequest{Cmd: proxy.ControlCertCacheClear}); err != nil {
+			return fmt.Errorf("failed to clear certificate cache: %w", err)
+		}
+
+		fmt.Println("Certificate cache cleared")
+		return nil
+	},
+}
+
+// writeCacheEntries prints cached certificates as a table.
+func writeCacheEntries(out io.Writer, entries []cert.CacheEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(out, "Certificate cache is empty")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "DOMAIN\tSERIAL\tEXPIRES\tNAMES\n")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			entry.Domain,
+			entry.Serial,
+			entry.NotAfter.Format("2006-01-02 15:04"),
+			strings.Join(entry.DNSNames, ","))
+	}
+	w.Flush()
+}
+
+func init() {
+	certCacheCmd.AddCommand(certCacheListCmd)
+	certCacheCmd.AddCommand(certCacheClearCmd)
+	certCmd.AddCommand(certCacheCmd)
+	rootCmd.AddCommand(certCmd)
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/cert"
+)
+
+func TestWriteCacheEntries(t *testing.T) {
+	t.Run("prints empty cache", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeCacheEntries(&buf, nil)
+
+		if !strings.Contains(buf.String(), "empty") {
+			t.Errorf("expected empty cache message, got: %s", buf.String())
+		}
+	})
+
+	t.Run("prints entries", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeCacheEntries(&buf, []cert.CacheEntry{
+			{
+				Domain:   "*.app.localhost",
+				Serial:   "1a2b3c",
+				DNSNames: []string{"*.app.localhost", "app.localhost"},
+				NotAfter: time.Date(2030, 1, 2, 3, 4, 0, 0, time.UTC),
+			},
+		})
+
+		out := buf.String()
+		for _, want := range []string{"DOMAIN", "*.app.localhost", "1a2b3c", "2030-01-02 03:04", "*.app.localhost,app.localhost"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected output to contain %q, got:\n%s", want, out)
+			}
+		}
+	})
+}
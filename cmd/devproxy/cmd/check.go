@@ -128,11 +128,17 @@ func checkCAExists() CheckResult {
 func checkCATrusted() CheckResult {
 	result := CheckResult{Name: "ca_trusted"}
 
-	if !ca.IsTrusted() {
+	switch ca.CheckTrust() {
+	case ca.TrustNone:
 		result.Passed = false
 		result.Message = "CA not trusted by system"
 		result.Suggestion = "Run: sudo devproxy setup"
 		return result
+	case ca.TrustStale:
+		result.Passed = false
+		result.Message = "System trusts an outdated CA; certificates are signed by a newer one"
+		result.Suggestion = "Run: sudo devproxy setup"
+		return result
 	}
 
 	result.Passed = true
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+)
+
+// Values for the --color flag.
+const (
+	colorAuto   = "auto"
+	colorAlways = "always"
+	colorNever  = "never"
+)
+
+// ANSI escape codes for colorized output.
+const (
+	ansiRed    = "\033[31m"
+	ansiYellow = "\033[33m"
+	ansiCyan   = "\033[36m"
+	ansiReset  = "\033[0m"
+)
+
+var colorMode string
+
+// colorEnabled reports whether output should be colorized for the given
+// --color mode. In auto mode color is used only for terminals and when
+// NO_COLOR (https://no-color.org) is unset or empty.
+func colorEnabled(mode string, terminal bool) bool {
+	switch mode {
+	case colorAlways:
+		return true
+	case colorNever:
+		return false
+	default:
+		if os.Getenv("NO_COLOR") != "" {
+			return false
+		}
+		return terminal
+	}
+}
+
+// isTerminal reports whether f is attached to a terminal.
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// stdoutColor reports whether colorized output should be written to stdout.
+func stdoutColor() bool {
+	return colorEnabled(colorMode, isTerminal(os.Stdout))
+}
+
+// validateColorMode checks the --color flag value.
+func validateColorMode(mode string) error {
+	switch mode {
+	case colorAuto, colorAlways, colorNever:
+		return nil
+	default:
+		return fmt.Errorf("invalid --color value %q: must be auto, always or never", mode)
+	}
+}
+
+// colorize wraps s in the given ANSI color when enabled.
+func colorize(s, color string, enabled bool) string {
+	if !enabled {
+		return s
+	}
+	return color + s + ansiReset
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestColorEnabled(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		terminal bool
+		noColor  string
+		want     bool
+	}{
+		{name: "auto on terminal", mode: colorAuto, terminal: true, want: true},
+		{name: "auto when piped", mode: colorAuto, terminal: false, want: false},
+		{name: "auto respects NO_COLOR", mode: colorAuto, terminal: true, noColor: "1", want: false},
+		{name: "always when piped", mode: colorAlways, terminal: false, want: true},
+		{name: "always ignores NO_COLOR", mode: colorAlways, terminal: true, noColor: "1", want: true},
+		{name: "never on terminal", mode: colorNever, terminal: true, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tt.noColor)
+
+			if got := colorEnabled(tt.mode, tt.terminal); got != tt.want {
+				t.Errorf("colorEnabled(%q, %v) = %v, want %v", tt.mode, tt.terminal, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateColorMode(t *testing.T) {
+	for _, mode := range []string{colorAuto, colorAlways, colorNever} {
+		if err := validateColorMode(mode); err != nil {
+			t.Errorf("expected %q to be valid, got %v", mode, err)
+		}
+	}
+	if err := validateColorMode("sometimes"); err == nil {
+		t.Error("expected error for invalid mode")
+	}
+}
+
+func TestPrintLogLine(t *testing.T) {
+	lines := []string{
+		"time=2026-01-02T15:04:05 level=ERROR msg=failed",
+		"time=2026-01-02T15:04:05 level=WARN msg=slow",
+		"time=2026-01-02T15:04:05 level=DEBUG msg=detail",
+		"time=2026-01-02T15:04:05 level=INFO msg=started",
+	}
+
+	t.Run("no ANSI codes without color", func(t *testing.T) {
+		var buf bytes.Buffer
+		printLogLines(&buf, lines, false)
+
+		if strings.Contains(buf.String(), "\033[") {
+			t.Errorf("expected no ANSI codes, got %q", buf.String())
+		}
+		if buf.String() != strings.Join(lines, "\n")+"\n" {
+			t.Errorf("expected lines unchanged, got %q", buf.String())
+		}
+	})
+
+	t.Run("colors by level", func(t *testing.T) {
+		var buf bytes.Buffer
+		printLogLines(&buf, lines, true)
+
+		out := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		wantPrefixes := []string{ansiRed, ansiYellow, ansiCyan, ""}
+		for i, prefix := range wantPrefixes {
+			if prefix == "" {
+				if strings.Contains(out[i], "\033[") {
+					t.Errorf("line %d: expected no color, got %q", i, out[i])
+				}
+				continue
+			}
+			if !strings.HasPrefix(out[i], prefix) || !strings.HasSuffix(out[i], ansiReset) {
+				t.Errorf("line %d: expected %q color, got %q", i, prefix, out[i])
+			}
+		}
+	})
+}
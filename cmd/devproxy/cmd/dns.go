@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/munichmade/devproxy/internal/config"
+	"github.com/munichmade/devproxy/internal/daemon"
+	"github.com/munichmade/devproxy/internal/dns"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+// dnsQueryTimeout bounds the query to devproxy's DNS server.
+const dnsQueryTimeout = 2 * time.Second
+
+var dnsCmd = &cobra.Command{
+	Use:   "dns",
+	Short: "Inspect DNS resolution of local domains",
+}
+
+var dnsCheckCmd = &cobra.Command{
+	Use:   "check <name>",
+	Short: "Check that a name resolves through devproxy",
+	Long: `Resolve a name the way applications do and report whether the answer
+came from devproxy's DNS server or from somewhere else, such as the upstream
+DNS server or a hosts file entry. A random name under the same domain, which
+only devproxy answers, confirms that the system resolver asks devproxy.
+
+Exits with status 1 if the name does not resolve through devproxy.
+
+Examples:
+  devproxy dns check app.localhost
+  devproxy dns check api.test`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		cfg, err := config.Load()
+		if err != nil {
+			cfg = config.Default()
+		}
+
+		result := runDNSCheck(cfg, args[0]).result(runtime.GOOS)
+		printResult(result)
+		if !result.Passed {
+			os.Exit(1)
+		}
+	},
+}
+
+var dnsFlushCmd = &cobra.Command{
+	Use:   "flush",
+	Short: "Flush the daemon's cache of upstream DNS answers",
+	Long: `Drop all upstream answers cached by devproxy's DNS server, e.g. after
+changing a record at your DNS provider. Answers for local domains are not
+cached and always reflect the current configuration.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !daemon.New().IsRunning() {
+			return daemon.ErrNotRunning
+		}
+
+		if _, err := proxy.Control(proxy.QueryRequest{Cmd: proxy.ControlDNSFlush}); err != nil {
+			return fmt.Errorf("failed to flush DNS cache: %w", err)
+		}
+		fmt.Println("DNS cache flushed")
+		return nil
+	},
+}
+
+func init() {
+	dnsCmd.AddCommand(dnsCheckCmd)
+	dnsCmd.AddCommand(dnsFlushCmd)
+	rootCmd.AddCommand(dnsCmd)
+}
+
+// dnsCheck holds what devproxy and the system resolver answered for a name.
+type dnsCheck struct {
+	name      string
+	listen    string   // devproxy's DNS listen address
+	domain    string   // matching dns.domains entry or static record, empty if none
+	expected  []net.IP // devproxy's answer
+	queryErr  error    // devproxy's DNS server did not answer
+	system    []string // system resolver's answer
+	systemErr error
+
+	// canary is a random name under domain that only devproxy answers, so
+	// the system resolver can only resolve it to devproxy's answer by asking
+	// devproxy. Empty for static records.
+	canary         string
+	canaryExpected []net.IP
+	canarySystem   []string
+	canaryErr      error
+}
+
+// runDNSCheck resolves name and a canary name under its domain through
+// devproxy's DNS server and the system resolver.
+func runDNSCheck(cfg *config.Config, name string) dnsCheck {
+	c := dnsCheck{
+		name:   strings.ToLower(strings.TrimSuffix(name, ".")),
+		listen: cfg.DNS.Listen,
+	}
+	c.domain = localDomain(cfg.DNS, c.name)
+
+	if c.domain != "" {
+		c.expected, c.queryErr = dns.Query(dialAddr(cfg.DNS.Listen), c.name, dnsQueryTimeout)
+	}
+	if c.domain != "" && c.domain != c.name && c.queryErr == nil {
+		c.canary = canaryName(c.domain)
+		c.canaryExpected, c.queryErr = dns.Query(dialAddr(cfg.DNS.Listen), c.canary, dnsQueryTimeout)
+		c.canarySystem, c.canaryErr = net.LookupHost(c.canary)
+	}
+	c.system, c.systemErr = net.LookupHost(c.name)
+	return c
+}
+
+// canaryName returns a random name under domain.
+func canaryName(domain string) string {
+	return "devproxy-check-" + strings.ToLower(rand.Text()[:12]) + "." + domain
+}
+
+// localDomain returns the static record or dns.domains entry that makes
+// devproxy answer name itself, or an empty string if it forwards name upstream.
+func localDomain(cfg config.DNSConfig, name string) string {
+	for record := range cfg.Records {
+		if strings.EqualFold(strings.TrimSuffix(record, "."), name) {
+			return name
+		}
+	}
+	for _, domain := range cfg.Domains {
+		if dns.IsLocalName([]string{domain}, name) {
+			return strings.ToLower(strings.TrimSuffix(domain, "."))
+		}
+	}
+	return ""
+}
+
+// dialAddr turns a listen address such as ":15353" into one that can be dialed.
+func dialAddr(listen string) string {
+	host, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return listen
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// result evaluates the check, with hints for the platform goos.
+func (c dnsCheck) result(goos string) CheckResult {
+	result := CheckResult{Name: "dns_check"}
+
+	switch {
+	case c.domain == "":
+		result.Message = fmt.Sprintf("%s is not a devproxy domain and is forwarded upstream", c.name)
+		result.Suggestion = "Add its domain to dns.domains in the config file"
+
+	case c.queryErr != nil:
+		result.Message = fmt.Sprintf("devproxy DNS server at %s is not answering: %v", c.listen, c.queryErr)
+		result.Suggestion = "Start devproxy with 'devproxy start' and make sure dns.enabled is true"
+
+	case c.canary != "" && c.canaryErr != nil:
+		result.Message = fmt.Sprintf("queries for %s do not reach devproxy's DNS server: %s does not resolve: %v", c.domain, c.canary, c.canaryErr)
+		result.Suggestion = resolverHint(goos, c.domain, c.listen)
+
+	case c.canary != "" && !overlaps(c.canaryExpected, c.canarySystem):
+		result.Message = fmt.Sprintf("queries for %s do not reach devproxy's DNS server: %s resolves to %s instead of devproxy's %s",
+			c.domain, c.canary, strings.Join(c.canarySystem, ", "), joinIPs(c.canaryExpected))
+		result.Suggestion = resolverHint(goos, c.domain, c.listen)
+
+	case c.systemErr != nil:
+		result.Message = fmt.Sprintf("%s does not resolve through the system resolver: %v", c.name, c.systemErr)
+		result.Suggestion = resolverHint(goos, c.domain, c.listen)
+
+	case overlaps(c.expected, c.system):
+		result.Passed = true
+		result.Message = fmt.Sprintf("%s resolves through devproxy (%s)", c.name, strings.Join(c.system, ", "))
+		if c.domain == "localhost" {
+			// RFC 6761: resolvers answer localhost names with loopback themselves
+			result.Message = fmt.Sprintf("%s resolves to devproxy's address (%s); the system resolver answers localhost names itself",
+				c.name, strings.Join(c.system, ", "))
+		}
+
+	default:
+		result.Message = fmt.Sprintf("%s resolves to %s instead of devproxy's %s (upstream DNS or a hosts file entry)",
+			c.name, strings.Join(c.system, ", "), joinIPs(c.expected))
+		result.Suggestion = resolverHint(goos, c.domain, c.listen)
+	}
+	return result
+}
+
+// resolverHint explains how to send queries for domain to devproxy on goos.
+func resolverHint(goos, domain, listen string) string {
+	switch goos {
+	case "darwin":
+		return fmt.Sprintf("Run 'sudo devproxy setup' to create /etc/resolver/%s", domain)
+	case "linux":
+		return fmt.Sprintf("Forward %s to %s, e.g. with DNS=%s and Domains=~%s in /etc/systemd/resolved.conf",
+			domain, dialAddr(listen), dialAddr(listen), domain)
+	default:
+		return fmt.Sprintf("Configure the system resolver to send queries for %s to %s", domain, dialAddr(listen))
+	}
+}
+
+// overlaps reports whether any of addrs is one of ips.
+func overlaps(ips []net.IP, addrs []string) bool {
+	for _, addr := range addrs {
+		parsed := net.ParseIP(addr)
+		for _, ip := range ips {
+			if ip.Equal(parsed) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// joinIPs formats ips as a comma-separated list.
+func joinIPs(ips []net.IP) string {
+	s := make([]string, len(ips))
+	for i, ip := range ips {
+		s[i] = ip.String()
+	}
+	return strings.Join(s, ", ")
+}
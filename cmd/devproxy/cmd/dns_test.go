@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/config"
+)
+
+func TestLocalDomain(t *testing.T) {
+	cfg := config.DNSConfig{
+		Domains: []string{"localhost", "Test."},
+		Records: map[string]string{"db.internal": "10.0.0.5"},
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"app.localhost", "localhost"},
+		{"api.app.test", "test"},
+		{"db.internal", "db.internal"},
+		{"example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := localDomain(cfg, tt.name); got != tt.want {
+				t.Errorf("localDomain(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDialAddr(t *testing.T) {
+	tests := []struct {
+		listen string
+		want   string
+	}{
+		{":15353", "127.0.0.1:15353"},
+		{"0.0.0.0:53", "127.0.0.1:53"},
+		{"127.0.0.2:53", "127.0.0.2:53"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.listen, func(t *testing.T) {
+			if got := dialAddr(tt.listen); got != tt.want {
+				t.Errorf("dialAddr(%q) = %q, want %q", tt.listen, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDNSCheckResult(t *testing.T) {
+	loopback := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+
+	tests := []struct {
+		name           string
+		check          dnsCheck
+		goos           string
+		wantPassed     bool
+		wantMessage    string
+		wantSuggestion string
+	}{
+		{
+			name: "resolves through devproxy",
+			check: dnsCheck{name: "app.test", domain: "test", expected: loopback, system: []string{"::1", "127.0.0.1"},
+				canary: "devproxy-check-abc.test", canaryExpected: loopback, canarySystem: []string{"127.0.0.1"}},
+			wantPassed:  true,
+			wantMessage: "resolves through devproxy",
+		},
+		{
+			name: "localhost names are answered by the system",
+			check: dnsCheck{name: "app.localhost", domain: "localhost", expected: loopback, system: []string{"::1", "127.0.0.1"},
+				canary: "devproxy-check-abc.localhost", canaryExpected: loopback, canarySystem: []string{"::1", "127.0.0.1"}},
+			wantPassed:  true,
+			wantMessage: "answers localhost names itself",
+		},
+		{
+			name: "localhost names resolving to loopback instead of the configured IP",
+			check: dnsCheck{name: "app.localhost", listen: ":15353", domain: "localhost", expected: []net.IP{net.ParseIP("10.0.0.5")}, system: []string{"127.0.0.1"},
+				canary: "devproxy-check-abc.localhost", canaryExpected: []net.IP{net.ParseIP("10.0.0.5")}, canarySystem: []string{"127.0.0.1"}},
+			goos:           "linux",
+			wantMessage:    "devproxy-check-abc.localhost resolves to 127.0.0.1 instead of devproxy's 10.0.0.5",
+			wantSuggestion: "Domains=~localhost",
+		},
+		{
+			name: "name in a hosts file but domain not sent to devproxy",
+			check: dnsCheck{name: "app.test", listen: ":15353", domain: "test", expected: loopback, system: []string{"127.0.0.1"},
+				canary: "devproxy-check-abc.test", canaryExpected: loopback, canaryErr: errors.New("no such host")},
+			goos:           "darwin",
+			wantMessage:    "queries for test do not reach devproxy's DNS server",
+			wantSuggestion: "/etc/resolver/test",
+		},
+		{
+			name:           "not a devproxy domain",
+			check:          dnsCheck{name: "example.com", system: []string{"93.184.216.34"}},
+			wantMessage:    "not a devproxy domain",
+			wantSuggestion: "dns.domains",
+		},
+		{
+			name:           "devproxy not answering",
+			check:          dnsCheck{name: "app.localhost", listen: ":15353", domain: "localhost", queryErr: errors.New("timeout")},
+			wantMessage:    "not answering",
+			wantSuggestion: "devproxy start",
+		},
+		{
+			name:           "system resolver does not know the domain on macOS",
+			check:          dnsCheck{name: "app.test", listen: ":15353", domain: "test", expected: loopback, systemErr: errors.New("no such host")},
+			goos:           "darwin",
+			wantMessage:    "does not resolve through the system resolver",
+			wantSuggestion: "/etc/resolver/test",
+		},
+		{
+			name:           "resolves upstream on linux",
+			check:          dnsCheck{name: "app.test", listen: ":15353", domain: "test", expected: loopback, system: []string{"10.1.2.3"}},
+			goos:           "linux",
+			wantMessage:    "resolves to 10.1.2.3 instead of devproxy's 127.0.0.1, ::1",
+			wantSuggestion: "DNS=127.0.0.1:15353 and Domains=~test",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.check.result(tt.goos)
+			if got.Passed != tt.wantPassed {
+				t.Errorf("Passed = %v, want %v", got.Passed, tt.wantPassed)
+			}
+			if !strings.Contains(got.Message, tt.wantMessage) {
+				t.Errorf("Message = %q, want it to contain %q", got.Message, tt.wantMessage)
+			}
+			if !strings.Contains(got.Suggestion, tt.wantSuggestion) {
+				t.Errorf("Suggestion = %q, want it to contain %q", got.Suggestion, tt.wantSuggestion)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/config"
+	"github.com/munichmade/devproxy/internal/daemon"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+var domainAddExact bool
+
+var domainCmd = &cobra.Command{
+	Use:   "domain",
+	Short: "Manage local domains and their certificates",
+}
+
+var domainListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the domains routed by the daemon",
+	Long: `List the domains the running daemon routes, with their backend and where
+the route comes from:
+
+  docker  discovered from the labels of a running container
+  config  listed in the routes section of the config file
+  manual  added with 'devproxy route add' or 'devproxy route import'`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !daemon.New().IsRunning() {
+			return daemon.ErrNotRunning
+		}
+		routes, err := proxy.LoadState()
+		if err != nil {
+			return fmt.Errorf("failed to load routes: %w", err)
+		}
+		writeDomainList(os.Stdout, routes)
+		return nil
+	},
+}
+
+var domainAddCmd = &cobra.Command{
+	Use:   "add <domain>",
+	Short: "Generate a certificate for a domain",
+	Long: `Generate a certificate for a domain ahead of time.
+
+By default subdomains are covered by a wildcard certificate for their parent
+domain, so 'devproxy domain add api.example.localhost' creates a certificate
+for *.example.localhost that also covers other subdomains of example.localhost.
+Use --exact to issue a certificate for exactly the given name instead.
+
+Examples:
+  devproxy domain add api.example.localhost          # *.example.localhost
+  devproxy domain add api.example.localhost --exact  # api.example.localhost only`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		certManager, err := cert.NewManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize certificate manager: %w", err)
+		}
+		if cfg, err := config.Load(); err == nil {
+			certManager.SetReuseKey(cfg.Cert.ReuseKey)
+			certManager.SetWildcardDepth(cfg.Cert.WildcardDepth)
+			certManager.SetKeyType(cert.KeyType(cfg.Cert.KeyType))
+			certManager.SetValidity(cfg.Cert.ValidityDays, cfg.Cert.RenewBeforeDays)
+		}
+
+		leaf, err := addDomain(certManager, args[0], domainAddExact)
+		if err != nil {
+			return err
+		}
+
+		// Make a running daemon look for the new certificate on disk
+		if daemon.New().IsRunning() {
+			if _, err := proxy.Control(proxy.QueryRequest{Cmd: proxy.ControlCertRescan}); err != nil {
+				fmt.Fprintf(os.Stderr, "warning: failed to notify daemon: %v\n", err)
+			}
+		}
+
+		fmt.Printf("Certificate ready: %s\n", leaf.Subject.CommonName)
+		fmt.Printf("  Covers: %s\n", strings.Join(leaf.DNSNames, ", "))
+		fmt.Printf("  Expires: %s\n", leaf.NotAfter.Format("2006-01-02"))
+		return nil
+	},
+}
+
+// addDomain ensures a certificate exists for the domain and returns the
+// certificate that will be served for it.
+func addDomain(certManager *cert.Manager, domain string, exact bool) (*x509.Certificate, error) {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+
+	ensure := certManager.EnsureCertificate
+	if exact {
+		ensure = certManager.EnsureExactCertificate
+	}
+	if err := ensure(domain); err != nil {
+		return nil, err
+	}
+
+	tlsCert, err := certManager.GetCertificate(&tls.ClientHelloInfo{ServerName: domain})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load certificate for %s: %w", domain, err)
+	}
+
+	leaf, err := x509.ParseCertificate(tlsCert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	return leaf, nil
+}
+
+// writeDomainList prints routes as a table sorted by host.
+func writeDomainList(out io.Writer, routes []proxy.Route) {
+	if len(routes) == 0 {
+		fmt.Fprintln(out, "No domains routed")
+		return
+	}
+
+	routes = append([]proxy.Route(nil), routes...)
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		return routes[i].Entrypoint < routes[j].Entrypoint
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "HOST\tBACKEND\tPROTOCOL\tSOURCE\n")
+	for _, route := range routes {
+		protocol := route.Protocol
+		if protocol == "" {
+			protocol = proxy.ProtocolHTTP
+		}
+		source := route.Origin()
+		if source == "" {
+			source = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", route.Host, route.Backend, protocol, source)
+	}
+	w.Flush()
+}
+
+func init() {
+	domainCmd.AddCommand(domainListCmd)
+	domainAddCmd.Flags().BoolVar(&domainAddExact, "exact", false, "Issue a certificate for the exact name instead of a wildcard")
+	domainCmd.AddCommand(domainAddCmd)
+	rootCmd.AddCommand(domainCmd)
+}
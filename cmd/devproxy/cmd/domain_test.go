@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/paths"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+func TestAddDomain(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	t.Cleanup(paths.Reset)
+
+	if _, err := ca.Generate(); err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	certManager, err := cert.NewManager()
+	if err != nil {
+		t.Fatalf("failed to create cert manager: %v", err)
+	}
+
+	t.Run("exact issues certificate for the exact name", func(t *testing.T) {
+		leaf, err := addDomain(certManager, "api.exact.localhost", true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if leaf.Subject.CommonName != "api.exact.localhost" {
+			t.Errorf("expected CN api.exact.localhost, got %q", leaf.Subject.CommonName)
+		}
+		for _, name := range leaf.DNSNames {
+			if name != "api.exact.localhost" {
+				t.Errorf("unexpected SAN %q in exact certificate", name)
+			}
+		}
+	})
+
+	t.Run("default issues wildcard certificate", func(t *testing.T) {
+		leaf, err := addDomain(certManager, "api.wild.localhost", false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if leaf.Subject.CommonName != "*.wild.localhost" {
+			t.Errorf("expected CN *.wild.localhost, got %q", leaf.Subject.CommonName)
+		}
+	})
+
+	t.Run("rejects empty domain", func(t *testing.T) {
+		if _, err := addDomain(certManager, " ", true); err == nil {
+			t.Error("expected error for empty domain")
+		}
+	})
+}
+
+func TestWriteDomainList(t *testing.T) {
+	var buf bytes.Buffer
+	writeDomainList(&buf, []proxy.Route{
+		{Host: "web.localhost", Backend: "172.18.0.2:80", ContainerID: "abc123", Source: proxy.SourceDocker},
+		{Host: "grafana.localhost", Backend: "127.0.0.1:3000", Source: proxy.SourceConfig},
+		{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: proxy.ProtocolTCP, Source: proxy.SourceManual},
+		{Host: "old.localhost", Backend: "172.18.0.3:80", ContainerID: "def456"},
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := [][]string{
+		{"HOST", "BACKEND", "PROTOCOL", "SOURCE"},
+		{"db.localhost", "127.0.0.1:5432", "tcp", "manual"},
+		{"grafana.localhost", "127.0.0.1:3000", "http", "config"},
+		{"old.localhost", "172.18.0.3:80", "http", "docker"},
+		{"web.localhost", "172.18.0.2:80", "http", "docker"},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(want), len(lines), buf.String())
+	}
+	for i, fields := range want {
+		if got := strings.Fields(lines[i]); strings.Join(got, " ") != strings.Join(fields, " ") {
+			t.Errorf("line %d = %q, want %q", i, got, fields)
+		}
+	}
+
+	t.Run("no routes", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeDomainList(&buf, nil)
+		if !strings.Contains(buf.String(), "No domains routed") {
+			t.Errorf("unexpected output %q", buf.String())
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/config"
+	"github.com/munichmade/devproxy/internal/logging"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+// entrypointDrainTimeout is how long connections of an entrypoint stopped by a
+// reload may run before they are closed.
+const entrypointDrainTimeout = 30 * time.Second
+
+// tcpEntrypointSet runs the TCP entrypoints of the config and applies
+// entrypoint changes on reload without touching the HTTP and HTTPS servers.
+type tcpEntrypointSet struct {
+	ctx    context.Context
+	shared proxy.TCPEntrypointConfig // settings shared by all entrypoints
+
+	mu      sync.Mutex
+	running map[string]runningEntrypoint
+}
+
+// runningEntrypoint is a started entrypoint and the config it was started with.
+type runningEntrypoint struct {
+	cfg config.EntrypointConfig
+	ep  *proxy.TCPEntrypoint
+}
+
+// entrypointChanges lists the names of entrypoints changed by a reload.
+type entrypointChanges struct {
+	started   []string
+	stopped   []string
+	restarted []string
+}
+
+// empty reports whether nothing changed.
+func (c entrypointChanges) empty() bool {
+	return len(c.started) == 0 && len(c.stopped) == 0 && len(c.restarted) == 0
+}
+
+// newTCPEntrypointSet creates an empty set. The name, listen address and
+// per-entrypoint settings of shared are replaced for each entrypoint.
+func newTCPEntrypointSet(ctx context.Context, shared proxy.TCPEntrypointConfig) *tcpEntrypointSet {
+	return &tcpEntrypointSet{
+		ctx:     ctx,
+		shared:  shared,
+		running: make(map[string]runningEntrypoint),
+	}
+}
+
+// start starts the entrypoint name. A nil listener binds epCfg.Listen.
+func (s *tcpEntrypointSet) start(name string, epCfg config.EntrypointConfig, listener net.Listener) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.startLocked(name, epCfg, listener)
+}
+
+func (s *tcpEntrypointSet) startLocked(name string, epCfg config.EntrypointConfig, listener net.Listener) error {
+	tcpCfg := s.shared
+	tcpCfg.Name = name
+	tcpCfg.Listen = epCfg.Listen
+	tcpCfg.TargetPort = epCfg.TargetPort
+	tcpCfg.TargetPortMode = proxy.TargetPortMode(epCfg.TargetPortMode)
+	tcpCfg.DefaultHost = epCfg.DefaultHost
+	tcpCfg.RateLimit = epCfg.RateLimit
+	tcpCfg.Backlog = epCfg.Backlog
+	certManager, err := entrypointCertManager(s.shared.CertManager, name, epCfg)
+	if err != nil {
+		return err
+	}
+	tcpCfg.CertManager = certManager
+
+	ep := proxy.NewTCPEntrypointWithListener(tcpCfg, listener)
+	if err := ep.Start(s.ctx); err != nil {
+		return privilegedPortError(epCfg.Listen, err)
+	}
+	s.running[name] = runningEntrypoint{cfg: epCfg, ep: ep}
+	logging.Info("TCP entrypoint started", "name", name, "address", epCfg.Listen, "target_port", epCfg.TargetPort)
+	return nil
+}
+
+// apply starts entrypoints added to entrypoints, stops removed ones and
+// restarts ones whose settings changed. Stopped entrypoints stop accepting
+// connections right away; their active connections are drained for up to
+// entrypointDrainTimeout.
+func (s *tcpEntrypointSet) apply(entrypoints map[string]config.EntrypointConfig) entrypointChanges {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wanted := tcpEntrypointConfigs(entrypoints)
+	var changes entrypointChanges
+	for _, name := range sortedKeys(s.running) {
+		if _, ok := wanted[name]; !ok {
+			s.drain(name, s.running[name].ep)
+			delete(s.running, name)
+			logging.Info("TCP entrypoint stopped", "name", name)
+			changes.stopped = append(changes.stopped, name)
+		}
+	}
+
+	for _, name := range sortedKeys(wanted) {
+		epCfg := wanted[name]
+		old, ok := s.running[name]
+		switch {
+		case !ok:
+			if err := s.startLocked(name, epCfg, nil); err != nil {
+				logging.Error("failed to start TCP entrypoint", "name", name, "error", err)
+				continue
+			}
+			changes.started = append(changes.started, name)
+
+		case !reflect.DeepEqual(old.cfg, epCfg):
+			if old.cfg.Listen == epCfg.Listen {
+				// Free the address for the replacement
+				old.ep.Close()
+				delete(s.running, name)
+			}
+			err := s.startLocked(name, epCfg, nil)
+			if old.cfg.Listen == epCfg.Listen || err == nil {
+				s.drain(name, old.ep)
+			}
+			if err != nil {
+				logging.Error("failed to restart TCP entrypoint", "name", name, "address", epCfg.Listen, "error", err)
+				continue
+			}
+			changes.restarted = append(changes.restarted, name)
+		}
+	}
+	return changes
+}
+
+// drain stops ep in the background, closing its connections still open after
+// entrypointDrainTimeout or when the set's context is done.
+func (s *tcpEntrypointSet) drain(name string, ep *proxy.TCPEntrypoint) {
+	ep.Close()
+	go func() {
+		ctx, cancel := context.WithTimeout(s.ctx, entrypointDrainTimeout)
+		defer cancel()
+		if err := ep.Stop(ctx); err != nil {
+			logging.Error("failed to stop TCP entrypoint", "name", name, "error", err)
+		}
+	}()
+}
+
+// privilegedPortError explains a failure to bind a port below 1024, which
+// only root may bind. The daemon binds the ports of its initial config before
+// dropping root, so an entrypoint added by a reload cannot use one.
+func privilegedPortError(addr string, err error) error {
+	if !errors.Is(err, syscall.EACCES) {
+		return err
+	}
+	_, portStr, splitErr := net.SplitHostPort(addr)
+	port, convErr := strconv.Atoi(portStr)
+	if splitErr != nil || convErr != nil || port >= 1024 {
+		return err
+	}
+	return fmt.Errorf("%w (port %d is privileged and the daemon no longer runs as root; restart devproxy to bind it)", err, port)
+}
+
+// stopAll stops all entrypoints, waiting for their connections up to ctx.
+func (s *tcpEntrypointSet) stopAll(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, r := range s.running {
+		if err := r.ep.Stop(ctx); err != nil {
+			logging.Error("failed to stop TCP entrypoint", "name", name, "error", err)
+		}
+	}
+	s.running = make(map[string]runningEntrypoint)
+}
+
+// addr returns the address the entrypoint name listens on, or an empty
+// string if it is not running.
+func (s *tcpEntrypointSet) addr(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.running[name]; ok {
+		return r.ep.Addr()
+	}
+	return ""
+}
+
+// entrypointCertManager returns the certificate manager for the entrypoint
+// name, which applies the entrypoint's certificate settings to certs.
+func entrypointCertManager(certs *cert.Manager, name string, epCfg config.EntrypointConfig) (*cert.Manager, error) {
+	if certs == nil {
+		return nil, nil
+	}
+
+	var policy cert.Policy
+	for _, ip := range epCfg.Cert.IPSANs {
+		policy.IPAddresses = append(policy.IPAddresses, net.ParseIP(ip))
+	}
+	if epCfg.Cert.CACert != "" {
+		issuer, err := ca.LoadFiles(epCfg.Cert.CACert, epCfg.Cert.CAKey)
+		if err != nil {
+			return nil, fmt.Errorf("entrypoint %s: failed to load CA: %w", name, err)
+		}
+		policy.CA = issuer
+	}
+	return certs.ForEntrypoint(name, policy)
+}
+
+// tcpEntrypointConfigs returns the entrypoints served as TCP entrypoints.
+func tcpEntrypointConfigs(entrypoints map[string]config.EntrypointConfig) map[string]config.EntrypointConfig {
+	tcp := make(map[string]config.EntrypointConfig)
+	for name, ep := range entrypoints {
+		if name == "http" || name == "https" || ep.TargetPort <= 0 {
+			continue
+		}
+		tcp[name] = ep
+	}
+	return tcp
+}
+
+// sortedKeys returns the keys of m in order, so changes are applied and
+// logged deterministically.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/config"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+func TestTCPEntrypointSet_Apply(t *testing.T) {
+	set := newTCPEntrypointSet(context.Background(), proxy.TCPEntrypointConfig{Registry: proxy.NewRegistry()})
+	defer set.stopAll(context.Background())
+
+	entrypoints := map[string]config.EntrypointConfig{
+		"http":     {Listen: "127.0.0.1:0"},
+		"postgres": {Listen: "127.0.0.1:0", TargetPort: 5432},
+		"mysql":    {Listen: "127.0.0.1:0", TargetPort: 3306},
+	}
+	changes := set.apply(entrypoints)
+	if !slices.Equal(changes.started, []string{"mysql", "postgres"}) {
+		t.Fatalf("expected mysql and postgres to start, got %v", changes.started)
+	}
+	mysqlAddr, postgresAddr := set.addr("mysql"), set.addr("postgres")
+
+	t.Run("ignores unchanged entrypoints", func(t *testing.T) {
+		if changes := set.apply(entrypoints); !changes.empty() {
+			t.Errorf("expected no changes, got %+v", changes)
+		}
+		if set.addr("postgres") != postgresAddr {
+			t.Error("expected postgres to keep running on the same listener")
+		}
+	})
+
+	t.Run("starts, stops and restarts", func(t *testing.T) {
+		changes := set.apply(map[string]config.EntrypointConfig{
+			"postgres": {Listen: "127.0.0.1:0", TargetPort: 5433},
+			"redis":    {Listen: "127.0.0.1:0", TargetPort: 6379},
+		})
+
+		if !slices.Equal(changes.started, []string{"redis"}) {
+			t.Errorf("expected redis to start, got %v", changes.started)
+		}
+		if !slices.Equal(changes.stopped, []string{"mysql"}) {
+			t.Errorf("expected mysql to stop, got %v", changes.stopped)
+		}
+		if !slices.Equal(changes.restarted, []string{"postgres"}) {
+			t.Errorf("expected postgres to restart, got %v", changes.restarted)
+		}
+
+		if set.addr("mysql") != "" {
+			t.Error("expected mysql to be removed")
+		}
+		if conn, err := net.Dial("tcp", mysqlAddr); err == nil {
+			conn.Close()
+			t.Error("expected stopped entrypoint to refuse connections")
+		}
+		for _, name := range []string{"postgres", "redis"} {
+			conn, err := net.Dial("tcp", set.addr(name))
+			if err != nil {
+				t.Errorf("expected %s to accept connections: %v", name, err)
+				continue
+			}
+			conn.Close()
+		}
+	})
+}
+
+func TestPrivilegedPortError(t *testing.T) {
+	denied := &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EACCES)}
+	inUse := &net.OpError{Op: "listen", Net: "tcp", Err: os.NewSyscallError("bind", syscall.EADDRINUSE)}
+
+	tests := []struct {
+		name     string
+		addr     string
+		err      error
+		wantHint bool
+	}{
+		{name: "privileged port denied", addr: ":443", err: denied, wantHint: true},
+		{name: "unprivileged port denied", addr: ":8443", err: denied},
+		{name: "privileged port in use", addr: ":443", err: inUse},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := privilegedPortError(tt.addr, tt.err)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v to wrap %v", err, tt.err)
+			}
+			if hint := strings.Contains(err.Error(), "restart devproxy"); hint != tt.wantHint {
+				t.Errorf("privilegedPortError() = %q, want restart hint %v", err, tt.wantHint)
+			}
+		})
+	}
+}
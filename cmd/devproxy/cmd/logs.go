@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,6 +24,7 @@ var (
 	logsSince  string
 	logsLines  int
 	logsLevel  string
+	logsHost   string
 )
 
 var logsCmd = &cobra.Command{
@@ -27,12 +32,17 @@ var logsCmd = &cobra.Command{
 	Short: "View daemon logs",
 	Long: `Display logs from the devproxy daemon.
 
+With --host, only lines whose host field is the given host are shown, such as
+its access log entries and the route and health check messages for it. The
+container's own output is shown by 'devproxy route logs' instead.
+
 Examples:
   devproxy logs              # Show recent logs
   devproxy logs -f           # Follow log output
   devproxy logs --lines 100  # Show last 100 lines
   devproxy logs --since 1h   # Show logs from last hour
-  devproxy logs --level error # Filter by log level`,
+  devproxy logs --level error # Filter by log level
+  devproxy logs --host app.localhost -f # Follow the lines of one host`,
 	RunE: runLogs,
 }
 
@@ -41,10 +51,17 @@ func init() {
 	logsCmd.Flags().StringVar(&logsSince, "since", "", "Show logs since duration (e.g., 1h, 30m, 24h)")
 	logsCmd.Flags().IntVarP(&logsLines, "lines", "n", 50, "Number of lines to show")
 	logsCmd.Flags().StringVar(&logsLevel, "level", "", "Filter by log level (debug, info, warn, error)")
+	logsCmd.Flags().StringVar(&logsHost, "host", "", "Show only lines for this host")
 	rootCmd.AddCommand(logsCmd)
 }
 
 func runLogs(cmd *cobra.Command, args []string) error {
+	// The host field is only reliable in the daemon's own log file; journal
+	// lines carry a prefix
+	if logsHost != "" {
+		return runLogsFromFile()
+	}
+
 	switch runtime.GOOS {
 	case "darwin":
 		return runLogsMacOS()
@@ -83,6 +100,13 @@ func runLogsLinux() error {
 	}
 
 	execCmd := exec.Command("journalctl", args...)
+	// journalctl detects terminals and NO_COLOR itself; only explicit modes are forced
+	switch colorMode {
+	case colorAlways:
+		execCmd.Env = append(os.Environ(), "SYSTEMD_COLORS=1")
+	case colorNever:
+		execCmd.Env = append(os.Environ(), "SYSTEMD_COLORS=0")
+	}
 	execCmd.Stdout = os.Stdout
 	execCmd.Stderr = os.Stderr
 
@@ -108,8 +132,10 @@ func runLogsFromFile() error {
 	}
 	defer file.Close()
 
+	color := stdoutColor()
+
 	if logsFollow {
-		return followLogFile(file)
+		return followLogFile(file, color)
 	}
 
 	// Read all lines and get the last N
@@ -123,6 +149,11 @@ func runLogsFromFile() error {
 		return fmt.Errorf("error reading log file: %w", err)
 	}
 
+	// Filter by host before taking the last lines, so they are the host's
+	if logsHost != "" {
+		lines = filterByHost(lines, logsHost)
+	}
+
 	// Apply since filter if specified
 	if logsSince != "" {
 		duration, err := parseDuration(logsSince)
@@ -141,13 +172,13 @@ func runLogsFromFile() error {
 
 	// Filter by level if specified
 	filtered := filterLogLines(lines)
-	printLogLines(filtered)
+	printLogLines(os.Stdout, filtered, color)
 
 	return nil
 }
 
 // followLogFile tails the log file
-func followLogFile(file *os.File) error {
+func followLogFile(file *os.File, color bool) error {
 	// Seek to end of file
 	_, err := file.Seek(0, 2)
 	if err != nil {
@@ -166,8 +197,8 @@ func followLogFile(file *os.File) error {
 		}
 
 		line = strings.TrimSuffix(line, "\n")
-		if logsLevel == "" || matchesLevel(line, logsLevel) {
-			printLogLine(line)
+		if (logsLevel == "" || matchesLevel(line, logsLevel)) && (logsHost == "" || matchesHost(line, logsHost)) {
+			printLogLine(os.Stdout, line, color)
 		}
 	}
 }
@@ -187,6 +218,66 @@ func filterLogLines(lines []string) []string {
 	return filtered
 }
 
+// filterByHost returns the lines whose host field is host
+func filterByHost(lines []string, host string) []string {
+	var filtered []string
+	for _, line := range lines {
+		if matchesHost(line, host) {
+			filtered = append(filtered, line)
+		}
+	}
+	return filtered
+}
+
+// matchesHost checks if the host field of a log line is host, ignoring case
+// and the port of request hosts
+func matchesHost(line, host string) bool {
+	lineHost := logLineHost(line)
+	if h, _, err := net.SplitHostPort(lineHost); err == nil {
+		lineHost = h
+	}
+	return lineHost != "" && strings.EqualFold(lineHost, strings.TrimSpace(host))
+}
+
+// logLineHost returns the host field of a log line in slog's JSON or text
+// format, or "" if it has none
+func logLineHost(line string) string {
+	if strings.HasPrefix(line, "{") {
+		var entry struct {
+			Host string `json:"host"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return ""
+		}
+		return entry.Host
+	}
+
+	// Text format: key=value pairs, values with spaces or quotes are quoted
+	for rest := strings.TrimSpace(line); rest != ""; rest = strings.TrimLeft(rest, " ") {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			return ""
+		}
+		key, value := rest[:eq], rest[eq+1:]
+		if strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(value)
+			if err != nil {
+				return ""
+			}
+			rest = value[len(quoted):]
+			value, _ = strconv.Unquote(quoted)
+		} else if end := strings.IndexByte(value, ' '); end >= 0 {
+			value, rest = value[:end], value[end:]
+		} else {
+			rest = ""
+		}
+		if key == "host" {
+			return value
+		}
+	}
+	return ""
+}
+
 // matchesLevel checks if a log line matches the specified level
 func matchesLevel(line, level string) bool {
 	level = strings.ToUpper(level)
@@ -264,25 +355,25 @@ func extractTimestamp(line string) time.Time {
 }
 
 // printLogLines prints log lines with optional colorization
-func printLogLines(lines []string) {
+func printLogLines(w io.Writer, lines []string, color bool) {
 	for _, line := range lines {
-		printLogLine(line)
+		printLogLine(w, line, color)
 	}
 }
 
-// printLogLine prints a single log line with colorization
-func printLogLine(line string) {
+// printLogLine prints a single log line, colorized by level if color is set
+func printLogLine(w io.Writer, line string, color bool) {
 	// Simple colorization based on level
 	upper := strings.ToUpper(line)
 
 	if strings.Contains(upper, "ERROR") || strings.Contains(upper, "ERR") {
-		fmt.Printf("\033[31m%s\033[0m\n", line) // Red
+		fmt.Fprintln(w, colorize(line, ansiRed, color))
 	} else if strings.Contains(upper, "WARN") {
-		fmt.Printf("\033[33m%s\033[0m\n", line) // Yellow
+		fmt.Fprintln(w, colorize(line, ansiYellow, color))
 	} else if strings.Contains(upper, "DEBUG") {
-		fmt.Printf("\033[36m%s\033[0m\n", line) // Cyan
+		fmt.Fprintln(w, colorize(line, ansiCyan, color))
 	} else {
-		fmt.Println(line)
+		fmt.Fprintln(w, line)
 	}
 }
 
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// daemonLogLines writes log entries like the daemon's with handler and
+// returns them as lines.
+func daemonLogLines(t *testing.T, newHandler func(*bytes.Buffer) slog.Handler) []string {
+	t.Helper()
+	var buf bytes.Buffer
+	logger := slog.New(newHandler(&buf))
+
+	logger.Info("access", "method", "GET", "host", "app.localhost:443", "path", "/", "status", 200)
+	logger.Info("access", "method", "GET", "host", "api.localhost", "path", "/v1", "status", 502)
+	logger.Warn("backend health check failing", "host", "APP.localhost", "backend", "172.18.0.2:80", "error", "connection refused")
+	logger.Info("route added host=app.localhost", "host", "other.localhost")
+	logger.Warn("no route for default host", "entrypoint", "postgres", "default_host", "app.localhost")
+	logger.Info("configuration reloaded")
+
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
+
+func TestFilterByHost(t *testing.T) {
+	formats := []struct {
+		name       string
+		newHandler func(*bytes.Buffer) slog.Handler
+	}{
+		{"text", func(buf *bytes.Buffer) slog.Handler { return slog.NewTextHandler(buf, nil) }},
+		{"json", func(buf *bytes.Buffer) slog.Handler { return slog.NewJSONHandler(buf, nil) }},
+	}
+
+	for _, format := range formats {
+		t.Run(format.name, func(t *testing.T) {
+			lines := daemonLogLines(t, format.newHandler)
+
+			filtered := filterByHost(lines, "app.localhost")
+			if len(filtered) != 2 {
+				t.Fatalf("expected 2 lines for app.localhost, got %d:\n%s", len(filtered), strings.Join(filtered, "\n"))
+			}
+			if !strings.Contains(filtered[0], "access") || !strings.Contains(filtered[1], "health check failing") {
+				t.Errorf("expected the access log and health check lines, got:\n%s", strings.Join(filtered, "\n"))
+			}
+
+			// Neither the message nor other keys ending in host count
+			if got := filterByHost(lines, "other.localhost"); len(got) != 1 {
+				t.Errorf("expected 1 line for other.localhost, got %d", len(got))
+			}
+			if got := filterByHost(lines, "missing.localhost"); len(got) != 0 {
+				t.Errorf("expected no lines for missing.localhost, got %d", len(got))
+			}
+		})
+	}
+}
+
+func TestLogLineHost(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{"text", `time=2026-01-02T15:04:05Z level=INFO msg=access host=app.localhost status=200`, "app.localhost"},
+		{"quoted text value", `time=2026-01-02T15:04:05Z level=INFO msg=access host="odd host" status=200`, "odd host"},
+		{"json", `{"time":"2026-01-02T15:04:05Z","level":"INFO","msg":"access","host":"app.localhost"}`, "app.localhost"},
+		{"no host field", `time=2026-01-02T15:04:05Z level=INFO msg="configuration reloaded"`, ""},
+		{"unstructured", `dropped privileges to user dev (uid=1000)`, ""},
+		{"invalid json", `{"host":`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logLineHost(tt.line); got != tt.want {
+				t.Errorf("logLineHost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
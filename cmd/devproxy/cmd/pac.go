@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/munichmade/devproxy/internal/config"
+)
+
+var pacOutput string
+
+var pacCmd = &cobra.Command{
+	Use:   "pac",
+	Short: "Generate a proxy auto-config (PAC) file for browsers",
+	Long: `Generate a proxy auto-config (PAC) file that routes the configured local
+domains through devproxy. All other hosts are connected DIRECT.
+
+This is an alternative to 'devproxy setup' when the system DNS resolver
+cannot be modified. Point your browser's automatic proxy configuration
+at the generated file.
+
+Examples:
+  devproxy pac                      # Print PAC file to stdout
+  devproxy pac -o ~/devproxy.pac    # Write PAC file`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			cfg = config.Default()
+		}
+
+		pac := generatePAC(cfg)
+
+		if pacOutput == "" {
+			fmt.Print(pac)
+			return nil
+		}
+
+		if err := os.WriteFile(pacOutput, []byte(pac), 0o644); err != nil {
+			return fmt.Errorf("failed to write PAC file: %w", err)
+		}
+		fmt.Printf("PAC file written to %s\n", pacOutput)
+		return nil
+	},
+}
+
+// generatePAC builds a PAC file from the configured DNS domains and entrypoint ports.
+// Local domains are sent to the HTTP entrypoint, which redirects to HTTPS and
+// tunnels CONNECT requests to the HTTPS entrypoint.
+func generatePAC(cfg *config.Config) string {
+	httpPort := 80
+	if ep, ok := cfg.GetEntrypoint("http"); ok {
+		httpPort = extractPort(ep.Listen, 80)
+	}
+	httpsPort := 443
+	if ep, ok := cfg.GetEntrypoint("https"); ok {
+		httpsPort = extractPort(ep.Listen, 443)
+	}
+
+	conditions := make([]string, 0, len(cfg.DNS.Domains))
+	for _, domain := range cfg.DNS.Domains {
+		domain = strings.ToLower(strings.Trim(domain, "."))
+		if domain == "" {
+			continue
+		}
+		conditions = append(conditions,
+			fmt.Sprintf("host == %q || dnsDomainIs(host, %q)", domain, "."+domain))
+	}
+
+	var b strings.Builder
+	b.WriteString("// devproxy proxy auto-config (generated by 'devproxy pac')\n")
+	fmt.Fprintf(&b, "// Local domains: %s\n", strings.Join(cfg.DNS.Domains, ", "))
+	fmt.Fprintf(&b, "// HTTP entrypoint: 127.0.0.1:%d, HTTPS entrypoint: 127.0.0.1:%d\n", httpPort, httpsPort)
+	b.WriteString("function FindProxyForURL(url, host) {\n")
+	b.WriteString("  host = host.toLowerCase();\n")
+	if len(conditions) > 0 {
+		fmt.Fprintf(&b, "  if (%s) {\n", strings.Join(conditions, " ||\n      "))
+		fmt.Fprintf(&b, "    return \"PROXY 127.0.0.1:%d\";\n", httpPort)
+		b.WriteString("  }\n")
+	}
+	b.WriteString("  return \"DIRECT\";\n")
+	b.WriteString("}\n")
+
+	return b.String()
+}
+
+func init() {
+	pacCmd.Flags().StringVarP(&pacOutput, "output", "o", "", "Write PAC file to path instead of stdout")
+	rootCmd.AddCommand(pacCmd)
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/config"
+)
+
+func TestGeneratePAC(t *testing.T) {
+	t.Run("references configured domains and ports", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.DNS.Domains = []string{"localhost", "test"}
+		cfg.Entrypoints["http"] = config.EntrypointConfig{Listen: ":8080"}
+		cfg.Entrypoints["https"] = config.EntrypointConfig{Listen: "127.0.0.1:8443"}
+
+		pac := generatePAC(cfg)
+
+		expected := []string{
+			"function FindProxyForURL(url, host)",
+			`host == "localhost" || dnsDomainIs(host, ".localhost")`,
+			`host == "test" || dnsDomainIs(host, ".test")`,
+			`return "PROXY 127.0.0.1:8080";`,
+			"HTTPS entrypoint: 127.0.0.1:8443",
+			`return "DIRECT";`,
+		}
+		for _, want := range expected {
+			if !strings.Contains(pac, want) {
+				t.Errorf("expected PAC to contain %q, got:\n%s", want, pac)
+			}
+		}
+	})
+
+	t.Run("uses default ports", func(t *testing.T) {
+		pac := generatePAC(config.Default())
+
+		if !strings.Contains(pac, `return "PROXY 127.0.0.1:80";`) {
+			t.Errorf("expected PAC to proxy via port 80, got:\n%s", pac)
+		}
+		if !strings.Contains(pac, "HTTPS entrypoint: 127.0.0.1:443") {
+			t.Errorf("expected PAC to reference port 443, got:\n%s", pac)
+		}
+	})
+
+	t.Run("normalizes domain case and dots", func(t *testing.T) {
+		cfg := config.Default()
+		cfg.DNS.Domains = []string{".Dev.Test."}
+
+		pac := generatePAC(cfg)
+
+		if !strings.Contains(pac, `dnsDomainIs(host, ".dev.test")`) {
+			t.Errorf("expected normalized domain, got:\n%s", pac)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/logging"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+// prewarmDelay is how long route changes must settle before certificates
+// are generated, so a burst of changes (e.g., docker compose up) is handled
+// in one pass.
+const prewarmDelay = 500 * time.Millisecond
+
+// certPrewarmer generates the certificates of all routes in the background
+// after routes change.
+type certPrewarmer struct {
+	certs    *cert.Manager
+	registry *proxy.Registry
+	delay    time.Duration
+
+	mu    sync.Mutex
+	timer *time.Timer
+
+	// runMu keeps passes from overlapping when changes arrive during one
+	runMu sync.Mutex
+}
+
+// newCertPrewarmer creates a prewarmer for the routes of registry.
+func newCertPrewarmer(certs *cert.Manager, registry *proxy.Registry) *certPrewarmer {
+	return &certPrewarmer{
+		certs:    certs,
+		registry: registry,
+		delay:    prewarmDelay,
+	}
+}
+
+// schedule runs a pass once no further changes arrive within the delay.
+func (p *certPrewarmer) schedule() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.timer == nil {
+		p.timer = time.AfterFunc(p.delay, p.run)
+		return
+	}
+	p.timer.Reset(p.delay)
+}
+
+// stop cancels a scheduled pass.
+func (p *certPrewarmer) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+}
+
+// run ensures certificates for the hosts of all routes.
+func (p *certPrewarmer) run() {
+	p.runMu.Lock()
+	defer p.runMu.Unlock()
+
+	routes := p.registry.List()
+	hosts := make([]string, 0, len(routes))
+	for _, route := range routes {
+		hosts = append(hosts, route.Host)
+	}
+
+	start := time.Now()
+	generated, err := p.certs.Prewarm(hosts)
+	if err != nil {
+		logging.Warn("failed to pre-generate some certificates", "error", err)
+	}
+	if generated > 0 {
+		logging.Info("certificates pre-generated", "count", generated, "routes", len(routes), "duration", time.Since(start))
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/paths"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+func TestCertPrewarmer(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	t.Cleanup(paths.Reset)
+
+	if _, err := ca.Generate(); err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	certManager, err := cert.NewManager()
+	if err != nil {
+		t.Fatalf("failed to create cert manager: %v", err)
+	}
+
+	registry := proxy.NewRegistry()
+	prewarmer := newCertPrewarmer(certManager, registry)
+	prewarmer.delay = 10 * time.Millisecond
+	registry.OnChange(prewarmer.schedule)
+	defer prewarmer.stop()
+
+	hosts := []string{"app.localhost", "api.shop.localhost", "web.shop.localhost", "*.docs.localhost"}
+	for _, host := range hosts {
+		registry.Add(proxy.Route{Host: host, Backend: "127.0.0.1:3000"})
+	}
+
+	// A burst of changes leads to one pass once it settles
+	deadline := time.Now().Add(5 * time.Second)
+	for len(certManager.CacheEntries()) < 3 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	domains := make(map[string]bool)
+	for _, entry := range certManager.CacheEntries() {
+		domains[entry.Domain] = true
+	}
+	for _, want := range []string{"app.localhost", "*.shop.localhost", "*.docs.localhost"} {
+		if !domains[want] {
+			t.Errorf("expected a certificate for %s, got %v", want, domains)
+		}
+	}
+	if len(domains) != 3 {
+		t.Errorf("expected hosts sharing a wildcard to share a certificate, got %v", domains)
+	}
+}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"time"
 
@@ -12,10 +13,20 @@ import (
 	"github.com/munichmade/devproxy/internal/privilege"
 )
 
+const (
+	// restartTimeout bounds how long restart waits for the old daemon to
+	// exit. Stop sends SIGKILL after 10 seconds, so this leaves it time to die.
+	restartTimeout = 15 * time.Second
+
+	// restartPollInterval is how often restart checks whether it exited.
+	restartPollInterval = 100 * time.Millisecond
+)
+
 var restartCmd = &cobra.Command{
 	Use:   "restart",
 	Short: "Restart the devproxy daemon",
-	Long:  `Stop and start the devproxy daemon.`,
+	Long: `Stop the running devproxy daemon, wait for it to exit and start a new one.
+If the daemon is not running, it is started.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Elevate to root if needed (for binding ports 80/443)
 		if err := privilege.RequireRoot("binding to ports 80 and 443"); err != nil {
@@ -23,39 +34,73 @@ var restartCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		d := daemon.New()
-
-		// Stop if running
-		if d.IsRunning() {
-			if err := d.Stop(); err != nil && !errors.Is(err, daemon.ErrNotRunning) {
-				fmt.Fprintf(os.Stderr, "failed to stop daemon: %v\n", err)
-				os.Exit(1)
-			}
-
-			// Wait a moment for the process to exit
-			for i := 0; i < 10; i++ {
-				if !d.IsRunning() {
-					break
-				}
-				time.Sleep(100 * time.Millisecond)
-			}
-
-			if d.IsRunning() {
-				fmt.Fprintln(os.Stderr, "daemon did not stop in time")
-				os.Exit(1)
-			}
+		r := &restarter{
+			daemon:   daemon.New(),
+			running:  daemon.IsProcessRunning,
+			out:      os.Stdout,
+			timeout:  restartTimeout,
+			interval: restartPollInterval,
 		}
-
-		// Start
-		if err := d.Start(); err != nil {
-			fmt.Fprintf(os.Stderr, "failed to start daemon: %v\n", err)
+		if err := r.restart(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
-
-		fmt.Println("devproxy restarted")
 	},
 }
 
+// daemonControl is the part of daemon.Daemon a restart uses.
+type daemonControl interface {
+	GetPID() (int, error)
+	Stop() error
+	Start() error
+}
+
+// restarter stops a running daemon and starts a new one once the old process
+// is gone, so the new one does not race it for the ports.
+type restarter struct {
+	daemon   daemonControl
+	running  func(pid int) bool // reports whether the process pid exists
+	out      io.Writer
+	timeout  time.Duration
+	interval time.Duration
+}
+
+// restart runs the stop and start phases, reporting each to r.out.
+func (r *restarter) restart() error {
+	if pid, err := r.daemon.GetPID(); err == nil && r.running(pid) {
+		fmt.Fprintf(r.out, "Stopping devproxy (pid %d)...\n", pid)
+		if err := r.daemon.Stop(); err != nil && !errors.Is(err, daemon.ErrNotRunning) {
+			return fmt.Errorf("failed to stop daemon: %w", err)
+		}
+		// Stop gives up waiting after SIGKILL, so wait for the process itself
+		if err := r.waitForExit(pid); err != nil {
+			return err
+		}
+		fmt.Fprintln(r.out, "devproxy stopped")
+	} else {
+		fmt.Fprintln(r.out, "devproxy is not running")
+	}
+
+	fmt.Fprintln(r.out, "Starting devproxy...")
+	if err := r.daemon.Start(); err != nil {
+		return fmt.Errorf("failed to start daemon: %w", err)
+	}
+	fmt.Fprintln(r.out, "devproxy restarted")
+	return nil
+}
+
+// waitForExit polls until the process pid is gone or r.timeout passes.
+func (r *restarter) waitForExit(pid int) error {
+	deadline := time.Now().Add(r.timeout)
+	for r.running(pid) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("daemon (pid %d) did not exit within %s", pid, r.timeout)
+		}
+		time.Sleep(r.interval)
+	}
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(restartCmd)
 }
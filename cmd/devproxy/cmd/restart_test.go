@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDaemon records the calls of a restart. Its process exits exitPolls
+// state checks after Stop, or never if exitPolls is negative.
+type fakeDaemon struct {
+	pid       int
+	alive     bool
+	exitPolls int
+	startErr  error
+	calls     []string
+}
+
+func (d *fakeDaemon) GetPID() (int, error) {
+	if d.pid == 0 {
+		return 0, os.ErrNotExist
+	}
+	return d.pid, nil
+}
+
+func (d *fakeDaemon) Stop() error {
+	d.calls = append(d.calls, "stop")
+	return nil
+}
+
+func (d *fakeDaemon) Start() error {
+	d.calls = append(d.calls, "start")
+	if d.alive {
+		return errors.New("ports in use by the old daemon")
+	}
+	return d.startErr
+}
+
+func (d *fakeDaemon) running(pid int) bool {
+	if pid != d.pid || !d.alive {
+		return false
+	}
+	if slices.Contains(d.calls, "stop") && d.exitPolls >= 0 {
+		if d.exitPolls == 0 {
+			d.alive = false
+			return false
+		}
+		d.exitPolls--
+	}
+	return true
+}
+
+func TestRestarter(t *testing.T) {
+	tests := []struct {
+		name      string
+		daemon    *fakeDaemon
+		wantCalls []string
+		wantOut   []string
+		wantErr   string
+	}{
+		{
+			name:      "not running",
+			daemon:    &fakeDaemon{},
+			wantCalls: []string{"start"},
+			wantOut:   []string{"devproxy is not running", "devproxy restarted"},
+		},
+		{
+			name:      "stale pid file",
+			daemon:    &fakeDaemon{pid: 42},
+			wantCalls: []string{"start"},
+			wantOut:   []string{"devproxy is not running", "devproxy restarted"},
+		},
+		{
+			name:      "waits for the old process to exit",
+			daemon:    &fakeDaemon{pid: 42, alive: true, exitPolls: 3},
+			wantCalls: []string{"stop", "start"},
+			wantOut:   []string{"Stopping devproxy (pid 42)", "devproxy stopped", "Starting devproxy", "devproxy restarted"},
+		},
+		{
+			name:      "old process does not exit",
+			daemon:    &fakeDaemon{pid: 42, alive: true, exitPolls: -1},
+			wantCalls: []string{"stop"},
+			wantErr:   "did not exit",
+		},
+		{
+			name:      "start fails",
+			daemon:    &fakeDaemon{startErr: errors.New("boom")},
+			wantCalls: []string{"start"},
+			wantErr:   "failed to start daemon: boom",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			r := &restarter{
+				daemon:   tt.daemon,
+				running:  tt.daemon.running,
+				out:      &out,
+				timeout:  50 * time.Millisecond,
+				interval: time.Millisecond,
+			}
+
+			err := r.restart()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+			} else if err != nil {
+				t.Errorf("restart() error = %v", err)
+			}
+
+			if !slices.Equal(tt.daemon.calls, tt.wantCalls) {
+				t.Errorf("expected calls %v, got %v", tt.wantCalls, tt.daemon.calls)
+			}
+			rest := out.String()
+			for _, want := range tt.wantOut {
+				i := strings.Index(rest, want)
+				if i < 0 {
+					t.Errorf("expected %q in order in output:\n%s", want, out.String())
+					break
+				}
+				rest = rest[i+len(want):]
+			}
+		})
+	}
+}
@@ -6,25 +6,23 @@ import (
 	"os"
 
 	"github.com/spf13/cobra"
-)
 
-// Build-time variables set via ldflags.
-var (
-	Version   = "dev"
-	Commit    = "unknown"
-	BuildDate = "unknown"
+	"github.com/munichmade/devproxy/internal/version"
 )
 
 var rootCmd = &cobra.Command{
 	Use:     "devproxy",
 	Short:   "Local development reverse proxy with TLS and SNI support",
-	Version: Version,
+	Version: version.Version,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return validateColorMode(colorMode)
+	},
 }
 
 // Execute runs the root command.
 func Execute() {
 	// Update version and long description (set here since Version may be set via ldflags after init)
-	rootCmd.Version = Version
+	rootCmd.Version = version.Version
 	rootCmd.Long = fmt.Sprintf(`DevProxy v%s - Local development reverse proxy
 
 DevProxy provides:
@@ -35,7 +33,7 @@ DevProxy provides:
   - Built-in DNS server for seamless domain resolution
 
 Start by running 'devproxy setup' to configure your system,
-then 'devproxy start' to run the proxy daemon.`, Version)
+then 'devproxy start' to run the proxy daemon.`, version.Version)
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -44,5 +42,6 @@ then 'devproxy start' to run the proxy daemon.`, Version)
 }
 
 func init() {
-	rootCmd.SetVersionTemplate(fmt.Sprintf("devproxy version {{.Version}}\ncommit: %s\nbuilt: %s\n", Commit, BuildDate))
+	rootCmd.PersistentFlags().StringVar(&colorMode, "color", colorAuto, "Colorize output: auto, always or never")
+	rootCmd.SetVersionTemplate(fmt.Sprintf("devproxy version {{.Version}}\ncommit: %s\nbuilt: %s\n", version.Commit, version.BuildDate))
 }
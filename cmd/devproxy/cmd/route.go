@@ -0,0 +1,341 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/munichmade/devproxy/internal/config"
+	"github.com/munichmade/devproxy/internal/daemon"
+	"github.com/munichmade/devproxy/internal/docker"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+// ErrNotDockerRoute is returned when a route is not backed by a Docker container.
+var ErrNotDockerRoute = errors.New("route is not backed by a Docker container")
+
+var (
+	routeLogsLines     int
+	routeExportFormat  string
+	routeAddEntrypoint string
+)
+
+var routeCmd = &cobra.Command{
+	Use:   "route",
+	Short: "Inspect and control proxied routes",
+}
+
+var routeLogsCmd = &cobra.Command{
+	Use:   "logs <host>",
+	Short: "Show logs of the container backing a route",
+	Long: `Show the last lines of the Docker container logs for the route serving <host>.
+
+Examples:
+  devproxy route logs app.localhost           # Last 50 lines
+  devproxy route logs app.localhost -n 200    # Last 200 lines`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !daemon.New().IsRunning() {
+			return daemon.ErrNotRunning
+		}
+
+		routes, err := proxy.LoadState()
+		if err != nil {
+			return fmt.Errorf("failed to load routes: %w", err)
+		}
+
+		route, err := findRoute(routes, args[0])
+		if err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			cfg = config.Default()
+		}
+
+		client, err := docker.NewClientWithVersion(cfg.Docker.APIVersion, slog.Default())
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		return client.ContainerLogs(ctx, route.ContainerID, routeLogsLines, os.Stdout)
+	},
+}
+
+var routeDisableCmd = &cobra.Command{
+	Use:   "disable <host>",
+	Short: "Temporarily stop proxying a route (HTTP requests get 503)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return toggleRoute(args[0], false)
+	},
+}
+
+var routeEnableCmd = &cobra.Command{
+	Use:   "enable <host>",
+	Short: "Resume proxying a disabled route",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return toggleRoute(args[0], true)
+	},
+}
+
+var routeAddCmd = &cobra.Command{
+	Use:   "add <host> <backend>",
+	Short: "Proxy a host to a backend until the daemon stops",
+	Long: `Add a route to the running daemon without editing the config file. The
+route lasts until the daemon stops; add it to the routes section of the config
+file to keep it. Hosts that already have a route are refused.
+
+Routes proxy HTTP(S) requests unless --entrypoint is set, which proxies TCP
+connections on that entrypoint instead.
+
+Examples:
+  devproxy route add grafana.localhost 127.0.0.1:3000
+  devproxy route add db.localhost 127.0.0.1:5432 --entrypoint postgres`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		route, err := manualRoute(args[0], args[1], routeAddEntrypoint)
+		if err != nil {
+			return err
+		}
+		if !daemon.New().IsRunning() {
+			return daemon.ErrNotRunning
+		}
+		if err := proxy.AddRoute(route); err != nil {
+			return fmt.Errorf("failed to add route: %w", err)
+		}
+		fmt.Printf("Added route %s -> %s\n", route.Host, route.Backend)
+		return nil
+	},
+}
+
+var routeRemoveCmd = &cobra.Command{
+	Use:     "rm <host>",
+	Aliases: []string{"remove"},
+	Short:   "Remove a route added with 'devproxy route add'",
+	Long: `Remove the routes of a host added with 'devproxy route add' or
+'devproxy route import' from the running daemon. Routes from Docker containers
+or the config file come back with their source and are refused.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if !daemon.New().IsRunning() {
+			return daemon.ErrNotRunning
+		}
+		if err := proxy.RemoveRoute(args[0]); err != nil {
+			return fmt.Errorf("failed to remove route: %w", err)
+		}
+		fmt.Printf("Removed route %s\n", args[0])
+		return nil
+	},
+}
+
+var routeExportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export manual routes for sharing",
+	Long: `Write the routes not backed by Docker containers to a file, or to stdout
+if no file is given. Docker routes are skipped, since they come and go with
+their containers. Basic auth credentials are not exported.
+
+The format is taken from the file extension (.json, .yaml or .yml) unless
+--format is set, and defaults to YAML.
+
+Examples:
+  devproxy route export routes.yaml
+  devproxy route export --format json > routes.json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		routes, err := proxy.LoadState()
+		if err != nil {
+			return fmt.Errorf("failed to load routes: %w", err)
+		}
+
+		file := ""
+		if len(args) > 0 {
+			file = args[0]
+		}
+		manual := proxy.ManualRoutes(routes)
+		data, err := proxy.ExportRoutes(manual, exportFormat(file, routeExportFormat))
+		if err != nil {
+			return err
+		}
+
+		if file == "" || file == "-" {
+			_, err = os.Stdout.Write(data)
+			return err
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return fmt.Errorf("failed to write routes: %w", err)
+		}
+		fmt.Printf("Exported %d routes to %s\n", len(manual), file)
+		return nil
+	},
+}
+
+var routeImportCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Add routes exported with 'devproxy route export'",
+	Long: `Add the routes in a file written by 'devproxy route export' to the running
+daemon, reading stdin if no file is given. Routes for hosts that already have
+a route are skipped. Imported routes last until the daemon stops; add them to
+the routes section of the config file to keep them.
+
+Examples:
+  devproxy route import routes.yaml
+  cat routes.json | devproxy route import`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var data []byte
+		var err error
+		if len(args) == 0 || args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read routes: %w", err)
+		}
+
+		routes, err := proxy.ParseRoutes(data)
+		if err != nil {
+			return err
+		}
+		return importRoutes(routes)
+	},
+}
+
+// manualRoute builds the route added by 'devproxy route add'. With an
+// entrypoint, it is a TCP route on that entrypoint.
+func manualRoute(host, backend, entrypoint string) (proxy.Route, error) {
+	route := proxy.Route{
+		Host:     strings.ToLower(strings.TrimSpace(host)),
+		Backend:  backend,
+		Protocol: proxy.ProtocolHTTP,
+	}
+	if route.Host == "" {
+		return proxy.Route{}, errors.New("host is empty")
+	}
+	if entrypoint != "" {
+		route.Protocol = proxy.ProtocolTCP
+		route.Entrypoint = entrypoint
+	}
+	if err := proxy.ValidateBackend(backend, route.Protocol == proxy.ProtocolTCP); err != nil {
+		return proxy.Route{}, err
+	}
+	return route, nil
+}
+
+// exportFormat returns format if set, otherwise the format matching the
+// extension of file, defaulting to YAML.
+func exportFormat(file, format string) string {
+	if format != "" {
+		return format
+	}
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		return "json"
+	}
+	return "yaml"
+}
+
+// importRoutes asks the daemon to add routes and reports the routes that were
+// not added.
+func importRoutes(routes []proxy.Route) error {
+	if !daemon.New().IsRunning() {
+		return daemon.ErrNotRunning
+	}
+	if len(routes) == 0 {
+		fmt.Println("No routes to import")
+		return nil
+	}
+
+	skipped, err := proxy.ImportRoutes(routes)
+	if err != nil {
+		return fmt.Errorf("failed to import routes: %w", err)
+	}
+	for _, reason := range skipped {
+		fmt.Printf("Skipped %s\n", reason)
+	}
+	fmt.Printf("Imported %d routes\n", len(routes)-len(skipped))
+	return nil
+}
+
+// toggleRoute asks the daemon to enable or disable the route serving host.
+func toggleRoute(host string, enabled bool) error {
+	if !daemon.New().IsRunning() {
+		return daemon.ErrNotRunning
+	}
+
+	routes, err := proxy.ListRoutes()
+	if err != nil {
+		return fmt.Errorf("failed to list routes: %w", err)
+	}
+	route, err := lookupRoute(routes, host)
+	if err != nil {
+		return err
+	}
+
+	if err := proxy.SetRouteEnabled(route.Host, enabled); err != nil {
+		return fmt.Errorf("failed to change route: %w", err)
+	}
+
+	action := "disabled"
+	if enabled {
+		action = "enabled"
+	}
+	fmt.Printf("Route %s %s\n", route.Host, action)
+	return nil
+}
+
+// lookupRoute returns the route serving host, preferring exact over wildcard routes.
+func lookupRoute(routes []proxy.Route, host string) (*proxy.Route, error) {
+	registry := proxy.NewRegistry()
+	for _, route := range routes {
+		_ = registry.Add(route)
+	}
+
+	route := registry.Lookup(host)
+	if route == nil {
+		return nil, fmt.Errorf("no route configured for host: %s", host)
+	}
+	return route, nil
+}
+
+// findRoute returns the route serving host, preferring exact over wildcard routes.
+// Returns ErrNotDockerRoute if the route has no backing container.
+func findRoute(routes []proxy.Route, host string) (*proxy.Route, error) {
+	route, err := lookupRoute(routes, host)
+	if err != nil {
+		return nil, err
+	}
+	if route.ContainerID == "" {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrNotDockerRoute, route.Host, route.Backend)
+	}
+	return route, nil
+}
+
+func init() {
+	routeLogsCmd.Flags().IntVarP(&routeLogsLines, "lines", "n", 50, "Number of lines to show")
+	routeCmd.AddCommand(routeLogsCmd)
+	routeCmd.AddCommand(routeDisableCmd)
+	routeCmd.AddCommand(routeEnableCmd)
+	routeAddCmd.Flags().StringVar(&routeAddEntrypoint, "entrypoint", "", "Proxy TCP connections on this entrypoint instead of HTTP(S)")
+	routeCmd.AddCommand(routeAddCmd)
+	routeCmd.AddCommand(routeRemoveCmd)
+	routeExportCmd.Flags().StringVar(&routeExportFormat, "format", "", "Output format: yaml or json (default: from the file extension, else yaml)")
+	routeCmd.AddCommand(routeExportCmd)
+	routeCmd.AddCommand(routeImportCmd)
+	rootCmd.AddCommand(routeCmd)
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+func TestFindRoute(t *testing.T) {
+	routes := []proxy.Route{
+		{Host: "app.localhost", Backend: "172.18.0.2:80", ContainerID: "abc123"},
+		{Host: "*.docs.localhost", Backend: "172.18.0.3:80", ContainerID: "def456"},
+		{Host: "grafana.localhost", Backend: "127.0.0.1:3000"},
+	}
+
+	tests := []struct {
+		name            string
+		host            string
+		wantContainerID string
+		wantErr         error
+	}{
+		{name: "exact route", host: "app.localhost", wantContainerID: "abc123"},
+		{name: "wildcard route", host: "v2.docs.localhost", wantContainerID: "def456"},
+		{name: "non-docker route", host: "grafana.localhost", wantErr: ErrNotDockerRoute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := findRoute(routes, tt.host)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if route.ContainerID != tt.wantContainerID {
+				t.Errorf("expected container %s, got %s", tt.wantContainerID, route.ContainerID)
+			}
+		})
+	}
+
+	t.Run("unknown host", func(t *testing.T) {
+		if _, err := findRoute(routes, "missing.localhost"); err == nil {
+			t.Error("expected error for unknown host")
+		}
+	})
+}
+
+func TestExportFormat(t *testing.T) {
+	tests := []struct {
+		file, format string
+		want         string
+	}{
+		{file: "", want: "yaml"},
+		{file: "routes.yaml", want: "yaml"},
+		{file: "routes.JSON", want: "json"},
+		{file: "routes.json", format: "yaml", want: "yaml"},
+		{file: "", format: "json", want: "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file+"/"+tt.format, func(t *testing.T) {
+			if got := exportFormat(tt.file, tt.format); got != tt.want {
+				t.Errorf("exportFormat(%q, %q) = %q, want %q", tt.file, tt.format, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestManualRoute(t *testing.T) {
+	tests := []struct {
+		name       string
+		host       string
+		backend    string
+		entrypoint string
+		want       proxy.Route
+		wantErr    error
+	}{
+		{
+			name:    "http route",
+			host:    "Grafana.localhost",
+			backend: "127.0.0.1:3000",
+			want:    proxy.Route{Host: "grafana.localhost", Backend: "127.0.0.1:3000", Protocol: proxy.ProtocolHTTP},
+		},
+		{
+			name:       "tcp route",
+			host:       "db.localhost",
+			backend:    "127.0.0.1",
+			entrypoint: "postgres",
+			want:       proxy.Route{Host: "db.localhost", Backend: "127.0.0.1", Protocol: proxy.ProtocolTCP, Entrypoint: "postgres"},
+		},
+		{name: "http backend without port", host: "app.localhost", backend: "127.0.0.1", wantErr: proxy.ErrInvalidBackend},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, err := manualRoute(tt.host, tt.backend, tt.entrypoint)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("manualRoute() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("manualRoute() error = %v", err)
+			}
+			if route.Host != tt.want.Host || route.Backend != tt.want.Backend ||
+				route.Protocol != tt.want.Protocol || route.Entrypoint != tt.want.Entrypoint {
+				t.Errorf("manualRoute() = %+v, want %+v", route, tt.want)
+			}
+		})
+	}
+}
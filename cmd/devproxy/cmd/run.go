@@ -2,12 +2,20 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
+	"net/http"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -17,10 +25,13 @@ import (
 	"github.com/munichmade/devproxy/internal/daemon"
 	"github.com/munichmade/devproxy/internal/dns"
 	"github.com/munichmade/devproxy/internal/docker"
+	"github.com/munichmade/devproxy/internal/listen"
 	"github.com/munichmade/devproxy/internal/logging"
+	"github.com/munichmade/devproxy/internal/metrics"
 	"github.com/munichmade/devproxy/internal/paths"
 	"github.com/munichmade/devproxy/internal/privilege"
 	"github.com/munichmade/devproxy/internal/proxy"
+	"github.com/munichmade/devproxy/internal/tracing"
 )
 
 // chownRecursive changes ownership of a directory and all its contents
@@ -78,13 +89,13 @@ func runDaemon() error {
 	httpsCfg, _ := cfg.GetEntrypoint("https")
 
 	// Bind HTTP port
-	httpListener, err := net.Listen("tcp", httpCfg.Listen)
+	httpListener, err := listen.TCP(httpCfg.Listen, httpCfg.Backlog)
 	if err != nil {
 		return fmt.Errorf("failed to bind HTTP port %s: %w", httpCfg.Listen, err)
 	}
 
 	// Bind HTTPS port
-	httpsListener, err := net.Listen("tcp", httpsCfg.Listen)
+	httpsListener, err := listen.TCP(httpsCfg.Listen, httpsCfg.Backlog)
 	if err != nil {
 		httpListener.Close()
 		return fmt.Errorf("failed to bind HTTPS port %s: %w", httpsCfg.Listen, err)
@@ -93,7 +104,7 @@ func runDaemon() error {
 	// Bind DNS port if enabled
 	var dnsListener net.PacketConn
 	if cfg.DNS.Enabled {
-		dnsListener, err = net.ListenPacket("udp", cfg.DNS.Listen)
+		dnsListener, err = dns.ListenPacket(cfg.DNS.Listen, cfg.DNS.BindRetries)
 		if err != nil {
 			httpListener.Close()
 			httpsListener.Close()
@@ -103,11 +114,8 @@ func runDaemon() error {
 
 	// Bind TCP entrypoint ports
 	tcpListeners := make(map[string]net.Listener)
-	for name, epCfg := range cfg.Entrypoints {
-		if name == "http" || name == "https" || epCfg.TargetPort <= 0 {
-			continue
-		}
-		listener, err := net.Listen("tcp", epCfg.Listen)
+	for name, epCfg := range tcpEntrypointConfigs(cfg.Entrypoints) {
+		listener, err := listen.TCP(epCfg.Listen, epCfg.Backlog)
 		if err != nil {
 			// Clean up already-bound listeners
 			httpListener.Close()
@@ -170,7 +178,7 @@ func runDaemon() error {
 
 	// Initialize logging with configured level
 	logLevel := logging.ParseLevel(cfg.Logging.Level)
-	if err = logging.SetupFile(logLevel, logFile); err != nil {
+	if err = logging.SetupFile(logLevel, logging.ParseFormat(cfg.Logging.Format), logFile); err != nil {
 		return fmt.Errorf("failed to initialize logging: %w", err)
 	}
 
@@ -219,20 +227,83 @@ func runDaemon() error {
 	if err != nil {
 		return fmt.Errorf("failed to initialize certificate manager: %w", err)
 	}
-	logging.Info("certificate manager initialized")
+	certManager.SetReuseKey(cfg.Cert.ReuseKey)
+	certManager.SetWildcardDepth(cfg.Cert.WildcardDepth)
+	certManager.SetKeyType(cert.KeyType(cfg.Cert.KeyType))
+	certManager.SetValidity(cfg.Cert.ValidityDays, cfg.Cert.RenewBeforeDays)
+	if overrides := cfg.Cert.Testing.SNIOverrides; len(overrides) > 0 {
+		certManager.SetSNIOverrides(overrides)
+		logging.Warn("certificate SNI overrides enabled - clients are served mismatched certificates", "overrides", overrides)
+	}
+	logging.Info("certificate manager initialized", "reuse_key", cfg.Cert.ReuseKey, "wildcard_depth", cfg.Cert.WildcardDepth, "key_type", cfg.Cert.KeyType)
+
+	// Renew certificates ahead of expiry, also for hosts without handshakes
+	certManager.StartRenewal(cert.DefaultRenewalInterval)
+	shutdown.OnShutdown(certManager.StopRenewal)
 
 	// =========================================================================
 	// Initialize Route Registry
 	// =========================================================================
 	registry := proxy.NewRegistry()
+	ejectCooldown, _ := time.ParseDuration(httpsCfg.EjectCooldown)
+	registry.SetHealthPolicy(httpsCfg.FailureThreshold, ejectCooldown)
+	registry.SetRejectShadowing(cfg.Proxy.RejectShadowedRoutes)
+	registry.SetMaxRoutes(cfg.Proxy.MaxRoutes)
+
+	// Certificates for new routes are generated before their first handshake
+	var prewarmer *certPrewarmer
+	if cfg.Cert.Prewarm {
+		prewarmer = newCertPrewarmer(certManager, registry)
+		shutdown.OnShutdown(func() {
+			prewarmer.stop()
+		})
+	}
+
 	registry.OnChange(func() {
 		logging.Debug("route registry updated", "count", registry.Count())
 		// Save state to file for CLI to read
 		if err := registry.SaveState(); err != nil {
 			logging.Error("failed to save route state", "error", err)
 		}
+		if prewarmer != nil {
+			prewarmer.schedule()
+		}
 	})
-	logging.Info("route registry initialized")
+	syncStaticRoutes(registry, nil, cfg.Routes)
+
+	// Docker TCP routes are also indexed by host and entrypoint for the TCP entrypoints
+	tcpRegistry := proxy.NewTCPRegistry()
+	logging.Info("route registry initialized", "static_routes", len(cfg.Routes))
+
+	// Serve read-only registry queries (list, lookup, health) for editor integrations
+	queryServer := proxy.NewQueryServer(registry, slog.Default())
+	if err := queryServer.ListenUnix(proxy.QuerySocket()); err != nil {
+		logging.Warn("failed to start query socket", "error", err)
+	} else {
+		shutdown.OnShutdown(func() {
+			if err := queryServer.Close(); err != nil {
+				logging.Error("failed to close query socket", "error", err)
+			}
+		})
+		logging.Info("query socket listening", "path", proxy.QuerySocket())
+	}
+
+	// Change routes at runtime for 'devproxy route', and manage the caches for
+	// 'devproxy cert cache', 'devproxy domain add', 'devproxy ca rotate' and
+	// 'devproxy dns flush'. It is the CLI's only way to reach the daemon
+	// besides signals for stopping and reloading.
+	controlServer := proxy.NewControlServer(registry, slog.Default())
+	handleCertControls(controlServer, certManager)
+	if err := controlServer.ListenUnix(proxy.ControlSocket()); err != nil {
+		logging.Warn("failed to start control socket", "error", err)
+	} else {
+		shutdown.OnShutdown(func() {
+			if err := controlServer.Close(); err != nil {
+				logging.Error("failed to close control socket", "error", err)
+			}
+		})
+		logging.Info("control socket listening", "path", proxy.ControlSocket())
+	}
 
 	// =========================================================================
 	// Start DNS Server (using pre-bound listener)
@@ -240,10 +311,21 @@ func runDaemon() error {
 	var dnsServer *dns.Server
 	if cfg.DNS.Enabled && dnsListener != nil {
 		dnsConfig := dns.Config{
-			Addr:      cfg.DNS.Listen,
-			Domains:   cfg.DNS.Domains,
-			ResolveIP: net.ParseIP("127.0.0.1"),
-			Upstream:  cfg.DNS.Upstream,
+			Addr:             cfg.DNS.Listen,
+			Domains:          cfg.DNS.Domains,
+			ResolveIP:        net.ParseIP("127.0.0.1"),
+			Upstreams:        cfg.DNS.Upstreams,
+			Records:          dnsRecords(cfg.DNS.Records),
+			PTR:              cfg.DNS.PTRRecords(),
+			Aliases:          cfg.DNS.Aliases,
+			DomainIPs:        dnsRecords(cfg.DNS.DomainIPs),
+			ContainerDomains: cfg.DNS.ContainerDomains(),
+			ContainerIP:      containerIP(registry),
+			ServeStale:       cfg.DNS.ServeStale,
+			LocalOnly:        cfg.DNS.LocalOnly,
+			LocalOnlyRcode:   dns.ParseRcode(cfg.DNS.LocalOnlyRcode),
+			BindRetries:      cfg.DNS.BindRetries,
+			CacheSize:        cfg.DNS.CacheSize,
 		}
 		dnsServer = dns.NewWithListener(dnsConfig, dnsListener)
 		if err := dnsServer.Start(); err != nil {
@@ -259,6 +341,68 @@ func runDaemon() error {
 		logging.Info("DNS server disabled (using external DNS)")
 	}
 
+	// Flush the upstream answer cache for 'devproxy dns flush'
+	controlServer.Handle(proxy.ControlDNSFlush, func(proxy.QueryRequest) (proxy.QueryResponse, error) {
+		if dnsServer == nil {
+			return proxy.QueryResponse{}, errors.New("DNS server is not running (is dns.enabled true?)")
+		}
+		dnsServer.Flush()
+		return proxy.QueryResponse{}, nil
+	})
+
+	// Closures read the current config, so they see reloads; they run on
+	// other goroutines than the reload
+	var current atomic.Pointer[config.Config]
+	current.Store(cfg)
+
+	// =========================================================================
+	// Build HTTPS Handler
+	// =========================================================================
+	proxyHandler := proxy.NewProxyHandler(registry)
+	proxyHandler.SetMaxIdleConnsPerHost(cfg.Proxy.MaxIdleConnsPerHost)
+	// Wrap with access logger that checks config dynamically
+	// This allows hot-reloading the access_log setting
+	proxyHandler.SetNoRoute(func() proxy.NoRoute {
+		return proxy.ParseNoRoute(current.Load().Proxy.NoRoute, current.Load().Proxy.DefaultBackend)
+	})
+	proxyHandler.SetTrustedProxies(func() []netip.Prefix {
+		// Validated when the config was loaded
+		trusted, _ := proxy.ParseCIDRList(strings.Join(current.Load().Proxy.TrustedProxies, ","))
+		return trusted
+	})
+	// In-tree extensions register request/response transformers here
+	transformers := proxy.NewTransformers()
+	transformers.AddResponse(proxy.StripResponseHeaders(func() []string {
+		return current.Load().Proxy.StripResponseHeaders
+	}))
+	transformers.SetMaxBufferSize(func() int64 {
+		return current.Load().Proxy.MaxBufferSize
+	})
+	proxyHandler.SetTransformers(transformers)
+	compressor := proxy.NewCompressor(proxyHandler, func() bool {
+		return current.Load().Proxy.Compression
+	}, func() int64 {
+		return current.Load().Proxy.CompressionMinSize
+	})
+	var httpsHandler http.Handler = proxy.NewAccessLogger(compressor, slog.Default(), func() bool {
+		return current.Load().Logging.AccessLog
+	})
+	if cfg.Tracing.Enabled {
+		tracerProvider, err := tracing.NewProvider(context.Background(), cfg.Tracing.Endpoint)
+		if err != nil {
+			return fmt.Errorf("failed to initialize tracing: %w", err)
+		}
+		shutdown.OnShutdown(func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := tracerProvider.Shutdown(ctx); err != nil {
+				logging.Error("failed to flush traces", "error", err)
+			}
+		})
+		httpsHandler = proxy.NewTracingHandler(httpsHandler, tracerProvider)
+		logging.Info("request tracing enabled", "endpoint", cfg.Tracing.Endpoint)
+	}
+
 	// =========================================================================
 	// Start HTTP Server (using pre-bound listener)
 	// =========================================================================
@@ -271,6 +415,11 @@ func runDaemon() error {
 	}
 
 	httpServer := proxy.NewHTTPServerWithListener(httpListener, httpsPort)
+	httpServer.SetCAHost(func() string {
+		return current.Load().Proxy.CAHost
+	})
+	// Routes labeled devproxy.http=proxy are served without the redirect
+	httpServer.SetProxy(registry, httpsHandler)
 	if err := httpServer.Start(); err != nil {
 		return fmt.Errorf("failed to start HTTP server: %w", err)
 	}
@@ -284,15 +433,20 @@ func runDaemon() error {
 	// =========================================================================
 	// Start HTTPS Server (using pre-bound listener)
 	// =========================================================================
-	proxyHandler := proxy.NewProxyHandler(registry)
-	// Wrap with access logger that checks config dynamically
-	// This allows hot-reloading the access_log setting
-	// Use a pointer-to-pointer so the closure sees config updates
-	cfgPtr := &cfg
-	httpsHandler := proxy.NewAccessLogger(proxyHandler, slog.Default(), func() bool {
-		return (*cfgPtr).Logging.AccessLog
-	})
-	httpsServer := proxy.NewHTTPSServerWithListener(httpsListener, certManager, httpsHandler)
+	// Shared rotating ticket keys let browsers resume TLS sessions on reload
+	ticketKeys, err := proxy.NewSessionTicketKeys(proxy.DefaultTicketKeyRotation)
+	if err != nil {
+		return fmt.Errorf("failed to initialize TLS session tickets: %w", err)
+	}
+	ticketKeys.Start()
+	shutdown.OnShutdown(ticketKeys.Stop)
+
+	httpsCerts, err := entrypointCertManager(certManager, "https", httpsCfg)
+	if err != nil {
+		return err
+	}
+	httpsServer := proxy.NewHTTPSServerWithListener(httpsListener, httpsCerts, httpsHandler)
+	httpsServer.SetSessionTicketKeys(ticketKeys)
 	if err := httpsServer.Start(); err != nil {
 		return fmt.Errorf("failed to start HTTPS server: %w", err)
 	}
@@ -306,67 +460,87 @@ func runDaemon() error {
 	// =========================================================================
 	// Start TCP Entrypoints (using pre-bound listeners)
 	// =========================================================================
-	var tcpEntrypoints []*proxy.TCPEntrypoint
-	for name, epCfg := range cfg.Entrypoints {
-		// Skip http and https - they're handled above
-		if name == "http" || name == "https" {
-			continue
-		}
-
-		// Only start if target_port is configured (indicates TCP entrypoint)
-		if epCfg.TargetPort <= 0 {
-			continue
-		}
-
+	tcpEntrypoints := newTCPEntrypointSet(ctx, proxy.TCPEntrypointConfig{
+		Registry:       registry,
+		TCPRoutes:      tcpRegistry,
+		CertManager:    certManager,
+		Logger:         logger,
+		SessionTickets: ticketKeys,
+	})
+	for name, epCfg := range tcpEntrypointConfigs(cfg.Entrypoints) {
 		listener, ok := tcpListeners[name]
 		if !ok {
 			continue
 		}
-
-		tcpCfg := proxy.TCPEntrypointConfig{
-			Name:        name,
-			Listen:      epCfg.Listen,
-			TargetPort:  epCfg.TargetPort,
-			Registry:    registry,
-			CertManager: certManager,
-			Logger:      logger,
-		}
-
-		tcpEntry := proxy.NewTCPEntrypointWithListener(tcpCfg, listener)
-		if err := tcpEntry.Start(ctx); err != nil {
+		if err := tcpEntrypoints.start(name, epCfg, listener); err != nil {
 			logging.Error("failed to start TCP entrypoint", "name", name, "error", err)
-			continue
 		}
-
-		tcpEntrypoints = append(tcpEntrypoints, tcpEntry)
-		logging.Info("TCP entrypoint started", "name", name, "address", epCfg.Listen, "target_port", epCfg.TargetPort)
 	}
 
 	// Register TCP entrypoint cleanup
 	shutdown.OnShutdown(func() {
-		for _, ep := range tcpEntrypoints {
-			if err := ep.Stop(context.Background()); err != nil {
-				logging.Error("failed to stop TCP entrypoint", "error", err)
-			}
-		}
+		tcpEntrypoints.stopAll(context.Background())
 	})
 
+	// =========================================================================
+	// Start Metrics Server
+	// =========================================================================
+	if cfg.Metrics.Enabled {
+		metricsServer := metrics.NewServer(cfg.Metrics.Listen, metrics.Default)
+		if err := metricsServer.Start(); err != nil {
+			return fmt.Errorf("failed to start metrics server: %w", err)
+		}
+		shutdown.OnShutdown(func() {
+			if err := metricsServer.Stop(); err != nil {
+				logging.Error("failed to stop metrics server", "error", err)
+			}
+		})
+		logging.Info("metrics server started", "address", "http://"+cfg.Metrics.Listen+metrics.Path)
+	}
+
 	// =========================================================================
 	// Initialize Docker Integration
 	// =========================================================================
+	// Set once Docker is connected, so reloads can update its entrypoints
+	var activeRouteSync atomic.Pointer[docker.RouteSync]
 	if cfg.Docker.Enabled {
-		dockerClient, err := docker.NewClient(logger)
+		dockerClient, err := docker.NewClientWithVersion(cfg.Docker.APIVersion, logger)
 		if err != nil {
 			logging.Error("failed to create Docker client", "error", err)
 		} else {
-			if err := dockerClient.Connect(ctx); err != nil {
-				logging.Error("failed to connect to Docker", "error", err)
-			} else {
+			// startDocker may run while a reload replaces cfg
+			dockerCfg := cfg.Docker
+			connectTimeout, _ := time.ParseDuration(dockerCfg.ConnectTimeout)
+			startDocker := func() {
+				if err := dockerClient.ConnectWithin(ctx, connectTimeout, docker.DefaultConnectBackoff); err != nil {
+					if ctx.Err() == nil {
+						logging.Error("failed to connect to Docker", "error", err)
+					}
+					return
+				}
 				logging.Info("connected to Docker daemon")
 
 				// Create route sync to handle container events
-				routeSync := docker.NewRouteSync(registry, dockerClient, "", logger)
+				routeSync := docker.NewRouteSync(registry, dockerClient, dockerCfg.Network, logger)
 				routeSync.SetCertManager(certManager)
+				routeSync.SetTCPRegistry(tcpRegistry)
+				routeSync.SetNetworkFallback(dockerCfg.NetworkFallback)
+				routeSync.SetAllowedNetworks(dockerCfg.Networks)
+				routeSync.SetSyncConcurrency(dockerCfg.SyncConcurrency)
+				routeSync.SetDefaultPort(dockerCfg.DefaultPort)
+				activeRouteSync.Store(routeSync)
+				// After the Store, so a reload either sees routeSync or has
+				// already stored the config read here
+				routeSync.SetEntrypoints(current.Load().TCPEntrypointNames())
+				queryServer.SetDockerStatus(func() any {
+					return routeSync.Status()
+				})
+				if timeout, err := time.ParseDuration(dockerCfg.ReadyTimeout); err == nil {
+					routeSync.SetReadinessCheck(docker.DialReadinessCheck(timeout, 500*time.Millisecond))
+				}
+				if _, err := routeSync.ValidateNetwork(ctx); err != nil {
+					logging.Warn("failed to validate Docker network", "network", dockerCfg.Network, "error", err)
+				}
 
 				// Create and start watcher
 				watcher := docker.NewWatcher(dockerClient, routeSync.HandleEvent, logger)
@@ -378,12 +552,20 @@ func runDaemon() error {
 					// Register cleanup
 					shutdown.OnShutdown(func() {
 						watcher.Stop()
+						routeSync.Close()
 						if err := dockerClient.Close(); err != nil {
 							logging.Error("failed to close Docker client", "error", err)
 						}
 					})
 				}
 			}
+
+			if connectTimeout > 0 {
+				// Docker may still be starting; wait for it without holding up the daemon
+				go startDocker()
+			} else {
+				startDocker()
+			}
 		}
 	} else {
 		logging.Info("Docker integration disabled")
@@ -402,11 +584,21 @@ func runDaemon() error {
 	// =========================================================================
 	// Start Config File Watcher for Hot Reload
 	// =========================================================================
+	// The config watcher and SIGHUP may reload at the same time
+	var reloadMu sync.Mutex
+	reload := func(newCfg *config.Config) {
+		reloadMu.Lock()
+		defer reloadMu.Unlock()
+
+		applyConfigChanges(current.Load(), newCfg, registry, dnsServer, tcpEntrypoints)
+		current.Store(newCfg)
+		if routeSync := activeRouteSync.Load(); routeSync != nil {
+			routeSync.SetEntrypoints(newCfg.TCPEntrypointNames())
+		}
+	}
+
 	configPath := paths.ConfigFile()
-	configWatcher := config.NewWatcher(configPath, func(newCfg *config.Config) {
-		applyConfigChanges(cfg, newCfg, dnsServer)
-		cfg = newCfg
-	})
+	configWatcher := config.NewWatcher(configPath, reload)
 	if err := configWatcher.Start(); err != nil {
 		logging.Warn("failed to start config watcher", "error", err)
 	} else {
@@ -431,15 +623,34 @@ func runDaemon() error {
 				logging.Error("failed to reload config", "error", err)
 				continue
 			}
-			applyConfigChanges(cfg, newCfg, dnsServer)
-			cfg = newCfg
+			reload(newCfg)
 			logging.Info("configuration reloaded")
 		}
 	}
 }
 
+// handleCertControls answers the certificate cache commands of the control
+// socket with certManager.
+func handleCertControls(controlServer *proxy.QueryServer, certManager *cert.Manager) {
+	controlServer.Handle(proxy.ControlCertCache, func(proxy.QueryRequest) (proxy.QueryResponse, error) {
+		return proxy.QueryResponse{CertCache: certManager.CacheEntries()}, nil
+	})
+	controlServer.Handle(proxy.ControlCertCacheClear, func(proxy.QueryRequest) (proxy.QueryResponse, error) {
+		return proxy.QueryResponse{}, certManager.ClearCache()
+	})
+	controlServer.Handle(proxy.ControlCertRescan, func(proxy.QueryRequest) (proxy.QueryResponse, error) {
+		certManager.Rescan()
+		return proxy.QueryResponse{}, nil
+	})
+	controlServer.Handle(proxy.ControlCAReload, func(proxy.QueryRequest) (proxy.QueryResponse, error) {
+		n, err := certManager.ReloadCA()
+		return proxy.QueryResponse{Reissued: n}, err
+	})
+}
+
 // applyConfigChanges applies configuration changes that can be hot-reloaded.
-func applyConfigChanges(oldCfg, newCfg *config.Config, dnsServer *dns.Server) {
+// A nil dnsServer or tcpEntrypoints skips the DNS or TCP entrypoint changes.
+func applyConfigChanges(oldCfg, newCfg *config.Config, registry *proxy.Registry, dnsServer *dns.Server, tcpEntrypoints *tcpEntrypointSet) {
 	// Update logging level
 	if oldCfg.Logging.Level != newCfg.Logging.Level {
 		newLevel := logging.ParseLevel(newCfg.Logging.Level)
@@ -447,13 +658,48 @@ func applyConfigChanges(oldCfg, newCfg *config.Config, dnsServer *dns.Server) {
 		logging.Info("log level changed", "old", oldCfg.Logging.Level, "new", newCfg.Logging.Level)
 	}
 
-	// Update DNS settings (domains and upstream only - listen address requires restart)
+	if oldCfg.Proxy.RejectShadowedRoutes != newCfg.Proxy.RejectShadowedRoutes {
+		registry.SetRejectShadowing(newCfg.Proxy.RejectShadowedRoutes)
+	}
+
+	if oldCfg.Proxy.MaxRoutes != newCfg.Proxy.MaxRoutes {
+		registry.SetMaxRoutes(newCfg.Proxy.MaxRoutes)
+	}
+
+	// Apply added and removed static routes
+	syncStaticRoutes(registry, oldCfg.Routes, newCfg.Routes)
+
+	// Update DNS settings (domains, upstream, serve_stale, local_only, records, PTR records, aliases and domain IPs only - listen address requires restart)
 	if dnsServer != nil {
 		domainsChanged := !equalStringSlices(oldCfg.DNS.Domains, newCfg.DNS.Domains)
-		upstreamChanged := oldCfg.DNS.Upstream != newCfg.DNS.Upstream
+		upstreamChanged := !equalStringSlices(oldCfg.DNS.Upstreams, newCfg.DNS.Upstreams)
 
 		if domainsChanged || upstreamChanged {
-			dnsServer.UpdateConfig(newCfg.DNS.Domains, newCfg.DNS.Upstream)
+			dnsServer.UpdateConfig(newCfg.DNS.Domains, newCfg.DNS.Upstreams)
+		}
+
+		if oldCfg.DNS.ServeStale != newCfg.DNS.ServeStale {
+			dnsServer.SetServeStale(newCfg.DNS.ServeStale)
+		}
+
+		if oldCfg.DNS.LocalOnly != newCfg.DNS.LocalOnly || dns.ParseRcode(oldCfg.DNS.LocalOnlyRcode) != dns.ParseRcode(newCfg.DNS.LocalOnlyRcode) {
+			dnsServer.SetLocalOnly(newCfg.DNS.LocalOnly, dns.ParseRcode(newCfg.DNS.LocalOnlyRcode))
+		}
+
+		if !equalStringMaps(oldCfg.DNS.Records, newCfg.DNS.Records) {
+			dnsServer.SetRecords(dnsRecords(newCfg.DNS.Records))
+		}
+
+		if !equalStringMaps(oldCfg.DNS.PTRRecords(), newCfg.DNS.PTRRecords()) {
+			dnsServer.SetPTR(newCfg.DNS.PTRRecords())
+		}
+
+		if !equalStringMaps(oldCfg.DNS.Aliases, newCfg.DNS.Aliases) {
+			dnsServer.SetAliases(newCfg.DNS.Aliases)
+		}
+
+		if !equalStringMaps(oldCfg.DNS.DomainIPs, newCfg.DNS.DomainIPs) {
+			dnsServer.SetDomainIPs(dnsRecords(newCfg.DNS.DomainIPs), newCfg.DNS.ContainerDomains())
 		}
 
 		// Warn if listen address changed (requires restart)
@@ -461,15 +707,77 @@ func applyConfigChanges(oldCfg, newCfg *config.Config, dnsServer *dns.Server) {
 			logging.Warn("DNS listen address changed - restart required to apply",
 				"old", oldCfg.DNS.Listen, "new", newCfg.DNS.Listen)
 		}
+
+		if oldCfg.DNS.CacheSize != newCfg.DNS.CacheSize {
+			logging.Warn("DNS cache size changed - restart required to apply",
+				"old", oldCfg.DNS.CacheSize, "new", newCfg.DNS.CacheSize)
+		}
+	}
+
+	if oldCfg.Logging.Format != newCfg.Logging.Format {
+		logging.Warn("log format changed - restart required to apply",
+			"old", oldCfg.Logging.Format, "new", newCfg.Logging.Format)
+	}
+
+	if !equalStringMaps(oldCfg.Cert.Testing.SNIOverrides, newCfg.Cert.Testing.SNIOverrides) {
+		logging.Warn("certificate SNI overrides changed - restart required to apply")
+	}
+
+	if oldCfg.Docker.DefaultPort != newCfg.Docker.DefaultPort {
+		logging.Warn("docker default port changed - restart required to apply",
+			"old", oldCfg.Docker.DefaultPort, "new", newCfg.Docker.DefaultPort)
 	}
 
-	// Check for entrypoint changes
-	for name, oldEp := range oldCfg.Entrypoints {
-		if newEp, exists := newCfg.Entrypoints[name]; exists {
+	if oldCfg.Cert.ValidityDays != newCfg.Cert.ValidityDays || oldCfg.Cert.RenewBeforeDays != newCfg.Cert.RenewBeforeDays {
+		logging.Warn("certificate validity changed - restart required to apply")
+	}
+
+	if oldCfg.Cert.Prewarm != newCfg.Cert.Prewarm {
+		logging.Warn("certificate prewarm changed - restart required to apply",
+			"old", oldCfg.Cert.Prewarm, "new", newCfg.Cert.Prewarm)
+	}
+
+	// Tracing is wired into the HTTPS handler at startup
+	if oldCfg.Tracing != newCfg.Tracing {
+		logging.Warn("tracing configuration changed - restart required to apply")
+	}
+
+	// The backend transport is built at startup
+	if oldCfg.Proxy.MaxIdleConnsPerHost != newCfg.Proxy.MaxIdleConnsPerHost {
+		logging.Warn("proxy idle connections per backend changed - restart required to apply",
+			"old", oldCfg.Proxy.MaxIdleConnsPerHost, "new", newCfg.Proxy.MaxIdleConnsPerHost)
+	}
+
+	if oldCfg.Metrics != newCfg.Metrics {
+		logging.Warn("metrics configuration changed - restart required to apply")
+	}
+
+	// Start, stop and restart TCP entrypoints; HTTPS connections are not touched
+	if tcpEntrypoints != nil {
+		if changes := tcpEntrypoints.apply(newCfg.Entrypoints); !changes.empty() {
+			logging.Info("TCP entrypoints reloaded",
+				"started", changes.started, "stopped", changes.stopped, "restarted", changes.restarted)
+		}
+	}
+
+	// The HTTP and HTTPS servers keep their pre-bound listeners
+	for _, name := range []string{"http", "https"} {
+		if oldEp, exists := oldCfg.Entrypoints[name]; exists {
+			newEp, exists := newCfg.Entrypoints[name]
+			if !exists {
+				continue
+			}
 			if oldEp.Listen != newEp.Listen {
 				logging.Warn("entrypoint listen address changed - restart required to apply",
 					"entrypoint", name, "old", oldEp.Listen, "new", newEp.Listen)
 			}
+			if oldEp.Backlog != newEp.Backlog {
+				logging.Warn("entrypoint backlog changed - restart required to apply",
+					"entrypoint", name, "old", oldEp.Backlog, "new", newEp.Backlog)
+			}
+			if !reflect.DeepEqual(oldEp.Cert, newEp.Cert) {
+				logging.Warn("entrypoint certificate settings changed - restart required to apply", "entrypoint", name)
+			}
 		}
 	}
 }
@@ -40,11 +40,16 @@ Administrator privileges are required to install the CA and configure DNS.`,
 			// Use defaults if config doesn't exist
 			cfg = config.Default()
 		}
+		configureTrust()
 
 		// Step 1: Generate CA if needed
 		fmt.Print("1. Checking CA... ")
 		if ca.Exists() {
 			fmt.Println("exists")
+		} else if ca.IsImported() {
+			fmt.Println("missing")
+			fmt.Fprintf(os.Stderr, "   %v\n", ca.ErrImportedCAMissing)
+			os.Exit(1)
 		} else {
 			fmt.Println("generating")
 			if _, err := ca.Generate(); err != nil {
@@ -56,10 +61,14 @@ Administrator privileges are required to install the CA and configure DNS.`,
 
 		// Step 2: Install CA trust
 		fmt.Print("2. Checking trust store... ")
-		if ca.IsTrusted() {
+		if trust := ca.CheckTrust(); trust == ca.TrustCurrent {
 			fmt.Println("already trusted")
 		} else {
-			fmt.Println("installing")
+			if trust == ca.TrustStale {
+				fmt.Println("stale, reinstalling")
+			} else {
+				fmt.Println("installing")
+			}
 			if err := ca.InstallTrust(); err != nil {
 				fmt.Fprintf(os.Stderr, "   Failed to install CA trust: %v\n", err)
 				os.Exit(1)
@@ -109,13 +118,18 @@ Administrator privileges are required.`,
 			fmt.Fprintf(os.Stderr, "failed to elevate privileges: %v\n", err)
 			os.Exit(1)
 		}
+		configureTrust()
 
 		fmt.Println("Removing devproxy configuration...")
 		fmt.Println()
 
 		// Step 1: Remove CA trust
 		fmt.Print("1. Removing CA from trust store... ")
-		if !ca.IsTrusted() {
+		// A CA replaced by a rotation goes first; it shares the current CA's name
+		if _, err := ca.RetirePrevious(true); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to remove the previous CA: %v\n", err)
+		}
+		if ca.CheckTrust() == ca.TrustNone {
 			fmt.Println("not installed")
 		} else {
 			if err := ca.UninstallTrust(); err != nil {
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"net"
+
+	"github.com/munichmade/devproxy/internal/config"
+	"github.com/munichmade/devproxy/internal/logging"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+// staticRoute converts a configured static route to a registry route.
+func staticRoute(rc config.RouteConfig) proxy.Route {
+	route := proxy.Route{
+		Host:     rc.Host,
+		Backend:  rc.Backend,
+		Protocol: proxy.ProtocolHTTP,
+		Source:   proxy.SourceConfig,
+		Ready:    true,
+	}
+	if rc.Protocol == string(proxy.ProtocolTCP) {
+		route.Protocol = proxy.ProtocolTCP
+		route.Entrypoint = rc.Entrypoint
+		route.ALPNBackends = rc.ALPN
+	}
+	return route
+}
+
+// syncStaticRoutes applies the difference between two sets of configured static
+// routes to the registry. Routes that were removed or changed are pulled, and
+// new or changed routes are added. Routes from other sources, such as Docker
+// containers, are never touched.
+func syncStaticRoutes(registry *proxy.Registry, oldRoutes, newRoutes []config.RouteConfig) {
+	oldByHost := make(map[string]config.RouteConfig, len(oldRoutes))
+	for _, rc := range oldRoutes {
+		oldByHost[rc.Host] = rc
+	}
+	newByHost := make(map[string]config.RouteConfig, len(newRoutes))
+	for _, rc := range newRoutes {
+		newByHost[rc.Host] = rc
+	}
+
+	current := make(map[string]proxy.Route)
+	for _, route := range registry.List() {
+		current[route.Host] = route
+	}
+
+	for host, oldRC := range oldByHost {
+		if newRC, ok := newByHost[host]; ok && equalRouteConfig(newRC, oldRC) {
+			continue
+		}
+		if route, ok := current[host]; !ok || route.Source != proxy.SourceConfig {
+			continue
+		}
+		if err := registry.Remove(host); err != nil {
+			logging.Warn("failed to remove static route", "host", host, "error", err)
+			continue
+		}
+		logging.Info("static route removed", "host", host)
+	}
+
+	for host, newRC := range newByHost {
+		if oldRC, ok := oldByHost[host]; ok && equalRouteConfig(oldRC, newRC) {
+			continue
+		}
+		if err := registry.Add(staticRoute(newRC)); err != nil {
+			if errors.Is(err, proxy.ErrRouteExists) || errors.Is(err, proxy.ErrWildcardRouteExists) {
+				logging.Warn("static route conflicts with an existing route", "host", host)
+				continue
+			}
+			logging.Warn("failed to add static route", "host", host, "error", err)
+			continue
+		}
+		logging.Info("static route added", "host", host, "backend", newRC.Backend)
+	}
+}
+
+// equalRouteConfig reports whether two configured static routes are identical.
+func equalRouteConfig(a, b config.RouteConfig) bool {
+	return a.Host == b.Host &&
+		a.Backend == b.Backend &&
+		a.Protocol == b.Protocol &&
+		a.Entrypoint == b.Entrypoint &&
+		equalStringMaps(a.ALPN, b.ALPN)
+}
+
+// dnsRecords converts configured static DNS records to IPs.
+// Invalid IPs are skipped; they are rejected by config validation.
+func dnsRecords(records map[string]string) map[string]net.IP {
+	parsed := make(map[string]net.IP, len(records))
+	for name, value := range records {
+		if ip := net.ParseIP(value); ip != nil {
+			parsed[name] = ip
+		}
+	}
+	return parsed
+}
+
+// containerIP returns a function reporting the address of the Docker container
+// registry routes host to, or nil for routes that are not to a container.
+func containerIP(registry *proxy.Registry) func(host string) net.IP {
+	return func(host string) net.IP {
+		route := registry.Lookup(host)
+		if route == nil || route.ContainerID == "" {
+			return nil
+		}
+		ip, _, err := net.SplitHostPort(route.Backend)
+		if err != nil {
+			return nil
+		}
+		return net.ParseIP(ip)
+	}
+}
+
+// equalStringMaps reports whether two string maps have the same entries.
+func equalStringMaps(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, ip := range a {
+		if other, ok := b[name]; !ok || other != ip {
+			return false
+		}
+	}
+	return true
+}
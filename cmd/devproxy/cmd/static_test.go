@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"net"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/config"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+func TestApplyConfigChanges_StaticRoutes(t *testing.T) {
+	oldCfg := config.Default()
+	oldCfg.Routes = []config.RouteConfig{
+		{Host: "grafana.localhost", Backend: "127.0.0.1:3000"},
+		{Host: "api.localhost", Backend: "127.0.0.1:8080"},
+		{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: "tcp", Entrypoint: "postgres"},
+	}
+
+	registry := proxy.NewRegistry()
+	syncStaticRoutes(registry, nil, oldCfg.Routes)
+	if registry.Count() != 3 {
+		t.Fatalf("expected 3 routes after startup, got %d", registry.Count())
+	}
+
+	// A Docker route must survive reloads
+	if err := registry.Add(proxy.Route{Host: "web.localhost", Backend: "172.18.0.2:80", ContainerID: "abc123"}); err != nil {
+		t.Fatalf("failed to add docker route: %v", err)
+	}
+
+	newCfg := config.Default()
+	newCfg.Routes = []config.RouteConfig{
+		{Host: "api.localhost", Backend: "127.0.0.1:9090"},
+		{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: "tcp", Entrypoint: "postgres"},
+		{Host: "*.docs.localhost", Backend: "127.0.0.1:4000"},
+	}
+
+	applyConfigChanges(oldCfg, newCfg, registry, nil, nil)
+
+	tests := []struct {
+		host        string
+		wantBackend string
+	}{
+		{host: "grafana.localhost", wantBackend: ""},
+		{host: "api.localhost", wantBackend: "127.0.0.1:9090"},
+		{host: "db.localhost", wantBackend: "127.0.0.1:5432"},
+		{host: "v2.docs.localhost", wantBackend: "127.0.0.1:4000"},
+		{host: "web.localhost", wantBackend: "172.18.0.2:80"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			route := registry.Lookup(tt.host)
+			if tt.wantBackend == "" {
+				if route != nil {
+					t.Errorf("expected route to be removed, got backend %s", route.Backend)
+				}
+				return
+			}
+			if route == nil {
+				t.Fatalf("expected route for %s", tt.host)
+			}
+			if route.Backend != tt.wantBackend {
+				t.Errorf("expected backend %s, got %s", tt.wantBackend, route.Backend)
+			}
+		})
+	}
+
+	if route := registry.Lookup("db.localhost"); route == nil || route.Protocol != proxy.ProtocolTCP || route.Entrypoint != "postgres" {
+		t.Errorf("expected tcp route on postgres entrypoint, got %+v", route)
+	}
+}
+
+func TestSyncStaticRoutes_SkipsDockerRoutes(t *testing.T) {
+	registry := proxy.NewRegistry()
+	if err := registry.Add(proxy.Route{Host: "app.localhost", Backend: "172.18.0.2:80", ContainerID: "abc123"}); err != nil {
+		t.Fatalf("failed to add docker route: %v", err)
+	}
+
+	// A static route for a host Docker already owns conflicts and is skipped
+	routes := []config.RouteConfig{{Host: "app.localhost", Backend: "127.0.0.1:3000"}}
+	syncStaticRoutes(registry, nil, routes)
+
+	// Removing it from config must not pull the Docker route
+	syncStaticRoutes(registry, routes, nil)
+
+	route := registry.Lookup("app.localhost")
+	if route == nil || route.ContainerID != "abc123" {
+		t.Errorf("expected docker route to be kept, got %+v", route)
+	}
+}
+
+func TestSyncStaticRoutes_SkipsManualRoutes(t *testing.T) {
+	registry := proxy.NewRegistry()
+	if err := registry.Add(proxy.Route{Host: "api.localhost", Backend: "127.0.0.1:8080", Source: proxy.SourceManual}); err != nil {
+		t.Fatalf("failed to add manual route: %v", err)
+	}
+
+	routes := []config.RouteConfig{{Host: "grafana.localhost", Backend: "127.0.0.1:3000"}}
+	syncStaticRoutes(registry, nil, routes)
+	if route := registry.Lookup("grafana.localhost"); route == nil || route.Source != proxy.SourceConfig {
+		t.Errorf("expected config route, got %+v", route)
+	}
+
+	// Removing the static routes must only pull the ones from config
+	syncStaticRoutes(registry, routes, nil)
+	if route := registry.Lookup("grafana.localhost"); route != nil {
+		t.Errorf("expected config route to be removed, got %+v", route)
+	}
+	if route := registry.Lookup("api.localhost"); route == nil || route.Source != proxy.SourceManual {
+		t.Errorf("expected manual route to be kept, got %+v", route)
+	}
+}
+
+func TestSyncStaticRoutes_ALPNChange(t *testing.T) {
+	oldRoutes := []config.RouteConfig{
+		{Host: "api.localhost", Backend: "127.0.0.1:8080", Protocol: "tcp", Entrypoint: "postgres"},
+	}
+	newRoutes := []config.RouteConfig{
+		{Host: "api.localhost", Backend: "127.0.0.1:8080", Protocol: "tcp", Entrypoint: "postgres",
+			ALPN: map[string]string{"h2": "127.0.0.1:50051"}},
+	}
+
+	registry := proxy.NewRegistry()
+	syncStaticRoutes(registry, nil, oldRoutes)
+	syncStaticRoutes(registry, oldRoutes, newRoutes)
+
+	route := registry.Lookup("api.localhost")
+	if route == nil {
+		t.Fatal("expected route to exist")
+	}
+	if route.ALPNBackends["h2"] != "127.0.0.1:50051" {
+		t.Errorf("expected updated ALPN backends, got %v", route.ALPNBackends)
+	}
+}
+
+func TestDNSRecords(t *testing.T) {
+	records := dnsRecords(map[string]string{
+		"nas.home.arpa": "192.168.1.10",
+		"v6.localhost":  "fd00::1",
+		"bad.localhost": "not-an-ip",
+	})
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if !records["nas.home.arpa"].Equal(net.ParseIP("192.168.1.10")) {
+		t.Errorf("unexpected IP for nas.home.arpa: %v", records["nas.home.arpa"])
+	}
+}
+
+func TestContainerIP(t *testing.T) {
+	registry := proxy.NewRegistry()
+	registry.Add(proxy.Route{Host: "web.docker.localhost", Backend: "172.18.0.2:80", ContainerID: "abc123"})
+	registry.Add(proxy.Route{Host: "grafana.docker.localhost", Backend: "127.0.0.1:3000"})
+	lookup := containerIP(registry)
+
+	tests := []struct {
+		host string
+		want net.IP
+	}{
+		{host: "web.docker.localhost", want: net.ParseIP("172.18.0.2")},
+		{host: "grafana.docker.localhost", want: nil},
+		{host: "missing.docker.localhost", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := lookup(tt.host); !got.Equal(tt.want) {
+				t.Errorf("containerIP(%s) = %v, want %v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -44,6 +45,9 @@ type Status struct {
 	Uptime      string                    `json:"uptime,omitempty"`
 	Entrypoints []Entrypoint              `json:"entrypoints"`
 	Projects    map[string]*ProjectRoutes `json:"projects"`
+
+	// Hints suggest config changes, e.g. keeping more idle connections.
+	Hints []string `json:"hints,omitempty"`
 }
 
 // Entrypoint represents a listening endpoint.
@@ -62,11 +66,38 @@ type ProjectRoutes struct {
 
 // RouteStatus represents a proxied route.
 type RouteStatus struct {
-	Host          string `json:"host"`
-	Backend       string `json:"backend"`
-	ContainerName string `json:"container_name,omitempty"`
-	ContainerID   string `json:"container_id,omitempty"`
-	Protocol      string `json:"protocol"`
+	Host          string   `json:"host"`
+	Path          string   `json:"path,omitempty"`
+	Backend       string   `json:"backend"`
+	Backends      []string `json:"backends,omitempty"`
+	ContainerName string   `json:"container_name,omitempty"`
+	ContainerID   string   `json:"container_id,omitempty"`
+	Protocol      string   `json:"protocol"`
+	Ready         bool     `json:"ready"`
+	Disabled      bool     `json:"disabled,omitempty"`
+
+	// Health holds the results of the backend's active health checks, if any.
+	Health *proxy.HealthStats `json:"health,omitempty"`
+
+	// BackendHealth holds the health check results of each of Backends that
+	// is checked, for routes load-balanced across several backends.
+	BackendHealth map[string]proxy.HealthStats `json:"backend_health,omitempty"`
+
+	// Conns counts the connections to the route's backends, if it proxied
+	// any HTTP requests.
+	Conns *proxy.ConnStats `json:"connections,omitempty"`
+}
+
+// State returns "disabled" for routes switched off, otherwise "ready" once
+// the route can serve traffic and "starting" before.
+func (r RouteStatus) State() string {
+	if r.Disabled {
+		return "disabled"
+	}
+	if r.Ready {
+		return "ready"
+	}
+	return "starting"
 }
 
 func getStatus() Status {
@@ -121,35 +152,139 @@ func getStatus() Status {
 	if status.Running {
 		routes, err := proxy.LoadState()
 		if err == nil && len(routes) > 0 {
-			for _, route := range routes {
-				projectName := route.ProjectName
-				if projectName == "" {
-					projectName = "ungrouped"
-				}
+			// Health checks change too often for the state file, ask the daemon
+			var checks map[string]proxy.HealthStats
+			if resp, err := proxy.Query(proxy.QueryRequest{Cmd: proxy.QueryHealthChecks}); err == nil {
+				checks = resp.HealthChecks
+			}
+			var conns map[string]proxy.ConnStats
+			if resp, err := proxy.Query(proxy.QueryRequest{Cmd: proxy.QueryConnStats}); err == nil {
+				conns = resp.ConnStats
+			}
 
-				project, exists := status.Projects[projectName]
-				if !exists {
-					project = &ProjectRoutes{
-						ProjectDir: shortenPath(route.ProjectDir),
-						Routes:     []RouteStatus{},
+			status.Projects = groupRoutes(routes, checks, conns)
+			for _, project := range status.Projects {
+				for _, route := range project.Routes {
+					if hint := idleConnsHint(route.Host, route.Conns, cfg.Proxy.MaxIdleConnsPerHost); hint != "" {
+						status.Hints = append(status.Hints, hint)
 					}
-					status.Projects[projectName] = project
 				}
-
-				project.Routes = append(project.Routes, RouteStatus{
-					Host:          route.Host,
-					Backend:       route.Backend,
-					ContainerName: route.ContainerName,
-					ContainerID:   route.ContainerID,
-					Protocol:      string(route.Protocol),
-				})
 			}
 		}
 	}
 
+	sort.Strings(status.Hints)
 	return status
 }
 
+// ungroupedProject groups the routes not started by Docker Compose.
+const ungroupedProject = "ungrouped"
+
+// groupRoutes groups routes by the Docker Compose project of their container,
+// taken from its com.docker.compose.project labels, with the results of
+// their health checks and their connection counts.
+func groupRoutes(routes []proxy.Route, checks map[string]proxy.HealthStats, conns map[string]proxy.ConnStats) map[string]*ProjectRoutes {
+	projects := make(map[string]*ProjectRoutes)
+	for _, route := range routes {
+		projectName := route.ProjectName
+		if projectName == "" {
+			projectName = ungroupedProject
+		}
+
+		project, exists := projects[projectName]
+		if !exists {
+			project = &ProjectRoutes{
+				ProjectDir: shortenPath(route.ProjectDir),
+				Routes:     []RouteStatus{},
+			}
+			projects[projectName] = project
+		}
+
+		var health *proxy.HealthStats
+		if stats, ok := checks[route.Backend]; ok {
+			health = &stats
+		}
+		var backendHealth map[string]proxy.HealthStats
+		if len(route.Backends) > 1 {
+			for _, backend := range route.Backends {
+				if stats, ok := checks[backend]; ok {
+					if backendHealth == nil {
+						backendHealth = make(map[string]proxy.HealthStats)
+					}
+					backendHealth[backend] = stats
+				}
+			}
+		}
+
+		project.Routes = append(project.Routes, RouteStatus{
+			Host:          route.Host,
+			Path:          route.PathPrefix,
+			Backend:       route.Backend,
+			Backends:      route.Backends,
+			ContainerName: route.ContainerName,
+			ContainerID:   route.ContainerID,
+			Protocol:      string(route.Protocol),
+			Ready:         route.Ready,
+			Disabled:      route.Disabled,
+			Health:        health,
+			BackendHealth: backendHealth,
+			Conns:         routeConnStats(conns, route),
+		})
+	}
+	return projects
+}
+
+// idleConnsHintMinDialed is the number of dialed connections from which status
+// suggests keeping more idle connections, so a few requests after startup,
+// which always dial, do not trigger it.
+const idleConnsHintMinDialed = 50
+
+// routeConnStats sums the connection counts of the route's backends, or
+// returns nil if none of them was connected to.
+func routeConnStats(conns map[string]proxy.ConnStats, route proxy.Route) *proxy.ConnStats {
+	backends := route.Backends
+	if len(backends) == 0 {
+		backends = []string{route.Backend}
+	}
+
+	var total proxy.ConnStats
+	found := false
+	for _, backend := range backends {
+		if stats, ok := conns[backend]; ok {
+			total.Reused += stats.Reused
+			total.Dialed += stats.Dialed
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return &total
+}
+
+// idleConnsHint returns a hint to raise proxy.max_idle_conns_per_host if most
+// requests to host needed a new connection, or "" otherwise.
+func idleConnsHint(host string, stats *proxy.ConnStats, maxIdle int) string {
+	if stats == nil || stats.Dialed < idleConnsHintMinDialed || stats.Dialed <= stats.Reused {
+		return ""
+	}
+	if maxIdle <= 0 {
+		maxIdle = proxy.DefaultMaxIdleConnsPerHost
+	}
+	percent := stats.Dialed * 100 / (stats.Dialed + stats.Reused)
+	return fmt.Sprintf("%d%% of the backend connections of %s were newly dialed; raise proxy.max_idle_conns_per_host (now %d) if it gets concurrent requests",
+		percent, host, maxIdle)
+}
+
+// formatConns describes connection counts, e.g. "120 reused, 3 dialed", or
+// returns "-" for routes without proxied requests.
+func formatConns(stats *proxy.ConnStats) string {
+	if stats == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%d reused, %d dialed", stats.Reused, stats.Dialed)
+}
+
 func getListenerStatus(running bool) string {
 	if running {
 		return "listening"
@@ -206,10 +341,10 @@ func outputStatusText(status Status) {
 		}
 		sort.Slice(projectNames, func(i, j int) bool {
 			// "ungrouped" always goes last
-			if projectNames[i] == "ungrouped" {
+			if projectNames[i] == ungroupedProject {
 				return false
 			}
-			if projectNames[j] == "ungrouped" {
+			if projectNames[j] == ungroupedProject {
 				return true
 			}
 			return projectNames[i] < projectNames[j]
@@ -227,17 +362,71 @@ func outputStatusText(status Status) {
 
 			// Routes table
 			w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-			fmt.Fprintf(w, "    HOST\tBACKEND\tCONTAINER\n")
+			fmt.Fprintf(w, "    HOST\tBACKEND\tCONTAINER\tSTATUS\tHEALTH\tCONNECTIONS\n")
 			for _, route := range project.Routes {
 				container := route.ContainerName
 				if container == "" {
 					container = "-"
 				}
-				fmt.Fprintf(w, "    %s\t%s\t%s\n", route.Host, route.Backend, container)
+				backend := route.Backend
+				if len(route.Backends) > 1 {
+					backend = fmt.Sprintf("%s (+%d)", backend, len(route.Backends)-1)
+				}
+				if len(route.BackendHealth) == 0 {
+					fmt.Fprintf(w, "    %s\t%s\t%s\t%s\t%s\t%s\n", route.Host+route.Path, backend, container, route.State(), formatHealth(route.Health), formatConns(route.Conns))
+					continue
+				}
+
+				// One row per backend, so each backend's health is shown
+				fmt.Fprintf(w, "    %s\t%s\t%s\t%s\t%s\t%s\n", route.Host+route.Path, backend, container, route.State(), formatBackendsHealth(route.BackendHealth, len(route.Backends)), formatConns(route.Conns))
+				for _, b := range route.Backends {
+					var stats *proxy.HealthStats
+					if s, ok := route.BackendHealth[b]; ok {
+						stats = &s
+					}
+					fmt.Fprintf(w, "      \t%s\t\t\t%s\t\n", b, formatHealth(stats))
+				}
 			}
 			w.Flush()
 		}
 	}
+
+	if len(status.Hints) > 0 {
+		fmt.Println()
+		for _, hint := range status.Hints {
+			fmt.Printf("Hint: %s\n", hint)
+		}
+	}
+}
+
+// formatHealth describes health check results, e.g. "healthy, 3ms, 120
+// consecutive OK", or returns "-" for routes without a health check.
+func formatHealth(stats *proxy.HealthStats) string {
+	if stats == nil {
+		return "-"
+	}
+
+	// Checks of local backends often take less than a millisecond
+	latency := stats.Latency.Round(time.Millisecond)
+	if latency == 0 {
+		latency = stats.Latency.Round(time.Microsecond)
+	}
+	if stats.Healthy {
+		return fmt.Sprintf("healthy, %s, %d consecutive OK", latency, stats.ConsecutiveSuccesses)
+	}
+	return fmt.Sprintf("failing, %s, %d consecutive failures", latency, stats.ConsecutiveFailures)
+}
+
+// formatBackendsHealth summarizes the health checks of a route's backends,
+// e.g. "2/3 healthy".
+func formatBackendsHealth(checks map[string]proxy.HealthStats, backends int) string {
+	healthy := 0
+	for _, stats := range checks {
+		if stats.Healthy {
+			healthy++
+		}
+	}
+	return fmt.Sprintf("%d/%d healthy", healthy, backends)
 }
 
 func outputStatusJSON(status Status) {
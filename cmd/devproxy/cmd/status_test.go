@@ -2,7 +2,11 @@ package cmd
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/proxy"
 )
 
 func TestShortenPath(t *testing.T) {
@@ -217,3 +221,174 @@ func TestStatusStruct(t *testing.T) {
 		}
 	})
 }
+
+func TestRouteStatus_State(t *testing.T) {
+	t.Run("starting before ready", func(t *testing.T) {
+		route := RouteStatus{Host: "app.localhost"}
+		if route.State() != "starting" {
+			t.Errorf("expected 'starting', got %q", route.State())
+		}
+	})
+
+	t.Run("ready after readiness", func(t *testing.T) {
+		route := RouteStatus{Host: "app.localhost", Ready: true}
+		if route.State() != "ready" {
+			t.Errorf("expected 'ready', got %q", route.State())
+		}
+	})
+
+	t.Run("disabled overrides readiness", func(t *testing.T) {
+		route := RouteStatus{Host: "app.localhost", Ready: true, Disabled: true}
+		if route.State() != "disabled" {
+			t.Errorf("expected 'disabled', got %q", route.State())
+		}
+	})
+}
+
+func TestFormatHealth(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats *proxy.HealthStats
+		want  string
+	}{
+		{"no health check", nil, "-"},
+		{"healthy", &proxy.HealthStats{Healthy: true, Latency: 3200 * time.Microsecond, ConsecutiveSuccesses: 120}, "healthy, 3ms, 120 consecutive OK"},
+		{"sub-millisecond", &proxy.HealthStats{Healthy: true, Latency: 412345 * time.Nanosecond, ConsecutiveSuccesses: 1}, "healthy, 412µs, 1 consecutive OK"},
+		{"failing", &proxy.HealthStats{Latency: 5 * time.Second, ConsecutiveFailures: 4}, "failing, 5s, 4 consecutive failures"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatHealth(tt.stats); got != tt.want {
+				t.Errorf("formatHealth() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteConnStats(t *testing.T) {
+	conns := map[string]proxy.ConnStats{
+		"172.18.0.2:80": {Reused: 10, Dialed: 2},
+		"172.18.0.3:80": {Reused: 5, Dialed: 1},
+	}
+
+	tests := []struct {
+		name  string
+		route proxy.Route
+		want  *proxy.ConnStats
+	}{
+		{"single backend", proxy.Route{Backend: "172.18.0.2:80"}, &proxy.ConnStats{Reused: 10, Dialed: 2}},
+		{"load balanced", proxy.Route{Backend: "172.18.0.2:80", Backends: []string{"172.18.0.2:80", "172.18.0.3:80"}}, &proxy.ConnStats{Reused: 15, Dialed: 3}},
+		{"no requests", proxy.Route{Backend: "172.18.0.9:80"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := routeConnStats(conns, tt.route)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("routeConnStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIdleConnsHint(t *testing.T) {
+	tests := []struct {
+		name    string
+		stats   *proxy.ConnStats
+		maxIdle int
+		want    string
+	}{
+		{"no requests", nil, 0, ""},
+		{"mostly reused", &proxy.ConnStats{Reused: 900, Dialed: 100}, 0, ""},
+		{"few requests", &proxy.ConnStats{Reused: 1, Dialed: 10}, 0, ""},
+		{"mostly dialed", &proxy.ConnStats{Reused: 25, Dialed: 75}, 0, "75% of the backend connections of api.localhost were newly dialed; raise proxy.max_idle_conns_per_host (now 2) if it gets concurrent requests"},
+		{"configured limit", &proxy.ConnStats{Reused: 0, Dialed: 60}, 8, "100% of the backend connections of api.localhost were newly dialed; raise proxy.max_idle_conns_per_host (now 8) if it gets concurrent requests"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idleConnsHint("api.localhost", tt.stats, tt.maxIdle); got != tt.want {
+				t.Errorf("idleConnsHint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupRoutes(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skipf("could not get home dir: %v", err)
+	}
+
+	routes := []proxy.Route{
+		{Host: "web.localhost", Backend: "172.18.0.2:80", ContainerID: "abc123", ProjectName: "shop", ProjectDir: home + "/src/shop"},
+		{Host: "api.localhost", Backend: "172.18.0.3:80", ContainerID: "def456", ProjectName: "shop", ProjectDir: home + "/src/shop"},
+		{Host: "blog.localhost", Backend: "172.18.0.4:80", ContainerID: "ghi789", ProjectName: "blog", ProjectDir: "/srv/blog"},
+		{Host: "scaled.localhost", Backend: "172.18.0.6:80", Backends: []string{"172.18.0.6:80", "172.18.0.7:80", "172.18.0.8:80"}, ProjectName: "blog", ProjectDir: "/srv/blog"},
+		{Host: "solo.localhost", Backend: "172.18.0.5:80", ContainerID: "jkl012"},
+		{Host: "grafana.localhost", Backend: "127.0.0.1:3000", Source: proxy.SourceConfig},
+	}
+	checks := map[string]proxy.HealthStats{
+		"172.18.0.2:80": {Healthy: true},
+		"172.18.0.6:80": {Healthy: true},
+		"172.18.0.7:80": {Healthy: false, ConsecutiveFailures: 3},
+	}
+	conns := map[string]proxy.ConnStats{"172.18.0.3:80": {Reused: 4, Dialed: 1}}
+
+	projects := groupRoutes(routes, checks, conns)
+
+	tests := []struct {
+		project   string
+		wantDir   string
+		wantHosts []string
+	}{
+		{project: "shop", wantDir: "~/src/shop", wantHosts: []string{"web.localhost", "api.localhost"}},
+		{project: "blog", wantDir: "/srv/blog", wantHosts: []string{"blog.localhost", "scaled.localhost"}},
+		{project: ungroupedProject, wantDir: "", wantHosts: []string{"solo.localhost", "grafana.localhost"}},
+	}
+	if len(projects) != len(tests) {
+		t.Fatalf("expected %d projects, got %d", len(tests), len(projects))
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.project, func(t *testing.T) {
+			project, ok := projects[tt.project]
+			if !ok {
+				t.Fatalf("expected project %s", tt.project)
+			}
+			if project.ProjectDir != tt.wantDir {
+				t.Errorf("expected project dir %q, got %q", tt.wantDir, project.ProjectDir)
+			}
+			var hosts []string
+			for _, route := range project.Routes {
+				hosts = append(hosts, route.Host)
+			}
+			if strings.Join(hosts, ",") != strings.Join(tt.wantHosts, ",") {
+				t.Errorf("expected hosts %v, got %v", tt.wantHosts, hosts)
+			}
+		})
+	}
+
+	shop := projects["shop"].Routes
+	if shop[0].Health == nil || !shop[0].Health.Healthy {
+		t.Errorf("expected health of web.localhost, got %+v", shop[0].Health)
+	}
+	if shop[1].Conns == nil || shop[1].Conns.Reused != 4 {
+		t.Errorf("expected connections of api.localhost, got %+v", shop[1].Conns)
+	}
+	if shop[0].BackendHealth != nil {
+		t.Errorf("expected no per-backend health for a single backend, got %+v", shop[0].BackendHealth)
+	}
+
+	scaled := projects["blog"].Routes[1]
+	if len(scaled.BackendHealth) != 2 {
+		t.Fatalf("expected health of the two checked backends, got %+v", scaled.BackendHealth)
+	}
+	if !scaled.BackendHealth["172.18.0.6:80"].Healthy || scaled.BackendHealth["172.18.0.7:80"].Healthy {
+		t.Errorf("expected each backend's own health, got %+v", scaled.BackendHealth)
+	}
+	if got := formatBackendsHealth(scaled.BackendHealth, len(scaled.Backends)); got != "1/3 healthy" {
+		t.Errorf("formatBackendsHealth() = %q, want %q", got, "1/3 healthy")
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/munichmade/devproxy/internal/daemon"
+	"github.com/munichmade/devproxy/internal/paths"
+	"github.com/munichmade/devproxy/internal/version"
+)
+
+var (
+	versionJSONOutput bool
+	versionVerbose    bool
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Show version, build and runtime information",
+	Long: `Show the devproxy version, git commit, build date, Go version and platform,
+and whether the daemon is currently running.
+
+Use --verbose to include configuration and data directories, which is
+helpful when filing bug reports.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		report := getVersionReport(versionVerbose)
+
+		if versionJSONOutput {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			_ = enc.Encode(report)
+			return
+		}
+		writeVersionText(os.Stdout, report)
+	},
+}
+
+// VersionReport combines build metadata with daemon and path information.
+type VersionReport struct {
+	version.Info
+	DaemonRunning bool          `json:"daemon_running"`
+	DaemonPID     int           `json:"daemon_pid,omitempty"`
+	Paths         *VersionPaths `json:"paths,omitempty"`
+}
+
+// VersionPaths lists the directories used by devproxy.
+type VersionPaths struct {
+	ConfigDir  string `json:"config_dir"`
+	ConfigFile string `json:"config_file"`
+	DataDir    string `json:"data_dir"`
+	CertsDir   string `json:"certs_dir"`
+	LogFile    string `json:"log_file"`
+	PIDFile    string `json:"pid_file"`
+}
+
+func getVersionReport(verbose bool) VersionReport {
+	report := VersionReport{Info: version.Get()}
+
+	d := daemon.New()
+	if d.IsRunning() {
+		report.DaemonRunning = true
+		pid, _ := d.GetPID()
+		report.DaemonPID = pid
+	}
+
+	if verbose {
+		report.Paths = &VersionPaths{
+			ConfigDir:  paths.ConfigDir(),
+			ConfigFile: paths.ConfigFile(),
+			DataDir:    paths.DataDir(),
+			CertsDir:   paths.CertsDir(),
+			LogFile:    paths.LogFile(),
+			PIDFile:    paths.PIDFile(),
+		}
+	}
+
+	return report
+}
+
+func writeVersionText(w io.Writer, report VersionReport) {
+	fmt.Fprintf(w, "devproxy version %s\n", report.Version)
+	fmt.Fprintf(w, "  Commit:     %s\n", report.Commit)
+	fmt.Fprintf(w, "  Built:      %s\n", report.BuildDate)
+	fmt.Fprintf(w, "  Go version: %s\n", report.GoVersion)
+	fmt.Fprintf(w, "  Platform:   %s\n", report.Platform())
+
+	if report.DaemonRunning {
+		fmt.Fprintf(w, "  Daemon:     running (PID %d)\n", report.DaemonPID)
+	} else {
+		fmt.Fprintf(w, "  Daemon:     not running\n")
+	}
+
+	if report.Paths != nil {
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "  Config dir:  %s\n", report.Paths.ConfigDir)
+		fmt.Fprintf(w, "  Config file: %s\n", report.Paths.ConfigFile)
+		fmt.Fprintf(w, "  Data dir:    %s\n", report.Paths.DataDir)
+		fmt.Fprintf(w, "  Certs dir:   %s\n", report.Paths.CertsDir)
+		fmt.Fprintf(w, "  Log file:    %s\n", report.Paths.LogFile)
+		fmt.Fprintf(w, "  PID file:    %s\n", report.Paths.PIDFile)
+	}
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&versionJSONOutput, "json", false, "Output as JSON")
+	versionCmd.Flags().BoolVarP(&versionVerbose, "verbose", "v", false, "Include configuration and data paths")
+	rootCmd.AddCommand(versionCmd)
+}
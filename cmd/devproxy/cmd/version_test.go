@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/version"
+)
+
+func TestWriteVersionText(t *testing.T) {
+	info := version.Info{
+		Version:   "1.2.3",
+		Commit:    "abc1234",
+		BuildDate: "2024-01-02T03:04:05Z",
+		GoVersion: "go1.25.5",
+		OS:        "linux",
+		Arch:      "amd64",
+	}
+
+	t.Run("shows build info and running daemon", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeVersionText(&buf, VersionReport{Info: info, DaemonRunning: true, DaemonPID: 4242})
+
+		out := buf.String()
+		for _, want := range []string{"devproxy version 1.2.3", "abc1234", "2024-01-02T03:04:05Z", "go1.25.5", "linux/amd64", "running (PID 4242)"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected output to contain %q, got:\n%s", want, out)
+			}
+		}
+		if strings.Contains(out, "Config dir") {
+			t.Error("expected paths to be omitted without verbose")
+		}
+	})
+
+	t.Run("shows stopped daemon and paths", func(t *testing.T) {
+		var buf bytes.Buffer
+		writeVersionText(&buf, VersionReport{
+			Info:  info,
+			Paths: &VersionPaths{ConfigDir: "/etc/devproxy", DataDir: "/var/lib/devproxy"},
+		})
+
+		out := buf.String()
+		for _, want := range []string{"not running", "Config dir:  /etc/devproxy", "Data dir:    /var/lib/devproxy"} {
+			if !strings.Contains(out, want) {
+				t.Errorf("expected output to contain %q, got:\n%s", want, out)
+			}
+		}
+	})
+}
@@ -0,0 +1,259 @@
+// Package archive exports and imports devproxy state (CA, certificates,
+// config and route state) as a tar archive.
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/paths"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+// Names of the entries in an archive. Directories hold every file below them.
+const (
+	configEntry = "config.yaml"
+	routesEntry = "routes.json"
+	caDir       = "ca"
+	certsDir    = "certs"
+)
+
+// keySuffix marks private keys, which are always restored with mode 0600.
+const keySuffix = "-key.pem"
+
+// maxFileSize bounds a single archive entry to guard against bogus archives.
+const maxFileSize = 10 << 20
+
+// ErrInvalidArchive is returned when an archive contains unexpected entries.
+var ErrInvalidArchive = errors.New("invalid devproxy archive")
+
+// File is a single file in an archive.
+type File struct {
+	Name string // Archive path with forward slashes, e.g. "ca/root-ca.pem"
+	Mode fs.FileMode
+	Data []byte
+}
+
+// Archive is the contents of an exported devproxy state archive.
+type Archive struct {
+	Files []File
+}
+
+// Write bundles the CA, certificates, config file and route state into a tar
+// archive written to w. Missing files are skipped.
+// Returns the names of the files written.
+func Write(w io.Writer) ([]string, error) {
+	files, err := collect()
+	if err != nil {
+		return nil, err
+	}
+
+	tw := tar.NewWriter(w)
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    f.Name,
+			Mode:    int64(f.Mode.Perm()),
+			Size:    int64(len(f.Data)),
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.Name, err)
+		}
+		if _, err := tw.Write(f.Data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.Name, err)
+		}
+		names = append(names, f.Name)
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// collect reads all exported files from their locations on disk.
+func collect() ([]File, error) {
+	var files []File
+
+	for name, src := range map[string]string{
+		configEntry: paths.ConfigFile(),
+		routesEntry: proxy.StateFile(),
+	} {
+		f, err := readFile(name, src)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	for name, dir := range map[string]string{
+		caDir:    paths.CADir(),
+		certsDir: paths.CertsDir(),
+	} {
+		entries, err := os.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() {
+				continue
+			}
+			f, err := readFile(path.Join(name, entry.Name()), filepath.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Name < files[j].Name
+	})
+	return files, nil
+}
+
+// readFile reads src into a File named name.
+func readFile(name, src string) (File, error) {
+	info, err := os.Stat(src)
+	if err != nil {
+		return File{}, err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return File{}, err
+	}
+	return File{Name: name, Mode: info.Mode().Perm(), Data: data}, nil
+}
+
+// Read parses a tar archive produced by Write.
+// Entries outside the known locations are rejected.
+func Read(r io.Reader) (*Archive, error) {
+	a := &Archive{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("%w: unexpected entry type for %s", ErrInvalidArchive, hdr.Name)
+		}
+		if _, err := destination(hdr.Name); err != nil {
+			return nil, err
+		}
+		if hdr.Size > maxFileSize {
+			return nil, fmt.Errorf("%w: %s is too large", ErrInvalidArchive, hdr.Name)
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+		}
+		a.Files = append(a.Files, File{Name: hdr.Name, Mode: fs.FileMode(hdr.Mode).Perm(), Data: data})
+	}
+	return a, nil
+}
+
+// destination maps an archive entry to its location on disk.
+func destination(name string) (string, error) {
+	switch name {
+	case configEntry:
+		return paths.ConfigFile(), nil
+	case routesEntry:
+		return proxy.StateFile(), nil
+	}
+
+	dir, file := path.Split(name)
+	if file == "" || file == "." || file == ".." || strings.ContainsRune(file, '\\') {
+		return "", fmt.Errorf("%w: unexpected entry %s", ErrInvalidArchive, name)
+	}
+	switch dir {
+	case caDir + "/":
+		return filepath.Join(paths.CADir(), file), nil
+	case certsDir + "/":
+		return filepath.Join(paths.CertsDir(), file), nil
+	default:
+		return "", fmt.Errorf("%w: unexpected entry %s", ErrInvalidArchive, name)
+	}
+}
+
+// HasCAKey reports whether the archive contains a CA private key.
+func (a *Archive) HasCAKey() bool {
+	return a.file(path.Join(caDir, ca.CAKeyFilename)) != nil
+}
+
+// ReplacesCA reports whether restoring the archive would overwrite an
+// existing CA certificate with a different one.
+func (a *Archive) ReplacesCA() bool {
+	f := a.file(path.Join(caDir, ca.CACertFilename))
+	if f == nil {
+		return false
+	}
+	existing, err := os.ReadFile(ca.CertPath())
+	if err != nil {
+		return false
+	}
+	return !bytes.Equal(existing, f.Data)
+}
+
+// file returns the archive entry with the given name, or nil.
+func (a *Archive) file(name string) *File {
+	for i := range a.Files {
+		if a.Files[i].Name == name {
+			return &a.Files[i]
+		}
+	}
+	return nil
+}
+
+// Restore writes all files in the archive to their locations, replacing
+// existing files. Private keys are always written with mode 0600.
+// Returns the paths written.
+func (a *Archive) Restore() ([]string, error) {
+	written := make([]string, 0, len(a.Files))
+	for _, f := range a.Files {
+		dst, err := destination(f.Name)
+		if err != nil {
+			return written, err
+		}
+
+		mode := f.Mode.Perm()
+		if strings.HasSuffix(f.Name, keySuffix) || mode == 0 {
+			mode = 0o600
+		}
+
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(dst, f.Data, mode); err != nil {
+			return written, fmt.Errorf("failed to restore %s: %w", f.Name, err)
+		}
+		// WriteFile keeps the mode of existing files; enforce the archived one
+		if err := os.Chmod(dst, mode); err != nil {
+			return written, err
+		}
+		written = append(written, dst)
+	}
+	return written, nil
+}
@@ -0,0 +1,211 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/paths"
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+// useTempDirs points config and data directories at fresh temp directories.
+func useTempDirs(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	paths.Reset()
+	t.Cleanup(paths.Reset)
+}
+
+func writeTestFile(t *testing.T, path string, data string, mode os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(data), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWriteRestore_RoundTrip(t *testing.T) {
+	useTempDirs(t)
+
+	if _, err := ca.Generate(); err != nil {
+		t.Fatalf("ca.Generate() error = %v", err)
+	}
+	writeTestFile(t, paths.ConfigFile(), "logging:\n  level: debug\n", 0o644)
+	writeTestFile(t, proxy.StateFile(), `{"routes":[]}`, 0o644)
+	writeTestFile(t, filepath.Join(paths.CertsDir(), "_wildcard.app.localhost.pem"), "cert", 0o644)
+	writeTestFile(t, filepath.Join(paths.CertsDir(), "_wildcard.app.localhost-key.pem"), "key", 0o600)
+	// Runtime files are not exported
+	writeTestFile(t, paths.LogFile(), "log line", 0o644)
+
+	sourceCA, err := os.ReadFile(ca.CertPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	names, err := Write(&buf)
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	want := []string{
+		"ca/root-ca-key.pem",
+		"ca/root-ca.pem",
+		"certs/_wildcard.app.localhost-key.pem",
+		"certs/_wildcard.app.localhost.pem",
+		"config.yaml",
+		"routes.json",
+	}
+	if len(names) != len(want) {
+		t.Fatalf("Write() = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("entry %d = %s, want %s", i, names[i], want[i])
+		}
+	}
+
+	// Restore into a different machine's directories
+	useTempDirs(t)
+
+	a, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if !a.HasCAKey() {
+		t.Error("expected archive to contain CA key")
+	}
+	if a.ReplacesCA() {
+		t.Error("expected no CA to be replaced on a fresh machine")
+	}
+
+	written, err := a.Restore()
+	if err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if len(written) != len(want) {
+		t.Errorf("Restore() wrote %d files, want %d", len(written), len(want))
+	}
+
+	restoredCA, err := os.ReadFile(ca.CertPath())
+	if err != nil {
+		t.Fatalf("CA certificate not restored: %v", err)
+	}
+	if !bytes.Equal(restoredCA, sourceCA) {
+		t.Error("restored CA certificate differs from source")
+	}
+	if _, err := ca.Load(); err != nil {
+		t.Errorf("restored CA cannot be loaded: %v", err)
+	}
+
+	config, err := os.ReadFile(paths.ConfigFile())
+	if err != nil || string(config) != "logging:\n  level: debug\n" {
+		t.Errorf("config not restored: %q, %v", config, err)
+	}
+
+	for _, key := range []string{ca.KeyPath(), filepath.Join(paths.CertsDir(), "_wildcard.app.localhost-key.pem")} {
+		info, err := os.Stat(key)
+		if err != nil {
+			t.Fatalf("key not restored: %v", err)
+		}
+		if perm := info.Mode().Perm(); perm != 0o600 {
+			t.Errorf("%s has mode %o, want 600", filepath.Base(key), perm)
+		}
+	}
+
+	if _, err := os.Stat(paths.LogFile()); !os.IsNotExist(err) {
+		t.Error("log file should not be part of the archive")
+	}
+}
+
+func TestRestore_KeyPermissions(t *testing.T) {
+	useTempDirs(t)
+
+	// A key archived with loose permissions is still restored as 0600
+	a := &Archive{Files: []File{
+		{Name: "certs/_wildcard.app.localhost-key.pem", Mode: 0o644, Data: []byte("key")},
+	}}
+	if _, err := a.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(paths.CertsDir(), "_wildcard.app.localhost-key.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("key has mode %o, want 600", perm)
+	}
+}
+
+func TestArchive_ReplacesCA(t *testing.T) {
+	useTempDirs(t)
+
+	if _, err := ca.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.ReplacesCA() {
+		t.Error("expected own CA not to count as replaced")
+	}
+
+	// Another machine with its own CA
+	useTempDirs(t)
+	if _, err := ca.Generate(); err != nil {
+		t.Fatal(err)
+	}
+	if !a.ReplacesCA() {
+		t.Error("expected a different CA to be replaced")
+	}
+}
+
+func TestRead_RejectsUnexpectedEntries(t *testing.T) {
+	useTempDirs(t)
+
+	tests := []struct {
+		name  string
+		entry string
+		flag  byte
+	}{
+		{name: "path traversal", entry: "../evil", flag: tar.TypeReg},
+		{name: "nested traversal", entry: "certs/../../evil", flag: tar.TypeReg},
+		{name: "unknown file", entry: "devproxy.pid", flag: tar.TypeReg},
+		{name: "subdirectory", entry: "certs/sub/file.pem", flag: tar.TypeReg},
+		{name: "symlink", entry: "certs/link.pem", flag: tar.TypeSymlink},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			hdr := &tar.Header{Name: tt.entry, Mode: 0o644, Typeflag: tt.flag}
+			if tt.flag == tar.TypeSymlink {
+				hdr.Linkname = "/etc/passwd"
+			}
+			if err := tw.WriteHeader(hdr); err != nil {
+				t.Fatal(err)
+			}
+			tw.Close()
+
+			if _, err := Read(&buf); !errors.Is(err, ErrInvalidArchive) {
+				t.Errorf("Read() error = %v, want ErrInvalidArchive", err)
+			}
+		})
+	}
+}
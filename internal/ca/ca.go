@@ -3,17 +3,21 @@
 package ca
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"fmt"
 	"math/big"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/munichmade/devproxy/internal/paths"
@@ -24,6 +28,8 @@ const (
 	CACertFilename = "root-ca.pem"
 	// CAKeyFilename is the filename for the CA private key.
 	CAKeyFilename = "root-ca-key.pem"
+	// CAImportedFilename marks a CA that was imported rather than generated.
+	CAImportedFilename = "root-ca.imported"
 
 	// caValidityYears is how long the CA certificate is valid.
 	caValidityYears = 1
@@ -34,10 +40,15 @@ const (
 	caCommonName = "DevProxy Local CA"
 )
 
+// ErrImportedCAMissing is returned when an imported CA was removed, so a
+// new one is not generated in its place.
+var ErrImportedCAMissing = errors.New("imported CA is missing - run 'devproxy ca import' again")
+
 // CA represents a Certificate Authority with its certificate and private key.
+// Generated CAs have ECDSA keys; imported ones may also have RSA keys.
 type CA struct {
 	Certificate *x509.Certificate
-	PrivateKey  *ecdsa.PrivateKey
+	PrivateKey  crypto.Signer
 
 	// Raw PEM-encoded data for convenience
 	CertPEM []byte
@@ -159,6 +170,11 @@ func Load() (*CA, error) {
 		return nil, fmt.Errorf("failed to read private key: %w", err)
 	}
 
+	return parse(certPEM, keyPEM)
+}
+
+// parse decodes a PEM-encoded CA certificate and private key.
+func parse(certPEM, keyPEM []byte) (*CA, error) {
 	// Parse certificate
 	certBlock, _ := pem.Decode(certPEM)
 	if certBlock == nil {
@@ -174,7 +190,7 @@ func Load() (*CA, error) {
 	if keyBlock == nil {
 		return nil, errors.New("failed to decode private key PEM")
 	}
-	privateKey, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	privateKey, err := parsePrivateKey(keyBlock.Bytes)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse private key: %w", err)
 	}
@@ -187,14 +203,71 @@ func Load() (*CA, error) {
 	}, nil
 }
 
+// parsePrivateKey parses an EC (SEC 1), PKCS #1 or PKCS #8 private key.
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return signer, nil
+}
+
 // LoadOrGenerate loads an existing CA or generates a new one if none exists.
+// An imported CA is never replaced by a generated one.
 func LoadOrGenerate() (*CA, error) {
 	if Exists() {
 		return Load()
 	}
+	if IsImported() {
+		return nil, ErrImportedCAMissing
+	}
 	return Generate()
 }
 
+// IsImported reports whether the CA was imported with Import.
+func IsImported() bool {
+	_, err := os.Stat(filepath.Join(paths.CADir(), CAImportedFilename))
+	return err == nil
+}
+
+// commonName returns the common name of the current CA certificate, which
+// differs from caCommonName for imported CAs.
+func commonName() string {
+	if current, err := Load(); err == nil && current.Certificate.Subject.CommonName != "" {
+		return current.Certificate.Subject.CommonName
+	}
+	return caCommonName
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the CA certificate as
+// colon-separated uppercase hex, as shown by browsers and openssl.
+func (c *CA) Fingerprint() string {
+	return fingerprint(c.Certificate)
+}
+
+// fingerprint returns the SHA-256 fingerprint of cert as colon-separated
+// uppercase hex.
+func fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	hexSum := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	parts := make([]string, 0, len(sum))
+	for i := 0; i < len(hexSum); i += 2 {
+		parts = append(parts, hexSum[i:i+2])
+	}
+	return strings.Join(parts, ":")
+}
+
 // CertPath returns the full path to the CA certificate file.
 func CertPath() string {
 	return filepath.Join(paths.CADir(), CACertFilename)
@@ -1,9 +1,13 @@
 package ca
 
 import (
+	"crypto/ecdsa"
+	"crypto/sha256"
 	"crypto/x509"
+	"encoding/hex"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -139,7 +143,7 @@ func TestLoad(t *testing.T) {
 	}
 
 	// Verify private key matches by comparing public keys
-	if !loaded.PrivateKey.PublicKey.Equal(&generated.PrivateKey.PublicKey) {
+	if !loaded.PrivateKey.Public().(*ecdsa.PublicKey).Equal(generated.PrivateKey.Public()) {
 		t.Error("Loaded private key does not match generated")
 	}
 }
@@ -194,3 +198,22 @@ func TestKeyPath(t *testing.T) {
 		t.Errorf("KeyPath() base = %q, want %q", filepath.Base(path), CAKeyFilename)
 	}
 }
+
+func TestFingerprint(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+
+	ca, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	sum := sha256.Sum256(ca.Certificate.Raw)
+	got := ca.Fingerprint()
+	if want := strings.ToUpper(hex.EncodeToString(sum[:])); strings.ReplaceAll(got, ":", "") != want {
+		t.Errorf("Fingerprint() = %s, want the SHA-256 of the certificate %s", got, want)
+	}
+	if len(got) != 32*3-1 || strings.Count(got, ":") != 31 {
+		t.Errorf("expected 32 colon-separated bytes, got %s", got)
+	}
+}
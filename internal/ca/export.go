@@ -0,0 +1,27 @@
+package ca
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io"
+)
+
+// ExportCert writes the CA certificate to w in format: "pem", or "der" for
+// tools that expect a binary .cer/.crt file, such as the Windows certificate
+// import wizard.
+func ExportCert(w io.Writer, format string) error {
+	current, err := Load()
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "pem":
+		_, err = w.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: current.Certificate.Raw}))
+	case "der":
+		_, err = w.Write(current.Certificate.Raw)
+	default:
+		return fmt.Errorf("unsupported format %q (use pem or der)", format)
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package ca
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+func TestExportCert(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	var out bytes.Buffer
+	if err := ExportCert(&out, "pem"); err == nil {
+		t.Error("expected an error without a CA")
+	}
+
+	generated, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	tests := []struct {
+		format string
+		decode func(data []byte) []byte
+	}{
+		{"pem", func(data []byte) []byte {
+			block, rest := pem.Decode(data)
+			if block == nil || block.Type != "CERTIFICATE" || len(rest) != 0 {
+				return nil
+			}
+			return block.Bytes
+		}},
+		{"der", func(data []byte) []byte { return data }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := ExportCert(&out, tt.format); err != nil {
+				t.Fatalf("ExportCert() error = %v", err)
+			}
+			cert, err := x509.ParseCertificate(tt.decode(out.Bytes()))
+			if err != nil {
+				t.Fatalf("failed to parse exported certificate: %v", err)
+			}
+			if !cert.Equal(generated.Certificate) {
+				t.Error("expected exported certificate to be the CA certificate")
+			}
+		})
+	}
+
+	if err := ExportCert(&out, "p12"); err == nil {
+		t.Error("expected an error for an unsupported format")
+	}
+}
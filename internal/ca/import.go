@@ -0,0 +1,151 @@
+package ca
+
+import (
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+var (
+	// ErrNotCA is returned when an imported certificate may not issue certificates.
+	ErrNotCA = errors.New("certificate is not a CA")
+
+	// ErrKeyMismatch is returned when an imported key does not belong to the certificate.
+	ErrKeyMismatch = errors.New("private key does not match the certificate")
+)
+
+// Import replaces the CA with the PEM-encoded certificate and private key at
+// certPath and keyPath, e.g. a root shared by a team so browsers trust one CA
+// for everyone. The certificate must be a valid CA certificate and the key
+// must match it. Certificates issued by the previous CA are not removed.
+func Import(certPath, keyPath string) (*CA, error) {
+	imported, err := LoadFiles(certPath, keyPath)
+	if err != nil {
+		return nil, err
+	}
+	certPEM, keyPEM := imported.CertPEM, imported.KeyPEM
+
+	if err := os.MkdirAll(paths.CADir(), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create CA directory: %w", err)
+	}
+	if err := replacePair(certPEM, keyPEM); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(paths.CADir(), CAImportedFilename), nil, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to mark CA as imported: %w", err)
+	}
+
+	return imported, nil
+}
+
+// LoadFiles reads a CA from the PEM-encoded certificate and private key at
+// certPath and keyPath, which need not be devproxy's. It fails unless the
+// certificate is a valid CA certificate and the key matches it.
+func LoadFiles(certPath, keyPath string) (*CA, error) {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read private key: %w", err)
+	}
+
+	loaded, err := parse(certPEM, keyPEM)
+	if err != nil {
+		return nil, err
+	}
+	if err := validate(loaded, time.Now()); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// validate checks that c can issue certificates at now with its key.
+func validate(c *CA, now time.Time) error {
+	cert := c.Certificate
+	if !cert.BasicConstraintsValid || !cert.IsCA {
+		return ErrNotCA
+	}
+	if cert.KeyUsage != 0 && cert.KeyUsage&x509.KeyUsageCertSign == 0 {
+		return fmt.Errorf("%w: key usage does not allow signing certificates", ErrNotCA)
+	}
+	if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		return fmt.Errorf("certificate is not valid now (valid %s to %s)",
+			cert.NotBefore.Format(time.DateOnly), cert.NotAfter.Format(time.DateOnly))
+	}
+
+	public, ok := cert.PublicKey.(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !public.Equal(c.PrivateKey.Public()) {
+		return ErrKeyMismatch
+	}
+	return nil
+}
+
+// replacePair replaces the CA certificate and key as a pair. Both are written
+// to temporary files first, so a failed write leaves the previous pair
+// intact, and the certificate is renamed into place last; if that fails, the
+// previous key is put back.
+func replacePair(certPEM, keyPEM []byte) error {
+	keyTmp, err := writeTemp(KeyPath(), keyPEM, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	certTmp, err := writeTemp(CertPath(), certPEM, 0o644)
+	if err != nil {
+		os.Remove(keyTmp)
+		return fmt.Errorf("failed to write certificate: %w", err)
+	}
+
+	previousKey, readErr := os.ReadFile(KeyPath())
+	if err := os.Rename(keyTmp, KeyPath()); err != nil {
+		os.Remove(keyTmp)
+		os.Remove(certTmp)
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.Rename(certTmp, CertPath()); err != nil {
+		os.Remove(certTmp)
+		if readErr == nil {
+			_ = os.WriteFile(KeyPath(), previousKey, 0o600)
+		} else {
+			os.Remove(KeyPath())
+		}
+		return fmt.Errorf("failed to write certificate: %w", err)
+	}
+	return nil
+}
+
+// replaceFile writes data to path through a temporary file, so a failed
+// write leaves the previous file intact.
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := writeTemp(path, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// writeTemp writes data with perm to a temporary file next to path and
+// returns its name.
+func writeTemp(path string, data []byte, perm os.FileMode) (string, error) {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return tmp, nil
+}
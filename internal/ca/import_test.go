@@ -0,0 +1,226 @@
+package ca
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+// writeTeamCA writes a CA certificate made from template, signed by key, and
+// keyPEM to dir and returns their paths.
+func writeTeamCA(t *testing.T, dir string, template *x509.Certificate, key crypto.Signer, keyPEM []byte) (certPath, keyPath string) {
+	t.Helper()
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	certPath = filepath.Join(dir, "team-ca.pem")
+	keyPath = filepath.Join(dir, "team-ca-key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestImport(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER})
+
+	caTemplate := func(modify func(*x509.Certificate)) *x509.Certificate {
+		template := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "Team Dev CA"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().AddDate(1, 0, 0),
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+			BasicConstraintsValid: true,
+			IsCA:                  true,
+		}
+		if modify != nil {
+			modify(template)
+		}
+		return template
+	}
+
+	tests := []struct {
+		name     string
+		template *x509.Certificate
+		signer   crypto.Signer
+		keyPEM   []byte
+		wantErr  error
+		wantFail bool
+	}{
+		{name: "rsa pkcs1", template: caTemplate(nil), signer: rsaKey, keyPEM: rsaPEM},
+		{name: "ecdsa pkcs8", template: caTemplate(nil), signer: ecKey, keyPEM: ecPEM},
+		{
+			name:     "not a ca",
+			template: caTemplate(func(c *x509.Certificate) { c.IsCA = false; c.KeyUsage = x509.KeyUsageDigitalSignature }),
+			signer:   ecKey,
+			keyPEM:   ecPEM,
+			wantErr:  ErrNotCA,
+		},
+		{name: "key mismatch", template: caTemplate(nil), signer: ecKey, keyPEM: rsaPEM, wantErr: ErrKeyMismatch},
+		{
+			name:     "expired",
+			template: caTemplate(func(c *x509.Certificate) { c.NotAfter = time.Now().Add(-time.Minute) }),
+			signer:   ecKey,
+			keyPEM:   ecPEM,
+			wantFail: true,
+		},
+		{name: "garbage key", template: caTemplate(nil), signer: ecKey, keyPEM: []byte("not a key"), wantFail: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_DATA_HOME", t.TempDir())
+			paths.Reset()
+			defer paths.Reset()
+
+			generated, err := Generate()
+			if err != nil {
+				t.Fatalf("Generate() failed: %v", err)
+			}
+			certPath, keyPath := writeTeamCA(t, t.TempDir(), tt.template, tt.signer, tt.keyPEM)
+
+			imported, err := Import(certPath, keyPath)
+			if tt.wantErr != nil || tt.wantFail {
+				if err == nil {
+					t.Fatal("expected Import() to fail")
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+				// The generated CA is kept
+				if current, err := Load(); err != nil || !current.Certificate.Equal(generated.Certificate) {
+					t.Error("expected failed import to keep the previous CA")
+				}
+				if IsImported() {
+					t.Error("expected failed import not to mark the CA as imported")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			current, err := LoadOrGenerate()
+			if err != nil {
+				t.Fatalf("LoadOrGenerate() error = %v", err)
+			}
+			if !current.Certificate.Equal(imported.Certificate) || current.Certificate.Subject.CommonName != "Team Dev CA" {
+				t.Errorf("expected imported CA to be loaded, got %s", current.Certificate.Subject.CommonName)
+			}
+			if !IsImported() {
+				t.Error("expected CA to be marked as imported")
+			}
+			for path, want := range map[string]os.FileMode{CertPath(): 0o644, KeyPath(): 0o600} {
+				if info, err := os.Stat(path); err != nil || info.Mode().Perm() != want {
+					t.Errorf("expected %s to have permissions %o", filepath.Base(path), want)
+				}
+			}
+
+			// Trust is recognized although the common name is not devproxy's
+			if got := trustState(concat(generated.CertPEM, current.CertPEM), current.Certificate); got != TrustCurrent {
+				t.Errorf("trustState() = %v, want %v", got, TrustCurrent)
+			}
+
+			// A missing imported CA is not replaced by a generated one
+			os.Remove(CertPath())
+			if _, err := LoadOrGenerate(); !errors.Is(err, ErrImportedCAMissing) {
+				t.Errorf("expected ErrImportedCAMissing, got %v", err)
+			}
+			if Exists() {
+				t.Error("expected no CA to be generated in place of the imported one")
+			}
+		})
+	}
+}
+
+func TestImport_KeepsPairOnFailure(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	ecDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Team Dev CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(1, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	tests := []struct {
+		name  string
+		block func() string // path made a directory so writing the certificate fails
+	}{
+		{name: "certificate not written", block: func() string { return CertPath() + ".tmp" }},
+		{name: "certificate not renamed", block: CertPath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_DATA_HOME", t.TempDir())
+			paths.Reset()
+			defer paths.Reset()
+
+			generated, err := Generate()
+			if err != nil {
+				t.Fatalf("Generate() failed: %v", err)
+			}
+			certPath, keyPath := writeTeamCA(t, t.TempDir(), template, ecKey, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER}))
+
+			blocked := tt.block()
+			os.Remove(blocked)
+			if err := os.Mkdir(blocked, 0o700); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Import(certPath, keyPath); err == nil {
+				t.Fatal("expected Import() to fail")
+			}
+
+			if key, err := os.ReadFile(KeyPath()); err != nil || !bytes.Equal(key, generated.KeyPEM) {
+				t.Error("expected failed import to keep the previous key")
+			}
+			if _, err := os.Stat(KeyPath() + ".tmp"); !os.IsNotExist(err) {
+				t.Error("expected temporary key file to be removed")
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package ca
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+const (
+	// CAPreviousFilename holds the certificate of the CA replaced by Rotate
+	// until it is retired.
+	CAPreviousFilename = "root-ca.previous.pem"
+
+	// CAPreviousRetireFilename holds when the previous CA is due for
+	// removal, in RFC 3339 format.
+	CAPreviousRetireFilename = "root-ca.previous.retire"
+
+	// DefaultRotationGrace is how long the previous CA stays trusted after
+	// a rotation.
+	DefaultRotationGrace = 7 * 24 * time.Hour
+)
+
+// ErrRotateImported is returned when rotating an imported CA, which devproxy
+// cannot replace with a generated one.
+var ErrRotateImported = errors.New("imported CA cannot be rotated - import its successor with 'devproxy ca import'")
+
+// Previous is a CA replaced by Rotate that is still trusted.
+type Previous struct {
+	Certificate *x509.Certificate
+
+	// RetireAfter is when RetirePrevious removes it from the trust stores.
+	RetireAfter time.Time
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the previous CA certificate
+// in the format of CA.Fingerprint.
+func (p *Previous) Fingerprint() string {
+	return fingerprint(p.Certificate)
+}
+
+// PreviousPath returns the path to the certificate of the previous CA.
+func PreviousPath() string {
+	return filepath.Join(paths.CADir(), CAPreviousFilename)
+}
+
+// previousRetirePath returns the path to the file recording when the previous
+// CA is due for removal.
+func previousRetirePath() string {
+	return filepath.Join(paths.CADir(), CAPreviousRetireFilename)
+}
+
+// Rotate replaces the CA with a newly generated one and installs it into the
+// trust stores. The previous CA stays trusted alongside it for grace
+// (DefaultRotationGrace if not positive), so certificates it issued keep
+// working until they are reissued; RetirePrevious removes it afterwards.
+// Certificates are not reissued here. This requires root privileges.
+func Rotate(grace time.Duration) (*CA, error) {
+	// A CA replaced before is dropped; certificates it issued were reissued
+	// by the current CA after the last rotation
+	if previous, err := LoadPrevious(); err == nil {
+		if err := uninstallPreviousTrust(previous.Certificate); err != nil {
+			return nil, fmt.Errorf("failed to remove the CA replaced before: %w", err)
+		}
+	}
+
+	rotated, err := rotate(grace, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := InstallTrust(); err != nil {
+		return rotated, err
+	}
+	if err := installPreviousTrust(PreviousPath()); err != nil {
+		return rotated, fmt.Errorf("failed to keep the previous CA trusted: %w", err)
+	}
+	return rotated, nil
+}
+
+// rotate keeps the current CA certificate as the previous one, due for
+// retirement grace after now, and generates a new CA.
+func rotate(grace time.Duration, now time.Time) (*CA, error) {
+	if IsImported() {
+		return nil, ErrRotateImported
+	}
+	current, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	if grace <= 0 {
+		grace = DefaultRotationGrace
+	}
+
+	retireAfter := now.Add(grace).UTC().Format(time.RFC3339)
+	if err := replaceFile(previousRetirePath(), []byte(retireAfter+"\n"), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to schedule retiring the previous CA: %w", err)
+	}
+	if err := replaceFile(PreviousPath(), current.CertPEM, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to keep the previous CA: %w", err)
+	}
+
+	return Generate()
+}
+
+// LoadPrevious returns the CA replaced by the last rotation. It returns an
+// error wrapping os.ErrNotExist if there is none.
+func LoadPrevious() (*Previous, error) {
+	data, err := os.ReadFile(PreviousPath())
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("failed to decode previous CA certificate PEM")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse previous CA certificate: %w", err)
+	}
+
+	// Without a valid schedule the previous CA is due right away
+	var retireAfter time.Time
+	if data, err := os.ReadFile(previousRetirePath()); err == nil {
+		retireAfter, _ = time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	}
+
+	return &Previous{Certificate: cert, RetireAfter: retireAfter}, nil
+}
+
+// RetirePrevious removes the previous CA from the trust stores once its grace
+// period has passed, or right away with force. It reports whether a CA was
+// retired. This requires root privileges.
+func RetirePrevious(force bool) (bool, error) {
+	previous, err := LoadPrevious()
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !force && time.Now().Before(previous.RetireAfter) {
+		return false, nil
+	}
+
+	if err := uninstallPreviousTrust(previous.Certificate); err != nil {
+		return false, err
+	}
+	for _, path := range []string{PreviousPath(), previousRetirePath()} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// sha1Fingerprint returns the SHA-1 hash of cert in hex, which the macOS and
+// Windows trust store tools use to identify a certificate.
+func sha1Fingerprint(cert *x509.Certificate) string {
+	sum := sha1.Sum(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,104 @@
+package ca
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+func TestRotate(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	if _, err := LoadPrevious(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("LoadPrevious() without a rotation error = %v, want os.ErrNotExist", err)
+	}
+
+	original, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		grace time.Duration
+		want  time.Duration
+	}{
+		{name: "custom grace", grace: 48 * time.Hour, want: 48 * time.Hour},
+		{name: "default grace", grace: 0, want: DefaultRotationGrace},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, err := Load()
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+
+			rotated, err := rotate(tt.grace, now)
+			if err != nil {
+				t.Fatalf("rotate() failed: %v", err)
+			}
+			if rotated.Fingerprint() == before.Fingerprint() {
+				t.Error("expected rotate() to generate a new CA")
+			}
+			current, err := Load()
+			if err != nil {
+				t.Fatalf("Load() failed: %v", err)
+			}
+			if current.Fingerprint() != rotated.Fingerprint() {
+				t.Error("expected the rotated CA to be the current one")
+			}
+
+			previous, err := LoadPrevious()
+			if err != nil {
+				t.Fatalf("LoadPrevious() failed: %v", err)
+			}
+			if !bytes.Equal(previous.Certificate.Raw, before.Certificate.Raw) {
+				t.Error("expected the replaced CA to be kept as the previous one")
+			}
+			if previous.Fingerprint() != before.Fingerprint() {
+				t.Errorf("Previous.Fingerprint() = %s, want %s", previous.Fingerprint(), before.Fingerprint())
+			}
+			if want := now.Add(tt.want); !previous.RetireAfter.Equal(want) {
+				t.Errorf("RetireAfter = %s, want %s", previous.RetireAfter, want)
+			}
+		})
+	}
+
+	// Rotating again replaces the previous CA, not the original one
+	previous, err := LoadPrevious()
+	if err != nil {
+		t.Fatalf("LoadPrevious() failed: %v", err)
+	}
+	if bytes.Equal(previous.Certificate.Raw, original.Certificate.Raw) {
+		t.Error("expected the second rotation to keep the first rotated CA")
+	}
+}
+
+func TestRotate_Imported(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	if _, err := Generate(); err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(paths.CADir(), CAImportedFilename), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rotate(time.Hour, time.Now()); !errors.Is(err, ErrRotateImported) {
+		t.Errorf("rotate() error = %v, want ErrRotateImported", err)
+	}
+	if _, err := LoadPrevious(); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no previous CA after a refused rotation, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package ca
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+)
+
+// DefaultTrustTimeout bounds each run of a trust store update command.
+const DefaultTrustTimeout = 2 * time.Minute
+
+// trustCmdTimeout bounds each run of a trust store update command.
+var trustCmdTimeout = DefaultTrustTimeout
+
+// SetTrustTimeout sets how long a trust store update command may run before
+// it is stopped and retried. A non-positive timeout selects
+// DefaultTrustTimeout. Only Linux and Windows run such commands.
+func SetTrustTimeout(timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = DefaultTrustTimeout
+	}
+	trustCmdTimeout = timeout
+}
+
+// TrustState describes whether the system trust store holds the current CA.
+type TrustState int
+
+const (
+	// TrustNone means no devproxy CA is in the trust store.
+	TrustNone TrustState = iota
+	// TrustStale means a devproxy CA is trusted, but not the current one,
+	// e.g. after the CA was regenerated, or the current one is only trusted
+	// by some of the trust stores.
+	TrustStale
+	// TrustCurrent means the current CA certificate is trusted.
+	TrustCurrent
+)
+
+// String returns a human-readable description of the trust state.
+func (s TrustState) String() string {
+	switch s {
+	case TrustCurrent:
+		return "trusted and current"
+	case TrustStale:
+		return "trusted but stale"
+	default:
+		return "not trusted"
+	}
+}
+
+// StoreTrust is the trust state of the current CA in one trust store.
+type StoreTrust struct {
+	Name  string
+	State TrustState
+}
+
+// CheckStores compares the devproxy CA certificates in each detected trust
+// store against the current CA certificate: the system trust store first,
+// then on Linux the NSS databases of Firefox and Chromium. It returns nothing
+// if there is no CA.
+func CheckStores() []StoreTrust {
+	if !Exists() {
+		return nil
+	}
+	current, err := Load()
+	if err != nil {
+		return nil
+	}
+	system := TrustNone
+	if installed, err := installedCerts(); err == nil {
+		system = trustState(installed, current.Certificate)
+	}
+	return append([]StoreTrust{{Name: TrustStoreName(), State: system}}, nssTrustStores(current.Certificate)...)
+}
+
+// CheckTrust returns the overall trust state of the current CA across the
+// trust stores reported by CheckStores.
+func CheckTrust() TrustState {
+	stores := CheckStores()
+	if len(stores) == 0 {
+		return TrustNone
+	}
+	states := make([]TrustState, len(stores))
+	for i, store := range stores {
+		states[i] = store.State
+	}
+	return combineTrust(states)
+}
+
+// combineTrust returns the overall state of several trust stores: current if
+// every store trusts the current CA, none if no store trusts a devproxy CA,
+// and stale otherwise.
+func combineTrust(states []TrustState) TrustState {
+	combined := states[0]
+	for _, state := range states[1:] {
+		if state != combined {
+			return TrustStale
+		}
+	}
+	return combined
+}
+
+// IsTrusted reports whether the current CA certificate is in every trust store.
+// A previously trusted CA that has since been regenerated does not count.
+func IsTrusted() bool {
+	return CheckTrust() == TrustCurrent
+}
+
+// trustState classifies PEM-encoded certificates read from a trust store.
+// Certificates other than current and not named like it or the generated
+// devproxy CA are ignored, so installed may be a system-wide bundle.
+func trustState(installed []byte, current *x509.Certificate) TrustState {
+	state := TrustNone
+	for {
+		var block *pem.Block
+		block, installed = pem.Decode(installed)
+		if block == nil {
+			return state
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+		if bytes.Equal(cert.Raw, current.Raw) {
+			return TrustCurrent
+		}
+		if cert.Subject.CommonName == caCommonName || cert.Subject.CommonName == current.Subject.CommonName {
+			state = TrustStale
+		}
+	}
+}
@@ -6,6 +6,7 @@ package ca
 
 import (
 	"bytes"
+	"crypto/x509"
 	"fmt"
 	"os"
 	"os/exec"
@@ -26,9 +27,14 @@ func InstallTrust() error {
 		return fmt.Errorf("CA certificate not found at %s, run 'devproxy ca generate' first", certPath)
 	}
 
-	// Check if already trusted
-	if IsTrusted() {
+	switch CheckTrust() {
+	case TrustCurrent:
 		return nil // Already trusted, nothing to do
+	case TrustStale:
+		// Remove the outdated CA so it is not mistaken for the current one
+		if err := UninstallTrust(); err != nil {
+			return err
+		}
 	}
 
 	// Add to System Keychain with trust settings
@@ -71,12 +77,12 @@ func UninstallTrust() error {
 	if isRoot() {
 		// Already running as root, no need for sudo
 		cmd = exec.Command("security", "delete-certificate",
-			"-c", caCommonName,
+			"-c", commonName(),
 			"/Library/Keychains/System.keychain",
 		)
 	} else {
 		cmd = exec.Command("sudo", "security", "delete-certificate",
-			"-c", caCommonName,
+			"-c", commonName(),
 			"/Library/Keychains/System.keychain",
 		)
 	}
@@ -96,26 +102,73 @@ func UninstallTrust() error {
 	return nil
 }
 
-// IsTrusted checks if the CA certificate is trusted in the macOS System Keychain.
-func IsTrusted() bool {
-	if !Exists() {
-		return false
+// installPreviousTrust keeps the CA certificate at certPath, replaced by a
+// rotation, trusted in the macOS System Keychain.
+func installPreviousTrust(certPath string) error {
+	args := []string{"security", "add-trusted-cert",
+		"-d",
+		"-r", "trustRoot",
+		"-k", "/Library/Keychains/System.keychain",
+		certPath,
+	}
+	if !isRoot() {
+		args = append([]string{"sudo"}, args...)
+	}
+	cmd := exec.Command(args[0], args[1:]...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to add previous CA to System Keychain: %w\n%s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// uninstallPreviousTrust removes the CA certificate replaced by a rotation
+// from the macOS System Keychain. It shares the name of the current CA, so
+// it is deleted by its hash.
+func uninstallPreviousTrust(previous *x509.Certificate) error {
+	args := []string{"security", "delete-certificate",
+		"-Z", sha1Fingerprint(previous),
+		"/Library/Keychains/System.keychain",
+	}
+	if !isRoot() {
+		args = append([]string{"sudo"}, args...)
 	}
+	cmd := exec.Command(args[0], args[1:]...)
 
-	// Check if the certificate exists in the System Keychain
-	// This is the correct way to verify installation, not verify-cert
-	// (verify-cert returns success for self-signed CAs even if not installed)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stderr.String(), "could not be found") ||
+			strings.Contains(stderr.String(), "SecKeychainSearchCopyNext") {
+			return nil
+		}
+		return fmt.Errorf("failed to remove previous CA from System Keychain: %w\n%s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// installedCerts returns the PEM-encoded devproxy CA certificates in the
+// macOS System Keychain.
+func installedCerts() ([]byte, error) {
+	// find-certificate exits non-zero if no certificate matches
 	cmd := exec.Command("security", "find-certificate",
-		"-c", caCommonName,
+		"-a",
+		"-c", commonName(),
+		"-p",
 		"/Library/Keychains/System.keychain",
 	)
+	return cmd.Output()
+}
 
-	// Suppress output - we only care about the exit code
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-
-	// find-certificate returns 0 if found, non-zero otherwise
-	return cmd.Run() == nil
+// nssTrustStores returns no stores: NSS databases are only managed on Linux.
+func nssTrustStores(*x509.Certificate) []StoreTrust {
+	return nil
 }
 
 // NeedsSudo returns true if trust operations require sudo.
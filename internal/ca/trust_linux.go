@@ -9,11 +9,16 @@
 package ca
 
 import (
+	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // Linux distribution types
@@ -31,17 +36,49 @@ const (
 	// Debian/Ubuntu
 	debianCertDir   = "/usr/local/share/ca-certificates"
 	debianCertName  = "devproxy-ca.crt"
+	debianPrevName  = "devproxy-ca-previous.crt"
 	debianUpdateCmd = "update-ca-certificates"
 
 	// RHEL/Fedora
 	rhelCertDir   = "/etc/pki/ca-trust/source/anchors"
 	rhelCertName  = "devproxy-ca.pem"
+	rhelPrevName  = "devproxy-ca-previous.pem"
 	rhelUpdateCmd = "update-ca-trust"
 
 	// Arch Linux
-	archTrustCmd = "trust"
+	archTrustCmd   = "trust"
+	archBundlePath = "/etc/ca-certificates/extracted/tls-ca-bundle.pem"
 )
 
+// Errors of the trust store operations, telling apart why an update failed.
+var (
+	errNotRoot         = errors.New("root privileges required - run with sudo")
+	errCommandNotFound = errors.New("command not found")
+	errCommandFailed   = errors.New("command failed")
+	errNoSuchAnchor    = errors.New("certificate not in the trust store")
+)
+
+// The trust store update commands can be slow or fail transiently, so each
+// run is limited to trustCmdTimeout (see SetTrustTimeout) and a failed run
+// is retried once after trustCmdRetryDelay.
+var trustCmdRetryDelay = time.Second
+
+const trustCmdAttempts = 2
+
+// commandRunner runs a command and returns its combined output.
+type commandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// runCommand runs the trust store update commands; tests replace it.
+var runCommand commandRunner = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// isRoot reports whether the process runs as root, which the trust store
+// update commands require; tests replace it.
+var isRoot = func() bool {
+	return os.Geteuid() == 0
+}
+
 // detectDistro attempts to detect the Linux distribution.
 func detectDistro() distro {
 	// Check for os-release file (modern standard)
@@ -88,7 +125,8 @@ func detectDistro() distro {
 	return distroUnknown
 }
 
-// InstallTrust installs the CA certificate into the Linux system trust store.
+// InstallTrust installs the CA certificate into the Linux system trust store
+// and the NSS databases of Firefox and Chromium, if certutil is installed.
 func InstallTrust() error {
 	if !Exists() {
 		return fmt.Errorf("CA certificate not found at %s", CertPath())
@@ -96,159 +134,218 @@ func InstallTrust() error {
 
 	d := detectDistro()
 
+	var err error
 	switch d {
 	case distroDebian:
-		return installTrustDebian()
+		err = installTrustDebian()
 	case distroRHEL:
-		return installTrustRHEL()
+		err = installTrustRHEL()
 	case distroArch:
-		return installTrustArch()
+		err = installTrustArch()
 	default:
 		return fmt.Errorf("unsupported Linux distribution; please install the CA certificate manually from %s", CertPath())
 	}
+	if err != nil {
+		return err
+	}
+
+	return installTrustNSS()
 }
 
 // installTrustDebian installs trust for Debian/Ubuntu systems.
 func installTrustDebian() error {
-	destPath := filepath.Join(debianCertDir, debianCertName)
-
-	// Copy certificate to ca-certificates directory
-	if err := copyFile(CertPath(), destPath); err != nil {
-		return fmt.Errorf("failed to copy certificate (are you running as root?): %w", err)
-	}
-
-	// Run update-ca-certificates
-	cmd := exec.Command(debianUpdateCmd)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to run %s: %w\n%s", debianUpdateCmd, err, output)
-	}
-
-	return nil
+	return installAnchorFile(CertPath(), filepath.Join(debianCertDir, debianCertName), debianUpdateCmd)
 }
 
 // installTrustRHEL installs trust for RHEL/Fedora systems.
 func installTrustRHEL() error {
-	destPath := filepath.Join(rhelCertDir, rhelCertName)
-
-	// Copy certificate to anchors directory
-	if err := copyFile(CertPath(), destPath); err != nil {
-		return fmt.Errorf("failed to copy certificate (are you running as root?): %w", err)
-	}
-
-	// Run update-ca-trust
-	cmd := exec.Command(rhelUpdateCmd)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to run %s: %w\n%s", rhelUpdateCmd, err, output)
-	}
-
-	return nil
+	return installAnchorFile(CertPath(), filepath.Join(rhelCertDir, rhelCertName), rhelUpdateCmd)
 }
 
 // installTrustArch installs trust for Arch Linux systems.
 func installTrustArch() error {
-	// Use trust anchor --store which handles everything
-	cmd := exec.Command(archTrustCmd, "anchor", "--store", CertPath())
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to run trust anchor: %w\n%s", err, output)
-	}
+	return storeArchAnchor(CertPath())
+}
 
+// storeArchAnchor adds the certificate at certPath to the Arch trust store;
+// trust anchor --store also rebuilds the extracted bundles.
+func storeArchAnchor(certPath string) error {
+	return runUpdateCommand(archTrustCmd, "anchor", "--store", certPath)
+}
+
+// removeArchAnchor removes the certificate at certPath from the Arch trust
+// store. A certificate that is not in the store is not an error.
+func removeArchAnchor(certPath string) error {
+	if err := runUpdateCommand(archTrustCmd, "anchor", "--remove", certPath); err != nil && !errors.Is(err, errNoSuchAnchor) {
+		return err
+	}
 	return nil
 }
 
-// UninstallTrust removes the CA certificate from the Linux system trust store.
+// UninstallTrust removes the CA certificate from the Linux system trust store
+// and the NSS databases.
 func UninstallTrust() error {
 	d := detectDistro()
 
+	var err error
 	switch d {
 	case distroDebian:
-		return uninstallTrustDebian()
+		err = uninstallTrustDebian()
 	case distroRHEL:
-		return uninstallTrustRHEL()
+		err = uninstallTrustRHEL()
 	case distroArch:
-		return uninstallTrustArch()
+		err = uninstallTrustArch()
 	default:
 		return fmt.Errorf("unsupported Linux distribution; please remove the CA certificate manually")
 	}
+	if err != nil {
+		return err
+	}
+
+	return uninstallTrustNSS()
 }
 
 // uninstallTrustDebian removes trust for Debian/Ubuntu systems.
 func uninstallTrustDebian() error {
-	destPath := filepath.Join(debianCertDir, debianCertName)
+	return uninstallAnchorFile(filepath.Join(debianCertDir, debianCertName), debianUpdateCmd)
+}
 
-	// Remove certificate
-	if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove certificate (are you running as root?): %w", err)
-	}
+// uninstallTrustRHEL removes trust for RHEL/Fedora systems.
+func uninstallTrustRHEL() error {
+	return uninstallAnchorFile(filepath.Join(rhelCertDir, rhelCertName), rhelUpdateCmd)
+}
 
-	// Run update-ca-certificates
-	cmd := exec.Command(debianUpdateCmd)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to run %s: %w\n%s", debianUpdateCmd, err, output)
+// uninstallTrustArch removes trust for Arch Linux systems.
+func uninstallTrustArch() error {
+	return removeArchAnchor(CertPath())
+}
+
+// installPreviousTrust keeps the CA certificate at certPath, replaced by a
+// rotation, in the system trust store and the NSS databases.
+func installPreviousTrust(certPath string) error {
+	var err error
+	switch detectDistro() {
+	case distroDebian:
+		err = installAnchorFile(certPath, filepath.Join(debianCertDir, debianPrevName), debianUpdateCmd)
+	case distroRHEL:
+		err = installAnchorFile(certPath, filepath.Join(rhelCertDir, rhelPrevName), rhelUpdateCmd)
+	case distroArch:
+		err = storeArchAnchor(certPath)
+	default:
+		return fmt.Errorf("unsupported Linux distribution; please install the previous CA certificate manually from %s", certPath)
+	}
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return installPreviousTrustNSS(certPath)
 }
 
-// uninstallTrustRHEL removes trust for RHEL/Fedora systems.
-func uninstallTrustRHEL() error {
-	destPath := filepath.Join(rhelCertDir, rhelCertName)
-
-	// Remove certificate
-	if err := os.Remove(destPath); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove certificate (are you running as root?): %w", err)
+// uninstallPreviousTrust removes the CA certificate replaced by a rotation
+// from the system trust store and the NSS databases.
+func uninstallPreviousTrust(*x509.Certificate) error {
+	var err error
+	switch detectDistro() {
+	case distroDebian:
+		err = uninstallAnchorFile(filepath.Join(debianCertDir, debianPrevName), debianUpdateCmd)
+	case distroRHEL:
+		err = uninstallAnchorFile(filepath.Join(rhelCertDir, rhelPrevName), rhelUpdateCmd)
+	case distroArch:
+		err = removeArchAnchor(PreviousPath())
+	default:
+		return fmt.Errorf("unsupported Linux distribution; please remove the previous CA certificate manually")
+	}
+	if err != nil {
+		return err
 	}
 
-	// Run update-ca-trust
-	cmd := exec.Command(rhelUpdateCmd)
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("failed to run %s: %w\n%s", rhelUpdateCmd, err, output)
+	return uninstallPreviousTrustNSS()
+}
+
+// installAnchorFile copies the certificate at src to the anchor file dst and
+// runs updateCmd to rebuild the trust store.
+func installAnchorFile(src, dst, updateCmd string) error {
+	if err := copyFile(src, dst); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("failed to copy certificate: %w: %w", errNotRoot, err)
+		}
+		return fmt.Errorf("failed to copy certificate: %w", err)
 	}
 
-	return nil
+	return runUpdateCommand(updateCmd)
 }
 
-// uninstallTrustArch removes trust for Arch Linux systems.
-func uninstallTrustArch() error {
-	// trust anchor --remove requires the certificate path or a pkcs11 URI
-	// We need to find our certificate in the trust store first
-	cmd := exec.Command(archTrustCmd, "anchor", "--remove", CertPath())
-	if output, err := cmd.CombinedOutput(); err != nil {
-		// Ignore errors if certificate wasn't in the store
-		if !strings.Contains(string(output), "no such") {
-			return fmt.Errorf("failed to run trust anchor --remove: %w\n%s", err, output)
+// uninstallAnchorFile removes the anchor file path and runs updateCmd to
+// rebuild the trust store.
+func uninstallAnchorFile(path, updateCmd string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("failed to remove certificate: %w: %w", errNotRoot, err)
 		}
+		return fmt.Errorf("failed to remove certificate: %w", err)
 	}
 
-	return nil
+	return runUpdateCommand(updateCmd)
 }
 
-// IsTrusted checks if the CA certificate is trusted in the Linux system trust store.
-func IsTrusted() bool {
-	if !Exists() {
-		return false
+// runUpdateCommand runs the trust store update command name with args,
+// retrying once if it fails or times out. A missing command, lacking
+// privileges or a certificate missing from the store are not retried.
+func runUpdateCommand(name string, args ...string) error {
+	if !isRoot() {
+		return fmt.Errorf("%s: %w", name, errNotRoot)
 	}
 
+	var err error
+	for attempt := 1; attempt <= trustCmdAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(trustCmdRetryDelay)
+		}
+		err = runUpdateCommandOnce(name, args...)
+		if err == nil || !errors.Is(err, errCommandFailed) {
+			return err
+		}
+	}
+	return fmt.Errorf("%w (tried %d times)", err, trustCmdAttempts)
+}
+
+// runUpdateCommandOnce runs name once within trustCmdTimeout.
+func runUpdateCommandOnce(name string, args ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), trustCmdTimeout)
+	defer cancel()
+
+	output, err := runCommand(ctx, name, args...)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, exec.ErrNotFound):
+		return fmt.Errorf("%s: %w - is the ca-certificates package installed?", name, errCommandNotFound)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%s: %w: timed out after %s", name, errCommandFailed, trustCmdTimeout)
+	case name == archTrustCmd && strings.Contains(strings.ToLower(string(output)), "no such"):
+		// trust anchor --remove for a certificate that is not stored
+		return fmt.Errorf("%s: %w\n%s", name, errNoSuchAnchor, output)
+	default:
+		return fmt.Errorf("%s: %w: %w\n%s", name, errCommandFailed, err, output)
+	}
+}
+
+// installedCerts returns the PEM-encoded certificates that may hold the
+// devproxy CA in the Linux system trust store.
+func installedCerts() ([]byte, error) {
 	d := detectDistro()
 
 	switch d {
 	case distroDebian:
-		destPath := filepath.Join(debianCertDir, debianCertName)
-		_, err := os.Stat(destPath)
-		return err == nil
+		return os.ReadFile(filepath.Join(debianCertDir, debianCertName))
 	case distroRHEL:
-		destPath := filepath.Join(rhelCertDir, rhelCertName)
-		_, err := os.Stat(destPath)
-		return err == nil
+		return os.ReadFile(filepath.Join(rhelCertDir, rhelCertName))
 	case distroArch:
-		// Check if trust list contains our CA
-		cmd := exec.Command(archTrustCmd, "list")
-		output, err := cmd.Output()
-		if err != nil {
-			return false
-		}
-		return strings.Contains(string(output), caCommonName)
+		// trust anchor --store keeps its own copy; the extracted bundle
+		// holds every trusted anchor
+		return os.ReadFile(archBundlePath)
 	default:
-		return false
+		return nil, fmt.Errorf("unsupported Linux distribution")
 	}
 }
 
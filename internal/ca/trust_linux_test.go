@@ -0,0 +1,167 @@
+//go:build linux
+
+package ca
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// stubRunCommand replaces runCommand with run for the duration of the test,
+// running as root, and returns a pointer to the number of runs.
+func stubRunCommand(t *testing.T, run commandRunner) *int {
+	t.Helper()
+	origRun, origRoot, origTimeout, origDelay := runCommand, isRoot, trustCmdTimeout, trustCmdRetryDelay
+	t.Cleanup(func() {
+		runCommand, isRoot, trustCmdTimeout, trustCmdRetryDelay = origRun, origRoot, origTimeout, origDelay
+	})
+	isRoot = func() bool { return true }
+	trustCmdRetryDelay = 0
+
+	calls := 0
+	runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		calls++
+		return run(ctx, name, args...)
+	}
+	return &calls
+}
+
+func TestRunUpdateCommand(t *testing.T) {
+	errExit := errors.New("exit status 1")
+
+	tests := []struct {
+		name      string
+		notRoot   bool
+		run       func(call int) ([]byte, error)
+		wantErr   error
+		wantMsg   string
+		wantCalls int
+	}{
+		{
+			name:      "success",
+			run:       func(int) ([]byte, error) { return nil, nil },
+			wantCalls: 1,
+		},
+		{
+			name: "transient failure is retried",
+			run: func(call int) ([]byte, error) {
+				if call == 1 {
+					return []byte("resource busy"), errExit
+				}
+				return nil, nil
+			},
+			wantCalls: 2,
+		},
+		{
+			name:      "repeated failure",
+			run:       func(int) ([]byte, error) { return []byte("broken bundle"), errExit },
+			wantErr:   errCommandFailed,
+			wantMsg:   "tried 2 times",
+			wantCalls: 2,
+		},
+		{
+			name: "command not found",
+			run: func(int) ([]byte, error) {
+				return nil, &exec.Error{Name: debianUpdateCmd, Err: exec.ErrNotFound}
+			},
+			wantErr:   errCommandNotFound,
+			wantCalls: 1,
+		},
+		{
+			name:      "not root",
+			notRoot:   true,
+			run:       func(int) ([]byte, error) { return nil, nil },
+			wantErr:   errNotRoot,
+			wantCalls: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls *int
+			calls = stubRunCommand(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				return tt.run(*calls)
+			})
+			if tt.notRoot {
+				isRoot = func() bool { return false }
+			}
+
+			err := runUpdateCommand(debianUpdateCmd)
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("runUpdateCommand() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("runUpdateCommand() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantMsg != "" && !strings.Contains(err.Error(), tt.wantMsg) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantMsg)
+			}
+			if *calls != tt.wantCalls {
+				t.Errorf("command ran %d times, want %d", *calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRemoveArchAnchor(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		wantErr error
+	}{
+		{name: "removed"},
+		{name: "not in the store", output: "p11-kit: no such certificate", wantErr: nil},
+		{name: "failure", output: "p11-kit: couldn't remove", wantErr: errCommandFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotArgs []string
+			stubRunCommand(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				gotArgs = append([]string{name}, args...)
+				if tt.output == "" {
+					return nil, nil
+				}
+				return []byte(tt.output), errors.New("exit status 1")
+			})
+
+			err := removeArchAnchor("/tmp/ca.crt")
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("removeArchAnchor() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("removeArchAnchor() error = %v, want %v", err, tt.wantErr)
+			}
+			if want := "trust anchor --remove /tmp/ca.crt"; strings.Join(gotArgs, " ") != want {
+				t.Errorf("ran %q, want %q", strings.Join(gotArgs, " "), want)
+			}
+		})
+	}
+}
+
+func TestRunUpdateCommand_Timeout(t *testing.T) {
+	calls := stubRunCommand(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		// Like exec.CommandContext, a hanging command is killed when the
+		// context expires
+		<-ctx.Done()
+		return nil, fmt.Errorf("signal: killed")
+	})
+	SetTrustTimeout(10 * time.Millisecond)
+
+	start := time.Now()
+	err := runUpdateCommand(rhelUpdateCmd)
+	if !errors.Is(err, errCommandFailed) || !strings.Contains(err.Error(), "timed out after 10ms") {
+		t.Fatalf("runUpdateCommand() error = %v, want a timeout", err)
+	}
+	if *calls != 2 {
+		t.Errorf("command ran %d times, want 2", *calls)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("runUpdateCommand() took %s, want each run cut off after the timeout", elapsed)
+	}
+}
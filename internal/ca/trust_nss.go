@@ -0,0 +1,159 @@
+//go:build linux
+
+package ca
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+)
+
+// NSS databases are used by Firefox and Chromium instead of the system trust
+// store. They are managed with certutil from the NSS tools package.
+const (
+	nssCertutilCmd = "certutil"
+	nssNickname    = "devproxy CA"
+
+	// nssPreviousNickname names the CA replaced by a rotation while it is
+	// still trusted.
+	nssPreviousNickname = "devproxy CA (previous)"
+)
+
+// nssProfileGlobs are the directories below the home directory that may hold
+// NSS databases: the shared database of Chromium and the Firefox profiles,
+// including Firefox installed as a snap.
+var nssProfileGlobs = []string{
+	".pki/nssdb",
+	".mozilla/firefox/*",
+	"snap/firefox/common/.mozilla/firefox/*",
+}
+
+// nssDatabases returns the directories below home holding an NSS database.
+// Only the SQL format (cert9.db) is supported; certutil has read it since
+// NSS 3.12 and Firefox has not written the legacy format since version 58.
+func nssDatabases(home string) []string {
+	var dbs []string
+	for _, pattern := range nssProfileGlobs {
+		matches, _ := filepath.Glob(filepath.Join(home, pattern))
+		for _, dir := range matches {
+			if _, err := os.Stat(filepath.Join(dir, "cert9.db")); err == nil {
+				dbs = append(dbs, dir)
+			}
+		}
+	}
+	return dbs
+}
+
+// nssUser returns the user whose NSS databases are managed. Trust operations
+// run as root, so the user that invoked sudo is preferred.
+func nssUser() (*user.User, error) {
+	if name := os.Getenv("SUDO_USER"); name != "" && os.Geteuid() == 0 {
+		return user.Lookup(name)
+	}
+	return user.Current()
+}
+
+// nssTarget returns the NSS databases of the user, or none if certutil is not
+// installed.
+func nssTarget() (*user.User, []string) {
+	if _, err := exec.LookPath(nssCertutilCmd); err != nil {
+		return nil, nil
+	}
+	u, err := nssUser()
+	if err != nil {
+		return nil, nil
+	}
+	return u, nssDatabases(u.HomeDir)
+}
+
+// certutil runs certutil on the database in dir. When running as root for
+// another user, it runs as that user so the database keeps its owner.
+func certutil(u *user.User, dir string, args ...string) ([]byte, error) {
+	args = append([]string{"-d", "sql:" + dir}, args...)
+	cmd := exec.Command(nssCertutilCmd, args...)
+	if os.Geteuid() == 0 && u.Uid != "0" {
+		cmd = exec.Command("sudo", append([]string{"-u", u.Username, nssCertutilCmd}, args...)...)
+	}
+	return cmd.CombinedOutput()
+}
+
+// installTrustNSS adds the CA certificate to the user's NSS databases,
+// replacing a previously installed devproxy CA.
+func installTrustNSS() error {
+	u, dbs := nssTarget()
+
+	var errs []error
+	for _, dir := range dbs {
+		// Adding under an existing nickname would keep the old certificate
+		_, _ = certutil(u, dir, "-D", "-n", nssNickname)
+		if output, err := certutil(u, dir, "-A", "-t", "C,,", "-n", nssNickname, "-i", CertPath()); err != nil {
+			errs = append(errs, fmt.Errorf("failed to add CA to NSS database %s: %w\n%s", dir, err, output))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// uninstallTrustNSS removes the CA certificate from the user's NSS databases.
+func uninstallTrustNSS() error {
+	u, dbs := nssTarget()
+
+	var errs []error
+	for _, dir := range dbs {
+		output, err := certutil(u, dir, "-D", "-n", nssNickname)
+		if err != nil && !strings.Contains(string(output), "could not find") {
+			errs = append(errs, fmt.Errorf("failed to remove CA from NSS database %s: %w\n%s", dir, err, output))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// installPreviousTrustNSS adds the CA certificate at certPath, replaced by a
+// rotation, to the user's NSS databases.
+func installPreviousTrustNSS(certPath string) error {
+	u, dbs := nssTarget()
+
+	var errs []error
+	for _, dir := range dbs {
+		_, _ = certutil(u, dir, "-D", "-n", nssPreviousNickname)
+		if output, err := certutil(u, dir, "-A", "-t", "C,,", "-n", nssPreviousNickname, "-i", certPath); err != nil {
+			errs = append(errs, fmt.Errorf("failed to add previous CA to NSS database %s: %w\n%s", dir, err, output))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// uninstallPreviousTrustNSS removes the CA certificate replaced by a rotation
+// from the user's NSS databases.
+func uninstallPreviousTrustNSS() error {
+	u, dbs := nssTarget()
+
+	var errs []error
+	for _, dir := range dbs {
+		output, err := certutil(u, dir, "-D", "-n", nssPreviousNickname)
+		if err != nil && !strings.Contains(string(output), "could not find") {
+			errs = append(errs, fmt.Errorf("failed to remove previous CA from NSS database %s: %w\n%s", dir, err, output))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// nssTrustStores returns the trust state of each of the user's NSS databases.
+func nssTrustStores(current *x509.Certificate) []StoreTrust {
+	u, dbs := nssTarget()
+
+	stores := make([]StoreTrust, 0, len(dbs))
+	for _, dir := range dbs {
+		store := StoreTrust{Name: "NSS database " + dir, State: TrustNone}
+		// -L exits non-zero if no certificate has the nickname
+		if installed, err := certutil(u, dir, "-L", "-n", nssNickname, "-a"); err == nil {
+			store.State = trustState(installed, current)
+		}
+		stores = append(stores, store)
+	}
+	return stores
+}
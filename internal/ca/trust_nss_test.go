@@ -0,0 +1,47 @@
+//go:build linux
+
+package ca
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestNSSDatabases(t *testing.T) {
+	home := t.TempDir()
+
+	dbs := []string{
+		filepath.Join(home, ".pki", "nssdb"),
+		filepath.Join(home, ".mozilla", "firefox", "abc123.default-release"),
+		filepath.Join(home, "snap", "firefox", "common", ".mozilla", "firefox", "def456.default"),
+	}
+	for _, dir := range dbs {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cert9.db"), nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Profiles without an SQL database are skipped
+	legacy := filepath.Join(home, ".mozilla", "firefox", "old.default")
+	if err := os.MkdirAll(legacy, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "cert8.db"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".mozilla", "firefox", "profiles.ini"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := nssDatabases(home); !slices.Equal(got, dbs) {
+		t.Errorf("nssDatabases() = %v, want %v", got, dbs)
+	}
+	if got := nssDatabases(t.TempDir()); len(got) != 0 {
+		t.Errorf("expected no databases in empty home, got %v", got)
+	}
+}
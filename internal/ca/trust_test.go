@@ -0,0 +1,123 @@
+package ca
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+func TestTrustState(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+
+	old, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	// Regenerating replaces the CA; the old certificate may linger in the trust store
+	current, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+	other := otherCertPEM(t)
+
+	tests := []struct {
+		name      string
+		installed []byte
+		want      TrustState
+	}{
+		{"nothing installed", nil, TrustNone},
+		{"current installed", current.CertPEM, TrustCurrent},
+		{"old installed", old.CertPEM, TrustStale},
+		{"bundle without devproxy CA", other, TrustNone},
+		{"bundle with old and current", concat(other, old.CertPEM, current.CertPEM), TrustCurrent},
+		{"bundle with old only", concat(other, old.CertPEM), TrustStale},
+		{"garbage", []byte("not a certificate"), TrustNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trustState(tt.installed, current.Certificate); got != tt.want {
+				t.Errorf("trustState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTrustState_String(t *testing.T) {
+	tests := []struct {
+		state TrustState
+		want  string
+	}{
+		{TrustNone, "not trusted"},
+		{TrustStale, "trusted but stale"},
+		{TrustCurrent, "trusted and current"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.state.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCombineTrust(t *testing.T) {
+	tests := []struct {
+		name   string
+		states []TrustState
+		want   TrustState
+	}{
+		{"system only", []TrustState{TrustCurrent}, TrustCurrent},
+		{"all current", []TrustState{TrustCurrent, TrustCurrent, TrustCurrent}, TrustCurrent},
+		{"none anywhere", []TrustState{TrustNone, TrustNone}, TrustNone},
+		{"missing from a browser", []TrustState{TrustCurrent, TrustNone}, TrustStale},
+		{"only in a browser", []TrustState{TrustNone, TrustCurrent}, TrustStale},
+		{"stale in a browser", []TrustState{TrustCurrent, TrustStale}, TrustStale},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := combineTrust(tt.states); got != tt.want {
+				t.Errorf("combineTrust() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// otherCertPEM returns a self-signed certificate that is not a devproxy CA.
+func otherCertPEM(t *testing.T) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Some Other Root"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func concat(parts ...[]byte) []byte {
+	var out []byte
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
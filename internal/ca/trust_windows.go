@@ -0,0 +1,159 @@
+//go:build windows
+
+package ca
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Trust store tools. certutil ships with Windows; PowerShell exports the
+// installed certificates as PEM, which certutil cannot do.
+const (
+	certutilCmd   = "certutil"
+	powerShellCmd = "powershell"
+
+	// windowsRootStore is the Trusted Root Certification Authorities store
+	// of the local machine, which all users and services trust.
+	windowsRootStore = "ROOT"
+
+	// certutil reports these status codes; its messages are localized, so
+	// the codes are matched instead.
+	cryptNotFound = "0x80092004" // CRYPT_E_NOT_FOUND: no certificate matches
+	accessDenied  = "0x80070005" // E_ACCESSDENIED: the prompt is not elevated
+)
+
+// Errors of the trust store operations, telling apart why an update failed.
+var (
+	errNotElevated     = errors.New("administrator privileges required - run from an elevated prompt")
+	errCommandNotFound = errors.New("command not found")
+	errCommandFailed   = errors.New("command failed")
+	errNoSuchAnchor    = errors.New("certificate not in the trust store")
+)
+
+// commandRunner runs a command and returns its combined output.
+type commandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// runCommand runs certutil and PowerShell; tests replace it.
+var runCommand commandRunner = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	return exec.CommandContext(ctx, name, args...).CombinedOutput()
+}
+
+// InstallTrust adds the CA certificate to the local machine's Trusted Root
+// Certification Authorities store. This requires an elevated prompt.
+func InstallTrust() error {
+	certPath := CertPath()
+
+	if !Exists() {
+		return fmt.Errorf("CA certificate not found at %s, run 'devproxy ca generate' first", certPath)
+	}
+
+	switch CheckTrust() {
+	case TrustCurrent:
+		return nil // Already trusted, nothing to do
+	case TrustStale:
+		// Remove the outdated CA so it is not mistaken for the current one
+		if err := UninstallTrust(); err != nil {
+			return err
+		}
+	}
+
+	// -f: replace a certificate that is already in the store
+	if _, err := runTrustCommand(certutilCmd, "-addstore", "-f", windowsRootStore, certPath); err != nil {
+		return fmt.Errorf("failed to add CA to the Windows root store: %w", err)
+	}
+	return nil
+}
+
+// UninstallTrust removes the CA certificate from the local machine's Trusted
+// Root Certification Authorities store. This requires an elevated prompt.
+func UninstallTrust() error {
+	// -delstore deletes the certificates whose subject matches the name
+	_, err := runTrustCommand(certutilCmd, "-delstore", windowsRootStore, commonName())
+	if err != nil && !errors.Is(err, errNoSuchAnchor) {
+		return fmt.Errorf("failed to remove CA from the Windows root store: %w", err)
+	}
+	return nil
+}
+
+// installPreviousTrust keeps the CA certificate at certPath, replaced by a
+// rotation, in the local machine's Trusted Root Certification Authorities
+// store.
+func installPreviousTrust(certPath string) error {
+	if _, err := runTrustCommand(certutilCmd, "-addstore", "-f", windowsRootStore, certPath); err != nil {
+		return fmt.Errorf("failed to add previous CA to the Windows root store: %w", err)
+	}
+	return nil
+}
+
+// uninstallPreviousTrust removes the CA certificate replaced by a rotation
+// from the local machine's Trusted Root Certification Authorities store. It
+// shares the name of the current CA, so it is deleted by its hash.
+func uninstallPreviousTrust(previous *x509.Certificate) error {
+	_, err := runTrustCommand(certutilCmd, "-delstore", windowsRootStore, sha1Fingerprint(previous))
+	if err != nil && !errors.Is(err, errNoSuchAnchor) {
+		return fmt.Errorf("failed to remove previous CA from the Windows root store: %w", err)
+	}
+	return nil
+}
+
+// installedCerts returns the PEM-encoded devproxy CA certificates in the
+// local machine's Trusted Root Certification Authorities store.
+func installedCerts() ([]byte, error) {
+	// Single quotes are escaped by doubling them in PowerShell strings
+	name := strings.ReplaceAll(commonName(), "'", "''")
+	script := fmt.Sprintf(`Get-ChildItem Cert:\LocalMachine\Root | `+
+		`Where-Object { $_.GetNameInfo('SimpleName', $false) -eq '%s' } | `+
+		`ForEach-Object { '-----BEGIN CERTIFICATE-----'; `+
+		`[Convert]::ToBase64String($_.RawData, 'InsertLineBreaks'); `+
+		`'-----END CERTIFICATE-----' }`, name)
+
+	output, err := runTrustCommand(powerShellCmd, "-NoProfile", "-NonInteractive", "-Command", script)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the Windows root store: %w", err)
+	}
+	return output, nil
+}
+
+// runTrustCommand runs name with args within trustCmdTimeout and returns its
+// output, classifying a failure by certutil's status code.
+func runTrustCommand(name string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), trustCmdTimeout)
+	defer cancel()
+
+	output, err := runCommand(ctx, name, args...)
+	switch {
+	case err == nil:
+		return output, nil
+	case errors.Is(err, exec.ErrNotFound):
+		return nil, fmt.Errorf("%s: %w", name, errCommandNotFound)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("%s: %w: timed out after %s", name, errCommandFailed, trustCmdTimeout)
+	case strings.Contains(string(output), cryptNotFound):
+		return nil, fmt.Errorf("%s: %w", name, errNoSuchAnchor)
+	case strings.Contains(string(output), accessDenied):
+		return nil, fmt.Errorf("%s: %w", name, errNotElevated)
+	default:
+		return nil, fmt.Errorf("%s: %w: %w\n%s", name, errCommandFailed, err, output)
+	}
+}
+
+// nssTrustStores returns no stores: NSS databases are only managed on Linux.
+func nssTrustStores(*x509.Certificate) []StoreTrust {
+	return nil
+}
+
+// NeedsSudo returns true since the local machine's root store can only be
+// modified from an elevated prompt.
+func NeedsSudo() bool {
+	return true
+}
+
+// TrustStoreName returns a human-readable name for the trust store.
+func TrustStoreName() string {
+	return "Windows Trusted Root Certification Authorities (local machine)"
+}
@@ -0,0 +1,109 @@
+//go:build windows
+
+package ca
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+// stubRunCommand replaces runCommand with run for the duration of the test
+// and returns the commands that ran, one line each.
+func stubRunCommand(t *testing.T, run commandRunner) *[]string {
+	t.Helper()
+	orig := runCommand
+	t.Cleanup(func() { runCommand = orig })
+
+	var ran []string
+	runCommand = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		ran = append(ran, strings.Join(append([]string{name}, args...), " "))
+		return run(ctx, name, args...)
+	}
+	return &ran
+}
+
+func TestInstallTrust_Windows(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	current, err := Generate()
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	t.Run("not trusted", func(t *testing.T) {
+		ran := stubRunCommand(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return nil, nil // the root store holds no devproxy CA
+		})
+		if err := InstallTrust(); err != nil {
+			t.Fatalf("InstallTrust() error = %v", err)
+		}
+		want := "certutil -addstore -f ROOT " + CertPath()
+		if len(*ran) != 2 || !strings.HasPrefix((*ran)[0], "powershell ") || (*ran)[1] != want {
+			t.Errorf("ran %q, want the store read and %q", *ran, want)
+		}
+	})
+
+	t.Run("already trusted", func(t *testing.T) {
+		ran := stubRunCommand(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return current.CertPEM, nil
+		})
+		if err := InstallTrust(); err != nil {
+			t.Fatalf("InstallTrust() error = %v", err)
+		}
+		if len(*ran) != 1 {
+			t.Errorf("ran %q, want only the store read", *ran)
+		}
+	})
+}
+
+func TestUninstallTrust_Windows(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		wantErr error
+	}{
+		{name: "removed"},
+		{name: "not in the store", output: "CertUtil: -delstore command FAILED: 0x80092004 (-2146885628 CRYPT_E_NOT_FOUND)"},
+		{name: "not elevated", output: "CertUtil: -delstore command FAILED: 0x80070005 (WIN32: 5 ERROR_ACCESS_DENIED)", wantErr: errNotElevated},
+		{name: "failure", output: "CertUtil: -delstore command FAILED: 0x80070002", wantErr: errCommandFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran := stubRunCommand(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+				if tt.output == "" {
+					return nil, nil
+				}
+				return []byte(tt.output), errors.New("exit status 1")
+			})
+
+			err := UninstallTrust()
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("UninstallTrust() error = %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UninstallTrust() error = %v, want %v", err, tt.wantErr)
+			}
+			if want := "certutil -delstore ROOT " + commonName(); len(*ran) != 1 || (*ran)[0] != want {
+				t.Errorf("ran %q, want %q", *ran, want)
+			}
+		})
+	}
+}
+
+func TestRunTrustCommand_NotFound(t *testing.T) {
+	stubRunCommand(t, func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		return nil, &exec.Error{Name: name, Err: exec.ErrNotFound}
+	})
+
+	if _, err := runTrustCommand(certutilCmd, "-store", windowsRootStore); !errors.Is(err, errCommandNotFound) {
+		t.Errorf("runTrustCommand() error = %v, want errCommandNotFound", err)
+	}
+}
@@ -0,0 +1,39 @@
+package cert
+
+import (
+	"crypto/x509"
+	"sort"
+	"time"
+)
+
+// CacheEntry describes a certificate held in the in-memory cache.
+type CacheEntry struct {
+	Domain   string    `json:"domain"`
+	Serial   string    `json:"serial"`
+	DNSNames []string  `json:"dns_names"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// CacheEntries returns the certificates in the in-memory cache, sorted by domain.
+func (m *Manager) CacheEntries() []CacheEntry {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	entries := make([]CacheEntry, 0, len(m.cache))
+	for domain, cert := range m.cache {
+		entry := CacheEntry{Domain: domain}
+		if cert != nil && len(cert.Certificate) > 0 {
+			if x509Cert, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+				entry.Serial = x509Cert.SerialNumber.Text(16)
+				entry.DNSNames = x509Cert.DNSNames
+				entry.NotAfter = x509Cert.NotAfter
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Domain < entries[j].Domain
+	})
+	return entries
+}
@@ -0,0 +1,42 @@
+package cert
+
+import "testing"
+
+func TestCacheEntries(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if entries := m.CacheEntries(); len(entries) != 0 {
+		t.Fatalf("expected empty cache, got %d entries", len(entries))
+	}
+
+	if err := m.EnsureCertificate("web.app.localhost"); err != nil {
+		t.Fatalf("EnsureCertificate() error = %v", err)
+	}
+	if err := m.EnsureExactCertificate("api.app.localhost"); err != nil {
+		t.Fatalf("EnsureExactCertificate() error = %v", err)
+	}
+
+	entries := m.CacheEntries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	// Sorted by domain
+	if entries[0].Domain != "*.app.localhost" || entries[1].Domain != "api.app.localhost" {
+		t.Errorf("unexpected domains: %q, %q", entries[0].Domain, entries[1].Domain)
+	}
+	for _, entry := range entries {
+		if entry.Serial == "" {
+			t.Errorf("expected serial for %s", entry.Domain)
+		}
+		if entry.NotAfter.IsZero() {
+			t.Errorf("expected expiry for %s", entry.Domain)
+		}
+	}
+}
@@ -3,9 +3,12 @@
 package cert
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
@@ -14,29 +17,62 @@ import (
 	"encoding/pem"
 	"errors"
 	"fmt"
+	"maps"
 	"math/big"
+	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/metrics"
 	"github.com/munichmade/devproxy/internal/paths"
+	"golang.org/x/sync/singleflight"
 )
 
 const (
-	// certValidityDays is how long generated certificates are valid.
-	certValidityDays = 30
+	// DefaultValidityDays is how long generated certificates are valid by default.
+	DefaultValidityDays = 30
 
-	// renewBeforeDays is how many days before expiry to renew.
-	renewBeforeDays = 7
+	// DefaultRenewBeforeDays is how many days before expiry certificates are
+	// renewed by default.
+	DefaultRenewBeforeDays = 7
 
 	// certFileSuffix is the file extension for certificate files.
 	certFileSuffix = ".pem"
 
 	// keyFileSuffix is the file extension for key files.
 	keyFileSuffix = "-key.pem"
+
+	// rsaKeyBits is the size of RSA leaf keys.
+	rsaKeyBits = 2048
+
+	// maxDNSNames caps the SANs a certificate collects for the names it was
+	// requested for, so made-up SNI names cannot grow it without bound.
+	maxDNSNames = 100
+
+	// missTTL is how long a key without a certificate on disk is not looked
+	// up on disk again, unless a certificate is issued or the cache changes.
+	missTTL = time.Minute
+
+	// maxMissing caps the keys remembered as not on disk; the record is
+	// emptied when full.
+	maxMissing = 1000
+)
+
+// KeyType is the algorithm of generated certificate keys.
+type KeyType string
+
+const (
+	// KeyTypeECDSA generates ECDSA P-256 keys (the default).
+	KeyTypeECDSA KeyType = "ecdsa"
+
+	// KeyTypeRSA generates RSA 2048 keys, for clients that reject ECDSA
+	// certificates (e.g., old Java keystores).
+	KeyTypeRSA KeyType = "rsa"
 )
 
 var (
@@ -49,9 +85,52 @@ var (
 
 // Manager handles certificate generation and caching.
 type Manager struct {
-	ca    *ca.CA
+	ca    *ca.CA // guarded by mu, since ReloadCA replaces it
+	dir   string // directory of the disk cache
 	mu    sync.RWMutex
 	cache map[string]*tls.Certificate
+
+	// missing holds when keys were last not found on disk, guarded by mu, so
+	// handshakes for them do not read the disk each time
+	missing map[string]time.Time
+
+	// issuing makes concurrent misses for a key share one generation
+	issuing singleflight.Group
+
+	// entrypoints holds the managers returned by ForEntrypoint, guarded by mu
+	entrypoints map[string]*Manager
+
+	// derived is set for entrypoint managers, which do not report the cache
+	// size metric of the default certificates
+	derived bool
+
+	// ipAddresses are added to the SANs of every generated certificate
+	ipAddresses []net.IP
+
+	// reuseKey keeps a domain's private key when its certificate is renewed
+	reuseKey bool
+
+	// wildcardDepth is how many labels below a domain one certificate covers
+	wildcardDepth int
+
+	// keyType is the algorithm of generated keys (empty = KeyTypeECDSA)
+	keyType KeyType
+
+	// validityDays is how long generated certificates are valid
+	validityDays int
+
+	// renewBeforeDays is how many days before expiry a certificate is renewed
+	renewBeforeDays int
+
+	// sniOverrides maps an SNI name to the name whose certificate it is served
+	sniOverrides map[string]string
+
+	statsMu sync.Mutex
+	stats   Stats
+
+	renewMu   sync.Mutex
+	renewStop chan struct{}
+	renewDone chan struct{}
 }
 
 // NewManager creates a new certificate manager.
@@ -68,14 +147,69 @@ func NewManager() (*Manager, error) {
 	}
 
 	return &Manager{
-		ca:    rootCA,
-		cache: make(map[string]*tls.Certificate),
+		ca:              rootCA,
+		dir:             paths.CertsDir(),
+		cache:           make(map[string]*tls.Certificate),
+		validityDays:    DefaultValidityDays,
+		renewBeforeDays: DefaultRenewBeforeDays,
 	}, nil
 }
 
+// SetReuseKey makes renewals reuse the private key stored for a domain, so
+// its public key (and SPKI pin) stays the same across renewals.
+// It must be called before the manager is used.
+func (m *Manager) SetReuseKey(reuse bool) {
+	m.reuseKey = reuse
+}
+
+// SetWildcardDepth makes one certificate cover subdomains up to depth labels
+// below a domain, e.g. with depth 2 both api.example.localhost and
+// v1.api.example.localhost are served the *.example.localhost certificate.
+// Its SANs grow to include *.api.example.localhost as such names are seen,
+// since a wildcard only matches a single label. Depth 0 or 1 issues a
+// certificate per parent domain. It must be called before the manager is used.
+func (m *Manager) SetWildcardDepth(depth int) {
+	m.wildcardDepth = depth
+}
+
+// SetKeyType selects the algorithm of generated keys. Certificates of each
+// key type are stored under their own file names, so switching does not
+// serve certificates cached with the other type. It must be called before
+// the manager is used.
+func (m *Manager) SetKeyType(keyType KeyType) {
+	m.keyType = keyType
+}
+
+// SetValidity sets how many days generated certificates are valid and how
+// many days before expiry cached ones are renewed. Zero keeps the default.
+// renewBeforeDays must be less than validityDays, or every certificate is
+// renewed on use. It must be called before the manager is used.
+func (m *Manager) SetValidity(validityDays, renewBeforeDays int) {
+	if validityDays > 0 {
+		m.validityDays = validityDays
+	}
+	if renewBeforeDays > 0 {
+		m.renewBeforeDays = renewBeforeDays
+	}
+}
+
+// SetSNIOverrides makes GetCertificate serve, for an SNI name in overrides,
+// the certificate it would serve for the mapped name instead, e.g. the
+// app.localhost certificate for evil.localhost. The certificate need not be
+// valid for the SNI name, which is the point: it reproduces certificate
+// mismatches for testing. It must be called before the manager is used.
+func (m *Manager) SetSNIOverrides(overrides map[string]string) {
+	m.sniOverrides = make(map[string]string, len(overrides))
+	for sni, target := range overrides {
+		m.sniOverrides[normalizeDomain(sni)] = normalizeDomain(target)
+	}
+}
+
 // GetCertificate returns a certificate for the given domain.
 // This is designed to be used as tls.Config.GetCertificate.
 // It generates wildcard certificates for subdomains (e.g., *.example.localhost).
+// An exact-name certificate created by EnsureExactCertificate takes precedence.
+// SNI overrides are consulted before either.
 func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
 	domain := hello.ServerName
 	if domain == "" {
@@ -83,106 +217,246 @@ func (m *Manager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate,
 	}
 
 	// Normalize domain and determine wildcard base
-	domain = strings.ToLower(domain)
-	wildcardDomain := toWildcard(domain)
+	domain = normalizeDomain(domain)
+	if target, ok := m.sniOverrides[domain]; ok {
+		domain = target
+	}
+	wildcardDomain := toWildcardDepth(domain, m.wildcardDepth)
 
-	// Check memory cache first
-	m.mu.RLock()
-	if cert, ok := m.cache[wildcardDomain]; ok {
-		m.mu.RUnlock()
-		// Check if still valid
-		if isValid(cert) {
+	// Prefer an exact-name certificate if one was requested
+	if wildcardDomain != domain {
+		if cert := m.lookup(domain); cert != nil {
+			metrics.ObserveCertificateRequest("cached")
 			return cert, nil
 		}
-	} else {
-		m.mu.RUnlock()
 	}
 
-	// Try to load from disk
-	cert, err := m.loadFromDisk(wildcardDomain)
-	if err == nil && isValid(cert) {
-		m.mu.Lock()
-		m.cache[wildcardDomain] = cert
-		m.mu.Unlock()
-		return cert, nil
+	cached := m.lookup(wildcardDomain)
+	if cached != nil && covers(cached, domain) {
+		metrics.ObserveCertificateRequest("cached")
+		return cached, nil
 	}
 
-	// Generate new certificate
-	cert, err = m.generate(wildcardDomain, domain)
+	cert, err := m.issue(wildcardDomain, domain)
 	if err != nil {
+		metrics.ObserveCertificateRequest("error")
 		return nil, err
 	}
-
-	// Cache in memory
-	m.mu.Lock()
-	m.cache[wildcardDomain] = cert
-	m.mu.Unlock()
-
+	metrics.ObserveCertificateRequest("generated")
 	return cert, nil
 }
 
 // EnsureCertificate proactively generates or loads a certificate for the given domain.
 // This can be called when a new route is registered to pre-warm the certificate cache.
-// Returns nil error if the certificate is already valid and cached.
+// Subdomains are covered by a wildcard certificate (e.g., api.example.localhost
+// uses *.example.localhost). Returns nil error if the certificate is already valid and cached.
 func (m *Manager) EnsureCertificate(domain string) error {
 	if domain == "" {
 		return ErrInvalidDomain
 	}
 
 	// Normalize domain and determine wildcard base
-	domain = strings.ToLower(domain)
-	wildcardDomain := toWildcard(domain)
+	domain = normalizeDomain(domain)
+	return m.ensure(toWildcardDepth(domain, m.wildcardDepth), domain)
+}
+
+// Prewarm ensures certificates for hosts, so their first TLS handshake does
+// not wait for one to be generated. Hosts sharing a wildcard certificate are
+// ensured once. It returns how many certificates were generated and the
+// errors of hosts that failed.
+func (m *Manager) Prewarm(hosts []string) (int, error) {
+	seen := make(map[string]bool)
+	var generated int
+	var errs []error
+	for _, host := range hosts {
+		if host == "" {
+			continue
+		}
+		domain := normalizeDomain(host)
+		key := toWildcardDepth(domain, m.wildcardDepth)
+		if seen[key] && m.wildcardDepth <= 1 {
+			continue // The certificate for key covers all its hosts
+		}
+		seen[key] = true
+
+		if cached := m.lookup(key); cached != nil && covers(cached, domain) {
+			continue
+		}
+		if err := m.ensure(key, domain); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		generated++
+	}
+	return generated, errors.Join(errs...)
+}
+
+// EnsureExactCertificate generates or loads a certificate issued for exactly the
+// given domain, without converting subdomains to a wildcard.
+// Returns nil error if the certificate is already valid and cached.
+func (m *Manager) EnsureExactCertificate(domain string) error {
+	if domain == "" {
+		return ErrInvalidDomain
+	}
+
+	domain = normalizeDomain(domain)
+	return m.ensure(domain, domain)
+}
+
+// ensure loads the certificate cached under key or generates one covering domain.
+func (m *Manager) ensure(key, domain string) error {
+	cached := m.lookup(key)
+	if cached != nil && covers(cached, domain) {
+		return nil // Already cached and valid
+	}
+
+	if _, err := m.issue(key, domain); err != nil {
+		return fmt.Errorf("failed to generate certificate for %s: %w", domain, err)
+	}
+	return nil
+}
+
+// issue generates and caches a certificate for key covering domain, keeping
+// the names the cached one covers. Concurrent calls for a key share one
+// generation; a caller whose domain the shared certificate does not cover
+// (another name below the same key) issues once more, now with both names.
+// IP addresses are refused, since certificates cover them only as IP SANs.
+func (m *Manager) issue(key, domain string) (*tls.Certificate, error) {
+	if net.ParseIP(domain) != nil {
+		return nil, fmt.Errorf("%w: %s is an IP address", ErrInvalidDomain, domain)
+	}
+
+	for range 2 {
+		v, err, _ := m.issuing.Do(key, func() (any, error) {
+			// A call that finished while this one waited may have issued it
+			cached := m.lookup(key)
+			if cached != nil && covers(cached, domain) {
+				return cached, nil
+			}
+
+			cert, err := m.generate(key, domain, m.extraNames(cached, domain)...)
+			if err != nil {
+				return nil, err
+			}
+			m.store(key, cert)
+			return cert, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if cert := v.(*tls.Certificate); covers(cert, domain) {
+			return cert, nil
+		}
+	}
+	return nil, fmt.Errorf("certificate for %s does not cover %s", key, domain)
+}
 
+// lookup returns a valid certificate for key from the memory or disk cache.
+// Returns nil if no valid certificate is cached. A key not found on disk is
+// not looked up there again for missTTL.
+func (m *Manager) lookup(key string) *tls.Certificate {
 	// Check memory cache first
 	m.mu.RLock()
-	if cert, ok := m.cache[wildcardDomain]; ok {
-		m.mu.RUnlock()
-		if isValid(cert) {
-			return nil // Already cached and valid
-		}
-	} else {
-		m.mu.RUnlock()
+	cert, ok := m.cache[key]
+	missed, isMissing := m.missing[key]
+	m.mu.RUnlock()
+	if ok && m.isValid(cert) {
+		return cert
+	}
+	if !ok && isMissing && time.Since(missed) < missTTL {
+		return nil
 	}
 
 	// Try to load from disk
-	cert, err := m.loadFromDisk(wildcardDomain)
-	if err == nil && isValid(cert) {
-		m.mu.Lock()
-		m.cache[wildcardDomain] = cert
-		m.mu.Unlock()
+	cert, err := m.loadFromDisk(key)
+	if err != nil || !m.isValid(cert) {
+		if !ok {
+			m.mu.Lock()
+			if m.missing == nil || len(m.missing) >= maxMissing {
+				m.missing = make(map[string]time.Time)
+			}
+			m.missing[key] = time.Now()
+			m.mu.Unlock()
+		}
 		return nil
 	}
 
-	// Generate new certificate
-	cert, err = m.generate(wildcardDomain, domain)
-	if err != nil {
-		return fmt.Errorf("failed to generate certificate for %s: %w", domain, err)
+	m.store(key, cert)
+	return cert
+}
+
+// store caches cert in memory under key.
+func (m *Manager) store(key string, cert *tls.Certificate) {
+	m.mu.Lock()
+	m.cache[key] = cert
+	delete(m.missing, key)
+	if !m.derived {
+		metrics.SetCertificateCacheSize(len(m.cache))
 	}
+	m.mu.Unlock()
+}
 
-	// Cache in memory
+// Rescan drops the record of keys not found on disk, so certificates
+// written by another process (e.g., "devproxy domain add") are picked up.
+func (m *Manager) Rescan() {
 	m.mu.Lock()
-	m.cache[wildcardDomain] = cert
+	m.missing = nil
+	derived := slices.Collect(maps.Values(m.entrypoints))
 	m.mu.Unlock()
+	for _, d := range derived {
+		d.Rescan()
+	}
+}
 
-	return nil
+// extraNames returns the SANs a certificate for domain needs besides its key
+// and the domain itself: the names cached already covers, and the wildcard
+// for domain's parent if that is below the certificate's key. Once cached
+// holds maxDNSNames names they are dropped, and clients of those names get a
+// new certificate again when they next connect.
+func (m *Manager) extraNames(cached *tls.Certificate, domain string) []string {
+	var names []string
+	if cached != nil && cached.Leaf != nil && len(cached.Leaf.DNSNames) < maxDNSNames {
+		names = append(names, cached.Leaf.DNSNames...)
+	}
+	if m.wildcardDepth > 1 {
+		if parent := toWildcard(domain); parent != domain {
+			names = append(names, parent)
+		}
+	}
+	return names
 }
 
-// generate creates a new certificate for the given domain.
-func (m *Manager) generate(wildcardDomain, originalDomain string) (*tls.Certificate, error) {
-	// Generate ECDSA P-256 private key (faster than P-384 for leaf certs)
-	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate private key: %w", err)
+// generate creates a new certificate for the given domain, adding extraNames
+// to its SANs. With key reuse enabled, the domain's stored private key is
+// signed again.
+func (m *Manager) generate(wildcardDomain, originalDomain string, extraNames ...string) (*tls.Certificate, error) {
+	start := time.Now()
+
+	var privateKey crypto.Signer
+	if m.reuseKey {
+		privateKey = m.loadKeyFromDisk(wildcardDomain)
+	}
+	if privateKey == nil {
+		var err error
+		privateKey, err = m.generateKey()
+		if err != nil {
+			return nil, m.generationFailed(FailureKey, fmt.Errorf("failed to generate private key: %w", err))
+		}
 	}
 
 	// Generate serial number
 	serialNumber, err := generateSerialNumber()
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+		return nil, m.generationFailed(FailureKey, fmt.Errorf("failed to generate serial number: %w", err))
 	}
 
 	// Build DNS names for SAN
 	dnsNames := buildDNSNames(wildcardDomain, originalDomain)
+	if len(extraNames) > 0 {
+		dnsNames = append(dnsNames, extraNames...)
+		slices.Sort(dnsNames)
+		dnsNames = slices.Compact(dnsNames)
+	}
 
 	// Create certificate template
 	now := time.Now()
@@ -193,23 +467,25 @@ func (m *Manager) generate(wildcardDomain, originalDomain string) (*tls.Certific
 			CommonName:   wildcardDomain,
 		},
 		NotBefore:             now,
-		NotAfter:              now.AddDate(0, 0, certValidityDays),
+		NotAfter:              now.AddDate(0, 0, m.validityDays),
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
 		DNSNames:              dnsNames,
+		IPAddresses:           m.ipAddresses,
 	}
 
 	// Sign with CA
+	issuer := m.issuer()
 	certDER, err := x509.CreateCertificate(
 		rand.Reader,
 		template,
-		m.ca.Certificate,
-		&privateKey.PublicKey,
-		m.ca.PrivateKey,
+		issuer.Certificate,
+		privateKey.Public(),
+		issuer.PrivateKey,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create certificate: %w", err)
+		return nil, m.generationFailed(FailureCA, fmt.Errorf("failed to create certificate: %w", err))
 	}
 
 	// Encode to PEM
@@ -218,35 +494,75 @@ func (m *Manager) generate(wildcardDomain, originalDomain string) (*tls.Certific
 		Bytes: certDER,
 	})
 
-	keyDER, err := x509.MarshalECPrivateKey(privateKey)
+	keyPEM, err := encodeKey(privateKey)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal private key: %w", err)
+		return nil, m.generationFailed(FailureKey, fmt.Errorf("failed to marshal private key: %w", err))
 	}
-	keyPEM := pem.EncodeToMemory(&pem.Block{
-		Type:  "EC PRIVATE KEY",
-		Bytes: keyDER,
-	})
 
 	// Save to disk
 	if err := m.saveToDisk(wildcardDomain, certPEM, keyPEM); err != nil {
 		// Log but don't fail - we can still use the cert in memory
+		m.generationFailed(FailureDisk, err)
 		fmt.Fprintf(os.Stderr, "warning: failed to cache certificate: %v\n", err)
 	}
 
 	// Create tls.Certificate
 	tlsCert, err := tls.X509KeyPair(certPEM, keyPEM)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create TLS certificate: %w", err)
+		return nil, m.generationFailed(FailureKey, fmt.Errorf("failed to create TLS certificate: %w", err))
 	}
 
+	m.generated(time.Since(start))
 	return &tlsCert, nil
 }
 
+// generateKey generates a private key of the manager's key type.
+func (m *Manager) generateKey() (crypto.Signer, error) {
+	if m.keyType == KeyTypeRSA {
+		return rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	}
+	// P-256 is faster than P-384 for leaf certs
+	return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+}
+
+// encodeKey encodes an ECDSA or RSA private key as PEM.
+func encodeKey(key crypto.Signer) ([]byte, error) {
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}), nil
+	case *ecdsa.PrivateKey:
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{
+			Type:  "EC PRIVATE KEY",
+			Bytes: keyDER,
+		}), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// filename returns the name of the files a certificate for domain is stored
+// in, without suffix. RSA certificates get an "@rsa" suffix, which no domain
+// name contains.
+func (m *Manager) filename(domain string) string {
+	filename := domainToFilename(domain)
+	if m.keyType == KeyTypeRSA {
+		filename += "@rsa"
+	}
+	return filename
+}
+
 // loadFromDisk attempts to load a certificate from the disk cache.
 func (m *Manager) loadFromDisk(wildcardDomain string) (*tls.Certificate, error) {
-	filename := domainToFilename(wildcardDomain)
-	certPath := filepath.Join(paths.CertsDir(), filename+certFileSuffix)
-	keyPath := filepath.Join(paths.CertsDir(), filename+keyFileSuffix)
+	filename := m.filename(wildcardDomain)
+	certPath := filepath.Join(m.dir, filename+certFileSuffix)
+	keyPath := filepath.Join(m.dir, filename+keyFileSuffix)
 
 	certPEM, err := os.ReadFile(certPath)
 	if err != nil {
@@ -266,11 +582,43 @@ func (m *Manager) loadFromDisk(wildcardDomain string) (*tls.Certificate, error)
 	return &tlsCert, nil
 }
 
+// loadKeyFromDisk returns the private key stored for a domain, or nil if
+// there is none or it cannot be used.
+func (m *Manager) loadKeyFromDisk(wildcardDomain string) crypto.Signer {
+	keyPath := filepath.Join(m.dir, m.filename(wildcardDomain)+keyFileSuffix)
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil
+	}
+
+	blockType := "EC PRIVATE KEY"
+	if m.keyType == KeyTypeRSA {
+		blockType = "RSA PRIVATE KEY"
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil || block.Type != blockType {
+		fmt.Fprintf(os.Stderr, "warning: ignoring unusable key %s, generating a new one\n", keyPath)
+		return nil
+	}
+
+	var key crypto.Signer
+	if m.keyType == KeyTypeRSA {
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: ignoring unusable key %s, generating a new one: %v\n", keyPath, err)
+		return nil
+	}
+	return key
+}
+
 // saveToDisk saves a certificate to the disk cache.
 func (m *Manager) saveToDisk(wildcardDomain string, certPEM, keyPEM []byte) error {
-	filename := domainToFilename(wildcardDomain)
-	certPath := filepath.Join(paths.CertsDir(), filename+certFileSuffix)
-	keyPath := filepath.Join(paths.CertsDir(), filename+keyFileSuffix)
+	filename := m.filename(wildcardDomain)
+	certPath := filepath.Join(m.dir, filename+certFileSuffix)
+	keyPath := filepath.Join(m.dir, filename+keyFileSuffix)
 
 	if err := os.WriteFile(certPath, certPEM, 0o644); err != nil {
 		return fmt.Errorf("failed to write certificate: %w", err)
@@ -284,41 +632,60 @@ func (m *Manager) saveToDisk(wildcardDomain string, certPEM, keyPEM []byte) erro
 	return nil
 }
 
+// normalizeDomain lowercases a domain and strips a trailing dot, so "App." and
+// "app" share a certificate.
+func normalizeDomain(domain string) string {
+	return strings.TrimSuffix(strings.ToLower(domain), ".")
+}
+
 // toWildcard converts a domain to its wildcard form.
 // e.g., "api.example.localhost" -> "*.example.localhost"
 // e.g., "example.localhost" -> "example.localhost" (no wildcard for TLD+1)
+// e.g., "app" -> "app" (single-label names always get an exact certificate)
 func toWildcard(domain string) string {
 	parts := strings.Split(domain, ".")
 	if len(parts) <= 2 {
-		// e.g., "example.localhost" - no wildcard
+		// e.g., "example.localhost" or "app" - no wildcard, since clients
+		// reject wildcards directly below a single label (e.g., "*.localhost")
 		return domain
 	}
 	// e.g., "api.example.localhost" -> "*.example.localhost"
 	return "*." + strings.Join(parts[1:], ".")
 }
 
+// toWildcardDepth converts a domain to the wildcard of the domain depth
+// labels above it, so one certificate covers that many subdomain levels.
+// Like toWildcard, it never goes above a two-label domain.
+// e.g., depth 2: "v1.api.example.localhost" -> "*.example.localhost"
+// e.g., depth 2: "a.v1.api.example.localhost" -> "*.api.example.localhost"
+func toWildcardDepth(domain string, depth int) string {
+	parts := strings.Split(domain, ".")
+	if depth <= 1 || len(parts) <= 2 {
+		return toWildcard(domain)
+	}
+	root := max(2, len(parts)-depth)
+	return "*." + strings.Join(parts[len(parts)-root:], ".")
+}
+
+// covers reports whether cert is valid for domain.
+func covers(cert *tls.Certificate, domain string) bool {
+	return cert.Leaf == nil || cert.Leaf.VerifyHostname(domain) == nil
+}
+
 // buildDNSNames creates the list of DNS names for the certificate SAN.
+// The names are sorted and de-duplicated so the same inputs always produce
+// the same SAN list.
 func buildDNSNames(wildcardDomain, originalDomain string) []string {
-	names := make(map[string]bool)
-
-	// Add the wildcard domain
-	names[wildcardDomain] = true
+	// Add the wildcard domain and the original requested domain
+	names := []string{wildcardDomain, originalDomain}
 
 	// Add the base domain (without wildcard prefix)
 	if strings.HasPrefix(wildcardDomain, "*.") {
-		baseDomain := wildcardDomain[2:]
-		names[baseDomain] = true
+		names = append(names, wildcardDomain[2:])
 	}
 
-	// Add the original requested domain
-	names[originalDomain] = true
-
-	// Convert to slice
-	result := make([]string, 0, len(names))
-	for name := range names {
-		result = append(result, name)
-	}
-	return result
+	slices.Sort(names)
+	return slices.Compact(names)
 }
 
 // domainToFilename converts a domain to a safe filename.
@@ -336,8 +703,26 @@ func domainToFilename(domain string) string {
 	return safe
 }
 
-// isValid checks if a certificate is still valid and not expiring soon.
-func isValid(cert *tls.Certificate) bool {
+// issuer returns the CA signing the manager's certificates.
+func (m *Manager) issuer() *ca.CA {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.ca
+}
+
+// issuedBy reports whether cert was signed by issuer. The key identifiers
+// are compared when both are set, which is much cheaper than verifying the
+// signature on every handshake.
+func issuedBy(cert *x509.Certificate, issuer *ca.CA) bool {
+	if len(cert.AuthorityKeyId) > 0 && len(issuer.Certificate.SubjectKeyId) > 0 {
+		return bytes.Equal(cert.AuthorityKeyId, issuer.Certificate.SubjectKeyId)
+	}
+	return cert.CheckSignatureFrom(issuer.Certificate) == nil
+}
+
+// isValid checks if a certificate was issued by the current CA, is still
+// valid and not within the renewal window.
+func (m *Manager) isValid(cert *tls.Certificate) bool {
 	if cert == nil || len(cert.Certificate) == 0 {
 		return false
 	}
@@ -347,8 +732,13 @@ func isValid(cert *tls.Certificate) bool {
 		return false
 	}
 
+	// Certificates of a CA replaced by a rotation are reissued
+	if !issuedBy(x509Cert, m.issuer()) {
+		return false
+	}
+
 	// Check if expired or expiring soon
-	renewTime := x509Cert.NotAfter.AddDate(0, 0, -renewBeforeDays)
+	renewTime := x509Cert.NotAfter.AddDate(0, 0, -m.renewBeforeDays)
 	return time.Now().Before(renewTime)
 }
 
@@ -358,23 +748,34 @@ func generateSerialNumber() (*big.Int, error) {
 	return rand.Int(rand.Reader, serialNumberLimit)
 }
 
-// ClearCache removes all cached certificates from memory and disk.
+// ClearCache removes all cached certificates from memory and disk, including
+// those of entrypoint managers.
 func (m *Manager) ClearCache() error {
 	m.mu.Lock()
 	m.cache = make(map[string]*tls.Certificate)
+	m.missing = nil
+	if !m.derived {
+		metrics.SetCertificateCacheSize(0)
+	}
+	derived := slices.Collect(maps.Values(m.entrypoints))
 	m.mu.Unlock()
 
 	// Remove all files in certs directory
-	entries, err := os.ReadDir(paths.CertsDir())
+	entries, err := os.ReadDir(m.dir)
 	if err != nil {
 		return err
 	}
 
 	for _, entry := range entries {
 		if !entry.IsDir() {
-			os.Remove(filepath.Join(paths.CertsDir(), entry.Name()))
+			os.Remove(filepath.Join(m.dir, entry.Name()))
 		}
 	}
 
+	for _, d := range derived {
+		if err := d.ClearCache(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -1,13 +1,26 @@
 package cert
 
 import (
+	"bytes"
+	"cmp"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/metrics"
 	"github.com/munichmade/devproxy/internal/paths"
 )
 
@@ -152,6 +165,348 @@ func TestGetCertificate(t *testing.T) {
 	}
 }
 
+func TestGetCertificate_SingleLabel(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "app"})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+
+	x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+
+	if x509Cert.Subject.CommonName != "app" {
+		t.Errorf("CommonName = %q, want %q", x509Cert.Subject.CommonName, "app")
+	}
+	if len(x509Cert.DNSNames) != 1 || x509Cert.DNSNames[0] != "app" {
+		t.Errorf("DNSNames = %v, want [app]", x509Cert.DNSNames)
+	}
+
+	caData, _ := ca.Load()
+	roots := x509.NewCertPool()
+	roots.AddCert(caData.Certificate)
+	if _, err := x509Cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: "app"}); err != nil {
+		t.Errorf("certificate verification for app failed: %v", err)
+	}
+
+	// Case and trailing dot variants share the certificate
+	again, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "APP."})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	if again != cert {
+		t.Error("expected APP. to reuse the certificate for app")
+	}
+}
+
+func TestGetCertificate_IPAddress(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	for _, ip := range []string{"127.0.0.1", "::1"} {
+		t.Run(ip, func(t *testing.T) {
+			done := make(chan error, 1)
+			go func() {
+				_, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: ip})
+				done <- err
+			}()
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, ErrInvalidDomain) {
+					t.Errorf("GetCertificate() error = %v, want ErrInvalidDomain", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("GetCertificate() did not return for an IP address")
+			}
+		})
+	}
+
+	if entries, _ := os.ReadDir(paths.CertsDir()); len(entries) != 0 {
+		t.Errorf("expected no certificates on disk, got %d files", len(entries))
+	}
+}
+
+func TestGetCertificate_WildcardDepth(t *testing.T) {
+	names := []string{"a.b.example.localhost", "api.example.localhost", "v1.api.example.localhost", "c.d.example.localhost"}
+
+	tests := []struct {
+		name      string
+		depth     int
+		wantCerts int // distinct certificates served for names
+	}{
+		{name: "certificate per parent domain by default", depth: 0, wantCerts: 4},
+		{name: "depth 1 matches the default", depth: 1, wantCerts: 4},
+		{name: "depth 2 shares one certificate", depth: 2, wantCerts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanup := setupTestEnv(t)
+			defer cleanup()
+
+			m, err := NewManager()
+			if err != nil {
+				t.Fatalf("NewManager() error = %v", err)
+			}
+			m.SetWildcardDepth(tt.depth)
+
+			caData, _ := ca.Load()
+			roots := x509.NewCertPool()
+			roots.AddCert(caData.Certificate)
+
+			commonNames := make(map[string]bool)
+			for _, name := range names {
+				cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+				if err != nil {
+					t.Fatalf("GetCertificate(%s) error = %v", name, err)
+				}
+				x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+				if err != nil {
+					t.Fatalf("failed to parse certificate: %v", err)
+				}
+				if _, err := x509Cert.Verify(x509.VerifyOptions{Roots: roots, DNSName: name}); err != nil {
+					t.Errorf("certificate verification for %s failed: %v", name, err)
+				}
+				commonNames[x509Cert.Subject.CommonName] = true
+			}
+			if len(commonNames) != tt.wantCerts {
+				t.Errorf("expected %d certificates, got %v", tt.wantCerts, commonNames)
+			}
+
+			// Names requested earlier stay covered after the certificate grows
+			for _, name := range names {
+				cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+				if err != nil {
+					t.Fatalf("GetCertificate(%s) error = %v", name, err)
+				}
+				if err := cert.Leaf.VerifyHostname(name); err != nil {
+					t.Errorf("expected %s to remain covered: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestGetCertificate_Concurrent(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	m.SetWildcardDepth(2)
+
+	// Handshakes for many names below one key, several at a time per name
+	names := []string{"a.b.example.localhost", "c.d.example.localhost", "e.f.example.localhost", "api.example.localhost"}
+	const perName = 8
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(names)*perName)
+	for _, name := range names {
+		for range perName {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+				if err == nil {
+					err = cert.Leaf.VerifyHostname(name)
+				}
+				errs <- err
+			}()
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("GetCertificate() error = %v", err)
+		}
+	}
+
+	if generated := m.Stats().Generated; generated > uint64(len(names)) {
+		t.Errorf("expected at most %d generations, got %d", len(names), generated)
+	}
+
+	// The certificate cached last covers every name requested
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: names[0]})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	for _, name := range names {
+		if err := cert.Leaf.VerifyHostname(name); err != nil {
+			t.Errorf("expected %s to be covered: %v", name, err)
+		}
+	}
+}
+
+func TestGetCertificate_MaxDNSNames(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	m.SetWildcardDepth(2)
+
+	for i := range maxDNSNames {
+		name := fmt.Sprintf("x.n%d.example.localhost", i)
+		cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+		if err != nil {
+			t.Fatalf("GetCertificate(%s) error = %v", name, err)
+		}
+		if n := len(cert.Leaf.DNSNames); n > maxDNSNames+3 {
+			t.Fatalf("certificate has %d SANs, want at most %d", n, maxDNSNames+3)
+		}
+	}
+}
+
+func TestGetCertificate_SNIOverrides(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	m.SetSNIOverrides(map[string]string{"Evil.localhost": "app.localhost"})
+
+	app, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.localhost"})
+	if err != nil {
+		t.Fatalf("GetCertificate(app.localhost) error = %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		serverName   string
+		wantAppCert  bool
+		wantMismatch bool
+	}{
+		{name: "mapped SNI is served the target's certificate", serverName: "evil.localhost", wantAppCert: true, wantMismatch: true},
+		{name: "mapping ignores case and trailing dot", serverName: "EVIL.localhost.", wantAppCert: true, wantMismatch: true},
+		{name: "target is served its own certificate", serverName: "app.localhost", wantAppCert: true},
+		{name: "other names are generated normally", serverName: "other.localhost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: tt.serverName})
+			if err != nil {
+				t.Fatalf("GetCertificate(%s) error = %v", tt.serverName, err)
+			}
+			if got := cert == app; got != tt.wantAppCert {
+				t.Errorf("served app.localhost certificate = %v, want %v", got, tt.wantAppCert)
+			}
+			mismatch := cert.Leaf.VerifyHostname(normalizeDomain(tt.serverName)) != nil
+			if mismatch != tt.wantMismatch {
+				t.Errorf("certificate mismatch = %v, want %v", mismatch, tt.wantMismatch)
+			}
+		})
+	}
+}
+
+func TestPrewarm(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	hosts := []string{"app.localhost", "api.shop.localhost", "WEB.shop.localhost.", "*.docs.localhost", "db.localhost"}
+	generated, err := m.Prewarm(hosts)
+	if err != nil {
+		t.Fatalf("Prewarm() error = %v", err)
+	}
+	if generated != 4 {
+		t.Errorf("expected 4 certificates, got %d", generated)
+	}
+
+	// Every host has a certificate on disk, found by a fresh manager
+	fresh, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	for _, host := range hosts {
+		key := toWildcard(normalizeDomain(host))
+		cert := fresh.lookup(key)
+		if cert == nil || !covers(cert, normalizeDomain(host)) {
+			t.Errorf("expected a cached certificate covering %s", host)
+		}
+	}
+
+	// Certificates already cached are not generated again
+	if generated, err := m.Prewarm(hosts); err != nil || generated != 0 {
+		t.Errorf("expected second Prewarm() to generate nothing, got %d, %v", generated, err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.localhost"}); err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	stats := m.Stats()
+	if stats.Generated != 1 || stats.GenerationTime <= 0 {
+		t.Errorf("expected one timed generation, got %+v", stats)
+	}
+
+	// A CA key that does not match the CA certificate cannot sign
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	m.ca.PrivateKey = otherKey
+
+	if _, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "api.localhost"}); err == nil {
+		t.Fatal("expected GetCertificate() to fail with an unusable CA key")
+	}
+	stats = m.Stats()
+	if stats.Generated != 1 {
+		t.Errorf("expected failed generation not to count as generated, got %d", stats.Generated)
+	}
+	if stats.Failures[FailureCA] != 1 {
+		t.Errorf("expected one %s failure, got %v", FailureCA, stats.Failures)
+	}
+
+	var b strings.Builder
+	if _, err := metrics.Default.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	for _, want := range []string{
+		`devproxy_certificate_generation_failures_total{reason="ca"}`,
+		"devproxy_certificate_generation_duration_seconds_count",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("expected metrics to contain %s", want)
+		}
+	}
+}
+
 func TestGetCertificateCaching(t *testing.T) {
 	cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -226,6 +581,47 @@ func TestGetCertificateDiskCache(t *testing.T) {
 	}
 }
 
+func TestGetCertificate_RemembersDiskMisses(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	hello := &tls.ClientHelloInfo{ServerName: "api.example.localhost"}
+	if _, err := m.GetCertificate(hello); err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+
+	// Another process, like "devproxy domain add --exact", writes an exact certificate
+	other, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if err := other.EnsureExactCertificate("api.example.localhost"); err != nil {
+		t.Fatalf("EnsureExactCertificate() error = %v", err)
+	}
+
+	cert, err := m.GetCertificate(hello)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	if cn := cert.Leaf.Subject.CommonName; cn != "*.example.localhost" {
+		t.Errorf("expected the disk miss to be remembered and the wildcard served, got %s", cn)
+	}
+
+	// Rescan makes the manager look at the disk again
+	m.Rescan()
+	cert, err = m.GetCertificate(hello)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	if cn := cert.Leaf.Subject.CommonName; cn != "api.example.localhost" {
+		t.Errorf("expected the exact certificate after Rescan, got %s", cn)
+	}
+}
+
 func TestCertificateValidity(t *testing.T) {
 	cleanup := setupTestEnv(t)
 	defer cleanup()
@@ -252,17 +648,173 @@ func TestCertificateValidity(t *testing.T) {
 		t.Error("certificate NotBefore is in the future")
 	}
 
-	expectedExpiry := now.AddDate(0, 0, certValidityDays)
+	expectedExpiry := now.AddDate(0, 0, DefaultValidityDays)
 	if x509Cert.NotAfter.Before(now) {
 		t.Error("certificate is already expired")
 	}
 
-	// Should expire within certValidityDays (+/- 1 day for timing)
+	// Should expire within DefaultValidityDays (+/- 1 day for timing)
 	if x509Cert.NotAfter.After(expectedExpiry.AddDate(0, 0, 1)) {
 		t.Errorf("certificate expires too late: %v", x509Cert.NotAfter)
 	}
 }
 
+func TestSetValidity(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	shortLived, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	shortLived.SetValidity(2, 1)
+
+	cert, err := shortLived.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.localhost"})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	if days := time.Until(cert.Leaf.NotAfter).Hours() / 24; days < 1.9 || days > 2 {
+		t.Errorf("expected a certificate valid for 2 days, got %.2f", days)
+	}
+	if shortLived.lookup("app.localhost") == nil {
+		t.Error("expected certificate outside the 1 day renewal window to be served")
+	}
+
+	// With the default 7 day window, the 2 day certificate is due for renewal
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if m.lookup("app.localhost") != nil {
+		t.Error("expected certificate inside the renewal window not to be served")
+	}
+	renewed, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.localhost"})
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	if !renewed.Leaf.NotAfter.After(cert.Leaf.NotAfter.AddDate(0, 0, 7)) {
+		t.Errorf("expected renewed certificate to be valid for %d days, expires %v", DefaultValidityDays, renewed.Leaf.NotAfter)
+	}
+}
+
+func TestGetCertificate_KeyType(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	caData, _ := ca.Load()
+	roots := x509.NewCertPool()
+	roots.AddCert(caData.Certificate)
+
+	serials := make(map[KeyType]string)
+	for _, tt := range []struct {
+		keyType KeyType
+		wantAlg x509.PublicKeyAlgorithm
+	}{
+		{keyType: "", wantAlg: x509.ECDSA},
+		{keyType: KeyTypeRSA, wantAlg: x509.RSA},
+		{keyType: KeyTypeECDSA, wantAlg: x509.ECDSA},
+	} {
+		t.Run(string(tt.keyType), func(t *testing.T) {
+			// A fresh manager on the same certs directory, as after a restart
+			m, err := NewManager()
+			if err != nil {
+				t.Fatalf("NewManager() error = %v", err)
+			}
+			m.SetKeyType(tt.keyType)
+			m.SetReuseKey(true)
+
+			cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "legacy.example.localhost"})
+			if err != nil {
+				t.Fatalf("GetCertificate() error = %v", err)
+			}
+			if cert.Leaf.PublicKeyAlgorithm != tt.wantAlg {
+				t.Errorf("public key algorithm = %v, want %v", cert.Leaf.PublicKeyAlgorithm, tt.wantAlg)
+			}
+			if key, ok := cert.Leaf.PublicKey.(*rsa.PublicKey); ok && key.N.BitLen() != rsaKeyBits {
+				t.Errorf("RSA key has %d bits, want %d", key.N.BitLen(), rsaKeyBits)
+			}
+			if _, err := cert.Leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "legacy.example.localhost"}); err != nil {
+				t.Errorf("certificate verification failed: %v", err)
+			}
+
+			// Each key type keeps its own cached certificate
+			serial := cert.Leaf.SerialNumber.String()
+			keyType := cmp.Or(tt.keyType, KeyTypeECDSA)
+			if previous, ok := serials[keyType]; ok && previous != serial {
+				t.Errorf("expected the cached %s certificate to be served again", keyType)
+			}
+			serials[keyType] = serial
+		})
+	}
+
+	if serials[KeyTypeECDSA] == serials[KeyTypeRSA] {
+		t.Error("expected RSA and ECDSA certificates to be cached separately")
+	}
+}
+
+func TestGenerate_ReuseKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		reuseKey   bool
+		wantSame   bool
+		corruptKey bool
+	}{
+		{name: "fresh key by default", reuseKey: false, wantSame: false},
+		{name: "reuses stored key", reuseKey: true, wantSame: true},
+		{name: "unusable stored key is replaced", reuseKey: true, corruptKey: true, wantSame: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanup := setupTestEnv(t)
+			defer cleanup()
+
+			m, err := NewManager()
+			if err != nil {
+				t.Fatalf("NewManager() error = %v", err)
+			}
+			m.SetReuseKey(tt.reuseKey)
+
+			first, err := m.generate("*.pin.localhost", "api.pin.localhost")
+			if err != nil {
+				t.Fatalf("generate() error = %v", err)
+			}
+			if tt.corruptKey {
+				keyPath := filepath.Join(paths.CertsDir(), domainToFilename("*.pin.localhost")+keyFileSuffix)
+				if err := os.WriteFile(keyPath, []byte("not a key"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Renew the certificate
+			renewed, err := m.generate("*.pin.localhost", "api.pin.localhost")
+			if err != nil {
+				t.Fatalf("generate() renewal error = %v", err)
+			}
+
+			firstLeaf, _ := x509.ParseCertificate(first.Certificate[0])
+			renewedLeaf, _ := x509.ParseCertificate(renewed.Certificate[0])
+			if firstLeaf.SerialNumber.Cmp(renewedLeaf.SerialNumber) == 0 {
+				t.Error("expected renewal to issue a new certificate")
+			}
+
+			same := bytes.Equal(firstLeaf.RawSubjectPublicKeyInfo, renewedLeaf.RawSubjectPublicKeyInfo)
+			if same != tt.wantSame {
+				t.Errorf("public key unchanged = %v, want %v", same, tt.wantSame)
+			}
+
+			// The renewed certificate is stored with its key
+			loaded, err := m.loadFromDisk("*.pin.localhost")
+			if err != nil {
+				t.Fatalf("loadFromDisk() error = %v", err)
+			}
+			if !bytes.Equal(loaded.Certificate[0], renewed.Certificate[0]) {
+				t.Error("renewed certificate not saved to disk")
+			}
+		})
+	}
+}
+
 func TestToWildcard(t *testing.T) {
 	tests := []struct {
 		domain string
@@ -273,6 +825,8 @@ func TestToWildcard(t *testing.T) {
 		{"api.example.localhost", "*.example.localhost"},
 		{"v1.api.example.localhost", "*.api.example.localhost"},
 		{"a.b.c.d.localhost", "*.b.c.d.localhost"},
+		{"app", "app"},
+		{"api.app", "api.app"},
 	}
 
 	for _, tt := range tests {
@@ -285,23 +839,65 @@ func TestToWildcard(t *testing.T) {
 	}
 }
 
-func TestBuildDNSNames(t *testing.T) {
-	names := buildDNSNames("*.example.localhost", "api.example.localhost")
+func TestToWildcardDepth(t *testing.T) {
+	tests := []struct {
+		domain string
+		depth  int
+		want   string
+	}{
+		{"a.b.example.localhost", 0, "*.b.example.localhost"},
+		{"a.b.example.localhost", 1, "*.b.example.localhost"},
+		{"a.b.example.localhost", 2, "*.example.localhost"},
+		{"a.b.example.localhost", 3, "*.example.localhost"},
+		{"x.a.b.example.localhost", 2, "*.b.example.localhost"},
+		{"api.example.localhost", 2, "*.example.localhost"},
+		{"example.localhost", 2, "example.localhost"},
+		{"app", 2, "app"},
+	}
 
-	expected := map[string]bool{
-		"*.example.localhost":   true,
-		"example.localhost":     true,
-		"api.example.localhost": true,
+	for _, tt := range tests {
+		t.Run(tt.domain, func(t *testing.T) {
+			if got := toWildcardDepth(tt.domain, tt.depth); got != tt.want {
+				t.Errorf("toWildcardDepth(%q, %d) = %q, want %q", tt.domain, tt.depth, got, tt.want)
+			}
+		})
 	}
+}
 
-	if len(names) != len(expected) {
-		t.Errorf("got %d names, want %d", len(names), len(expected))
+func TestBuildDNSNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		wildcard string
+		original string
+		want     []string
+	}{
+		{
+			name:     "wildcard with subdomain",
+			wildcard: "*.example.localhost",
+			original: "api.example.localhost",
+			want:     []string{"*.example.localhost", "api.example.localhost", "example.localhost"},
+		},
+		{
+			name:     "original equals base domain",
+			wildcard: "*.example.localhost",
+			original: "example.localhost",
+			want:     []string{"*.example.localhost", "example.localhost"},
+		},
+		{
+			name:     "single-label domain",
+			wildcard: "app",
+			original: "app",
+			want:     []string{"app"},
+		},
 	}
 
-	for _, name := range names {
-		if !expected[name] {
-			t.Errorf("unexpected DNS name: %q", name)
-		}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildDNSNames(tt.wildcard, tt.original)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("buildDNSNames(%q, %q) = %v, want %v", tt.wildcard, tt.original, got, tt.want)
+			}
+		})
 	}
 }
 
@@ -419,3 +1015,73 @@ func TestEnsureCertificate(t *testing.T) {
 		}
 	})
 }
+
+func TestEnsureExactCertificate(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+
+	t.Run("issues certificate for exact name", func(t *testing.T) {
+		if err := m.EnsureExactCertificate("API.Example.localhost"); err != nil {
+			t.Fatalf("EnsureExactCertificate() error = %v", err)
+		}
+
+		hello := &tls.ClientHelloInfo{ServerName: "api.example.localhost"}
+		cert, err := m.GetCertificate(hello)
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+
+		x509Cert, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatalf("failed to parse certificate: %v", err)
+		}
+		if x509Cert.Subject.CommonName != "api.example.localhost" {
+			t.Errorf("CommonName = %q, want %q", x509Cert.Subject.CommonName, "api.example.localhost")
+		}
+		if len(x509Cert.DNSNames) != 1 || x509Cert.DNSNames[0] != "api.example.localhost" {
+			t.Errorf("DNSNames = %v, want [api.example.localhost]", x509Cert.DNSNames)
+		}
+	})
+
+	t.Run("sibling subdomains still use wildcard", func(t *testing.T) {
+		hello := &tls.ClientHelloInfo{ServerName: "web.example.localhost"}
+		cert, err := m.GetCertificate(hello)
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+
+		x509Cert, _ := x509.ParseCertificate(cert.Certificate[0])
+		if x509Cert.Subject.CommonName != "*.example.localhost" {
+			t.Errorf("CommonName = %q, want %q", x509Cert.Subject.CommonName, "*.example.localhost")
+		}
+	})
+
+	t.Run("loads exact certificate from disk", func(t *testing.T) {
+		m2, err := NewManager()
+		if err != nil {
+			t.Fatalf("NewManager() error = %v", err)
+		}
+
+		hello := &tls.ClientHelloInfo{ServerName: "api.example.localhost"}
+		cert, err := m2.GetCertificate(hello)
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+
+		x509Cert, _ := x509.ParseCertificate(cert.Certificate[0])
+		if x509Cert.Subject.CommonName != "api.example.localhost" {
+			t.Errorf("CommonName = %q, want %q", x509Cert.Subject.CommonName, "api.example.localhost")
+		}
+	})
+
+	t.Run("returns error for empty domain", func(t *testing.T) {
+		if err := m.EnsureExactCertificate(""); err == nil {
+			t.Error("expected error for empty domain")
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/munichmade/devproxy/internal/ca"
+)
+
+// policyFilename stores the fingerprint of the policy an entrypoint's cached
+// certificates were issued with.
+const policyFilename = "policy"
+
+// Policy changes the certificates served on one entrypoint, e.g. for an
+// internal entrypoint next to a public-facing one.
+type Policy struct {
+	IPAddresses []net.IP // added to the SANs of every certificate
+	CA          *ca.CA   // issues the certificates instead of the manager's CA (optional)
+}
+
+// IsZero reports whether p changes nothing.
+func (p Policy) IsZero() bool {
+	return len(p.IPAddresses) == 0 && p.CA == nil
+}
+
+// fingerprint identifies the certificates issued under p.
+func (p Policy) fingerprint() string {
+	h := sha256.New()
+	ips := make([]string, 0, len(p.IPAddresses))
+	for _, ip := range p.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	slices.Sort(ips)
+	for _, ip := range ips {
+		fmt.Fprintln(h, ip)
+	}
+	if p.CA != nil {
+		h.Write(p.CA.Certificate.Raw)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ForEntrypoint returns the manager serving certificates on the entrypoint
+// name with policy applied. It has m's settings, caches its certificates
+// apart from m's, and is renewed and cleared with m. A zero policy returns m
+// itself. Calling it again for name replaces the previous manager; cached
+// certificates of a different policy are discarded.
+func (m *Manager) ForEntrypoint(name string, policy Policy) (*Manager, error) {
+	if policy.IsZero() {
+		m.mu.Lock()
+		delete(m.entrypoints, name)
+		m.mu.Unlock()
+		return m, nil
+	}
+
+	dir := filepath.Join(m.dir, "entrypoints", domainToFilename(name))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create certs directory: %w", err)
+	}
+
+	derived := &Manager{
+		ca:              m.issuer(),
+		dir:             dir,
+		cache:           make(map[string]*tls.Certificate),
+		derived:         true,
+		ipAddresses:     policy.IPAddresses,
+		reuseKey:        m.reuseKey,
+		wildcardDepth:   m.wildcardDepth,
+		keyType:         m.keyType,
+		validityDays:    m.validityDays,
+		renewBeforeDays: m.renewBeforeDays,
+		sniOverrides:    m.sniOverrides,
+	}
+	if policy.CA != nil {
+		derived.ca = policy.CA
+	}
+
+	// Certificates issued under another policy lack its SANs or CA
+	fingerprint := []byte(policy.fingerprint())
+	policyPath := filepath.Join(dir, policyFilename)
+	if previous, err := os.ReadFile(policyPath); err != nil || !bytes.Equal(previous, fingerprint) {
+		if err := derived.ClearCache(); err != nil {
+			return nil, fmt.Errorf("failed to clear certificates of entrypoint %s: %w", name, err)
+		}
+		if err := os.WriteFile(policyPath, fingerprint, 0o600); err != nil {
+			return nil, fmt.Errorf("failed to write certificate policy: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	if m.entrypoints == nil {
+		m.entrypoints = make(map[string]*Manager)
+	}
+	m.entrypoints[name] = derived
+	m.mu.Unlock()
+	return derived, nil
+}
@@ -0,0 +1,105 @@
+package cert
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+func TestForEntrypoint(t *testing.T) {
+	// A second CA for the internal entrypoint, generated elsewhere
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	internalCA, err := ca.Generate()
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	defaultCA := m.ca
+
+	if same, err := m.ForEntrypoint("https", Policy{}); err != nil || same != m {
+		t.Fatalf("expected a zero policy to return the manager itself, got %v, %v", same, err)
+	}
+	internalIP := net.ParseIP("10.0.0.5")
+	internal, err := m.ForEntrypoint("internal", Policy{IPAddresses: []net.IP{internalIP}, CA: internalCA})
+	if err != nil {
+		t.Fatalf("ForEntrypoint() error = %v", err)
+	}
+
+	hello := &tls.ClientHelloInfo{ServerName: "api.app.localhost"}
+	public, err := m.GetCertificate(hello)
+	if err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+	private, err := internal.GetCertificate(hello)
+	if err != nil {
+		t.Fatalf("GetCertificate() on internal entrypoint error = %v", err)
+	}
+
+	verify := func(cert *tls.Certificate, issuer *ca.CA) error {
+		roots := x509.NewCertPool()
+		roots.AddCert(issuer.Certificate)
+		_, err := cert.Leaf.Verify(x509.VerifyOptions{Roots: roots, DNSName: "api.app.localhost"})
+		return err
+	}
+	if err := verify(public, defaultCA); err != nil {
+		t.Errorf("expected default certificate to be issued by the devproxy CA: %v", err)
+	}
+	if len(public.Leaf.IPAddresses) != 0 {
+		t.Errorf("expected no IP SANs on the default certificate, got %v", public.Leaf.IPAddresses)
+	}
+	if err := verify(private, internalCA); err != nil {
+		t.Errorf("expected internal certificate to be issued by the internal CA: %v", err)
+	}
+	if len(private.Leaf.IPAddresses) != 1 || !private.Leaf.IPAddresses[0].Equal(internalIP) {
+		t.Errorf("expected internal certificate to include %s, got %v", internalIP, private.Leaf.IPAddresses)
+	}
+
+	// The caches are separate: the default manager keeps serving its certificate
+	if again, _ := m.GetCertificate(hello); again.Leaf.SerialNumber.Cmp(public.Leaf.SerialNumber) != 0 {
+		t.Error("expected the default certificate to stay cached")
+	}
+
+	t.Run("discards certificates of another policy", func(t *testing.T) {
+		otherIP := net.ParseIP("10.0.0.6")
+		replaced, err := m.ForEntrypoint("internal", Policy{IPAddresses: []net.IP{otherIP}, CA: internalCA})
+		if err != nil {
+			t.Fatalf("ForEntrypoint() error = %v", err)
+		}
+		cert, err := replaced.GetCertificate(hello)
+		if err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+		if len(cert.Leaf.IPAddresses) != 1 || !cert.Leaf.IPAddresses[0].Equal(otherIP) {
+			t.Errorf("expected certificate with %s, got %v", otherIP, cert.Leaf.IPAddresses)
+		}
+	})
+
+	t.Run("cleared with the manager", func(t *testing.T) {
+		if err := m.ClearCache(); err != nil {
+			t.Fatalf("ClearCache() error = %v", err)
+		}
+		entries, err := os.ReadDir(filepath.Join(m.dir, "entrypoints", "internal"))
+		if err != nil {
+			t.Fatalf("failed to read entrypoint certs: %v", err)
+		}
+		for _, entry := range entries {
+			if entry.Name() != policyFilename {
+				t.Errorf("expected entrypoint certificates to be removed, found %s", entry.Name())
+			}
+		}
+	})
+}
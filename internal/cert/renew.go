@@ -0,0 +1,194 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/logging"
+)
+
+// DefaultRenewalInterval is how often the renewal loop scans for certificates
+// due for renewal.
+const DefaultRenewalInterval = time.Hour
+
+// StartRenewal renews certificates within the renewal window in the
+// background, once right away and then every interval, so certificates of
+// rarely visited hosts do not expire between handshakes. A non-positive
+// interval uses DefaultRenewalInterval.
+func (m *Manager) StartRenewal(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultRenewalInterval
+	}
+
+	m.renewMu.Lock()
+	defer m.renewMu.Unlock()
+
+	if m.renewStop != nil {
+		return
+	}
+	m.renewStop = make(chan struct{})
+	m.renewDone = make(chan struct{})
+
+	go m.renewLoop(interval, m.renewStop, m.renewDone)
+}
+
+// StopRenewal halts background renewal, waiting for a running scan to finish.
+func (m *Manager) StopRenewal() {
+	m.renewMu.Lock()
+	stop, done := m.renewStop, m.renewDone
+	m.renewStop, m.renewDone = nil, nil
+	m.renewMu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// renewLoop renews certificates until stop is closed.
+func (m *Manager) renewLoop(interval time.Duration, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := m.RenewExpiring(); err != nil {
+			logging.Warn("certificate renewal failed", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// RenewExpiring regenerates the certificates in the memory and disk cache that
+// are within the renewal window, expired or issued by a CA other than the
+// current one, keeping their names, also for entrypoint managers. It returns
+// how many were renewed; failures are joined in the error.
+func (m *Manager) RenewExpiring() (int, error) {
+	keys, err := m.cachedKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	renewed := 0
+	var errs []error
+	m.mu.RLock()
+	derived := slices.Collect(maps.Values(m.entrypoints))
+	m.mu.RUnlock()
+	for _, d := range derived {
+		n, err := d.RenewExpiring()
+		renewed += n
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, key := range keys {
+		m.mu.RLock()
+		cert := m.cache[key]
+		m.mu.RUnlock()
+		if cert == nil {
+			if cert, err = m.loadFromDisk(key); err != nil {
+				continue
+			}
+		}
+		if m.isValid(cert) || cert.Leaf == nil {
+			continue
+		}
+
+		// Sign the names the certificate covers again, so grown SANs are kept
+		fresh, err := m.generate(key, key, cert.Leaf.DNSNames...)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		m.store(key, fresh)
+		renewed++
+		logging.Info("certificate renewed", "domain", key, "old_expiry", cert.Leaf.NotAfter, "new_expiry", fresh.Leaf.NotAfter)
+	}
+	return renewed, errors.Join(errs...)
+}
+
+// ReloadCA loads the CA from disk again, e.g. after 'devproxy ca rotate', and
+// reissues the cached certificates of the CA it replaces. Entrypoint managers
+// using the same CA switch with m; those with a CA of their own keep it. It
+// returns how many certificates were reissued or renewed.
+func (m *Manager) ReloadCA() (int, error) {
+	rootCA, err := ca.Load()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrNoCA, err)
+	}
+
+	m.mu.Lock()
+	previous := m.ca
+	m.ca = rootCA
+	m.missing = nil
+	derived := slices.Collect(maps.Values(m.entrypoints))
+	m.mu.Unlock()
+	for _, d := range derived {
+		d.mu.Lock()
+		if d.ca == previous {
+			d.ca = rootCA
+		}
+		d.missing = nil
+		d.mu.Unlock()
+	}
+	logging.Info("CA reloaded", "fingerprint", rootCA.Fingerprint())
+
+	return m.RenewExpiring()
+}
+
+// cachedKeys returns the keys of certificates in the memory cache and of
+// certificates of the manager's key type on disk, sorted.
+func (m *Manager) cachedKeys() ([]string, error) {
+	m.mu.RLock()
+	keys := make([]string, 0, len(m.cache))
+	for key := range m.cache {
+		keys = append(keys, key)
+	}
+	m.mu.RUnlock()
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read certs directory: %w", err)
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, certFileSuffix) || strings.HasSuffix(name, keyFileSuffix) {
+			continue
+		}
+
+		// Certificates are stored under their key, which is the common name
+		data, err := os.ReadFile(filepath.Join(m.dir, name))
+		if err != nil {
+			continue
+		}
+		block, _ := pem.Decode(data)
+		if block == nil {
+			continue
+		}
+		leaf, err := x509.ParseCertificate(block.Bytes)
+		if err != nil || m.filename(leaf.Subject.CommonName)+certFileSuffix != name {
+			continue // not a certificate of this key type
+		}
+		keys = append(keys, leaf.Subject.CommonName)
+	}
+
+	slices.Sort(keys)
+	return slices.Compact(keys), nil
+}
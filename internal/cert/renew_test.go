@@ -0,0 +1,162 @@
+package cert
+
+import (
+	"crypto/tls"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/ca"
+)
+
+func TestRenewExpiring(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	shortLived, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	shortLived.SetValidity(2, 1)
+	for _, host := range []string{"app.localhost", "api.shop.localhost"} {
+		if _, err := shortLived.GetCertificate(&tls.ClientHelloInfo{ServerName: host}); err != nil {
+			t.Fatalf("GetCertificate(%s) error = %v", host, err)
+		}
+	}
+	if err := shortLived.EnsureExactCertificate("db.shop.localhost"); err != nil {
+		t.Fatalf("EnsureExactCertificate() error = %v", err)
+	}
+
+	// Nothing is due within the 1 day window
+	if renewed, err := shortLived.RenewExpiring(); err != nil || renewed != 0 {
+		t.Errorf("expected nothing to renew, got %d, %v", renewed, err)
+	}
+
+	// With the default 7 day window all three are due, found on disk
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	renewed, err := m.RenewExpiring()
+	if err != nil {
+		t.Fatalf("RenewExpiring() error = %v", err)
+	}
+	if renewed != 3 {
+		t.Errorf("expected 3 renewed certificates, got %d", renewed)
+	}
+
+	for key, wantNames := range map[string][]string{
+		"app.localhost":     {"app.localhost"},
+		"*.shop.localhost":  {"*.shop.localhost", "api.shop.localhost", "shop.localhost"},
+		"db.shop.localhost": {"db.shop.localhost"},
+	} {
+		cert := m.lookup(key)
+		if cert == nil {
+			t.Errorf("expected a valid certificate for %s after renewal", key)
+			continue
+		}
+		if days := time.Until(cert.Leaf.NotAfter).Hours() / 24; days < DefaultValidityDays-1 {
+			t.Errorf("expected %s to be valid for %d days, got %.2f", key, DefaultValidityDays, days)
+		}
+		if !slices.Equal(cert.Leaf.DNSNames, wantNames) {
+			t.Errorf("expected %s to keep names %v, got %v", key, wantNames, cert.Leaf.DNSNames)
+		}
+	}
+
+	if renewed, err := m.RenewExpiring(); err != nil || renewed != 0 {
+		t.Errorf("expected second scan to renew nothing, got %d, %v", renewed, err)
+	}
+}
+
+func TestStartRenewal(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	shortLived, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	shortLived.SetValidity(2, 1)
+	if _, err := shortLived.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.localhost"}); err != nil {
+		t.Fatalf("GetCertificate() error = %v", err)
+	}
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	m.StartRenewal(time.Hour)
+	m.StartRenewal(time.Hour) // no-op while running
+
+	// The first scan runs right away
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Stats().Generated == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	m.StopRenewal()
+	m.StopRenewal() // no-op once stopped
+
+	if m.Stats().Generated != 1 {
+		t.Errorf("expected the certificate to be renewed once, got %d", m.Stats().Generated)
+	}
+	if m.lookup("app.localhost") == nil {
+		t.Error("expected a valid certificate after renewal")
+	}
+}
+
+func TestReloadCA(t *testing.T) {
+	cleanup := setupTestEnv(t)
+	defer cleanup()
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	ep, err := m.ForEntrypoint("postgres", Policy{IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}})
+	if err != nil {
+		t.Fatalf("ForEntrypoint() error = %v", err)
+	}
+	for _, mgr := range []*Manager{m, ep} {
+		if _, err := mgr.GetCertificate(&tls.ClientHelloInfo{ServerName: "app.localhost"}); err != nil {
+			t.Fatalf("GetCertificate() error = %v", err)
+		}
+	}
+
+	// Rotate the CA on disk, as 'devproxy ca rotate' does
+	rotated, err := ca.Generate()
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+
+	// A manager using the new CA does not serve certificates of the old one
+	fresh, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager() error = %v", err)
+	}
+	if fresh.lookup("app.localhost") != nil {
+		t.Error("expected a certificate of the replaced CA not to be valid")
+	}
+
+	renewed, err := m.ReloadCA()
+	if err != nil {
+		t.Fatalf("ReloadCA() error = %v", err)
+	}
+	if renewed != 2 {
+		t.Errorf("expected 2 reissued certificates, got %d", renewed)
+	}
+	for name, mgr := range map[string]*Manager{"default": m, "entrypoint": ep} {
+		cert := mgr.lookup("app.localhost")
+		if cert == nil {
+			t.Errorf("%s: expected a valid certificate after ReloadCA()", name)
+			continue
+		}
+		if err := cert.Leaf.CheckSignatureFrom(rotated.Certificate); err != nil {
+			t.Errorf("%s: expected the certificate to be issued by the new CA: %v", name, err)
+		}
+	}
+
+	if renewed, err := m.RenewExpiring(); err != nil || renewed != 0 {
+		t.Errorf("expected nothing left to reissue, got %d, %v", renewed, err)
+	}
+}
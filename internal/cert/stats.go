@@ -0,0 +1,64 @@
+package cert
+
+import (
+	"maps"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/metrics"
+)
+
+// Reasons a certificate generation failed, as counted in Stats.Failures.
+const (
+	// FailureKey means generating or encoding the private key or serial failed.
+	FailureKey = "key"
+
+	// FailureCA means the CA could not sign the certificate.
+	FailureCA = "ca"
+
+	// FailureDisk means the certificate could not be saved. It is still
+	// served from memory, so the generation itself succeeds.
+	FailureDisk = "disk"
+)
+
+// Stats summarizes the certificates a manager generated.
+type Stats struct {
+	Generated      uint64            // certificates generated
+	GenerationTime time.Duration     // total time spent generating them
+	Failures       map[string]uint64 // failures by reason (FailureKey, ...)
+}
+
+// Stats returns counters of certificate generation since the manager was created.
+func (m *Manager) Stats() Stats {
+	m.statsMu.Lock()
+	defer m.statsMu.Unlock()
+
+	stats := m.stats
+	stats.Failures = maps.Clone(m.stats.Failures)
+	if stats.Failures == nil {
+		stats.Failures = make(map[string]uint64)
+	}
+	return stats
+}
+
+// generated records a certificate generation that took duration.
+func (m *Manager) generated(duration time.Duration) {
+	m.statsMu.Lock()
+	m.stats.Generated++
+	m.stats.GenerationTime += duration
+	m.statsMu.Unlock()
+
+	metrics.ObserveCertificateGeneration(duration)
+}
+
+// generationFailed records a generation failure for reason and returns err.
+func (m *Manager) generationFailed(reason string, err error) error {
+	m.statsMu.Lock()
+	if m.stats.Failures == nil {
+		m.stats.Failures = make(map[string]uint64)
+	}
+	m.stats.Failures[reason]++
+	m.statsMu.Unlock()
+
+	metrics.CertificateGenerationFailed(reason)
+	return err
+}
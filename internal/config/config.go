@@ -2,72 +2,242 @@
 package config
 
 import (
+	"bytes"
+	"cmp"
 	"fmt"
+	"maps"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/munichmade/devproxy/internal/logging"
 	"github.com/munichmade/devproxy/internal/paths"
 )
 
+// Entrypoint types.
+const (
+	EntrypointHTTP  = "http"
+	EntrypointHTTPS = "https"
+	EntrypointTCP   = "tcp"
+)
+
+// DomainIPContainer as a dns.domain_ips value resolves names in the domain to
+// the address of the container routed for them.
+const DomainIPContainer = "container"
+
+// apiVersionPattern matches Docker API versions such as "1.41" or "v1.41".
+var apiVersionPattern = regexp.MustCompile(`^v?[0-9]+\.[0-9]+$`)
+
 // Config represents the complete devproxy configuration.
 type Config struct {
+	Version     int                         `yaml:"version"` // Schema version, see CurrentVersion
 	DNS         DNSConfig                   `yaml:"dns"`
 	Entrypoints map[string]EntrypointConfig `yaml:"entrypoints"`
 	Docker      DockerConfig                `yaml:"docker"`
+	Proxy       ProxyConfig                 `yaml:"proxy,omitempty"`
+	Cert        CertConfig                  `yaml:"cert,omitempty"`
 	Logging     LoggingConfig               `yaml:"logging"`
+	Tracing     TracingConfig               `yaml:"tracing"`
+	Metrics     MetricsConfig               `yaml:"metrics"`
+	Routes      []RouteConfig               `yaml:"routes,omitempty"`
 }
 
 // DNSConfig configures the built-in DNS server.
 type DNSConfig struct {
-	Enabled  bool     `yaml:"enabled"` // Enable built-in DNS server (can be disabled if using dnsmasq)
-	Listen   string   `yaml:"listen"`
-	Domains  []string `yaml:"domains"`
-	Upstream string   `yaml:"upstream"`
+	Enabled        bool              `yaml:"enabled"` // Enable built-in DNS server (can be disabled if using dnsmasq)
+	Listen         string            `yaml:"listen"`
+	Domains        []string          `yaml:"domains"`
+	Upstreams      UpstreamList      `yaml:"upstream"`                   // One server or a list, tried in order until one responds
+	ServeStale     bool              `yaml:"serve_stale,omitempty"`      // Serve last known answers when upstream fails
+	LocalOnly      bool              `yaml:"local_only,omitempty"`       // Never forward queries upstream, answer them with local_only_rcode
+	LocalOnlyRcode string            `yaml:"local_only_rcode,omitempty"` // Rcode of non-local queries in local-only mode: REFUSED (default) or NXDOMAIN
+	Records        map[string]string `yaml:"records,omitempty"`          // Static records: hostname -> IP
+	Aliases        map[string]string `yaml:"aliases,omitempty"`          // CNAME records: alias -> target hostname
+	ReverseLookup  bool              `yaml:"reverse_lookup,omitempty"`   // Answer reverse lookups of 127.0.0.1 and ::1 with the first of domains
+	PTR            map[string]string `yaml:"ptr,omitempty"`              // PTR records: IP -> hostname, for reverse lookups of other addresses
+	DomainIPs      map[string]string `yaml:"domain_ips,omitempty"`       // Per-domain resolve IP, or "container" for the routed container's address
+	BindRetries    int               `yaml:"bind_retries,omitempty"`     // Retry binding with backoff while the address is in use (0 = fail immediately)
+	CacheSize      int               `yaml:"cache_size,omitempty"`       // Upstream answers cached for their TTL (0 = 1000, -1 = no caching)
+}
+
+// UpstreamList is a list of upstream DNS servers. In YAML it is a single
+// server or a sequence of servers.
+type UpstreamList []string
+
+// UnmarshalYAML accepts a single server as well as a sequence.
+func (l *UpstreamList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		var server string
+		if err := value.Decode(&server); err != nil {
+			return err
+		}
+		*l = nil
+		if server != "" {
+			*l = UpstreamList{server}
+		}
+		return nil
+	}
+
+	var servers []string
+	if err := value.Decode(&servers); err != nil {
+		return err
+	}
+	*l = servers
+	return nil
+}
+
+// MarshalYAML writes a single server as a plain string, as older releases
+// expect.
+func (l UpstreamList) MarshalYAML() (any, error) {
+	if len(l) == 1 {
+		return l[0], nil
+	}
+	return []string(l), nil
 }
 
 // EntrypointConfig configures a single entrypoint (HTTP, HTTPS, or TCP).
 type EntrypointConfig struct {
-	Listen     string `yaml:"listen"`
-	TargetPort int    `yaml:"target_port,omitempty"`
+	Type        string `yaml:"type,omitempty"` // http, https or tcp (empty = inferred from the name and target_port)
+	Listen      string `yaml:"listen"`
+	TargetPort  int    `yaml:"target_port,omitempty"`
+	DefaultHost string `yaml:"default_host,omitempty"` // TCP only: route for connections without SNI
+	RateLimit   int64  `yaml:"rate_limit,omitempty"`   // TCP only: bytes per second per connection and direction (0 = unlimited)
+	Backlog     int    `yaml:"backlog,omitempty"`      // Connections the kernel queues until they are accepted (0 = system default)
+
+	// TCP only: when target_port replaces the container port: force, default or ignore (empty = force)
+	TargetPortMode string `yaml:"target_port_mode,omitempty"`
+
+	// HTTPS only: eject a backend after this many consecutive connection failures (0 = 3)
+	FailureThreshold int `yaml:"failure_threshold,omitempty"`
+	// HTTPS only: how long an ejected backend is skipped before it is retried (empty = 10s)
+	EjectCooldown string `yaml:"eject_cooldown,omitempty"`
+
+	// HTTPS and TCP only: certificates served on this entrypoint
+	Cert EntrypointCertConfig `yaml:"cert,omitempty"`
+}
+
+// EntrypointCertConfig changes the certificates served on one entrypoint,
+// e.g. for an internal entrypoint next to a public-facing one.
+type EntrypointCertConfig struct {
+	IPSANs []string `yaml:"ip_sans,omitempty"` // IP addresses added to the SANs of every certificate
+	CACert string   `yaml:"ca_cert,omitempty"` // PEM file of a CA issuing the certificates instead of the devproxy CA
+	CAKey  string   `yaml:"ca_key,omitempty"`  // PEM file of the private key of ca_cert
+}
+
+// ProxyConfig configures behavior shared by all HTTP routes.
+type ProxyConfig struct {
+	StripResponseHeaders []string `yaml:"strip_response_headers,omitempty"`  // Response headers removed for every route (e.g., Server, X-Powered-By)
+	CAHost               string   `yaml:"ca_host"`                           // Serves the CA certificate at http://<ca_host>/ca.crt (empty = disabled)
+	RejectShadowedRoutes bool     `yaml:"reject_shadowed_routes,omitempty"`  // Refuse routes overlapping an existing one (e.g., api.app.localhost and *.app.localhost) instead of warning
+	MaxBufferSize        int64    `yaml:"max_buffer_size,omitempty"`         // Largest response body in bytes buffered for body-rewriting transforms; larger ones stream unmodified (0 = 10 MiB)
+	Compression          bool     `yaml:"compression,omitempty"`             // Gzip or deflate responses for clients that accept it
+	CompressionMinSize   int64    `yaml:"compression_min_size,omitempty"`    // Smallest response body in bytes that is compressed (0 = 1 KiB)
+	MaxRoutes            int      `yaml:"max_routes,omitempty"`              // Routes past which new ones are rejected, guarding against runaway label generators (0 = 10000)
+	NoRoute              string   `yaml:"no_route,omitempty"`                // Answer for hosts without a route: notfound (default), default (proxy to default_backend) or a status code
+	DefaultBackend       string   `yaml:"default_backend,omitempty"`         // host:port receiving requests for hosts without a route with no_route: default
+	MaxIdleConnsPerHost  int      `yaml:"max_idle_conns_per_host,omitempty"` // Idle connections kept open per backend for reuse (0 = 2)
+	TrustedProxies       []string `yaml:"trusted_proxies,omitempty"`         // IPs or CIDRs of proxies whose X-Forwarded-For/X-Real-IP name the client for access lists (empty = none)
+}
+
+// CertConfig configures generated certificates.
+type CertConfig struct {
+	ReuseKey        bool              `yaml:"reuse_key,omitempty"`         // Keep a domain's private key on renewal so public key pins stay valid
+	WildcardDepth   int               `yaml:"wildcard_depth,omitempty"`    // Subdomain levels one certificate covers (0 = 1, a certificate per parent domain)
+	KeyType         string            `yaml:"key_type,omitempty"`          // Leaf key algorithm: ecdsa (default, P-256) or rsa (2048 bits)
+	Prewarm         bool              `yaml:"prewarm,omitempty"`           // Generate certificates for all routes when they are added instead of on first handshake
+	ValidityDays    int               `yaml:"validity_days,omitempty"`     // Days generated certificates are valid (0 = 30)
+	RenewBeforeDays int               `yaml:"renew_before_days,omitempty"` // Days before expiry a certificate is renewed (0 = 7)
+	TrustTimeout    string            `yaml:"trust_timeout,omitempty"`     // Limit for each run of a Linux trust store update command (empty = 2m)
+	Testing         CertTestingConfig `yaml:"testing,omitempty"`           // Testing only: deliberately serve wrong certificates
+}
+
+// CertTestingConfig holds certificate settings for testing TLS clients. They
+// break certificate validation on purpose and are never needed otherwise.
+type CertTestingConfig struct {
+	SNIOverrides map[string]string `yaml:"sni_overrides,omitempty"` // SNI name -> name whose certificate is served for it
 }
 
 // DockerConfig configures Docker integration.
 type DockerConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	Socket  string `yaml:"socket"`
+	Enabled         bool     `yaml:"enabled"`
+	Socket          string   `yaml:"socket"`
+	APIVersion      string   `yaml:"api_version,omitempty"`      // Pin the Docker API version (empty = negotiate with daemon)
+	Network         string   `yaml:"network,omitempty"`          // Preferred network for container IPs (empty = first available)
+	NetworkFallback bool     `yaml:"network_fallback,omitempty"` // Use another network if a container is not on the preferred one
+	Networks        []string `yaml:"networks,omitempty"`         // Only route containers attached to one of these networks (empty = all)
+	ReadyTimeout    string   `yaml:"ready_timeout,omitempty"`    // Probe backends for up to this long before marking routes ready (empty = no probe)
+	SyncConcurrency int      `yaml:"sync_concurrency,omitempty"` // Containers inspected in parallel during the startup scan (0 = default)
+	ConnectTimeout  string   `yaml:"connect_timeout,omitempty"`  // Keep retrying to reach Docker at startup for up to this long (empty = single attempt)
+	DefaultPort     int      `yaml:"default_port,omitempty"`     // Container port of services without a port label (0 = 80)
 }
 
 // LoggingConfig configures logging behavior.
 type LoggingConfig struct {
 	Level     string `yaml:"level"`
+	Format    string `yaml:"format,omitempty"` // "text" (default) or "json"
 	AccessLog bool   `yaml:"access_log"`
 }
 
+// RouteConfig defines a static route to a backend not managed by Docker.
+type RouteConfig struct {
+	Host       string            `yaml:"host"`
+	Backend    string            `yaml:"backend"`
+	Protocol   string            `yaml:"protocol,omitempty"`   // "http" (default) or "tcp"
+	Entrypoint string            `yaml:"entrypoint,omitempty"` // TCP only: entrypoint to serve the route on
+	ALPN       map[string]string `yaml:"alpn,omitempty"`       // TCP only: backend per negotiated ALPN protocol (e.g., h2)
+}
+
+// TracingConfig configures OpenTelemetry export of per-request spans.
+type TracingConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Endpoint string `yaml:"endpoint,omitempty"` // OTLP/HTTP collector URL (e.g., http://localhost:4318)
+}
+
+// MetricsConfig configures the Prometheus metrics endpoint.
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Listen  string `yaml:"listen,omitempty"` // Admin address serving /metrics (e.g., 127.0.0.1:9477)
+}
+
 // Default returns a Config with sensible default values.
 // HTTP/HTTPS use privileged ports 80/443 (requires running as root).
 // DNS uses unprivileged port 15353 to avoid conflicts with system DNS.
 func Default() *Config {
 	return &Config{
+		Version: CurrentVersion,
 		DNS: DNSConfig{
-			Listen:   ":15353", // Unprivileged port (resolver configured via setup)
-			Domains:  []string{"localhost"},
-			Upstream: "8.8.8.8:53",
-			Enabled:  true,
+			Listen:    ":15353", // Unprivileged port (resolver configured via setup)
+			Domains:   []string{"localhost"},
+			Upstreams: UpstreamList{"8.8.8.8:53"},
+			Enabled:   true,
 		},
 		Entrypoints: map[string]EntrypointConfig{
 			"http": {
+				Type:   EntrypointHTTP,
 				Listen: ":80", // Privileged port (requires root)
 			},
 			"https": {
+				Type:   EntrypointHTTPS,
 				Listen: ":443", // Privileged port (requires root)
 			},
 			"postgres": {
+				Type:       EntrypointTCP,
 				Listen:     ":15432",
 				TargetPort: 5432,
 			},
 			"mongo": {
+				Type:       EntrypointTCP,
 				Listen:     ":27017",
 				TargetPort: 27017,
 			},
@@ -80,6 +250,12 @@ func Default() *Config {
 			Level:     "info",
 			AccessLog: false,
 		},
+		Proxy: ProxyConfig{
+			CAHost: "proxy.localhost",
+		},
+		Metrics: MetricsConfig{
+			Listen: "127.0.0.1:9477",
+		},
 	}
 }
 
@@ -91,6 +267,8 @@ func Load() (*Config, error) {
 
 // LoadFromFile reads the configuration from the specified file path.
 // If the file doesn't exist, it creates a default configuration file.
+// Files written for an older schema version are migrated and, once the
+// result is valid, saved in the upgraded form next to a backup.
 func LoadFromFile(path string) (*Config, error) {
 	// Check if config file exists
 	if _, err := os.Stat(path); os.IsNotExist(err) {
@@ -108,20 +286,71 @@ func LoadFromFile(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Upgrade files written for an older schema version
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Tag == "!!null" {
+		root = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse config file: line %d: expected a mapping", doc.Line)
+	}
+	from, changes, err := migrate(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate config file: %w", err)
+	}
+	original := data
+	if from < CurrentVersion {
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&root); err != nil {
+			return nil, fmt.Errorf("failed to marshal migrated config: %w", err)
+		}
+		data = buf.Bytes()
+	}
+
 	// Start with defaults and overlay with file values
 	cfg := Default()
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	cfg.normalizeEntrypoints()
 
 	// Validate the configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	if from < CurrentVersion {
+		for _, change := range changes {
+			logging.Info("config migrated", "from", from, "to", CurrentVersion, "change", change)
+		}
+		saveMigrated(path, original, data, from)
+	}
+
 	return cfg, nil
 }
 
+// saveMigrated replaces the config file with its migrated form and keeps the
+// original as path.v<from>.bak. Failing to write is not fatal: the file is
+// then migrated again on the next load.
+func saveMigrated(path string, original, migrated []byte, from int) {
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err := os.WriteFile(backup, original, 0o600); err != nil {
+		logging.Warn("failed to back up config file before upgrading it", "path", backup, "error", err)
+		return
+	}
+	if err := os.WriteFile(path, migrated, 0o600); err != nil {
+		logging.Warn("failed to write upgraded config file", "path", path, "error", err)
+		return
+	}
+	logging.Info("config file upgraded", "path", path, "version", CurrentVersion, "backup", backup)
+}
+
 // Save writes the configuration to the default config file.
 func (c *Config) Save() error {
 	return c.SaveToFile(paths.ConfigFile())
@@ -151,6 +380,10 @@ func (c *Config) SaveToFile(path string) error {
 
 // Validate checks the configuration for errors.
 func (c *Config) Validate() error {
+	if c.Version != CurrentVersion {
+		return fmt.Errorf("version must be %d", CurrentVersion)
+	}
+
 	// Validate DNS config
 	if c.DNS.Listen == "" {
 		return fmt.Errorf("dns.listen is required")
@@ -159,6 +392,45 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("dns.domains must have at least one domain")
 	}
 
+	if c.DNS.BindRetries < 0 {
+		return fmt.Errorf("dns.bind_retries must not be negative")
+	}
+	if c.DNS.CacheSize < -1 {
+		return fmt.Errorf("dns.cache_size must be -1 (disabled) or greater")
+	}
+	switch strings.ToUpper(c.DNS.LocalOnlyRcode) {
+	case "", "REFUSED", "NXDOMAIN":
+	default:
+		return fmt.Errorf("dns.local_only_rcode must be REFUSED or NXDOMAIN, got %q", c.DNS.LocalOnlyRcode)
+	}
+	for _, server := range c.DNS.Upstreams {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			return fmt.Errorf("dns.upstream: invalid server %q (use host:port): %w", server, err)
+		}
+	}
+
+	for host, ip := range c.DNS.Records {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("dns.records: invalid IP %q for %s", ip, host)
+		}
+	}
+	for domain, ip := range c.DNS.DomainIPs {
+		if ip != DomainIPContainer && net.ParseIP(ip) == nil {
+			return fmt.Errorf("dns.domain_ips: invalid IP %q for %s (use an IP or %q)", ip, domain, DomainIPContainer)
+		}
+	}
+	for ip, host := range c.DNS.PTR {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("dns.ptr: invalid IP %q", ip)
+		}
+		if host == "" {
+			return fmt.Errorf("dns.ptr: hostname for %s must not be empty", ip)
+		}
+	}
+	if err := validateDNSAliases(c.DNS.Aliases, c.DNS.Records); err != nil {
+		return err
+	}
+
 	// Validate entrypoints
 	if len(c.Entrypoints) == 0 {
 		return fmt.Errorf("at least one entrypoint is required")
@@ -167,24 +439,299 @@ func (c *Config) Validate() error {
 		if ep.Listen == "" {
 			return fmt.Errorf("entrypoint %q: listen address is required", name)
 		}
+		if ep.Type != "" {
+			// The reserved names select the HTTP servers, all others are TCP
+			want := EntrypointTCP
+			if name == EntrypointHTTP || name == EntrypointHTTPS {
+				want = name
+			}
+			if ep.Type != want {
+				return fmt.Errorf("entrypoint %q: type must be %s", name, want)
+			}
+			if ep.Type == EntrypointTCP && ep.TargetPort <= 0 {
+				return fmt.Errorf("entrypoint %q: type tcp requires target_port", name)
+			}
+		}
+		if ep.RateLimit < 0 {
+			return fmt.Errorf("entrypoint %q: rate_limit must not be negative", name)
+		}
+		if ep.Backlog < 0 {
+			return fmt.Errorf("entrypoint %q: backlog must not be negative", name)
+		}
+		if !reflect.DeepEqual(ep.Cert, EntrypointCertConfig{}) && strings.ToLower(name) == EntrypointHTTP {
+			return fmt.Errorf("entrypoint %q: cert requires a TLS entrypoint", name)
+		}
+		for _, ip := range ep.Cert.IPSANs {
+			if net.ParseIP(ip) == nil {
+				return fmt.Errorf("entrypoint %q: cert.ip_sans: %q is not an IP address", name, ip)
+			}
+		}
+		if (ep.Cert.CACert == "") != (ep.Cert.CAKey == "") {
+			return fmt.Errorf("entrypoint %q: cert.ca_cert and cert.ca_key must be set together", name)
+		}
+		switch ep.TargetPortMode {
+		case "", "force", "default", "ignore":
+		default:
+			return fmt.Errorf("entrypoint %q: target_port_mode must be one of: force, default, ignore", name)
+		}
+		if ep.FailureThreshold < 0 {
+			return fmt.Errorf("entrypoint %q: failure_threshold must not be negative", name)
+		}
+		if ep.EjectCooldown != "" {
+			if d, err := time.ParseDuration(ep.EjectCooldown); err != nil || d <= 0 {
+				return fmt.Errorf("entrypoint %q: eject_cooldown must be a positive duration (e.g., 10s)", name)
+			}
+		}
+	}
+
+	for i, header := range c.Proxy.StripResponseHeaders {
+		if strings.TrimSpace(header) == "" {
+			return fmt.Errorf("proxy.strip_response_headers[%d]: header name is required", i)
+		}
+	}
+	if c.Proxy.MaxBufferSize < 0 {
+		return fmt.Errorf("proxy.max_buffer_size must not be negative")
+	}
+	if c.Proxy.CompressionMinSize < 0 {
+		return fmt.Errorf("proxy.compression_min_size must not be negative")
+	}
+	if c.Proxy.MaxRoutes < 0 {
+		return fmt.Errorf("proxy.max_routes must not be negative")
+	}
+	if c.Proxy.MaxIdleConnsPerHost < 0 {
+		return fmt.Errorf("proxy.max_idle_conns_per_host must not be negative")
+	}
+	for _, entry := range c.Proxy.TrustedProxies {
+		if _, err := netip.ParsePrefix(entry); err != nil {
+			if _, err := netip.ParseAddr(entry); err != nil {
+				return fmt.Errorf("proxy.trusted_proxies: %q is not an IP address or CIDR", entry)
+			}
+		}
+	}
+	switch c.Proxy.NoRoute {
+	case "", "notfound":
+	case "default":
+		if c.Proxy.DefaultBackend == "" {
+			return fmt.Errorf("proxy.no_route: default requires proxy.default_backend")
+		}
+	default:
+		if status, err := strconv.Atoi(c.Proxy.NoRoute); err != nil || status < 200 || status > 599 {
+			return fmt.Errorf("proxy.no_route must be notfound, default or a status code from 200 to 599")
+		}
+	}
+	if c.Proxy.DefaultBackend != "" {
+		if _, port, err := net.SplitHostPort(c.Proxy.DefaultBackend); err != nil || port == "" {
+			return fmt.Errorf("proxy.default_backend must be host:port (e.g., localhost:8080)")
+		}
+	}
+	if c.Cert.WildcardDepth < 0 {
+		return fmt.Errorf("cert.wildcard_depth must not be negative")
+	}
+	if c.Cert.ValidityDays < 0 || c.Cert.RenewBeforeDays < 0 {
+		return fmt.Errorf("cert.validity_days and cert.renew_before_days must not be negative")
+	}
+	if cmp.Or(c.Cert.RenewBeforeDays, 7) >= cmp.Or(c.Cert.ValidityDays, 30) {
+		return fmt.Errorf("cert.renew_before_days (default 7) must be less than cert.validity_days (default 30)")
+	}
+	switch c.Cert.KeyType {
+	case "", "ecdsa", "rsa":
+	default:
+		return fmt.Errorf("cert.key_type must be ecdsa or rsa")
+	}
+	if c.Cert.TrustTimeout != "" {
+		if d, err := time.ParseDuration(c.Cert.TrustTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("cert.trust_timeout must be a positive duration (e.g., 5m)")
+		}
+	}
+	for sni, target := range c.Cert.Testing.SNIOverrides {
+		if sni == "" || target == "" {
+			return fmt.Errorf("cert.testing.sni_overrides: SNI name and target must not be empty")
+		}
 	}
 
 	// Validate Docker config
 	if c.Docker.Enabled && c.Docker.Socket == "" {
 		return fmt.Errorf("docker.socket is required when docker is enabled")
 	}
+	if c.Docker.DefaultPort < 0 || c.Docker.DefaultPort > 65535 {
+		return fmt.Errorf("docker.default_port must be between 1 and 65535")
+	}
+	if c.Docker.APIVersion != "" && !apiVersionPattern.MatchString(c.Docker.APIVersion) {
+		return fmt.Errorf("docker.api_version must be in the form MAJOR.MINOR (e.g., 1.41)")
+	}
+	if c.Docker.ReadyTimeout != "" {
+		if d, err := time.ParseDuration(c.Docker.ReadyTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("docker.ready_timeout must be a positive duration (e.g., 30s)")
+		}
+	}
+	if c.Docker.ConnectTimeout != "" {
+		if d, err := time.ParseDuration(c.Docker.ConnectTimeout); err != nil || d <= 0 {
+			return fmt.Errorf("docker.connect_timeout must be a positive duration (e.g., 2m)")
+		}
+	}
+	if c.Docker.SyncConcurrency < 0 {
+		return fmt.Errorf("docker.sync_concurrency must not be negative")
+	}
+	for i, network := range c.Docker.Networks {
+		if strings.TrimSpace(network) == "" {
+			return fmt.Errorf("docker.networks[%d]: network name is required", i)
+		}
+	}
+	if c.Docker.Network != "" && len(c.Docker.Networks) > 0 && !slices.Contains(c.Docker.Networks, c.Docker.Network) {
+		return fmt.Errorf("docker.network %q must be listed in docker.networks", c.Docker.Network)
+	}
+
+	// Validate static routes
+	seenHosts := make(map[string]bool)
+	for i, route := range c.Routes {
+		if route.Host == "" {
+			return fmt.Errorf("routes[%d]: host is required", i)
+		}
+		if route.Backend == "" {
+			return fmt.Errorf("routes[%d]: backend is required", i)
+		}
+		if seenHosts[route.Host] {
+			return fmt.Errorf("routes[%d]: duplicate host %q", i, route.Host)
+		}
+		seenHosts[route.Host] = true
+
+		switch route.Protocol {
+		case "", "http":
+		case "tcp":
+			if _, ok := c.Entrypoints[route.Entrypoint]; !ok {
+				return fmt.Errorf("routes[%d]: tcp route requires a configured entrypoint", i)
+			}
+		default:
+			return fmt.Errorf("routes[%d]: protocol must be http or tcp", i)
+		}
+		if len(route.ALPN) > 0 && route.Protocol != "tcp" {
+			return fmt.Errorf("routes[%d]: alpn backends require protocol tcp", i)
+		}
+	}
+
+	// Validate tracing config
+	if c.Tracing.Enabled {
+		if c.Tracing.Endpoint == "" {
+			return fmt.Errorf("tracing.endpoint is required when tracing is enabled")
+		}
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("tracing.endpoint must be an http(s) URL (e.g., http://localhost:4318)")
+		}
+	}
+
+	if c.Metrics.Enabled {
+		if _, _, err := net.SplitHostPort(c.Metrics.Listen); err != nil {
+			return fmt.Errorf("metrics.listen must be a host:port address (e.g., 127.0.0.1:9477)")
+		}
+	}
 
 	// Validate logging config
 	validLevels := map[string]bool{"debug": true, "info": true, "warn": true, "error": true}
 	if !validLevels[c.Logging.Level] {
 		return fmt.Errorf("logging.level must be one of: debug, info, warn, error")
 	}
+	// Matched case-insensitively, like logging.ParseFormat
+	switch strings.ToLower(c.Logging.Format) {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("logging.format must be one of: text, json")
+	}
 
 	return nil
 }
 
+// validateDNSAliases checks that aliases have targets, do not shadow static
+// records and do not form loops.
+func validateDNSAliases(aliases, records map[string]string) error {
+	normalize := func(name string) string {
+		return strings.ToLower(strings.TrimSuffix(name, "."))
+	}
+
+	targets := make(map[string]string, len(aliases))
+	for alias, target := range aliases {
+		if normalize(target) == "" {
+			return fmt.Errorf("dns.aliases: %s has no target", alias)
+		}
+		targets[normalize(alias)] = normalize(target)
+	}
+	for host := range records {
+		if _, ok := targets[normalize(host)]; ok {
+			return fmt.Errorf("dns.aliases: %s is also a static record", host)
+		}
+	}
+
+	for _, alias := range slices.Sorted(maps.Keys(targets)) {
+		chain := []string{alias}
+		for name := targets[alias]; ; name = targets[name] {
+			if slices.Contains(chain, name) {
+				return fmt.Errorf("dns.aliases: loop %s", strings.Join(append(chain, name), " -> "))
+			}
+			if _, ok := targets[name]; !ok {
+				break
+			}
+			chain = append(chain, name)
+		}
+	}
+	return nil
+}
+
+// ContainerDomains returns the dns.domain_ips domains that resolve to
+// container addresses, sorted.
+func (c DNSConfig) ContainerDomains() []string {
+	var domains []string
+	for domain, ip := range c.DomainIPs {
+		if ip == DomainIPContainer {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+	return domains
+}
+
+// PTRRecords returns the PTR records (IP -> hostname) the DNS server answers:
+// with reverse_lookup, the loopback addresses point to the first domain, and
+// dns.ptr adds to or overrides them.
+func (c DNSConfig) PTRRecords() map[string]string {
+	records := make(map[string]string, len(c.PTR)+2)
+	if c.ReverseLookup && len(c.Domains) > 0 {
+		records["127.0.0.1"] = c.Domains[0]
+		records["::1"] = c.Domains[0]
+	}
+	maps.Copy(records, c.PTR)
+	return records
+}
+
+// TCPEntrypointNames returns the names of the TCP entrypoints, sorted.
+// These are all entrypoints except http and https that have a target_port.
+func (c *Config) TCPEntrypointNames() []string {
+	var names []string
+	for name, ep := range c.Entrypoints {
+		if name == "http" || name == "https" || ep.TargetPort <= 0 {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 // GetEntrypoint returns the entrypoint configuration by name.
 func (c *Config) GetEntrypoint(name string) (EntrypointConfig, bool) {
-	ep, ok := c.Entrypoints[name]
+	ep, ok := c.Entrypoints[strings.ToLower(name)]
 	return ep, ok
 }
+
+// normalizeEntrypoints lowercases entrypoint names so they match container
+// labels regardless of casing, and infers the type of entrypoints without
+// one. Defaults are lowercase, so an entry written with different casing in
+// the config file replaces the default entry.
+func (c *Config) normalizeEntrypoints() {
+	for name, ep := range c.Entrypoints {
+		lower := strings.ToLower(name)
+		if ep.Type == "" {
+			ep.Type = inferEntrypointType(lower, ep.TargetPort)
+		}
+		delete(c.Entrypoints, name)
+		c.Entrypoints[lower] = ep
+	}
+}
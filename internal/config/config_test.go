@@ -1,8 +1,11 @@
 package config
 
 import (
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 )
 
@@ -16,8 +19,8 @@ func TestDefault(t *testing.T) {
 	if len(cfg.DNS.Domains) != 1 || cfg.DNS.Domains[0] != "localhost" {
 		t.Errorf("DNS.Domains = %v, want [localhost]", cfg.DNS.Domains)
 	}
-	if cfg.DNS.Upstream != "8.8.8.8:53" {
-		t.Errorf("DNS.Upstream = %q, want %q", cfg.DNS.Upstream, "8.8.8.8:53")
+	if !slices.Equal(cfg.DNS.Upstreams, UpstreamList{"8.8.8.8:53"}) {
+		t.Errorf("DNS.Upstreams = %v, want [8.8.8.8:53]", cfg.DNS.Upstreams)
 	}
 
 	// Entrypoint defaults
@@ -60,6 +63,11 @@ func TestDefault(t *testing.T) {
 	if cfg.Logging.AccessLog {
 		t.Error("Logging.AccessLog = true, want false")
 	}
+
+	// Proxy defaults
+	if cfg.Proxy.CAHost != "proxy.localhost" {
+		t.Errorf("Proxy.CAHost = %q, want %q", cfg.Proxy.CAHost, "proxy.localhost")
+	}
 }
 
 func TestValidate(t *testing.T) {
@@ -83,6 +91,16 @@ func TestValidate(t *testing.T) {
 			modify:  func(c *Config) { c.DNS.Domains = nil },
 			wantErr: true,
 		},
+		{
+			name:    "DNS bind retries",
+			modify:  func(c *Config) { c.DNS.BindRetries = 5 },
+			wantErr: false,
+		},
+		{
+			name:    "negative DNS bind retries",
+			modify:  func(c *Config) { c.DNS.BindRetries = -1 },
+			wantErr: true,
+		},
 		{
 			name:    "no entrypoints",
 			modify:  func(c *Config) { c.Entrypoints = nil },
@@ -93,16 +111,514 @@ func TestValidate(t *testing.T) {
 			modify:  func(c *Config) { c.Entrypoints["test"] = EntrypointConfig{} },
 			wantErr: true,
 		},
+		{
+			name:    "entrypoint with rate limit",
+			modify:  func(c *Config) { c.Entrypoints["test"] = EntrypointConfig{Listen: ":15433", RateLimit: 65536} },
+			wantErr: false,
+		},
+		{
+			name:    "entrypoint with negative rate limit",
+			modify:  func(c *Config) { c.Entrypoints["test"] = EntrypointConfig{Listen: ":15433", RateLimit: -1} },
+			wantErr: true,
+		},
+		{
+			name:    "entrypoint with backlog",
+			modify:  func(c *Config) { c.Entrypoints["test"] = EntrypointConfig{Listen: ":15433", Backlog: 1024} },
+			wantErr: false,
+		},
+		{
+			name: "entrypoint with cert policy",
+			modify: func(c *Config) {
+				c.Entrypoints["internal"] = EntrypointConfig{Listen: ":15443", TargetPort: 443, Cert: EntrypointCertConfig{
+					IPSANs: []string{"10.0.0.5", "fd00::5"}, CACert: "ca.pem", CAKey: "ca-key.pem",
+				}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "entrypoint cert with invalid ip",
+			modify: func(c *Config) {
+				c.Entrypoints["internal"] = EntrypointConfig{Listen: ":15443", TargetPort: 443, Cert: EntrypointCertConfig{IPSANs: []string{"internal"}}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "entrypoint cert ca without key",
+			modify: func(c *Config) {
+				c.Entrypoints["internal"] = EntrypointConfig{Listen: ":15443", TargetPort: 443, Cert: EntrypointCertConfig{CACert: "ca.pem"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "cert on http entrypoint",
+			modify: func(c *Config) {
+				ep := c.Entrypoints["http"]
+				ep.Cert.IPSANs = []string{"10.0.0.5"}
+				c.Entrypoints["http"] = ep
+			},
+			wantErr: true,
+		},
+		{
+			name:    "entrypoint with negative backlog",
+			modify:  func(c *Config) { c.Entrypoints["test"] = EntrypointConfig{Listen: ":15433", Backlog: -1} },
+			wantErr: true,
+		},
+		{
+			name:    "entrypoint with target port mode",
+			modify:  func(c *Config) { c.Entrypoints["test"] = EntrypointConfig{Listen: ":15433", TargetPortMode: "default"} },
+			wantErr: false,
+		},
+		{
+			name: "entrypoint with health policy",
+			modify: func(c *Config) {
+				c.Entrypoints["https"] = EntrypointConfig{Listen: ":443", FailureThreshold: 5, EjectCooldown: "30s"}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "entrypoint with negative failure threshold",
+			modify:  func(c *Config) { c.Entrypoints["https"] = EntrypointConfig{Listen: ":443", FailureThreshold: -1} },
+			wantErr: true,
+		},
+		{
+			name:    "entrypoint with invalid eject cooldown",
+			modify:  func(c *Config) { c.Entrypoints["https"] = EntrypointConfig{Listen: ":443", EjectCooldown: "soon"} },
+			wantErr: true,
+		},
+		{
+			name:    "entrypoint with unknown target port mode",
+			modify:  func(c *Config) { c.Entrypoints["test"] = EntrypointConfig{Listen: ":15433", TargetPortMode: "always"} },
+			wantErr: true,
+		},
+		{
+			name:    "strip response headers",
+			modify:  func(c *Config) { c.Proxy.StripResponseHeaders = []string{"Server", "X-Powered-By"} },
+			wantErr: false,
+		},
+		{
+			name:    "empty strip response header",
+			modify:  func(c *Config) { c.Proxy.StripResponseHeaders = []string{"Server", " "} },
+			wantErr: true,
+		},
+		{
+			name: "explicit entrypoint types",
+			modify: func(c *Config) {
+				c.Entrypoints["https"] = EntrypointConfig{Type: "https", Listen: ":443"}
+				c.Entrypoints["redis"] = EntrypointConfig{Type: "tcp", Listen: ":16379", TargetPort: 6379}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "entrypoint type not matching name",
+			modify:  func(c *Config) { c.Entrypoints["http"] = EntrypointConfig{Type: "tcp", Listen: ":80"} },
+			wantErr: true,
+		},
+		{
+			name:    "tcp entrypoint without target port",
+			modify:  func(c *Config) { c.Entrypoints["redis"] = EntrypointConfig{Type: "tcp", Listen: ":16379"} },
+			wantErr: true,
+		},
+		{
+			name:    "unsupported version",
+			modify:  func(c *Config) { c.Version = CurrentVersion + 1 },
+			wantErr: true,
+		},
+		{
+			name:    "max buffer size",
+			modify:  func(c *Config) { c.Proxy.MaxBufferSize = 1 << 20 },
+			wantErr: false,
+		},
+		{
+			name:    "negative max buffer size",
+			modify:  func(c *Config) { c.Proxy.MaxBufferSize = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "compression",
+			modify:  func(c *Config) { c.Proxy.Compression = true; c.Proxy.CompressionMinSize = 4096 },
+			wantErr: false,
+		},
+		{
+			name:    "negative compression min size",
+			modify:  func(c *Config) { c.Proxy.CompressionMinSize = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "max routes",
+			modify:  func(c *Config) { c.Proxy.MaxRoutes = 500 },
+			wantErr: false,
+		},
+		{
+			name:    "negative max routes",
+			modify:  func(c *Config) { c.Proxy.MaxRoutes = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "max idle conns per host",
+			modify:  func(c *Config) { c.Proxy.MaxIdleConnsPerHost = 32 },
+			wantErr: false,
+		},
+		{
+			name:    "negative max idle conns per host",
+			modify:  func(c *Config) { c.Proxy.MaxIdleConnsPerHost = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "trusted proxies",
+			modify:  func(c *Config) { c.Proxy.TrustedProxies = []string{"10.0.0.0/8", "::1"} },
+			wantErr: false,
+		},
+		{
+			name:    "invalid trusted proxy",
+			modify:  func(c *Config) { c.Proxy.TrustedProxies = []string{"proxy.local"} },
+			wantErr: true,
+		},
+		{
+			name:    "no route status",
+			modify:  func(c *Config) { c.Proxy.NoRoute = "421" },
+			wantErr: false,
+		},
+		{
+			name:    "no route default backend",
+			modify:  func(c *Config) { c.Proxy.NoRoute, c.Proxy.DefaultBackend = "default", "localhost:8080" },
+			wantErr: false,
+		},
+		{
+			name:    "no route default without backend",
+			modify:  func(c *Config) { c.Proxy.NoRoute = "default" },
+			wantErr: true,
+		},
+		{
+			name:    "no route invalid status",
+			modify:  func(c *Config) { c.Proxy.NoRoute = "99" },
+			wantErr: true,
+		},
+		{
+			name:    "no route unknown mode",
+			modify:  func(c *Config) { c.Proxy.NoRoute = "passthrough" },
+			wantErr: true,
+		},
+		{
+			name:    "default backend without port",
+			modify:  func(c *Config) { c.Proxy.NoRoute, c.Proxy.DefaultBackend = "default", "localhost" },
+			wantErr: true,
+		},
+		{
+			name:    "wildcard depth",
+			modify:  func(c *Config) { c.Cert.WildcardDepth = 3 },
+			wantErr: false,
+		},
+		{
+			name:    "negative wildcard depth",
+			modify:  func(c *Config) { c.Cert.WildcardDepth = -1 },
+			wantErr: true,
+		},
 		{
 			name:    "docker enabled without socket",
 			modify:  func(c *Config) { c.Docker.Enabled = true; c.Docker.Socket = "" },
 			wantErr: true,
 		},
+		{
+			name:    "docker default port",
+			modify:  func(c *Config) { c.Docker.DefaultPort = 3000 },
+			wantErr: false,
+		},
+		{
+			name:    "docker default port out of range",
+			modify:  func(c *Config) { c.Docker.DefaultPort = 70000 },
+			wantErr: true,
+		},
 		{
 			name:    "docker disabled without socket is ok",
 			modify:  func(c *Config) { c.Docker.Enabled = false; c.Docker.Socket = "" },
 			wantErr: false,
 		},
+		{
+			name:    "valid docker api version",
+			modify:  func(c *Config) { c.Docker.APIVersion = "1.41" },
+			wantErr: false,
+		},
+		{
+			name:    "invalid docker api version",
+			modify:  func(c *Config) { c.Docker.APIVersion = "latest" },
+			wantErr: true,
+		},
+		{
+			name:    "valid docker ready timeout",
+			modify:  func(c *Config) { c.Docker.ReadyTimeout = "30s" },
+			wantErr: false,
+		},
+		{
+			name:    "invalid docker ready timeout",
+			modify:  func(c *Config) { c.Docker.ReadyTimeout = "soon" },
+			wantErr: true,
+		},
+		{
+			name:    "valid docker sync concurrency",
+			modify:  func(c *Config) { c.Docker.SyncConcurrency = 32 },
+			wantErr: false,
+		},
+		{
+			name:    "negative docker sync concurrency",
+			modify:  func(c *Config) { c.Docker.SyncConcurrency = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "docker networks allowlist",
+			modify:  func(c *Config) { c.Docker.Networks = []string{"devproxy", "shared"} },
+			wantErr: false,
+		},
+		{
+			name:    "empty docker networks entry",
+			modify:  func(c *Config) { c.Docker.Networks = []string{"devproxy", " "} },
+			wantErr: true,
+		},
+		{
+			name: "preferred network listed in allowlist",
+			modify: func(c *Config) {
+				c.Docker.Network = "devproxy"
+				c.Docker.Networks = []string{"devproxy"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "preferred network missing from allowlist",
+			modify: func(c *Config) {
+				c.Docker.Network = "devproxy"
+				c.Docker.Networks = []string{"shared"}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "docker connect timeout",
+			modify:  func(c *Config) { c.Docker.ConnectTimeout = "2m" },
+			wantErr: false,
+		},
+		{
+			name:    "invalid docker connect timeout",
+			modify:  func(c *Config) { c.Docker.ConnectTimeout = "0s" },
+			wantErr: true,
+		},
+		{
+			name: "valid static routes",
+			modify: func(c *Config) {
+				c.Routes = []RouteConfig{
+					{Host: "grafana.localhost", Backend: "127.0.0.1:3000"},
+					{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: "tcp", Entrypoint: "postgres"},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "static route without backend",
+			modify:  func(c *Config) { c.Routes = []RouteConfig{{Host: "grafana.localhost"}} },
+			wantErr: true,
+		},
+		{
+			name: "duplicate static route host",
+			modify: func(c *Config) {
+				c.Routes = []RouteConfig{
+					{Host: "grafana.localhost", Backend: "127.0.0.1:3000"},
+					{Host: "grafana.localhost", Backend: "127.0.0.1:3001"},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "tcp static route with unknown entrypoint",
+			modify: func(c *Config) {
+				c.Routes = []RouteConfig{{Host: "db.localhost", Backend: "127.0.0.1:3306", Protocol: "tcp", Entrypoint: "mysql"}}
+			},
+			wantErr: true,
+		},
+		{
+			name: "alpn backends on http route",
+			modify: func(c *Config) {
+				c.Routes = []RouteConfig{{Host: "api.localhost", Backend: "127.0.0.1:8080", ALPN: map[string]string{"h2": "127.0.0.1:50051"}}}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "invalid dns record IP",
+			modify:  func(c *Config) { c.DNS.Records = map[string]string{"nas.localhost": "nas"} },
+			wantErr: true,
+		},
+		{
+			name:    "dns cache disabled",
+			modify:  func(c *Config) { c.DNS.CacheSize = -1 },
+			wantErr: false,
+		},
+		{
+			name:    "invalid dns cache size",
+			modify:  func(c *Config) { c.DNS.CacheSize = -2 },
+			wantErr: true,
+		},
+		{
+			name:    "dns local only with nxdomain",
+			modify:  func(c *Config) { c.DNS.LocalOnly, c.DNS.LocalOnlyRcode = true, "nxdomain" },
+			wantErr: false,
+		},
+		{
+			name:    "invalid dns local only rcode",
+			modify:  func(c *Config) { c.DNS.LocalOnly, c.DNS.LocalOnlyRcode = true, "SERVFAIL" },
+			wantErr: true,
+		},
+		{
+			name:    "cert rsa keys",
+			modify:  func(c *Config) { c.Cert.KeyType = "rsa" },
+			wantErr: false,
+		},
+		{
+			name:    "invalid cert key type",
+			modify:  func(c *Config) { c.Cert.KeyType = "ed25519" },
+			wantErr: true,
+		},
+		{
+			name:    "cert trust timeout",
+			modify:  func(c *Config) { c.Cert.TrustTimeout = "5m" },
+			wantErr: false,
+		},
+		{
+			name:    "invalid cert trust timeout",
+			modify:  func(c *Config) { c.Cert.TrustTimeout = "soon" },
+			wantErr: true,
+		},
+		{
+			name:    "cert validity",
+			modify:  func(c *Config) { c.Cert.ValidityDays, c.Cert.RenewBeforeDays = 365, 30 },
+			wantErr: false,
+		},
+		{
+			name:    "renewal window of default validity",
+			modify:  func(c *Config) { c.Cert.RenewBeforeDays = 29 },
+			wantErr: false,
+		},
+		{
+			name:    "validity within default renewal window",
+			modify:  func(c *Config) { c.Cert.ValidityDays = 7 },
+			wantErr: true,
+		},
+		{
+			name:    "renewal window not less than validity",
+			modify:  func(c *Config) { c.Cert.ValidityDays, c.Cert.RenewBeforeDays = 10, 10 },
+			wantErr: true,
+		},
+		{
+			name:    "negative validity",
+			modify:  func(c *Config) { c.Cert.ValidityDays = -1 },
+			wantErr: true,
+		},
+		{
+			name:    "cert sni override",
+			modify:  func(c *Config) { c.Cert.Testing.SNIOverrides = map[string]string{"evil.localhost": "app.localhost"} },
+			wantErr: false,
+		},
+		{
+			name:    "cert sni override without target",
+			modify:  func(c *Config) { c.Cert.Testing.SNIOverrides = map[string]string{"evil.localhost": ""} },
+			wantErr: true,
+		},
+		{
+			name:    "dns ptr record",
+			modify:  func(c *Config) { c.DNS.PTR = map[string]string{"172.18.0.2": "web.localhost"} },
+			wantErr: false,
+		},
+		{
+			name:    "invalid dns ptr address",
+			modify:  func(c *Config) { c.DNS.PTR = map[string]string{"web.localhost": "172.18.0.2"} },
+			wantErr: true,
+		},
+		{
+			name:    "multiple dns upstreams",
+			modify:  func(c *Config) { c.DNS.Upstreams = UpstreamList{"10.8.0.1:53", "[2606:4700:4700::1111]:53"} },
+			wantErr: false,
+		},
+		{
+			name:    "dns upstream without port",
+			modify:  func(c *Config) { c.DNS.Upstreams = UpstreamList{"10.8.0.1:53", "1.1.1.1"} },
+			wantErr: true,
+		},
+		{
+			name: "dns domain IPs",
+			modify: func(c *Config) {
+				c.DNS.DomainIPs = map[string]string{"lan.test": "192.168.1.5", "docker.localhost": DomainIPContainer}
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid dns domain IP",
+			modify:  func(c *Config) { c.DNS.DomainIPs = map[string]string{"lan.test": "lan"} },
+			wantErr: true,
+		},
+		{
+			name: "dns aliases",
+			modify: func(c *Config) {
+				c.DNS.Aliases = map[string]string{"short.localhost": "app.localhost", "s.localhost": "short.localhost"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "dns alias loop",
+			modify: func(c *Config) {
+				c.DNS.Aliases = map[string]string{"a.localhost": "b.localhost", "b.localhost": "A.localhost."}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "dns alias to itself",
+			modify:  func(c *Config) { c.DNS.Aliases = map[string]string{"a.localhost": "a.localhost"} },
+			wantErr: true,
+		},
+		{
+			name:    "dns alias without target",
+			modify:  func(c *Config) { c.DNS.Aliases = map[string]string{"a.localhost": ""} },
+			wantErr: true,
+		},
+		{
+			name: "dns alias shadowing a record",
+			modify: func(c *Config) {
+				c.DNS.Records = map[string]string{"nas.localhost": "10.0.0.1"}
+				c.DNS.Aliases = map[string]string{"nas.localhost": "app.localhost"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid tracing endpoint",
+			modify: func(c *Config) {
+				c.Tracing.Enabled = true
+				c.Tracing.Endpoint = "http://localhost:4318"
+			},
+			wantErr: false,
+		},
+		{
+			name:    "tracing enabled without endpoint",
+			modify:  func(c *Config) { c.Tracing.Enabled = true },
+			wantErr: true,
+		},
+		{
+			name: "tracing endpoint without scheme",
+			modify: func(c *Config) {
+				c.Tracing.Enabled = true
+				c.Tracing.Endpoint = "localhost:4318"
+			},
+			wantErr: true,
+		},
+		{
+			name: "valid metrics listen address",
+			modify: func(c *Config) {
+				c.Metrics.Enabled = true
+				c.Metrics.Listen = "127.0.0.1:9091"
+			},
+			wantErr: false,
+		},
+		{
+			name: "metrics enabled without listen address",
+			modify: func(c *Config) {
+				c.Metrics.Enabled = true
+				c.Metrics.Listen = ""
+			},
+			wantErr: true,
+		},
 		{
 			name:    "invalid log level",
 			modify:  func(c *Config) { c.Logging.Level = "invalid" },
@@ -123,6 +639,21 @@ func TestValidate(t *testing.T) {
 			modify:  func(c *Config) { c.Logging.Level = "error" },
 			wantErr: false,
 		},
+		{
+			name:    "valid log format json",
+			modify:  func(c *Config) { c.Logging.Format = "json" },
+			wantErr: false,
+		},
+		{
+			name:    "log format is case-insensitive",
+			modify:  func(c *Config) { c.Logging.Format = "JSON" },
+			wantErr: false,
+		},
+		{
+			name:    "invalid log format",
+			modify:  func(c *Config) { c.Logging.Format = "logfmt" },
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -149,7 +680,7 @@ func TestSaveAndLoad(t *testing.T) {
 
 	// Create and save config
 	cfg := Default()
-	cfg.DNS.Upstream = "1.1.1.1:53"
+	cfg.DNS.Upstreams = UpstreamList{"1.1.1.1:53", "9.9.9.9:53"}
 	cfg.Logging.Level = "debug"
 	cfg.Docker.Enabled = false
 
@@ -169,8 +700,8 @@ func TestSaveAndLoad(t *testing.T) {
 	}
 
 	// Verify values
-	if loaded.DNS.Upstream != "1.1.1.1:53" {
-		t.Errorf("DNS.Upstream = %q, want %q", loaded.DNS.Upstream, "1.1.1.1:53")
+	if !slices.Equal(loaded.DNS.Upstreams, UpstreamList{"1.1.1.1:53", "9.9.9.9:53"}) {
+		t.Errorf("DNS.Upstreams = %v, want [1.1.1.1:53 9.9.9.9:53]", loaded.DNS.Upstreams)
 	}
 	if loaded.Logging.Level != "debug" {
 		t.Errorf("Logging.Level = %q, want %q", loaded.Logging.Level, "debug")
@@ -255,6 +786,79 @@ logging:
 	}
 }
 
+func TestLoadFromFile_Upstreams(t *testing.T) {
+	tests := []struct {
+		name string
+		yaml string
+		want UpstreamList
+	}{
+		{name: "single server", yaml: `upstream: "1.1.1.1:53"`, want: UpstreamList{"1.1.1.1:53"}},
+		{name: "list", yaml: `upstream: ["10.8.0.1:53", "1.1.1.1:53"]`, want: UpstreamList{"10.8.0.1:53", "1.1.1.1:53"}},
+		{name: "omitted", yaml: `listen: ":15353"`, want: UpstreamList{"8.8.8.8:53"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(t.TempDir(), "config.yaml")
+			data := "dns:\n  " + tt.yaml + "\n"
+			if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			cfg, err := LoadFromFile(configPath)
+			if err != nil {
+				t.Fatalf("LoadFromFile() error = %v", err)
+			}
+			if !slices.Equal(cfg.DNS.Upstreams, tt.want) {
+				t.Errorf("DNS.Upstreams = %v, want %v", cfg.DNS.Upstreams, tt.want)
+			}
+
+			// A single server is written back in the old form
+			if err := cfg.SaveToFile(configPath); err != nil {
+				t.Fatalf("SaveToFile() error = %v", err)
+			}
+			saved, _ := os.ReadFile(configPath)
+			if len(tt.want) == 1 && !strings.Contains(string(saved), "upstream: "+tt.want[0]) {
+				t.Errorf("expected single upstream as a string, got:\n%s", saved)
+			}
+		})
+	}
+}
+
+func TestLoadFromFile_EntrypointCasing(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	data := `
+entrypoints:
+  Postgres:
+    listen: ":25432"
+    target_port: 5432
+  MySQL:
+    listen: ":23306"
+    target_port: 3306
+`
+	if err := os.WriteFile(configPath, []byte(data), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadFromFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	// The mixed-case entry replaces the lowercase default
+	if ep, ok := cfg.Entrypoints["postgres"]; !ok || ep.Listen != ":25432" {
+		t.Errorf("Entrypoints[postgres] = %+v, want listen :25432", ep)
+	}
+	if _, ok := cfg.Entrypoints["mysql"]; !ok {
+		t.Error("expected MySQL entrypoint to be stored as mysql")
+	}
+	for name := range cfg.Entrypoints {
+		if name != strings.ToLower(name) {
+			t.Errorf("entrypoint name %q not normalized", name)
+		}
+	}
+}
+
 func TestGetEntrypoint(t *testing.T) {
 	cfg := Default()
 
@@ -267,9 +871,63 @@ func TestGetEntrypoint(t *testing.T) {
 		t.Errorf("GetEntrypoint(postgres).Listen = %q, want %q", ep.Listen, ":15432")
 	}
 
+	// Names are case-insensitive
+	if _, ok := cfg.GetEntrypoint("Postgres"); !ok {
+		t.Error("GetEntrypoint(Postgres) returned false, want true")
+	}
+
 	// Non-existent entrypoint
 	_, ok = cfg.GetEntrypoint("nonexistent")
 	if ok {
 		t.Error("GetEntrypoint(nonexistent) returned true, want false")
 	}
 }
+
+func TestPTRRecords(t *testing.T) {
+	tests := []struct {
+		name string
+		dns  DNSConfig
+		want map[string]string
+	}{
+		{name: "disabled by default", dns: DNSConfig{Domains: []string{"localhost"}}, want: map[string]string{}},
+		{
+			name: "loopback points to the first domain",
+			dns:  DNSConfig{Domains: []string{"test", "localhost"}, ReverseLookup: true},
+			want: map[string]string{"127.0.0.1": "test", "::1": "test"},
+		},
+		{
+			name: "ptr adds and overrides",
+			dns: DNSConfig{
+				Domains:       []string{"localhost"},
+				ReverseLookup: true,
+				PTR:           map[string]string{"127.0.0.1": "dev.localhost", "172.18.0.2": "web.localhost"},
+			},
+			want: map[string]string{"127.0.0.1": "dev.localhost", "::1": "localhost", "172.18.0.2": "web.localhost"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dns.PTRRecords(); !maps.Equal(got, tt.want) {
+				t.Errorf("PTRRecords() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTCPEntrypointNames(t *testing.T) {
+	cfg := Default()
+	cfg.Entrypoints["mysql"] = EntrypointConfig{Listen: ":13306", TargetPort: 3306}
+	cfg.Entrypoints["unused"] = EntrypointConfig{Listen: ":19999"}
+
+	got := cfg.TCPEntrypointNames()
+	want := []string{"mongo", "mysql", "postgres"}
+	if len(got) != len(want) {
+		t.Fatalf("TCPEntrypointNames() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("TCPEntrypointNames()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config schema version written by this release.
+// Files without a version field are version 1.
+const CurrentVersion = 2
+
+// migration upgrades a config document by one schema version and returns a
+// description of each change it made.
+type migration func(doc *yaml.Node) []string
+
+// migrations[i] upgrades a document from version i+1 to i+2.
+var migrations = []migration{
+	migrateEntrypointTypes, // 1 -> 2
+}
+
+// migrate upgrades a config document, the mapping at the root of a config
+// file, to CurrentVersion in place. Working on the YAML nodes keeps the
+// file's comments and key order. It returns the version the document had and
+// the changes made.
+func migrate(doc *yaml.Node) (int, []string, error) {
+	version := 1
+	if v := mappingValue(doc, "version"); v != nil {
+		n, err := strconv.Atoi(v.Value)
+		if v.Kind != yaml.ScalarNode || err != nil || n < 1 {
+			return 0, nil, fmt.Errorf("version must be a positive integer, got %v", v.Value)
+		}
+		version = n
+	}
+	if version > CurrentVersion {
+		return version, nil, fmt.Errorf("config version %d is newer than this devproxy supports (%d); upgrade devproxy", version, CurrentVersion)
+	}
+
+	from := version
+	var changes []string
+	for ; version < CurrentVersion; version++ {
+		changes = append(changes, migrations[version-1](doc)...)
+	}
+	if from < CurrentVersion {
+		setVersion(doc, CurrentVersion)
+	}
+	return from, changes, nil
+}
+
+// migrateEntrypointTypes sets the type of each entrypoint, which version 1
+// derived from the entrypoint's name and target_port.
+func migrateEntrypointTypes(doc *yaml.Node) []string {
+	entrypoints := mappingValue(doc, "entrypoints")
+	if entrypoints == nil || entrypoints.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	eps := make(map[string]*yaml.Node)
+	names := make([]string, 0, len(entrypoints.Content)/2)
+	for i := 0; i+1 < len(entrypoints.Content); i += 2 {
+		name := entrypoints.Content[i].Value
+		eps[name] = entrypoints.Content[i+1]
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changes []string
+	for _, name := range names {
+		ep := eps[name]
+		if ep.Kind != yaml.MappingNode || mappingValue(ep, "type") != nil {
+			continue
+		}
+		var targetPort int
+		if v := mappingValue(ep, "target_port"); v != nil {
+			targetPort, _ = strconv.Atoi(v.Value)
+		}
+		if typ := inferEntrypointType(name, targetPort); typ != "" {
+			ep.Content = append(ep.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "type"},
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: typ},
+			)
+			changes = append(changes, fmt.Sprintf("entrypoints.%s.type set to %s", name, typ))
+		}
+	}
+	return changes
+}
+
+// mappingValue returns the value of key in the mapping node m, or nil if m
+// has no such key.
+func mappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setVersion sets the version of doc, adding it as the first key if doc has
+// none. A comment above the file's first key stays at the top.
+func setVersion(doc *yaml.Node, version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value == "version" {
+			doc.Content[i+1] = value
+			return
+		}
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	if len(doc.Content) > 0 {
+		key.HeadComment, doc.Content[0].HeadComment = doc.Content[0].HeadComment, ""
+	}
+	doc.Content = append([]*yaml.Node{key, value}, doc.Content...)
+}
+
+// inferEntrypointType returns the type of an entrypoint without an explicit
+// one: "http" and "https" by name, "tcp" if it has a target_port. It returns
+// an empty string for entrypoints that are not served.
+func inferEntrypointType(name string, targetPort int) string {
+	switch name = strings.ToLower(name); {
+	case name == EntrypointHTTP || name == EntrypointHTTPS:
+		return name
+	case targetPort > 0:
+		return EntrypointTCP
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseDoc returns the root mapping of the YAML document src.
+func parseDoc(t *testing.T, src string) *yaml.Node {
+	t.Helper()
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(src), &root); err != nil {
+		t.Fatalf("failed to parse %q: %v", src, err)
+	}
+	return root.Content[0]
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("infers entrypoint types of version 1", func(t *testing.T) {
+		doc := parseDoc(t, `
+entrypoints:
+  http: {listen: ":8080"}
+  redis: {listen: ":16379", target_port: 6379}
+  unused: {listen: ":9999"}
+`)
+
+		from, changes, err := migrate(doc)
+		if err != nil {
+			t.Fatalf("migrate() error = %v", err)
+		}
+		if v := mappingValue(doc, "version"); from != 1 || v == nil || v.Value != strconv.Itoa(CurrentVersion) {
+			t.Errorf("expected migration from 1 to %d, got from %d to %v", CurrentVersion, from, v)
+		}
+		want := []string{"entrypoints.http.type set to http", "entrypoints.redis.type set to tcp"}
+		if strings.Join(changes, "; ") != strings.Join(want, "; ") {
+			t.Errorf("changes = %q, want %q", changes, want)
+		}
+		unused := mappingValue(mappingValue(doc, "entrypoints"), "unused")
+		if mappingValue(unused, "type") != nil {
+			t.Error("expected entrypoint without target_port to stay untyped")
+		}
+	})
+
+	t.Run("keeps explicit types", func(t *testing.T) {
+		doc := parseDoc(t, `entrypoints: {https: {type: https, listen: ":443"}}`)
+		if _, changes, err := migrate(doc); err != nil || len(changes) != 0 {
+			t.Errorf("expected no changes, got %q, %v", changes, err)
+		}
+	})
+
+	t.Run("current version is unchanged", func(t *testing.T) {
+		doc := parseDoc(t, fmt.Sprintf(`{version: %d, entrypoints: {http: {listen: ":80"}}}`, CurrentVersion))
+		from, changes, err := migrate(doc)
+		if err != nil || from != CurrentVersion || len(changes) != 0 {
+			t.Errorf("expected no migration, got from %d, changes %q, error %v", from, changes, err)
+		}
+	})
+
+	t.Run("rejects newer versions", func(t *testing.T) {
+		doc := parseDoc(t, fmt.Sprintf("version: %d", CurrentVersion+1))
+		if _, _, err := migrate(doc); err == nil || !strings.Contains(err.Error(), "upgrade devproxy") {
+			t.Errorf("expected error asking to upgrade devproxy, got %v", err)
+		}
+	})
+
+	t.Run("rejects invalid versions", func(t *testing.T) {
+		if _, _, err := migrate(parseDoc(t, "version: two")); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestLoadFromFile_MigratesOldSchema(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	old := `# written by an older devproxy
+dns:
+  listen: ":15353"
+  domains: ["localhost", "test"]
+  upstream: "1.1.1.1:53"
+entrypoints:
+  http:
+    listen: ":8080"
+  https:
+    listen: ":8443"
+  redis:
+    listen: ":16379"
+    target_port: 6379
+logging:
+  level: "debug"
+`
+	if err := os.WriteFile(configPath, []byte(old), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadFromFile(configPath)
+	if err != nil {
+		t.Fatalf("LoadFromFile() error = %v", err)
+	}
+
+	if cfg.Version != CurrentVersion {
+		t.Errorf("Version = %d, want %d", cfg.Version, CurrentVersion)
+	}
+	for name, want := range map[string]string{"http": "http", "https": "https", "redis": "tcp", "postgres": "tcp"} {
+		if ep := cfg.Entrypoints[name]; ep.Type != want {
+			t.Errorf("Entrypoints[%s].Type = %q, want %q", name, ep.Type, want)
+		}
+	}
+	// File values are kept and defaults fill the rest
+	if cfg.Entrypoints["http"].Listen != ":8080" || cfg.Logging.Level != "debug" {
+		t.Errorf("expected file values to be kept, got http %+v, level %q", cfg.Entrypoints["http"], cfg.Logging.Level)
+	}
+	if !cfg.Docker.Enabled || cfg.Proxy.CAHost != "proxy.localhost" {
+		t.Errorf("expected defaults for missing sections, got docker %+v, proxy %+v", cfg.Docker, cfg.Proxy)
+	}
+
+	// The upgraded form replaces the file, the original is kept as a backup
+	upgraded, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if !strings.Contains(string(upgraded), "version: 2") || !strings.Contains(string(upgraded), "type: tcp") {
+		t.Errorf("expected upgraded config file, got:\n%s", upgraded)
+	}
+	if !strings.Contains(string(upgraded), "# written by an older devproxy") {
+		t.Errorf("expected upgraded config file to keep its comments, got:\n%s", upgraded)
+	}
+	backup, err := os.ReadFile(configPath + ".v1.bak")
+	if err != nil || string(backup) != old {
+		t.Errorf("expected original config in backup, got %q, %v", backup, err)
+	}
+
+	// Loading the upgraded file migrates nothing
+	if _, err := LoadFromFile(configPath); err != nil {
+		t.Fatalf("LoadFromFile() of upgraded file error = %v", err)
+	}
+	if again, _ := os.ReadFile(configPath); string(again) != string(upgraded) {
+		t.Error("expected upgraded config file to be left alone")
+	}
+}
@@ -96,7 +96,7 @@ func (d *Daemon) Start() error {
 	time.Sleep(500 * time.Millisecond)
 
 	// Verify the process is still running
-	if !isProcessRunning(pid) {
+	if !IsProcessRunning(pid) {
 		_ = d.removePIDFile()
 		return fmt.Errorf("daemon process exited immediately - check logs at %s", paths.LogFile())
 	}
@@ -145,7 +145,7 @@ func (d *Daemon) Stop() error {
 			_ = d.removePIDFile()
 			return nil
 		case <-ticker.C:
-			if !isProcessRunning(pid) {
+			if !IsProcessRunning(pid) {
 				_ = d.removePIDFile()
 				return nil
 			}
@@ -184,7 +184,7 @@ func (d *Daemon) IsRunning() bool {
 	if err != nil {
 		return false
 	}
-	return isProcessRunning(pid)
+	return IsProcessRunning(pid)
 }
 
 // GetPID reads and returns the PID from the PID file.
@@ -241,7 +241,7 @@ func (d *Daemon) cleanStalePIDFile() error {
 		return nil
 	}
 
-	if !isProcessRunning(pid) {
+	if !IsProcessRunning(pid) {
 		// Process is dead, clean up stale PID file
 		return d.removePIDFile()
 	}
@@ -249,8 +249,8 @@ func (d *Daemon) cleanStalePIDFile() error {
 	return nil
 }
 
-// isProcessRunning checks if a process with the given PID is running.
-func isProcessRunning(pid int) bool {
+// IsProcessRunning checks if a process with the given PID is running.
+func IsProcessRunning(pid int) bool {
 	process, err := os.FindProcess(pid)
 	if err != nil {
 		return false
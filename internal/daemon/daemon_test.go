@@ -226,12 +226,12 @@ func TestPIDFile(t *testing.T) {
 
 func TestIsProcessRunning(t *testing.T) {
 	// Current process should be running
-	if !isProcessRunning(os.Getpid()) {
-		t.Error("isProcessRunning(current PID) = false, want true")
+	if !IsProcessRunning(os.Getpid()) {
+		t.Error("IsProcessRunning(current PID) = false, want true")
 	}
 
 	// Non-existent process should not be running
-	if isProcessRunning(99999999) {
-		t.Error("isProcessRunning(99999999) = true, want false")
+	if IsProcessRunning(99999999) {
+		t.Error("IsProcessRunning(99999999) = true, want false")
 	}
 }
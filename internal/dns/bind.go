@@ -0,0 +1,82 @@
+package dns
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"syscall"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/listen"
+	"github.com/munichmade/devproxy/internal/logging"
+)
+
+const (
+	// DefaultBindBackoff is the delay before the first bind retry.
+	// It doubles after each attempt, up to maxBindBackoff.
+	DefaultBindBackoff = 250 * time.Millisecond
+
+	// maxBindBackoff caps the delay between bind retries.
+	maxBindBackoff = 2 * time.Second
+)
+
+// ListenPacket binds a UDP socket on addr. While the address is in use, it
+// retries up to retries times with exponential backoff, e.g. while a previous
+// instance is still shutting down.
+func ListenPacket(addr string, retries int) (net.PacketConn, error) {
+	var conn net.PacketConn
+	err := bindWithRetry(addr, retries, DefaultBindBackoff, func() error {
+		var err error
+		conn, err = net.ListenPacket("udp", addr)
+		return err
+	})
+	return conn, err
+}
+
+// listenTCP binds a TCP listener on addr, retrying like ListenPacket. Unlike
+// the UDP socket, Go binds it with SO_REUSEADDR, so a restart is not blocked
+// by connections in TIME_WAIT.
+func listenTCP(addr string, retries int) (net.Listener, error) {
+	var listener net.Listener
+	err := bindWithRetry(addr, retries, DefaultBindBackoff, func() error {
+		var err error
+		listener, err = listen.TCP(addr, 0)
+		return err
+	})
+	return listener, err
+}
+
+// bindWithRetry calls bind until it succeeds, fails with an error other than
+// EADDRINUSE, or retries are exhausted.
+func bindWithRetry(addr string, retries int, backoff time.Duration, bind func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := bind()
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return err
+		}
+		if attempt >= retries {
+			return addrInUseError(addr, err, runtime.GOOS)
+		}
+
+		logging.Warn("DNS address in use, retrying", "addr", addr, "attempt", attempt+1, "delay", backoff)
+		time.Sleep(backoff)
+		backoff = min(backoff*2, maxBindBackoff)
+	}
+}
+
+// addrInUseError adds a hint for the common conflict with mDNSResponder when
+// binding port 53 on macOS.
+func addrInUseError(addr string, err error, goos string) error {
+	if goos != "darwin" {
+		return err
+	}
+	if _, port, splitErr := net.SplitHostPort(addr); splitErr != nil || port != "53" {
+		return err
+	}
+	return fmt.Errorf("%w (on macOS, port 53 is usually taken by mDNSResponder; "+
+		"use the default dns.listen \":15353\" and run 'devproxy setup' to point the resolver at it)", err)
+}
@@ -0,0 +1,101 @@
+package dns
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestListenPacket_Retry(t *testing.T) {
+	held, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to pre-bind port: %v", err)
+	}
+	addr := held.LocalAddr().String()
+
+	t.Run("fails immediately without retries", func(t *testing.T) {
+		start := time.Now()
+		conn, err := ListenPacket(addr, 0)
+		if err == nil {
+			conn.Close()
+			t.Fatal("expected bind to fail while port is held")
+		}
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			t.Errorf("expected EADDRINUSE, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed >= DefaultBindBackoff {
+			t.Errorf("expected no retry delay, took %v", elapsed)
+		}
+	})
+
+	t.Run("succeeds once the port is released", func(t *testing.T) {
+		// A previous instance shutting down releases the port shortly after
+		go func() {
+			time.Sleep(DefaultBindBackoff / 2)
+			held.Close()
+		}()
+
+		conn, err := ListenPacket(addr, 3)
+		if err != nil {
+			t.Fatalf("expected bind to succeed after retry, got %v", err)
+		}
+		conn.Close()
+	})
+}
+
+func TestServerStart_AddrInUse(t *testing.T) {
+	held, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to pre-bind port: %v", err)
+	}
+	defer held.Close()
+
+	s := New(Config{Addr: held.Addr().String(), BindRetries: 1})
+	if err := s.Start(); err == nil {
+		s.Stop()
+		t.Fatal("expected start to fail while TCP port is held")
+	} else if !errors.Is(err, syscall.EADDRINUSE) {
+		t.Errorf("expected EADDRINUSE, got %v", err)
+	}
+	if s.Running() {
+		t.Error("server should not be running")
+	}
+
+	// The UDP socket bound before the TCP failure is released again
+	conn, err := net.ListenPacket("udp", held.Addr().String())
+	if err != nil {
+		t.Fatalf("expected UDP port to be released, got %v", err)
+	}
+	conn.Close()
+}
+
+func TestAddrInUseError(t *testing.T) {
+	inUse := &net.OpError{Op: "listen", Net: "udp", Err: syscall.EADDRINUSE}
+
+	tests := []struct {
+		name     string
+		addr     string
+		goos     string
+		wantHint bool
+	}{
+		{name: "macOS port 53", addr: "127.0.0.1:53", goos: "darwin", wantHint: true},
+		{name: "macOS port 53 any address", addr: ":53", goos: "darwin", wantHint: true},
+		{name: "macOS other port", addr: ":15353", goos: "darwin", wantHint: false},
+		{name: "linux port 53", addr: ":53", goos: "linux", wantHint: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := addrInUseError(tt.addr, inUse, tt.goos)
+			if !errors.Is(err, syscall.EADDRINUSE) {
+				t.Errorf("expected error to wrap EADDRINUSE, got %v", err)
+			}
+			if hasHint := strings.Contains(err.Error(), "mDNSResponder"); hasHint != tt.wantHint {
+				t.Errorf("hint present = %v, want %v: %v", hasHint, tt.wantHint, err)
+			}
+		})
+	}
+}
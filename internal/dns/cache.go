@@ -0,0 +1,213 @@
+package dns
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/munichmade/devproxy/internal/logging"
+)
+
+// DefaultCacheSize is how many upstream answers are cached when no size is
+// configured.
+const DefaultCacheSize = 1000
+
+// cache holds upstream answers until their TTL expires.
+type cache struct {
+	mu         sync.Mutex
+	entries    map[string]cacheEntry
+	maxEntries int
+	now        func() time.Time
+}
+
+// cacheEntry is a cached answer and when it was stored and expires.
+type cacheEntry struct {
+	msg     *dns.Msg
+	stored  time.Time
+	expires time.Time
+}
+
+// newCache creates a cache holding up to maxEntries answers.
+func newCache(maxEntries int) *cache {
+	return &cache{
+		entries:    make(map[string]cacheEntry),
+		maxEntries: maxEntries,
+		now:        time.Now,
+	}
+}
+
+// cacheKey returns the key answers to r are cached under, in the answer cache
+// and the stale answers alike: its question's name, ignoring case, type and
+// class. A message without a question has no key.
+func cacheKey(r *dns.Msg) string {
+	if len(r.Question) == 0 {
+		return ""
+	}
+	q := r.Question[0]
+	return fmt.Sprintf("%s/%d/%d", strings.ToLower(q.Name), q.Qtype, q.Qclass)
+}
+
+// get returns a copy of the cached answer for r with TTLs lowered by the time
+// it spent in the cache, or nil if there is none or it expired.
+func (c *cache) get(r *dns.Msg) *dns.Msg {
+	resp, stored := c.lookup(r)
+	if resp == nil {
+		return nil
+	}
+
+	elapsed := uint32(c.now().Sub(stored) / time.Second)
+	forEachRR(resp, func(rr dns.RR) {
+		if rr.Header().Ttl > elapsed {
+			rr.Header().Ttl -= elapsed
+		} else {
+			rr.Header().Ttl = 0
+		}
+	})
+	return resp
+}
+
+// lookup returns a copy of the answer cached for r as it was stored and when
+// it was stored, or nil if there is none or it expired.
+func (c *cache) lookup(r *dns.Msg) (*dns.Msg, time.Time) {
+	key := cacheKey(r)
+	if key == "" {
+		return nil, time.Time{}
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !c.now().Before(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+	c.mu.Unlock()
+	if !ok {
+		return nil, time.Time{}
+	}
+	return entry.msg.Copy(), entry.stored
+}
+
+// put caches resp as the answer for r for the TTL it allows. When the cache is
+// full, expired answers are dropped first, then the one expiring soonest.
+func (c *cache) put(r *dns.Msg, resp *dns.Msg) {
+	key := cacheKey(r)
+	ttl, ok := cacheTTL(resp)
+	if key == "" || !ok || ttl == 0 {
+		return
+	}
+	c.putFor(r, resp, time.Duration(ttl)*time.Second)
+}
+
+// putFor caches resp as the answer for r for lifetime, regardless of its TTLs.
+func (c *cache) putFor(r *dns.Msg, resp *dns.Msg, lifetime time.Duration) {
+	key := cacheKey(r)
+	if key == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= c.maxEntries {
+		c.evict(now)
+	}
+	c.entries[key] = cacheEntry{
+		msg:     resp.Copy(),
+		stored:  now,
+		expires: now.Add(lifetime),
+	}
+}
+
+// evict makes room for one entry. The caller must hold c.mu.
+func (c *cache) evict(now time.Time) {
+	var soonest string
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+			continue
+		}
+		if soonest == "" || entry.expires.Before(c.entries[soonest].expires) {
+			soonest = key
+		}
+	}
+	if len(c.entries) >= c.maxEntries && soonest != "" {
+		delete(c.entries, soonest)
+	}
+}
+
+// flush removes all answers and returns how many there were.
+func (c *cache) flush() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := len(c.entries)
+	c.entries = make(map[string]cacheEntry)
+	return n
+}
+
+// size returns the number of cached answers, including expired ones not yet dropped.
+func (c *cache) size() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
+
+// cacheTTL returns how long resp may be cached: the lowest TTL of its answers,
+// or for NXDOMAIN and empty answers the negative TTL from the authority's SOA
+// record (RFC 2308). Truncated responses and failures are not cached.
+func cacheTTL(resp *dns.Msg) (uint32, bool) {
+	if resp.Truncated {
+		return 0, false
+	}
+
+	switch {
+	case resp.Rcode == dns.RcodeSuccess && len(resp.Answer) > 0:
+		ttl := resp.Answer[0].Header().Ttl
+		for _, rr := range resp.Answer[1:] {
+			ttl = min(ttl, rr.Header().Ttl)
+		}
+		return ttl, true
+
+	case resp.Rcode == dns.RcodeSuccess || resp.Rcode == dns.RcodeNameError:
+		for _, rr := range resp.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				return min(soa.Hdr.Ttl, soa.Minttl), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// forEachRR calls fn for each record of m, skipping OPT pseudo-records, which
+// use the TTL field for flags.
+func forEachRR(m *dns.Msg, fn func(rr dns.RR)) {
+	for _, section := range [][]dns.RR{m.Answer, m.Ns, m.Extra} {
+		for _, rr := range section {
+			if rr.Header().Rrtype == dns.TypeOPT {
+				continue
+			}
+			fn(rr)
+		}
+	}
+}
+
+// Flush removes all cached upstream answers.
+func (s *Server) Flush() {
+	if s.cache == nil {
+		return
+	}
+	n := s.cache.flush()
+	logging.Info("DNS cache flushed", "entries", n)
+}
+
+// CacheSize returns the number of cached upstream answers.
+func (s *Server) CacheSize() int {
+	if s.cache == nil {
+		return 0
+	}
+	return s.cache.size()
+}
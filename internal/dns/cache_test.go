@@ -0,0 +1,180 @@
+package dns
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	c := newCache(2)
+	c.now = func() time.Time { return now }
+
+	question := func(name string) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		return r
+	}
+	answer := func(r *dns.Msg, ttls ...uint32) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		for _, ttl := range ttls {
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: ttl},
+				A:   net.ParseIP("192.0.2.1"),
+			})
+		}
+		return m
+	}
+	negative := func(r *dns.Msg, rcode int, soaTTL, minTTL uint32) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetRcode(r, rcode)
+		m.Ns = []dns.RR{&dns.SOA{
+			Hdr:    dns.RR_Header{Name: "com.", Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: soaTTL},
+			Minttl: minTTL,
+		}}
+		return m
+	}
+
+	t.Run("expires after the lowest answer TTL", func(t *testing.T) {
+		r := question("example.com.")
+		c.put(r, answer(r, 300, 60))
+
+		now = now.Add(45 * time.Second)
+		cached := c.get(r)
+		if cached == nil {
+			t.Fatal("expected cached answer")
+		}
+		if ttl := cached.Answer[1].Header().Ttl; ttl != 15 {
+			t.Errorf("expected TTL lowered to 15, got %d", ttl)
+		}
+
+		now = now.Add(15 * time.Second)
+		if c.get(r) != nil {
+			t.Error("expected answer to expire")
+		}
+	})
+
+	t.Run("matches names case-insensitively", func(t *testing.T) {
+		c.put(question("example.org."), answer(question("example.org."), 60))
+		if c.get(question("EXAMPLE.org.")) == nil {
+			t.Error("expected cached answer for differently cased name")
+		}
+	})
+
+	t.Run("caches negative answers for the SOA minimum", func(t *testing.T) {
+		c.flush()
+		r := question("missing.example.com.")
+		c.put(r, negative(r, dns.RcodeNameError, 900, 30))
+
+		cached := c.get(r)
+		if cached == nil || cached.Rcode != dns.RcodeNameError {
+			t.Fatalf("expected cached NXDOMAIN, got %v", cached)
+		}
+		now = now.Add(30 * time.Second)
+		if c.get(r) != nil {
+			t.Error("expected negative answer to expire after the SOA minimum")
+		}
+	})
+
+	t.Run("putFor keeps answers for the given lifetime", func(t *testing.T) {
+		c.flush()
+		r := question("stale.example.com.")
+		c.putFor(r, answer(r, 60), time.Hour)
+
+		now = now.Add(59 * time.Minute)
+		cached, _ := c.lookup(r)
+		if cached == nil || cached.Answer[0].Header().Ttl != 60 {
+			t.Fatalf("expected answer stored as is past its TTL, got %v", cached)
+		}
+		now = now.Add(time.Minute)
+		if cached, _ := c.lookup(r); cached != nil {
+			t.Error("expected answer to expire after its lifetime")
+		}
+	})
+
+	tests := []struct {
+		name string
+		resp func(r *dns.Msg) *dns.Msg
+	}{
+		{name: "zero TTL", resp: func(r *dns.Msg) *dns.Msg { return answer(r, 0) }},
+		{name: "server failure", resp: func(r *dns.Msg) *dns.Msg { m := new(dns.Msg); m.SetRcode(r, dns.RcodeServerFailure); return m }},
+		{name: "NXDOMAIN without SOA", resp: func(r *dns.Msg) *dns.Msg { m := new(dns.Msg); m.SetRcode(r, dns.RcodeNameError); return m }},
+		{name: "truncated", resp: func(r *dns.Msg) *dns.Msg { m := answer(r, 60); m.Truncated = true; return m }},
+	}
+	for _, tt := range tests {
+		t.Run("does not cache "+tt.name, func(t *testing.T) {
+			c.flush()
+			r := question("nocache.example.com.")
+			c.put(r, tt.resp(r))
+			if c.get(r) != nil {
+				t.Error("expected response not to be cached")
+			}
+		})
+	}
+
+	t.Run("evicts the answer expiring soonest", func(t *testing.T) {
+		c.flush()
+		a, b, d := question("a.example.com."), question("b.example.com."), question("d.example.com.")
+		c.put(a, answer(a, 60))
+		c.put(b, answer(b, 300))
+		c.put(d, answer(d, 300))
+
+		if c.size() != 2 {
+			t.Fatalf("expected 2 entries, got %d", c.size())
+		}
+		if c.get(a) != nil {
+			t.Error("expected a.example.com to be evicted")
+		}
+		if c.get(b) == nil || c.get(d) == nil {
+			t.Error("expected later-expiring answers to be kept")
+		}
+	})
+}
+
+func TestServer_CachesUpstreamAnswers(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	upstream, stop := startUpstream(t, "192.0.2.10")
+	s := New(Config{Upstreams: []string{upstream}})
+	s.client.Timeout = 200 * time.Millisecond
+
+	query := func(name string) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, dns.TypeA)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		s.handleUpstreamQuery(m, r, false)
+		return m
+	}
+
+	query("example.com.")
+	if s.CacheSize() != 1 {
+		t.Fatalf("expected 1 cached answer, got %d", s.CacheSize())
+	}
+
+	// Answered from the cache while the upstream is down, in the query's case
+	stop()
+	m := query("Example.COM.")
+	if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+		t.Fatalf("expected cached answer, got rcode %d with %d answers", m.Rcode, len(m.Answer))
+	}
+	if name := m.Answer[0].Header().Name; name != "Example.COM." {
+		t.Errorf("answer name = %q, want %q", name, "Example.COM.")
+	}
+
+	s.Flush()
+	if s.CacheSize() != 0 {
+		t.Errorf("expected cache to be flushed, got %d entries", s.CacheSize())
+	}
+	if m := query("example.com."); m.Rcode != dns.RcodeServerFailure {
+		t.Errorf("expected SERVFAIL after flush with upstream down, got rcode %d", m.Rcode)
+	}
+}
@@ -0,0 +1,39 @@
+package dns
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Query asks the DNS server at addr for the A and AAAA records of name and
+// returns the addresses it answered with.
+func Query(addr, name string, timeout time.Duration) ([]net.IP, error) {
+	c := &dns.Client{Timeout: timeout}
+
+	var ips []net.IP
+	for _, qtype := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := new(dns.Msg)
+		m.SetQuestion(dns.Fqdn(name), qtype)
+
+		r, _, err := c.Exchange(m, addr)
+		if err != nil {
+			return nil, fmt.Errorf("query %s at %s: %w", name, addr, err)
+		}
+		if r.Rcode != dns.RcodeSuccess {
+			return nil, fmt.Errorf("query %s at %s: %s", name, addr, dns.RcodeToString[r.Rcode])
+		}
+
+		for _, rr := range r.Answer {
+			switch rr := rr.(type) {
+			case *dns.A:
+				ips = append(ips, rr.A)
+			case *dns.AAAA:
+				ips = append(ips, rr.AAAA)
+			}
+		}
+	}
+	return ips, nil
+}
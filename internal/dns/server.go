@@ -2,8 +2,10 @@
 package dns
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -22,8 +24,22 @@ const (
 
 	// DefaultUpstream is the default upstream DNS server.
 	DefaultUpstream = "8.8.8.8:53"
+
+	// MaxUDPSize is the EDNS0 UDP payload size advertised in responses.
+	MaxUDPSize = 1232
+
+	// StaleTTL is the TTL for stale answers served during an upstream outage.
+	StaleTTL = 30
+
+	// MaxStaleAge is how long an upstream answer is kept for serving stale.
+	MaxStaleAge = 24 * time.Hour
 )
 
+// upstreamStagger is how long exchange waits for an upstream to answer before
+// also querying the next one, so an unreachable upstream does not delay every
+// query by the full client timeout.
+var upstreamStagger = 300 * time.Millisecond
+
 // Server is a DNS server that resolves local development domains.
 type Server struct {
 	// addr is the address to listen on (e.g., "127.0.0.1:53").
@@ -35,8 +51,30 @@ type Server struct {
 	// resolveIP is the IP address to resolve local domains to.
 	resolveIP net.IP
 
-	// upstream is the upstream DNS server for non-local queries.
-	upstream string
+	// domainIPs overrides resolveIP for domains and their subdomains (normalized like records).
+	domainIPs map[string]net.IP
+
+	// containerDomains are answered with the address from containerIP when it has one.
+	containerDomains []string
+
+	// containerIP returns the container address routed for a name, or nil.
+	containerIP func(name string) net.IP
+
+	// upstreams are the upstream DNS servers for non-local queries, tried in order.
+	upstreams []string
+
+	// lastUpstream is the upstream that answered last; it is tried first.
+	lastUpstream string
+
+	// records maps static hostnames (lowercase, without trailing dot) to IPs.
+	records map[string]net.IP
+
+	// ptr maps reverse names (e.g., 1.0.0.127.in-addr.arpa, normalized like
+	// records) to the hostnames they point to.
+	ptr map[string]string
+
+	// aliases maps alias names to their CNAME targets (both normalized like records).
+	aliases map[string]string
 
 	// udpServer is the UDP DNS server.
 	udpServer *dns.Server
@@ -47,6 +85,10 @@ type Server struct {
 	// client is the DNS client for upstream queries.
 	client *dns.Client
 
+	// tcpClient queries upstreams over TCP, for queries received over TCP
+	// and answers that were truncated over UDP.
+	tcpClient *dns.Client
+
 	// mu protects the server state.
 	mu sync.RWMutex
 
@@ -55,6 +97,24 @@ type Server struct {
 
 	// prebound listener for privilege dropping
 	preboundListener net.PacketConn
+
+	// bindRetries is how often binding is retried while the address is in use.
+	bindRetries int
+
+	// serveStale enables answering from the last known response on upstream failure.
+	serveStale bool
+
+	// localOnly answers non-local queries with localOnlyRcode instead of
+	// forwarding them upstream.
+	localOnly      bool
+	localOnlyRcode int
+
+	// stale holds the last successful upstream response per question for up
+	// to MaxStaleAge, bounded like the answer cache.
+	stale *cache
+
+	// cache holds upstream answers until their TTL expires (nil = disabled).
+	cache *cache
 }
 
 // Config holds DNS server configuration.
@@ -68,8 +128,57 @@ type Config struct {
 	// ResolveIP is the IP to resolve local domains to (default: 127.0.0.1).
 	ResolveIP net.IP
 
-	// Upstream is the upstream DNS server (default: "8.8.8.8:53").
-	Upstream string
+	// DomainIPs overrides ResolveIP for domains and their subdomains. The
+	// most specific domain wins.
+	DomainIPs map[string]net.IP
+
+	// ContainerDomains are domains whose names resolve to the address of the
+	// container routed for them, as returned by ContainerIP, bypassing the
+	// proxy. Names without a container fall back to DomainIPs and ResolveIP.
+	ContainerDomains []string
+
+	// ContainerIP returns the address of the container routed for a name,
+	// or nil if there is none.
+	ContainerIP func(name string) net.IP
+
+	// Upstreams are the upstream DNS servers (default: "8.8.8.8:53"). They
+	// are tried in order until one responds, starting with the last one
+	// that did.
+	Upstreams []string
+
+	// Records maps static hostnames to IPs. They are answered locally
+	// regardless of Domains.
+	Records map[string]net.IP
+
+	// PTR maps IPs to the hostnames reverse lookups of them are answered
+	// with. Other reverse lookups are forwarded upstream.
+	PTR map[string]string
+
+	// Aliases maps alias names to the names they are CNAMEs for. Queries
+	// for an alias are answered with the CNAME and, if the server answers
+	// the target itself, its records.
+	Aliases map[string]string
+
+	// ServeStale answers with the last known upstream response when the
+	// upstream fails, instead of returning SERVFAIL.
+	ServeStale bool
+
+	// LocalOnly answers queries that are not answered locally with
+	// LocalOnlyRcode instead of forwarding them upstream, so nothing leaks
+	// and nothing waits on an unreachable upstream when offline.
+	LocalOnly bool
+
+	// LocalOnlyRcode is the rcode of non-local queries in local-only mode
+	// (default: dns.RcodeRefused).
+	LocalOnlyRcode int
+
+	// BindRetries is how often binding the address is retried, with
+	// exponential backoff, while it is in use (default: 0, fail immediately).
+	BindRetries int
+
+	// CacheSize is how many upstream answers are cached for their TTL
+	// (0 = DefaultCacheSize, negative = no caching).
+	CacheSize int
 }
 
 // DefaultConfig returns a default DNS server configuration.
@@ -78,7 +187,7 @@ func DefaultConfig() Config {
 		Addr:      fmt.Sprintf("127.0.0.1:%d", DefaultPort),
 		Domains:   []string{"localhost"},
 		ResolveIP: net.ParseIP("127.0.0.1"),
-		Upstream:  DefaultUpstream,
+		Upstreams: []string{DefaultUpstream},
 	}
 }
 
@@ -93,18 +202,45 @@ func New(cfg Config) *Server {
 	if cfg.ResolveIP == nil {
 		cfg.ResolveIP = net.ParseIP("127.0.0.1")
 	}
-	if cfg.Upstream == "" {
-		cfg.Upstream = DefaultUpstream
+	if len(cfg.Upstreams) == 0 {
+		cfg.Upstreams = []string{DefaultUpstream}
+	}
+	if cfg.LocalOnlyRcode == dns.RcodeSuccess {
+		cfg.LocalOnlyRcode = dns.RcodeRefused
+	}
+
+	var answers *cache
+	switch {
+	case cfg.CacheSize == 0:
+		answers = newCache(DefaultCacheSize)
+	case cfg.CacheSize > 0:
+		answers = newCache(cfg.CacheSize)
 	}
 
 	return &Server{
-		addr:      cfg.Addr,
-		domains:   cfg.Domains,
-		resolveIP: cfg.ResolveIP,
-		upstream:  cfg.Upstream,
+		addr:             cfg.Addr,
+		domains:          cfg.Domains,
+		resolveIP:        cfg.ResolveIP,
+		domainIPs:        normalizeRecords(cfg.DomainIPs),
+		containerDomains: cfg.ContainerDomains,
+		containerIP:      cfg.ContainerIP,
+		upstreams:        cfg.Upstreams,
+		records:          normalizeRecords(cfg.Records),
+		ptr:              normalizePTR(cfg.PTR),
+		aliases:          normalizeAliases(cfg.Aliases),
+		serveStale:       cfg.ServeStale,
+		localOnly:        cfg.LocalOnly,
+		localOnlyRcode:   cfg.LocalOnlyRcode,
+		bindRetries:      cfg.BindRetries,
+		stale:            newCache(DefaultCacheSize),
+		cache:            answers,
 		client: &dns.Client{
 			Timeout: 5 * time.Second,
 		},
+		tcpClient: &dns.Client{
+			Net:     "tcp",
+			Timeout: 5 * time.Second,
+		},
 	}
 }
 
@@ -128,41 +264,51 @@ func (s *Server) Start() error {
 	// Create DNS handler
 	handler := dns.HandlerFunc(s.handleDNS)
 
-	// Start UDP server
-	s.udpServer = &dns.Server{
-		Addr:    s.addr,
-		Net:     "udp",
-		Handler: handler,
+	// Bind sockets up front so an address in use can be retried
+	udpConn := s.preboundListener
+	if udpConn == nil {
+		conn, err := ListenPacket(s.addr, s.bindRetries)
+		if err != nil {
+			return fmt.Errorf("UDP server failed: %w", err)
+		}
+		udpConn = conn
+	}
+	tcpListener, err := listenTCP(s.addr, s.bindRetries)
+	if err != nil {
+		if s.preboundListener == nil {
+			udpConn.Close()
+		}
+		return fmt.Errorf("TCP server failed: %w", err)
 	}
 
-	// If we have a prebound listener, use it
-	if s.preboundListener != nil {
-		s.udpServer.PacketConn = s.preboundListener
+	// Start UDP server
+	s.udpServer = &dns.Server{
+		Addr:       s.addr,
+		Net:        "udp",
+		Handler:    handler,
+		PacketConn: udpConn,
 	}
 
 	// Start TCP server
 	s.tcpServer = &dns.Server{
-		Addr:    s.addr,
-		Net:     "tcp",
-		Handler: handler,
+		Addr:     s.addr,
+		Net:      "tcp",
+		Handler:  handler,
+		Listener: tcpListener,
 	}
 
 	// Start UDP in goroutine
 	udpErrCh := make(chan error, 1)
 	go func() {
 		logging.Info("starting DNS server (UDP)", "addr", s.addr)
-		if s.preboundListener != nil {
-			udpErrCh <- s.udpServer.ActivateAndServe()
-		} else {
-			udpErrCh <- s.udpServer.ListenAndServe()
-		}
+		udpErrCh <- s.udpServer.ActivateAndServe()
 	}()
 
 	// Start TCP in goroutine
 	tcpErrCh := make(chan error, 1)
 	go func() {
 		logging.Info("starting DNS server (TCP)", "addr", s.addr)
-		tcpErrCh <- s.tcpServer.ListenAndServe()
+		tcpErrCh <- s.tcpServer.ActivateAndServe()
 	}()
 
 	// Give servers a moment to start and check for immediate errors
@@ -225,9 +371,9 @@ func (s *Server) Addr() string {
 }
 
 // UpdateConfig updates the DNS server configuration at runtime.
-// Only domains and upstream can be changed without restart.
+// Only domains and upstreams can be changed without restart.
 // The listen address cannot be changed at runtime.
-func (s *Server) UpdateConfig(domains []string, upstream string) {
+func (s *Server) UpdateConfig(domains []string, upstreams []string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -236,9 +382,183 @@ func (s *Server) UpdateConfig(domains []string, upstream string) {
 		logging.Info("DNS domains updated", "domains", domains)
 	}
 
-	if upstream != "" && upstream != s.upstream {
-		s.upstream = upstream
-		logging.Info("DNS upstream updated", "upstream", upstream)
+	if len(upstreams) > 0 && !slices.Equal(upstreams, s.upstreams) {
+		s.upstreams = upstreams
+		s.lastUpstream = ""
+		logging.Info("DNS upstreams updated", "upstreams", upstreams)
+
+		// Answers from the old upstream may differ
+		if s.cache != nil {
+			s.cache.flush()
+		}
+	}
+}
+
+// SetRecords replaces the static records at runtime.
+func (s *Server) SetRecords(records map[string]net.IP) {
+	normalized := normalizeRecords(records)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var added, removed int
+	for name := range normalized {
+		if _, ok := s.records[name]; !ok {
+			added++
+		}
+	}
+	for name := range s.records {
+		if _, ok := normalized[name]; !ok {
+			removed++
+		}
+	}
+
+	s.records = normalized
+	logging.Info("DNS records updated", "count", len(normalized), "added", added, "removed", removed)
+}
+
+// GetRecords returns a copy of the current static records.
+func (s *Server) GetRecords() map[string]net.IP {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make(map[string]net.IP, len(s.records))
+	for name, ip := range s.records {
+		records[name] = ip
+	}
+	return records
+}
+
+// normalizeRecords lowercases names and strips trailing dots.
+func normalizeRecords(records map[string]net.IP) map[string]net.IP {
+	normalized := make(map[string]net.IP, len(records))
+	for name, ip := range records {
+		normalized[strings.ToLower(strings.TrimSuffix(name, "."))] = ip
+	}
+	return normalized
+}
+
+// SetPTR replaces the reverse lookup records (IP -> hostname) at runtime.
+func (s *Server) SetPTR(ptr map[string]string) {
+	normalized := normalizePTR(ptr)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ptr = normalized
+	logging.Info("DNS PTR records updated", "count", len(normalized))
+}
+
+// normalizePTR keys PTR records by the reverse name of their IP. Invalid IPs
+// are skipped; they are rejected by config validation.
+func normalizePTR(ptr map[string]string) map[string]string {
+	normalized := make(map[string]string, len(ptr))
+	for ip, host := range ptr {
+		reverse, err := dns.ReverseAddr(ip)
+		if err != nil {
+			continue
+		}
+		normalized[normalizeName(reverse)] = dns.Fqdn(host)
+	}
+	return normalized
+}
+
+// SetDomainIPs replaces the per-domain resolve IPs and container domains at runtime.
+func (s *Server) SetDomainIPs(domainIPs map[string]net.IP, containerDomains []string) {
+	normalized := normalizeRecords(domainIPs)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.domainIPs = normalized
+	s.containerDomains = containerDomains
+	logging.Info("DNS domain IPs updated", "domains", len(normalized), "container_domains", containerDomains)
+}
+
+// localIP returns the address a local name resolves to: its container's
+// address in a container domain, else the IP of the most specific matching
+// domain in domainIPs, else resolveIP.
+func (s *Server) localIP(name string) net.IP {
+	name = normalizeName(name)
+
+	s.mu.RLock()
+	containerDomains, containerIP := s.containerDomains, s.containerIP
+	var ip net.IP
+	best := -1
+	for domain, domainIP := range s.domainIPs {
+		if (name == domain || strings.HasSuffix(name, "."+domain)) && len(domain) > best {
+			ip, best = domainIP, len(domain)
+		}
+	}
+	s.mu.RUnlock()
+
+	if containerIP != nil && IsLocalName(containerDomains, name) {
+		if addr := containerIP(name); addr != nil {
+			return addr
+		}
+	}
+	if ip != nil {
+		return ip
+	}
+	return s.resolveIP
+}
+
+// SetAliases replaces the CNAME aliases at runtime.
+func (s *Server) SetAliases(aliases map[string]string) {
+	normalized := normalizeAliases(aliases)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aliases = normalized
+	logging.Info("DNS aliases updated", "count", len(normalized))
+}
+
+// normalizeAliases lowercases alias names and targets and strips trailing dots.
+func normalizeAliases(aliases map[string]string) map[string]string {
+	normalized := make(map[string]string, len(aliases))
+	for name, target := range aliases {
+		normalized[normalizeName(name)] = normalizeName(target)
+	}
+	return normalized
+}
+
+// normalizeName lowercases name and strips a trailing dot.
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
+
+// SetServeStale enables or disables serving stale answers on upstream failure.
+func (s *Server) SetServeStale(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if enabled != s.serveStale {
+		s.serveStale = enabled
+		logging.Info("DNS serve_stale updated", "enabled", enabled)
+	}
+}
+
+// ParseRcode returns the rcode named name, e.g. "NXDOMAIN" (case-insensitive),
+// or 0 if name is empty or unknown.
+func ParseRcode(name string) int {
+	return dns.StringToRcode[strings.ToUpper(name)]
+}
+
+// SetLocalOnly enables or disables local-only mode, answering non-local
+// queries with rcode (0 = dns.RcodeRefused) instead of forwarding them.
+func (s *Server) SetLocalOnly(enabled bool, rcode int) {
+	if rcode == dns.RcodeSuccess {
+		rcode = dns.RcodeRefused
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if enabled != s.localOnly || rcode != s.localOnlyRcode {
+		s.localOnly = enabled
+		s.localOnlyRcode = rcode
+		logging.Info("DNS local_only updated", "enabled", enabled, "rcode", dns.RcodeToString[rcode])
 	}
 }
 
@@ -249,14 +569,16 @@ func (s *Server) GetDomains() []string {
 	return s.domains
 }
 
-// GetUpstream returns the current upstream DNS server.
-func (s *Server) GetUpstream() string {
+// GetUpstreams returns the current upstream DNS servers.
+func (s *Server) GetUpstreams() []string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.upstream
+	return s.upstreams
 }
 
-// handleDNS handles incoming DNS queries.
+// handleDNS handles incoming DNS queries. Answers use the question name
+// exactly as queried, preserving its case for resolvers that randomize it
+// (DNS 0x20).
 func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 	m := new(dns.Msg)
 	m.SetReply(r)
@@ -265,28 +587,119 @@ func (s *Server) handleDNS(w dns.ResponseWriter, r *dns.Msg) {
 	for _, q := range r.Question {
 		logging.Debug("DNS query", "name", q.Name, "type", dns.TypeToString[q.Qtype])
 
-		if s.isLocalDomain(q.Name) {
-			s.handleLocalQuery(m, q)
-		} else {
-			s.handleUpstreamQuery(m, r)
+		if !s.answerLocally(m, q, 0) {
+			s.mu.RLock()
+			localOnly, rcode := s.localOnly, s.localOnlyRcode
+			s.mu.RUnlock()
+			if localOnly {
+				m.Rcode = rcode
+				break
+			}
+
+			s.handleUpstreamQuery(m, r, !isUDP(w))
 			break // Upstream handles entire message
 		}
 	}
 
+	fitResponse(m, r, isUDP(w))
 	if err := w.WriteMsg(m); err != nil {
 		logging.Error("failed to write DNS response", "error", err)
 	}
 }
 
+// fitResponse answers EDNS0 queries with an OPT record and, over UDP, drops
+// records that exceed the payload size the client advertised (512 bytes
+// without EDNS0) and sets the TC bit, so the client retries over TCP.
+func fitResponse(m *dns.Msg, r *dns.Msg, udp bool) {
+	// Upstream responses carry the upstream's OPT record; ours replaces it
+	m.Extra = slices.DeleteFunc(m.Extra, func(rr dns.RR) bool {
+		return rr.Header().Rrtype == dns.TypeOPT
+	})
+
+	size := dns.MinMsgSize
+	if opt := r.IsEdns0(); opt != nil {
+		size = int(min(opt.UDPSize(), MaxUDPSize))
+		m.SetEdns0(MaxUDPSize, opt.Do())
+	}
+	if udp {
+		m.Truncate(size)
+	}
+}
+
+// isUDP reports whether w answers over UDP.
+func isUDP(w dns.ResponseWriter) bool {
+	addr := w.LocalAddr()
+	return addr == nil || addr.Network() == "udp"
+}
+
+// maxAliasChain bounds how many aliases are followed for one query. Loops are
+// rejected by config validation; this only guards against them at runtime.
+const maxAliasChain = 8
+
+// answerLocally answers q from the aliases, static records or local domains
+// and reports whether it did. depth counts the aliases already followed.
+func (s *Server) answerLocally(m *dns.Msg, q dns.Question, depth int) bool {
+	if target := s.lookupAlias(q.Name); target != "" && depth < maxAliasChain {
+		s.handleAliasQuery(m, q, target, depth)
+	} else if ip := s.lookupRecord(q.Name); ip != nil {
+		handleRecordQuery(m, q, ip)
+	} else if host := s.lookupPTR(q.Name); host != "" {
+		handlePTRQuery(m, q, host)
+	} else if s.isLocalDomain(q.Name) {
+		s.handleLocalQuery(m, q)
+	} else {
+		return false
+	}
+	return true
+}
+
+// handleAliasQuery answers a query for an alias with a CNAME to target and,
+// unless CNAME records were asked for, target's records if they are local.
+func (s *Server) handleAliasQuery(m *dns.Msg, q dns.Question, target string, depth int) {
+	m.Answer = append(m.Answer, &dns.CNAME{
+		Hdr: dns.RR_Header{
+			Name:   q.Name,
+			Rrtype: dns.TypeCNAME,
+			Class:  dns.ClassINET,
+			Ttl:    DefaultTTL,
+		},
+		Target: dns.Fqdn(target),
+	})
+	if q.Qtype == dns.TypeCNAME {
+		return
+	}
+
+	// Local targets are answered in the same response; anything else is
+	// left to the client, which follows the CNAME itself.
+	s.answerLocally(m, dns.Question{Name: dns.Fqdn(target), Qtype: q.Qtype, Qclass: q.Qclass}, depth+1)
+}
+
+// lookupAlias returns the CNAME target for name, or an empty string if name
+// is not an alias.
+func (s *Server) lookupAlias(name string) string {
+	name = normalizeName(name)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.aliases[name]
+}
+
 // isLocalDomain checks if the domain should be resolved locally.
 func (s *Server) isLocalDomain(name string) bool {
+	return IsLocalName(s.domains, name)
+}
+
+// IsLocalName reports whether name is one of domains or a subdomain of one,
+// i.e. whether the server answers it itself instead of forwarding it upstream.
+func IsLocalName(domains []string, name string) bool {
 	// Remove trailing dot
 	name = strings.TrimSuffix(name, ".")
 	name = strings.ToLower(name)
 
-	for _, domain := range s.domains {
-		domain = strings.ToLower(domain)
-		// Match exact domain or subdomain
+	for _, domain := range domains {
+		domain = strings.TrimSuffix(strings.ToLower(domain), ".")
+		// Match exact domain or subdomain. Single-label names (e.g., "app")
+		// are local only if listed as a domain themselves.
 		if name == domain || strings.HasSuffix(name, "."+domain) {
 			return true
 		}
@@ -294,12 +707,68 @@ func (s *Server) isLocalDomain(name string) bool {
 	return false
 }
 
+// lookupRecord returns the static record IP for name, or nil if none is configured.
+func (s *Server) lookupRecord(name string) net.IP {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.records[name]
+}
+
+// lookupPTR returns the hostname the reverse name points to, or an empty
+// string if it has no PTR record.
+func (s *Server) lookupPTR(name string) string {
+	name = normalizeName(name)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ptr[name]
+}
+
+// handlePTRQuery answers a query for a reverse name with a PTR record.
+func handlePTRQuery(m *dns.Msg, q dns.Question, host string) {
+	if q.Qtype != dns.TypePTR {
+		return
+	}
+	m.Answer = append(m.Answer, &dns.PTR{
+		Hdr: dns.RR_Header{
+			Name:   q.Name,
+			Rrtype: dns.TypePTR,
+			Class:  dns.ClassINET,
+			Ttl:    DefaultTTL,
+		},
+		Ptr: host,
+	})
+}
+
+// handleRecordQuery answers a query for a static record.
+func handleRecordQuery(m *dns.Msg, q dns.Question, ip net.IP) {
+	hdr := dns.RR_Header{
+		Name:  q.Name,
+		Class: dns.ClassINET,
+		Ttl:   DefaultTTL,
+	}
+
+	switch {
+	case q.Qtype == dns.TypeA && ip.To4() != nil:
+		hdr.Rrtype = dns.TypeA
+		m.Answer = append(m.Answer, &dns.A{Hdr: hdr, A: ip.To4()})
+
+	case q.Qtype == dns.TypeAAAA && ip.To4() == nil:
+		hdr.Rrtype = dns.TypeAAAA
+		m.Answer = append(m.Answer, &dns.AAAA{Hdr: hdr, AAAA: ip})
+	}
+}
+
 // handleLocalQuery handles queries for local domains.
 func (s *Server) handleLocalQuery(m *dns.Msg, q dns.Question) {
+	ip := s.localIP(q.Name)
+
 	switch q.Qtype {
 	case dns.TypeA:
 		// Return IPv4 address
-		if ip4 := s.resolveIP.To4(); ip4 != nil {
+		if ip4 := ip.To4(); ip4 != nil {
 			rr := &dns.A{
 				Hdr: dns.RR_Header{
 					Name:   q.Name,
@@ -314,7 +783,11 @@ func (s *Server) handleLocalQuery(m *dns.Msg, q dns.Question) {
 
 	case dns.TypeAAAA:
 		// Return IPv6 address (::1 for localhost)
-		if s.resolveIP.Equal(net.ParseIP("127.0.0.1")) {
+		ip6 := ip
+		if ip.Equal(net.ParseIP("127.0.0.1")) {
+			ip6 = net.ParseIP("::1")
+		}
+		if ip6 != nil && ip6.To4() == nil {
 			rr := &dns.AAAA{
 				Hdr: dns.RR_Header{
 					Name:   q.Name,
@@ -322,7 +795,7 @@ func (s *Server) handleLocalQuery(m *dns.Msg, q dns.Question) {
 					Class:  dns.ClassINET,
 					Ttl:    DefaultTTL,
 				},
-				AAAA: net.ParseIP("::1"),
+				AAAA: ip6,
 			}
 			m.Answer = append(m.Answer, rr)
 		}
@@ -333,18 +806,193 @@ func (s *Server) handleLocalQuery(m *dns.Msg, q dns.Question) {
 	}
 }
 
-// handleUpstreamQuery forwards a query to the upstream DNS server.
-func (s *Server) handleUpstreamQuery(m *dns.Msg, r *dns.Msg) {
-	resp, _, err := s.client.Exchange(r, s.upstream)
+// handleUpstreamQuery forwards a query to the upstream DNS servers, unless
+// the answer is cached. tcp is set for queries received over TCP. With
+// serve_stale enabled, a failure of all upstreams is answered from the last
+// successful response for the same question.
+func (s *Server) handleUpstreamQuery(m *dns.Msg, r *dns.Msg, tcp bool) {
+	if s.cache != nil {
+		if cached := s.cache.get(r); cached != nil {
+			matchQuestionCase(cached, r)
+			copyResponse(m, cached)
+			return
+		}
+	}
+
+	s.mu.RLock()
+	serveStale := s.serveStale
+	s.mu.RUnlock()
+
+	resp, err := s.exchange(r, tcp)
 	if err != nil {
+		if serveStale {
+			if cached := s.lookupStale(r); cached != nil {
+				logging.Warn("upstream DNS query failed, serving stale answer", "name", r.Question[0].Name, "error", err)
+				copyResponse(m, cached)
+				return
+			}
+		}
 		logging.Error("upstream DNS query failed", "error", err)
 		m.Rcode = dns.RcodeServerFailure
 		return
 	}
 
-	// Copy response
+	// A truncated answer is incomplete; TCP clients would get it again
+	if resp.Truncated {
+		copyResponse(m, resp)
+		return
+	}
+	if serveStale && resp.Rcode == dns.RcodeSuccess {
+		s.storeStale(r, resp)
+	}
+	if s.cache != nil {
+		s.cache.put(r, resp)
+	}
+
+	copyResponse(m, resp)
+}
+
+// exchange sends r to the upstreams, starting with the one that answered
+// last, and returns the first response. The next upstream is queried when one
+// fails, or also when one has not answered within upstreamStagger; each
+// attempt is bounded by the client timeout. Queries go over UDP unless tcp is
+// set; an answer truncated over UDP is queried again over TCP, so large
+// records resolve.
+func (s *Server) exchange(r *dns.Msg, tcp bool) (*dns.Msg, error) {
+	s.mu.RLock()
+	upstreams := s.upstreams
+	last := s.lastUpstream
+	s.mu.RUnlock()
+
+	order := make([]string, 0, len(upstreams))
+	if slices.Contains(upstreams, last) {
+		order = append(order, last)
+	}
+	for _, upstream := range upstreams {
+		if upstream != last {
+			order = append(order, upstream)
+		}
+	}
+
+	type result struct {
+		upstream string
+		resp     *dns.Msg
+		err      error
+	}
+	results := make(chan result, len(order))
+	var next, pending int
+	queryNext := func() bool {
+		if next >= len(order) {
+			return false
+		}
+		upstream := order[next]
+		next++
+		pending++
+		go func() {
+			resp, err := s.exchangeWith(r, upstream, tcp)
+			results <- result{upstream: upstream, resp: resp, err: err}
+		}()
+		return true
+	}
+
+	queryNext()
+	stagger := time.NewTimer(upstreamStagger)
+	defer stagger.Stop()
+
+	var errs []error
+	for pending > 0 {
+		select {
+		case res := <-results:
+			pending--
+			if res.err != nil {
+				logging.Debug("upstream DNS server failed", "upstream", res.upstream, "error", res.err)
+				errs = append(errs, fmt.Errorf("%s: %w", res.upstream, res.err))
+				if queryNext() {
+					stagger.Reset(upstreamStagger)
+				}
+				continue
+			}
+
+			if res.upstream != last {
+				s.mu.Lock()
+				s.lastUpstream = res.upstream
+				s.mu.Unlock()
+			}
+			logging.Debug("upstream DNS answer", "name", r.Question[0].Name, "upstream", res.upstream)
+			return res.resp, nil
+
+		case <-stagger.C:
+			if queryNext() {
+				stagger.Reset(upstreamStagger)
+			}
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// exchangeWith sends r to upstream over UDP, or TCP if tcp is set, and
+// queries again over TCP if the UDP answer is truncated.
+func (s *Server) exchangeWith(r *dns.Msg, upstream string, tcp bool) (*dns.Msg, error) {
+	client := s.client
+	if tcp {
+		client = s.tcpClient
+	}
+
+	resp, _, err := client.Exchange(r, upstream)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Truncated && !tcp {
+		if full, _, err := s.tcpClient.Exchange(r, upstream); err == nil {
+			resp = full
+		} else {
+			logging.Debug("upstream DNS query over TCP failed, keeping truncated answer", "upstream", upstream, "error", err)
+		}
+	}
+	return resp, nil
+}
+
+// copyResponse copies the answer sections, rcode and TC bit of resp into m.
+func copyResponse(m *dns.Msg, resp *dns.Msg) {
 	m.Answer = resp.Answer
 	m.Ns = resp.Ns
 	m.Extra = resp.Extra
 	m.Rcode = resp.Rcode
+	m.Truncated = resp.Truncated
+}
+
+// storeStale records resp as the last known answer for r.
+func (s *Server) storeStale(r *dns.Msg, resp *dns.Msg) {
+	s.stale.putFor(r, resp, MaxStaleAge)
+}
+
+// lookupStale returns a copy of the last known answer for r with TTLs
+// lowered to StaleTTL, or nil if none is recorded within MaxStaleAge.
+func (s *Server) lookupStale(r *dns.Msg) *dns.Msg {
+	resp, _ := s.stale.lookup(r)
+	if resp == nil {
+		return nil
+	}
+
+	forEachRR(resp, func(rr dns.RR) {
+		if rr.Header().Ttl > StaleTTL {
+			rr.Header().Ttl = StaleTTL
+		}
+	})
+	matchQuestionCase(resp, r)
+	return resp
+}
+
+// matchQuestionCase renames records for the question name of r to r's
+// spelling. An answer stored for a query with different case would otherwise
+// be rejected by resolvers using 0x20 case randomization.
+func matchQuestionCase(resp *dns.Msg, r *dns.Msg) {
+	name := r.Question[0].Name
+	for _, section := range [][]dns.RR{resp.Answer, resp.Ns, resp.Extra} {
+		for _, rr := range section {
+			if strings.EqualFold(rr.Header().Name, name) {
+				rr.Header().Name = name
+			}
+		}
+	}
 }
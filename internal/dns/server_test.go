@@ -2,6 +2,9 @@ package dns
 
 import (
 	"net"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -29,7 +32,7 @@ func TestNewWithCustomConfig(t *testing.T) {
 		Addr:      "127.0.0.1:5353",
 		Domains:   []string{"test", "local"},
 		ResolveIP: net.ParseIP("10.0.0.1"),
-		Upstream:  "1.1.1.1:53",
+		Upstreams: []string{"1.1.1.1:53"},
 	}
 
 	s := New(cfg)
@@ -46,8 +49,8 @@ func TestNewWithCustomConfig(t *testing.T) {
 		t.Errorf("expected resolveIP 10.0.0.1, got %v", s.resolveIP)
 	}
 
-	if s.upstream != "1.1.1.1:53" {
-		t.Errorf("expected upstream 1.1.1.1:53, got %s", s.upstream)
+	if len(s.upstreams) != 1 || s.upstreams[0] != "1.1.1.1:53" {
+		t.Errorf("expected upstreams [1.1.1.1:53], got %v", s.upstreams)
 	}
 }
 
@@ -79,6 +82,33 @@ func TestIsLocalDomain(t *testing.T) {
 	}
 }
 
+func TestIsLocalDomain_SingleLabel(t *testing.T) {
+	s := New(Config{
+		Domains: []string{"localhost", "App", "db."},
+	})
+
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{"app.", true},
+		{"APP.", true},
+		{"app", true},
+		{"db.", true},
+		{"api.app.", true},
+		{"web.", false}, // Single-label names are local only when listed
+		{"apps.", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.isLocalDomain(tt.name); got != tt.expected {
+				t.Errorf("isLocalDomain(%s) = %v, want %v", tt.name, got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestServerStartStop(t *testing.T) {
 	// Use a random high port to avoid conflicts with running devproxy
 	cfg := Config{
@@ -286,3 +316,796 @@ func TestMultipleDomains(t *testing.T) {
 		})
 	}
 }
+
+// startUpstream starts a UDP DNS server answering every A query with ip.
+func startUpstream(t *testing.T, ip string) (addr string, stop func()) {
+	t.Helper()
+	return startUpstreamAt(t, "127.0.0.1:0", ip)
+}
+
+// startUpstreamAt is like startUpstream but listens on listen.
+func startUpstreamAt(t *testing.T, listen, ip string) (addr string, stop func()) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", listen)
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	srv := &dns.Server{
+		PacketConn: pc,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+			m := new(dns.Msg)
+			m.SetReply(r)
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+				A:   net.ParseIP(ip),
+			})
+			w.WriteMsg(m)
+		}),
+	}
+
+	started := make(chan struct{})
+	srv.NotifyStartedFunc = func() { close(started) }
+	go srv.ActivateAndServe()
+	<-started
+
+	var once sync.Once
+	return pc.LocalAddr().String(), func() { once.Do(func() { srv.Shutdown() }) }
+}
+
+func TestUpstreamFailover(t *testing.T) {
+	first, stopFirst := startUpstream(t, "192.0.2.10")
+	second, stopSecond := startUpstream(t, "192.0.2.20")
+	defer stopSecond()
+
+	s := New(Config{Upstreams: []string{first, second}, CacheSize: -1})
+
+	query := func() *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion("example.com.", dns.TypeA)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		s.handleUpstreamQuery(m, r, false)
+		return m
+	}
+	answeredBy := func(t *testing.T, m *dns.Msg) string {
+		t.Helper()
+		if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+			t.Fatalf("expected an answer, got rcode %d with %d answers", m.Rcode, len(m.Answer))
+		}
+		return m.Answer[0].(*dns.A).A.String()
+	}
+
+	if ip := answeredBy(t, query()); ip != "192.0.2.10" {
+		t.Errorf("expected the first upstream to answer, got %s", ip)
+	}
+
+	stopFirst()
+	if ip := answeredBy(t, query()); ip != "192.0.2.20" {
+		t.Errorf("expected failover to the second upstream, got %s", ip)
+	}
+
+	// The first upstream recovers, but the last good one is kept
+	restarted, stopRestarted := startUpstreamAt(t, first, "192.0.2.30")
+	defer stopRestarted()
+	if restarted != first {
+		t.Fatalf("failed to restart upstream on %s", first)
+	}
+	if ip := answeredBy(t, query()); ip != "192.0.2.20" {
+		t.Errorf("expected the last good upstream to answer, got %s", ip)
+	}
+
+	t.Run("fails when every upstream is down", func(t *testing.T) {
+		stopSecond()
+		stopRestarted()
+		if m := query(); m.Rcode != dns.RcodeServerFailure {
+			t.Errorf("expected SERVFAIL, got rcode %d", m.Rcode)
+		}
+	})
+}
+
+func TestUpstreamFailover_SilentUpstream(t *testing.T) {
+	// An upstream that accepts queries but never answers, like one behind a
+	// firewall that drops packets
+	silent, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer silent.Close()
+
+	second, stopSecond := startUpstream(t, "192.0.2.20")
+	defer stopSecond()
+
+	s := New(Config{Upstreams: []string{silent.LocalAddr().String(), second}, CacheSize: -1})
+
+	r := new(dns.Msg)
+	r.SetQuestion("example.com.", dns.TypeA)
+	start := time.Now()
+	resp, err := s.exchange(r, false)
+	if err != nil {
+		t.Fatalf("exchange() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= s.client.Timeout {
+		t.Errorf("exchange() took %s, want the second upstream queried before the %s timeout", elapsed, s.client.Timeout)
+	}
+	if ip := resp.Answer[0].(*dns.A).A.String(); ip != "192.0.2.20" {
+		t.Errorf("expected the second upstream to answer, got %s", ip)
+	}
+	if s.lastUpstream != second {
+		t.Errorf("lastUpstream = %q, want %q", s.lastUpstream, second)
+	}
+}
+
+func TestServeStale(t *testing.T) {
+	tests := []struct {
+		name       string
+		serveStale bool
+		wantRcode  int
+		wantAnswer bool
+	}{
+		{name: "serves stale answer when enabled", serveStale: true, wantRcode: dns.RcodeSuccess, wantAnswer: true},
+		{name: "returns SERVFAIL when disabled", serveStale: false, wantRcode: dns.RcodeServerFailure, wantAnswer: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, stop := startUpstream(t, "192.0.2.10")
+
+			s := New(Config{Upstreams: []string{upstream}, ServeStale: tt.serveStale, CacheSize: -1})
+			s.client.Timeout = 200 * time.Millisecond
+
+			query := new(dns.Msg)
+			query.SetQuestion("example.com.", dns.TypeA)
+
+			// Populate from a healthy upstream
+			m := new(dns.Msg)
+			m.SetReply(query)
+			s.handleUpstreamQuery(m, query, false)
+			if m.Rcode != dns.RcodeSuccess || len(m.Answer) != 1 {
+				t.Fatalf("expected upstream answer, got rcode %d with %d answers", m.Rcode, len(m.Answer))
+			}
+
+			// Simulate an outage
+			stop()
+
+			m = new(dns.Msg)
+			m.SetReply(query)
+			s.handleUpstreamQuery(m, query, false)
+
+			if m.Rcode != tt.wantRcode {
+				t.Errorf("expected rcode %d, got %d", tt.wantRcode, m.Rcode)
+			}
+			if !tt.wantAnswer {
+				if len(m.Answer) != 0 {
+					t.Errorf("expected no answers, got %d", len(m.Answer))
+				}
+				return
+			}
+
+			if len(m.Answer) != 1 {
+				t.Fatalf("expected 1 stale answer, got %d", len(m.Answer))
+			}
+			a, ok := m.Answer[0].(*dns.A)
+			if !ok {
+				t.Fatalf("expected A record, got %T", m.Answer[0])
+			}
+			if !a.A.Equal(net.ParseIP("192.0.2.10")) {
+				t.Errorf("expected 192.0.2.10, got %v", a.A)
+			}
+			if a.Hdr.Ttl != StaleTTL {
+				t.Errorf("expected stale TTL %d, got %d", StaleTTL, a.Hdr.Ttl)
+			}
+		})
+	}
+}
+
+func TestServeStale_PreservesQueryCase(t *testing.T) {
+	upstream, stop := startUpstream(t, "192.0.2.10")
+
+	s := New(Config{Upstreams: []string{upstream}, ServeStale: true, CacheSize: -1})
+	s.client.Timeout = 200 * time.Millisecond
+
+	first := new(dns.Msg)
+	first.SetQuestion("example.com.", dns.TypeA)
+	s.handleUpstreamQuery(new(dns.Msg), first, false)
+	stop()
+
+	query := new(dns.Msg)
+	query.SetQuestion("ExAmPlE.cOm.", dns.TypeA)
+	m := new(dns.Msg)
+	m.SetReply(query)
+	s.handleUpstreamQuery(m, query, false)
+
+	if len(m.Answer) != 1 {
+		t.Fatalf("expected 1 stale answer, got %d", len(m.Answer))
+	}
+	if name := m.Answer[0].Header().Name; name != "ExAmPlE.cOm." {
+		t.Errorf("answer name = %q, want the query's case %q", name, "ExAmPlE.cOm.")
+	}
+}
+
+func TestServeStale_NoCachedAnswer(t *testing.T) {
+	upstream, stop := startUpstream(t, "192.0.2.10")
+	stop()
+
+	s := New(Config{Upstreams: []string{upstream}, ServeStale: true, CacheSize: -1})
+	s.client.Timeout = 200 * time.Millisecond
+
+	query := new(dns.Msg)
+	query.SetQuestion("example.com.", dns.TypeA)
+
+	m := new(dns.Msg)
+	m.SetReply(query)
+	s.handleUpstreamQuery(m, query, false)
+
+	if m.Rcode != dns.RcodeServerFailure {
+		t.Errorf("expected SERVFAIL without cached answer, got rcode %d", m.Rcode)
+	}
+}
+
+func TestStaticRecords(t *testing.T) {
+	s := New(Config{
+		Domains: []string{"localhost"},
+		Records: map[string]net.IP{
+			"NAS.home.arpa.": net.ParseIP("192.168.1.10"),
+		},
+	})
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		w := &recordingWriter{}
+		s.handleDNS(w, r)
+		return w.msg
+	}
+
+	t.Run("answers configured record", func(t *testing.T) {
+		m := query("nas.home.arpa.", dns.TypeA)
+		if len(m.Answer) != 1 {
+			t.Fatalf("expected 1 answer, got %d", len(m.Answer))
+		}
+		if a := m.Answer[0].(*dns.A); !a.A.Equal(net.ParseIP("192.168.1.10")) {
+			t.Errorf("expected 192.168.1.10, got %v", a.A)
+		}
+	})
+
+	t.Run("applies updated records", func(t *testing.T) {
+		s.SetRecords(map[string]net.IP{
+			"app.localhost": net.ParseIP("10.0.0.5"),
+		})
+
+		m := query("app.localhost.", dns.TypeA)
+		if len(m.Answer) != 1 {
+			t.Fatalf("expected 1 answer, got %d", len(m.Answer))
+		}
+		if a := m.Answer[0].(*dns.A); !a.A.Equal(net.ParseIP("10.0.0.5")) {
+			t.Errorf("expected record to override local domain, got %v", a.A)
+		}
+
+		if _, ok := s.GetRecords()["nas.home.arpa"]; ok {
+			t.Error("expected removed record to be gone")
+		}
+	})
+}
+
+func TestAliases(t *testing.T) {
+	s := New(Config{
+		Domains: []string{"localhost"},
+		Records: map[string]net.IP{"nas.home.arpa": net.ParseIP("192.168.1.10")},
+		Aliases: map[string]string{
+			"Short.localhost.": "myreallylongappname.localhost",
+			"s.localhost":      "short.localhost",
+			"files.localhost":  "nas.home.arpa",
+			"docs.localhost":   "docs.example.com",
+		},
+	})
+
+	query := func(name string, qtype uint16) *dns.Msg {
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		w := &recordingWriter{}
+		s.handleDNS(w, r)
+		return w.msg
+	}
+
+	tests := []struct {
+		name        string
+		qtype       uint16
+		wantTargets []string // CNAME targets in answer order
+		wantIP      string   // final A or AAAA record, empty for none
+	}{
+		{name: "short.localhost.", qtype: dns.TypeA, wantTargets: []string{"myreallylongappname.localhost."}, wantIP: "127.0.0.1"},
+		{name: "short.localhost.", qtype: dns.TypeAAAA, wantTargets: []string{"myreallylongappname.localhost."}, wantIP: "::1"},
+		{name: "short.localhost.", qtype: dns.TypeCNAME, wantTargets: []string{"myreallylongappname.localhost."}},
+		{name: "s.localhost.", qtype: dns.TypeA, wantTargets: []string{"short.localhost.", "myreallylongappname.localhost."}, wantIP: "127.0.0.1"},
+		{name: "files.localhost.", qtype: dns.TypeA, wantTargets: []string{"nas.home.arpa."}, wantIP: "192.168.1.10"},
+		{name: "docs.localhost.", qtype: dns.TypeA, wantTargets: []string{"docs.example.com."}},
+		{name: "myreallylongappname.localhost.", qtype: dns.TypeCNAME},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+dns.TypeToString[tt.qtype], func(t *testing.T) {
+			m := query(tt.name, tt.qtype)
+
+			var targets []string
+			var ip string
+			for _, rr := range m.Answer {
+				switch rr := rr.(type) {
+				case *dns.CNAME:
+					targets = append(targets, rr.Target)
+				case *dns.A:
+					ip = rr.A.String()
+				case *dns.AAAA:
+					ip = rr.AAAA.String()
+				}
+			}
+			if !slices.Equal(targets, tt.wantTargets) {
+				t.Errorf("CNAME targets = %v, want %v", targets, tt.wantTargets)
+			}
+			if ip != tt.wantIP {
+				t.Errorf("address = %q, want %q", ip, tt.wantIP)
+			}
+		})
+	}
+
+	t.Run("applies updated aliases", func(t *testing.T) {
+		s.SetAliases(map[string]string{"new.localhost": "app.localhost"})
+
+		if m := query("short.localhost.", dns.TypeCNAME); len(m.Answer) != 0 {
+			t.Errorf("expected removed alias to be gone, got %v", m.Answer)
+		}
+		if m := query("new.localhost.", dns.TypeA); len(m.Answer) != 2 {
+			t.Errorf("expected CNAME and A record, got %v", m.Answer)
+		}
+	})
+}
+
+func TestPTR(t *testing.T) {
+	s := New(Config{
+		Domains: []string{"localhost"},
+		PTR: map[string]string{
+			"127.0.0.1":  "localhost",
+			"::1":        "localhost",
+			"172.18.0.2": "web.localhost.",
+		},
+		CacheSize: -1,
+	})
+
+	tests := []struct {
+		name      string
+		qtype     uint16
+		wantPTR   string
+		wantLocal bool // answered without forwarding upstream
+	}{
+		{name: "1.0.0.127.in-addr.arpa.", qtype: dns.TypePTR, wantPTR: "localhost.", wantLocal: true},
+		{name: "1.0.0.127.IN-ADDR.ARPA.", qtype: dns.TypePTR, wantPTR: "localhost.", wantLocal: true},
+		{name: "2.0.18.172.in-addr.arpa.", qtype: dns.TypePTR, wantPTR: "web.localhost.", wantLocal: true},
+		{name: "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.ip6.arpa.", qtype: dns.TypePTR, wantPTR: "localhost.", wantLocal: true},
+		{name: "1.0.0.127.in-addr.arpa.", qtype: dns.TypeA, wantLocal: true},
+		{name: "3.0.18.172.in-addr.arpa.", qtype: dns.TypePTR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := new(dns.Msg)
+			local := s.answerLocally(m, dns.Question{Name: tt.name, Qtype: tt.qtype, Qclass: dns.ClassINET}, 0)
+			if local != tt.wantLocal {
+				t.Fatalf("answered locally = %v, want %v", local, tt.wantLocal)
+			}
+
+			var got string
+			if len(m.Answer) > 0 {
+				ptr, ok := m.Answer[0].(*dns.PTR)
+				if !ok {
+					t.Fatalf("expected PTR answer, got %v", m.Answer[0])
+				}
+				got = ptr.Ptr
+				if ptr.Hdr.Name != tt.name {
+					t.Errorf("answer name = %q, want %q", ptr.Hdr.Name, tt.name)
+				}
+			}
+			if got != tt.wantPTR {
+				t.Errorf("PTR = %q, want %q", got, tt.wantPTR)
+			}
+		})
+	}
+
+	s.SetPTR(nil)
+	if s.answerLocally(new(dns.Msg), dns.Question{Name: "1.0.0.127.in-addr.arpa.", Qtype: dns.TypePTR}, 0) {
+		t.Error("expected reverse lookups to be forwarded after removing the PTR records")
+	}
+}
+
+func TestDomainIPs(t *testing.T) {
+	s := New(Config{
+		Domains: []string{"localhost", "test"},
+		DomainIPs: map[string]net.IP{
+			"lan.test":     net.ParseIP("192.168.1.5"),
+			"api.lan.test": net.ParseIP("192.168.1.6"),
+			"v6.test.":     net.ParseIP("fd00::1"),
+		},
+		ContainerDomains: []string{"docker.localhost"},
+		ContainerIP: func(name string) net.IP {
+			if name == "web.docker.localhost" {
+				return net.ParseIP("172.18.0.2")
+			}
+			return nil
+		},
+	})
+
+	query := func(name string, qtype uint16) string {
+		r := new(dns.Msg)
+		r.SetQuestion(name, qtype)
+		w := &recordingWriter{}
+		s.handleDNS(w, r)
+		if len(w.msg.Answer) == 0 {
+			return ""
+		}
+		switch rr := w.msg.Answer[0].(type) {
+		case *dns.A:
+			return rr.A.String()
+		case *dns.AAAA:
+			return rr.AAAA.String()
+		}
+		return ""
+	}
+
+	tests := []struct {
+		name  string
+		qtype uint16
+		want  string
+	}{
+		{name: "app.localhost.", qtype: dns.TypeA, want: "127.0.0.1"},
+		{name: "app.localhost.", qtype: dns.TypeAAAA, want: "::1"},
+		{name: "nas.lan.test.", qtype: dns.TypeA, want: "192.168.1.5"},
+		{name: "nas.lan.test.", qtype: dns.TypeAAAA, want: ""},
+		{name: "v1.api.lan.test.", qtype: dns.TypeA, want: "192.168.1.6"},
+		{name: "host.v6.test.", qtype: dns.TypeA, want: ""},
+		{name: "host.v6.test.", qtype: dns.TypeAAAA, want: "fd00::1"},
+		{name: "WEB.docker.localhost.", qtype: dns.TypeA, want: "172.18.0.2"},
+		{name: "other.docker.localhost.", qtype: dns.TypeA, want: "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+dns.TypeToString[tt.qtype], func(t *testing.T) {
+			if got := query(tt.name, tt.qtype); got != tt.want {
+				t.Errorf("answer = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("applies updated domain IPs", func(t *testing.T) {
+		s.SetDomainIPs(map[string]net.IP{"docker.localhost": net.ParseIP("10.0.0.9")}, nil)
+
+		if got := query("web.docker.localhost.", dns.TypeA); got != "10.0.0.9" {
+			t.Errorf("answer = %q, want 10.0.0.9", got)
+		}
+		if got := query("nas.lan.test.", dns.TypeA); got != "127.0.0.1" {
+			t.Errorf("expected removed domain IP to fall back to 127.0.0.1, got %q", got)
+		}
+	})
+}
+
+func TestAnswerPreservesQueryCase(t *testing.T) {
+	s := New(Config{
+		Domains: []string{"localhost"},
+		Records: map[string]net.IP{"nas.home.arpa": net.ParseIP("192.168.1.10")},
+		Aliases: map[string]string{"short.localhost": "app.localhost"},
+	})
+
+	tests := []struct {
+		name  string
+		qtype uint16
+	}{
+		{name: "ApP.LoCaLhOsT.", qtype: dns.TypeA},
+		{name: "ApP.LoCaLhOsT.", qtype: dns.TypeAAAA},
+		{name: "nAs.HoMe.ArPa.", qtype: dns.TypeA},
+		{name: "sHoRt.LOCALHOST.", qtype: dns.TypeCNAME},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+" "+dns.TypeToString[tt.qtype], func(t *testing.T) {
+			r := new(dns.Msg)
+			r.SetQuestion(tt.name, tt.qtype)
+			w := &recordingWriter{}
+			s.handleDNS(w, r)
+
+			if len(w.msg.Answer) == 0 {
+				t.Fatal("expected an answer")
+			}
+			if name := w.msg.Answer[0].Header().Name; name != tt.name {
+				t.Errorf("answer name = %q, want the query's case %q", name, tt.name)
+			}
+			if name := w.msg.Question[0].Name; name != tt.name {
+				t.Errorf("question name = %q, want %q", name, tt.name)
+			}
+		})
+	}
+}
+
+// recordingWriter is a dns.ResponseWriter that keeps the written message.
+// It answers over UDP unless tcp is set.
+type recordingWriter struct {
+	dns.ResponseWriter
+	msg *dns.Msg
+	tcp bool
+}
+
+func (w *recordingWriter) WriteMsg(m *dns.Msg) error {
+	w.msg = m
+	return nil
+}
+
+func (w *recordingWriter) LocalAddr() net.Addr {
+	if w.tcp {
+		return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: DefaultPort}
+	}
+	return &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: DefaultPort}
+}
+
+func TestFitResponse(t *testing.T) {
+	// largeResponse answers r with 100 A records, about 1.6 KB
+	largeResponse := func(r *dns.Msg) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		for i := range 100 {
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 60},
+				A:   net.IPv4(10, 0, 0, byte(i)),
+			})
+		}
+		return m
+	}
+
+	tests := []struct {
+		name          string
+		udpSize       uint16 // 0 = no EDNS0
+		tcp           bool
+		wantTruncated bool
+		wantMaxSize   int
+	}{
+		{name: "UDP without EDNS0 fits 512 bytes", wantTruncated: true, wantMaxSize: dns.MinMsgSize},
+		{name: "UDP with EDNS0 fits the advertised size", udpSize: 1024, wantTruncated: true, wantMaxSize: 1024},
+		{name: "UDP with a large EDNS0 size is capped", udpSize: 4096, wantTruncated: true, wantMaxSize: MaxUDPSize},
+		{name: "TCP is not truncated", tcp: true},
+		{name: "TCP with EDNS0 is not truncated", udpSize: 1024, tcp: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := new(dns.Msg)
+			r.SetQuestion("big.example.com.", dns.TypeA)
+			if tt.udpSize > 0 {
+				r.SetEdns0(tt.udpSize, true)
+			}
+			m := largeResponse(r)
+			m.SetEdns0(4096, false) // the upstream's OPT record
+
+			fitResponse(m, r, !tt.tcp)
+
+			if m.Truncated != tt.wantTruncated {
+				t.Errorf("Truncated = %v, want %v", m.Truncated, tt.wantTruncated)
+			}
+			if tt.wantMaxSize > 0 && m.Len() > tt.wantMaxSize {
+				t.Errorf("response is %d bytes, want at most %d", m.Len(), tt.wantMaxSize)
+			}
+			if !tt.tcp && (len(m.Answer) == 0 || len(m.Answer) == 100) {
+				t.Errorf("expected the answers that fit, got %d", len(m.Answer))
+			}
+
+			opts := 0
+			for _, rr := range m.Extra {
+				if rr.Header().Rrtype == dns.TypeOPT {
+					opts++
+				}
+			}
+			if wantOpts := min(int(tt.udpSize), 1); opts != wantOpts {
+				t.Fatalf("expected %d OPT records, got %d", wantOpts, opts)
+			}
+			if opt := m.IsEdns0(); opt != nil && (opt.UDPSize() != MaxUDPSize || !opt.Do()) {
+				t.Errorf("OPT = size %d, DO %v, want size %d with the query's DO bit", opt.UDPSize(), opt.Do(), MaxUDPSize)
+			}
+		})
+	}
+}
+
+func TestHandleDNS_EDNS0(t *testing.T) {
+	s := New(Config{Domains: []string{"localhost"}})
+
+	r := new(dns.Msg)
+	r.SetQuestion("app.localhost.", dns.TypeA)
+	r.SetEdns0(4096, false)
+
+	w := &recordingWriter{}
+	s.handleDNS(w, r)
+
+	if len(w.msg.Answer) != 1 || w.msg.Truncated {
+		t.Errorf("expected one untruncated answer, got %d (TC %v)", len(w.msg.Answer), w.msg.Truncated)
+	}
+	if opt := w.msg.IsEdns0(); opt == nil || opt.UDPSize() != MaxUDPSize {
+		t.Errorf("expected OPT record advertising %d bytes, got %v", MaxUDPSize, opt)
+	}
+}
+
+// startLargeUpstream starts an upstream on UDP and TCP answering with 100 A
+// records, about 1.6 KB. Over UDP it truncates the answer to 512 bytes and
+// sets the TC bit, like real servers. It counts the queries per network.
+func startLargeUpstream(t *testing.T) (addr string, queries map[string]*atomic.Int32) {
+	t.Helper()
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	ln, err := net.Listen("tcp", pc.LocalAddr().String())
+	if err != nil {
+		pc.Close()
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	queries = map[string]*atomic.Int32{"udp": {}, "tcp": {}}
+	handler := dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		network := w.LocalAddr().Network()
+		queries[network].Add(1)
+
+		m := new(dns.Msg)
+		m.SetReply(r)
+		for i := range 100 {
+			m.Answer = append(m.Answer, &dns.A{
+				Hdr: dns.RR_Header{Name: r.Question[0].Name, Rrtype: dns.TypeA, Class: dns.ClassINET, Ttl: 300},
+				A:   net.IPv4(10, 0, 0, byte(i)),
+			})
+		}
+		if network == "udp" {
+			m.Truncate(dns.MinMsgSize)
+		}
+		w.WriteMsg(m)
+	})
+
+	for _, srv := range []*dns.Server{{PacketConn: pc, Handler: handler}, {Listener: ln, Handler: handler}} {
+		started := make(chan struct{})
+		srv.NotifyStartedFunc = func() { close(started) }
+		go srv.ActivateAndServe()
+		<-started
+		t.Cleanup(func() { srv.Shutdown() })
+	}
+	return pc.LocalAddr().String(), queries
+}
+
+func TestUpstreamTruncatedAnswer(t *testing.T) {
+	upstream, queries := startLargeUpstream(t)
+	s := New(Config{Upstreams: []string{upstream}, CacheSize: -1})
+
+	r := new(dns.Msg)
+	r.SetQuestion("big.example.com.", dns.TypeA)
+	r.SetEdns0(4096, false)
+
+	// Over UDP, the answer is fetched in full over TCP and the client gets
+	// what fits its UDP size, more than the upstream's truncated 512 bytes
+	udp := &recordingWriter{}
+	s.handleDNS(udp, r)
+	if !udp.msg.Truncated || udp.msg.Len() > MaxUDPSize {
+		t.Fatalf("expected a truncated UDP answer of at most %d bytes, got %d bytes (TC %v)", MaxUDPSize, udp.msg.Len(), udp.msg.Truncated)
+	}
+	if udp.msg.Len() <= dns.MinMsgSize {
+		t.Errorf("expected the client's UDP size to be filled, got %d bytes", udp.msg.Len())
+	}
+	if got := queries["tcp"].Load(); got != 1 {
+		t.Errorf("expected the truncated upstream answer to be retried over TCP, got %d TCP queries", got)
+	}
+
+	// The client retries over TCP and gets every record
+	tcp := &recordingWriter{tcp: true}
+	s.handleDNS(tcp, r)
+	if tcp.msg.Truncated || len(tcp.msg.Answer) != 100 {
+		t.Errorf("expected all 100 records over TCP, got %d (TC %v)", len(tcp.msg.Answer), tcp.msg.Truncated)
+	}
+	if got := queries["udp"].Load(); got != 1 {
+		t.Errorf("expected the TCP query to skip UDP, got %d UDP queries", got)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	s := New(Config{
+		Addr:      "127.0.0.1:15358",
+		Domains:   []string{"localhost"},
+		ResolveIP: net.ParseIP("127.0.0.1"),
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer s.Stop()
+
+	time.Sleep(50 * time.Millisecond)
+
+	ips, err := Query("127.0.0.1:15358", "app.localhost", 2*time.Second)
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(ips) != 2 || !ips[0].Equal(net.ParseIP("127.0.0.1")) || !ips[1].Equal(net.ParseIP("::1")) {
+		t.Errorf("expected [127.0.0.1 ::1], got %v", ips)
+	}
+
+	if _, err := Query("127.0.0.1:1", "app.localhost", 200*time.Millisecond); err == nil {
+		t.Error("expected error when no server is listening")
+	}
+}
+
+func TestLocalOnly(t *testing.T) {
+	// Counts queries that reach the upstream without answering them
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer pc.Close()
+	var forwarded atomic.Int32
+	go func() {
+		buf := make([]byte, dns.MaxMsgSize)
+		for {
+			if _, _, err := pc.ReadFrom(buf); err != nil {
+				return
+			}
+			forwarded.Add(1)
+		}
+	}()
+
+	tests := []struct {
+		name      string
+		rcode     int
+		wantRcode int
+	}{
+		{name: "refused by default", rcode: 0, wantRcode: dns.RcodeRefused},
+		{name: "configured nxdomain", rcode: ParseRcode("nxdomain"), wantRcode: dns.RcodeNameError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(Config{
+				Domains:        []string{"localhost"},
+				Upstreams:      []string{pc.LocalAddr().String()},
+				LocalOnly:      true,
+				LocalOnlyRcode: tt.rcode,
+				CacheSize:      -1,
+			})
+			s.client.Timeout = 200 * time.Millisecond
+
+			r := new(dns.Msg)
+			r.SetQuestion("example.com.", dns.TypeA)
+			w := &recordingWriter{}
+			s.handleDNS(w, r)
+			if w.msg.Rcode != tt.wantRcode || len(w.msg.Answer) != 0 {
+				t.Errorf("expected rcode %s without answers, got %s with %d answers",
+					dns.RcodeToString[tt.wantRcode], dns.RcodeToString[w.msg.Rcode], len(w.msg.Answer))
+			}
+
+			// Local names are still answered
+			r = new(dns.Msg)
+			r.SetQuestion("app.localhost.", dns.TypeA)
+			s.handleDNS(w, r)
+			if w.msg.Rcode != dns.RcodeSuccess || len(w.msg.Answer) != 1 {
+				t.Errorf("expected local answer, got rcode %d with %d answers", w.msg.Rcode, len(w.msg.Answer))
+			}
+		})
+	}
+
+	if n := forwarded.Load(); n != 0 {
+		t.Errorf("expected no upstream queries in local-only mode, got %d", n)
+	}
+
+	t.Run("forwards again when disabled", func(t *testing.T) {
+		upstream, stop := startUpstream(t, "192.0.2.10")
+		defer stop()
+
+		s := New(Config{Upstreams: []string{upstream}, LocalOnly: true, CacheSize: -1})
+		s.SetLocalOnly(false, 0)
+
+		r := new(dns.Msg)
+		r.SetQuestion("example.com.", dns.TypeA)
+		w := &recordingWriter{}
+		s.handleDNS(w, r)
+		if w.msg.Rcode != dns.RcodeSuccess || len(w.msg.Answer) != 1 {
+			t.Errorf("expected upstream answer, got rcode %d with %d answers", w.msg.Rcode, len(w.msg.Answer))
+		}
+	})
+}
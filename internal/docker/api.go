@@ -3,10 +3,13 @@ package docker
 
 import (
 	"context"
+	"io"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
 )
 
 // DockerAPI defines the Docker client operations used by devproxy.
@@ -21,6 +24,15 @@ type DockerAPI interface {
 	// ContainerInspect returns detailed information about a container.
 	ContainerInspect(ctx context.Context, containerID string) (container.InspectResponse, error)
 
+	// ContainerLogs returns the log stream of a container.
+	ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
+
+	// NetworkInspect returns detailed information about a network.
+	NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
+
+	// ServiceInspectWithRaw returns detailed information about a Swarm service.
+	ServiceInspectWithRaw(ctx context.Context, serviceID string, options swarm.ServiceInspectOptions) (swarm.Service, []byte, error)
+
 	// Events returns a stream of Docker events.
 	Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error)
 
@@ -3,20 +3,49 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/versions"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
+const (
+	// DefaultConnectBackoff is the delay before the first retry in ConnectWithin.
+	DefaultConnectBackoff = 500 * time.Millisecond
+
+	// maxConnectBackoff caps the delay between ConnectWithin attempts.
+	maxConnectBackoff = 5 * time.Second
+)
+
+// ErrAPIVersionMismatch is returned by Connect when the pinned API version
+// is not supported by the Docker daemon.
+var ErrAPIVersionMismatch = errors.New("docker API version not supported by daemon")
+
+// versionedAPI is implemented by Docker clients that track the API version
+// in use (the real Docker client does; test doubles may not).
+type versionedAPI interface {
+	ClientVersion() string
+	NegotiateAPIVersionPing(types.Ping)
+}
+
 // Client wraps the Docker API client with connection management.
 type Client struct {
-	api    DockerAPI
-	logger *slog.Logger
+	api        DockerAPI
+	logger     *slog.Logger
+	apiVersion string // pinned API version, empty when negotiating
 
 	mu        sync.RWMutex
 	connected bool
@@ -25,17 +54,31 @@ type Client struct {
 // NewClient creates a new Docker client using environment configuration.
 // It uses DOCKER_HOST, DOCKER_CERT_PATH, etc. from environment.
 func NewClient(logger *slog.Logger) (*Client, error) {
-	cli, err := client.NewClientWithOpts(
-		client.FromEnv,
-		client.WithAPIVersionNegotiation(),
-	)
+	return NewClientWithVersion("", logger)
+}
+
+// NewClientWithVersion creates a Docker client using environment configuration
+// and pins it to the given API version (e.g., "1.41").
+// If apiVersion is empty, the version is negotiated with the daemon.
+func NewClientWithVersion(apiVersion string, logger *slog.Logger) (*Client, error) {
+	apiVersion = strings.TrimPrefix(apiVersion, "v")
+
+	opts := []client.Opt{client.FromEnv}
+	if apiVersion != "" {
+		opts = append(opts, client.WithVersion(apiVersion))
+	} else {
+		opts = append(opts, client.WithAPIVersionNegotiation())
+	}
+
+	cli, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Docker client: %w", err)
 	}
 
 	return &Client{
-		api:    cli,
-		logger: logger,
+		api:        cli,
+		logger:     logger,
+		apiVersion: apiVersion,
 	}, nil
 }
 
@@ -65,22 +108,73 @@ func NewClientWithAPI(api DockerAPI, logger *slog.Logger) *Client {
 }
 
 // Connect verifies the connection to Docker daemon.
+// It also checks that the API version in use is supported by the daemon,
+// so that version errors surface here instead of mid-run.
 func (c *Client) Connect(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	// Ping Docker daemon to verify connection
-	_, err := c.api.Ping(ctx)
+	ping, err := c.api.Ping(ctx)
 	if err != nil {
 		c.connected = false
 		return fmt.Errorf("failed to connect to Docker daemon: %w", err)
 	}
 
+	if err := c.checkAPIVersion(ping); err != nil {
+		c.connected = false
+		return err
+	}
+
 	c.connected = true
-	c.logger.Info("connected to Docker daemon")
+	c.logger.Info("connected to Docker daemon",
+		"api_version", c.clientVersion(),
+		"daemon_api_version", ping.APIVersion,
+		"pinned", c.apiVersion != "")
+	return nil
+}
+
+// checkAPIVersion validates the client API version against the version
+// reported by the daemon. When negotiating, it applies the negotiation
+// immediately so the negotiated version can be logged.
+func (c *Client) checkAPIVersion(ping types.Ping) error {
+	if c.apiVersion == "" {
+		if v, ok := c.api.(versionedAPI); ok {
+			v.NegotiateAPIVersionPing(ping)
+		}
+		return nil
+	}
+
+	if ping.APIVersion == "" {
+		c.logger.Warn("Docker daemon did not report its API version, cannot validate pinned version",
+			"api_version", c.apiVersion)
+		return nil
+	}
+
+	if versions.GreaterThan(c.apiVersion, ping.APIVersion) {
+		return fmt.Errorf("%w: pinned version %s is newer than the daemon's maximum version %s (lower docker.api_version or remove it to negotiate)",
+			ErrAPIVersionMismatch, c.apiVersion, ping.APIVersion)
+	}
+
 	return nil
 }
 
+// clientVersion returns the API version the client uses for requests.
+func (c *Client) clientVersion() string {
+	if v, ok := c.api.(versionedAPI); ok {
+		return v.ClientVersion()
+	}
+	return c.apiVersion
+}
+
+// APIVersion returns the Docker API version used for requests.
+// Before Connect, this may be the client's default rather than the negotiated version.
+func (c *Client) APIVersion() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.clientVersion()
+}
+
 // IsConnected returns whether the client is connected to Docker daemon.
 func (c *Client) IsConnected() bool {
 	c.mu.RLock()
@@ -153,6 +247,53 @@ func (c *Client) InspectContainer(ctx context.Context, containerID string) (cont
 	return c.api.ContainerInspect(ctx, containerID)
 }
 
+// ContainerLogs writes the last tail lines of a container's stdout and stderr to w.
+// A tail of zero or less writes the full log.
+func (c *Client) ContainerLogs(ctx context.Context, containerID string, tail int, w io.Writer) error {
+	info, err := c.api.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	options := container.LogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Tail:       "all",
+	}
+	if tail > 0 {
+		options.Tail = strconv.Itoa(tail)
+	}
+
+	logs, err := c.api.ContainerLogs(ctx, containerID, options)
+	if err != nil {
+		return fmt.Errorf("failed to fetch container logs: %w", err)
+	}
+	defer logs.Close()
+
+	// Containers without a TTY multiplex stdout and stderr into one stream
+	if info.Config != nil && info.Config.Tty {
+		_, err = io.Copy(w, logs)
+	} else {
+		_, err = stdcopy.StdCopy(w, w, logs)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read container logs: %w", err)
+	}
+	return nil
+}
+
+// NetworkExists checks whether a Docker network with the given name or ID exists.
+func (c *Client) NetworkExists(ctx context.Context, name string) (bool, error) {
+	_, err := c.api.NetworkInspect(ctx, name, network.InspectOptions{})
+	if err != nil {
+		if cerrdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // API returns the underlying DockerAPI for advanced operations.
 func (c *Client) API() DockerAPI {
 	return c.api
@@ -178,6 +319,44 @@ func (c *Client) WaitForConnection(ctx context.Context, maxRetries int, retryInt
 	return fmt.Errorf("failed to connect to Docker after %d attempts", maxRetries)
 }
 
+// ConnectWithin keeps trying to connect to Docker until window has elapsed,
+// starting with backoff between attempts and doubling it each time, e.g.
+// while Docker starts alongside devproxy. A window of zero makes a single
+// attempt. API version mismatches are returned without retrying.
+func (c *Client) ConnectWithin(ctx context.Context, window, backoff time.Duration) error {
+	deadline := time.Now().Add(window)
+	for attempt := 1; ; attempt++ {
+		err := c.Connect(ctx)
+		if err == nil {
+			return nil
+		}
+		if errors.Is(err, ErrAPIVersionMismatch) {
+			return err
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			if attempt == 1 {
+				return err
+			}
+			return fmt.Errorf("gave up after %d attempts in %s: %w", attempt, window, err)
+		}
+
+		wait := min(backoff, remaining)
+		c.logger.Warn("Docker daemon not available, retrying",
+			"attempt", attempt,
+			"retry_in", wait,
+			"error", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+}
+
 // hasPrefix checks if a string has a given prefix.
 func hasPrefix(s, prefix string) bool {
 	return len(s) >= len(prefix) && s[:len(prefix)] == prefix
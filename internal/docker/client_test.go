@@ -1,14 +1,18 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"io"
 	"log/slog"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
 func testLogger() *slog.Logger {
@@ -198,6 +202,56 @@ func TestClient_Connect_WithMock(t *testing.T) {
 			t.Error("expected IsConnected to be false after failed Connect")
 		}
 	})
+
+	t.Run("returns version mismatch when pinned version is newer than daemon", func(t *testing.T) {
+		mockAPI := newMockBuilder().
+			withPingSuccess().
+			build()
+
+		client := NewClientWithAPI(mockAPI, testLogger())
+		client.apiVersion = "1.45"
+
+		err := client.Connect(context.Background())
+		if !errors.Is(err, ErrAPIVersionMismatch) {
+			t.Fatalf("expected ErrAPIVersionMismatch, got %v", err)
+		}
+		if !strings.Contains(err.Error(), "1.41") {
+			t.Errorf("expected error to include daemon version 1.41, got %q", err.Error())
+		}
+		if client.IsConnected() {
+			t.Error("expected IsConnected to be false after version mismatch")
+		}
+	})
+
+	t.Run("accepts pinned version older than daemon", func(t *testing.T) {
+		mockAPI := newMockBuilder().
+			withPingSuccess().
+			build()
+
+		client := NewClientWithAPI(mockAPI, testLogger())
+		client.apiVersion = "1.40"
+
+		if err := client.Connect(context.Background()); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+		if client.APIVersion() != "1.40" {
+			t.Errorf("expected APIVersion 1.40, got %q", client.APIVersion())
+		}
+	})
+}
+
+func TestNewClientWithVersion(t *testing.T) {
+	t.Run("pins the requested version", func(t *testing.T) {
+		client, err := NewClientWithVersion("v1.41", testLogger())
+		if err != nil {
+			t.Fatalf("NewClientWithVersion failed: %v", err)
+		}
+		defer client.Close()
+
+		if client.APIVersion() != "1.41" {
+			t.Errorf("expected APIVersion 1.41, got %q", client.APIVersion())
+		}
+	})
 }
 
 func TestClient_Ping_WithMock(t *testing.T) {
@@ -387,6 +441,83 @@ func TestClient_InspectContainer_WithMock(t *testing.T) {
 	})
 }
 
+func TestClient_ContainerLogs_WithMock(t *testing.T) {
+	// multiplexed builds a log stream as Docker sends it for non-TTY containers
+	multiplexed := func(stdout, stderr string) []byte {
+		var buf bytes.Buffer
+		stdcopy.NewStdWriter(&buf, stdcopy.Stdout).Write([]byte(stdout))
+		stdcopy.NewStdWriter(&buf, stdcopy.Stderr).Write([]byte(stderr))
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		name     string
+		tty      bool
+		stream   []byte
+		tail     int
+		wantTail string
+		want     string
+	}{
+		{
+			name:     "demultiplexes stdout and stderr",
+			stream:   multiplexed("listening on :3000\n", "panic: boom\n"),
+			tail:     50,
+			wantTail: "50",
+			want:     "listening on :3000\npanic: boom\n",
+		},
+		{
+			name:     "copies raw stream for tty containers",
+			tty:      true,
+			stream:   []byte("raw output\n"),
+			wantTail: "all",
+			want:     "raw output\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotOptions container.LogsOptions
+			mockAPI := newMockBuilder().
+				withContainerInspectResult(container.InspectResponse{Config: &container.Config{Tty: tt.tty}}).
+				withContainerLogs(func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+					gotOptions = options
+					return io.NopCloser(bytes.NewReader(tt.stream)), nil
+				}).
+				build()
+
+			client := NewClientWithAPI(mockAPI, testLogger())
+
+			var out bytes.Buffer
+			if err := client.ContainerLogs(context.Background(), "container123", tt.tail, &out); err != nil {
+				t.Fatalf("ContainerLogs failed: %v", err)
+			}
+
+			if out.String() != tt.want {
+				t.Errorf("expected logs %q, got %q", tt.want, out.String())
+			}
+			if gotOptions.Tail != tt.wantTail {
+				t.Errorf("expected tail %q, got %q", tt.wantTail, gotOptions.Tail)
+			}
+			if !gotOptions.ShowStdout || !gotOptions.ShowStderr {
+				t.Error("expected both stdout and stderr to be requested")
+			}
+		})
+	}
+
+	t.Run("returns error when container is gone", func(t *testing.T) {
+		mockAPI := newMockBuilder().
+			withContainerInspectError(errMockNotFound).
+			build()
+
+		client := NewClientWithAPI(mockAPI, testLogger())
+
+		err := client.ContainerLogs(context.Background(), "gone", 10, io.Discard)
+		if err == nil {
+			t.Error("expected ContainerLogs to fail")
+		}
+	})
+}
+
 func TestClient_WaitForConnection_WithMock(t *testing.T) {
 	t.Run("succeeds on first try", func(t *testing.T) {
 		mockAPI := newMockBuilder().
@@ -471,6 +602,101 @@ func TestClient_WaitForConnection_WithMock(t *testing.T) {
 	})
 }
 
+func TestClient_ConnectWithin_WithMock(t *testing.T) {
+	t.Run("retries until daemon is up", func(t *testing.T) {
+		attempts := 0
+		mockAPI := newMockBuilder().
+			withPing(func(ctx context.Context) (types.Ping, error) {
+				attempts++
+				if attempts < 4 {
+					return types.Ping{}, errMockConnection
+				}
+				return types.Ping{APIVersion: "1.41"}, nil
+			}).
+			build()
+
+		var buf bytes.Buffer
+		client := NewClientWithAPI(mockAPI, slog.New(slog.NewTextHandler(&buf, nil)))
+
+		if err := client.ConnectWithin(context.Background(), time.Second, time.Millisecond); err != nil {
+			t.Fatalf("ConnectWithin failed: %v", err)
+		}
+		if attempts != 4 {
+			t.Errorf("expected 4 attempts, got %d", attempts)
+		}
+		if got := strings.Count(buf.String(), "Docker daemon not available, retrying"); got != 3 {
+			t.Errorf("expected each failed attempt to be logged, got %d log lines", got)
+		}
+	})
+
+	t.Run("zero window makes a single attempt", func(t *testing.T) {
+		attempts := 0
+		mockAPI := newMockBuilder().
+			withPing(func(ctx context.Context) (types.Ping, error) {
+				attempts++
+				return types.Ping{}, errMockConnection
+			}).
+			build()
+
+		client := NewClientWithAPI(mockAPI, testLogger())
+		if err := client.ConnectWithin(context.Background(), 0, time.Millisecond); !errors.Is(err, errMockConnection) {
+			t.Errorf("expected connection error, got %v", err)
+		}
+		if attempts != 1 {
+			t.Errorf("expected 1 attempt, got %d", attempts)
+		}
+	})
+
+	t.Run("gives up after window", func(t *testing.T) {
+		mockAPI := newMockBuilder().
+			withPingError(errMockConnection).
+			build()
+
+		client := NewClientWithAPI(mockAPI, testLogger())
+		start := time.Now()
+		err := client.ConnectWithin(context.Background(), 50*time.Millisecond, 10*time.Millisecond)
+		if !errors.Is(err, errMockConnection) {
+			t.Errorf("expected connection error, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+			t.Errorf("expected to retry for the window, took %v", elapsed)
+		}
+	})
+
+	t.Run("does not retry version mismatch", func(t *testing.T) {
+		attempts := 0
+		mockAPI := newMockBuilder().
+			withPing(func(ctx context.Context) (types.Ping, error) {
+				attempts++
+				return types.Ping{APIVersion: "1.24"}, nil
+			}).
+			build()
+
+		client := NewClientWithAPI(mockAPI, testLogger())
+		client.apiVersion = "1.41"
+		if err := client.ConnectWithin(context.Background(), time.Second, time.Millisecond); !errors.Is(err, ErrAPIVersionMismatch) {
+			t.Errorf("expected ErrAPIVersionMismatch, got %v", err)
+		}
+		if attempts != 1 {
+			t.Errorf("expected 1 attempt, got %d", attempts)
+		}
+	})
+
+	t.Run("respects context cancellation", func(t *testing.T) {
+		mockAPI := newMockBuilder().
+			withPingError(errMockConnection).
+			build()
+
+		client := NewClientWithAPI(mockAPI, testLogger())
+		ctx, cancel := context.WithTimeout(context.Background(), 25*time.Millisecond)
+		defer cancel()
+
+		if err := client.ConnectWithin(ctx, time.Minute, 10*time.Millisecond); err != context.DeadlineExceeded {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
+
 func TestClient_Close_WithMock(t *testing.T) {
 	t.Run("calls close on API", func(t *testing.T) {
 		closeCalled := false
@@ -0,0 +1,124 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"time"
+)
+
+// healthCheckTimeout bounds a single health check request.
+const healthCheckTimeout = 5 * time.Second
+
+// healthCheck is a running health check of a container's backend.
+type healthCheck struct {
+	backend string
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when the check stopped
+}
+
+// startHealthCheck probes http://backend{path} every interval until the
+// container stops and reports the result to the registry. The backend gets
+// no traffic until the first check passes. A backend the container already
+// checks is not checked twice, e.g. when its route is added again.
+func (s *RouteSync) startHealthCheck(containerID, host, backend, path string, interval time.Duration) {
+	ctx, cancel := context.WithCancel(context.Background())
+	check := healthCheck{backend: backend, cancel: cancel, done: make(chan struct{})}
+	s.mu.Lock()
+	if slices.ContainsFunc(s.healthChecks[containerID], func(c healthCheck) bool { return c.backend == backend }) {
+		s.mu.Unlock()
+		cancel()
+		return
+	}
+	s.healthChecks[containerID] = append(s.healthChecks[containerID], check)
+	s.mu.Unlock()
+	s.registry.SetBackendHealthy(backend, false)
+
+	go func() {
+		defer close(check.done)
+
+		client := &http.Client{
+			Timeout: healthCheckTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+		healthy := false
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			start := time.Now()
+			err := checkHealth(ctx, client, host, backend, path)
+			if ctx.Err() != nil {
+				return
+			}
+			switch {
+			case err == nil && !healthy:
+				s.logger.Info("backend health check passing", "host", host, "backend", backend)
+			case err != nil && healthy:
+				s.logger.Warn("backend health check failing", "host", host, "backend", backend, "error", err)
+			case err != nil:
+				s.logger.Debug("backend not healthy yet", "host", host, "backend", backend, "error", err)
+			}
+			healthy = err == nil
+			s.registry.RecordHealthCheck(backend, time.Since(start), err)
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopHealthChecks stops the health checks of a container and forgets their
+// results, since its address may be reused by another container. It returns
+// once the checks stopped, so none records a result afterwards.
+func (s *RouteSync) stopHealthChecks(containerID string) {
+	s.mu.Lock()
+	checks := s.healthChecks[containerID]
+	delete(s.healthChecks, containerID)
+	s.mu.Unlock()
+
+	for _, check := range checks {
+		check.cancel()
+		<-check.done
+		s.registry.ForgetHealthCheck(check.backend)
+	}
+}
+
+// Close stops the health checks of all containers.
+func (s *RouteSync) Close() {
+	s.mu.RLock()
+	containerIDs := slices.Collect(maps.Keys(s.healthChecks))
+	s.mu.RUnlock()
+
+	for _, id := range containerIDs {
+		s.stopHealthChecks(id)
+	}
+}
+
+// checkHealth returns nil if GET http://backend{path} answers with a 2xx status.
+// The request carries the route's host so virtual-hosted apps answer it.
+func checkHealth(ctx context.Context, client *http.Client, host, backend, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+backend+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Host = host
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s returned %s", path, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+func TestRouteSync_HealthCheck(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	var gotHost atomic.Value
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			t.Errorf("unexpected health check path %q", r.URL.Path)
+		}
+		gotHost.Store(r.Host)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer backend.Close()
+
+	_, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+
+	registry := proxy.NewRegistry()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockAPI := newMockBuilder().
+		withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			return makeContainerInspectResponse(containerID, "web", "127.0.0.1", "bridge"), nil
+		}).
+		build()
+	sync := NewRouteSync(registry, NewClientWithAPI(mockAPI, logger), "bridge", logger)
+
+	sync.HandleEvent(ContainerEvent{
+		ContainerID:   "web123",
+		ContainerName: "web",
+		Labels: map[string]string{
+			"devproxy.enable":               "true",
+			"devproxy.host":                 "web.localhost",
+			"devproxy.port":                 port,
+			"devproxy.healthcheck.path":     "/healthz",
+			"devproxy.healthcheck.interval": "10ms",
+		},
+		Type: "start",
+	})
+
+	route := registry.Lookup("web.localhost")
+	if route == nil {
+		t.Fatal("expected route to be added")
+	}
+	if _, ok := registry.SelectBackend(route); ok {
+		t.Error("expected no traffic before the first health check passes")
+	}
+
+	waitForAvailable := func(want bool) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if _, ok := registry.SelectBackend(route); ok == want {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("backend available = %v, want %v", !want, want)
+	}
+
+	status.Store(http.StatusOK)
+	waitForAvailable(true)
+	if host, _ := gotHost.Load().(string); host != "web.localhost" {
+		t.Errorf("expected health check Host web.localhost, got %q", host)
+	}
+
+	if stats, ok := registry.HealthStats(route.Backend); !ok || !stats.Healthy || stats.ConsecutiveSuccesses == 0 || stats.Latency <= 0 {
+		t.Errorf("expected passing health check stats, got %+v", stats)
+	}
+
+	status.Store(http.StatusInternalServerError)
+	waitForAvailable(false)
+	if stats, _ := registry.HealthStats(route.Backend); stats.Healthy || stats.ConsecutiveFailures == 0 || stats.ConsecutiveSuccesses != 0 {
+		t.Errorf("expected failing health check stats, got %+v", stats)
+	}
+
+	// Stopping the container forgets the health state of its address
+	sync.HandleEvent(ContainerEvent{ContainerID: "web123", Type: "stop"})
+	waitForAvailable(true)
+	if _, ok := registry.HealthStats(route.Backend); ok {
+		t.Error("expected health check stats to be forgotten")
+	}
+	if registry.Lookup("web.localhost") != nil {
+		t.Error("expected route to be removed")
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"ok", http.StatusOK, false},
+		{"no content", http.StatusNoContent, false},
+		{"redirect", http.StatusFound, true},
+		{"server error", http.StatusInternalServerError, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status == http.StatusFound {
+					w.Header().Set("Location", "/login")
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+			err := checkHealth(context.Background(), client, "app.localhost", server.Listener.Addr().String(), "/healthz")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkHealth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRouteSync_HealthCheckRestart(t *testing.T) {
+	var checks atomic.Int32
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		checks.Add(1)
+	}))
+	defer backend.Close()
+
+	_, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+
+	registry := proxy.NewRegistry()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockAPI := newMockBuilder().
+		withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			return makeContainerInspectResponse(containerID, "web", "127.0.0.1", "bridge"), nil
+		}).
+		build()
+	sync := NewRouteSync(registry, NewClientWithAPI(mockAPI, logger), "bridge", logger)
+
+	start := ContainerEvent{
+		ContainerID:   "web123",
+		ContainerName: "web",
+		Labels: map[string]string{
+			"devproxy.enable":               "true",
+			"devproxy.host":                 "web.localhost",
+			"devproxy.port":                 port,
+			"devproxy.healthcheck.path":     "/healthz",
+			"devproxy.healthcheck.interval": "1h",
+		},
+		Type: "start",
+	}
+	sync.HandleEvent(start)
+	// The route is removed by hand while the container keeps running
+	if err := registry.Remove("web.localhost"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	sync.HandleEvent(start)
+
+	sync.mu.RLock()
+	running := len(sync.healthChecks["web123"])
+	sync.mu.RUnlock()
+	if running != 1 {
+		t.Errorf("expected the re-added route to keep one health check, got %d running", running)
+	}
+
+	// After Close no check is running or records a result
+	sync.Close()
+	if _, ok := registry.HealthStats("127.0.0.1:" + port); ok {
+		t.Error("expected health check stats to be forgotten on Close")
+	}
+	sync.mu.RLock()
+	running = len(sync.healthChecks)
+	sync.mu.RUnlock()
+	if running != 0 {
+		t.Errorf("expected no health checks after Close, got %d containers", running)
+	}
+}
@@ -3,10 +3,16 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -128,6 +134,83 @@ func (h *testHelper) removeContainer(ctx context.Context, containerID string) {
 	}
 }
 
+func TestIntegration_ClientConnectLogsAPIVersion(t *testing.T) {
+	helper := newTestHelper(t)
+	defer helper.close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	ping, err := helper.client.Ping(ctx)
+	if err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+
+	t.Run("logs negotiated version", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+		devproxyClient, err := NewClient(logger)
+		if err != nil {
+			t.Fatalf("NewClient failed: %v", err)
+		}
+		defer devproxyClient.Close()
+
+		if err := devproxyClient.Connect(ctx); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+
+		version := devproxyClient.APIVersion()
+		if version == "" {
+			t.Fatal("expected negotiated API version to be set")
+		}
+		if !strings.Contains(buf.String(), "api_version="+version) {
+			t.Errorf("expected log to contain api_version=%s, got: %s", version, buf.String())
+		}
+		if !strings.Contains(buf.String(), "pinned=false") {
+			t.Errorf("expected log to report pinned=false, got: %s", buf.String())
+		}
+	})
+
+	t.Run("logs pinned version", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+		devproxyClient, err := NewClientWithVersion(ping.APIVersion, logger)
+		if err != nil {
+			t.Fatalf("NewClientWithVersion failed: %v", err)
+		}
+		defer devproxyClient.Close()
+
+		if err := devproxyClient.Connect(ctx); err != nil {
+			t.Fatalf("Connect failed: %v", err)
+		}
+
+		if !strings.Contains(buf.String(), "api_version="+ping.APIVersion) {
+			t.Errorf("expected log to contain api_version=%s, got: %s", ping.APIVersion, buf.String())
+		}
+		if !strings.Contains(buf.String(), "pinned=true") {
+			t.Errorf("expected log to report pinned=true, got: %s", buf.String())
+		}
+	})
+
+	t.Run("rejects pinned version newer than daemon", func(t *testing.T) {
+		devproxyClient, err := NewClientWithVersion("99.0", helper.logger)
+		if err != nil {
+			t.Fatalf("NewClientWithVersion failed: %v", err)
+		}
+		defer devproxyClient.Close()
+
+		err = devproxyClient.Connect(ctx)
+		if !errors.Is(err, ErrAPIVersionMismatch) {
+			t.Fatalf("expected ErrAPIVersionMismatch, got %v", err)
+		}
+		if !strings.Contains(err.Error(), ping.APIVersion) {
+			t.Errorf("expected error to include daemon version %s, got %q", ping.APIVersion, err.Error())
+		}
+	})
+}
+
 func TestIntegration_ClientConnect(t *testing.T) {
 	helper := newTestHelper(t)
 	defer helper.close()
@@ -503,3 +586,112 @@ func TestIntegration_RouteSyncFullFlow(t *testing.T) {
 		t.Error("expected route to be removed after container stop")
 	}
 }
+
+// forwardUnixSocket accepts connections on ln and forwards them to target.
+func forwardUnixSocket(ln net.Listener, target string) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			upstream, err := net.Dial("unix", target)
+			if err != nil {
+				return
+			}
+			defer upstream.Close()
+			go io.Copy(upstream, conn)
+			io.Copy(conn, upstream)
+		}()
+	}
+}
+
+func TestIntegration_ConnectWithinDockerStartingLate(t *testing.T) {
+	helper := newTestHelper(t)
+	defer helper.close()
+
+	host := helper.client.DaemonHost()
+	if !strings.HasPrefix(host, "unix://") {
+		t.Skipf("Docker host %s is not a unix socket", host)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	helper.pullImage(ctx)
+	containerID := helper.createContainer(ctx, testContainerName+"-late-docker", map[string]string{
+		"devproxy.enable": "true",
+		"devproxy.host":   "late-docker.localhost",
+		"devproxy.port":   "3000",
+	})
+	helper.startContainer(ctx, containerID)
+
+	// devproxy talks to a socket that only comes up after a delay,
+	// like a Docker daemon starting at the same time as devproxy
+	dir, err := os.MkdirTemp("", "dpd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "docker.sock")
+
+	devproxyClient, err := NewClientWithHost("unix://"+socket, helper.logger)
+	if err != nil {
+		t.Fatalf("NewClientWithHost failed: %v", err)
+	}
+	defer devproxyClient.Close()
+
+	receivedEvents := make(chan ContainerEvent, 10)
+	watcher := NewWatcher(devproxyClient, func(event ContainerEvent) {
+		receivedEvents <- event
+	}, helper.logger)
+	defer watcher.Stop()
+
+	started := make(chan error, 1)
+	go func() {
+		if err := devproxyClient.ConnectWithin(ctx, 30*time.Second, 100*time.Millisecond); err != nil {
+			started <- err
+			return
+		}
+		started <- watcher.Start(ctx)
+	}()
+
+	time.Sleep(time.Second)
+	select {
+	case err := <-started:
+		t.Fatalf("expected to still be waiting for Docker, got %v", err)
+	default:
+	}
+
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("failed to listen on %s: %v", socket, err)
+	}
+	defer ln.Close()
+	go forwardUnixSocket(ln, strings.TrimPrefix(host, "unix://"))
+
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatalf("failed to start once Docker was available: %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("timeout waiting for Docker connection")
+	}
+	if !watcher.IsRunning() {
+		t.Error("expected watcher to be running")
+	}
+
+	// The startup scan picks up the container started before Docker was reachable
+	for {
+		select {
+		case event := <-receivedEvents:
+			if event.ContainerID == containerID {
+				return
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("timeout waiting for existing container to be scanned")
+		}
+	}
+}
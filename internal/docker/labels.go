@@ -3,13 +3,35 @@ package docker
 
 import (
 	"fmt"
+	"maps"
+	"net/http"
+	"net/netip"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/proxy"
 )
 
 // LabelPrefix is the prefix used for all devproxy Docker labels.
 const LabelPrefix = "devproxy"
 
+// Labels Docker sets on the task containers of a Swarm service. Devproxy
+// labels set under deploy.labels live on the service rather than its tasks.
+const (
+	swarmServiceIDLabel   = "com.docker.swarm.service.id"
+	swarmServiceNameLabel = "com.docker.swarm.service.name"
+	stackNamespaceLabel   = "com.docker.stack.namespace"
+)
+
+// MaxFollowRedirects caps the follow_redirects label.
+const MaxFollowRedirects = 10
+
+// DefaultHealthInterval is how often a backend's health check path is probed
+// when the healthcheck.interval label is not set.
+const DefaultHealthInterval = 10 * time.Second
+
 // ServiceConfig represents a parsed service configuration from Docker labels.
 type ServiceConfig struct {
 	// Name is the service name (for multi-service configs) or empty for single service.
@@ -18,22 +40,92 @@ type ServiceConfig struct {
 	// Host is the hostname to route to this service (required).
 	Host string
 
-	// Port is the container port to forward to (defaults to 80 for HTTP).
+	// Port is the container port to forward to (defaults to the parser's
+	// default port, DefaultPort unless configured).
 	Port int
 
 	// Entrypoint specifies which TCP entrypoint to use (empty for HTTP).
 	// Examples: "postgres", "mongo", "redis"
 	Entrypoint string
+
+	// ExplicitPort is set when the port was paired with the entrypoint
+	// (e.g., "postgres:5432") and must not be replaced by its target_port.
+	ExplicitPort bool
+
+	// PortLabeled is set when the port came from labels (the port label or an
+	// entrypoint pairing) rather than the default.
+	PortLabeled bool
+
+	// Allow lists client networks permitted to access the service (empty = all).
+	Allow []netip.Prefix
+
+	// Deny lists client networks rejected from the service (takes precedence over Allow).
+	Deny []netip.Prefix
+
+	// Methods lists the HTTP methods the service accepts (empty = all).
+	Methods []string
+
+	// BasicAuth maps user names to bcrypt hashes required to access the service (empty = no auth).
+	BasicAuth map[string]string
+
+	// FollowRedirects is the number of backend redirects followed server-side (0 = none).
+	FollowRedirects int
+
+	// HealthPath is the HTTP path probed to decide whether the backend gets
+	// traffic (e.g., "/healthz"). Empty disables the active health check.
+	HealthPath string
+
+	// HealthInterval is how often HealthPath is probed.
+	HealthInterval time.Duration
+
+	// Timeout bounds how long a request may take (0 = proxy default).
+	Timeout time.Duration
+
+	// StickyCookie names the cookie pinning clients to one replica (empty = none).
+	StickyCookie string
+
+	// PathPrefix limits the HTTP route to request paths below it (empty = all paths).
+	PathPrefix string
+
+	// StripPrefix is removed from request paths before proxying (empty = none).
+	StripPrefix string
+
+	// RequestHeaders are set on proxied requests (empty value = remove).
+	RequestHeaders map[string]string
+
+	// ResponseHeaders are set on backend responses (empty value = remove).
+	ResponseHeaders map[string]string
+
+	// WebSocket selects how streaming requests are detected (empty = auto).
+	WebSocket proxy.WebSocketMode
+
+	// HTTP selects whether plain HTTP requests are proxied or redirected to
+	// HTTPS (empty = redirect).
+	HTTP proxy.HTTPMode
 }
 
+// DefaultPort is the container port routed to when no port label is set.
+const DefaultPort = 80
+
 // LabelParser parses Docker container labels into service configurations.
 type LabelParser struct {
-	prefix string
+	prefix      string
+	defaultPort int
 }
 
 // NewLabelParser creates a new label parser with the devproxy prefix.
 func NewLabelParser() *LabelParser {
-	return &LabelParser{prefix: LabelPrefix}
+	return &LabelParser{prefix: LabelPrefix, defaultPort: DefaultPort}
+}
+
+// SetDefaultPort sets the container port of services without a port label,
+// e.g. 3000 for teams whose dev servers all listen there. Values below 1
+// restore DefaultPort. It must be called before labels are parsed.
+func (p *LabelParser) SetDefaultPort(port int) {
+	if port < 1 {
+		port = DefaultPort
+	}
+	p.defaultPort = port
 }
 
 // ParseLabels parses container labels and returns service configurations.
@@ -81,11 +173,99 @@ func (p *LabelParser) parseSingleService(labels map[string]string) ([]ServiceCon
 		}
 	}
 
+	entrypoints, err := parseEntrypoints(labels[entrypointKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s: %w", entrypointKey, err)
+	}
+
 	config := ServiceConfig{
-		Host:       host,
-		Port:       80, // Default port
-		Entrypoint: labels[entrypointKey],
+		Host: host,
+		Port: p.defaultPort,
+	}
+
+	// Parse client access lists
+	allow, err := proxy.ParseCIDRList(labels[p.prefix+".allow"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.allow: %w", p.prefix, err)
+	}
+	deny, err := proxy.ParseCIDRList(labels[p.prefix+".deny"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.deny: %w", p.prefix, err)
+	}
+	config.Allow = allow
+	config.Deny = deny
+
+	methods, err := proxy.ParseMethodList(labels[p.prefix+".methods"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.methods: %w", p.prefix, err)
+	}
+	config.Methods = methods
+
+	basicAuth, err := proxy.ParseBasicAuth(labels[p.prefix+".auth.basic"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.auth.basic: %w", p.prefix, err)
+	}
+	config.BasicAuth = basicAuth
+
+	followRedirects, err := parseFollowRedirects(labels[p.prefix+".follow_redirects"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.follow_redirects: %w", p.prefix, err)
+	}
+	config.FollowRedirects = followRedirects
+
+	healthPath, healthInterval, err := parseHealthCheck(labels[p.prefix+".healthcheck.path"], labels[p.prefix+".healthcheck.interval"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.healthcheck: %w", p.prefix, err)
+	}
+	config.HealthPath = healthPath
+	config.HealthInterval = healthInterval
+
+	timeout, err := parseTimeout(labels[p.prefix+".timeout"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.timeout: %w", p.prefix, err)
+	}
+	config.Timeout = timeout
+
+	stickyCookie, err := parseStickyCookie(labels[p.prefix+".sticky.cookie"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.sticky.cookie: %w", p.prefix, err)
+	}
+	config.StickyCookie = stickyCookie
+
+	pathPrefix, err := parsePathPrefix(labels[p.prefix+".path"], host, entrypoints)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.path: %w", p.prefix, err)
+	}
+	config.PathPrefix = pathPrefix
+
+	stripPrefix, err := parseStripPrefix(labels[p.prefix+".strip_prefix"], entrypoints)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.strip_prefix: %w", p.prefix, err)
+	}
+	config.StripPrefix = stripPrefix
+
+	responseHeaders, err := proxy.ParseHeaderList(labels[p.prefix+".headers"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.headers: %w", p.prefix, err)
+	}
+	requestHeaders, err := proxy.ParseHeaderList(labels[p.prefix+".request_headers"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.request_headers: %w", p.prefix, err)
 	}
+	config.ResponseHeaders = responseHeaders
+	config.RequestHeaders = requestHeaders
+
+	webSocket, err := parseWebSocketMode(labels[p.prefix+".websocket"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.websocket: %w", p.prefix, err)
+	}
+	config.WebSocket = webSocket
+
+	httpMode, err := parseHTTPMode(labels[p.prefix+".http"], entrypoints)
+	if err != nil {
+		return nil, fmt.Errorf("invalid label %s.http: %w", p.prefix, err)
+	}
+	config.HTTP = httpMode
 
 	// Parse port if specified
 	if portStr := labels[portKey]; portStr != "" {
@@ -97,9 +277,10 @@ func (p *LabelParser) parseSingleService(labels map[string]string) ([]ServiceCon
 			return nil, fmt.Errorf("port %d out of valid range (1-65535)", port)
 		}
 		config.Port = port
+		config.PortLabeled = true
 	}
 
-	return []ServiceConfig{config}, nil
+	return expandEntrypoints(config, entrypoints), nil
 }
 
 // parseMultiService parses multi-service labels.
@@ -123,6 +304,9 @@ func (p *LabelParser) parseMultiService(labels map[string]string) ([]ServiceConf
 
 		serviceName := parts[0]
 		field := parts[1]
+		if serviceName == "" {
+			return nil, fmt.Errorf("empty service name in label %s", key)
+		}
 
 		if services[serviceName] == nil {
 			services[serviceName] = make(map[string]string)
@@ -134,9 +318,10 @@ func (p *LabelParser) parseMultiService(labels map[string]string) ([]ServiceConf
 		return nil, fmt.Errorf("no valid service configurations found")
 	}
 
-	// Parse each service
+	// Parse each service, in order so errors and routes are deterministic
 	var configs []ServiceConfig
-	for name, fields := range services {
+	for _, name := range slices.Sorted(maps.Keys(services)) {
+		fields := services[name]
 		host := fields["host"]
 		if host == "" {
 			return nil, fmt.Errorf("service %q missing required field: host", name)
@@ -150,12 +335,100 @@ func (p *LabelParser) parseMultiService(labels map[string]string) ([]ServiceConf
 			}
 		}
 
+		entrypoints, err := parseEntrypoints(fields["entrypoint"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid entrypoint: %w", name, err)
+		}
+
 		config := ServiceConfig{
-			Name:       name,
-			Host:       host,
-			Port:       80,
-			Entrypoint: fields["entrypoint"],
+			Name: name,
+			Host: host,
+			Port: p.defaultPort,
+		}
+
+		// Parse client access lists
+		allow, err := proxy.ParseCIDRList(fields["allow"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid allow list: %w", name, err)
+		}
+		deny, err := proxy.ParseCIDRList(fields["deny"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid deny list: %w", name, err)
+		}
+		config.Allow = allow
+		config.Deny = deny
+
+		methods, err := proxy.ParseMethodList(fields["methods"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid methods: %w", name, err)
+		}
+		config.Methods = methods
+
+		basicAuth, err := proxy.ParseBasicAuth(fields["auth.basic"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid auth.basic: %w", name, err)
+		}
+		config.BasicAuth = basicAuth
+
+		followRedirects, err := parseFollowRedirects(fields["follow_redirects"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid follow_redirects: %w", name, err)
+		}
+		config.FollowRedirects = followRedirects
+
+		healthPath, healthInterval, err := parseHealthCheck(fields["healthcheck.path"], fields["healthcheck.interval"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid healthcheck: %w", name, err)
 		}
+		config.HealthPath = healthPath
+		config.HealthInterval = healthInterval
+
+		timeout, err := parseTimeout(fields["timeout"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid timeout: %w", name, err)
+		}
+		config.Timeout = timeout
+
+		stickyCookie, err := parseStickyCookie(fields["sticky.cookie"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid sticky.cookie: %w", name, err)
+		}
+		config.StickyCookie = stickyCookie
+
+		pathPrefix, err := parsePathPrefix(fields["path"], host, entrypoints)
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid path: %w", name, err)
+		}
+		config.PathPrefix = pathPrefix
+
+		stripPrefix, err := parseStripPrefix(fields["strip_prefix"], entrypoints)
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid strip_prefix: %w", name, err)
+		}
+		config.StripPrefix = stripPrefix
+
+		responseHeaders, err := proxy.ParseHeaderList(fields["headers"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid headers: %w", name, err)
+		}
+		requestHeaders, err := proxy.ParseHeaderList(fields["request_headers"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid request_headers: %w", name, err)
+		}
+		config.ResponseHeaders = responseHeaders
+		config.RequestHeaders = requestHeaders
+
+		webSocket, err := parseWebSocketMode(fields["websocket"])
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid websocket: %w", name, err)
+		}
+		config.WebSocket = webSocket
+
+		httpMode, err := parseHTTPMode(fields["http"], entrypoints)
+		if err != nil {
+			return nil, fmt.Errorf("service %q has invalid http: %w", name, err)
+		}
+		config.HTTP = httpMode
 
 		// Parse port if specified
 		if portStr := fields["port"]; portStr != "" {
@@ -167,14 +440,210 @@ func (p *LabelParser) parseMultiService(labels map[string]string) ([]ServiceConf
 				return nil, fmt.Errorf("service %q port %d out of valid range (1-65535)", name, port)
 			}
 			config.Port = port
+			config.PortLabeled = true
 		}
 
-		configs = append(configs, config)
+		configs = append(configs, expandEntrypoints(config, entrypoints)...)
 	}
 
 	return configs, nil
 }
 
+// entrypointPort is an entrypoint from the entrypoint label, optionally paired with a port.
+type entrypointPort struct {
+	Name string
+	Port int // 0 if not paired
+}
+
+// parseEntrypoints parses an entrypoint label value: a comma-separated list of
+// entrypoint names, each optionally paired with the container port to route
+// to (e.g., "postgres:5432,mysql:3306").
+func parseEntrypoints(value string) ([]entrypointPort, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var entrypoints []entrypointPort
+	seen := make(map[string]bool)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		name, portStr, paired := strings.Cut(item, ":")
+		// Entrypoint names are case-insensitive, like config keys
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			return nil, fmt.Errorf("empty entrypoint name in %q", value)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("entrypoint %q listed more than once", name)
+		}
+		seen[name] = true
+
+		ep := entrypointPort{Name: name}
+		if paired {
+			port, err := strconv.Atoi(portStr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid port for entrypoint %q: %q", name, portStr)
+			}
+			if port < 1 || port > 65535 {
+				return nil, fmt.Errorf("port %d for entrypoint %q out of valid range (1-65535)", port, name)
+			}
+			ep.Port = port
+		}
+		entrypoints = append(entrypoints, ep)
+	}
+	return entrypoints, nil
+}
+
+// expandEntrypoints returns one service config per entrypoint, using the
+// paired port where given. Without entrypoints the config is returned as is.
+func expandEntrypoints(config ServiceConfig, entrypoints []entrypointPort) []ServiceConfig {
+	if len(entrypoints) == 0 {
+		return []ServiceConfig{config}
+	}
+
+	configs := make([]ServiceConfig, 0, len(entrypoints))
+	for _, ep := range entrypoints {
+		c := config
+		c.Entrypoint = ep.Name
+		if ep.Port > 0 {
+			c.Port = ep.Port
+			c.ExplicitPort = true
+			c.PortLabeled = true
+		}
+		configs = append(configs, c)
+	}
+	return configs
+}
+
+// parseFollowRedirects parses a follow_redirects label value.
+// An empty value means redirects are passed to the client.
+func parseFollowRedirects(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", value)
+	}
+	if n < 0 || n > MaxFollowRedirects {
+		return 0, fmt.Errorf("%d out of valid range (0-%d)", n, MaxFollowRedirects)
+	}
+	return n, nil
+}
+
+// parseHealthCheck parses the healthcheck.path and healthcheck.interval label
+// values. The interval defaults to DefaultHealthInterval.
+func parseHealthCheck(path, interval string) (string, time.Duration, error) {
+	path = strings.TrimSpace(path)
+	interval = strings.TrimSpace(interval)
+	if path == "" {
+		if interval != "" {
+			return "", 0, fmt.Errorf("interval %q set without a path", interval)
+		}
+		return "", 0, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", 0, fmt.Errorf("path %q must start with /", path)
+	}
+	if interval == "" {
+		return path, DefaultHealthInterval, nil
+	}
+	d, err := time.ParseDuration(interval)
+	if err != nil || d <= 0 {
+		return "", 0, fmt.Errorf("interval %q must be a positive duration (e.g., 10s)", interval)
+	}
+	return path, d, nil
+}
+
+// parseTimeout parses a timeout label value.
+// An empty value selects the proxy's default request timeout.
+func parseTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q must be a positive duration (e.g., 2m)", value)
+	}
+	return d, nil
+}
+
+// parseStickyCookie parses a sticky.cookie label value.
+// An empty value disables sticky sessions.
+func parseStickyCookie(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	if err := (&http.Cookie{Name: value, Value: "x"}).Valid(); err != nil {
+		return "", fmt.Errorf("%q is not a valid cookie name", value)
+	}
+	return value, nil
+}
+
+// parsePathPrefix parses a path label value for a service with the given
+// hosts and entrypoints. Path prefixes only apply to HTTP routes on exact hosts.
+func parsePathPrefix(value, hosts string, entrypoints []entrypointPort) (string, error) {
+	prefix, err := proxy.NormalizePathPrefix(value)
+	if err != nil || prefix == "" {
+		return "", err
+	}
+	if len(entrypoints) > 0 {
+		return "", fmt.Errorf("%q cannot be combined with a TCP entrypoint", value)
+	}
+	for _, host := range strings.Split(hosts, ",") {
+		if isValidWildcard(strings.TrimSpace(host)) {
+			return "", fmt.Errorf("%q cannot be combined with wildcard host %s", value, strings.TrimSpace(host))
+		}
+	}
+	return prefix, nil
+}
+
+// parseStripPrefix parses a strip_prefix label value. Stripping only applies
+// to HTTP routes.
+func parseStripPrefix(value string, entrypoints []entrypointPort) (string, error) {
+	prefix, err := proxy.NormalizePathPrefix(value)
+	if err != nil || prefix == "" {
+		return "", err
+	}
+	if len(entrypoints) > 0 {
+		return "", fmt.Errorf("%q cannot be combined with a TCP entrypoint", value)
+	}
+	return prefix, nil
+}
+
+// parseWebSocketMode parses a websocket label value. An empty value selects
+// the default detection.
+func parseWebSocketMode(value string) (proxy.WebSocketMode, error) {
+	mode := proxy.WebSocketMode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case "", proxy.WebSocketAuto:
+		return "", nil
+	case proxy.WebSocketForce, proxy.WebSocketOff:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%q must be one of: force, auto, off", value)
+	}
+}
+
+// parseHTTPMode parses an http label value. An empty value selects the
+// redirect to HTTPS. Proxying plain HTTP is not supported on TCP entrypoints.
+func parseHTTPMode(value string, entrypoints []entrypointPort) (proxy.HTTPMode, error) {
+	mode := proxy.HTTPMode(strings.ToLower(strings.TrimSpace(value)))
+	switch mode {
+	case "", proxy.HTTPRedirect:
+		return "", nil
+	case proxy.HTTPProxy:
+		if len(entrypoints) > 0 {
+			return "", fmt.Errorf("%q cannot be combined with a TCP entrypoint", value)
+		}
+		return mode, nil
+	default:
+		return "", fmt.Errorf("%q must be one of: proxy, redirect", value)
+	}
+}
+
 // IsEnabled checks if devproxy is enabled for the given labels.
 func (p *LabelParser) IsEnabled(labels map[string]string) bool {
 	enableKey := p.prefix + ".enable"
@@ -1,7 +1,12 @@
 package docker
 
 import (
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/proxy"
 )
 
 func TestLabelParser_ParseLabels(t *testing.T) {
@@ -43,6 +48,438 @@ func TestLabelParser_ParseLabels(t *testing.T) {
 		}
 	})
 
+	t.Run("parses allow and deny lists", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable": "true",
+			"devproxy.host":   "app.localhost",
+			"devproxy.allow":  "192.168.0.0/16, fd00::/8",
+			"devproxy.deny":   "192.168.1.13",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if len(configs[0].Allow) != 2 {
+			t.Errorf("expected 2 allow entries, got %v", configs[0].Allow)
+		}
+		if len(configs[0].Deny) != 1 || configs[0].Deny[0].String() != "192.168.1.13/32" {
+			t.Errorf("expected deny [192.168.1.13/32], got %v", configs[0].Deny)
+		}
+	})
+
+	t.Run("rejects invalid allow list", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable": "true",
+			"devproxy.host":   "app.localhost",
+			"devproxy.allow":  "192.168.0.0/99",
+		}
+
+		if _, err := parser.ParseLabels(labels); err == nil {
+			t.Error("expected error for invalid allow list")
+		}
+	})
+
+	t.Run("parses methods", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":  "true",
+			"devproxy.host":    "app.localhost",
+			"devproxy.methods": "get, HEAD",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(configs[0].Methods, []string{"GET", "HEAD"}) {
+			t.Errorf("expected methods [GET HEAD], got %v", configs[0].Methods)
+		}
+
+		labels["devproxy.methods"] = "GET, BAD METHOD"
+		if _, err := parser.ParseLabels(labels); err == nil {
+			t.Error("expected error for invalid method")
+		}
+	})
+
+	t.Run("parses follow_redirects", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":           "true",
+			"devproxy.host":             "app.localhost",
+			"devproxy.follow_redirects": "3",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configs[0].FollowRedirects != 3 {
+			t.Errorf("expected FollowRedirects 3, got %d", configs[0].FollowRedirects)
+		}
+	})
+
+	t.Run("rejects invalid follow_redirects", func(t *testing.T) {
+		for _, value := range []string{"yes", "-1", "11"} {
+			labels := map[string]string{
+				"devproxy.enable":                        "true",
+				"devproxy.services.web.host":             "app.localhost",
+				"devproxy.services.web.follow_redirects": value,
+			}
+			if _, err := parser.ParseLabels(labels); err == nil {
+				t.Errorf("expected error for follow_redirects=%q", value)
+			}
+		}
+	})
+
+	t.Run("parses healthcheck with default interval", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":           "true",
+			"devproxy.host":             "app.localhost",
+			"devproxy.healthcheck.path": "/healthz",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configs[0].HealthPath != "/healthz" {
+			t.Errorf("expected HealthPath /healthz, got %q", configs[0].HealthPath)
+		}
+		if configs[0].HealthInterval != DefaultHealthInterval {
+			t.Errorf("expected HealthInterval %v, got %v", DefaultHealthInterval, configs[0].HealthInterval)
+		}
+	})
+
+	t.Run("parses multi-service healthcheck with interval", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":                            "true",
+			"devproxy.services.web.host":                 "app.localhost",
+			"devproxy.services.web.healthcheck.path":     "/ready",
+			"devproxy.services.web.healthcheck.interval": "2s",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configs[0].HealthPath != "/ready" || configs[0].HealthInterval != 2*time.Second {
+			t.Errorf("expected /ready every 2s, got %q every %v", configs[0].HealthPath, configs[0].HealthInterval)
+		}
+	})
+
+	t.Run("rejects invalid healthcheck", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			labels map[string]string
+		}{
+			{"path without slash", map[string]string{"devproxy.healthcheck.path": "healthz"}},
+			{"invalid interval", map[string]string{"devproxy.healthcheck.path": "/healthz", "devproxy.healthcheck.interval": "soon"}},
+			{"negative interval", map[string]string{"devproxy.healthcheck.path": "/healthz", "devproxy.healthcheck.interval": "-1s"}},
+			{"interval without path", map[string]string{"devproxy.healthcheck.interval": "5s"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.labels["devproxy.enable"] = "true"
+				tt.labels["devproxy.host"] = "app.localhost"
+				if _, err := parser.ParseLabels(tt.labels); err == nil {
+					t.Error("expected error")
+				}
+			})
+		}
+	})
+
+	t.Run("parses timeout", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":                   "true",
+			"devproxy.services.reports.host":    "reports.localhost",
+			"devproxy.services.reports.timeout": "2m",
+			"devproxy.services.web.host":        "app.localhost",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, config := range configs {
+			want := time.Duration(0)
+			if config.Name == "reports" {
+				want = 2 * time.Minute
+			}
+			if config.Timeout != want {
+				t.Errorf("service %s: expected Timeout %v, got %v", config.Name, want, config.Timeout)
+			}
+		}
+	})
+
+	t.Run("rejects invalid timeout", func(t *testing.T) {
+		for _, value := range []string{"soon", "0s", "-5s", "30"} {
+			labels := map[string]string{
+				"devproxy.enable":  "true",
+				"devproxy.host":    "app.localhost",
+				"devproxy.timeout": value,
+			}
+			if _, err := parser.ParseLabels(labels); err == nil {
+				t.Errorf("expected error for timeout=%q", value)
+			}
+		}
+	})
+
+	t.Run("parses sticky cookie", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":                         "true",
+			"devproxy.services.web.host":              "app.localhost",
+			"devproxy.services.web.sticky.cookie":     "app_backend",
+			"devproxy.services.metrics.host":          "metrics.localhost",
+			"devproxy.services.metrics.sticky.cookie": "",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, config := range configs {
+			want := ""
+			if config.Name == "web" {
+				want = "app_backend"
+			}
+			if config.StickyCookie != want {
+				t.Errorf("service %s: expected StickyCookie %q, got %q", config.Name, want, config.StickyCookie)
+			}
+		}
+	})
+
+	t.Run("rejects invalid sticky cookie name", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":        "true",
+			"devproxy.host":          "app.localhost",
+			"devproxy.sticky.cookie": "my cookie;",
+		}
+		if _, err := parser.ParseLabels(labels); err == nil {
+			t.Error("expected error for invalid cookie name")
+		}
+	})
+
+	t.Run("parses path prefix", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":            "true",
+			"devproxy.services.api.host": "app.localhost",
+			"devproxy.services.api.path": "/api/",
+			"devproxy.services.web.host": "app.localhost",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, config := range configs {
+			want := ""
+			if config.Name == "api" {
+				want = "/api"
+			}
+			if config.PathPrefix != want {
+				t.Errorf("service %s: expected PathPrefix %q, got %q", config.Name, want, config.PathPrefix)
+			}
+		}
+	})
+
+	t.Run("rejects invalid path prefix", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			labels map[string]string
+		}{
+			{"relative path", map[string]string{"devproxy.host": "app.localhost", "devproxy.path": "api"}},
+			{"wildcard host", map[string]string{"devproxy.host": "app.localhost,*.app.localhost", "devproxy.path": "/api"}},
+			{"TCP entrypoint", map[string]string{"devproxy.host": "db.localhost", "devproxy.entrypoint": "postgres", "devproxy.path": "/api"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.labels["devproxy.enable"] = "true"
+				if _, err := parser.ParseLabels(tt.labels); err == nil {
+					t.Error("expected error")
+				}
+			})
+		}
+	})
+
+	t.Run("parses strip prefix", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":       "true",
+			"devproxy.host":         "app.localhost",
+			"devproxy.path":         "/api",
+			"devproxy.strip_prefix": "/api/",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configs[0].StripPrefix != "/api" {
+			t.Errorf("expected StripPrefix /api, got %q", configs[0].StripPrefix)
+		}
+	})
+
+	t.Run("parses basic auth", func(t *testing.T) {
+		const hash = "$2a$04$gP52.y1YGIFV6mNMXvJ8t.SXtQk6yxzHFSmnStfoocOLK9HO/mQSi"
+		labels := map[string]string{
+			"devproxy.enable":                  "true",
+			"devproxy.services.api.host":       "api.localhost",
+			"devproxy.services.api.auth.basic": "alice:" + hash + ",bob:" + hash,
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := configs[0].BasicAuth; len(got) != 2 || got["alice"] != hash || got["bob"] != hash {
+			t.Errorf("unexpected BasicAuth %v", got)
+		}
+
+		if _, err := parser.ParseLabels(map[string]string{
+			"devproxy.enable":     "true",
+			"devproxy.host":       "app.localhost",
+			"devproxy.auth.basic": "alice:secret",
+		}); err == nil {
+			t.Error("expected error for a plain text password")
+		}
+	})
+
+	t.Run("parses headers", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":          "true",
+			"devproxy.host":            "app.localhost",
+			"devproxy.headers":         "X-Frame-Options=DENY;Access-Control-Allow-Origin=*",
+			"devproxy.request_headers": "X-Tenant=acme",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := configs[0].ResponseHeaders; len(got) != 2 || got["X-Frame-Options"] != "DENY" || got["Access-Control-Allow-Origin"] != "*" {
+			t.Errorf("unexpected ResponseHeaders %v", got)
+		}
+		if got := configs[0].RequestHeaders; len(got) != 1 || got["X-Tenant"] != "acme" {
+			t.Errorf("unexpected RequestHeaders %v", got)
+		}
+	})
+
+	t.Run("rejects invalid headers", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			labels map[string]string
+		}{
+			{"response header without value", map[string]string{"devproxy.host": "app.localhost", "devproxy.headers": "X-Frame-Options"}},
+			{"request header with invalid name", map[string]string{"devproxy.host": "app.localhost", "devproxy.request_headers": "X Tenant=acme"}},
+			{"multi-service", map[string]string{"devproxy.services.api.host": "app.localhost", "devproxy.services.api.headers": "=DENY"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.labels["devproxy.enable"] = "true"
+				if _, err := parser.ParseLabels(tt.labels); err == nil {
+					t.Error("expected error")
+				}
+			})
+		}
+	})
+
+	t.Run("parses websocket mode", func(t *testing.T) {
+		tests := []struct {
+			value string
+			want  proxy.WebSocketMode
+		}{
+			{"", ""},
+			{"auto", ""},
+			{"force", proxy.WebSocketForce},
+			{"OFF", proxy.WebSocketOff},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.value, func(t *testing.T) {
+				configs, err := parser.ParseLabels(map[string]string{
+					"devproxy.enable":    "true",
+					"devproxy.host":      "app.localhost",
+					"devproxy.websocket": tt.value,
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if configs[0].WebSocket != tt.want {
+					t.Errorf("expected WebSocket %q, got %q", tt.want, configs[0].WebSocket)
+				}
+			})
+		}
+
+		if _, err := parser.ParseLabels(map[string]string{
+			"devproxy.enable":                 "true",
+			"devproxy.services.api.host":      "app.localhost",
+			"devproxy.services.api.websocket": "always",
+		}); err == nil {
+			t.Error("expected error for invalid websocket mode")
+		}
+	})
+
+	t.Run("parses http mode", func(t *testing.T) {
+		tests := []struct {
+			value string
+			want  proxy.HTTPMode
+		}{
+			{"", ""},
+			{"redirect", ""},
+			{"Proxy", proxy.HTTPProxy},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.value, func(t *testing.T) {
+				configs, err := parser.ParseLabels(map[string]string{
+					"devproxy.enable": "true",
+					"devproxy.host":   "hooks.localhost",
+					"devproxy.http":   tt.value,
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if configs[0].HTTP != tt.want {
+					t.Errorf("expected HTTP %q, got %q", tt.want, configs[0].HTTP)
+				}
+			})
+		}
+
+		invalid := []map[string]string{
+			{"devproxy.host": "hooks.localhost", "devproxy.http": "plain"},
+			{"devproxy.host": "db.localhost", "devproxy.entrypoint": "postgres", "devproxy.http": "proxy"},
+			{"devproxy.services.hooks.host": "hooks.localhost", "devproxy.services.hooks.http": "always"},
+		}
+		for _, labels := range invalid {
+			labels["devproxy.enable"] = "true"
+			if _, err := parser.ParseLabels(labels); err == nil {
+				t.Errorf("expected error for labels %v", labels)
+			}
+		}
+	})
+
+	t.Run("rejects invalid strip prefix", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			labels map[string]string
+		}{
+			{"relative path", map[string]string{"devproxy.host": "app.localhost", "devproxy.strip_prefix": "api"}},
+			{"TCP entrypoint", map[string]string{"devproxy.host": "db.localhost", "devproxy.entrypoint": "postgres", "devproxy.strip_prefix": "/api"}},
+			{"multi-service", map[string]string{"devproxy.services.api.host": "app.localhost", "devproxy.services.api.strip_prefix": "/api?x"}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.labels["devproxy.enable"] = "true"
+				if _, err := parser.ParseLabels(tt.labels); err == nil {
+					t.Error("expected error")
+				}
+			})
+		}
+	})
+
 	t.Run("parses single-service with custom port", func(t *testing.T) {
 		labels := map[string]string{
 			"devproxy.enable": "true",
@@ -78,6 +515,112 @@ func TestLabelParser_ParseLabels(t *testing.T) {
 		}
 	})
 
+	t.Run("pairs entrypoints with ports", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":     "true",
+			"devproxy.host":       "db.localhost",
+			"devproxy.entrypoint": "postgres:5432, mysql:3306",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(configs) != 2 {
+			t.Fatalf("expected 2 configs, got %d", len(configs))
+		}
+		for i, want := range []struct {
+			entrypoint string
+			port       int
+		}{{"postgres", 5432}, {"mysql", 3306}} {
+			c := configs[i]
+			if c.Host != "db.localhost" || c.Entrypoint != want.entrypoint || c.Port != want.port || !c.ExplicitPort {
+				t.Errorf("config %d = %+v, want %s on port %d", i, c, want.entrypoint, want.port)
+			}
+		}
+	})
+
+	t.Run("entrypoint without port keeps implicit port", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":     "true",
+			"devproxy.host":       "db.localhost",
+			"devproxy.port":       "5433",
+			"devproxy.entrypoint": "postgres",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configs[0].Port != 5433 || configs[0].ExplicitPort || !configs[0].PortLabeled {
+			t.Errorf("expected labeled implicit port 5433, got %+v", configs[0])
+		}
+	})
+
+	t.Run("default port is not labeled", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":     "true",
+			"devproxy.host":       "db.localhost",
+			"devproxy.entrypoint": "postgres",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configs[0].PortLabeled {
+			t.Errorf("expected default port to be unlabeled, got %+v", configs[0])
+		}
+	})
+
+	t.Run("pairs entrypoints in multi-service labels", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":                 "true",
+			"devproxy.services.db.host":       "db.localhost",
+			"devproxy.services.db.entrypoint": "postgres:5432,mysql:3306",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(configs) != 2 {
+			t.Fatalf("expected 2 configs, got %d", len(configs))
+		}
+		if configs[0].Name != "db" || configs[1].Name != "db" {
+			t.Errorf("expected both configs to belong to service db, got %+v", configs)
+		}
+	})
+
+	t.Run("lowercases entrypoint names", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":     "true",
+			"devproxy.host":       "db.localhost",
+			"devproxy.entrypoint": "Postgres:5432",
+		}
+
+		configs, err := parser.ParseLabels(labels)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if configs[0].Entrypoint != "postgres" {
+			t.Errorf("expected entrypoint postgres, got %q", configs[0].Entrypoint)
+		}
+	})
+
+	t.Run("rejects invalid entrypoint pairs", func(t *testing.T) {
+		for _, value := range []string{"postgres:abc", "postgres:0", ":5432", "postgres,postgres:5432", "postgres,Postgres"} {
+			labels := map[string]string{
+				"devproxy.enable":     "true",
+				"devproxy.host":       "db.localhost",
+				"devproxy.entrypoint": value,
+			}
+			if _, err := parser.ParseLabels(labels); err == nil {
+				t.Errorf("expected error for entrypoint %q", value)
+			}
+		}
+	})
+
 	t.Run("returns error for missing host", func(t *testing.T) {
 		labels := map[string]string{
 			"devproxy.enable": "true",
@@ -153,6 +696,100 @@ func TestLabelParser_ParseLabels(t *testing.T) {
 			t.Error("expected error for missing host in multi-service")
 		}
 	})
+
+	t.Run("returns error for empty service name", func(t *testing.T) {
+		labels := map[string]string{
+			"devproxy.enable":            "true",
+			"devproxy.services..host":    "app.localhost",
+			"devproxy.services.web.host": "web.localhost",
+		}
+
+		_, err := parser.ParseLabels(labels)
+		if err == nil || !strings.Contains(err.Error(), "empty service name") {
+			t.Errorf("expected empty service name error, got %v", err)
+		}
+	})
+}
+
+// decodeFuzzLabels turns fuzz input into labels: one key=value pair per line.
+func decodeFuzzLabels(data string) map[string]string {
+	labels := make(map[string]string)
+	for _, line := range strings.Split(data, "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		labels[key] = value
+	}
+	return labels
+}
+
+// FuzzLabelParser_ParseLabels feeds arbitrary labels to the parser and checks
+// that it never panics and that every config it accepts is routable.
+func FuzzLabelParser_ParseLabels(f *testing.F) {
+	for _, seed := range []string{
+		"devproxy.enable=true\ndevproxy.host=app.localhost",
+		"devproxy.enable=true",
+		"devproxy.enable=false\ndevproxy.host=app.localhost",
+		"devproxy.enable=true\ndevproxy.host=*.app.localhost\ndevproxy.port=3000",
+		"devproxy.enable=true\ndevproxy.host=app.localhost,*.\ndevproxy.port=70000",
+		"devproxy.enable=true\ndevproxy.host=db.localhost\ndevproxy.entrypoint=postgres:5432,redis",
+		"devproxy.enable=true\ndevproxy.services.web.host=web.localhost\ndevproxy.services.api.host=api.localhost\ndevproxy.services.api.port=8080",
+		"devproxy.enable=true\ndevproxy.services..host=app.localhost",
+		"devproxy.enable=true\ndevproxy.services.web\ndevproxy.services.web.=x",
+		"devproxy.enable=true\ndevproxy.services.web.port=3000",
+		"devproxy.enable=true\ndevproxy.host=app.localhost\ndevproxy.path=/api\ndevproxy.strip_prefix=/api",
+		"devproxy.enable=true\ndevproxy.host=app.localhost\ndevproxy.healthcheck.interval=5s",
+	} {
+		f.Add(seed)
+	}
+
+	parser := NewLabelParser()
+	f.Fuzz(func(t *testing.T, data string) {
+		labels := decodeFuzzLabels(data)
+		configs, err := parser.ParseLabels(labels)
+
+		if labels["devproxy.enable"] != "true" {
+			if configs != nil || err != nil {
+				t.Fatalf("expected nothing for disabled labels, got %v, %v", configs, err)
+			}
+			return
+		}
+
+		multiService := false
+		for key := range labels {
+			if strings.HasPrefix(key, "devproxy.services.") {
+				multiService = true
+			}
+		}
+		if !multiService && labels["devproxy.host"] == "" && err == nil {
+			t.Fatal("expected an error for enabled labels without a host")
+		}
+		if err != nil {
+			return
+		}
+
+		if len(configs) == 0 {
+			t.Fatal("expected at least one config without an error")
+		}
+		for _, c := range configs {
+			if c.Port < 1 || c.Port > 65535 {
+				t.Errorf("port %d out of range in %+v", c.Port, c)
+			}
+			for _, host := range strings.Split(c.Host, ",") {
+				if err := validateHost(strings.TrimSpace(host)); err != nil {
+					t.Errorf("accepted invalid host %q: %v", host, err)
+				}
+			}
+			if multiService {
+				if c.Name == "" {
+					t.Errorf("expected a service name in %+v", c)
+				}
+				if want := labels["devproxy.services."+c.Name+".host"]; c.Host != want {
+					t.Errorf("service %q got host %q, want %q from its labels", c.Name, c.Host, want)
+				}
+			} else if c.Name != "" || c.Host != labels["devproxy.host"] {
+				t.Errorf("single service got name %q host %q, want host %q", c.Name, c.Host, labels["devproxy.host"])
+			}
+		}
+	})
 }
 
 func TestLabelParser_IsEnabled(t *testing.T) {
@@ -187,6 +824,69 @@ func TestLabelParser_IsEnabled(t *testing.T) {
 	})
 }
 
+func TestLabelParser_SetDefaultPort(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultPort int
+		labels      map[string]string
+		wantPorts   []int
+	}{
+		{
+			name:        "unset keeps 80",
+			defaultPort: 0,
+			labels:      map[string]string{"devproxy.enable": "true", "devproxy.host": "app.localhost"},
+			wantPorts:   []int{DefaultPort},
+		},
+		{
+			name:        "applies without port label",
+			defaultPort: 3000,
+			labels:      map[string]string{"devproxy.enable": "true", "devproxy.host": "app.localhost"},
+			wantPorts:   []int{3000},
+		},
+		{
+			name:        "port label wins",
+			defaultPort: 3000,
+			labels:      map[string]string{"devproxy.enable": "true", "devproxy.host": "app.localhost", "devproxy.port": "8080"},
+			wantPorts:   []int{8080},
+		},
+		{
+			name:        "multi-service",
+			defaultPort: 3000,
+			labels: map[string]string{
+				"devproxy.enable":            "true",
+				"devproxy.services.api.host": "api.localhost",
+				"devproxy.services.web.host": "web.localhost",
+				"devproxy.services.web.port": "5173",
+			},
+			wantPorts: []int{3000, 5173},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser := NewLabelParser()
+			parser.SetDefaultPort(tt.defaultPort)
+
+			configs, err := parser.ParseLabels(tt.labels)
+			if err != nil {
+				t.Fatalf("ParseLabels() error = %v", err)
+			}
+			if len(configs) != len(tt.wantPorts) {
+				t.Fatalf("expected %d services, got %d", len(tt.wantPorts), len(configs))
+			}
+			slices.SortFunc(configs, func(a, b ServiceConfig) int { return strings.Compare(a.Host, b.Host) })
+			for i, config := range configs {
+				if config.Port != tt.wantPorts[i] {
+					t.Errorf("service %s: expected port %d, got %d", config.Host, tt.wantPorts[i], config.Port)
+				}
+				if config.PortLabeled != (config.Port != tt.defaultPort && config.Port != DefaultPort) {
+					t.Errorf("service %s: unexpected PortLabeled = %v", config.Host, config.PortLabeled)
+				}
+			}
+		})
+	}
+}
+
 // Wildcard host validation tests
 
 func TestLabelParser_WildcardValidHosts(t *testing.T) {
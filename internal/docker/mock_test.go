@@ -3,11 +3,14 @@ package docker
 import (
 	"context"
 	"errors"
+	"io"
+	"strings"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/events"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/api/types/swarm"
 )
 
 // mockDockerAPI is a test double for DockerAPI that allows configuring
@@ -16,6 +19,9 @@ type mockDockerAPI struct {
 	pingFunc             func(ctx context.Context) (types.Ping, error)
 	containerListFunc    func(ctx context.Context, options container.ListOptions) ([]container.Summary, error)
 	containerInspectFunc func(ctx context.Context, containerID string) (container.InspectResponse, error)
+	containerLogsFunc    func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)
+	networkInspectFunc   func(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error)
+	serviceInspectFunc   func(ctx context.Context, serviceID string) (swarm.Service, error)
 	eventsFunc           func(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error)
 	closeFunc            func() error
 }
@@ -41,6 +47,28 @@ func (m *mockDockerAPI) ContainerInspect(ctx context.Context, containerID string
 	return container.InspectResponse{}, nil
 }
 
+func (m *mockDockerAPI) ContainerLogs(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error) {
+	if m.containerLogsFunc != nil {
+		return m.containerLogsFunc(ctx, containerID, options)
+	}
+	return io.NopCloser(strings.NewReader("")), nil
+}
+
+func (m *mockDockerAPI) NetworkInspect(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error) {
+	if m.networkInspectFunc != nil {
+		return m.networkInspectFunc(ctx, networkID, options)
+	}
+	return network.Inspect{Name: networkID}, nil
+}
+
+func (m *mockDockerAPI) ServiceInspectWithRaw(ctx context.Context, serviceID string, options swarm.ServiceInspectOptions) (swarm.Service, []byte, error) {
+	if m.serviceInspectFunc != nil {
+		service, err := m.serviceInspectFunc(ctx, serviceID)
+		return service, nil, err
+	}
+	return swarm.Service{ID: serviceID}, nil, nil
+}
+
 func (m *mockDockerAPI) Events(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error) {
 	if m.eventsFunc != nil {
 		return m.eventsFunc(ctx, options)
@@ -126,6 +154,23 @@ func (b *mockDockerAPIBuilder) withContainerInspectError(err error) *mockDockerA
 	return b
 }
 
+func (b *mockDockerAPIBuilder) withContainerLogs(fn func(ctx context.Context, containerID string, options container.LogsOptions) (io.ReadCloser, error)) *mockDockerAPIBuilder {
+	b.mock.containerLogsFunc = fn
+	return b
+}
+
+func (b *mockDockerAPIBuilder) withNetworkInspectError(err error) *mockDockerAPIBuilder {
+	b.mock.networkInspectFunc = func(ctx context.Context, networkID string, options network.InspectOptions) (network.Inspect, error) {
+		return network.Inspect{}, err
+	}
+	return b
+}
+
+func (b *mockDockerAPIBuilder) withServiceInspect(fn func(ctx context.Context, serviceID string) (swarm.Service, error)) *mockDockerAPIBuilder {
+	b.mock.serviceInspectFunc = fn
+	return b
+}
+
 func (b *mockDockerAPIBuilder) withEvents(fn func(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error)) *mockDockerAPIBuilder {
 	b.mock.eventsFunc = fn
 	return b
@@ -3,15 +3,24 @@ package docker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/swarm"
 )
 
+// ErrNetworkNotAllowed is returned by ResolveInfo for containers attached to
+// none of the allowed networks.
+var ErrNetworkNotAllowed = errors.New("container is not attached to an allowed network")
+
 // ContainerResolver resolves container information from Docker.
 type ContainerResolver struct {
-	client  *Client
-	network string // preferred network name
+	client   *Client
+	network  string          // preferred network name
+	fallback bool            // use any network when the preferred one is not attached
+	allowed  map[string]bool // networks containers must be attached to (nil = any)
 }
 
 // NewContainerResolver creates a new container resolver.
@@ -23,7 +32,7 @@ func NewContainerResolver(client *Client, network string) *ContainerResolver {
 }
 
 // ResolveIP gets the IP address of a container.
-// It tries the preferred network first, then falls back to any available network.
+// When a preferred network is set, only that network is used unless fallback is enabled.
 func (r *ContainerResolver) ResolveIP(ctx context.Context, containerID string) (string, error) {
 	if r.client.API() == nil {
 		return "", fmt.Errorf("docker client not connected")
@@ -43,16 +52,28 @@ func (r *ContainerResolver) extractIP(settings *container.NetworkSettings) (stri
 		return "", fmt.Errorf("no network settings")
 	}
 
-	// Try the specified network first
+	// Use the specified network strictly unless fallback is allowed
 	if r.network != "" {
 		if network, ok := settings.Networks[r.network]; ok && network.IPAddress != "" {
 			return network.IPAddress, nil
 		}
+		if !r.fallback {
+			return "", fmt.Errorf("container has no IP address on network %q", r.network)
+		}
+	}
+
+	// Fall back to first available network, in name order for stable results
+	names := make([]string, 0, len(settings.Networks))
+	for name := range settings.Networks {
+		names = append(names, name)
 	}
+	sort.Strings(names)
 
-	// Fall back to first available network
-	for _, network := range settings.Networks {
-		if network.IPAddress != "" {
+	for _, name := range names {
+		if r.allowed != nil && !r.allowed[name] {
+			continue
+		}
+		if network := settings.Networks[name]; network != nil && network.IPAddress != "" {
 			return network.IPAddress, nil
 		}
 	}
@@ -94,6 +115,10 @@ func (r *ContainerResolver) ResolveInfo(ctx context.Context, containerID string)
 		return "", "", fmt.Errorf("failed to inspect container: %w", err)
 	}
 
+	if !r.isAllowed(info.NetworkSettings) {
+		return "", "", ErrNetworkNotAllowed
+	}
+
 	ip, err = r.extractIP(info.NetworkSettings)
 	if err != nil {
 		return "", "", err
@@ -103,7 +128,57 @@ func (r *ContainerResolver) ResolveInfo(ctx context.Context, containerID string)
 	return ip, name, nil
 }
 
+// ServiceLabels gets the labels of a Swarm service.
+func (r *ContainerResolver) ServiceLabels(ctx context.Context, serviceID string) (map[string]string, error) {
+	if r.client.API() == nil {
+		return nil, fmt.Errorf("docker client not connected")
+	}
+
+	service, _, err := r.client.API().ServiceInspectWithRaw(ctx, serviceID, swarm.ServiceInspectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect service: %w", err)
+	}
+	return service.Spec.Labels, nil
+}
+
 // SetNetwork changes the preferred network for IP resolution.
 func (r *ContainerResolver) SetNetwork(network string) {
 	r.network = network
 }
+
+// SetNetworkFallback controls whether containers not attached to the preferred
+// network resolve to their first available network instead of failing.
+func (r *ContainerResolver) SetNetworkFallback(fallback bool) {
+	r.fallback = fallback
+}
+
+// SetAllowedNetworks limits resolution to containers attached to at least one
+// of the given networks; IPs are only taken from those networks. An empty
+// list allows any network.
+func (r *ContainerResolver) SetAllowedNetworks(names []string) {
+	if len(names) == 0 {
+		r.allowed = nil
+		return
+	}
+	r.allowed = make(map[string]bool, len(names))
+	for _, name := range names {
+		r.allowed[name] = true
+	}
+}
+
+// isAllowed reports whether a container with the given network settings is
+// attached to an allowed network.
+func (r *ContainerResolver) isAllowed(settings *container.NetworkSettings) bool {
+	if r.allowed == nil {
+		return true
+	}
+	if settings == nil {
+		return false
+	}
+	for name := range settings.Networks {
+		if r.allowed[name] {
+			return true
+		}
+	}
+	return false
+}
@@ -11,6 +11,8 @@ func TestContainerResolver_extractIP(t *testing.T) {
 	tests := []struct {
 		name      string
 		network   string
+		fallback  bool
+		allowed   []string
 		settings  *container.NetworkSettings
 		wantIP    string
 		wantError bool
@@ -27,8 +29,9 @@ func TestContainerResolver_extractIP(t *testing.T) {
 			wantIP: "10.0.0.5",
 		},
 		{
-			name:    "preferred network missing, use first available",
-			network: "nonexistent",
+			name:     "preferred network missing, fallback uses first available",
+			network:  "nonexistent",
+			fallback: true,
 			settings: &container.NetworkSettings{
 				Networks: map[string]*network.EndpointSettings{
 					"bridge": {IPAddress: "172.17.0.2"},
@@ -36,6 +39,51 @@ func TestContainerResolver_extractIP(t *testing.T) {
 			},
 			wantIP: "172.17.0.2",
 		},
+		{
+			name:    "preferred network missing, strict mode fails",
+			network: "nonexistent",
+			settings: &container.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"bridge": {IPAddress: "172.17.0.2"},
+				},
+			},
+			wantError: true,
+		},
+		{
+			name:    "preferred network without IP, strict mode fails",
+			network: "mynetwork",
+			settings: &container.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"bridge":    {IPAddress: "172.17.0.2"},
+					"mynetwork": {IPAddress: ""},
+				},
+			},
+			wantError: true,
+		},
+		{
+			name:     "fallback picks networks in name order",
+			network:  "nonexistent",
+			fallback: true,
+			settings: &container.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"zeta":  {IPAddress: "10.0.0.26"},
+					"alpha": {IPAddress: "10.0.0.1"},
+					"mid":   {IPAddress: "10.0.0.13"},
+				},
+			},
+			wantIP: "10.0.0.1",
+		},
+		{
+			name:    "allowed networks restrict the first available",
+			allowed: []string{"project"},
+			settings: &container.NetworkSettings{
+				Networks: map[string]*network.EndpointSettings{
+					"bridge":  {IPAddress: "172.17.0.2"},
+					"project": {IPAddress: "10.0.0.5"},
+				},
+			},
+			wantIP: "10.0.0.5",
+		},
 		{
 			name:    "no preferred network, use first available",
 			network: "",
@@ -74,7 +122,8 @@ func TestContainerResolver_extractIP(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			resolver := &ContainerResolver{network: tt.network}
+			resolver := &ContainerResolver{network: tt.network, fallback: tt.fallback}
+			resolver.SetAllowedNetworks(tt.allowed)
 			ip, err := resolver.extractIP(tt.settings)
 
 			if tt.wantError {
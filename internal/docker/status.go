@@ -0,0 +1,81 @@
+package docker
+
+import (
+	"time"
+)
+
+// maxRecentErrors is how many sync errors Status reports.
+const maxRecentErrors = 10
+
+// SyncStatus describes the state of the Docker integration.
+type SyncStatus struct {
+	// Containers is the number of containers with registered routes.
+	Containers int `json:"containers"`
+
+	// Hosts is the number of hosts routed to those containers.
+	Hosts int `json:"hosts"`
+
+	// LastEvent is when the last container event was handled (zero = none yet).
+	LastEvent time.Time `json:"last_event,omitzero"`
+
+	// LastSync is when SyncExisting last completed (zero = never).
+	LastSync time.Time `json:"last_sync,omitzero"`
+
+	// RecentErrors lists the latest failures, oldest first.
+	RecentErrors []SyncError `json:"recent_errors,omitempty"`
+}
+
+// SyncError is a failure to route a container.
+type SyncError struct {
+	Time      time.Time `json:"time"`
+	Container string    `json:"container,omitempty"`
+	Error     string    `json:"error"`
+}
+
+// Status returns a snapshot of the tracked containers, event times and
+// recent errors.
+func (s *RouteSync) Status() SyncStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	status := SyncStatus{
+		Containers:   len(s.containers),
+		LastEvent:    s.lastEvent,
+		LastSync:     s.lastSync,
+		RecentErrors: append([]SyncError(nil), s.recentErrors...),
+	}
+	for _, hosts := range s.containers {
+		status.Hosts += len(hosts)
+	}
+	return status
+}
+
+// recordError keeps err for Status, dropping the oldest error past
+// maxRecentErrors.
+func (s *RouteSync) recordError(container string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recentErrors = append(s.recentErrors, SyncError{
+		Time:      time.Now(),
+		Container: container,
+		Error:     err.Error(),
+	})
+	if len(s.recentErrors) > maxRecentErrors {
+		s.recentErrors = s.recentErrors[len(s.recentErrors)-maxRecentErrors:]
+	}
+}
+
+// recordEvent notes that a container event was handled.
+func (s *RouteSync) recordEvent() {
+	s.mu.Lock()
+	s.lastEvent = time.Now()
+	s.mu.Unlock()
+}
+
+// recordSync notes that SyncExisting completed.
+func (s *RouteSync) recordSync() {
+	s.mu.Lock()
+	s.lastSync = time.Now()
+	s.mu.Unlock()
+}
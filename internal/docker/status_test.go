@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"github.com/munichmade/devproxy/internal/proxy"
+)
+
+func TestRouteSync_Status(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	mockAPI := newMockBuilder().
+		withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			if containerID == "brokencontainer1" {
+				return container.InspectResponse{}, errors.New("no such container")
+			}
+			return makeContainerInspectResponse(containerID, containerID, "172.17.0.5", "bridge"), nil
+		}).
+		build()
+	sync := NewRouteSync(proxy.NewRegistry(), NewClientWithAPI(mockAPI, logger), "bridge", logger)
+
+	if status := sync.Status(); status.Containers != 0 || !status.LastEvent.IsZero() || len(status.RecentErrors) != 0 {
+		t.Fatalf("expected empty status, got %+v", status)
+	}
+
+	start := func(id, hosts string) {
+		sync.HandleEvent(ContainerEvent{
+			ContainerID:   id,
+			ContainerName: id,
+			Labels:        map[string]string{"devproxy.enable": "true", "devproxy.host": hosts},
+			Type:          "start",
+		})
+	}
+
+	before := time.Now()
+	start("webcontainer1", "web.localhost,www.localhost")
+	start("apicontainer1", "api.localhost")
+
+	status := sync.Status()
+	if status.Containers != 2 || status.Hosts != 3 {
+		t.Errorf("expected 2 containers with 3 hosts, got %d with %d", status.Containers, status.Hosts)
+	}
+	if status.LastEvent.Before(before) {
+		t.Errorf("expected last event after %v, got %v", before, status.LastEvent)
+	}
+
+	sync.HandleEvent(ContainerEvent{ContainerID: "webcontainer1", Type: "stop"})
+	if status := sync.Status(); status.Containers != 1 || status.Hosts != 1 {
+		t.Errorf("expected 1 container with 1 host after stop, got %d with %d", status.Containers, status.Hosts)
+	}
+
+	t.Run("reports recent errors", func(t *testing.T) {
+		start("brokencontainer1", "broken.localhost")
+
+		errs := sync.Status().RecentErrors
+		if len(errs) != 1 || errs[0].Container != "brokencontainer1"[:12] || !strings.Contains(errs[0].Error, "no such container") {
+			t.Fatalf("expected IP resolution error, got %+v", errs)
+		}
+
+		for i := 0; i < maxRecentErrors+5; i++ {
+			start("brokencontainer1", "broken.localhost")
+		}
+		if n := len(sync.Status().RecentErrors); n != maxRecentErrors {
+			t.Errorf("expected %d recent errors, got %d", maxRecentErrors, n)
+		}
+	})
+}
+
+func TestRouteSync_Status_LastSync(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	t.Run("records completed sync", func(t *testing.T) {
+		mockAPI := newMockBuilder().withContainerListResult(nil).build()
+		sync := NewRouteSync(proxy.NewRegistry(), NewClientWithAPI(mockAPI, logger), "bridge", logger)
+
+		if err := sync.SyncExisting(context.Background()); err != nil {
+			t.Fatalf("SyncExisting() error = %v", err)
+		}
+		if sync.Status().LastSync.IsZero() {
+			t.Error("expected last sync time")
+		}
+	})
+
+	t.Run("records failed sync as error", func(t *testing.T) {
+		mockAPI := newMockBuilder().withContainerListError(errors.New("daemon unavailable")).build()
+		sync := NewRouteSync(proxy.NewRegistry(), NewClientWithAPI(mockAPI, logger), "bridge", logger)
+
+		if err := sync.SyncExisting(context.Background()); err == nil {
+			t.Fatal("expected error")
+		}
+		status := sync.Status()
+		if !status.LastSync.IsZero() || len(status.RecentErrors) != 1 {
+			t.Errorf("expected no sync time and one error, got %+v", status)
+		}
+	})
+}
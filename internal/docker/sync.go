@@ -2,11 +2,18 @@
 package docker
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/docker/docker/api/types/container"
 
@@ -23,30 +30,73 @@ type CertManager interface {
 	EnsureCertificate(domain string) error
 }
 
+// ReadinessCheck reports whether a backend is ready to receive traffic.
+type ReadinessCheck func(ctx context.Context, backend string) error
+
+// DialReadinessCheck returns a ReadinessCheck that retries TCP connections to the
+// backend every interval until one succeeds or the timeout elapses.
+func DialReadinessCheck(timeout, interval time.Duration) ReadinessCheck {
+	return func(ctx context.Context, backend string) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		var dialer net.Dialer
+		for {
+			conn, err := dialer.DialContext(ctx, "tcp", backend)
+			if err == nil {
+				conn.Close()
+				return nil
+			}
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("backend %s not reachable: %w", backend, err)
+			case <-time.After(interval):
+			}
+		}
+	}
+}
+
+// DefaultSyncConcurrency is the number of containers SyncExisting processes in parallel.
+const DefaultSyncConcurrency = 8
+
 // RouteSync synchronizes Docker container events with the route registry.
 type RouteSync struct {
 	registry    *proxy.Registry
+	tcpRoutes   *proxy.TCPRegistry
 	parser      *LabelParser
 	client      *Client
 	resolver    *ContainerResolver
 	certManager CertManager
+	readiness   ReadinessCheck
 	network     string
+	concurrency int
 	logger      *slog.Logger
 
-	mu         sync.RWMutex
-	containers map[string][]string // containerID -> list of hosts
+	mu           sync.RWMutex
+	entrypoints  map[string]bool          // known TCP entrypoints (nil = not validated)
+	containers   map[string][]string      // containerID -> list of hosts
+	healthChecks map[string][]healthCheck // containerID -> running health checks
+
+	// Reported by Status
+	lastEvent    time.Time
+	lastSync     time.Time
+	recentErrors []SyncError
 }
 
 // NewRouteSync creates a new route synchronizer.
 func NewRouteSync(registry *proxy.Registry, client *Client, network string, logger *slog.Logger) *RouteSync {
 	return &RouteSync{
-		registry:   registry,
-		parser:     NewLabelParser(),
-		client:     client,
-		resolver:   NewContainerResolver(client, network),
-		network:    network,
-		logger:     logger,
-		containers: make(map[string][]string),
+		registry:    registry,
+		parser:      NewLabelParser(),
+		client:      client,
+		resolver:    NewContainerResolver(client, network),
+		network:     network,
+		concurrency: DefaultSyncConcurrency,
+		logger:      logger,
+		containers:  make(map[string][]string),
+
+		healthChecks: make(map[string][]healthCheck),
 	}
 }
 
@@ -55,6 +105,92 @@ func (s *RouteSync) SetCertManager(cm CertManager) {
 	s.certManager = cm
 }
 
+// SetTCPRegistry sets the registry that TCP entrypoints use to look up
+// routes by host and entrypoint. TCP routes are added to it in addition to
+// the route registry, which remains the source for listing and toggling.
+func (s *RouteSync) SetTCPRegistry(registry *proxy.TCPRegistry) {
+	s.tcpRoutes = registry
+}
+
+// SetReadinessCheck sets an optional backend probe that must pass before
+// new routes are marked ready.
+func (s *RouteSync) SetReadinessCheck(check ReadinessCheck) {
+	s.readiness = check
+}
+
+// SetNetworkFallback allows containers that are not attached to the configured
+// network to be reached through their first available network.
+func (s *RouteSync) SetNetworkFallback(fallback bool) {
+	s.resolver.SetNetworkFallback(fallback)
+}
+
+// SetAllowedNetworks limits routing to containers attached to one of the
+// given networks. Other containers are ignored even if they carry devproxy
+// labels. An empty list allows all containers.
+func (s *RouteSync) SetAllowedNetworks(names []string) {
+	s.resolver.SetAllowedNetworks(names)
+}
+
+// SetDefaultPort sets the container port of services without a port label.
+// Values below 1 restore DefaultPort.
+func (s *RouteSync) SetDefaultPort(port int) {
+	s.parser.SetDefaultPort(port)
+}
+
+// SetSyncConcurrency sets how many containers SyncExisting processes in parallel.
+// Values below 1 restore the default.
+func (s *RouteSync) SetSyncConcurrency(n int) {
+	if n < 1 {
+		n = DefaultSyncConcurrency
+	}
+	s.concurrency = n
+}
+
+// SetEntrypoints sets the TCP entrypoints defined in the config.
+// Services whose entrypoint label names another entrypoint are skipped.
+// It may be called while events are handled, e.g. on config reload.
+func (s *RouteSync) SetEntrypoints(names []string) {
+	entrypoints := make(map[string]bool, len(names))
+	for _, name := range names {
+		entrypoints[strings.ToLower(name)] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entrypoints = entrypoints
+}
+
+// knownEntrypoint reports whether name is a configured TCP entrypoint.
+// All names are known until SetEntrypoints is called.
+func (s *RouteSync) knownEntrypoint(name string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.entrypoints == nil || s.entrypoints[name]
+}
+
+// ValidateNetwork checks that the configured network exists in Docker.
+// A missing network is logged as a warning and reported as false; it is not
+// treated as fatal since the network may be created after the daemon starts.
+func (s *RouteSync) ValidateNetwork(ctx context.Context) (bool, error) {
+	if s.network == "" {
+		return true, nil
+	}
+	if s.client == nil || s.client.API() == nil {
+		return false, fmt.Errorf("docker client not connected")
+	}
+
+	exists, err := s.client.NetworkExists(ctx, s.network)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect network %q: %w", s.network, err)
+	}
+	if !exists {
+		s.logger.Warn("configured Docker network does not exist",
+			"network", s.network,
+			"fallback", s.resolver.fallback)
+	}
+	return exists, nil
+}
+
 // HandleEvent processes a container event and updates routes accordingly.
 func (s *RouteSync) HandleEvent(event ContainerEvent) {
 	defer func() {
@@ -71,6 +207,7 @@ func (s *RouteSync) HandleEvent(event ContainerEvent) {
 	case "stop", "die":
 		s.handleStop(event)
 	}
+	s.recordEvent()
 
 	s.logger.Info("HandleEvent completed", "container", event.ContainerName)
 }
@@ -90,13 +227,16 @@ func (s *RouteSync) handleStart(event ContainerEvent) {
 		"id", containerIDShort,
 		"labels_count", len(event.Labels))
 
+	labels := s.taskLabels(ctx, event)
+
 	// Parse labels to get service configurations
-	configs, err := s.parser.ParseLabels(event.Labels)
+	configs, err := s.parser.ParseLabels(labels)
 	s.logger.Debug("parsed labels", "container", event.ContainerName, "configs", len(configs), "error", err)
 	if err != nil {
 		s.logger.Warn("failed to parse container labels",
 			"container", event.ContainerID[:12],
 			"error", err)
+		s.recordError(containerIDShort, fmt.Errorf("failed to parse labels: %w", err))
 		return
 	}
 
@@ -116,10 +256,16 @@ func (s *RouteSync) handleStart(event ContainerEvent) {
 	ip, resolvedName, err := s.resolver.ResolveInfo(ctx, event.ContainerID)
 
 	s.logger.Debug("resolved container IP", "container", event.ContainerName, "ip", ip, "error", err)
+	if errors.Is(err, ErrNetworkNotAllowed) {
+		s.logger.Debug("container is not on an allowed network, skipping",
+			"container", event.ContainerName)
+		return
+	}
 	if err != nil {
 		s.logger.Error("failed to resolve container IP",
 			"container", event.ContainerID[:12],
 			"error", err)
+		s.recordError(containerIDShort, fmt.Errorf("failed to resolve container IP: %w", err))
 		return
 	}
 
@@ -129,14 +275,23 @@ func (s *RouteSync) handleStart(event ContainerEvent) {
 		containerName = resolvedName
 	}
 
-	// Extract Docker Compose project info from labels
-	projectName := event.Labels["com.docker.compose.project"]
-	projectDir := event.Labels["com.docker.compose.project.working_dir"]
+	// Extract Docker Compose project info from labels; Swarm tasks are
+	// grouped by their stack instead
+	projectName := cmp.Or(labels["com.docker.compose.project"], labels[stackNamespaceLabel])
+	projectDir := labels["com.docker.compose.project.working_dir"]
 
 	// Register routes for each service
 	var hosts []string
+	healthChecked := make(map[string]bool) // backends with a running health check
 	s.logger.Debug("registering routes", "container", event.ContainerName, "count", len(configs))
 	for _, config := range configs {
+		if config.Entrypoint != "" && !s.knownEntrypoint(config.Entrypoint) {
+			s.logger.Warn("unknown entrypoint in labels, skipping service",
+				"container", containerName,
+				"entrypoint", config.Entrypoint)
+			continue
+		}
+
 		// Split comma-separated hosts into individual hosts
 		hostList := strings.Split(config.Host, ",")
 		for _, host := range hostList {
@@ -145,49 +300,102 @@ func (s *RouteSync) handleStart(event ContainerEvent) {
 				continue
 			}
 
-			backend := fmt.Sprintf("%s:%d", ip, config.Port)
+			backend := net.JoinHostPort(ip, strconv.Itoa(config.Port))
+			if err := proxy.ValidateBackend(backend, false); err != nil {
+				s.logger.Warn("invalid backend for container, skipping route",
+					"container", containerName,
+					"host", host,
+					"error", err)
+				continue
+			}
 			s.logger.Debug("creating route", "host", host, "backend", backend)
 
 			route := proxy.Route{
-				Host:          host,
-				Backend:       backend,
-				Protocol:      s.getProtocol(config),
-				Entrypoint:    config.Entrypoint,
-				ContainerID:   event.ContainerID,
-				ContainerName: containerName,
-				ProjectName:   projectName,
-				ProjectDir:    projectDir,
+				Host:            host,
+				Backend:         backend,
+				Protocol:        s.getProtocol(config),
+				Entrypoint:      config.Entrypoint,
+				ExplicitPort:    config.ExplicitPort,
+				PortLabeled:     config.PortLabeled,
+				Source:          proxy.SourceDocker,
+				ContainerID:     event.ContainerID,
+				ContainerName:   containerName,
+				ProjectName:     projectName,
+				ProjectDir:      projectDir,
+				AllowCIDRs:      config.Allow,
+				DenyCIDRs:       config.Deny,
+				Methods:         config.Methods,
+				BasicAuth:       config.BasicAuth,
+				FollowRedirects: config.FollowRedirects,
+				Timeout:         config.Timeout,
+				StickyCookie:    config.StickyCookie,
+				PathPrefix:      config.PathPrefix,
+				StripPrefix:     config.StripPrefix,
+				RequestHeaders:  config.RequestHeaders,
+				ResponseHeaders: config.ResponseHeaders,
+				WebSocket:       config.WebSocket,
+				HTTP:            config.HTTP,
 			}
 
-			if err := s.registry.Add(route); err != nil {
+			err := s.registry.Add(route)
+			if route.Protocol == proxy.ProtocolHTTP &&
+				(errors.Is(err, proxy.ErrRouteExists) || errors.Is(err, proxy.ErrWildcardRouteExists)) {
+				// Another container already serves the host, e.g. a scaled service
+				if err = s.registry.AddBackend(route); err == nil {
+					s.logger.Info("backend added to load-balanced route",
+						"host", host,
+						"backend", backend,
+						"container", containerName)
+				}
+			}
+			if errors.Is(err, proxy.ErrTooManyRoutes) {
+				s.logger.Error("route rejected, registry is full",
+					"host", host,
+					"container", containerName,
+					"error", err)
+				s.recordError(containerName, err)
+				continue
+			}
+			if err != nil {
 				s.logger.Warn("failed to add route",
 					"host", host,
 					"error", err)
+				s.recordError(containerName, fmt.Errorf("failed to add route for %s: %w", host, err))
 				continue
 			}
 
+			if route.Protocol == proxy.ProtocolTCP && s.tcpRoutes != nil {
+				s.tcpRoutes.Add(proxy.TCPRoute{
+					Host:         host,
+					Backend:      backend,
+					Entrypoint:   config.Entrypoint,
+					ContainerID:  event.ContainerID,
+					ExplicitPort: config.ExplicitPort,
+					PortLabeled:  config.PortLabeled,
+				})
+			}
+
 			s.logger.Info("route added successfully",
 				"host", host,
 				"backend", backend,
 				"container", containerName)
 
-			hosts = append(hosts, host)
+			if config.HealthPath != "" && route.Protocol == proxy.ProtocolHTTP && !healthChecked[backend] {
+				healthChecked[backend] = true
+				s.startHealthCheck(event.ContainerID, host, backend, config.HealthPath, config.HealthInterval)
+			}
+
+			// A host served on several entrypoints is tracked once;
+			// removing it removes all of its routes
+			if !slices.Contains(hosts, host) {
+				hosts = append(hosts, host)
+			}
 			s.logger.Info("route added",
 				"host", host,
 				"backend", backend,
 				"container", containerName)
 
-			// Pre-generate certificate for the domain
-			if s.certManager != nil {
-				if err := s.certManager.EnsureCertificate(host); err != nil {
-					s.logger.Warn("failed to pre-generate certificate",
-						"host", host,
-						"error", err)
-				} else {
-					s.logger.Debug("certificate ready",
-						"host", host)
-				}
-			}
+			s.prepareRoute(host, backend)
 		}
 	}
 
@@ -199,8 +407,80 @@ func (s *RouteSync) handleStart(event ContainerEvent) {
 	}
 }
 
+// prepareRoute pre-generates the certificate for host and marks the route
+// ready once it succeeds. If a readiness check is configured, the backend is
+// probed in the background so slow containers don't block event handling.
+func (s *RouteSync) prepareRoute(host, backend string) {
+	// Pre-generate certificate for the domain
+	if s.certManager != nil {
+		if err := s.certManager.EnsureCertificate(host); err != nil {
+			s.logger.Warn("failed to pre-generate certificate",
+				"host", host,
+				"error", err)
+			return
+		}
+		s.logger.Debug("certificate ready",
+			"host", host)
+	}
+
+	if s.readiness == nil {
+		s.markReady(host)
+		return
+	}
+
+	go func() {
+		if err := s.readiness(context.Background(), backend); err != nil {
+			s.logger.Warn("backend readiness check failed",
+				"host", host,
+				"backend", backend,
+				"error", err)
+			return
+		}
+		s.markReady(host)
+	}()
+}
+
+// markReady flags the route for host as ready.
+// The route may already be gone if the container stopped in the meantime.
+func (s *RouteSync) markReady(host string) {
+	if err := s.registry.SetReady(host, true); err != nil {
+		s.logger.Debug("route no longer registered, not marking ready",
+			"host", host)
+		return
+	}
+	s.logger.Debug("route ready", "host", host)
+}
+
+// taskLabels returns the labels of the container of event. For the task of a
+// Swarm service, the labels of the service are included, so devproxy labels
+// set under deploy.labels apply to each task; the container's own labels take
+// precedence. Tasks of one service share their hosts and so form a
+// load-balanced route as they start.
+func (s *RouteSync) taskLabels(ctx context.Context, event ContainerEvent) map[string]string {
+	serviceID := event.Labels[swarmServiceIDLabel]
+	if serviceID == "" {
+		return event.Labels
+	}
+
+	serviceLabels, err := s.resolver.ServiceLabels(ctx, serviceID)
+	if err != nil {
+		s.logger.Warn("failed to inspect Swarm service, using container labels only",
+			"container", event.ContainerName,
+			"service", event.Labels[swarmServiceNameLabel],
+			"error", err)
+		return event.Labels
+	}
+
+	labels := make(map[string]string, len(serviceLabels)+len(event.Labels))
+	maps.Copy(labels, serviceLabels)
+	maps.Copy(labels, event.Labels)
+	return labels
+}
+
 // handleStop processes a container stop event.
 func (s *RouteSync) handleStop(event ContainerEvent) {
+	s.stopHealthChecks(event.ContainerID)
+
 	s.mu.Lock()
 	hosts, exists := s.containers[event.ContainerID]
 	if exists {
@@ -208,6 +488,10 @@ func (s *RouteSync) handleStop(event ContainerEvent) {
 	}
 	s.mu.Unlock()
 
+	if s.tcpRoutes != nil {
+		s.tcpRoutes.RemoveByContainerID(event.ContainerID)
+	}
+
 	if !exists {
 		// Try registry's RemoveByContainerID as fallback
 		removed := s.registry.RemoveByContainerID(event.ContainerID)
@@ -219,9 +503,9 @@ func (s *RouteSync) handleStop(event ContainerEvent) {
 		return
 	}
 
-	// Remove each tracked host
+	// Remove the container's backend from each tracked host
 	for _, host := range hosts {
-		if err := s.registry.Remove(host); err != nil {
+		if err := s.registry.RemoveBackend(host, event.ContainerID); err != nil {
 			s.logger.Warn("failed to remove route",
 				"host", host,
 				"error", err)
@@ -256,6 +540,8 @@ func (s *RouteSync) ListContainers() map[string][]string {
 }
 
 // SyncExisting scans for existing containers and adds their routes.
+// Containers are processed by a bounded pool of workers so that inspect
+// latency overlaps; the order in which routes are added is not defined.
 func (s *RouteSync) SyncExisting(ctx context.Context) error {
 	if s.client.API() == nil {
 		return fmt.Errorf("docker client not connected")
@@ -263,7 +549,26 @@ func (s *RouteSync) SyncExisting(ctx context.Context) error {
 
 	containers, err := s.client.API().ContainerList(ctx, container.ListOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to list containers: %w", err)
+		err = fmt.Errorf("failed to list containers: %w", err)
+		s.recordError("", err)
+		return err
+	}
+
+	workers := s.concurrency
+	if workers > len(containers) {
+		workers = len(containers)
+	}
+
+	events := make(chan ContainerEvent)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for event := range events {
+				s.handleStart(event)
+			}
+		}()
 	}
 
 	for _, c := range containers {
@@ -273,8 +578,17 @@ func (s *RouteSync) SyncExisting(ctx context.Context) error {
 			Labels:      c.Labels,
 			Type:        "start",
 		}
-		s.handleStart(event)
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			close(events)
+			wg.Wait()
+			return ctx.Err()
+		}
 	}
+	close(events)
+	wg.Wait()
+	s.recordSync()
 
 	return nil
 }
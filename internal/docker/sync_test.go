@@ -1,15 +1,28 @@
 package docker
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"net"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/swarm"
 
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/paths"
 	"github.com/munichmade/devproxy/internal/proxy"
 )
 
@@ -259,6 +272,229 @@ func TestRouteSync_ContainerTracking(t *testing.T) {
 	})
 }
 
+func TestRouteSync_ScaledService(t *testing.T) {
+	registry := proxy.NewRegistry()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ips := map[string]string{"replica1": "172.17.0.5", "replica2": "172.17.0.6"}
+	mockAPI := newMockBuilder().
+		withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			return makeContainerInspectResponse(containerID, "web-"+containerID, ips[containerID], "bridge"), nil
+		}).
+		build()
+
+	client := NewClientWithAPI(mockAPI, logger)
+	sync := NewRouteSync(registry, client, "bridge", logger)
+
+	labels := map[string]string{
+		"devproxy.enable": "true",
+		"devproxy.host":   "web.localhost",
+		"devproxy.port":   "8080",
+	}
+	sync.HandleEvent(ContainerEvent{ContainerID: "replica1", ContainerName: "web-1", Labels: labels, Type: "start"})
+	sync.HandleEvent(ContainerEvent{ContainerID: "replica2", ContainerName: "web-2", Labels: labels, Type: "start"})
+
+	route := registry.Lookup("web.localhost")
+	if route == nil {
+		t.Fatal("expected route to be added")
+	}
+	if !slices.Equal(route.Backends, []string{"172.17.0.5:8080", "172.17.0.6:8080"}) {
+		t.Fatalf("expected both replicas as backends, got %v", route.Backends)
+	}
+
+	// Stopping the first replica keeps the route on the second
+	sync.HandleEvent(ContainerEvent{ContainerID: "replica1", Type: "stop"})
+
+	route = registry.Lookup("web.localhost")
+	if route == nil {
+		t.Fatal("expected route to remain while a replica is running")
+	}
+	if route.Backend != "172.17.0.6:8080" || route.ContainerID != "replica2" || len(route.Backends) != 0 {
+		t.Errorf("expected single backend of replica2, got %+v", route)
+	}
+
+	sync.HandleEvent(ContainerEvent{ContainerID: "replica2", Type: "stop"})
+	if registry.Lookup("web.localhost") != nil {
+		t.Error("expected route to be removed with the last replica")
+	}
+}
+
+func TestRouteSync_SwarmService(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	ips := map[string]string{"task1": "10.0.1.5", "task2": "10.0.1.6"}
+
+	newSync := func(t *testing.T, registry *proxy.Registry, inspectErr error) *RouteSync {
+		t.Helper()
+		mockAPI := newMockBuilder().
+			withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+				return makeContainerInspectResponse(containerID, "shop_web."+containerID, ips[containerID], "shop_default"), nil
+			}).
+			withServiceInspect(func(ctx context.Context, serviceID string) (swarm.Service, error) {
+				if inspectErr != nil {
+					return swarm.Service{}, inspectErr
+				}
+				service := swarm.Service{ID: serviceID}
+				service.Spec.Labels = map[string]string{
+					"devproxy.enable":            "true",
+					"devproxy.host":              "shop.localhost",
+					"devproxy.port":              "8080",
+					"com.docker.stack.namespace": "shop",
+				}
+				return service, nil
+			}).
+			build()
+		return NewRouteSync(registry, NewClientWithAPI(mockAPI, logger), "shop_default", logger)
+	}
+
+	// Task containers only carry the labels Docker sets on them
+	taskLabels := map[string]string{
+		"com.docker.swarm.service.id":   "svc1",
+		"com.docker.swarm.service.name": "shop_web",
+		"com.docker.stack.namespace":    "shop",
+	}
+
+	t.Run("aggregates tasks using the service labels", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		sync := newSync(t, registry, nil)
+
+		sync.HandleEvent(ContainerEvent{ContainerID: "task1", Labels: taskLabels, Type: "start"})
+		sync.HandleEvent(ContainerEvent{ContainerID: "task2", Labels: taskLabels, Type: "start"})
+
+		route := registry.Lookup("shop.localhost")
+		if route == nil {
+			t.Fatal("expected route from the service labels")
+		}
+		if !slices.Equal(route.Backends, []string{"10.0.1.5:8080", "10.0.1.6:8080"}) {
+			t.Fatalf("expected both tasks as backends, got %v", route.Backends)
+		}
+		if route.ProjectName != "shop" {
+			t.Errorf("expected the stack as project, got %q", route.ProjectName)
+		}
+
+		// Stopping one task keeps the route on the other
+		sync.HandleEvent(ContainerEvent{ContainerID: "task1", Type: "die"})
+		route = registry.Lookup("shop.localhost")
+		if route == nil || route.Backend != "10.0.1.6:8080" {
+			t.Fatalf("expected route to remain on task2, got %+v", route)
+		}
+
+		sync.HandleEvent(ContainerEvent{ContainerID: "task2", Type: "die"})
+		if registry.Lookup("shop.localhost") != nil {
+			t.Error("expected route to be removed with the last task")
+		}
+	})
+
+	t.Run("container labels take precedence", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		sync := newSync(t, registry, nil)
+
+		labels := maps.Clone(taskLabels)
+		labels["devproxy.port"] = "9090"
+		sync.HandleEvent(ContainerEvent{ContainerID: "task1", Labels: labels, Type: "start"})
+
+		route := registry.Lookup("shop.localhost")
+		if route == nil || route.Backend != "10.0.1.5:9090" {
+			t.Errorf("expected the container's port label, got %+v", route)
+		}
+	})
+
+	t.Run("falls back to container labels when the service cannot be inspected", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		sync := newSync(t, registry, errMockConnection)
+
+		labels := maps.Clone(taskLabels)
+		labels["devproxy.enable"] = "true"
+		labels["devproxy.host"] = "task.localhost"
+		sync.HandleEvent(ContainerEvent{ContainerID: "task1", Labels: labels, Type: "start"})
+
+		if registry.Lookup("task.localhost") == nil {
+			t.Error("expected route from the container labels")
+		}
+		if registry.Lookup("shop.localhost") != nil {
+			t.Error("expected no route from the service labels")
+		}
+	})
+}
+
+func TestRouteSync_PathRoutes(t *testing.T) {
+	registry := proxy.NewRegistry()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	ips := map[string]string{"web": "172.17.0.5", "api": "172.17.0.6"}
+	mockAPI := newMockBuilder().
+		withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			return makeContainerInspectResponse(containerID, containerID, ips[containerID], "bridge"), nil
+		}).
+		build()
+
+	client := NewClientWithAPI(mockAPI, logger)
+	sync := NewRouteSync(registry, client, "bridge", logger)
+
+	sync.HandleEvent(ContainerEvent{ContainerID: "web", ContainerName: "web", Type: "start", Labels: map[string]string{
+		"devproxy.enable": "true",
+		"devproxy.host":   "app.localhost",
+	}})
+	sync.HandleEvent(ContainerEvent{ContainerID: "api", ContainerName: "api", Type: "start", Labels: map[string]string{
+		"devproxy.enable":       "true",
+		"devproxy.host":         "app.localhost",
+		"devproxy.path":         "/api",
+		"devproxy.strip_prefix": "/api",
+	}})
+
+	if route := registry.LookupPath("app.localhost", "/api/users"); route == nil || route.ContainerID != "api" {
+		t.Fatalf("expected /api to route to the api container, got %+v", route)
+	} else if route.StripPrefix != "/api" {
+		t.Errorf("expected StripPrefix /api, got %q", route.StripPrefix)
+	}
+	if route := registry.LookupPath("app.localhost", "/"); route == nil || route.ContainerID != "web" {
+		t.Fatalf("expected / to route to the web container, got %+v", route)
+	}
+
+	// Stopping the api container leaves the web container's route in place
+	sync.HandleEvent(ContainerEvent{ContainerID: "api", Type: "stop"})
+	if route := registry.LookupPath("app.localhost", "/api/users"); route == nil || route.ContainerID != "web" {
+		t.Errorf("expected /api to fall back to the web container, got %+v", route)
+	}
+}
+
+func TestRouteSync_AllowedNetworks(t *testing.T) {
+	registry := proxy.NewRegistry()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	networks := map[string]string{"ours": "devproxy", "theirs": "other_project_default"}
+	mockAPI := newMockBuilder().
+		withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			return makeContainerInspectResponse(containerID, containerID, "172.18.0.5", networks[containerID]), nil
+		}).
+		build()
+
+	client := NewClientWithAPI(mockAPI, logger)
+	sync := NewRouteSync(registry, client, "", logger)
+	sync.SetAllowedNetworks([]string{"devproxy"})
+
+	for id, host := range map[string]string{"ours": "ours.localhost", "theirs": "theirs.localhost"} {
+		sync.HandleEvent(ContainerEvent{
+			ContainerID:   id,
+			ContainerName: id,
+			Labels: map[string]string{
+				"devproxy.enable": "true",
+				"devproxy.host":   host,
+			},
+			Type: "start",
+		})
+	}
+
+	if registry.Lookup("ours.localhost") == nil {
+		t.Error("expected route for container on an allowed network")
+	}
+	if registry.Lookup("theirs.localhost") != nil {
+		t.Error("expected no route for container on a network that is not allowed")
+	}
+	if hosts := sync.ListContainers()["theirs"]; len(hosts) != 0 {
+		t.Errorf("expected ignored container not to be tracked, got %v", hosts)
+	}
+}
+
 func TestRouteSync_handleStart_FullFlow(t *testing.T) {
 	t.Run("creates route with resolved IP", func(t *testing.T) {
 		registry := proxy.NewRegistry()
@@ -349,6 +585,93 @@ func TestRouteSync_handleStart_FullFlow(t *testing.T) {
 		}
 	})
 
+	t.Run("routes one host on several entrypoints", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		mockAPI := newMockBuilder().
+			withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+				return makeContainerInspectResponse(containerID, "databases", "172.17.0.20", "bridge"), nil
+			}).
+			build()
+
+		client := NewClientWithAPI(mockAPI, logger)
+		sync := NewRouteSync(registry, client, "bridge", logger)
+		sync.SetEntrypoints([]string{"postgres", "mysql"})
+
+		event := ContainerEvent{
+			ContainerID:   "dbcontainer123456",
+			ContainerName: "databases",
+			Labels: map[string]string{
+				"devproxy.enable":     "true",
+				"devproxy.host":       "db.localhost",
+				"devproxy.entrypoint": "postgres:5432,mysql:3306,redis:6379",
+			},
+			Type: "start",
+		}
+
+		sync.HandleEvent(event)
+
+		// The unknown redis entrypoint is skipped
+		if registry.Count() != 2 {
+			t.Fatalf("expected 2 routes, got %d", registry.Count())
+		}
+		for entrypoint, backend := range map[string]string{
+			"postgres": "172.17.0.20:5432",
+			"mysql":    "172.17.0.20:3306",
+		} {
+			route := registry.LookupEntrypoint("db.localhost", entrypoint)
+			if route == nil || route.Entrypoint != entrypoint {
+				t.Fatalf("expected route on entrypoint %s, got %+v", entrypoint, route)
+			}
+			if route.Backend != backend || !route.ExplicitPort || route.Protocol != proxy.ProtocolTCP {
+				t.Errorf("unexpected route on %s: %+v", entrypoint, route)
+			}
+		}
+
+		if hosts := sync.ListContainers()["dbcontainer123456"]; len(hosts) != 1 {
+			t.Errorf("expected host to be tracked once, got %v", hosts)
+		}
+
+		sync.HandleEvent(ContainerEvent{ContainerID: "dbcontainer123456", Type: "stop"})
+		if registry.Count() != 0 {
+			t.Errorf("expected all routes removed on stop, got %d", registry.Count())
+		}
+	})
+
+	t.Run("brackets IPv6 backend addresses", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		mockAPI := newMockBuilder().
+			withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+				return makeContainerInspectResponse(containerID, "ipv6-app", "fd00::5", "bridge"), nil
+			}).
+			build()
+
+		client := NewClientWithAPI(mockAPI, logger)
+		sync := NewRouteSync(registry, client, "bridge", logger)
+
+		sync.HandleEvent(ContainerEvent{
+			ContainerID:   "ipv6container12345",
+			ContainerName: "ipv6-app",
+			Labels: map[string]string{
+				"devproxy.enable": "true",
+				"devproxy.host":   "v6.localhost",
+				"devproxy.port":   "3000",
+			},
+			Type: "start",
+		})
+
+		route := registry.Lookup("v6.localhost")
+		if route == nil {
+			t.Fatal("expected route to be added")
+		}
+		if route.Backend != "[fd00::5]:3000" {
+			t.Errorf("expected backend [fd00::5]:3000, got %s", route.Backend)
+		}
+	})
+
 	t.Run("handles comma-separated hosts", func(t *testing.T) {
 		registry := proxy.NewRegistry()
 		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
@@ -728,6 +1051,156 @@ func TestRouteSync_SyncExisting(t *testing.T) {
 	})
 }
 
+func manyContainerSummaries(n int) []container.Summary {
+	summaries := make([]container.Summary, n)
+	for i := range summaries {
+		id := fmt.Sprintf("container%d", i)
+		summaries[i] = makeContainerSummary(id, id, map[string]string{
+			"devproxy.enable": "true",
+			"devproxy.host":   id + ".localhost",
+			"devproxy.port":   "8080",
+		})
+	}
+	return summaries
+}
+
+func slowInspect(delay time.Duration) func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+	return func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+		time.Sleep(delay)
+		return makeContainerInspectResponse(containerID, "test", "172.17.0.2", "bridge"), nil
+	}
+}
+
+func TestRouteSync_SyncExisting_Concurrency(t *testing.T) {
+	const (
+		count = 40
+		delay = 20 * time.Millisecond
+	)
+
+	scan := func(t *testing.T, concurrency int) time.Duration {
+		t.Helper()
+		registry := proxy.NewRegistry()
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		mockAPI := newMockBuilder().
+			withContainerListResult(manyContainerSummaries(count)).
+			withContainerInspect(slowInspect(delay)).
+			build()
+
+		rs := NewRouteSync(registry, NewClientWithAPI(mockAPI, logger), "bridge", logger)
+		rs.SetSyncConcurrency(concurrency)
+
+		start := time.Now()
+		if err := rs.SyncExisting(context.Background()); err != nil {
+			t.Fatalf("SyncExisting failed: %v", err)
+		}
+		elapsed := time.Since(start)
+
+		if registry.Count() != count {
+			t.Errorf("expected %d routes, got %d", count, registry.Count())
+		}
+		if len(rs.ListContainers()) != count {
+			t.Errorf("expected %d tracked containers, got %d", count, len(rs.ListContainers()))
+		}
+		return elapsed
+	}
+
+	t.Run("serial scan inspects one container at a time", func(t *testing.T) {
+		elapsed := scan(t, 1)
+		if elapsed < count*delay {
+			t.Errorf("serial scan took %v, expected at least %v", elapsed, count*delay)
+		}
+	})
+
+	t.Run("parallel scan overlaps inspect latency", func(t *testing.T) {
+		elapsed := scan(t, 10)
+		// 40 containers over 10 workers is 4 rounds; allow generous slack
+		if limit := count * delay / 2; elapsed >= limit {
+			t.Errorf("parallel scan took %v, expected less than %v", elapsed, limit)
+		}
+	})
+
+	t.Run("never exceeds configured concurrency", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		var mu sync.Mutex
+		var active, peak int
+		mockAPI := newMockBuilder().
+			withContainerListResult(manyContainerSummaries(count)).
+			withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+				mu.Lock()
+				active++
+				if active > peak {
+					peak = active
+				}
+				mu.Unlock()
+
+				time.Sleep(5 * time.Millisecond)
+
+				mu.Lock()
+				active--
+				mu.Unlock()
+				return makeContainerInspectResponse(containerID, "test", "172.17.0.2", "bridge"), nil
+			}).
+			build()
+
+		rs := NewRouteSync(registry, NewClientWithAPI(mockAPI, logger), "bridge", logger)
+		rs.SetSyncConcurrency(4)
+		if err := rs.SyncExisting(context.Background()); err != nil {
+			t.Fatalf("SyncExisting failed: %v", err)
+		}
+
+		if peak > 4 {
+			t.Errorf("expected at most 4 concurrent inspects, got %d", peak)
+		}
+	})
+
+	t.Run("stops when context is cancelled", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		mockAPI := newMockBuilder().
+			withContainerListResult(manyContainerSummaries(count)).
+			withContainerInspect(slowInspect(delay)).
+			build()
+
+		rs := NewRouteSync(registry, NewClientWithAPI(mockAPI, logger), "bridge", logger)
+		rs.SetSyncConcurrency(1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*delay)
+		defer cancel()
+
+		if err := rs.SyncExisting(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected context.DeadlineExceeded, got %v", err)
+		}
+		if registry.Count() >= count {
+			t.Errorf("expected scan to stop early, got %d routes", registry.Count())
+		}
+	})
+}
+
+func BenchmarkRouteSync_SyncExisting(b *testing.B) {
+	for _, concurrency := range []int{1, DefaultSyncConcurrency, 32} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+			mockAPI := newMockBuilder().
+				withContainerListResult(manyContainerSummaries(100)).
+				withContainerInspect(slowInspect(time.Millisecond)).
+				build()
+			client := NewClientWithAPI(mockAPI, logger)
+
+			for i := 0; i < b.N; i++ {
+				rs := NewRouteSync(proxy.NewRegistry(), client, "bridge", logger)
+				rs.SetSyncConcurrency(concurrency)
+				if err := rs.SyncExisting(context.Background()); err != nil {
+					b.Fatalf("SyncExisting failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
 func TestRouteSync_ListContainers(t *testing.T) {
 	t.Run("returns copy of tracked containers", func(t *testing.T) {
 		registry := proxy.NewRegistry()
@@ -826,3 +1299,386 @@ func (m *mockCertManager) EnsureCertificate(domain string) error {
 	}
 	return nil
 }
+
+func TestRouteSync_ValidateNetwork(t *testing.T) {
+	t.Run("no configured network is always valid", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		sync := NewRouteSync(proxy.NewRegistry(), &Client{}, "", logger)
+
+		exists, err := sync.ValidateNetwork(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !exists {
+			t.Error("expected valid result without configured network")
+		}
+	})
+
+	t.Run("existing network", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		client := NewClientWithAPI(newMockBuilder().build(), logger)
+		sync := NewRouteSync(proxy.NewRegistry(), client, "devnet", logger)
+
+		exists, err := sync.ValidateNetwork(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !exists {
+			t.Error("expected network to exist")
+		}
+	})
+
+	t.Run("missing network logs warning", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+		mockAPI := newMockBuilder().
+			withNetworkInspectError(fmt.Errorf("network devnet: %w", cerrdefs.ErrNotFound)).
+			build()
+		client := NewClientWithAPI(mockAPI, logger)
+		sync := NewRouteSync(proxy.NewRegistry(), client, "devnet", logger)
+
+		exists, err := sync.ValidateNetwork(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if exists {
+			t.Error("expected network to be reported missing")
+		}
+		if !strings.Contains(buf.String(), "configured Docker network does not exist") {
+			t.Errorf("expected warning in log, got: %s", buf.String())
+		}
+	})
+
+	t.Run("inspect failure is returned", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		mockAPI := newMockBuilder().
+			withNetworkInspectError(errMockConnection).
+			build()
+		client := NewClientWithAPI(mockAPI, logger)
+		sync := NewRouteSync(proxy.NewRegistry(), client, "devnet", logger)
+
+		if _, err := sync.ValidateNetwork(context.Background()); !errors.Is(err, errMockConnection) {
+			t.Errorf("expected connection error, got %v", err)
+		}
+	})
+}
+
+func TestRouteSync_StrictNetwork(t *testing.T) {
+	event := ContainerEvent{
+		ContainerID:   "othernetcontainer",
+		ContainerName: "other",
+		Labels: map[string]string{
+			"devproxy.enable": "true",
+			"devproxy.host":   "other.localhost",
+			"devproxy.port":   "8080",
+		},
+		Type: "start",
+	}
+
+	tests := []struct {
+		name       string
+		fallback   bool
+		wantRoutes int
+	}{
+		{name: "strict mode skips container on other network", fallback: false, wantRoutes: 0},
+		{name: "fallback uses container's other network", fallback: true, wantRoutes: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := proxy.NewRegistry()
+			logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+			// Inspect data only has the "bridge" network, not the configured one
+			mockAPI := newMockBuilder().
+				withContainerInspectResult(makeContainerInspectResponse(event.ContainerID, "/other", "172.17.0.9", "bridge")).
+				build()
+			client := NewClientWithAPI(mockAPI, logger)
+			sync := NewRouteSync(registry, client, "devnet", logger)
+			sync.SetNetworkFallback(tt.fallback)
+
+			sync.HandleEvent(event)
+
+			if registry.Count() != tt.wantRoutes {
+				t.Errorf("expected %d routes, got %d", tt.wantRoutes, registry.Count())
+			}
+		})
+	}
+}
+
+func TestRouteSync_Readiness(t *testing.T) {
+	newEvent := func(host string) ContainerEvent {
+		return ContainerEvent{
+			ContainerID:   "readycontainer",
+			ContainerName: "ready-test",
+			Labels: map[string]string{
+				"devproxy.enable": "true",
+				"devproxy.host":   host,
+				"devproxy.port":   "8080",
+			},
+			Type: "start",
+		}
+	}
+
+	newSync := func(registry *proxy.Registry) *RouteSync {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		mockAPI := newMockBuilder().
+			withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+				return makeContainerInspectResponse(containerID, "ready-test", "172.17.0.30", "bridge"), nil
+			}).
+			build()
+		return NewRouteSync(registry, NewClientWithAPI(mockAPI, logger), "bridge", logger)
+	}
+
+	waitReady := func(t *testing.T, registry *proxy.Registry, host string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			if route := registry.Lookup(host); route != nil && route.Ready {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("expected route %s to become ready", host)
+	}
+
+	t.Run("starting until certificate is ready", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		sync := newSync(registry)
+
+		generating := make(chan struct{})
+		release := make(chan struct{})
+		sync.SetCertManager(&mockCertManager{
+			ensureCertificateFunc: func(domain string) error {
+				close(generating)
+				<-release
+				return nil
+			},
+		})
+
+		done := make(chan struct{})
+		go func() {
+			sync.HandleEvent(newEvent("slowcert.localhost"))
+			close(done)
+		}()
+
+		<-generating
+		route := registry.Lookup("slowcert.localhost")
+		if route == nil {
+			t.Fatal("expected route to be registered during certificate generation")
+		}
+		if route.Ready {
+			t.Error("expected route to be starting before certificate is ready")
+		}
+
+		close(release)
+		<-done
+
+		if route := registry.Lookup("slowcert.localhost"); route == nil || !route.Ready {
+			t.Error("expected route to be ready after certificate generation")
+		}
+	})
+
+	t.Run("stays starting when certificate generation fails", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		sync := newSync(registry)
+		sync.SetCertManager(&mockCertManager{
+			ensureCertificateFunc: func(domain string) error {
+				return errors.New("cert generation failed")
+			},
+		})
+
+		sync.HandleEvent(newEvent("badcert.localhost"))
+
+		if route := registry.Lookup("badcert.localhost"); route == nil || route.Ready {
+			t.Error("expected route to be registered but not ready")
+		}
+	})
+
+	t.Run("waits for readiness check", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		sync := newSync(registry)
+		sync.SetCertManager(&mockCertManager{})
+
+		release := make(chan struct{})
+		var probed string
+		sync.SetReadinessCheck(func(ctx context.Context, backend string) error {
+			probed = backend
+			<-release
+			return nil
+		})
+
+		sync.HandleEvent(newEvent("probe.localhost"))
+
+		if route := registry.Lookup("probe.localhost"); route == nil || route.Ready {
+			t.Fatal("expected route to be starting until the probe passes")
+		}
+
+		close(release)
+		waitReady(t, registry, "probe.localhost")
+
+		if probed != "172.17.0.30:8080" {
+			t.Errorf("expected probe of backend 172.17.0.30:8080, got %q", probed)
+		}
+	})
+
+	t.Run("stays starting when readiness check fails", func(t *testing.T) {
+		registry := proxy.NewRegistry()
+		sync := newSync(registry)
+
+		checked := make(chan struct{})
+		sync.SetReadinessCheck(func(ctx context.Context, backend string) error {
+			defer close(checked)
+			return errors.New("connection refused")
+		})
+
+		sync.HandleEvent(newEvent("down.localhost"))
+		<-checked
+
+		// Give markReady a chance to run if it (incorrectly) would
+		time.Sleep(20 * time.Millisecond)
+		if route := registry.Lookup("down.localhost"); route == nil || route.Ready {
+			t.Error("expected route to stay starting when the probe fails")
+		}
+	})
+}
+
+func TestDialReadinessCheck(t *testing.T) {
+	t.Run("succeeds when backend accepts connections", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		defer ln.Close()
+
+		check := DialReadinessCheck(time.Second, 10*time.Millisecond)
+		if err := check(context.Background(), ln.Addr().String()); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("fails after timeout", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		addr := ln.Addr().String()
+		ln.Close()
+
+		check := DialReadinessCheck(50*time.Millisecond, 10*time.Millisecond)
+		if err := check(context.Background(), addr); err == nil {
+			t.Error("expected error for unreachable backend")
+		}
+	})
+}
+
+// greetingBackend starts a TCP server that writes greeting to every connection.
+func greetingBackend(t *testing.T, greeting string) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte(greeting))
+			conn.Close()
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestRouteSync_TCPEntrypointIntegration(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	t.Cleanup(paths.Reset)
+	if _, err := ca.Generate(); err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+	certManager, err := cert.NewManager()
+	if err != nil {
+		t.Fatalf("failed to create cert manager: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	registry := proxy.NewRegistry()
+	tcpRegistry := proxy.NewTCPRegistry()
+
+	mockAPI := newMockBuilder().
+		withContainerInspect(func(ctx context.Context, containerID string) (container.InspectResponse, error) {
+			return makeContainerInspectResponse(containerID, containerID, "127.0.0.1", "bridge"), nil
+		}).
+		build()
+	sync := NewRouteSync(registry, NewClientWithAPI(mockAPI, logger), "bridge", logger)
+	sync.SetTCPRegistry(tcpRegistry)
+	sync.SetEntrypoints([]string{"postgres"})
+
+	// Two databases share the postgres entrypoint, so only SNI can tell them apart
+	for _, db := range []string{"orders", "users"} {
+		port := greetingBackend(t, db+"-db")
+		sync.HandleEvent(ContainerEvent{
+			ContainerID:   db + "container123456",
+			ContainerName: db,
+			Labels: map[string]string{
+				"devproxy.enable":     "true",
+				"devproxy.host":       db + ".localhost",
+				"devproxy.entrypoint": fmt.Sprintf("postgres:%d", port),
+			},
+			Type: "start",
+		})
+	}
+	if tcpRegistry.Count() != 2 {
+		t.Fatalf("expected 2 TCP routes, got %d", tcpRegistry.Count())
+	}
+
+	ep := proxy.NewTCPEntrypoint(proxy.TCPEntrypointConfig{
+		Name:        "postgres",
+		Listen:      "127.0.0.1:0",
+		TargetPort:  5432,
+		Registry:    registry,
+		TCPRoutes:   tcpRegistry,
+		CertManager: certManager,
+		Logger:      logger,
+	})
+	if err := ep.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start entrypoint: %v", err)
+	}
+	defer ep.Stop(context.Background())
+
+	dial := func(host string) (string, error) {
+		dialer := &net.Dialer{Timeout: 2 * time.Second}
+		conn, err := tls.DialWithDialer(dialer, "tcp", ep.Addr(), &tls.Config{ServerName: host, InsecureSkipVerify: true})
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		data, err := io.ReadAll(conn)
+		return string(data), err
+	}
+
+	for _, db := range []string{"orders", "users"} {
+		got, err := dial(db + ".localhost")
+		if err != nil {
+			t.Fatalf("connecting to %s failed: %v", db, err)
+		}
+		if got != db+"-db" {
+			t.Errorf("expected %s backend, got %q", db, got)
+		}
+	}
+
+	sync.HandleEvent(ContainerEvent{ContainerID: "orderscontainer123456", Type: "stop"})
+	if tcpRegistry.Count() != 1 {
+		t.Errorf("expected stopped container's TCP route to be removed, got %d", tcpRegistry.Count())
+	}
+	if got, err := dial("orders.localhost"); err == nil {
+		t.Errorf("expected stopped container to be unreachable, got %q", got)
+	}
+}
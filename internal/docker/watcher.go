@@ -95,21 +95,34 @@ func (w *Watcher) Stop() {
 	<-w.stoppedCh
 }
 
-// scanExistingContainers discovers already-running containers with devproxy labels.
+// scanExistingContainers discovers already-running containers with devproxy
+// labels and the tasks of Swarm services, whose devproxy labels may be set on
+// the service instead.
 func (w *Watcher) scanExistingContainers(ctx context.Context) error {
 	enableLabel := LabelPrefix + ".enable"
 
-	// List running containers with our enable label
-	opts := container.ListOptions{
-		Filters: filters.NewArgs(
-			filters.Arg("status", "running"),
-			filters.Arg("label", enableLabel+"=true"),
-		),
-	}
+	// List running containers with our enable label, then Swarm tasks. Label
+	// filters are combined with AND, so this takes one list per label.
+	var containers []container.Summary
+	seen := make(map[string]bool)
+	for _, label := range []string{enableLabel + "=true", swarmServiceIDLabel} {
+		opts := container.ListOptions{
+			Filters: filters.NewArgs(
+				filters.Arg("status", "running"),
+				filters.Arg("label", label),
+			),
+		}
 
-	containers, err := w.client.API().ContainerList(ctx, opts)
-	if err != nil {
-		return err
+		list, err := w.client.API().ContainerList(ctx, opts)
+		if err != nil {
+			return err
+		}
+		for _, c := range list {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				containers = append(containers, c)
+			}
+		}
 	}
 
 	w.logger.Info("scanning existing containers", "count", len(containers))
@@ -191,8 +204,8 @@ func (w *Watcher) watchEventStream(ctx context.Context) {
 			return
 
 		case event := <-eventCh:
-			// Check if container has our enable label
-			if event.Actor.Attributes[enableLabel] != "true" {
+			// Check if container has our enable label or is a Swarm task
+			if event.Actor.Attributes[enableLabel] != "true" && event.Actor.Attributes[swarmServiceIDLabel] == "" {
 				continue
 			}
 
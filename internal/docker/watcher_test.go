@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"log/slog"
+	"slices"
 	"sync"
 	"testing"
 	"time"
@@ -220,6 +221,43 @@ func TestWatcher_scanExistingContainers(t *testing.T) {
 		}
 	})
 
+	t.Run("includes Swarm tasks once", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		enabled := makeContainerSummary("container1", "web-app", map[string]string{
+			"devproxy.enable": "true",
+		})
+		taskWithLabels := makeContainerSummary("task1", "shop_web.1", map[string]string{
+			"devproxy.enable":             "true",
+			"com.docker.swarm.service.id": "svc1",
+		})
+		task := makeContainerSummary("task2", "shop_web.2", map[string]string{
+			"com.docker.swarm.service.id": "svc1",
+		})
+
+		mockAPI := newMockBuilder().build()
+		mockAPI.containerListFunc = func(ctx context.Context, options container.ListOptions) ([]container.Summary, error) {
+			if options.Filters.ExactMatch("label", "com.docker.swarm.service.id") {
+				return []container.Summary{taskWithLabels, task}, nil
+			}
+			return []container.Summary{enabled, taskWithLabels}, nil
+		}
+
+		var receivedIDs []string
+		handler := func(event ContainerEvent) {
+			receivedIDs = append(receivedIDs, event.ContainerID)
+		}
+
+		watcher := NewWatcher(NewClientWithAPI(mockAPI, logger), handler, logger)
+		if err := watcher.scanExistingContainers(context.Background()); err != nil {
+			t.Fatalf("scanExistingContainers failed: %v", err)
+		}
+
+		if want := []string{"container1", "task1", "task2"}; !slices.Equal(receivedIDs, want) {
+			t.Errorf("expected events for %v, got %v", want, receivedIDs)
+		}
+	})
+
 	t.Run("handles list error gracefully", func(t *testing.T) {
 		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 
@@ -493,6 +531,65 @@ func TestWatcher_watchEventStream(t *testing.T) {
 		}
 	})
 
+	t.Run("passes events of Swarm tasks", func(t *testing.T) {
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+		eventCh := make(chan events.Message, 1)
+		errCh := make(chan error, 1)
+
+		mockAPI := newMockBuilder().
+			withEvents(func(ctx context.Context, options events.ListOptions) (<-chan events.Message, <-chan error) {
+				return eventCh, errCh
+			}).
+			build()
+
+		receivedCh := make(chan ContainerEvent, 1)
+		handler := func(event ContainerEvent) {
+			receivedCh <- event
+		}
+
+		watcher := NewWatcher(NewClientWithAPI(mockAPI, logger), handler, logger)
+		watcher.mu.Lock()
+		watcher.running = true
+		watcher.stopCh = make(chan struct{})
+		watcher.stoppedCh = make(chan struct{})
+		watcher.mu.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			watcher.watchEventStream(context.Background())
+			close(done)
+		}()
+
+		// The devproxy labels of a task may be set on its service only
+		eventCh <- events.Message{
+			Action: events.ActionStart,
+			Actor: events.Actor{
+				ID: "task1",
+				Attributes: map[string]string{
+					"com.docker.swarm.service.id": "svc1",
+					"name":                        "shop_web.1",
+				},
+			},
+		}
+
+		select {
+		case event := <-receivedCh:
+			if event.ContainerID != "task1" {
+				t.Errorf("expected task1, got %s", event.ContainerID)
+			}
+		case <-time.After(500 * time.Millisecond):
+			t.Error("timeout waiting for event")
+		}
+
+		close(watcher.stopCh)
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Error("watchEventStream did not exit")
+		}
+	})
+
 	t.Run("exits on error channel", func(t *testing.T) {
 		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 
@@ -0,0 +1,51 @@
+// Package listen binds the listening sockets of entrypoints.
+package listen
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// TCP binds a TCP listener on addr. A positive backlog sets the length of the
+// queue of connections not yet accepted; otherwise the system default
+// (somaxconn) is used. Go sets SO_REUSEADDR on TCP listeners, so a restarted
+// daemon can rebind its addresses while connections of the previous instance
+// linger in TIME_WAIT.
+func TCP(addr string, backlog int) (net.Listener, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if backlog > 0 {
+		if err := setBacklog(listener, backlog); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+	return listener, nil
+}
+
+// setBacklog calls listen(2) again on the bound socket, which replaces the
+// backlog Go chose.
+func setBacklog(listener net.Listener, backlog int) error {
+	tcpListener, ok := listener.(*net.TCPListener)
+	if !ok {
+		return fmt.Errorf("cannot set backlog on %T", listener)
+	}
+	rawConn, err := tcpListener.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var listenErr error
+	if err := rawConn.Control(func(fd uintptr) {
+		listenErr = syscall.Listen(int(fd), backlog)
+	}); err != nil {
+		return err
+	}
+	if listenErr != nil {
+		return fmt.Errorf("failed to set backlog: %w", listenErr)
+	}
+	return nil
+}
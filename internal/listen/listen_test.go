@@ -0,0 +1,50 @@
+package listen
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+func TestTCP_Rebind(t *testing.T) {
+	for _, backlog := range []int{0, 16} {
+		listener, err := TCP("127.0.0.1:0", backlog)
+		if err != nil {
+			t.Fatalf("TCP(backlog %d) error = %v", backlog, err)
+		}
+		addr := listener.Addr().String()
+
+		// Closing the accepted side first leaves it in TIME_WAIT on addr
+		client, err := net.Dial("tcp", addr)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		server, err := listener.Accept()
+		if err != nil {
+			t.Fatalf("failed to accept: %v", err)
+		}
+		server.Close()
+		io.Copy(io.Discard, client)
+		client.Close()
+		listener.Close()
+
+		rebound, err := TCP(addr, backlog)
+		if err != nil {
+			t.Fatalf("expected %s to be rebound right away, got %v", addr, err)
+		}
+		rebound.Close()
+	}
+}
+
+func TestTCP_AddrInUse(t *testing.T) {
+	held, err := TCP("127.0.0.1:0", 0)
+	if err != nil {
+		t.Fatalf("TCP() error = %v", err)
+	}
+	defer held.Close()
+
+	if listener, err := TCP(held.Addr().String(), 0); err == nil {
+		listener.Close()
+		t.Error("expected bind to fail while the address is listened on")
+	}
+}
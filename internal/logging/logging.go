@@ -5,6 +5,7 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"strings"
 	"sync"
 )
 
@@ -19,6 +20,15 @@ const (
 	LevelError = slog.LevelError
 )
 
+// Format selects how log records are written.
+type Format string
+
+// Log formats.
+const (
+	FormatText Format = "text" // human-readable key=value pairs
+	FormatJSON Format = "json" // one JSON object per line
+)
+
 // levelVar holds the current log level and allows runtime updates.
 var (
 	currentLevel = &slog.LevelVar{}
@@ -42,8 +52,19 @@ func ParseLevel(s string) Level {
 	}
 }
 
-// Setup configures the default slog logger with the specified level and output.
-func Setup(level Level, w io.Writer) {
+// ParseFormat parses a string into a Format, ignoring case. Unknown values
+// select FormatText.
+func ParseFormat(s string) Format {
+	switch strings.ToLower(s) {
+	case "json":
+		return FormatJSON
+	default:
+		return FormatText
+	}
+}
+
+// Setup configures the default slog logger with the specified level, format and output.
+func Setup(level Level, format Format, w io.Writer) {
 	if w == nil {
 		w = os.Stdout
 	}
@@ -54,12 +75,17 @@ func Setup(level Level, w io.Writer) {
 		Level: currentLevel,
 	}
 
-	handler := slog.NewTextHandler(w, opts)
+	var handler slog.Handler
+	if format == FormatJSON {
+		handler = slog.NewJSONHandler(w, opts)
+	} else {
+		handler = slog.NewTextHandler(w, opts)
+	}
 	slog.SetDefault(slog.New(handler))
 }
 
 // SetupFile configures the default logger to write to a file.
-func SetupFile(level Level, path string) error {
+func SetupFile(level Level, format Format, path string) error {
 	mu.Lock()
 	defer mu.Unlock()
 
@@ -68,7 +94,7 @@ func SetupFile(level Level, path string) error {
 		return err
 	}
 	currentFile = f
-	Setup(level, f)
+	Setup(level, format, f)
 	return nil
 }
 
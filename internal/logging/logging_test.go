@@ -2,6 +2,7 @@ package logging
 
 import (
 	"bytes"
+	"encoding/json"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -39,7 +40,7 @@ func TestParseLevel(t *testing.T) {
 
 func TestSetup(t *testing.T) {
 	var buf bytes.Buffer
-	Setup(LevelInfo, &buf)
+	Setup(LevelInfo, FormatText, &buf)
 
 	Info("test message", "key", "value")
 
@@ -61,6 +62,65 @@ func TestSetup(t *testing.T) {
 	}
 }
 
+func TestSetup_Format(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		Setup(LevelInfo, FormatJSON, &buf)
+
+		Info("test message", "key", "value")
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+			t.Fatalf("expected a JSON record, got %q: %v", buf.String(), err)
+		}
+		for key, want := range map[string]string{"level": "INFO", "msg": "test message", "key": "value"} {
+			if record[key] != want {
+				t.Errorf("expected %s=%q, got %v", key, want, record[key])
+			}
+		}
+		if _, ok := record["time"]; !ok {
+			t.Error("expected a time key")
+		}
+	})
+
+	t.Run("text", func(t *testing.T) {
+		var buf bytes.Buffer
+		Setup(LevelInfo, FormatText, &buf)
+
+		Info("test message", "key", "value")
+
+		var record map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &record); err == nil {
+			t.Errorf("expected text output not to be JSON, got %q", buf.String())
+		}
+		if !strings.Contains(buf.String(), "key=value") {
+			t.Errorf("output should contain key=value, got %q", buf.String())
+		}
+	})
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Format
+	}{
+		{"json", FormatJSON},
+		{"JSON", FormatJSON},
+		{"Json", FormatJSON},
+		{"text", FormatText},
+		{"", FormatText},
+		{"unknown", FormatText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ParseFormat(tt.input); got != tt.want {
+				t.Errorf("ParseFormat(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
 func TestLevelFiltering(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -92,7 +152,7 @@ func TestLevelFiltering(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			var buf bytes.Buffer
-			Setup(tt.level, &buf)
+			Setup(tt.level, FormatText, &buf)
 
 			switch tt.logLevel {
 			case LevelDebug:
@@ -117,7 +177,7 @@ func TestSetupFile(t *testing.T) {
 	tmpDir := t.TempDir()
 	logPath := filepath.Join(tmpDir, "test.log")
 
-	err := SetupFile(LevelInfo, logPath)
+	err := SetupFile(LevelInfo, FormatText, logPath)
 	if err != nil {
 		t.Fatalf("SetupFile() error = %v", err)
 	}
@@ -136,7 +196,7 @@ func TestSetupFile(t *testing.T) {
 }
 
 func TestSetupFile_InvalidPath(t *testing.T) {
-	err := SetupFile(LevelInfo, "/nonexistent/dir/test.log")
+	err := SetupFile(LevelInfo, FormatText, "/nonexistent/dir/test.log")
 	if err == nil {
 		t.Error("SetupFile should return error for invalid path")
 	}
@@ -156,7 +216,7 @@ func TestDefault(t *testing.T) {
 
 func TestNilOutput(t *testing.T) {
 	// Should not panic with nil output (defaults to stdout)
-	Setup(LevelInfo, nil)
+	Setup(LevelInfo, FormatText, nil)
 	// Verify it doesn't panic by logging something
 	Info("nil output test")
 }
@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+)
+
+// UnroutedHost is the host label of HTTP requests that matched no route.
+const UnroutedHost = "unrouted"
+
+// Daemon metrics, registered in Default.
+var (
+	httpRequests = Default.NewCounterVec("devproxy_http_requests_total",
+		"HTTP requests handled, by route host and status code.", "host", "code")
+	httpRequestDuration = Default.NewHistogramVec("devproxy_http_request_duration_seconds",
+		"Time until the response was complete, by route host.", DefaultBuckets, "host")
+	backendConnections = Default.NewCounterVec("devproxy_backend_connections_total",
+		"Backend connections HTTP requests were sent on, by backend and whether an idle connection was reused.", "backend", "reused")
+	tcpConnections = Default.NewCounterVec("devproxy_tcp_connections_total",
+		"TCP connections accepted, by entrypoint.", "entrypoint")
+	tcpActiveConnections = Default.NewGaugeVec("devproxy_tcp_connections_active",
+		"TCP connections currently open, by entrypoint.", "entrypoint")
+	certificateRequests = Default.NewCounterVec("devproxy_certificate_requests_total",
+		"Certificates requested during TLS handshakes, by result (cached, generated, error).", "result")
+	certificateCacheSize = Default.NewGaugeVec("devproxy_certificate_cache_size",
+		"Certificates held in the memory cache.")
+	certificateGenerationDuration = Default.NewHistogramVec("devproxy_certificate_generation_duration_seconds",
+		"Time spent generating a certificate.", DefaultBuckets)
+	certificateGenerationFailures = Default.NewCounterVec("devproxy_certificate_generation_failures_total",
+		"Failed certificate generations, by reason (key, ca, disk).", "reason")
+)
+
+// ObserveHTTPRequest records a completed HTTP request. host is the host of the
+// matched route, so wildcard routes count as one host; use UnroutedHost for
+// requests without a route.
+func ObserveHTTPRequest(host string, status int, duration time.Duration) {
+	httpRequests.Inc(host, strconv.Itoa(status))
+	httpRequestDuration.Observe(duration.Seconds(), host)
+}
+
+// ObserveBackendConnection records that an HTTP request to backend was sent on
+// a reused idle connection or a newly dialed one.
+func ObserveBackendConnection(backend string, reused bool) {
+	backendConnections.Inc(backend, strconv.FormatBool(reused))
+}
+
+// TCPConnectionOpened records a connection accepted on entrypoint. The
+// returned function records that it closed.
+func TCPConnectionOpened(entrypoint string) (closed func()) {
+	tcpConnections.Inc(entrypoint)
+	tcpActiveConnections.Add(1, entrypoint)
+	return func() {
+		tcpActiveConnections.Add(-1, entrypoint)
+	}
+}
+
+// ObserveCertificateRequest records the result of a certificate request:
+// "cached", "generated" or "error".
+func ObserveCertificateRequest(result string) {
+	certificateRequests.Inc(result)
+}
+
+// SetCertificateCacheSize records the number of cached certificates.
+func SetCertificateCacheSize(n int) {
+	certificateCacheSize.Set(float64(n))
+}
+
+// ObserveCertificateGeneration records how long generating a certificate took.
+func ObserveCertificateGeneration(duration time.Duration) {
+	certificateGenerationDuration.Observe(duration.Seconds())
+}
+
+// CertificateGenerationFailed records a failed certificate generation:
+// "key", "ca" or "disk".
+func CertificateGenerationFailed(reason string) {
+	certificateGenerationFailures.Inc(reason)
+}
@@ -0,0 +1,165 @@
+// Package metrics collects daemon metrics and exposes them in the Prometheus
+// text exposition format, using the Prometheus client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// DefaultMaxSeries caps the number of label combinations a metric tracks.
+// Observations for further combinations are recorded with every label set to
+// OverflowValue, so a flood of distinct hosts cannot grow memory unbounded.
+const DefaultMaxSeries = 500
+
+// OverflowValue is the label value of observations past DefaultMaxSeries.
+const OverflowValue = "other"
+
+// DefaultBuckets are the histogram upper bounds in seconds, from 5ms to 30s.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// Registry holds metrics and writes them sorted by name.
+type Registry struct {
+	reg *prometheus.Registry
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{reg: prometheus.NewRegistry()}
+}
+
+// Default is the registry the daemon's metrics are registered in.
+var Default = NewRegistry()
+
+// seriesLimit caps the label combinations of a metric at DefaultMaxSeries.
+type seriesLimit struct {
+	name   string
+	labels int
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+}
+
+func newSeriesLimit(name string, labels []string) *seriesLimit {
+	return &seriesLimit{name: name, labels: len(labels), seen: make(map[string]struct{})}
+}
+
+// values returns the label values to record values under: values itself, or
+// OverflowValue for every label once the limit is reached. It panics if the
+// number of values does not match the metric's labels.
+func (l *seriesLimit) values(values []string) []string {
+	if len(values) != l.labels {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", l.name, l.labels, len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.seen[key]; ok {
+		return values
+	}
+	if len(l.seen) >= DefaultMaxSeries {
+		overflow := make([]string, len(values))
+		for i := range overflow {
+			overflow[i] = OverflowValue
+		}
+		return overflow
+	}
+	l.seen[key] = struct{}{}
+	return values
+}
+
+// CounterVec is a counter partitioned by labels.
+type CounterVec struct {
+	vec   *prometheus.CounterVec
+	limit *seriesLimit
+}
+
+// NewCounterVec registers a counter with the given label names.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	vec := prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, labels)
+	r.reg.MustRegister(vec)
+	return &CounterVec{vec: vec, limit: newSeriesLimit(name, labels)}
+}
+
+// Inc adds one to the counter for the label values.
+func (c *CounterVec) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+// Add adds delta, which must not be negative, to the counter for the label values.
+func (c *CounterVec) Add(delta float64, values ...string) {
+	c.vec.WithLabelValues(c.limit.values(values)...).Add(delta)
+}
+
+// GaugeVec is a gauge partitioned by labels.
+type GaugeVec struct {
+	vec   *prometheus.GaugeVec
+	limit *seriesLimit
+}
+
+// NewGaugeVec registers a gauge with the given label names.
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	vec := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: help}, labels)
+	r.reg.MustRegister(vec)
+	return &GaugeVec{vec: vec, limit: newSeriesLimit(name, labels)}
+}
+
+// Set sets the gauge for the label values.
+func (g *GaugeVec) Set(value float64, values ...string) {
+	g.vec.WithLabelValues(g.limit.values(values)...).Set(value)
+}
+
+// Add adds delta to the gauge for the label values.
+func (g *GaugeVec) Add(delta float64, values ...string) {
+	g.vec.WithLabelValues(g.limit.values(values)...).Add(delta)
+}
+
+// HistogramVec is a histogram partitioned by labels.
+type HistogramVec struct {
+	vec   *prometheus.HistogramVec
+	limit *seriesLimit
+}
+
+// NewHistogramVec registers a histogram with the given bucket upper bounds,
+// which must be sorted, and label names.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	vec := prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: help, Buckets: buckets}, labels)
+	r.reg.MustRegister(vec)
+	return &HistogramVec{vec: vec, limit: newSeriesLimit(name, labels)}
+}
+
+// Observe records value in the histogram for the label values.
+func (h *HistogramVec) Observe(value float64, values ...string) {
+	h.vec.WithLabelValues(h.limit.values(values)...).Observe(value)
+}
+
+// WriteTo writes all metrics in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	families, err := r.reg.Gather()
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	for _, family := range families {
+		n, err := expfmt.MetricFamilyToText(w, family)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// Handler returns an http.Handler serving the registry's metrics.
+func (r *Registry) Handler() http.Handler {
+	return promhttp.HandlerFor(r.reg, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, r *Registry) string {
+	t.Helper()
+	var b strings.Builder
+	if _, err := r.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	return b.String()
+}
+
+func TestRegistry_WriteTo(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_requests_total", "Requests.", "host", "code")
+	active := r.NewGaugeVec("test_active", "Active connections.")
+	duration := r.NewHistogramVec("test_duration_seconds", "Durations.", []float64{0.1, 1}, "host")
+
+	requests.Inc("b.localhost", "200")
+	requests.Inc("a.localhost", "200")
+	requests.Add(2, "a.localhost", "200")
+	active.Add(3)
+	active.Add(-1)
+	duration.Observe(0.05, "a.localhost")
+	duration.Observe(0.5, "a.localhost")
+	duration.Observe(5, "a.localhost")
+
+	want := `# HELP test_active Active connections.
+# TYPE test_active gauge
+test_active 2
+# HELP test_duration_seconds Durations.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{host="a.localhost",le="0.1"} 1
+test_duration_seconds_bucket{host="a.localhost",le="1"} 2
+test_duration_seconds_bucket{host="a.localhost",le="+Inf"} 3
+test_duration_seconds_sum{host="a.localhost"} 5.55
+test_duration_seconds_count{host="a.localhost"} 3
+# HELP test_requests_total Requests.
+# TYPE test_requests_total counter
+test_requests_total{code="200",host="a.localhost"} 3
+test_requests_total{code="200",host="b.localhost"} 1
+`
+	if got := scrape(t, r); got != want {
+		t.Errorf("WriteTo() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRegistry_EscapesLabelValues(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounterVec("test_total", "Test.", "host").Inc("a\"b\\c\nd")
+
+	if got := scrape(t, r); !strings.Contains(got, `test_total{host="a\"b\\c\nd"} 1`) {
+		t.Errorf("expected escaped label value, got:\n%s", got)
+	}
+}
+
+func TestRegistry_CapsSeries(t *testing.T) {
+	r := NewRegistry()
+	requests := r.NewCounterVec("test_total", "Test.", "host")
+	for i := 0; i < DefaultMaxSeries+10; i++ {
+		requests.Inc(fmt.Sprintf("host%d.localhost", i))
+	}
+	requests.Inc("host0.localhost")
+
+	got := scrape(t, r)
+	if n := strings.Count(got, "test_total{"); n != DefaultMaxSeries+1 {
+		t.Errorf("expected %d series, got %d", DefaultMaxSeries+1, n)
+	}
+	if !strings.Contains(got, `test_total{host="other"} 10`) {
+		t.Error("expected observations past the limit to be counted as other")
+	}
+	if !strings.Contains(got, `test_total{host="host0.localhost"} 2`) {
+		t.Error("expected existing series to keep counting")
+	}
+}
+
+func TestRegistry_PanicsOnLabelMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for missing label value")
+		}
+	}()
+	NewRegistry().NewCounterVec("test_total", "Test.", "host", "code").Inc("a.localhost")
+}
+
+func TestServer(t *testing.T) {
+	ObserveHTTPRequest("app.localhost", http.StatusOK, 20*time.Millisecond)
+	closed := TCPConnectionOpened("postgres")
+	defer closed()
+
+	server := NewServer("127.0.0.1:0", Default)
+	if err := server.Start(); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer server.Stop()
+
+	resp, err := http.Get("http://" + server.Addr() + Path)
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("expected text/plain content type, got %q", ct)
+	}
+	for _, want := range []string{
+		`devproxy_http_requests_total{code="200",host="app.localhost"}`,
+		`devproxy_http_request_duration_seconds_bucket{host="app.localhost",le="0.025"}`,
+		`devproxy_tcp_connections_active{entrypoint="postgres"} 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("expected %s in:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Path is the URL path metrics are served on.
+const Path = "/metrics"
+
+// Server serves a registry's metrics over HTTP.
+type Server struct {
+	addr     string
+	registry *Registry
+	server   *http.Server
+	listener net.Listener
+}
+
+// NewServer creates a server exposing registry on addr (e.g., "127.0.0.1:9477").
+func NewServer(addr string, registry *Registry) *Server {
+	return &Server{
+		addr:     addr,
+		registry: registry,
+	}
+}
+
+// Start begins listening for scrape requests.
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	s.listener = listener
+
+	mux := http.NewServeMux()
+	mux.Handle(Path, s.registry.Handler())
+	s.server = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Error("metrics server error", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+// Stop gracefully shuts down the server.
+func (s *Server) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.server.Shutdown(ctx)
+}
+
+// Addr returns the address the server is listening on.
+// Returns empty string if not started.
+func (s *Server) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
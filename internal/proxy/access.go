@@ -0,0 +1,72 @@
+// Package proxy provides HTTP/HTTPS proxy functionality including client access control.
+package proxy
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// ParseCIDRList parses a comma-separated list of CIDRs (e.g., "10.0.0.0/8, ::1/128").
+// Bare IP addresses are accepted and treated as single-host prefixes.
+// Returns nil for an empty list.
+func ParseCIDRList(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			prefix, err := netip.ParsePrefix(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR %q: %w", entry, err)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q: %w", entry, err)
+		}
+		addr = addr.Unmap()
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// AllowsClient reports whether a client IP may access this route.
+// Deny entries take precedence over allow entries; an empty allow list allows all clients.
+// Unparseable client IPs are rejected when any access list is configured.
+func (r *Route) AllowsClient(clientIP string) bool {
+	if len(r.AllowCIDRs) == 0 && len(r.DenyCIDRs) == 0 {
+		return true
+	}
+
+	addr, err := netip.ParseAddr(clientIP)
+	if err != nil {
+		return false
+	}
+	// Treat IPv4-mapped IPv6 clients (::ffff:a.b.c.d) as IPv4
+	addr = addr.Unmap()
+
+	if containsAddr(r.DenyCIDRs, addr) {
+		return false
+	}
+	if len(r.AllowCIDRs) == 0 {
+		return true
+	}
+	return containsAddr(r.AllowCIDRs, addr)
+}
+
+// containsAddr checks if any prefix contains the address.
+func containsAddr(prefixes []netip.Prefix, addr netip.Addr) bool {
+	for _, prefix := range prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
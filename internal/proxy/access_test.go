@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"net/netip"
+	"testing"
+)
+
+func TestParseCIDRList(t *testing.T) {
+	t.Run("parses CIDRs and bare IPs", func(t *testing.T) {
+		prefixes, err := ParseCIDRList("10.0.0.0/8, 192.168.1.5, fd00::/8, ::1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		want := []string{"10.0.0.0/8", "192.168.1.5/32", "fd00::/8", "::1/128"}
+		if len(prefixes) != len(want) {
+			t.Fatalf("expected %d prefixes, got %d", len(want), len(prefixes))
+		}
+		for i, p := range prefixes {
+			if p.String() != want[i] {
+				t.Errorf("prefix %d: expected %s, got %s", i, want[i], p.String())
+			}
+		}
+	})
+
+	t.Run("returns nil for empty list", func(t *testing.T) {
+		prefixes, err := ParseCIDRList("")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if prefixes != nil {
+			t.Errorf("expected nil, got %v", prefixes)
+		}
+	})
+
+	t.Run("rejects invalid entries", func(t *testing.T) {
+		for _, list := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0.0/8, bogus"} {
+			if _, err := ParseCIDRList(list); err == nil {
+				t.Errorf("expected error for %q", list)
+			}
+		}
+	})
+}
+
+func TestRoute_AllowsClient(t *testing.T) {
+	mustParse := func(list string) []netip.Prefix {
+		prefixes, err := ParseCIDRList(list)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", list, err)
+		}
+		return prefixes
+	}
+
+	tests := []struct {
+		name     string
+		allow    string
+		deny     string
+		clientIP string
+		want     bool
+	}{
+		{name: "no lists allows all", clientIP: "203.0.113.7", want: true},
+		{name: "allowed IPv4", allow: "192.168.0.0/16", clientIP: "192.168.1.20", want: true},
+		{name: "not in allow list", allow: "192.168.0.0/16", clientIP: "10.1.2.3", want: false},
+		{name: "denied IPv4", deny: "10.0.0.0/8", clientIP: "10.1.2.3", want: false},
+		{name: "not in deny list", deny: "10.0.0.0/8", clientIP: "192.168.1.20", want: true},
+		{name: "deny takes precedence", allow: "10.0.0.0/8", deny: "10.0.0.5", clientIP: "10.0.0.5", want: false},
+		{name: "allowed IPv6", allow: "fd00::/8", clientIP: "fd12::1", want: true},
+		{name: "denied IPv6", deny: "::1", clientIP: "::1", want: false},
+		{name: "IPv6 not in IPv4 allow list", allow: "127.0.0.0/8", clientIP: "::1", want: false},
+		{name: "IPv4-mapped IPv6 matches IPv4 list", allow: "127.0.0.0/8", clientIP: "::ffff:127.0.0.1", want: true},
+		{name: "invalid client IP rejected", allow: "127.0.0.0/8", clientIP: "garbage", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := Route{
+				Host:       "app.localhost",
+				AllowCIDRs: mustParse(tt.allow),
+				DenyCIDRs:  mustParse(tt.deny),
+			}
+
+			if got := route.AllowsClient(tt.clientIP); got != tt.want {
+				t.Errorf("AllowsClient(%q) = %v, want %v", tt.clientIP, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// maxVerified caps the credentials basicAuth remembers. Only credentials that
+// passed are stored, so the map is emptied when full rather than tracking
+// which entry is oldest.
+const maxVerified = 64
+
+// ParseBasicAuth parses a comma-separated list of user:bcrypthash entries
+// (e.g., as generated by "htpasswd -nbB user password") into a map from user
+// name to hash. Returns nil for an empty list.
+func ParseBasicAuth(list string) (map[string]string, error) {
+	var users map[string]string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		user, hash, ok := strings.Cut(entry, ":")
+		if !ok || user == "" {
+			return nil, fmt.Errorf("invalid entry %q: want user:bcrypthash", entry)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("invalid bcrypt hash for user %s: %w", user, err)
+		}
+		if _, exists := users[user]; exists {
+			return nil, fmt.Errorf("duplicate user %s", user)
+		}
+
+		if users == nil {
+			users = make(map[string]string)
+		}
+		users[user] = hash
+	}
+	return users, nil
+}
+
+// basicAuth verifies basic auth credentials against bcrypt hashes. Hashing
+// is deliberately slow, so verified credentials are remembered.
+type basicAuth struct {
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]struct{} // sha256 of hash and password
+}
+
+// check reports whether r carries the credentials of one of users.
+func (a *basicAuth) check(r *http.Request, users map[string]string) bool {
+	user, password, ok := r.BasicAuth()
+	if !ok || len(users) == 0 {
+		return false
+	}
+
+	// Compare every name so the response time does not reveal which users exist
+	var hash string
+	for name, h := range users {
+		if subtle.ConstantTimeCompare([]byte(name), []byte(user)) == 1 {
+			hash = h
+		}
+	}
+	if hash == "" {
+		// Take as long as a wrong password would
+		for _, h := range users {
+			_ = bcrypt.CompareHashAndPassword([]byte(h), []byte(password))
+			break
+		}
+		return false
+	}
+
+	key := sha256.Sum256([]byte(hash + "\x00" + password))
+	a.mu.Lock()
+	_, ok = a.verified[key]
+	a.mu.Unlock()
+	if ok {
+		return true
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return false
+	}
+
+	a.mu.Lock()
+	if a.verified == nil || len(a.verified) >= maxVerified {
+		a.verified = make(map[[sha256.Size]byte]struct{})
+	}
+	a.verified[key] = struct{}{}
+	a.mu.Unlock()
+	return true
+}
+
+// requireBasicAuth answers a request without valid credentials for host.
+func requireBasicAuth(w http.ResponseWriter, host string) {
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf("Basic realm=%q, charset=\"UTF-8\"", host))
+	http.Error(w, fmt.Sprintf("authentication required for %s", host), http.StatusUnauthorized)
+}
@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// secretHash is the bcrypt hash of "secret" at the minimum cost.
+const secretHash = "$2a$04$gP52.y1YGIFV6mNMXvJ8t.SXtQk6yxzHFSmnStfoocOLK9HO/mQSi"
+
+func TestParseBasicAuth(t *testing.T) {
+	tests := []struct {
+		name      string
+		list      string
+		wantUsers []string
+		wantErr   bool
+	}{
+		{name: "empty", list: ""},
+		{name: "single user", list: "alice:" + secretHash, wantUsers: []string{"alice"}},
+		{name: "multiple users", list: "alice:" + secretHash + ", bob:" + secretHash, wantUsers: []string{"alice", "bob"}},
+		{name: "missing hash", list: "alice", wantErr: true},
+		{name: "missing user", list: ":" + secretHash, wantErr: true},
+		{name: "plain text password", list: "alice:secret", wantErr: true},
+		{name: "duplicate user", list: "alice:" + secretHash + ",alice:" + secretHash, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := ParseBasicAuth(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBasicAuth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(users) != len(tt.wantUsers) {
+				t.Fatalf("expected %d users, got %v", len(tt.wantUsers), users)
+			}
+			for _, user := range tt.wantUsers {
+				if users[user] != secretHash {
+					t.Errorf("expected hash for user %s, got %q", user, users[user])
+				}
+			}
+		})
+	}
+}
+
+func TestBasicAuth_Check(t *testing.T) {
+	users := map[string]string{"alice": secretHash, "bob": secretHash}
+
+	tests := []struct {
+		name     string
+		user     string
+		password string
+		noAuth   bool
+		want     bool
+	}{
+		{name: "valid credentials", user: "alice", password: "secret", want: true},
+		{name: "second user", user: "bob", password: "secret", want: true},
+		{name: "wrong password", user: "alice", password: "wrong"},
+		{name: "unknown user", user: "mallory", password: "secret"},
+		{name: "no credentials", noAuth: true},
+	}
+
+	var auth basicAuth
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if !tt.noAuth {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+			// Check twice so remembered credentials are covered too
+			for i := 0; i < 2; i++ {
+				if got := auth.check(req, users); got != tt.want {
+					t.Errorf("check() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestBasicAuth_VerifiedIsBounded(t *testing.T) {
+	var auth basicAuth
+	for i := range maxVerified + 1 {
+		password := fmt.Sprintf("secret%d", i)
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		if err != nil {
+			t.Fatalf("GenerateFromPassword() error = %v", err)
+		}
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.SetBasicAuth("alice", password)
+		if !auth.check(req, map[string]string{"alice": string(hash)}) {
+			t.Fatalf("check() = false for password %s", password)
+		}
+	}
+	if n := len(auth.verified); n > maxVerified {
+		t.Errorf("remembered %d credentials, want at most %d", n, maxVerified)
+	}
+}
@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// balancer picks backends round-robin, skipping unavailable ones.
+type balancer struct {
+	mu   sync.Mutex
+	next map[string]uint64 // route key -> round-robin counter
+}
+
+// newBalancer creates an empty balancer.
+func newBalancer() *balancer {
+	return &balancer{
+		next: make(map[string]uint64),
+	}
+}
+
+// pick returns the next available backend for key. It reports false if no
+// backend is available.
+func (b *balancer) pick(key string, backends []string, available func(string) bool) (string, bool) {
+	b.mu.Lock()
+	start := b.next[key]
+	b.next[key] = start + 1
+	b.mu.Unlock()
+
+	n := uint64(len(backends))
+	for i := uint64(0); i < n; i++ {
+		if backend := backends[(start+i)%n]; available(backend) {
+			return backend, true
+		}
+	}
+	return "", false
+}
+
+// StickyID returns the sticky session cookie value for backend. It is derived
+// from the address rather than the backend's position, so clients stay
+// pinned when other replicas come and go, and does not reveal the address.
+func StickyID(backend string) string {
+	h := fnv.New32a()
+	h.Write([]byte(backend))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
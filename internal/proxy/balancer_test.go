@@ -0,0 +1,49 @@
+package proxy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBalancer_Pick(t *testing.T) {
+	backends := []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80"}
+	all := func(string) bool { return true }
+
+	t.Run("rotates round-robin", func(t *testing.T) {
+		b := newBalancer()
+		for i, want := range []string{"10.0.0.1:80", "10.0.0.2:80", "10.0.0.3:80", "10.0.0.1:80"} {
+			if got, ok := b.pick("web.localhost", backends, all); !ok || got != want {
+				t.Errorf("pick %d = %q, %v, want %q", i, got, ok, want)
+			}
+		}
+	})
+
+	t.Run("skips unavailable backends", func(t *testing.T) {
+		b := newBalancer()
+		available := func(backend string) bool { return backend != "10.0.0.2:80" }
+		for i := 0; i < 6; i++ {
+			if got, _ := b.pick("web.localhost", backends, available); got == "10.0.0.2:80" {
+				t.Fatalf("pick %d returned an unavailable backend", i)
+			}
+		}
+	})
+
+	t.Run("reports when no backend is available", func(t *testing.T) {
+		b := newBalancer()
+		if _, ok := b.pick("web.localhost", backends, func(string) bool { return false }); ok {
+			t.Error("expected no backend when all are unavailable")
+		}
+	})
+}
+
+func TestStickyID(t *testing.T) {
+	if StickyID("10.0.0.1:80") != StickyID("10.0.0.1:80") {
+		t.Error("expected StickyID to be stable")
+	}
+	if StickyID("10.0.0.1:80") == StickyID("10.0.0.2:80") {
+		t.Error("expected different backends to get different ids")
+	}
+	if id := StickyID("10.0.0.1:80"); strings.Contains(id, "10.0.0.1") {
+		t.Errorf("expected id not to reveal the backend address, got %q", id)
+	}
+}
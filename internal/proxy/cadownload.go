@@ -0,0 +1,46 @@
+package proxy
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/munichmade/devproxy/internal/ca"
+)
+
+const (
+	// CADownloadPath is the path the CA certificate is served at on the HTTP entrypoint.
+	CADownloadPath = "/ca.crt"
+
+	// caCertContentType makes mobile browsers offer to install the certificate.
+	caCertContentType = "application/x-x509-ca-cert"
+)
+
+// isCADownload reports whether r asks for the CA certificate on host.
+func isCADownload(r *http.Request, host string) bool {
+	if host == "" || r.URL.Path != CADownloadPath {
+		return false
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	return strings.EqualFold(hostWithoutPort(r.Host), host)
+}
+
+// serveCACert writes the CA certificate in DER form, the encoding accepted by
+// iOS and Android certificate installers. Append ?format=pem for PEM.
+func serveCACert(w http.ResponseWriter, r *http.Request) {
+	rootCA, err := ca.Load()
+	if err != nil {
+		http.Error(w, "CA certificate not available", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", caCertContentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="devproxy-ca.crt"`)
+	w.Header().Set("Cache-Control", "no-store")
+	if r.URL.Query().Get("format") == "pem" {
+		w.Write(rootCA.CertPEM)
+		return
+	}
+	w.Write(rootCA.Certificate.Raw)
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/ca"
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+func TestHTTPServer_CADownload(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	rootCA, err := ca.Generate()
+	if err != nil {
+		t.Fatalf("failed to generate CA: %v", err)
+	}
+
+	server := NewHTTPServer("127.0.0.1:0", 443)
+	server.SetCAHost(func() string { return "proxy.localhost" })
+
+	tests := []struct {
+		name       string
+		host       string
+		target     string
+		wantStatus int
+		wantBody   []byte
+	}{
+		{name: "serves DER", host: "proxy.localhost", target: CADownloadPath, wantStatus: http.StatusOK, wantBody: rootCA.Certificate.Raw},
+		{name: "serves PEM", host: "proxy.localhost:80", target: CADownloadPath + "?format=pem", wantStatus: http.StatusOK, wantBody: rootCA.CertPEM},
+		{name: "redirects other paths", host: "proxy.localhost", target: "/", wantStatus: http.StatusMovedPermanently},
+		{name: "redirects other hosts", host: "app.localhost", target: CADownloadPath, wantStatus: http.StatusMovedPermanently},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Host = tt.host
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if tt.wantBody == nil {
+				return
+			}
+			if ct := w.Header().Get("Content-Type"); ct != "application/x-x509-ca-cert" {
+				t.Errorf("expected Content-Type application/x-x509-ca-cert, got %q", ct)
+			}
+			if !bytes.Equal(w.Body.Bytes(), tt.wantBody) {
+				t.Error("expected response body to be the CA certificate")
+			}
+		})
+	}
+}
+
+func TestHTTPServer_CADownloadDisabled(t *testing.T) {
+	server := NewHTTPServer("127.0.0.1:0", 443)
+	server.SetCAHost(func() string { return "" })
+
+	req := httptest.NewRequest(http.MethodGet, CADownloadPath, nil)
+	req.Host = "proxy.localhost"
+	w := httptest.NewRecorder()
+
+	server.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMovedPermanently {
+		t.Errorf("expected redirect when CA download is disabled, got %d", w.Code)
+	}
+}
@@ -0,0 +1,295 @@
+package proxy
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// DefaultCompressionMinSize is the smallest response body in bytes that is
+// compressed when no threshold is configured.
+const DefaultCompressionMinSize = 1024
+
+// Compressor wraps an http.Handler to gzip or deflate responses for clients
+// that accept it. Responses below the size threshold, already compressed
+// content types, event streams and protocol upgrades pass through unchanged.
+type Compressor struct {
+	handler   http.Handler
+	isEnabled func() bool
+	minSize   func() int64
+}
+
+// NewCompressor creates a new Compressor middleware. The isEnabled and minSize
+// functions are called on each request so configuration changes apply without
+// restarting. A nil minSize or a non-positive result selects
+// DefaultCompressionMinSize.
+func NewCompressor(handler http.Handler, isEnabled func() bool, minSize func() int64) *Compressor {
+	if isEnabled == nil {
+		isEnabled = func() bool { return true }
+	}
+	return &Compressor{
+		handler:   handler,
+		isEnabled: isEnabled,
+		minSize:   minSize,
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (c *Compressor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+	if !c.isEnabled() || encoding == "" || r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+		c.handler.ServeHTTP(w, r)
+		return
+	}
+
+	minSize := int64(DefaultCompressionMinSize)
+	if c.minSize != nil {
+		if size := c.minSize(); size > 0 {
+			minSize = size
+		}
+	}
+
+	cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize}
+	defer cw.close()
+	c.handler.ServeHTTP(cw, r)
+}
+
+// negotiateEncoding returns the preferred encoding the client accepts
+// ("gzip" or "deflate"), or an empty string if it accepts neither.
+func negotiateEncoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(part, ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+
+		switch name {
+		case "gzip", "deflate":
+			qualities[name] = q
+		case "*":
+			wildcard = q
+		}
+	}
+
+	best, bestQ := "", 0.0
+	for _, encoding := range []string{"gzip", "deflate"} {
+		q, ok := qualities[encoding]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoding, q
+		}
+	}
+	return best
+}
+
+// isCompressedType reports whether contentType is already compressed or a
+// stream that must not be held back, so compressing it would not help.
+func isCompressedType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(contentType))
+	}
+
+	switch {
+	case mediaType == "image/svg+xml":
+		return false
+	case strings.HasPrefix(mediaType, "image/"),
+		strings.HasPrefix(mediaType, "video/"),
+		strings.HasPrefix(mediaType, "audio/"):
+		return true
+	}
+
+	switch mediaType {
+	case "application/zip", "application/gzip", "application/x-gzip", "application/zstd",
+		"application/x-bzip2", "application/x-xz", "application/x-7z-compressed",
+		"application/x-rar-compressed", "application/octet-stream", "application/pdf",
+		"font/woff", "font/woff2", "text/event-stream":
+		return true
+	}
+	return false
+}
+
+// compressWriter holds back the start of a response body until it knows
+// whether the response is worth compressing, then either compresses the
+// body or passes it through unchanged.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int64
+
+	status  int    // status passed to WriteHeader (0 = not yet called)
+	buf     []byte // body held back until the decision
+	decided bool
+	enc     io.WriteCloser // compressing writer, nil if the response passes through
+}
+
+// WriteHeader records the status code until the compression decision is made.
+// Informational responses are sent immediately.
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.decided || cw.status != 0 {
+		return
+	}
+	if statusCode >= 100 && statusCode <= 199 {
+		cw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+	cw.status = statusCode
+	if !bodyAllowed(statusCode) {
+		cw.decide()
+	}
+}
+
+// Write buffers the body until the size threshold is reached.
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		if cw.status == 0 {
+			cw.status = http.StatusOK
+		}
+		cw.buf = append(cw.buf, b...)
+		if int64(len(cw.buf)) < cw.minSize {
+			return len(b), nil
+		}
+		if err := cw.decide(); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+// Flush sends everything written so far. A flush before the threshold is
+// reached decides with the data at hand, so streaming responses keep flowing.
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.enc.(interface{ Flush() error }); ok {
+		if err := flusher.Flush(); err != nil {
+			return
+		}
+	}
+	if flusher, ok := cw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap returns the underlying ResponseWriter for middleware compatibility.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// Hijack implements http.Hijacker for WebSocket support.
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if hijacker, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		return hijacker.Hijack()
+	}
+	return nil, nil, fmt.Errorf("underlying ResponseWriter does not support hijacking")
+}
+
+// decide writes the response header, compressing the body if it is worth it,
+// and then writes the held back body.
+func (cw *compressWriter) decide() error {
+	cw.decided = true
+	if cw.status == 0 {
+		cw.status = http.StatusOK
+	}
+
+	h := cw.Header()
+	if cw.shouldCompress() {
+		if h.Get("Content-Type") == "" {
+			// Sniff before compression hides the content
+			h.Set("Content-Type", http.DetectContentType(cw.buf))
+		}
+		// The backend's length, byte ranges and strong validator describe
+		// the uncompressed body
+		h.Del("Content-Length")
+		h.Del("Accept-Ranges")
+		if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			h.Set("ETag", "W/"+etag)
+		}
+		h.Set("Content-Encoding", cw.encoding)
+		h.Add("Vary", "Accept-Encoding")
+
+		if cw.encoding == "gzip" {
+			cw.enc = gzip.NewWriter(cw.ResponseWriter)
+		} else {
+			cw.enc = zlib.NewWriter(cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(cw.status)
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.enc != nil {
+		_, err = cw.enc.Write(buf)
+	} else {
+		_, err = cw.ResponseWriter.Write(buf)
+	}
+	return err
+}
+
+// shouldCompress reports whether the response is compressed. The size is
+// taken from Content-Length if the backend set it, otherwise from the body
+// held back so far.
+func (cw *compressWriter) shouldCompress() bool {
+	if !bodyAllowed(cw.status) || cw.status == http.StatusPartialContent {
+		return false
+	}
+
+	h := cw.Header()
+	if h.Get("Content-Encoding") != "" ||
+		strings.Contains(strings.ToLower(h.Get("Cache-Control")), "no-transform") ||
+		isCompressedType(h.Get("Content-Type")) {
+		return false
+	}
+
+	size := int64(len(cw.buf))
+	if length, err := strconv.ParseInt(h.Get("Content-Length"), 10, 64); err == nil {
+		size = length
+	}
+	return size >= cw.minSize
+}
+
+// close finishes the response once the handler returns.
+func (cw *compressWriter) close() {
+	if !cw.decided {
+		if err := cw.decide(); err != nil {
+			return
+		}
+	}
+	if cw.enc != nil {
+		cw.enc.Close()
+	}
+}
+
+// bodyAllowed reports whether a response with status may have a body.
+func bodyAllowed(status int) bool {
+	return status >= 200 && status != http.StatusNoContent && status != http.StatusNotModified
+}
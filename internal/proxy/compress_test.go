@@ -0,0 +1,273 @@
+package proxy
+
+import (
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"gzip, deflate, br", "gzip"},
+		{"deflate", "deflate"},
+		{"deflate;q=1.0, gzip;q=0.5", "deflate"},
+		{"gzip;q=0, deflate", "deflate"},
+		{"br", ""},
+		{"*", "gzip"},
+		{"*, gzip;q=0", "deflate"},
+		{"identity", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			if got := negotiateEncoding(tt.header); got != tt.want {
+				t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsCompressedType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        bool
+	}{
+		{"application/json", false},
+		{"text/html; charset=utf-8", false},
+		{"image/svg+xml", false},
+		{"image/png", true},
+		{"video/mp4", true},
+		{"application/zip", true},
+		{"font/woff2", true},
+		{"text/event-stream", true},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := isCompressedType(tt.contentType); got != tt.want {
+				t.Errorf("isCompressedType(%q) = %v, want %v", tt.contentType, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompressor(t *testing.T) {
+	large := strings.Repeat(`{"id":1,"name":"devproxy"},`, 100)
+
+	serve := func(handler http.HandlerFunc, enabled bool, acceptEncoding string) *httptest.ResponseRecorder {
+		c := NewCompressor(handler, func() bool { return enabled }, func() int64 { return 0 })
+		req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, req)
+		return rec
+	}
+	jsonHandler := func(body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(body))
+		}
+	}
+
+	t.Run("gzips large responses", func(t *testing.T) {
+		rec := serve(jsonHandler(large), true, "gzip, deflate")
+
+		if rec.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected gzip encoding, got %q", rec.Header().Get("Content-Encoding"))
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("expected Vary: Accept-Encoding, got %q", rec.Header().Get("Vary"))
+		}
+		if rec.Body.Len() >= len(large) {
+			t.Errorf("expected compressed body smaller than %d bytes, got %d", len(large), rec.Body.Len())
+		}
+		zr, err := gzip.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("gzip.NewReader: %v", err)
+		}
+		if body, _ := io.ReadAll(zr); string(body) != large {
+			t.Error("expected decompressed body to match the original")
+		}
+	})
+
+	t.Run("deflates when gzip is not accepted", func(t *testing.T) {
+		rec := serve(jsonHandler(large), true, "deflate")
+
+		if rec.Header().Get("Content-Encoding") != "deflate" {
+			t.Fatalf("expected deflate encoding, got %q", rec.Header().Get("Content-Encoding"))
+		}
+		zr, err := zlib.NewReader(rec.Body)
+		if err != nil {
+			t.Fatalf("zlib.NewReader: %v", err)
+		}
+		if body, _ := io.ReadAll(zr); string(body) != large {
+			t.Error("expected decompressed body to match the original")
+		}
+	})
+
+	t.Run("adjusts headers describing the uncompressed body", func(t *testing.T) {
+		rec := serve(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Length", "2700")
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(large))
+		}, true, "gzip")
+
+		if rec.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatalf("expected gzip encoding, got %q", rec.Header().Get("Content-Encoding"))
+		}
+		if rec.Header().Get("Content-Length") != "" {
+			t.Errorf("expected no Content-Length, got %q", rec.Header().Get("Content-Length"))
+		}
+		if rec.Header().Get("Accept-Ranges") != "" {
+			t.Errorf("expected no Accept-Ranges, got %q", rec.Header().Get("Accept-Ranges"))
+		}
+		if etag := rec.Header().Get("ETag"); etag != `W/"v1"` {
+			t.Errorf("expected weak ETag W/\"v1\", got %q", etag)
+		}
+	})
+
+	t.Run("keeps weak ETags", func(t *testing.T) {
+		rec := serve(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("ETag", `W/"v1"`)
+			w.Write([]byte(large))
+		}, true, "gzip")
+
+		if etag := rec.Header().Get("ETag"); etag != `W/"v1"` {
+			t.Errorf("expected ETag W/\"v1\", got %q", etag)
+		}
+	})
+
+	tests := []struct {
+		name           string
+		handler        http.HandlerFunc
+		enabled        bool
+		acceptEncoding string
+		wantStatus     int
+	}{
+		{name: "disabled", handler: jsonHandler(large), acceptEncoding: "gzip"},
+		{name: "client does not accept compression", handler: jsonHandler(large), enabled: true},
+		{name: "small response", handler: jsonHandler(`{"ok":true}`), enabled: true, acceptEncoding: "gzip"},
+		{
+			name: "already compressed content type",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				w.Write([]byte(large))
+			},
+			enabled:        true,
+			acceptEncoding: "gzip",
+		},
+		{
+			name: "backend already encoded the body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Encoding", "br")
+				w.Write([]byte(large))
+			},
+			enabled:        true,
+			acceptEncoding: "gzip",
+		},
+		{
+			name: "no body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			enabled:        true,
+			acceptEncoding: "gzip",
+			wantStatus:     http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run("passes through "+tt.name, func(t *testing.T) {
+			rec := serve(tt.handler, tt.enabled, tt.acceptEncoding)
+
+			wantStatus := tt.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+			if rec.Code != wantStatus {
+				t.Errorf("expected status %d, got %d", wantStatus, rec.Code)
+			}
+			if encoding := rec.Header().Get("Content-Encoding"); encoding == "gzip" {
+				t.Error("expected response not to be gzipped")
+			}
+		})
+	}
+
+	t.Run("streams flushed responses", func(t *testing.T) {
+		release := make(chan struct{})
+		c := NewCompressor(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Write([]byte("data: first\n\n"))
+			w.(http.Flusher).Flush()
+			<-release
+		}), nil, nil)
+		server := httptest.NewServer(c)
+		defer server.Close()
+		defer close(release)
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultTransport.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		defer resp.Body.Close()
+
+		line, err := bufio.NewReader(resp.Body).ReadString('\n')
+		if err != nil || line != "data: first\n" {
+			t.Errorf("expected first event before the handler returns, got %q, %v", line, err)
+		}
+	})
+
+	t.Run("does not break WebSocket upgrades", func(t *testing.T) {
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			if mt, msg, err := conn.ReadMessage(); err == nil {
+				conn.WriteMessage(mt, msg)
+			}
+		}))
+		defer backend.Close()
+
+		registry := NewRegistry()
+		registry.Add(Route{Host: "127.0.0.1", Backend: strings.TrimPrefix(backend.URL, "http://"), Protocol: ProtocolHTTP})
+		server := httptest.NewServer(NewCompressor(NewProxyHandler(registry), nil, nil))
+		defer server.Close()
+
+		header := http.Header{"Accept-Encoding": []string{"gzip"}}
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), header)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		conn.WriteMessage(websocket.TextMessage, []byte("hello"))
+		if _, msg, err := conn.ReadMessage(); err != nil || string(msg) != "hello" {
+			t.Errorf("expected echo, got %q, %v", msg, err)
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+)
+
+// routeContextKey is the context key of the matchedRoute a request's route
+// is recorded in.
+type routeContextKey struct{}
+
+// matchedRoute is filled in by ReverseProxy.ServeHTTP, so middleware wrapping
+// it can tell which route and backend served a request.
+type matchedRoute struct {
+	route   *Route
+	backend string
+}
+
+// withMatchedRoute returns r with an empty matchedRoute in its context, or r
+// itself if an outer handler already added one.
+func withMatchedRoute(r *http.Request) (*http.Request, *matchedRoute) {
+	if m, ok := r.Context().Value(routeContextKey{}).(*matchedRoute); ok {
+		return r, m
+	}
+	m := &matchedRoute{}
+	return r.WithContext(context.WithValue(r.Context(), routeContextKey{}, m)), m
+}
+
+// RouteFromContext returns the route that matched r and the backend selected
+// for it. The route is nil if the request was not routed, or if ServeHTTP has
+// not looked it up yet; the backend is empty until one is selected.
+// Middleware wrapping the proxy sees the result once the proxy returns.
+func RouteFromContext(r *http.Request) (*Route, string) {
+	m, ok := r.Context().Value(routeContextKey{}).(*matchedRoute)
+	if !ok {
+		return nil, ""
+	}
+	return m.route, m.backend
+}
+
+// containerName returns the name of the container serving backend for the
+// route, which differs from ContainerName for replicas of a scaled service.
+func (r *Route) containerName(backend string) string {
+	for _, rep := range r.replicaSet() {
+		if rep.backend == backend {
+			return rep.containerName
+		}
+	}
+	return r.ContainerName
+}
@@ -0,0 +1,50 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRouteFromContext(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer backend.Close()
+	backendAddr := strings.TrimPrefix(backend.URL, "http://")
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "app.localhost", Backend: "127.0.0.1:1", Protocol: ProtocolHTTP, ContainerName: "web-1"})
+	registry.AddBackend(Route{Host: "app.localhost", Backend: backendAddr, Protocol: ProtocolHTTP, ContainerID: "c2", ContainerName: "web-2"})
+	registry.SetBackendHealthy("127.0.0.1:1", false)
+	rp := NewReverseProxy(registry)
+
+	t.Run("records route and selected backend", func(t *testing.T) {
+		var route *Route
+		var selected string
+		outer := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, _ = withMatchedRoute(r)
+			rp.ServeHTTP(w, r)
+			route, selected = RouteFromContext(r)
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+		outer.ServeHTTP(httptest.NewRecorder(), req)
+
+		if route == nil || route.Host != "app.localhost" {
+			t.Fatalf("expected matched route app.localhost, got %+v", route)
+		}
+		if selected != backendAddr {
+			t.Errorf("expected selected backend %s, got %q", backendAddr, selected)
+		}
+		if name := route.containerName(selected); name != "web-2" {
+			t.Errorf("expected container of the selected replica, got %q", name)
+		}
+	})
+
+	t.Run("empty without a recording handler", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+		if route, backend := RouteFromContext(req); route != nil || backend != "" {
+			t.Errorf("expected no route, got %+v, %q", route, backend)
+		}
+	})
+}
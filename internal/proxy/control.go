@@ -0,0 +1,232 @@
+package proxy
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+// Control commands accepted on the control socket. Unlike the query socket,
+// the control socket changes the registry; like it, only the user running
+// the daemon may connect, since the socket is created with mode 0600.
+const (
+	ControlList   = "list"
+	ControlAdd    = "add"
+	ControlRemove = "remove"
+
+	// ControlEnable and ControlDisable switch the routes of a host on and off
+	// without removing them.
+	ControlEnable  = "enable"
+	ControlDisable = "disable"
+
+	// ControlImport adds the routes of a 'devproxy route export' file.
+	ControlImport = "import"
+
+	// ControlCertCache lists the certificates in the daemon's cache,
+	// ControlCertCacheClear clears it, ControlCertRescan picks up certificates
+	// written by 'devproxy domain add' and ControlCAReload loads the CA again
+	// after 'devproxy ca rotate'. The daemon registers them with Handle.
+	ControlCertCache      = "cert_cache"
+	ControlCertCacheClear = "cert_cache_clear"
+	ControlCertRescan     = "cert_rescan"
+	ControlCAReload       = "ca_reload"
+
+	// ControlDNSFlush drops the upstream answers cached by the DNS server.
+	ControlDNSFlush = "dns_flush"
+)
+
+// controlTimeout bounds a Control call. It is longer than queryTimeout since
+// commands like ControlCAReload reissue certificates before answering.
+const controlTimeout = 10 * time.Second
+
+// ErrNotManualRoute is returned when removing a route that was not added at
+// runtime; the Docker sync or the next config reload would restore it.
+var ErrNotManualRoute = errors.New("route was not added with 'devproxy route add' or 'devproxy route import'")
+
+// ControlSocket returns the path to the daemon's control socket.
+func ControlSocket() string {
+	return filepath.Join(paths.RuntimeDir(), "devproxy-control.sock")
+}
+
+// NewControlServer creates a server answering control commands for the given
+// registry. Routes it adds are manual routes and last until the daemon stops.
+func NewControlServer(registry *Registry, logger *slog.Logger) *QueryServer {
+	s := NewQueryServer(registry, logger)
+	s.handler = s.handleControl
+	return s
+}
+
+// Handle makes the control server answer cmd with fn, for commands acting on
+// other parts of the daemon than the registry. A failed command reports the
+// error of fn.
+func (s *QueryServer) Handle(cmd string, fn func(QueryRequest) (QueryResponse, error)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.controls == nil {
+		s.controls = make(map[string]func(QueryRequest) (QueryResponse, error))
+	}
+	s.controls[cmd] = fn
+}
+
+// handleControl answers a single control request.
+func (s *QueryServer) handleControl(req QueryRequest) QueryResponse {
+	switch req.Cmd {
+	case ControlList:
+		return QueryResponse{Routes: s.registry.List()}
+	case ControlAdd:
+		if req.Route == nil {
+			return QueryResponse{Error: "add requires a route"}
+		}
+		if err := s.addRoute(*req.Route); err != nil {
+			return QueryResponse{Error: err.Error()}
+		}
+		return QueryResponse{}
+	case ControlRemove:
+		if req.Host == "" {
+			return QueryResponse{Error: "remove requires a host"}
+		}
+		if err := s.removeRoute(req.Host); err != nil {
+			return QueryResponse{Error: err.Error()}
+		}
+		return QueryResponse{}
+	case ControlImport:
+		return QueryResponse{Skipped: s.importRoutes(req.Routes)}
+	case ControlEnable, ControlDisable:
+		if req.Host == "" {
+			return QueryResponse{Error: req.Cmd + " requires a host"}
+		}
+		if err := s.setEnabled(req.Host, req.Cmd == ControlEnable); err != nil {
+			return QueryResponse{Error: err.Error()}
+		}
+		return QueryResponse{}
+	}
+
+	s.mu.Lock()
+	fn := s.controls[req.Cmd]
+	s.mu.Unlock()
+	if fn == nil {
+		return QueryResponse{Error: fmt.Sprintf("unknown command %q", req.Cmd)}
+	}
+	resp, err := fn(req)
+	if err != nil {
+		return QueryResponse{Error: err.Error()}
+	}
+	return resp
+}
+
+// addRoute adds route as a manual route.
+func (s *QueryServer) addRoute(route Route) error {
+	route.Host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(route.Host)), ".")
+	if route.Host == "" {
+		return errors.New("route host is empty")
+	}
+	if route.ContainerID != "" {
+		return fmt.Errorf("%s: %w", route.Host, ErrDockerRoute)
+	}
+	if route.Protocol == "" {
+		route.Protocol = ProtocolHTTP
+	}
+	route.Ready = true
+	route.Source = SourceManual
+	if err := s.registry.Add(route); err != nil {
+		return fmt.Errorf("%s: %w", route.Host, err)
+	}
+	s.logger.Info("route added", "host", route.Host, "backend", route.Backend, "protocol", route.Protocol)
+	return nil
+}
+
+// importRoutes adds routes as manual routes. Routes that cannot be added,
+// e.g. because the host already has a route, are skipped; it returns why.
+func (s *QueryServer) importRoutes(routes []Route) []string {
+	var skipped []string
+	for _, route := range routes {
+		if err := s.addRoute(route); err != nil {
+			skipped = append(skipped, err.Error())
+		}
+	}
+	return skipped
+}
+
+// removeRoute removes the manual routes of host. Routes of the host from
+// other sources are left alone and reported as ErrNotManualRoute.
+func (s *QueryServer) removeRoute(host string) error {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+
+	found := false
+	for _, route := range s.registry.List() {
+		if !strings.EqualFold(route.Host, host) {
+			continue
+		}
+		found = true
+		if route.Source != SourceManual {
+			return fmt.Errorf("%s: %w (%s route)", host, ErrNotManualRoute, cmp.Or(route.Origin(), "unknown"))
+		}
+	}
+	if !found {
+		return fmt.Errorf("%s: %w", host, ErrRouteNotFound)
+	}
+
+	if err := s.registry.Remove(host); err != nil {
+		return fmt.Errorf("%s: %w", host, err)
+	}
+	s.logger.Info("route removed", "host", host)
+	return nil
+}
+
+// Control sends req to the daemon's control socket and returns its response.
+func Control(req QueryRequest) (QueryResponse, error) {
+	return queryWithin(ControlSocket(), req, controlTimeout)
+}
+
+// setEnabled enables or disables the routes of host.
+func (s *QueryServer) setEnabled(host string, enabled bool) error {
+	host = normalizeHost(host)
+	if err := s.registry.SetEnabled(host, enabled); err != nil {
+		return fmt.Errorf("%s: %w", host, err)
+	}
+	s.logger.Info("route toggled", "host", host, "enabled", enabled)
+	return nil
+}
+
+// AddRoute asks the daemon to add route through the control socket.
+func AddRoute(route Route) error {
+	_, err := Control(QueryRequest{Cmd: ControlAdd, Route: &route})
+	return err
+}
+
+// RemoveRoute asks the daemon to remove the routes of host added at runtime
+// through the control socket.
+func RemoveRoute(host string) error {
+	_, err := Control(QueryRequest{Cmd: ControlRemove, Host: host})
+	return err
+}
+
+// ImportRoutes asks the daemon to add routes through the control socket and
+// returns why the routes it skipped were not added.
+func ImportRoutes(routes []Route) ([]string, error) {
+	resp, err := Control(QueryRequest{Cmd: ControlImport, Routes: routes})
+	return resp.Skipped, err
+}
+
+// SetRouteEnabled asks the daemon to enable or disable the routes of host
+// through the control socket.
+func SetRouteEnabled(host string, enabled bool) error {
+	cmd := ControlDisable
+	if enabled {
+		cmd = ControlEnable
+	}
+	_, err := Control(QueryRequest{Cmd: cmd, Host: host})
+	return err
+}
+
+// ListRoutes returns the daemon's routes through the control socket.
+func ListRoutes() ([]Route, error) {
+	resp, err := Control(QueryRequest{Cmd: ControlList})
+	return resp.Routes, err
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newControlTestServer(t *testing.T) (*QueryServer, *Registry) {
+	t.Helper()
+	r := NewRegistry()
+	for _, route := range []Route{
+		{Host: "web.localhost", Backend: "172.18.0.2:80", ContainerID: "abc123", Source: SourceDocker},
+		{Host: "grafana.localhost", Backend: "127.0.0.1:3000", Source: SourceConfig},
+	} {
+		if err := r.Add(route); err != nil {
+			t.Fatalf("Add(%s) error = %v", route.Host, err)
+		}
+	}
+	return NewControlServer(r, slog.New(slog.NewTextHandler(io.Discard, nil))), r
+}
+
+func TestControlServer_Add(t *testing.T) {
+	server, registry := newControlTestServer(t)
+	client := newQueryClient(t, server)
+
+	resp := client.do(`{"cmd":"add","route":{"Host":"API.localhost","Backend":"127.0.0.1:8080"}}`)
+	if resp.Error != "" {
+		t.Fatalf("add error = %s", resp.Error)
+	}
+	route := registry.Lookup("api.localhost")
+	if route == nil {
+		t.Fatal("expected route to be added")
+	}
+	if route.Source != SourceManual || route.Protocol != ProtocolHTTP || !route.Ready {
+		t.Errorf("expected ready manual HTTP route, got %+v", route)
+	}
+
+	tests := []struct {
+		name    string
+		raw     string
+		wantErr string
+	}{
+		{name: "existing host", raw: `{"cmd":"add","route":{"Host":"grafana.localhost","Backend":"127.0.0.1:4000"}}`, wantErr: ErrRouteExists.Error()},
+		{name: "invalid backend", raw: `{"cmd":"add","route":{"Host":"new.localhost","Backend":"127.0.0.1"}}`, wantErr: "invalid backend"},
+		{name: "docker route", raw: `{"cmd":"add","route":{"Host":"new.localhost","Backend":"127.0.0.1:80","ContainerID":"def456"}}`, wantErr: ErrDockerRoute.Error()},
+		{name: "missing host", raw: `{"cmd":"add","route":{"Backend":"127.0.0.1:80"}}`, wantErr: "host is empty"},
+		{name: "missing route", raw: `{"cmd":"add"}`, wantErr: "requires a route"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := client.do(tt.raw); !strings.Contains(resp.Error, tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", resp.Error, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestControlServer_Remove(t *testing.T) {
+	server, registry := newControlTestServer(t)
+	client := newQueryClient(t, server)
+
+	if resp := client.do(`{"cmd":"add","route":{"Host":"api.localhost","Backend":"127.0.0.1:8080"}}`); resp.Error != "" {
+		t.Fatalf("add error = %s", resp.Error)
+	}
+	if resp := client.do(`{"cmd":"remove","host":"API.localhost"}`); resp.Error != "" {
+		t.Fatalf("remove error = %s", resp.Error)
+	}
+	if route := registry.Lookup("api.localhost"); route != nil {
+		t.Errorf("expected route to be removed, got %+v", route)
+	}
+
+	tests := []struct {
+		name    string
+		host    string
+		wantErr string
+	}{
+		{name: "docker route", host: "web.localhost", wantErr: "docker route"},
+		{name: "config route", host: "grafana.localhost", wantErr: "config route"},
+		{name: "unknown host", host: "missing.localhost", wantErr: ErrRouteNotFound.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := client.do(`{"cmd":"remove","host":"` + tt.host + `"}`); !strings.Contains(resp.Error, tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", resp.Error, tt.wantErr)
+			}
+		})
+	}
+	if registry.Count() != 2 {
+		t.Errorf("expected refused removals to keep the routes, got %d routes", registry.Count())
+	}
+}
+
+func TestControlServer_Import(t *testing.T) {
+	server, registry := newControlTestServer(t)
+	client := newQueryClient(t, server)
+
+	resp := client.do(`{"cmd":"import","routes":[` +
+		`{"Host":"web.localhost","Backend":"127.0.0.1:3000"},` +
+		`{"Host":"api.localhost","Backend":"127.0.0.1:8080"},` +
+		`{"Host":"app.localhost","Backend":"172.18.0.3:80","ContainerID":"def456"}]}`)
+	if resp.Error != "" {
+		t.Fatalf("import error = %s", resp.Error)
+	}
+	if len(resp.Skipped) != 2 ||
+		!strings.Contains(resp.Skipped[0], ErrRouteExists.Error()) ||
+		!strings.Contains(resp.Skipped[1], ErrDockerRoute.Error()) {
+		t.Errorf("skipped = %q, want the taken host and the container route", resp.Skipped)
+	}
+
+	if route := registry.Lookup("web.localhost"); route.ContainerID != "abc123" {
+		t.Errorf("expected existing route to be kept, got %+v", route)
+	}
+	if route := registry.Lookup("api.localhost"); route == nil || route.Source != SourceManual {
+		t.Errorf("expected api.localhost to be imported as a manual route, got %+v", route)
+	}
+	if route := registry.Lookup("app.localhost"); route != nil {
+		t.Error("expected container route not to be imported")
+	}
+}
+
+func TestControlServer_Toggle(t *testing.T) {
+	server, registry := newControlTestServer(t)
+	client := newQueryClient(t, server)
+
+	if resp := client.do(`{"cmd":"disable","host":"Grafana.localhost"}`); resp.Error != "" {
+		t.Fatalf("disable error = %s", resp.Error)
+	}
+	if route := registry.Lookup("grafana.localhost"); route == nil || !route.Disabled {
+		t.Errorf("expected grafana.localhost to be disabled, got %+v", route)
+	}
+	if resp := client.do(`{"cmd":"enable","host":"grafana.localhost"}`); resp.Error != "" {
+		t.Fatalf("enable error = %s", resp.Error)
+	}
+	if route := registry.Lookup("grafana.localhost"); route == nil || route.Disabled {
+		t.Errorf("expected grafana.localhost to be enabled, got %+v", route)
+	}
+
+	if resp := client.do(`{"cmd":"disable","host":"missing.localhost"}`); !strings.Contains(resp.Error, ErrRouteNotFound.Error()) {
+		t.Errorf("error = %q, want it to contain %q", resp.Error, ErrRouteNotFound)
+	}
+	if resp := client.do(`{"cmd":"enable"}`); !strings.Contains(resp.Error, "requires a host") {
+		t.Errorf("error = %q, want a missing host", resp.Error)
+	}
+}
+
+func TestControlServer_Handle(t *testing.T) {
+	server, _ := newControlTestServer(t)
+	server.Handle(ControlCAReload, func(QueryRequest) (QueryResponse, error) {
+		return QueryResponse{Reissued: 3}, nil
+	})
+	server.Handle(ControlCertCacheClear, func(QueryRequest) (QueryResponse, error) {
+		return QueryResponse{}, errors.New("disk full")
+	})
+	client := newQueryClient(t, server)
+
+	if resp := client.do(`{"cmd":"ca_reload"}`); resp.Error != "" || resp.Reissued != 3 {
+		t.Errorf("ca_reload = %+v, want 3 reissued", resp)
+	}
+	if resp := client.do(`{"cmd":"cert_cache_clear"}`); resp.Error != "disk full" {
+		t.Errorf("cert_cache_clear error = %q, want the handler's error", resp.Error)
+	}
+	if resp := client.do(`{"cmd":"cert_cache"}`); !strings.Contains(resp.Error, "unknown command") {
+		t.Errorf("cert_cache error = %q, want an unknown command", resp.Error)
+	}
+}
+
+func TestQueryServer_RejectsControlCommands(t *testing.T) {
+	registry := newQueryTestRegistry(t)
+	client := newQueryClient(t, NewQueryServer(registry, nil))
+
+	if resp := client.do(`{"cmd":"add","route":{"Host":"new.localhost","Backend":"127.0.0.1:80"}}`); resp.Error == "" {
+		t.Error("expected the query socket to refuse adding routes")
+	}
+	if registry.Count() != 3 {
+		t.Error("expected the query socket to leave the registry unchanged")
+	}
+}
+
+func TestControlServer_ListenUnix(t *testing.T) {
+	// Unix socket paths are length limited, so avoid the long t.TempDir path
+	dir, err := os.MkdirTemp("", "dpc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "devproxy-control.sock")
+
+	server, _ := newControlTestServer(t)
+	if err := server.ListenUnix(socket); err != nil {
+		t.Fatalf("ListenUnix() error = %v", err)
+	}
+	defer server.Close()
+
+	// Only the user running the daemon may change routes
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("socket mode = %o, want 600", perm)
+	}
+
+	if _, err := query(socket, QueryRequest{Cmd: ControlAdd, Route: &Route{Host: "api.localhost", Backend: "127.0.0.1:8080"}}); err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	resp, err := query(socket, QueryRequest{Cmd: ControlList})
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	if len(resp.Routes) != 3 {
+		t.Errorf("expected 3 routes, got %d", len(resp.Routes))
+	}
+	if _, err := query(socket, QueryRequest{Cmd: ControlRemove, Host: "grafana.localhost"}); err == nil {
+		t.Error("expected removing a config route to fail")
+	}
+}
@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrDockerRoute is returned when an imported route names a Docker container.
+var ErrDockerRoute = errors.New("route belongs to a Docker container")
+
+// exportOmitted are Route fields left out of exported routes. They are
+// computed when a route is added or belong to its Docker container.
+var exportOmitted = []string{
+	"IsWildcard", "Pattern", "Source", "ContainerID", "ContainerName",
+	"ProjectName", "ProjectDir", "Ready", "CreatedAt",
+}
+
+// ManualRoutes returns the routes not backed by a Docker container, sorted by
+// host. Docker routes come and go with their containers, so they are not shared.
+func ManualRoutes(routes []Route) []Route {
+	var manual []Route
+	for _, route := range routes {
+		if route.ContainerID == "" {
+			manual = append(manual, route)
+		}
+	}
+	sort.Slice(manual, func(i, j int) bool {
+		if manual[i].Host != manual[j].Host {
+			return manual[i].Host < manual[j].Host
+		}
+		return manual[i].Entrypoint < manual[j].Entrypoint
+	})
+	return manual
+}
+
+// ExportRoutes encodes routes as a RouteState document in format ("json" or
+// "yaml"), using the JSON field names of Route in both. Empty fields are
+// left out.
+func ExportRoutes(routes []Route, format string) ([]byte, error) {
+	data, err := json.Marshal(RouteState{Routes: routes})
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers are decoded as json.Number so durations are not written as floats
+	var doc struct {
+		Routes []map[string]any `json:"routes"`
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Routes == nil {
+		doc.Routes = []map[string]any{}
+	}
+	for _, route := range doc.Routes {
+		for _, field := range exportOmitted {
+			delete(route, field)
+		}
+		for field, value := range route {
+			switch value := value.(type) {
+			case nil:
+				delete(route, field) // e.g., AllowCIDRs: null
+			case string:
+				if value == "" {
+					delete(route, field)
+				}
+			case json.Number:
+				route[field] = yamlNumber(value)
+			}
+		}
+	}
+
+	switch format {
+	case "json":
+		out, err := json.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(out, '\n'), nil
+	case "yaml":
+		return yaml.Marshal(map[string]any{"routes": doc.Routes})
+	default:
+		return nil, fmt.Errorf("unsupported format %q (use json or yaml)", format)
+	}
+}
+
+// yamlNumber converts n to an int64 or float64, which YAML writes as numbers
+// instead of strings.
+func yamlNumber(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
+}
+
+// ParseRoutes decodes routes written by ExportRoutes. YAML is a superset of
+// JSON, so both formats are accepted.
+func ParseRoutes(data []byte) ([]Route, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid routes file: %w", err)
+	}
+
+	// Decode through JSON so fields keep the names and types of Route's JSON form
+	encoded, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid routes file: %w", err)
+	}
+	var state RouteState
+	if err := json.Unmarshal(encoded, &state); err != nil {
+		return nil, fmt.Errorf("invalid routes file: %w", err)
+	}
+	return state.Routes, nil
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"cmp"
+	"io"
+	"log/slog"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportRoutes_RoundTrip(t *testing.T) {
+	source := NewRegistry()
+	source.Add(Route{Host: "grafana.localhost", Backend: "127.0.0.1:3000", Timeout: 90 * time.Second, Ready: true})
+	source.Add(Route{
+		Host:            "api.localhost",
+		PathPrefix:      "/v1",
+		StripPrefix:     "/v1",
+		Backend:         "127.0.0.1:8080",
+		AllowCIDRs:      []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+		RequestHeaders:  map[string]string{"X-Env": "dev"},
+		ResponseHeaders: map[string]string{"Server": ""},
+	})
+	source.Add(Route{Host: "*.docs.localhost", Backend: "127.0.0.1:4000"})
+	source.Add(Route{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: ProtocolTCP, Entrypoint: "postgres"})
+	source.Add(Route{Host: "web.localhost", Backend: "172.18.0.2:80", ContainerID: "abc123", ContainerName: "web"})
+
+	manual := ManualRoutes(source.List())
+	if len(manual) != 4 {
+		t.Fatalf("expected 4 manual routes, got %d", len(manual))
+	}
+
+	// comparable drops the fields that are not exported and defaults the
+	// protocol like the control server does
+	comparable := func(routes []Route) []Route {
+		out := make([]Route, len(routes))
+		for i, route := range routes {
+			route.Ready, route.CreatedAt, route.Source = false, time.Time{}, ""
+			route.Protocol = cmp.Or(route.Protocol, ProtocolHTTP)
+			out[i] = route
+		}
+		return out
+	}
+
+	for _, format := range []string{"yaml", "json"} {
+		t.Run(format, func(t *testing.T) {
+			data, err := ExportRoutes(manual, format)
+			if err != nil {
+				t.Fatalf("ExportRoutes() error = %v", err)
+			}
+			for _, field := range []string{"CreatedAt", "Ready", "IsWildcard", "web.localhost"} {
+				if strings.Contains(string(data), field) {
+					t.Errorf("expected export not to contain %s:\n%s", field, data)
+				}
+			}
+
+			routes, err := ParseRoutes(data)
+			if err != nil {
+				t.Fatalf("ParseRoutes() error = %v", err)
+			}
+			target := NewRegistry()
+			server := NewControlServer(target, slog.New(slog.NewTextHandler(io.Discard, nil)))
+			if skipped := server.importRoutes(routes); len(skipped) != 0 {
+				t.Fatalf("importRoutes() skipped %v", skipped)
+			}
+
+			imported := ManualRoutes(target.List())
+			if !reflect.DeepEqual(comparable(imported), comparable(manual)) {
+				t.Errorf("imported routes differ:\ngot  %+v\nwant %+v", imported, manual)
+			}
+			for _, route := range imported {
+				if !route.Ready {
+					t.Errorf("expected imported route %s to be ready", route.Host)
+				}
+				if route.Source != SourceManual {
+					t.Errorf("expected imported route %s to have source %s, got %q", route.Host, SourceManual, route.Source)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRoutes_Invalid(t *testing.T) {
+	if _, err := ParseRoutes([]byte("routes: [")); err == nil {
+		t.Error("expected error for malformed file")
+	}
+	if _, err := ParseRoutes([]byte(`{"routes": [{"Host": 1}]}`)); err == nil {
+		t.Error("expected error for wrongly typed field")
+	}
+}
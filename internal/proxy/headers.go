@@ -0,0 +1,68 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ParseHeaderList parses a semicolon-separated list of headers
+// (e.g., "X-Frame-Options=DENY;Access-Control-Allow-Origin=*"). Names are
+// canonicalized; an empty value (e.g., "Server=") removes the header.
+// Returns nil for an empty list.
+func ParseHeaderList(list string) (map[string]string, error) {
+	var headers map[string]string
+	for _, entry := range strings.Split(list, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid header %q: want Name=Value", entry)
+		}
+		name = strings.TrimSpace(name)
+		if !validHeaderName(name) {
+			return nil, fmt.Errorf("invalid header name %q", name)
+		}
+		value = strings.TrimSpace(value)
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("invalid value for header %s: must not contain line breaks", name)
+		}
+
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers[http.CanonicalHeaderKey(name)] = value
+	}
+	return headers, nil
+}
+
+// validHeaderName reports whether name is a valid HTTP header field name (an RFC 7230 token).
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", c):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// applyHeaders sets headers on h, replacing existing values. Empty values
+// remove the header.
+func applyHeaders(h http.Header, headers map[string]string) {
+	for name, value := range headers {
+		if value == "" {
+			h.Del(name)
+		} else {
+			h.Set(name, value)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func TestParseHeaderList(t *testing.T) {
+	tests := []struct {
+		name    string
+		list    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "empty", list: "", want: nil},
+		{
+			name: "multiple headers",
+			list: "X-Frame-Options=DENY; access-control-allow-origin=*",
+			want: map[string]string{"X-Frame-Options": "DENY", "Access-Control-Allow-Origin": "*"},
+		},
+		{name: "value containing equals sign", list: "Content-Security-Policy=default-src 'self'; a=b", want: map[string]string{"Content-Security-Policy": "default-src 'self'", "A": "b"}},
+		{name: "empty value removes", list: "Server=", want: map[string]string{"Server": ""}},
+		{name: "trailing separator", list: "X-Test=1;", want: map[string]string{"X-Test": "1"}},
+		{name: "missing value", list: "X-Frame-Options", wantErr: true},
+		{name: "invalid name", list: "X Frame=DENY", wantErr: true},
+		{name: "empty name", list: "=DENY", wantErr: true},
+		{name: "line break in value", list: "X-Test=a\r\nSet-Cookie: x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseHeaderList(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseHeaderList(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseHeaderList(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-Frame-Options", "SAMEORIGIN")
+	h.Set("Server", "nginx")
+
+	applyHeaders(h, map[string]string{"X-Frame-Options": "DENY", "Server": "", "X-New": "1"})
+
+	if got := h.Values("X-Frame-Options"); len(got) != 1 || got[0] != "DENY" {
+		t.Errorf("expected X-Frame-Options to be replaced with DENY, got %v", got)
+	}
+	if h.Get("Server") != "" {
+		t.Errorf("expected Server to be removed, got %q", h.Get("Server"))
+	}
+	if h.Get("X-New") != "1" {
+		t.Errorf("expected X-New to be added, got %q", h.Get("X-New"))
+	}
+}
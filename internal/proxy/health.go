@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultFailureThreshold is the number of consecutive connection
+	// failures after which a backend is ejected.
+	DefaultFailureThreshold = 3
+
+	// DefaultEjectCooldown is how long an ejected backend is skipped before
+	// a request is sent to it again.
+	DefaultEjectCooldown = 10 * time.Second
+)
+
+// healthTracker counts consecutive connection failures per backend and
+// ejects backends that reach the threshold until the cooldown has passed.
+// Once it has, the next request is a trial: success clears the failures,
+// another failure ejects the backend again. Backends failing an active
+// health check are unavailable until it passes.
+type healthTracker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	backends  map[string]*backendHealth
+	unhealthy map[string]bool         // backends failing their active health check
+	checks    map[string]*HealthStats // results of active health checks
+}
+
+// HealthStats are the results of a backend's active health checks.
+type HealthStats struct {
+	Healthy bool `json:"healthy"` // whether the last check passed
+
+	// Latency is how long the last check took.
+	Latency time.Duration `json:"latency"`
+
+	// ConsecutiveSuccesses and ConsecutiveFailures count the checks since the
+	// result last changed; one of them is always zero.
+	ConsecutiveSuccesses int `json:"consecutive_successes"`
+	ConsecutiveFailures  int `json:"consecutive_failures"`
+
+	LastCheck time.Time `json:"last_check"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// backendHealth is the failure state of one backend.
+type backendHealth struct {
+	failures  int
+	ejectedAt time.Time
+}
+
+// newHealthTracker creates a tracker using the default policy.
+func newHealthTracker() *healthTracker {
+	return &healthTracker{
+		threshold: DefaultFailureThreshold,
+		cooldown:  DefaultEjectCooldown,
+		backends:  make(map[string]*backendHealth),
+		unhealthy: make(map[string]bool),
+		checks:    make(map[string]*HealthStats),
+	}
+}
+
+// setPolicy sets the failure threshold and cooldown. Zero values select the defaults.
+func (h *healthTracker) setPolicy(threshold int, cooldown time.Duration) {
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultEjectCooldown
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.threshold = threshold
+	h.cooldown = cooldown
+}
+
+// failure records a connection failure and reports whether it ejected backend.
+func (h *healthTracker) failure(backend string, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, ok := h.backends[backend]
+	if !ok {
+		state = &backendHealth{}
+		h.backends[backend] = state
+	}
+	state.failures++
+	if state.failures < h.threshold {
+		return false
+	}
+	state.ejectedAt = now
+	return true
+}
+
+// success clears the failures recorded for backend.
+func (h *healthTracker) success(backend string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.backends, backend)
+}
+
+// setHealthy records the result of an active health check of backend.
+func (h *healthTracker) setHealthy(backend string, healthy bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if healthy {
+		delete(h.unhealthy, backend)
+	} else {
+		h.unhealthy[backend] = true
+	}
+}
+
+// recordCheck records the result of an active health check of backend that
+// took latency and failed with err, or passed if err is nil.
+func (h *healthTracker) recordCheck(backend string, latency time.Duration, err error, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	stats, ok := h.checks[backend]
+	if !ok {
+		stats = &HealthStats{}
+		h.checks[backend] = stats
+	}
+	stats.Healthy = err == nil
+	stats.Latency = latency
+	stats.LastCheck = now
+	if err == nil {
+		stats.ConsecutiveSuccesses++
+		stats.ConsecutiveFailures = 0
+		stats.LastError = ""
+		delete(h.unhealthy, backend)
+	} else {
+		stats.ConsecutiveFailures++
+		stats.ConsecutiveSuccesses = 0
+		stats.LastError = err.Error()
+		h.unhealthy[backend] = true
+	}
+}
+
+// checkStats returns the active health check results of backend.
+func (h *healthTracker) checkStats(backend string) (HealthStats, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	stats, ok := h.checks[backend]
+	if !ok {
+		return HealthStats{}, false
+	}
+	return *stats, true
+}
+
+// allCheckStats returns the active health check results by backend.
+func (h *healthTracker) allCheckStats() map[string]HealthStats {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	all := make(map[string]HealthStats, len(h.checks))
+	for backend, stats := range h.checks {
+		all[backend] = *stats
+	}
+	return all
+}
+
+// forgetCheck removes the active health check state of backend.
+func (h *healthTracker) forgetCheck(backend string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.unhealthy, backend)
+	delete(h.checks, backend)
+}
+
+// available reports whether requests may be sent to backend.
+func (h *healthTracker) available(backend string, now time.Time) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.unhealthy[backend] {
+		return false
+	}
+	state, ok := h.backends[backend]
+	if !ok || state.failures < h.threshold {
+		return true
+	}
+	return now.Sub(state.ejectedAt) >= h.cooldown
+}
+
+// healthTransport reports each backend connection failure or success to the
+// registry's health tracking.
+type healthTransport struct {
+	base     http.RoundTripper
+	registry *Registry
+	backend  string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *healthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	switch {
+	case err == nil:
+		t.registry.ReportSuccess(t.backend)
+	case isDialError(err):
+		if t.registry.ReportFailure(t.backend) {
+			slog.Warn("backend ejected after repeated connection failures",
+				"host", req.Host,
+				"backend", t.backend,
+				"error", err)
+		}
+	}
+	return resp, err
+}
+
+// isDialError reports whether err means the backend could not be connected to.
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestHealthTracker(t *testing.T) {
+	const backend = "172.18.0.2:3000"
+	now := time.Now()
+
+	t.Run("ejects after consecutive failures", func(t *testing.T) {
+		h := newHealthTracker()
+		h.setPolicy(3, time.Minute)
+
+		for i := 1; i <= 3; i++ {
+			if !h.available(backend, now) {
+				t.Fatalf("expected backend available before failure %d", i)
+			}
+			if ejected := h.failure(backend, now); ejected != (i == 3) {
+				t.Errorf("failure %d: ejected = %v", i, ejected)
+			}
+		}
+		if h.available(backend, now) {
+			t.Error("expected backend to be ejected")
+		}
+	})
+
+	t.Run("success resets failures", func(t *testing.T) {
+		h := newHealthTracker()
+		h.setPolicy(2, time.Minute)
+
+		h.failure(backend, now)
+		h.success(backend)
+		h.failure(backend, now)
+		if !h.available(backend, now) {
+			t.Error("expected failures to be counted from the last success")
+		}
+	})
+
+	t.Run("retries after cooldown", func(t *testing.T) {
+		h := newHealthTracker()
+		h.setPolicy(1, time.Minute)
+
+		h.failure(backend, now)
+		if h.available(backend, now.Add(30*time.Second)) {
+			t.Error("expected backend to be ejected during cooldown")
+		}
+		later := now.Add(time.Minute)
+		if !h.available(backend, later) {
+			t.Fatal("expected a trial request after cooldown")
+		}
+
+		// A failed trial ejects the backend again
+		h.failure(backend, later)
+		if h.available(backend, later) {
+			t.Error("expected failed trial to eject backend again")
+		}
+	})
+
+	t.Run("unhealthy backend is unavailable until it passes", func(t *testing.T) {
+		h := newHealthTracker()
+
+		h.setHealthy(backend, false)
+		if h.available(backend, now) {
+			t.Error("expected unhealthy backend to be unavailable")
+		}
+		h.setHealthy(backend, true)
+		if !h.available(backend, now) {
+			t.Error("expected backend available after passing health check")
+		}
+	})
+
+	t.Run("zero policy uses defaults", func(t *testing.T) {
+		h := newHealthTracker()
+		h.setPolicy(0, 0)
+		if h.threshold != DefaultFailureThreshold || h.cooldown != DefaultEjectCooldown {
+			t.Errorf("expected default policy, got threshold %d cooldown %v", h.threshold, h.cooldown)
+		}
+	})
+}
+
+func TestHealthTracker_RecordCheck(t *testing.T) {
+	const backend = "172.18.0.2:3000"
+	now := time.Now()
+	errFailing := errors.New("GET /healthz returned 503 Service Unavailable")
+
+	h := newHealthTracker()
+	if _, ok := h.checkStats(backend); ok {
+		t.Fatal("expected no stats before the first check")
+	}
+
+	// pass, pass, pass, fail, fail, pass
+	steps := []struct {
+		err           error
+		wantHealthy   bool
+		wantSuccesses int
+		wantFailures  int
+	}{
+		{nil, true, 1, 0},
+		{nil, true, 2, 0},
+		{nil, true, 3, 0},
+		{errFailing, false, 0, 1},
+		{errFailing, false, 0, 2},
+		{nil, true, 1, 0},
+	}
+	for i, step := range steps {
+		latency := time.Duration(i+1) * time.Millisecond
+		h.recordCheck(backend, latency, step.err, now)
+
+		stats, ok := h.checkStats(backend)
+		if !ok {
+			t.Fatalf("check %d: expected stats", i+1)
+		}
+		if stats.Healthy != step.wantHealthy ||
+			stats.ConsecutiveSuccesses != step.wantSuccesses ||
+			stats.ConsecutiveFailures != step.wantFailures {
+			t.Errorf("check %d: got healthy=%v successes=%d failures=%d, want %v %d %d", i+1,
+				stats.Healthy, stats.ConsecutiveSuccesses, stats.ConsecutiveFailures,
+				step.wantHealthy, step.wantSuccesses, step.wantFailures)
+		}
+		if stats.Latency != latency {
+			t.Errorf("check %d: latency = %v, want %v", i+1, stats.Latency, latency)
+		}
+		if (stats.LastError != "") != (step.err != nil) {
+			t.Errorf("check %d: unexpected last error %q", i+1, stats.LastError)
+		}
+		if h.available(backend, now) != step.wantHealthy {
+			t.Errorf("check %d: expected available = %v", i+1, step.wantHealthy)
+		}
+	}
+
+	if all := h.allCheckStats(); len(all) != 1 || !all[backend].Healthy {
+		t.Errorf("expected stats of one healthy backend, got %+v", all)
+	}
+
+	h.recordCheck(backend, time.Millisecond, errFailing, now)
+	h.forgetCheck(backend)
+	if _, ok := h.checkStats(backend); ok {
+		t.Error("expected stats to be forgotten")
+	}
+	if !h.available(backend, now) {
+		t.Error("expected forgotten backend to be available")
+	}
+}
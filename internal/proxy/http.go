@@ -7,7 +7,10 @@ import (
 	"log/slog"
 	"net"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/munichmade/devproxy/internal/listen"
 )
 
 // HTTPServer handles HTTP requests and redirects them to HTTPS.
@@ -16,6 +19,13 @@ type HTTPServer struct {
 	httpsPort int
 	server    *http.Server
 	listener  net.Listener
+
+	// caHost returns the host serving the CA certificate download (optional)
+	caHost func() string
+
+	// registry and handler serve routes in HTTPProxy mode (optional)
+	registry *Registry
+	handler  http.Handler
 }
 
 // NewHTTPServer creates a new HTTP server that redirects to HTTPS.
@@ -37,11 +47,25 @@ func NewHTTPServerWithListener(listener net.Listener, httpsPort int) *HTTPServer
 	}
 }
 
+// SetCAHost sets a function returning the host on which the CA certificate is
+// served at CADownloadPath instead of redirecting, so devices that cannot
+// run the CLI can install it. An empty host disables the download.
+func (s *HTTPServer) SetCAHost(host func() string) {
+	s.caHost = host
+}
+
+// SetProxy sets the handler serving plain HTTP requests for routes of
+// registry in HTTPProxy mode. Requests for other routes are redirected.
+func (s *HTTPServer) SetProxy(registry *Registry, handler http.Handler) {
+	s.registry = registry
+	s.handler = handler
+}
+
 // Start begins listening for HTTP requests.
 func (s *HTTPServer) Start() error {
 	// If no listener was provided, create one
 	if s.listener == nil {
-		listener, err := net.Listen("tcp", s.addr)
+		listener, err := listen.TCP(s.addr, 0)
 		if err != nil {
 			return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
 		}
@@ -85,8 +109,25 @@ func (s *HTTPServer) Addr() string {
 	return s.listener.Addr().String()
 }
 
-// ServeHTTP handles incoming HTTP requests by redirecting to HTTPS.
+// ServeHTTP handles incoming HTTP requests by redirecting to HTTPS, or by
+// proxying them for routes in HTTPProxy mode. CONNECT requests (from browsers
+// configured via a PAC file) are tunneled to the HTTPS entrypoint.
 func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		s.handleConnect(w, r)
+		return
+	}
+
+	if s.caHost != nil && isCADownload(r, s.caHost()) {
+		serveCACert(w, r)
+		return
+	}
+
+	if s.proxiesPlainHTTP(r) {
+		s.handler.ServeHTTP(w, r)
+		return
+	}
+
 	// Build the HTTPS URL preserving the original path and query
 	host := r.Host
 
@@ -112,3 +153,62 @@ func (s *HTTPServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	http.Redirect(w, r, redirectURL, statusCode)
 }
+
+// proxiesPlainHTTP reports whether r is for a route in HTTPProxy mode.
+func (s *HTTPServer) proxiesPlainHTTP(r *http.Request) bool {
+	if s.registry == nil || s.handler == nil {
+		return false
+	}
+	route := s.registry.LookupPath(hostWithoutPort(r.Host), r.URL.Path)
+	return route != nil && route.HTTP == HTTPProxy
+}
+
+// handleConnect tunnels a CONNECT request to the local HTTPS entrypoint.
+// Only HTTPS ports are accepted and the tunnel always targets devproxy's own
+// HTTPS listener, so the HTTP entrypoint cannot be used as an open proxy.
+func (s *HTTPServer) handleConnect(w http.ResponseWriter, r *http.Request) {
+	_, port, err := net.SplitHostPort(r.Host)
+	if err != nil || (port != "443" && port != strconv.Itoa(s.httpsPort)) {
+		http.Error(w, "CONNECT is only supported to the HTTPS port", http.StatusForbidden)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "CONNECT not supported", http.StatusInternalServerError)
+		return
+	}
+
+	backend, err := net.DialTimeout("tcp", fmt.Sprintf("127.0.0.1:%d", s.httpsPort), tcpDialTimeout)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to connect to HTTPS entrypoint: %v", err), http.StatusBadGateway)
+		return
+	}
+	defer backend.Close()
+
+	client, buffered, err := hijacker.Hijack()
+	if err != nil {
+		slog.Error("failed to hijack CONNECT request", "error", err)
+		return
+	}
+	defer client.Close()
+
+	// Clear the server's read/write timeouts for the long-lived tunnel
+	_ = client.SetDeadline(time.Time{})
+
+	if _, err := client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n")); err != nil {
+		return
+	}
+
+	// Forward any bytes the client sent along with the CONNECT request
+	if n := buffered.Reader.Buffered(); n > 0 {
+		data, _ := buffered.Reader.Peek(n)
+		if _, err := backend.Write(data); err != nil {
+			return
+		}
+	}
+
+	if err := ProxyTCP(client, backend); err != nil {
+		slog.Debug("CONNECT tunnel error", "host", r.Host, "error", err)
+	}
+}
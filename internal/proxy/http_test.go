@@ -1,9 +1,15 @@
 package proxy
 
 import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHTTPServer_RedirectToHTTPS(t *testing.T) {
@@ -112,6 +118,53 @@ func TestHTTPServer_NonStandardHTTPSPort(t *testing.T) {
 	}
 }
 
+func TestHTTPServer_ProxyMode(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "proto=%s", r.Header.Get("X-Forwarded-Proto"))
+	}))
+	defer backend.Close()
+	backendAddr := strings.TrimPrefix(backend.URL, "http://")
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "hooks.localhost", Backend: backendAddr, Protocol: ProtocolHTTP, HTTP: HTTPProxy})
+	registry.Add(Route{Host: "app.localhost", Backend: backendAddr, Protocol: ProtocolHTTP})
+
+	server := NewHTTPServer("127.0.0.1:0", 443)
+	server.SetProxy(registry, NewProxyHandler(registry))
+
+	t.Run("proxies flagged host", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+		req.Host = "hooks.localhost"
+		w := httptest.NewRecorder()
+
+		server.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		if body := w.Body.String(); body != "proto=http" {
+			t.Errorf("expected backend response for plain HTTP, got %q", body)
+		}
+	})
+
+	for _, host := range []string{"app.localhost", "unknown.localhost"} {
+		t.Run("redirects "+host, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = host
+			w := httptest.NewRecorder()
+
+			server.ServeHTTP(w, req)
+
+			if w.Code != http.StatusMovedPermanently {
+				t.Errorf("expected status %d, got %d", http.StatusMovedPermanently, w.Code)
+			}
+			if location := w.Header().Get("Location"); location != "https://"+host+"/" {
+				t.Errorf("expected redirect to HTTPS, got %q", location)
+			}
+		})
+	}
+}
+
 func TestHTTPServer_StartStop(t *testing.T) {
 	server := NewHTTPServer("127.0.0.1:0", 443)
 
@@ -147,3 +200,72 @@ func TestHTTPServer_StartStop(t *testing.T) {
 		t.Errorf("failed to stop server: %v", err)
 	}
 }
+
+func TestHTTPServer_ConnectTunnel(t *testing.T) {
+	t.Run("tunnels to the HTTPS entrypoint", func(t *testing.T) {
+		// Stand-in for the HTTPS entrypoint: echo whatever is received
+		httpsListener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		defer httpsListener.Close()
+		go func() {
+			conn, err := httpsListener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			io.Copy(conn, conn)
+		}()
+
+		httpsPort := httpsListener.Addr().(*net.TCPAddr).Port
+		server := NewHTTPServer("127.0.0.1:0", httpsPort)
+		if err := server.Start(); err != nil {
+			t.Fatalf("failed to start server: %v", err)
+		}
+		defer server.Stop()
+
+		conn, err := net.Dial("tcp", server.Addr())
+		if err != nil {
+			t.Fatalf("failed to dial: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		fmt.Fprintf(conn, "CONNECT app.localhost:%d HTTP/1.1\r\nHost: app.localhost:%d\r\n\r\n", httpsPort, httpsPort)
+
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodConnect})
+		if err != nil {
+			t.Fatalf("failed to read CONNECT response: %v", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", resp.StatusCode)
+		}
+
+		if _, err := conn.Write([]byte("ping")); err != nil {
+			t.Fatalf("failed to write through tunnel: %v", err)
+		}
+		buf := make([]byte, 4)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			t.Fatalf("failed to read through tunnel: %v", err)
+		}
+		if string(buf) != "ping" {
+			t.Errorf("expected echo %q, got %q", "ping", string(buf))
+		}
+	})
+
+	t.Run("rejects non-HTTPS ports", func(t *testing.T) {
+		server := NewHTTPServer("127.0.0.1:0", 443)
+
+		req := httptest.NewRequest(http.MethodConnect, "http://example.com:22", nil)
+		req.Host = "example.com:22"
+		w := httptest.NewRecorder()
+
+		server.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", w.Code)
+		}
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/listen"
 )
 
 // HTTPSServer is an HTTPS server with dynamic certificate generation.
@@ -19,6 +20,8 @@ type HTTPSServer struct {
 	server      *http.Server
 	listener    net.Listener
 	handler     http.Handler
+	tickets     *SessionTicketKeys
+	tlsConfig   *tls.Config
 }
 
 // NewHTTPSServer creates a new HTTPS server.
@@ -42,6 +45,12 @@ func NewHTTPSServerWithListener(listener net.Listener, certManager *cert.Manager
 	}
 }
 
+// SetSessionTicketKeys enables TLS session resumption using shared rotating keys.
+// Must be called before Start.
+func (s *HTTPSServer) SetSessionTicketKeys(keys *SessionTicketKeys) {
+	s.tickets = keys
+}
+
 // Start starts the HTTPS server in the background.
 func (s *HTTPSServer) Start() error {
 	// Create TLS config with dynamic certificate generation
@@ -51,6 +60,10 @@ func (s *HTTPSServer) Start() error {
 		// Enable HTTP/2 by default
 		NextProtos: []string{"h2", "http/1.1"},
 	}
+	if s.tickets != nil {
+		s.tickets.Apply(tlsConfig)
+	}
+	s.tlsConfig = tlsConfig
 
 	s.server = &http.Server{
 		Addr:      s.addr,
@@ -65,7 +78,7 @@ func (s *HTTPSServer) Start() error {
 
 	// If no listener was provided, create one
 	if s.listener == nil {
-		ln, err := net.Listen("tcp", s.addr)
+		ln, err := listen.TCP(s.addr, 0)
 		if err != nil {
 			return fmt.Errorf("failed to listen on %s: %w", s.addr, err)
 		}
@@ -90,6 +103,9 @@ func (s *HTTPSServer) Stop() error {
 	if s.server == nil {
 		return nil
 	}
+	if s.tickets != nil {
+		s.tickets.Release(s.tlsConfig)
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
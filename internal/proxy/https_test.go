@@ -192,3 +192,67 @@ func TestHTTPSServer_HTTP2(t *testing.T) {
 		t.Errorf("expected HTTP/2, got %s", resp.Proto)
 	}
 }
+
+func TestHTTPSServer_SessionResumption(t *testing.T) {
+	mgr := setupTestCA(t)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	keys, err := NewSessionTicketKeys(0)
+	if err != nil {
+		t.Fatalf("failed to create session ticket keys: %v", err)
+	}
+
+	server := NewHTTPSServer("127.0.0.1:0", mgr, handler)
+	server.SetSessionTicketKeys(keys)
+
+	if err := server.Start(); err != nil {
+		t.Fatalf("failed to start server: %v", err)
+	}
+	defer server.Stop()
+
+	addr := server.Addr()
+
+	// Disable keep-alives so each request performs a new handshake
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: true,
+				ServerName:         "reload.localhost",
+				ClientSessionCache: tls.NewLRUClientSessionCache(8),
+			},
+			DisableKeepAlives: true,
+		},
+	}
+
+	get := func() *tls.ConnectionState {
+		req, _ := http.NewRequest("GET", "https://"+addr+"/", nil)
+		req.Host = "reload.localhost"
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("failed to make request: %v", err)
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		return resp.TLS
+	}
+
+	if state := get(); state.DidResume {
+		t.Error("expected first connection to perform a full handshake")
+	}
+
+	if state := get(); !state.DidResume {
+		t.Error("expected second connection to resume the TLS session")
+	}
+
+	// Tickets issued before a rotation remain valid
+	if err := keys.Rotate(); err != nil {
+		t.Fatalf("failed to rotate keys: %v", err)
+	}
+	if state := get(); !state.DidResume {
+		t.Error("expected session to resume after key rotation")
+	}
+}
@@ -45,6 +45,9 @@ func (a *AccessLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	start := time.Now()
 
+	// Let the proxy record which route served the request
+	r, matched := withMatchedRoute(r)
+
 	// Wrap the response writer to capture status and size
 	wrapped := &responseRecorder{
 		ResponseWriter: w,
@@ -52,19 +55,35 @@ func (a *AccessLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		bytesWritten:   0,
 	}
 
+	// Log from a deferred call so requests that abort mid-response (e.g., a
+	// backend that dies while streaming, which makes httputil.ReverseProxy
+	// panic with http.ErrAbortHandler) still produce an access log entry
+	defer func() {
+		var extra []any
+		if matched.route != nil {
+			extra = append(extra, "route", matched.route.Host)
+			if matched.backend != "" {
+				extra = append(extra, "backend", matched.backend)
+			}
+			if container := matched.route.containerName(matched.backend); container != "" {
+				extra = append(extra, "container", container)
+			}
+		}
+		if p := recover(); p != nil {
+			a.logRequest(r, wrapped.statusCode, wrapped.bytesWritten, time.Since(start), append(extra, "aborted", true)...)
+			panic(p)
+		}
+		a.logRequest(r, wrapped.statusCode, wrapped.bytesWritten, time.Since(start), extra...)
+	}()
+
 	// Call the wrapped handler
 	a.handler.ServeHTTP(wrapped, r)
-
-	// Calculate duration
-	duration := time.Since(start)
-
-	// Log the request
-	a.logRequest(r, wrapped.statusCode, wrapped.bytesWritten, duration)
 }
 
 // logRequest logs a request at INFO level.
 // Format: <method> <path> -> <status> <bytes> <duration>ms (host: <host>)
-func (a *AccessLogger) logRequest(r *http.Request, status int, bytes int64, duration time.Duration) {
+// Extra attributes are appended to the log entry.
+func (a *AccessLogger) logRequest(r *http.Request, status int, bytes int64, duration time.Duration, extra ...any) {
 	host := r.Host
 	if host == "" {
 		host = "-"
@@ -86,7 +105,7 @@ func (a *AccessLogger) logRequest(r *http.Request, status int, bytes int64, dura
 	}
 
 	// Log at INFO level with structured fields
-	a.logger.Info("access",
+	attrs := []any{
 		"method", r.Method,
 		"host", host,
 		"path", path,
@@ -94,7 +113,8 @@ func (a *AccessLogger) logRequest(r *http.Request, status int, bytes int64, dura
 		"bytes", bytes,
 		"duration_ms", duration.Milliseconds(),
 		"client", clientIP,
-	)
+	}
+	a.logger.Info("access", append(attrs, extra...)...)
 }
 
 // responseRecorder wraps http.ResponseWriter to capture status code and bytes written.
@@ -2,7 +2,9 @@ package proxy
 
 import (
 	"bytes"
+	"context"
 	"log/slog"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -145,6 +147,144 @@ func TestAccessLogger(t *testing.T) {
 	})
 }
 
+func TestAccessLogger_MatchedRoute(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	backendAddr := strings.TrimPrefix(backend.URL, "http://")
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "*.app.localhost", Backend: backendAddr, Protocol: ProtocolHTTP, ContainerName: "web-1"})
+
+	t.Run("logs backend and container", func(t *testing.T) {
+		var buf bytes.Buffer
+		middleware := NewAccessLogger(NewProxyHandler(registry), slog.New(slog.NewTextHandler(&buf, nil)), nil)
+
+		req := httptest.NewRequest(http.MethodGet, "http://api.app.localhost/", nil)
+		middleware.ServeHTTP(httptest.NewRecorder(), req)
+
+		logOutput := buf.String()
+		for _, want := range []string{`route=*.app.localhost`, "backend=" + backendAddr, "container=web-1"} {
+			if !strings.Contains(logOutput, want) {
+				t.Errorf("expected log to contain %q, got: %s", want, logOutput)
+			}
+		}
+	})
+
+	t.Run("omits route details for unknown host", func(t *testing.T) {
+		var buf bytes.Buffer
+		middleware := NewAccessLogger(NewProxyHandler(registry), slog.New(slog.NewTextHandler(&buf, nil)), nil)
+
+		req := httptest.NewRequest(http.MethodGet, "http://other.localhost/", nil)
+		middleware.ServeHTTP(httptest.NewRecorder(), req)
+
+		if logOutput := buf.String(); strings.Contains(logOutput, "backend=") || strings.Contains(logOutput, "container=") {
+			t.Errorf("expected no route details, got: %s", logOutput)
+		}
+	})
+}
+
+func TestAccessLogger_ProxyErrors(t *testing.T) {
+	t.Run("logs 404 for unknown host", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+		middleware := NewAccessLogger(NewProxyHandler(NewRegistry()), logger, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "http://unknown.localhost/", nil)
+		w := httptest.NewRecorder()
+
+		middleware.ServeHTTP(w, req)
+
+		logOutput := buf.String()
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("expected status 404, got %d", w.Code)
+		}
+		if !strings.Contains(logOutput, "status=404") {
+			t.Errorf("expected log to contain 'status=404', got: %s", logOutput)
+		}
+		if !strings.Contains(logOutput, "host=unknown.localhost") {
+			t.Errorf("expected log to contain host, got: %s", logOutput)
+		}
+	})
+
+	t.Run("logs 502 for dead backend", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+		// Reserve a port and close it so connections are refused
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to listen: %v", err)
+		}
+		deadBackend := ln.Addr().String()
+		ln.Close()
+
+		registry := NewRegistry()
+		registry.Add(Route{Host: "dead.localhost", Backend: deadBackend, Protocol: ProtocolHTTP})
+		middleware := NewAccessLogger(NewProxyHandler(registry), logger, nil)
+
+		req := httptest.NewRequest(http.MethodGet, "http://dead.localhost/api", nil)
+		w := httptest.NewRecorder()
+
+		middleware.ServeHTTP(w, req)
+
+		logOutput := buf.String()
+		if w.Code != http.StatusBadGateway {
+			t.Fatalf("expected status 502, got %d", w.Code)
+		}
+		if !strings.Contains(logOutput, "status=502") {
+			t.Errorf("expected log to contain 'status=502', got: %s", logOutput)
+		}
+		if !strings.Contains(logOutput, "path=/api") {
+			t.Errorf("expected log to contain path, got: %s", logOutput)
+		}
+	})
+
+	t.Run("logs response aborted mid-body", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+		// Backend promises 100 bytes but closes after sending 5
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Length", "100")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("12345"))
+			w.(http.Flusher).Flush()
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}))
+		defer backend.Close()
+
+		registry := NewRegistry()
+		registry.Add(Route{Host: "flaky.localhost", Backend: strings.TrimPrefix(backend.URL, "http://"), Protocol: ProtocolHTTP})
+		middleware := NewAccessLogger(NewProxyHandler(registry), logger, nil)
+
+		// ReverseProxy only aborts with a panic when serving inside an http.Server
+		req := httptest.NewRequest(http.MethodGet, "http://flaky.localhost/", nil)
+		req = req.WithContext(context.WithValue(req.Context(), http.ServerContextKey, &http.Server{}))
+		w := httptest.NewRecorder()
+
+		func() {
+			defer func() {
+				if p := recover(); p != http.ErrAbortHandler {
+					t.Errorf("expected http.ErrAbortHandler to propagate, got %v", p)
+				}
+			}()
+			middleware.ServeHTTP(w, req)
+		}()
+
+		logOutput := buf.String()
+		if !strings.Contains(logOutput, "status=200") || !strings.Contains(logOutput, "bytes=5") {
+			t.Errorf("expected log to contain partial response, got: %s", logOutput)
+		}
+		if !strings.Contains(logOutput, "aborted=true") {
+			t.Errorf("expected log to mark request as aborted, got: %s", logOutput)
+		}
+	})
+}
+
 func TestResponseRecorder(t *testing.T) {
 	t.Run("captures status code", func(t *testing.T) {
 		w := httptest.NewRecorder()
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ParseMethodList parses a comma-separated list of HTTP methods (e.g.,
+// "GET,HEAD"). Methods are uppercased and deduplicated. Returns nil for an
+// empty list, which allows all methods.
+func ParseMethodList(list string) ([]string, error) {
+	var methods []string
+	for _, entry := range strings.Split(list, ",") {
+		method := strings.ToUpper(strings.TrimSpace(entry))
+		if method == "" {
+			continue
+		}
+		// Method names have the token syntax of header names (RFC 9110)
+		if !validHeaderName(method) {
+			return nil, fmt.Errorf("invalid HTTP method %q", entry)
+		}
+		if !slices.Contains(methods, method) {
+			methods = append(methods, method)
+		}
+	}
+	return methods, nil
+}
+
+// AllowsMethod reports whether requests with method may be proxied to this
+// route. An empty Methods list allows all methods.
+func (r *Route) AllowsMethod(method string) bool {
+	return len(r.Methods) == 0 || slices.Contains(r.Methods, method)
+}
+
+// rejectMethod answers a request whose method the route does not allow.
+func rejectMethod(w http.ResponseWriter, r *http.Request, route *Route) {
+	w.Header().Set("Allow", strings.Join(route.Methods, ", "))
+	http.Error(w, fmt.Sprintf("method %s is not allowed for %s", r.Method, route.Host), http.StatusMethodNotAllowed)
+}
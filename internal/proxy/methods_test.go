@@ -0,0 +1,90 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseMethodList(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []string
+		wantErr bool
+	}{
+		{list: "", want: nil},
+		{list: "GET,HEAD", want: []string{"GET", "HEAD"}},
+		{list: " get , Head, GET ", want: []string{"GET", "HEAD"}},
+		{list: "PROPFIND", want: []string{"PROPFIND"}},
+		{list: "GET,PO ST", wantErr: true},
+		{list: "GET/1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.list, func(t *testing.T) {
+			got, err := ParseMethodList(tt.list)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMethodList(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseMethodList(%q) = %v, want %v", tt.list, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReverseProxy_Methods(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("mirror"))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:     "mirror.localhost",
+		Backend:  strings.TrimPrefix(backend.URL, "http://"),
+		Protocol: ProtocolHTTP,
+		Methods:  []string{"GET", "HEAD"},
+	})
+	registry.Add(Route{
+		Host:     "api.localhost",
+		Backend:  strings.TrimPrefix(backend.URL, "http://"),
+		Protocol: ProtocolHTTP,
+	})
+	proxy := NewReverseProxy(registry)
+
+	tests := []struct {
+		name       string
+		method     string
+		host       string
+		wantStatus int
+	}{
+		{name: "GET is allowed", method: http.MethodGet, host: "mirror.localhost", wantStatus: http.StatusOK},
+		{name: "HEAD is allowed", method: http.MethodHead, host: "mirror.localhost", wantStatus: http.StatusOK},
+		{name: "POST is rejected", method: http.MethodPost, host: "mirror.localhost", wantStatus: http.StatusMethodNotAllowed},
+		{name: "DELETE is rejected", method: http.MethodDelete, host: "mirror.localhost", wantStatus: http.StatusMethodNotAllowed},
+		{name: "all methods allowed by default", method: http.MethodDelete, host: "api.localhost", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://"+tt.host+"/", nil)
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			allow := rec.Header().Get("Allow")
+			if tt.wantStatus == http.StatusMethodNotAllowed {
+				if allow != "GET, HEAD" {
+					t.Errorf("Allow = %q, want %q", allow, "GET, HEAD")
+				}
+			} else if allow != "" {
+				t.Errorf("expected no Allow header, got %q", allow)
+			}
+		})
+	}
+}
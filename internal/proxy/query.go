@@ -0,0 +1,329 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/paths"
+)
+
+// Query commands accepted on the query socket.
+// The socket is read-only: commands never modify the registry.
+const (
+	QueryList   = "list"
+	QueryLookup = "lookup"
+	QueryHealth = "health"
+
+	// QueryHealthChecks reports the active health check results by backend.
+	QueryHealthChecks = "health_checks"
+
+	// QueryConnStats reports the reused and dialed connections by backend.
+	QueryConnStats = "conn_stats"
+)
+
+// queryTimeout bounds a Query call, so the CLI does not hang on a stuck daemon.
+const queryTimeout = 2 * time.Second
+
+// Match kinds reported by LookupAll.
+const (
+	MatchExact    = "exact"
+	MatchWildcard = "wildcard"
+)
+
+// QueryRequest is a single newline-delimited JSON request on the query socket,
+// e.g. {"cmd":"list"} or {"cmd":"lookup","host":"app.localhost"}. The control
+// socket takes the same requests with control commands.
+type QueryRequest struct {
+	Cmd    string  `json:"cmd"`
+	Host   string  `json:"host,omitempty"`
+	Route  *Route  `json:"route,omitempty"`  // route to add (ControlAdd)
+	Routes []Route `json:"routes,omitempty"` // routes to add (ControlImport)
+}
+
+// QueryResponse answers a QueryRequest. Error is set if the request failed;
+// otherwise Routes (list), Matches (lookup), Health (health), HealthChecks
+// (health_checks), ConnStats (conn_stats), Skipped (import), CertCache
+// (cert_cache) or Reissued (ca_reload) holds the result.
+type QueryResponse struct {
+	Error        string                 `json:"error,omitempty"`
+	Routes       []Route                `json:"routes,omitempty"`
+	Matches      []LookupMatch          `json:"matches,omitempty"`
+	Health       *Health                `json:"health,omitempty"`
+	HealthChecks map[string]HealthStats `json:"health_checks,omitempty"`
+	ConnStats    map[string]ConnStats   `json:"conn_stats,omitempty"`
+	Skipped      []string               `json:"skipped,omitempty"`
+	CertCache    []cert.CacheEntry      `json:"cert_cache,omitempty"`
+	Reissued     int                    `json:"reissued,omitempty"`
+}
+
+// Health describes the state of the daemon.
+type Health struct {
+	// Routes is the number of registered routes.
+	Routes int `json:"routes"`
+
+	// Docker is the status of the Docker integration, absent if it is disabled
+	// or not connected yet.
+	Docker any `json:"docker,omitempty"`
+}
+
+// LookupMatch explains how a route matches a host.
+type LookupMatch struct {
+	Route Route  `json:"route"`
+	Match string `json:"match"` // MatchExact or MatchWildcard
+
+	// Selected is true for the route Lookup returns for the host.
+	Selected bool `json:"selected"`
+}
+
+// QuerySocket returns the path to the daemon's query socket.
+func QuerySocket() string {
+	return filepath.Join(paths.RuntimeDir(), "devproxy.sock")
+}
+
+// LookupAll returns every route matching host in priority order: exact
+// routes first, then wildcard routes from most to least specific. The first
+// match is the one Lookup returns and is marked as selected.
+func (r *Registry) LookupAll(host string) []LookupMatch {
+	host = normalizeHost(host)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matches []LookupMatch
+	for _, route := range r.hostRoutes(host) {
+		matches = append(matches, LookupMatch{Route: *route, Match: MatchExact})
+	}
+
+	var wildcards []LookupMatch
+	for pattern, route := range r.wildcardRoutes {
+		if matchWildcard(host, pattern) {
+			wildcards = append(wildcards, LookupMatch{Route: *route, Match: MatchWildcard})
+		}
+	}
+	sort.Slice(wildcards, func(i, j int) bool {
+		return len(wildcards[i].Route.Pattern) > len(wildcards[j].Route.Pattern)
+	})
+	matches = append(matches, wildcards...)
+
+	if len(matches) > 0 {
+		matches[0].Selected = true
+	}
+	return matches
+}
+
+// QueryServer answers read-only registry queries over a stream socket, or
+// control commands if created with NewControlServer.
+type QueryServer struct {
+	registry *Registry
+	logger   *slog.Logger
+	handler  func(QueryRequest) QueryResponse
+
+	mu           sync.Mutex
+	dockerStatus func() any // reports Health.Docker (optional)
+	controls     map[string]func(QueryRequest) (QueryResponse, error)
+	listener     net.Listener
+	conns        map[net.Conn]struct{}
+	wg           sync.WaitGroup
+}
+
+// NewQueryServer creates a query server for the given registry.
+func NewQueryServer(registry *Registry, logger *slog.Logger) *QueryServer {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	s := &QueryServer{
+		registry: registry,
+		logger:   logger,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.handler = s.handle
+	return s
+}
+
+// SetDockerStatus sets the function reporting the Docker integration's status
+// in health queries. It may be called while the server is running.
+func (s *QueryServer) SetDockerStatus(status func() any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dockerStatus = status
+}
+
+// ListenUnix listens on a Unix socket at path, replacing a stale socket
+// left behind by a previous daemon, and serves queries in the background.
+func (s *QueryServer) ListenUnix(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		listener.Close()
+		return err
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	go func() {
+		if err := s.Serve(listener); err != nil {
+			s.logger.Error("query socket failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Serve accepts connections on listener until Close is called.
+func (s *QueryServer) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	s.listener = listener
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.ServeConn(conn)
+		}()
+	}
+}
+
+// ServeConn answers requests on conn until the client disconnects.
+// Each request gets exactly one response, in order.
+func (s *QueryServer) ServeConn(conn net.Conn) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req QueryRequest
+		if err := dec.Decode(&req); err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				// Malformed JSON leaves the stream unusable; report and hang up
+				_ = enc.Encode(QueryResponse{Error: fmt.Sprintf("invalid request: %v", err)})
+			}
+			return
+		}
+
+		if err := enc.Encode(s.handler(req)); err != nil {
+			s.logger.Debug("failed to write query response", "error", err)
+			return
+		}
+	}
+}
+
+// handle answers a single request.
+func (s *QueryServer) handle(req QueryRequest) QueryResponse {
+	switch req.Cmd {
+	case QueryList:
+		return QueryResponse{Routes: s.registry.List()}
+	case QueryLookup:
+		if req.Host == "" {
+			return QueryResponse{Error: "lookup requires a host"}
+		}
+		return QueryResponse{Matches: s.registry.LookupAll(req.Host)}
+	case QueryHealth:
+		return QueryResponse{Health: s.health()}
+	case QueryHealthChecks:
+		return QueryResponse{HealthChecks: s.registry.HealthChecks()}
+	case QueryConnStats:
+		return QueryResponse{ConnStats: s.registry.ConnStats()}
+	default:
+		return QueryResponse{Error: fmt.Sprintf("unknown command %q", req.Cmd)}
+	}
+}
+
+// health reports the registry size and the Docker integration's status.
+func (s *QueryServer) health() *Health {
+	s.mu.Lock()
+	dockerStatus := s.dockerStatus
+	s.mu.Unlock()
+
+	health := &Health{Routes: s.registry.Count()}
+	if dockerStatus != nil {
+		health.Docker = dockerStatus()
+	}
+	return health
+}
+
+// Query sends req to the daemon's query socket and returns its response.
+func Query(req QueryRequest) (QueryResponse, error) {
+	return query(QuerySocket(), req)
+}
+
+// query sends req to the socket at path and returns the response.
+func query(path string, req QueryRequest) (QueryResponse, error) {
+	return queryWithin(path, req, queryTimeout)
+}
+
+// queryWithin is query giving up after timeout.
+func queryWithin(path string, req QueryRequest, timeout time.Duration) (QueryResponse, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return QueryResponse{}, err
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return QueryResponse{}, err
+	}
+	var resp QueryResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return QueryResponse{}, err
+	}
+	if resp.Error != "" {
+		return resp, errors.New(resp.Error)
+	}
+	return resp, nil
+}
+
+// Close stops accepting connections, closes open ones and waits for their
+// handlers to return.
+func (s *QueryServer) Close() error {
+	s.mu.Lock()
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
@@ -0,0 +1,290 @@
+package proxy
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newQueryTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	for _, route := range []Route{
+		{Host: "app.localhost", Backend: "127.0.0.1:3000"},
+		{Host: "*.localhost", Backend: "127.0.0.1:4000"},
+		{Host: "*.api.localhost", Backend: "127.0.0.1:5000"},
+	} {
+		if err := r.Add(route); err != nil {
+			t.Fatalf("Add(%s) error = %v", route.Host, err)
+		}
+	}
+	return r
+}
+
+// queryClient sends requests over an in-memory connection to a QueryServer.
+type queryClient struct {
+	t    *testing.T
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+func newQueryClient(t *testing.T, server *QueryServer) *queryClient {
+	t.Helper()
+	client, conn := net.Pipe()
+	go server.ServeConn(conn)
+	t.Cleanup(func() { client.Close() })
+	return &queryClient{t: t, conn: client, dec: json.NewDecoder(client)}
+}
+
+func (c *queryClient) do(raw string) QueryResponse {
+	c.t.Helper()
+	if _, err := io.WriteString(c.conn, raw+"\n"); err != nil {
+		c.t.Fatalf("write request: %v", err)
+	}
+	var resp QueryResponse
+	if err := c.dec.Decode(&resp); err != nil {
+		c.t.Fatalf("read response: %v", err)
+	}
+	return resp
+}
+
+func TestRegistry_LookupAll(t *testing.T) {
+	r := newQueryTestRegistry(t)
+
+	tests := []struct {
+		name  string
+		host  string
+		hosts []string
+		kinds []string
+	}{
+		{
+			name:  "exact match wins over wildcard",
+			host:  "app.localhost",
+			hosts: []string{"app.localhost", "*.localhost"},
+			kinds: []string{MatchExact, MatchWildcard},
+		},
+		{
+			name:  "wildcards ordered by specificity",
+			host:  "v1.api.localhost",
+			hosts: []string{"*.api.localhost", "*.localhost"},
+			kinds: []string{MatchWildcard, MatchWildcard},
+		},
+		{
+			name:  "case and trailing dot ignored",
+			host:  "APP.localhost.",
+			hosts: []string{"app.localhost", "*.localhost"},
+			kinds: []string{MatchExact, MatchWildcard},
+		},
+		{
+			name: "no match",
+			host: "example.test",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := r.LookupAll(tt.host)
+			if len(matches) != len(tt.hosts) {
+				t.Fatalf("expected %d matches, got %d: %+v", len(tt.hosts), len(matches), matches)
+			}
+			for i, m := range matches {
+				if m.Route.Host != tt.hosts[i] || m.Match != tt.kinds[i] {
+					t.Errorf("match %d = %s (%s), want %s (%s)", i, m.Route.Host, m.Match, tt.hosts[i], tt.kinds[i])
+				}
+				if m.Selected != (i == 0) {
+					t.Errorf("match %d selected = %v", i, m.Selected)
+				}
+			}
+			if len(matches) > 0 {
+				if selected := r.Lookup(tt.host); selected.Host != matches[0].Route.Host {
+					t.Errorf("Lookup() = %s, selected match = %s", selected.Host, matches[0].Route.Host)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryServer_List(t *testing.T) {
+	server := NewQueryServer(newQueryTestRegistry(t), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client := newQueryClient(t, server)
+
+	resp := client.do(`{"cmd":"list"}`)
+	if resp.Error != "" {
+		t.Fatalf("unexpected error: %s", resp.Error)
+	}
+
+	want := []string{"*.api.localhost", "*.localhost", "app.localhost"}
+	if len(resp.Routes) != len(want) {
+		t.Fatalf("expected %d routes, got %d", len(want), len(resp.Routes))
+	}
+	for i, route := range resp.Routes {
+		if route.Host != want[i] {
+			t.Errorf("route %d = %s, want %s", i, route.Host, want[i])
+		}
+	}
+}
+
+func TestQueryServer_Lookup(t *testing.T) {
+	server := NewQueryServer(newQueryTestRegistry(t), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client := newQueryClient(t, server)
+
+	t.Run("explains matches", func(t *testing.T) {
+		resp := client.do(`{"cmd":"lookup","host":"app.localhost"}`)
+		if resp.Error != "" {
+			t.Fatalf("unexpected error: %s", resp.Error)
+		}
+		if len(resp.Matches) != 2 {
+			t.Fatalf("expected 2 matches, got %d", len(resp.Matches))
+		}
+		if m := resp.Matches[0]; m.Route.Host != "app.localhost" || m.Match != MatchExact || !m.Selected {
+			t.Errorf("unexpected first match: %+v", m)
+		}
+		if m := resp.Matches[1]; m.Route.Backend != "127.0.0.1:4000" || m.Selected {
+			t.Errorf("unexpected second match: %+v", m)
+		}
+	})
+
+	t.Run("unknown host has no matches", func(t *testing.T) {
+		resp := client.do(`{"cmd":"lookup","host":"example.test"}`)
+		if resp.Error != "" || len(resp.Matches) != 0 {
+			t.Errorf("expected empty response, got %+v", resp)
+		}
+	})
+
+	t.Run("requires host", func(t *testing.T) {
+		resp := client.do(`{"cmd":"lookup"}`)
+		if resp.Error == "" {
+			t.Error("expected error for lookup without host")
+		}
+	})
+}
+
+func TestQueryServer_Health(t *testing.T) {
+	server := NewQueryServer(newQueryTestRegistry(t), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client := newQueryClient(t, server)
+
+	resp := client.do(`{"cmd":"health"}`)
+	if resp.Error != "" || resp.Health == nil {
+		t.Fatalf("expected health, got %+v", resp)
+	}
+	if resp.Health.Routes != 3 || resp.Health.Docker != nil {
+		t.Errorf("expected 3 routes without Docker status, got %+v", resp.Health)
+	}
+
+	server.SetDockerStatus(func() any {
+		return map[string]int{"containers": 2}
+	})
+	resp = client.do(`{"cmd":"health"}`)
+	if docker, ok := resp.Health.Docker.(map[string]any); !ok || docker["containers"] != float64(2) {
+		t.Errorf("expected Docker status, got %+v", resp.Health.Docker)
+	}
+}
+
+func TestQueryServer_HealthChecks(t *testing.T) {
+	registry := newQueryTestRegistry(t)
+	server := NewQueryServer(registry, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client := newQueryClient(t, server)
+
+	if resp := client.do(`{"cmd":"health_checks"}`); resp.Error != "" || len(resp.HealthChecks) != 0 {
+		t.Fatalf("expected no health checks, got %+v", resp)
+	}
+
+	registry.RecordHealthCheck("127.0.0.1:3000", 3*time.Millisecond, nil)
+	registry.RecordHealthCheck("127.0.0.1:3000", 2*time.Millisecond, nil)
+	resp := client.do(`{"cmd":"health_checks"}`)
+	stats, ok := resp.HealthChecks["127.0.0.1:3000"]
+	if !ok || !stats.Healthy || stats.ConsecutiveSuccesses != 2 || stats.Latency != 2*time.Millisecond {
+		t.Errorf("expected two passing checks, got %+v", resp.HealthChecks)
+	}
+}
+
+func TestQueryServer_ConnStats(t *testing.T) {
+	registry := newQueryTestRegistry(t)
+	server := NewQueryServer(registry, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client := newQueryClient(t, server)
+
+	registry.conns.record("127.0.0.1:3000", false)
+	registry.conns.record("127.0.0.1:3000", true)
+	registry.conns.record("127.0.0.1:3000", true)
+	resp := client.do(`{"cmd":"conn_stats"}`)
+	if stats := resp.ConnStats["127.0.0.1:3000"]; stats.Reused != 2 || stats.Dialed != 1 {
+		t.Errorf("expected 2 reused and 1 dialed connection, got %+v", resp.ConnStats)
+	}
+}
+
+func TestQueryServer_RejectsUnknownCommands(t *testing.T) {
+	registry := newQueryTestRegistry(t)
+	server := NewQueryServer(registry, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client := newQueryClient(t, server)
+
+	resp := client.do(`{"cmd":"remove","host":"app.localhost"}`)
+	if resp.Error == "" {
+		t.Error("expected error for unknown command")
+	}
+	if registry.Lookup("app.localhost") == nil {
+		t.Error("query socket must not modify the registry")
+	}
+
+	// The connection stays usable after an unknown command
+	if resp := client.do(`{"cmd":"list"}`); len(resp.Routes) != 3 {
+		t.Errorf("expected 3 routes after error, got %d", len(resp.Routes))
+	}
+}
+
+func TestQueryServer_InvalidJSON(t *testing.T) {
+	server := NewQueryServer(NewRegistry(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	client := newQueryClient(t, server)
+
+	if resp := client.do(`{"cmd":}`); resp.Error == "" {
+		t.Error("expected error for malformed request")
+	}
+}
+
+func TestQueryServer_ListenUnix(t *testing.T) {
+	// Unix socket paths are length limited, so avoid the long t.TempDir path
+	dir, err := os.MkdirTemp("", "dpq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "devproxy.sock")
+
+	// A stale socket file from a previous run is replaced
+	if err := os.WriteFile(socket, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewQueryServer(newQueryTestRegistry(t), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := server.ListenUnix(socket); err != nil {
+		t.Fatalf("ListenUnix() error = %v", err)
+	}
+
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := io.WriteString(conn, `{"cmd":"list"}`+"\n"); err != nil {
+		t.Fatal(err)
+	}
+	var resp QueryResponse
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if len(resp.Routes) != 3 {
+		t.Errorf("expected 3 routes, got %d", len(resp.Routes))
+	}
+
+	if err := server.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, err := net.Dial("unix", socket); err == nil {
+		t.Error("expected dial to fail after Close")
+	}
+}
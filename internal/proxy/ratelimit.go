@@ -0,0 +1,71 @@
+package proxy
+
+import (
+	"io"
+	"time"
+)
+
+// rateLimitedWriter throttles writes to a byte rate using a token bucket.
+// The bucket holds at most one chunk, so bursts stay small and throughput
+// converges on the configured rate even for short transfers.
+// It is not safe for concurrent use; each copy direction gets its own writer.
+type rateLimitedWriter struct {
+	w     io.Writer
+	rate  float64 // bytes per second
+	burst int     // maximum bytes written at once
+
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimitedWriter wraps w so that no more than bytesPerSecond are written per second.
+func newRateLimitedWriter(w io.Writer, bytesPerSecond int64) *rateLimitedWriter {
+	burst := tcpCopyBufferSize
+	if bytesPerSecond < int64(burst) {
+		burst = int(bytesPerSecond)
+	}
+	return &rateLimitedWriter{
+		w:      w,
+		rate:   float64(bytesPerSecond),
+		burst:  burst,
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Write writes p in chunks of at most burst bytes, waiting for tokens before each chunk.
+func (l *rateLimitedWriter) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		chunk := len(p)
+		if chunk > l.burst {
+			chunk = l.burst
+		}
+
+		l.wait(chunk)
+
+		n, err := l.w.Write(p[:chunk])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[chunk:]
+	}
+	return written, nil
+}
+
+// wait refills the bucket for the time elapsed since the last write, takes
+// n tokens and sleeps until the bucket is no longer in debt.
+func (l *rateLimitedWriter) wait(n int) {
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > float64(l.burst) {
+		l.tokens = float64(l.burst)
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens < 0 {
+		time.Sleep(time.Duration(-l.tokens / l.rate * float64(time.Second)))
+	}
+}
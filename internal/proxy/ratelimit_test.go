@@ -0,0 +1,120 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRateLimitedWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  int64
+		total int
+	}{
+		{name: "rate above buffer size", rate: 64 * 1024, total: 96 * 1024},
+		{name: "rate below buffer size", rate: 8 * 1024, total: 16 * 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := newRateLimitedWriter(&buf, tt.rate)
+
+			data := bytes.Repeat([]byte("x"), tt.total)
+			start := time.Now()
+			n, err := w.Write(data)
+			elapsed := time.Since(start)
+
+			if err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if n != tt.total || buf.Len() != tt.total {
+				t.Fatalf("wrote %d bytes (buffer %d), want %d", n, buf.Len(), tt.total)
+			}
+
+			// The initial burst is free; the rest must take at least rest/rate
+			minElapsed := time.Duration(float64(tt.total-w.burst) / float64(tt.rate) * float64(time.Second))
+			if elapsed < minElapsed*9/10 {
+				t.Errorf("transfer took %v, expected at least %v", elapsed, minElapsed)
+			}
+		})
+	}
+}
+
+func TestTCPEntrypoint_RateLimit(t *testing.T) {
+	const (
+		rate  = 64 * 1024
+		total = 160 * 1024
+
+		// Long enough for the entrypoint's protocol detection not to wait for more
+		greeting = "hello devproxy"
+	)
+
+	// Backend that sends a fixed payload after the client speaks first
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		conn, err := backend.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, len(greeting))
+		if _, err := io.ReadFull(conn, buf); err != nil {
+			return
+		}
+		conn.Write(bytes.Repeat([]byte("x"), total))
+	}()
+
+	registry := NewRegistry()
+	if err := registry.Add(Route{Host: "db.localhost", Backend: backend.Addr().String(), Protocol: "tcp", Entrypoint: "postgres"}); err != nil {
+		t.Fatalf("failed to add route: %v", err)
+	}
+
+	ep := NewTCPEntrypoint(TCPEntrypointConfig{
+		Name:      "postgres",
+		Listen:    "127.0.0.1:0",
+		RateLimit: rate,
+		Registry:  registry,
+		Logger:    slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := ep.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start entrypoint: %v", err)
+	}
+	defer ep.Stop(context.Background())
+
+	conn, err := net.DialTimeout("tcp", ep.Addr(), 2*time.Second)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(greeting)); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	start := time.Now()
+	n, err := io.Copy(io.Discard, conn)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if n != total {
+		t.Fatalf("received %d bytes, want %d", n, total)
+	}
+
+	// Allow the initial burst on top of the configured rate
+	allowed := float64(rate)*elapsed.Seconds() + tcpCopyBufferSize
+	if float64(n) > allowed {
+		t.Errorf("received %d bytes in %v, exceeds cap of %d bytes/sec", n, elapsed, rate)
+	}
+}
@@ -0,0 +1,132 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ErrRedirectLoop is returned when a backend redirects to a URL already visited.
+var ErrRedirectLoop = errors.New("backend redirect loop")
+
+// maxDrainBytes bounds how much of an intermediate redirect body is read
+// so the connection can be reused.
+const maxDrainBytes = 64 * 1024
+
+// redirectTransport follows backend redirects server-side, up to maxHops,
+// and returns the final response to the client. Only redirects that stay on
+// the backend (or point at the route's public host) are followed; others are
+// passed to the client unchanged.
+type redirectTransport struct {
+	base    http.RoundTripper
+	maxHops int
+}
+
+// newRedirectTransport wraps base to follow up to maxHops backend redirects.
+func newRedirectTransport(base http.RoundTripper, maxHops int) *redirectTransport {
+	return &redirectTransport{base: base, maxHops: maxHops}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	visited := map[string]bool{req.URL.String(): true}
+	for hop := 0; hop < t.maxHops; hop++ {
+		next := t.nextRequest(req, resp)
+		if next == nil {
+			return resp, nil
+		}
+		if visited[next.URL.String()] {
+			drainBody(resp)
+			return nil, fmt.Errorf("%w: %s", ErrRedirectLoop, next.URL.Path)
+		}
+		visited[next.URL.String()] = true
+
+		drainBody(resp)
+		req = next
+		resp, err = t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// nextRequest builds the request for the redirect in resp, or returns nil if
+// resp is not a redirect that can be followed server-side.
+func (t *redirectTransport) nextRequest(req *http.Request, resp *http.Response) *http.Request {
+	switch resp.StatusCode {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+	default:
+		return nil
+	}
+
+	loc := resp.Header.Get("Location")
+	if loc == "" {
+		return nil
+	}
+	target, err := req.URL.Parse(loc)
+	if err != nil {
+		return nil
+	}
+
+	// Absolute redirects to the public host are served by the same backend
+	switch {
+	case strings.EqualFold(target.Host, req.URL.Host):
+	case strings.EqualFold(target.Hostname(), hostWithoutPort(req.Host)):
+		target.Scheme = req.URL.Scheme
+		target.Host = req.URL.Host
+	default:
+		return nil
+	}
+
+	keepMethod := resp.StatusCode == http.StatusTemporaryRedirect || resp.StatusCode == http.StatusPermanentRedirect
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	if keepMethod && hasBody {
+		// The original body was already sent and cannot be replayed
+		return nil
+	}
+
+	next := req.Clone(req.Context())
+	next.URL = &url.URL{
+		Scheme:   target.Scheme,
+		Host:     target.Host,
+		Path:     target.Path,
+		RawPath:  target.RawPath,
+		RawQuery: target.RawQuery,
+	}
+	if !keepMethod && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		next.Method = http.MethodGet
+	}
+	if !keepMethod {
+		next.Body = nil
+		next.ContentLength = 0
+		next.Header.Del("Content-Type")
+		next.Header.Del("Content-Length")
+	}
+	return next
+}
+
+// drainBody discards and closes a response body so its connection can be reused.
+func drainBody(resp *http.Response) {
+	_, _ = io.CopyN(io.Discard, resp.Body, maxDrainBytes)
+	resp.Body.Close()
+}
+
+// hostWithoutPort strips an optional port from a Host header value.
+func hostWithoutPort(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newRedirectBackend serves /hop/N, which redirects to /hop/N-1 until /hop/0
+// answers with the method it received, plus a few special redirect paths.
+func newRedirectBackend(t *testing.T) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/hop/"):
+			n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/hop/"))
+			if n > 0 {
+				http.Redirect(w, r, fmt.Sprintf("/hop/%d", n-1), http.StatusFound)
+				return
+			}
+			fmt.Fprintf(w, "final %s host=%s", r.Method, r.Host)
+		case r.URL.Path == "/loop/a":
+			http.Redirect(w, r, "/loop/b", http.StatusFound)
+		case r.URL.Path == "/loop/b":
+			http.Redirect(w, r, "/loop/a", http.StatusFound)
+		case r.URL.Path == "/public":
+			http.Redirect(w, r, "https://app.localhost/hop/0", http.StatusMovedPermanently)
+		case r.URL.Path == "/external":
+			http.Redirect(w, r, "https://example.com/", http.StatusFound)
+		case r.URL.Path == "/see-other":
+			http.Redirect(w, r, "/hop/0", http.StatusSeeOther)
+		case r.URL.Path == "/temporary":
+			http.Redirect(w, r, "/hop/0", http.StatusTemporaryRedirect)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(backend.Close)
+	return backend
+}
+
+func TestReverseProxy_FollowRedirects(t *testing.T) {
+	backend := newRedirectBackend(t)
+
+	tests := []struct {
+		name         string
+		follow       int
+		method       string
+		path         string
+		body         string
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+	}{
+		{name: "disabled passes redirect through", follow: 0, method: http.MethodGet, path: "/hop/1", wantStatus: http.StatusFound, wantLocation: "/hop/0"},
+		{name: "follows up to N hops", follow: 3, method: http.MethodGet, path: "/hop/3", wantStatus: http.StatusOK, wantBody: "final GET host=app.localhost"},
+		{name: "stops after N hops", follow: 2, method: http.MethodGet, path: "/hop/3", wantStatus: http.StatusFound, wantLocation: "/hop/0"},
+		{name: "detects loops", follow: 5, method: http.MethodGet, path: "/loop/a", wantStatus: http.StatusLoopDetected},
+		{name: "follows redirect to public host", follow: 1, method: http.MethodGet, path: "/public", wantStatus: http.StatusOK, wantBody: "final GET host=app.localhost"},
+		{name: "passes external redirect through", follow: 1, method: http.MethodGet, path: "/external", wantStatus: http.StatusFound, wantLocation: "https://example.com/"},
+		{name: "303 turns POST into GET", follow: 1, method: http.MethodPost, path: "/see-other", body: "data", wantStatus: http.StatusOK, wantBody: "final GET host=app.localhost"},
+		{name: "307 keeps method without body", follow: 1, method: http.MethodDelete, path: "/temporary", wantStatus: http.StatusOK, wantBody: "final DELETE host=app.localhost"},
+		{name: "307 with body is passed through", follow: 1, method: http.MethodPost, path: "/temporary", body: "data", wantStatus: http.StatusTemporaryRedirect, wantLocation: "/hop/0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			registry.Add(Route{
+				Host:            "app.localhost",
+				Backend:         strings.TrimPrefix(backend.URL, "http://"),
+				Protocol:        ProtocolHTTP,
+				FollowRedirects: tt.follow,
+			})
+			proxy := NewReverseProxy(registry)
+
+			var req *http.Request
+			if tt.body != "" {
+				req = httptest.NewRequest(tt.method, "http://app.localhost"+tt.path, strings.NewReader(tt.body))
+			} else {
+				req = httptest.NewRequest(tt.method, "http://app.localhost"+tt.path, nil)
+			}
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+			if loc := rec.Header().Get("Location"); loc != tt.wantLocation {
+				t.Errorf("expected Location %q, got %q", tt.wantLocation, loc)
+			}
+		})
+	}
+}
@@ -2,40 +2,181 @@
 package proxy
 
 import (
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/netip"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/munichmade/devproxy/internal/metrics"
 )
 
+// DefaultRequestTimeout bounds non-WebSocket requests on routes without a timeout.
+const DefaultRequestTimeout = 60 * time.Second
+
 // ReverseProxy routes incoming requests to backend services based on Host header.
 type ReverseProxy struct {
 	registry *Registry
+
+	// transformers are applied to every proxied request and response (optional)
+	transformers *Transformers
+
+	// auth verifies basic auth credentials of protected routes
+	auth basicAuth
+
+	// noRoute returns how requests for hosts without a route are answered (optional)
+	noRoute func() NoRoute
+
+	// transport is shared by all routes, so backend connections are reused
+	transport *http.Transport
+
+	// trustedProxies returns the proxies whose forwarding headers name the
+	// client (optional, default none)
+	trustedProxies func() []netip.Prefix
+}
+
+// NoRoute is how the proxy answers requests for hosts without a route. The
+// zero value answers 404 Not Found.
+type NoRoute struct {
+	// Backend receives the requests when set, like the backend of a
+	// catch-all route. Status is then ignored.
+	Backend string
+
+	// Status is the status code answered otherwise (0 = 404).
+	Status int
+}
+
+// ParseNoRoute returns the behavior for the proxy.no_route mode: "notfound"
+// or empty, "default" to proxy to defaultBackend, or a status code. An
+// invalid mode answers 404.
+func ParseNoRoute(mode, defaultBackend string) NoRoute {
+	switch mode {
+	case "", "notfound":
+		return NoRoute{}
+	case "default":
+		return NoRoute{Backend: defaultBackend}
+	}
+	status, err := strconv.Atoi(mode)
+	if err != nil {
+		return NoRoute{}
+	}
+	return NoRoute{Status: status}
 }
 
 // NewReverseProxy creates a new reverse proxy with the given route registry.
 func NewReverseProxy(registry *Registry) *ReverseProxy {
 	return &ReverseProxy{
-		registry: registry,
+		registry:  registry,
+		transport: newBackendTransport(0),
+	}
+}
+
+// SetMaxIdleConnsPerHost sets the number of idle connections kept open per
+// backend (0 = DefaultMaxIdleConnsPerHost). It replaces the transport, so it
+// must be called before the proxy serves requests.
+func (rp *ReverseProxy) SetMaxIdleConnsPerHost(n int) {
+	rp.transport = newBackendTransport(n)
+}
+
+// SetTransformers sets the request and response transformers applied to all
+// HTTP routes. Request transformers run after access checks, just before the
+// request is proxied; response transformers run before the route's response
+// headers are applied, so those always win.
+func (rp *ReverseProxy) SetTransformers(transformers *Transformers) {
+	rp.transformers = transformers
+}
+
+// SetNoRoute sets a function returning how requests for hosts without a route
+// are answered. It is called per request, so config reloads take effect
+// without rebuilding the proxy.
+func (rp *ReverseProxy) SetNoRoute(noRoute func() NoRoute) {
+	rp.noRoute = noRoute
+}
+
+// SetTrustedProxies sets a function returning the proxies allowed to name the
+// client in X-Forwarded-For or X-Real-IP. Requests from other addresses are
+// identified by their remote address only, so clients cannot bypass access
+// lists with a forged header. It is called per request.
+func (rp *ReverseProxy) SetTrustedProxies(trusted func() []netip.Prefix) {
+	rp.trustedProxies = trusted
+}
+
+// clientIP returns the client IP of r, trusting forwarding headers only from
+// the configured proxies.
+func (rp *ReverseProxy) clientIP(r *http.Request) string {
+	var trusted []netip.Prefix
+	if rp.trustedProxies != nil {
+		trusted = rp.trustedProxies()
 	}
+	return getClientIP(r, trusted)
 }
 
 // ServeHTTP implements http.Handler for the reverse proxy.
 func (rp *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Extract host without port
-	host := r.Host
-	if h, _, err := net.SplitHostPort(host); err == nil {
-		host = h
+	rp.serveRoute(w, r, rp.registry.LookupPath(hostWithoutPort(r.Host), r.URL.Path))
+}
+
+// serveRoute proxies r to route, the result of looking up its host and path
+// (nil if none matched), so callers that needed the route first do not look
+// it up again.
+func (rp *ReverseProxy) serveRoute(w http.ResponseWriter, r *http.Request, route *Route) {
+	host := hostWithoutPort(r.Host)
+
+	// Count requests by route host, so arbitrary Host headers and wildcard
+	// subdomains do not each add a series
+	start := time.Now()
+	recorder := &responseRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+	w = recorder
+	metricsHost := metrics.UnroutedHost
+	defer func() {
+		metrics.ObserveHTTPRequest(metricsHost, recorder.statusCode, time.Since(start))
+	}()
+
+	if route != nil {
+		metricsHost = route.Host
+	} else {
+		var noRoute NoRoute
+		if rp.noRoute != nil {
+			noRoute = rp.noRoute()
+		}
+		if noRoute.Backend == "" {
+			http.Error(w, fmt.Sprintf("no route configured for host: %s", host), cmp.Or(noRoute.Status, http.StatusNotFound))
+			return
+		}
+		// Proxy like a catch-all route, still counted as unrouted
+		route = &Route{Host: host, Backend: noRoute.Backend, Protocol: ProtocolHTTP}
 	}
 
-	// Look up route
-	route := rp.registry.Lookup(host)
-	if route == nil {
-		http.Error(w, fmt.Sprintf("no route configured for host: %s", host), http.StatusNotFound)
+	// Record the route for middleware such as the access logger
+	r, matched := withMatchedRoute(r)
+	matched.route = route
+
+	if route.Disabled {
+		http.Error(w, fmt.Sprintf("route disabled: %s", host), http.StatusServiceUnavailable)
+		return
+	}
+
+	// Enforce client IP access lists
+	if !route.AllowsClient(rp.clientIP(r)) {
+		http.Error(w, fmt.Sprintf("access to %s is not allowed from this client", host), http.StatusForbidden)
+		return
+	}
+
+	// Protected routes require credentials, including for WebSocket upgrades
+	if len(route.BasicAuth) > 0 && !rp.auth.check(r, route.BasicAuth) {
+		requireBasicAuth(w, host)
+		return
+	}
+
+	if !route.AllowsMethod(r.Method) {
+		rejectMethod(w, r, route)
 		return
 	}
 
@@ -45,37 +186,95 @@ func (rp *ReverseProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if rp.transformers != nil {
+		if err := rp.transformers.transformRequest(r, route); err != nil {
+			writeTransformError(w, err)
+			return
+		}
+	}
+
+	backend, ok := rp.stickyBackend(r, route)
+	if !ok {
+		backend, ok = rp.registry.SelectBackend(route)
+		if !ok {
+			http.Error(w, fmt.Sprintf("no healthy backend for host: %s (health check failing or backend refusing connections)", host), http.StatusServiceUnavailable)
+			return
+		}
+		if route.StickyCookie != "" {
+			// Pin the client to the selected backend
+			http.SetCookie(w, &http.Cookie{
+				Name:     route.StickyCookie,
+				Value:    StickyID(backend),
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+	}
+	recordBackend(r.Context(), backend)
+	matched.backend = backend
+
 	// Parse backend URL
-	backendURL, err := url.Parse("http://" + route.Backend)
+	backendURL, err := url.Parse("http://" + backend)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("invalid backend URL: %v", err), http.StatusInternalServerError)
 		return
 	}
 
 	// Create reverse proxy for this request
-	proxy := rp.createProxy(backendURL, r)
+	proxy := rp.createProxy(backendURL, r, route)
 	proxy.ServeHTTP(w, r)
 }
 
+// stickyBackend returns the backend the request's sticky session cookie pins
+// it to. It reports false if the route has no sticky sessions, the request
+// carries no valid cookie, or the pinned backend is unavailable.
+func (rp *ReverseProxy) stickyBackend(r *http.Request, route *Route) (string, bool) {
+	if route.StickyCookie == "" {
+		return "", false
+	}
+	cookie, err := r.Cookie(route.StickyCookie)
+	if err != nil {
+		return "", false
+	}
+	return rp.registry.PinnedBackend(route, cookie.Value)
+}
+
 // createProxy creates an httputil.ReverseProxy configured for the given backend.
-func (rp *ReverseProxy) createProxy(target *url.URL, originalReq *http.Request) *httputil.ReverseProxy {
+// If the route's FollowRedirects is positive, up to that many backend redirects are followed server-side.
+func (rp *ReverseProxy) createProxy(target *url.URL, originalReq *http.Request, route *Route) *httputil.ReverseProxy {
 	director := func(req *http.Request) {
 		// Set target URL
 		req.URL.Scheme = target.Scheme
 		req.URL.Host = target.Host
 
+		// Strip the route's prefix so backends serving at / need not know it
+		if stripped, ok := stripPathPrefix(req.URL.Path, route.StripPrefix); ok {
+			req.URL.Path = stripped
+			if req.URL.RawPath, ok = stripPathPrefix(req.URL.RawPath, route.StripPrefix); !ok {
+				req.URL.RawPath = ""
+			}
+			req.Header.Set("X-Forwarded-Prefix", route.StripPrefix)
+		}
+
 		// Preserve original path if target has a path
 		if target.Path != "" && target.Path != "/" {
 			req.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
 		}
 
+		// Without stream handling, the backend must answer as plain HTTP
+		if route.WebSocket == WebSocketOff {
+			req.Header.Del("Upgrade")
+		}
+
 		// Preserve original Host header for the backend
 		// Most applications expect the original Host header for virtual hosting,
 		// URL generation, and multi-tenant routing
 		req.Host = originalReq.Host
 
 		// Set proxy headers
-		clientIP := getClientIP(originalReq)
+		clientIP := rp.clientIP(originalReq)
+		realIP := clientIP
 
 		// X-Forwarded-For: append client IP
 		if prior, ok := req.Header["X-Forwarded-For"]; ok {
@@ -94,28 +293,49 @@ func (rp *ReverseProxy) createProxy(target *url.URL, originalReq *http.Request)
 		req.Header.Set("X-Forwarded-Host", originalReq.Host)
 
 		// X-Real-IP: client IP
-		req.Header.Set("X-Real-IP", getClientIP(originalReq))
+		req.Header.Set("X-Real-IP", realIP)
+
+		// Route headers come last so they can override any of the above
+		applyHeaders(req.Header, route.RequestHeaders)
+	}
+
+	var transport http.RoundTripper = &connStatsTransport{base: rp.transport, registry: rp.registry, backend: target.Host}
+	transport = &healthTransport{base: transport, registry: rp.registry, backend: target.Host}
+	if route.FollowRedirects > 0 {
+		transport = newRedirectTransport(transport, route.FollowRedirects)
 	}
 
 	proxy := &httputil.ReverseProxy{
-		Director: director,
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   30 * time.Second,
-				KeepAlive: 30 * time.Second,
-			}).DialContext,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
+		Director:  director,
+		Transport: transport,
 		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if errors.Is(err, ErrRedirectLoop) {
+				http.Error(w, fmt.Sprintf("proxy error: %v", err), http.StatusLoopDetected)
+				return
+			}
+			if errors.Is(err, context.DeadlineExceeded) {
+				http.Error(w, fmt.Sprintf("gateway timeout: backend %s did not respond within %s", target.Host, requestTimeout(route)), http.StatusGatewayTimeout)
+				return
+			}
 			http.Error(w, fmt.Sprintf("proxy error: %v", err), http.StatusBadGateway)
 		},
 		// FlushInterval for streaming responses (including WebSocket)
 		FlushInterval: -1,
 	}
 
+	transform := rp.transformers != nil && rp.transformers.hasResponse()
+	if len(route.ResponseHeaders) > 0 || transform {
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			if transform {
+				if err := rp.transformers.transformResponse(resp, route); err != nil {
+					return err
+				}
+			}
+			applyHeaders(resp.Header, route.ResponseHeaders)
+			return nil
+		}
+	}
+
 	return proxy
 }
 
@@ -137,28 +357,51 @@ func singleJoiningSlash(a, b string) string {
 	return a + b
 }
 
-// getClientIP extracts the client IP from a request.
-func getClientIP(r *http.Request) string {
-	// Check X-Forwarded-For first (in case of upstream proxy)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP
-		if idx := strings.Index(xff, ","); idx != -1 {
-			return strings.TrimSpace(xff[:idx])
+// getClientIP extracts the client IP from a request. X-Forwarded-For and
+// X-Real-IP are only honored when the request comes from a trusted proxy;
+// anyone else could send them to pose as another client.
+func getClientIP(r *http.Request, trusted []netip.Prefix) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !isTrustedProxy(ip, trusted) {
+		return ip
+	}
+
+	// Walk X-Forwarded-For from the nearest hop; entries left of the first
+	// untrusted one may have been set by the client
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if hop == "" {
+				continue
+			}
+			ip = hop
+			if !isTrustedProxy(hop, trusted) {
+				break
+			}
 		}
-		return strings.TrimSpace(xff)
+		return ip
 	}
 
-	// Check X-Real-IP
-	if xri := r.Header.Get("X-Real-IP"); xri != "" {
+	if xri := strings.TrimSpace(r.Header.Get("X-Real-IP")); xri != "" {
 		return xri
 	}
+	return ip
+}
 
-	// Fall back to RemoteAddr
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+// isTrustedProxy reports whether ip is in one of the trusted prefixes.
+func isTrustedProxy(ip string, trusted []netip.Prefix) bool {
+	if len(trusted) == 0 {
+		return false
+	}
+	addr, err := netip.ParseAddr(ip)
 	if err != nil {
-		return r.RemoteAddr
+		return false
 	}
-	return ip
+	return containsAddr(trusted, addr.Unmap())
 }
 
 // ProxyHandler wraps the reverse proxy to add context-aware features.
@@ -173,16 +416,75 @@ func NewProxyHandler(registry *Registry) *ProxyHandler {
 	}
 }
 
+// SetTransformers sets the transformers applied to all HTTP routes.
+// See ReverseProxy.SetTransformers.
+func (ph *ProxyHandler) SetTransformers(transformers *Transformers) {
+	ph.proxy.SetTransformers(transformers)
+}
+
+// SetMaxIdleConnsPerHost sets the idle connections kept per backend.
+// See ReverseProxy.SetMaxIdleConnsPerHost.
+func (ph *ProxyHandler) SetMaxIdleConnsPerHost(n int) {
+	ph.proxy.SetMaxIdleConnsPerHost(n)
+}
+
+// SetNoRoute sets how requests for hosts without a route are answered.
+// See ReverseProxy.SetNoRoute.
+func (ph *ProxyHandler) SetNoRoute(noRoute func() NoRoute) {
+	ph.proxy.SetNoRoute(noRoute)
+}
+
+// SetTrustedProxies sets the proxies whose forwarding headers are trusted.
+// See ReverseProxy.SetTrustedProxies.
+func (ph *ProxyHandler) SetTrustedProxies(trusted func() []netip.Prefix) {
+	ph.proxy.SetTrustedProxies(trusted)
+}
+
 // ServeHTTP implements http.Handler with additional context handling.
 func (ph *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Add timeout context for non-WebSocket requests
-	if !isWebSocketRequest(r) {
-		ctx, cancel := context.WithTimeout(r.Context(), 60*time.Second)
+	route := ph.proxy.registry.LookupPath(hostWithoutPort(r.Host), r.URL.Path)
+
+	// Add the route's timeout to requests that are not streamed
+	if isStreamingRequest(r, route) {
+		// Streams may outlive the server's write timeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	} else {
+		timeout := requestTimeout(route)
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
 		defer cancel()
 		r = r.WithContext(ctx)
+
+		// Let the response outlive the server's write timeout so slow routes
+		// still get their answer; recorders in tests do not support deadlines
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + time.Second))
 	}
 
-	ph.proxy.ServeHTTP(w, r)
+	ph.proxy.serveRoute(w, r, route)
+}
+
+// requestTimeout returns the timeout of route, or DefaultRequestTimeout if it has none.
+func requestTimeout(route *Route) time.Duration {
+	if route == nil || route.Timeout <= 0 {
+		return DefaultRequestTimeout
+	}
+	return route.Timeout
+}
+
+// isStreamingRequest reports whether r is handled as a long-lived stream
+// according to the WebSocket mode of route.
+func isStreamingRequest(r *http.Request, route *Route) bool {
+	if route == nil {
+		return isWebSocketRequest(r)
+	}
+	switch route.WebSocket {
+	case WebSocketForce:
+		return true
+	case WebSocketOff:
+		return false
+	default:
+		return isWebSocketRequest(r)
+	}
 }
 
 // isWebSocketRequest checks if the request is a WebSocket upgrade.
@@ -1,12 +1,19 @@
 package proxy
 
 import (
+	"bufio"
+	"fmt"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/websocket"
+
+	"github.com/munichmade/devproxy/internal/metrics"
 )
 
 func TestReverseProxy_ServeHTTP(t *testing.T) {
@@ -70,6 +77,57 @@ func TestReverseProxy_ServeHTTP(t *testing.T) {
 		}
 	})
 
+	t.Run("returns 403 for client outside allow list", func(t *testing.T) {
+		allow, _ := ParseCIDRList("10.0.0.0/8")
+		registry := NewRegistry()
+		registry.Add(Route{
+			Host:       "app.localhost",
+			Backend:    "127.0.0.1:59999",
+			Protocol:   ProtocolHTTP,
+			AllowCIDRs: allow,
+		})
+		rp := NewReverseProxy(registry)
+
+		req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+		req.Host = "app.localhost"
+		req.RemoteAddr = "[2001:db8::1]:51234"
+		w := httptest.NewRecorder()
+
+		rp.ServeHTTP(w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("expected status 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("proxies request for client in allow list", func(t *testing.T) {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer backend.Close()
+
+		allow, _ := ParseCIDRList("192.0.2.0/24")
+		registry := NewRegistry()
+		registry.Add(Route{
+			Host:       "app.localhost",
+			Backend:    strings.TrimPrefix(backend.URL, "http://"),
+			Protocol:   ProtocolHTTP,
+			AllowCIDRs: allow,
+		})
+		rp := NewReverseProxy(registry)
+
+		// httptest.NewRequest uses 192.0.2.1 as RemoteAddr
+		req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+		req.Host = "app.localhost"
+		w := httptest.NewRecorder()
+
+		rp.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", w.Code)
+		}
+	})
+
 	t.Run("returns 502 on backend error", func(t *testing.T) {
 		// Set up registry with invalid backend
 		registry := NewRegistry()
@@ -143,6 +201,128 @@ func TestReverseProxy_ServeHTTP(t *testing.T) {
 	})
 }
 
+func TestReverseProxy_RecordsMetrics(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "*.metrics.localhost", Backend: strings.TrimPrefix(backend.URL, "http://"), Protocol: ProtocolHTTP})
+	rp := NewReverseProxy(registry)
+
+	for _, host := range []string{"a.metrics.localhost", "b.metrics.localhost", "nothing.localhost"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = host
+		rp.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	var b strings.Builder
+	metrics.Default.WriteTo(&b)
+	scraped := b.String()
+
+	// Subdomains of the wildcard route share the route's series
+	if !strings.Contains(scraped, `devproxy_http_requests_total{code="418",host="*.metrics.localhost"} 2`) {
+		t.Errorf("expected requests counted by route host, got:\n%s", scraped)
+	}
+	if !strings.Contains(scraped, `devproxy_http_requests_total{code="404",host="unrouted"}`) {
+		t.Error("expected request without route counted as unrouted")
+	}
+	if strings.Contains(scraped, "nothing.localhost") {
+		t.Error("expected Host header of unrouted request not to become a label")
+	}
+}
+
+func TestReverseProxy_NoRoute(t *testing.T) {
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Gateway", "yes")
+		fmt.Fprintf(w, "gateway saw %s", r.Host)
+	}))
+	defer gateway.Close()
+	gatewayAddr := strings.TrimPrefix(gateway.URL, "http://")
+
+	tests := []struct {
+		name        string
+		mode        string
+		wantStatus  int
+		wantBody    string
+		wantGateway bool
+	}{
+		{name: "unset", mode: "", wantStatus: http.StatusNotFound, wantBody: "no route configured for host: unknown.localhost"},
+		{name: "notfound", mode: "notfound", wantStatus: http.StatusNotFound, wantBody: "no route configured"},
+		{name: "status", mode: "421", wantStatus: http.StatusMisdirectedRequest, wantBody: "no route configured"},
+		{name: "default backend", mode: "default", wantStatus: http.StatusOK, wantBody: "gateway saw unknown.localhost", wantGateway: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			registry.Add(Route{Host: "known.localhost", Backend: "127.0.0.1:1", Protocol: ProtocolHTTP})
+			rp := NewReverseProxy(registry)
+			rp.SetNoRoute(func() NoRoute { return ParseNoRoute(tt.mode, gatewayAddr) })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = "unknown.localhost"
+			w := httptest.NewRecorder()
+			rp.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, w.Code)
+			}
+			if !strings.Contains(w.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.wantBody, w.Body.String())
+			}
+			if got := w.Header().Get("X-Gateway") == "yes"; got != tt.wantGateway {
+				t.Errorf("expected request to reach the default backend: %v, got %v", tt.wantGateway, got)
+			}
+		})
+	}
+}
+
+func TestReverseProxy_DisabledRoute(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Hello from backend"))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:     "app.localhost",
+		Backend:  strings.TrimPrefix(backend.URL, "http://"),
+		Protocol: ProtocolHTTP,
+	})
+	proxy := NewReverseProxy(registry)
+
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if err := registry.SetEnabled("app.localhost", false); err != nil {
+		t.Fatalf("SetEnabled() error = %v", err)
+	}
+	rec := serve()
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 for disabled route, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "route disabled") {
+		t.Errorf("expected 'route disabled' message, got %q", rec.Body.String())
+	}
+
+	if err := registry.SetEnabled("app.localhost", true); err != nil {
+		t.Fatalf("SetEnabled() error = %v", err)
+	}
+	rec = serve()
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 after re-enabling, got %d", rec.Code)
+	}
+	if rec.Body.String() != "Hello from backend" {
+		t.Errorf("expected backend response, got %q", rec.Body.String())
+	}
+}
+
 func TestReverseProxy_ProxyHeaders(t *testing.T) {
 	t.Run("sets X-Forwarded-For header", func(t *testing.T) {
 		var receivedXFF string
@@ -335,6 +515,425 @@ func TestReverseProxy_ProxyHeaders(t *testing.T) {
 	})
 }
 
+func TestReverseProxy_StripResponseHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "nginx/1.25")
+		w.Header().Set("X-Powered-By", "Express")
+		w.Header().Set("X-Backend-Node", "node-3")
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:            "app.localhost",
+		Backend:         strings.TrimPrefix(backend.URL, "http://"),
+		Protocol:        ProtocolHTTP,
+		ResponseHeaders: map[string]string{"X-Frame-Options": "DENY"},
+	})
+
+	tests := []struct {
+		name      string
+		strip     []string
+		wantGone  []string
+		wantKept  []string
+		noSetting bool
+	}{
+		{name: "not configured", noSetting: true, wantKept: []string{"Server", "X-Powered-By", "X-Backend-Node", "Content-Type"}},
+		{name: "empty list", strip: nil, wantKept: []string{"Server", "X-Powered-By", "X-Backend-Node", "Content-Type"}},
+		{name: "listed headers removed", strip: []string{"Server", "X-Powered-By"}, wantGone: []string{"Server", "X-Powered-By"}, wantKept: []string{"X-Backend-Node", "Content-Type"}},
+		{name: "names are case-insensitive", strip: []string{"x-backend-node"}, wantGone: []string{"X-Backend-Node"}, wantKept: []string{"Server"}},
+		{name: "route headers are applied after stripping", strip: []string{"X-Frame-Options"}, wantKept: []string{"X-Frame-Options", "Server"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := NewReverseProxy(registry)
+			if !tt.noSetting {
+				transformers := NewTransformers()
+				transformers.AddResponse(StripResponseHeaders(func() []string { return tt.strip }))
+				rp.SetTransformers(transformers)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+			w := httptest.NewRecorder()
+			rp.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK || w.Body.String() != "ok" {
+				t.Fatalf("unexpected response %d: %s", w.Code, w.Body.String())
+			}
+			for _, header := range tt.wantGone {
+				if v := w.Header().Get(header); v != "" {
+					t.Errorf("expected %s to be stripped, got %q", header, v)
+				}
+			}
+			for _, header := range tt.wantKept {
+				if w.Header().Get(header) == "" {
+					t.Errorf("expected %s to pass through", header)
+				}
+			}
+		})
+	}
+}
+
+func TestReverseProxy_LoadBalancing(t *testing.T) {
+	newBackend := func(name string) *httptest.Server {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+		t.Cleanup(backend.Close)
+		return backend
+	}
+	web1 := newBackend("web-1")
+	web2 := newBackend("web-2")
+
+	// A listener that is closed right away leaves an address that refuses connections
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "web.localhost", Backend: strings.TrimPrefix(web1.URL, "http://"), Protocol: ProtocolHTTP, ContainerID: "web1"})
+	registry.AddBackend(Route{Host: "web.localhost", Backend: strings.TrimPrefix(web2.URL, "http://"), ContainerID: "web2"})
+	registry.AddBackend(Route{Host: "web.localhost", Backend: strings.TrimPrefix(dead.URL, "http://"), ContainerID: "web3"})
+	registry.SetHealthPolicy(1, time.Minute)
+	proxy := NewReverseProxy(registry)
+
+	get := func() (int, string) {
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://web.localhost/", nil))
+		return rec.Code, rec.Body.String()
+	}
+
+	// The first round reaches every backend once, including the dead one
+	seen := map[string]int{}
+	var failures int
+	for i := 0; i < 3; i++ {
+		code, body := get()
+		if code == http.StatusBadGateway {
+			failures++
+			continue
+		}
+		seen[body]++
+	}
+	if failures != 1 || seen["web-1"] != 1 || seen["web-2"] != 1 {
+		t.Fatalf("expected one request per backend, got %v with %d failures", seen, failures)
+	}
+
+	// The dead backend is ejected afterwards
+	for i := 0; i < 4; i++ {
+		if code, body := get(); code != http.StatusOK {
+			t.Fatalf("request %d: expected dead backend to be skipped, got %d: %s", i, code, body)
+		}
+	}
+}
+
+func TestReverseProxy_PathRouting(t *testing.T) {
+	newBackend := func(name string) string {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name + " " + r.URL.Path))
+		}))
+		t.Cleanup(backend.Close)
+		return strings.TrimPrefix(backend.URL, "http://")
+	}
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "app.localhost", Backend: newBackend("web"), Protocol: ProtocolHTTP})
+	registry.Add(Route{Host: "app.localhost", PathPrefix: "/api", Backend: newBackend("api"), Protocol: ProtocolHTTP})
+	proxy := NewReverseProxy(registry)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/", "web /"},
+		{"/api/users", "api /api/users"},
+		{"/apiv2", "web /apiv2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.localhost"+tt.path, nil))
+			if rec.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReverseProxy_StripPrefix(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.Path + " " + r.Header.Get("X-Forwarded-Prefix")))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:        "app.localhost",
+		PathPrefix:  "/api",
+		StripPrefix: "/api",
+		Backend:     strings.TrimPrefix(backend.URL, "http://"),
+		Protocol:    ProtocolHTTP,
+	})
+	proxy := NewReverseProxy(registry)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/users?page=2", "/users /api"},
+		{"/api", "/ /api"},
+		{"/api/", "/ /api"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.localhost"+tt.path, nil))
+			if rec.Body.String() != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReverseProxy_RouteHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Frame-Options", "SAMEORIGIN")
+		w.Header().Set("Server", "backend")
+		w.Write([]byte(r.Header.Get("X-Tenant") + "|" + r.Header.Get("Authorization")))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:            "app.localhost",
+		Backend:         strings.TrimPrefix(backend.URL, "http://"),
+		Protocol:        ProtocolHTTP,
+		RequestHeaders:  map[string]string{"X-Tenant": "acme", "Authorization": ""},
+		ResponseHeaders: map[string]string{"X-Frame-Options": "DENY", "Access-Control-Allow-Origin": "*", "Server": ""},
+	})
+	proxy := NewReverseProxy(registry)
+
+	req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+	req.Header.Set("X-Tenant", "other")
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, req)
+
+	if body := rec.Body.String(); body != "acme|" {
+		t.Errorf("expected request headers to be replaced and removed, backend got %q", body)
+	}
+	if got := rec.Header().Get("X-Frame-Options"); got != "DENY" {
+		t.Errorf("expected route header to override the backend's, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("expected Access-Control-Allow-Origin *, got %q", got)
+	}
+	if got := rec.Header().Get("Server"); got != "" {
+		t.Errorf("expected Server header to be removed, got %q", got)
+	}
+}
+
+func TestReverseProxy_BasicAuth(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("protected"))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:      "app.localhost",
+		Backend:   strings.TrimPrefix(backend.URL, "http://"),
+		Protocol:  ProtocolHTTP,
+		BasicAuth: map[string]string{"alice": secretHash},
+	})
+	proxy := NewReverseProxy(registry)
+
+	tests := []struct {
+		name       string
+		user       string
+		password   string
+		webSocket  bool
+		wantStatus int
+	}{
+		{name: "valid credentials", user: "alice", password: "secret", wantStatus: http.StatusOK},
+		{name: "missing credentials", wantStatus: http.StatusUnauthorized},
+		{name: "wrong password", user: "alice", password: "wrong", wantStatus: http.StatusUnauthorized},
+		{name: "WebSocket upgrade without credentials", webSocket: true, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+			if tt.user != "" {
+				req.SetBasicAuth(tt.user, tt.password)
+			}
+			if tt.webSocket {
+				req.Header.Set("Upgrade", "websocket")
+				req.Header.Set("Connection", "Upgrade")
+			}
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				if got := rec.Header().Get("WWW-Authenticate"); got != `Basic realm="app.localhost", charset="UTF-8"` {
+					t.Errorf("unexpected WWW-Authenticate %q", got)
+				}
+			} else if rec.Body.String() != "protected" {
+				t.Errorf("expected backend response, got %q", rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestReverseProxy_StickySessions(t *testing.T) {
+	newBackend := func(name string) string {
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(name))
+		}))
+		t.Cleanup(backend.Close)
+		return strings.TrimPrefix(backend.URL, "http://")
+	}
+	web1 := newBackend("web-1")
+	web2 := newBackend("web-2")
+
+	newRegistry := func(cookie string) *Registry {
+		registry := NewRegistry()
+		registry.Add(Route{Host: "web.localhost", Backend: web1, Protocol: ProtocolHTTP, ContainerID: "web1", StickyCookie: cookie})
+		registry.AddBackend(Route{Host: "web.localhost", Backend: web2, ContainerID: "web2"})
+		return registry
+	}
+
+	get := func(proxy *ReverseProxy, cookie *http.Cookie) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "http://web.localhost/", nil)
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, req)
+		return rec
+	}
+
+	stickyCookie := func(t *testing.T, rec *httptest.ResponseRecorder) *http.Cookie {
+		t.Helper()
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == "devproxy_backend" {
+				return cookie
+			}
+		}
+		t.Fatalf("expected sticky cookie in response, got %v", rec.Header().Values("Set-Cookie"))
+		return nil
+	}
+
+	t.Run("pins client to one backend", func(t *testing.T) {
+		proxy := NewReverseProxy(newRegistry("devproxy_backend"))
+
+		first := get(proxy, nil)
+		cookie := stickyCookie(t, first)
+		for i := 0; i < 4; i++ {
+			rec := get(proxy, cookie)
+			if rec.Body.String() != first.Body.String() {
+				t.Fatalf("request %d: expected pinned backend %s, got %s", i, first.Body.String(), rec.Body.String())
+			}
+			if len(rec.Result().Cookies()) != 0 {
+				t.Errorf("request %d: expected no new cookie for pinned client", i)
+			}
+		}
+	})
+
+	t.Run("re-pins when pinned backend is unavailable", func(t *testing.T) {
+		registry := newRegistry("devproxy_backend")
+		proxy := NewReverseProxy(registry)
+
+		registry.SetBackendHealthy(web1, false)
+		rec := get(proxy, &http.Cookie{Name: "devproxy_backend", Value: StickyID(web1)})
+		if rec.Body.String() != "web-2" {
+			t.Fatalf("expected fallback to web-2, got %q", rec.Body.String())
+		}
+		if cookie := stickyCookie(t, rec); cookie.Value != StickyID(web2) {
+			t.Errorf("expected client re-pinned to web-2, got cookie %q", cookie.Value)
+		}
+	})
+
+	t.Run("unknown cookie value selects and pins a backend", func(t *testing.T) {
+		proxy := NewReverseProxy(newRegistry("devproxy_backend"))
+
+		rec := get(proxy, &http.Cookie{Name: "devproxy_backend", Value: "stale"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		stickyCookie(t, rec)
+	})
+
+	t.Run("routes without sticky cookie keep balancing", func(t *testing.T) {
+		proxy := NewReverseProxy(newRegistry(""))
+
+		seen := map[string]bool{}
+		for i := 0; i < 4; i++ {
+			rec := get(proxy, nil)
+			if len(rec.Result().Cookies()) != 0 {
+				t.Fatal("expected no cookie for route without sticky sessions")
+			}
+			seen[rec.Body.String()] = true
+		}
+		if !seen["web-1"] || !seen["web-2"] {
+			t.Errorf("expected both backends to be used, got %v", seen)
+		}
+	})
+}
+
+func TestReverseProxy_EjectsFailingBackend(t *testing.T) {
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "app.localhost", Backend: strings.TrimPrefix(dead.URL, "http://"), Protocol: ProtocolHTTP})
+	registry.SetHealthPolicy(2, time.Minute)
+	proxy := NewReverseProxy(registry)
+
+	for i, want := range []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusServiceUnavailable} {
+		rec := httptest.NewRecorder()
+		proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil))
+		if rec.Code != want {
+			t.Errorf("request %d: expected status %d, got %d", i, want, rec.Code)
+		}
+	}
+}
+
+func TestReverseProxy_UnhealthyBackend(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	backendAddr := strings.TrimPrefix(backend.URL, "http://")
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "app.localhost", Backend: backendAddr, Protocol: ProtocolHTTP})
+	registry.SetBackendHealthy(backendAddr, false)
+	proxy := NewReverseProxy(registry)
+
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "health check failing") {
+		t.Errorf("expected body to explain the failing health check, got %q", rec.Body.String())
+	}
+
+	registry.SetBackendHealthy(backendAddr, true)
+	rec = httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("expected status 200 once healthy, got %d", rec.Code)
+	}
+}
+
 func TestReverseProxy_WebSocket(t *testing.T) {
 	t.Run("proxies WebSocket connections", func(t *testing.T) {
 		// Create a WebSocket backend server
@@ -517,6 +1116,37 @@ func TestProxyHandler_TimeoutForNonWebSocket(t *testing.T) {
 		}
 	})
 
+	t.Run("route timeout returns 504", func(t *testing.T) {
+		release := make(chan struct{})
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+		}))
+		defer backend.Close()
+		defer close(release)
+
+		registry := NewRegistry()
+		registry.Add(Route{
+			Host:     "slow.localhost",
+			Backend:  strings.TrimPrefix(backend.URL, "http://"),
+			Protocol: ProtocolHTTP,
+			Timeout:  50 * time.Millisecond,
+		})
+
+		ph := NewProxyHandler(registry)
+		w := httptest.NewRecorder()
+		ph.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://slow.localhost/report", nil))
+
+		if w.Code != http.StatusGatewayTimeout {
+			t.Errorf("expected status 504, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "did not respond within 50ms") {
+			t.Errorf("expected timeout in body, got %q", w.Body.String())
+		}
+	})
+
 	t.Run("WebSocket requests bypass timeout", func(t *testing.T) {
 		// Verify WebSocket detection works in ProxyHandler
 		req := httptest.NewRequest(http.MethodGet, "http://app.localhost/ws", nil)
@@ -529,10 +1159,184 @@ func TestProxyHandler_TimeoutForNonWebSocket(t *testing.T) {
 	})
 }
 
+func TestIsStreamingRequest(t *testing.T) {
+	webSocket := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	webSocket.Header.Set("Upgrade", "websocket")
+	webSocket.Header.Set("Connection", "Upgrade")
+	custom := httptest.NewRequest(http.MethodGet, "/tunnel", nil)
+	custom.Header.Set("Upgrade", "x-custom-tunnel")
+	custom.Header.Set("Connection", "Upgrade")
+	plain := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	tests := []struct {
+		name string
+		mode WebSocketMode
+		req  *http.Request
+		want bool
+	}{
+		{"auto WebSocket", "", webSocket, true},
+		{"auto non-standard upgrade", WebSocketAuto, custom, false},
+		{"auto plain request", WebSocketAuto, plain, false},
+		{"force non-standard upgrade", WebSocketForce, custom, true},
+		{"force plain request", WebSocketForce, plain, true},
+		{"off WebSocket", WebSocketOff, webSocket, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isStreamingRequest(tt.req, &Route{WebSocket: tt.mode}); got != tt.want {
+				t.Errorf("isStreamingRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !isStreamingRequest(webSocket, nil) {
+		t.Error("expected WebSocket detection without a route")
+	}
+}
+
+func TestProxyHandler_WebSocketMode(t *testing.T) {
+	// upgradeBackend switches to any requested protocol and echoes one line
+	upgradeBackend := func(t *testing.T) (string, chan string) {
+		upgrades := make(chan string, 1)
+		backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			upgrades <- r.Header.Get("Upgrade")
+			if r.Header.Get("Upgrade") == "" {
+				w.Write([]byte("plain"))
+				return
+			}
+			conn, buf, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: " + r.Header.Get("Upgrade") + "\r\n\r\n")
+			buf.Flush()
+			line, err := buf.ReadString('\n')
+			if err != nil {
+				return
+			}
+			buf.WriteString(line)
+			buf.Flush()
+		}))
+		t.Cleanup(backend.Close)
+		return strings.TrimPrefix(backend.URL, "http://"), upgrades
+	}
+
+	serve := func(t *testing.T, mode WebSocketMode, backend string) string {
+		registry := NewRegistry()
+		registry.Add(Route{
+			Host:      "tunnel.localhost",
+			Backend:   backend,
+			Protocol:  ProtocolHTTP,
+			Timeout:   50 * time.Millisecond,
+			WebSocket: mode,
+		})
+		server := httptest.NewServer(NewProxyHandler(registry))
+		t.Cleanup(server.Close)
+		return strings.TrimPrefix(server.URL, "http://")
+	}
+
+	t.Run("force keeps a non-standard upgrade open past the timeout", func(t *testing.T) {
+		backend, _ := upgradeBackend(t)
+		conn, err := net.Dial("tcp", serve(t, WebSocketForce, backend))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		conn.Write([]byte("GET /tunnel HTTP/1.1\r\nHost: tunnel.localhost\r\nConnection: Upgrade\r\nUpgrade: x-custom-tunnel\r\n\r\n"))
+		reader := bufio.NewReader(conn)
+		resp, err := http.ReadResponse(reader, nil)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Fatalf("expected 101, got %d", resp.StatusCode)
+		}
+
+		// Outlive the route timeout before using the tunnel
+		time.Sleep(100 * time.Millisecond)
+		conn.Write([]byte("ping\n"))
+		line, err := reader.ReadString('\n')
+		if err != nil || line != "ping\n" {
+			t.Errorf("expected echo through the tunnel, got %q, %v", line, err)
+		}
+	})
+
+	t.Run("off drops the upgrade", func(t *testing.T) {
+		backend, upgrades := upgradeBackend(t)
+		req, _ := http.NewRequest(http.MethodGet, "http://"+serve(t, WebSocketOff, backend)+"/ws", nil)
+		req.Host = "tunnel.localhost"
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Connection", "Upgrade")
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("expected 200, got %d", resp.StatusCode)
+		}
+		if upgrade := <-upgrades; upgrade != "" {
+			t.Errorf("expected backend to get no Upgrade header, got %q", upgrade)
+		}
+	})
+
+	t.Run("auto passes WebSocket upgrades through", func(t *testing.T) {
+		backend, upgrades := upgradeBackend(t)
+		conn, err := net.Dial("tcp", serve(t, WebSocketAuto, backend))
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: tunnel.localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+		resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+		if err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		if resp.StatusCode != http.StatusSwitchingProtocols {
+			t.Errorf("expected 101, got %d", resp.StatusCode)
+		}
+		if upgrade := <-upgrades; upgrade != "websocket" {
+			t.Errorf("expected backend to get Upgrade websocket, got %q", upgrade)
+		}
+	})
+}
+
+func TestRequestTimeout(t *testing.T) {
+	tests := []struct {
+		name  string
+		route *Route
+		want  time.Duration
+	}{
+		{"no route", nil, DefaultRequestTimeout},
+		{"route without timeout", &Route{}, DefaultRequestTimeout},
+		{"route timeout", &Route{Timeout: 2 * time.Minute}, 2 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requestTimeout(tt.route); got != tt.want {
+				t.Errorf("requestTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetClientIP(t *testing.T) {
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+	chain := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("10.0.0.2/32")}
+
 	tests := []struct {
 		name       string
 		remoteAddr string
+		trusted    []netip.Prefix
 		headers    map[string]string
 		expected   string
 	}{
@@ -542,27 +1346,51 @@ func TestGetClientIP(t *testing.T) {
 			headers:    nil,
 			expected:   "192.168.1.1",
 		},
+		{
+			name:       "forged X-Forwarded-For without trusted proxies",
+			remoteAddr: "192.168.1.1:12345",
+			headers:    map[string]string{"X-Forwarded-For": "127.0.0.1"},
+			expected:   "192.168.1.1",
+		},
+		{
+			name:       "forged X-Real-IP from untrusted address",
+			remoteAddr: "192.168.1.1:12345",
+			trusted:    loopback,
+			headers:    map[string]string{"X-Real-IP": "127.0.0.1"},
+			expected:   "192.168.1.1",
+		},
 		{
 			name:       "from X-Real-IP",
 			remoteAddr: "127.0.0.1:12345",
+			trusted:    loopback,
 			headers:    map[string]string{"X-Real-IP": "10.0.0.1"},
 			expected:   "10.0.0.1",
 		},
 		{
 			name:       "from X-Forwarded-For single",
 			remoteAddr: "127.0.0.1:12345",
+			trusted:    loopback,
 			headers:    map[string]string{"X-Forwarded-For": "10.0.0.1"},
 			expected:   "10.0.0.1",
 		},
 		{
-			name:       "from X-Forwarded-For chain",
+			name:       "X-Forwarded-For chain stops at the first untrusted hop",
 			remoteAddr: "127.0.0.1:12345",
-			headers:    map[string]string{"X-Forwarded-For": "10.0.0.1, 10.0.0.2, 10.0.0.3"},
+			trusted:    chain,
+			headers:    map[string]string{"X-Forwarded-For": "127.0.0.1, 10.0.0.1, 10.0.0.2"},
 			expected:   "10.0.0.1",
 		},
+		{
+			name:       "X-Forwarded-For chain of trusted proxies",
+			remoteAddr: "127.0.0.1:12345",
+			trusted:    chain,
+			headers:    map[string]string{"X-Forwarded-For": "127.0.0.2, 10.0.0.2"},
+			expected:   "127.0.0.2",
+		},
 		{
 			name:       "X-Forwarded-For takes precedence",
 			remoteAddr: "127.0.0.1:12345",
+			trusted:    loopback,
 			headers: map[string]string{
 				"X-Forwarded-For": "10.0.0.1",
 				"X-Real-IP":       "10.0.0.2",
@@ -579,7 +1407,7 @@ func TestGetClientIP(t *testing.T) {
 				req.Header.Set(k, v)
 			}
 
-			got := getClientIP(req)
+			got := getClientIP(req, tt.trusted)
 			if got != tt.expected {
 				t.Errorf("getClientIP() = %q, want %q", got, tt.expected)
 			}
@@ -587,6 +1415,50 @@ func TestGetClientIP(t *testing.T) {
 	}
 }
 
+func TestReverseProxy_AccessListIgnoresForgedHeaders(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:       "admin.localhost",
+		Backend:    strings.TrimPrefix(backend.URL, "http://"),
+		Protocol:   ProtocolHTTP,
+		AllowCIDRs: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")},
+	})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		trusted    []netip.Prefix
+		wantStatus int
+	}{
+		{name: "untrusted client", remoteAddr: "192.168.1.50:4000", wantStatus: http.StatusForbidden},
+		{name: "client not behind the trusted proxy", remoteAddr: "192.168.1.50:4000", trusted: []netip.Prefix{netip.MustParsePrefix("172.16.0.1/32")}, wantStatus: http.StatusForbidden},
+		{name: "trusted proxy", remoteAddr: "172.16.0.1:4000", trusted: []netip.Prefix{netip.MustParsePrefix("172.16.0.1/32")}, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rp := NewReverseProxy(registry)
+			rp.SetTrustedProxies(func() []netip.Prefix { return tt.trusted })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Host = "admin.localhost"
+			req.RemoteAddr = tt.remoteAddr
+			req.Header.Set("X-Forwarded-For", "127.0.0.1")
+			w := httptest.NewRecorder()
+			rp.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
 func TestSingleJoiningSlash(t *testing.T) {
 	tests := []struct {
 		a, b     string
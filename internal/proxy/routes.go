@@ -4,9 +4,15 @@ package proxy
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -25,6 +31,53 @@ const (
 	ProtocolTCP Protocol = "tcp"
 )
 
+// Source identifies where a route came from.
+type Source string
+
+const (
+	// SourceDocker routes are created from the labels of a Docker container
+	// and removed with it.
+	SourceDocker Source = "docker"
+
+	// SourceConfig routes are listed in the routes section of the config and
+	// follow it on reload.
+	SourceConfig Source = "config"
+
+	// SourceManual routes were added with 'devproxy route add' or
+	// 'devproxy route import'.
+	SourceManual Source = "manual"
+)
+
+// WebSocketMode controls whether requests to an HTTP route are handled as
+// long-lived streams, which pass protocol upgrades through and are not
+// subject to the request timeout.
+type WebSocketMode string
+
+const (
+	// WebSocketAuto treats WebSocket upgrade requests as streams (default).
+	WebSocketAuto WebSocketMode = "auto"
+
+	// WebSocketForce treats every request as a stream, including
+	// non-standard upgrades and long-polling or event-stream responses.
+	WebSocketForce WebSocketMode = "force"
+
+	// WebSocketOff treats no request as a stream: upgrade headers are
+	// dropped and every request gets the timeout.
+	WebSocketOff WebSocketMode = "off"
+)
+
+// HTTPMode controls how the HTTP entrypoint handles requests for a route.
+type HTTPMode string
+
+const (
+	// HTTPRedirect redirects plain HTTP requests to HTTPS (default).
+	HTTPRedirect HTTPMode = "redirect"
+
+	// HTTPProxy proxies plain HTTP requests to the backend, for clients
+	// that do not follow the redirect (e.g., webhook senders).
+	HTTPProxy HTTPMode = "proxy"
+)
+
 // Route represents a proxy route from a host to a backend.
 type Route struct {
 	// Host is the domain name to match (e.g., "app.localhost" or "*.app.localhost").
@@ -37,15 +90,52 @@ type Route struct {
 	// Empty for exact routes.
 	Pattern string
 
+	// PathPrefix limits an HTTP route to request paths below it (e.g., "/api"
+	// matches "/api" and "/api/users" but not "/apiv2"). Among the routes of
+	// a host, the longest matching prefix wins; routes without one match any
+	// path. Only supported on exact hosts.
+	PathPrefix string `json:",omitempty"`
+
+	// StripPrefix is removed from the request path before it is proxied
+	// (e.g., "/api" sends "/api/users" to the backend as "/users"). The
+	// backend learns the removed prefix from the X-Forwarded-Prefix header.
+	StripPrefix string `json:",omitempty"`
+
 	// Backend is the upstream address (e.g., "172.18.0.3:3000").
 	Backend string
 
+	// Backends lists every upstream address when several containers serve
+	// the host (e.g., docker compose up --scale web=3). HTTP requests are
+	// balanced round-robin across them and Backend is the first.
+	// Empty for single-backend routes.
+	Backends []string `json:",omitempty"`
+
+	// replicas holds the container serving each entry of Backends.
+	replicas []replica
+
 	// Protocol is the proxy type ("http" or "tcp").
 	Protocol Protocol
 
 	// Entrypoint is the service type for TCP routes (e.g., "postgres", "redis").
+	// A host can have one TCP route per entrypoint.
 	Entrypoint string
 
+	// ExplicitPort is set when the backend port was paired with the entrypoint
+	// (e.g., devproxy.entrypoint=postgres:5432); the entrypoint's target_port
+	// then does not replace it.
+	ExplicitPort bool `json:",omitempty"`
+
+	// PortLabeled is set when the backend port came from labels rather than
+	// the default; an entrypoint in target_port_mode "default" keeps it.
+	PortLabeled bool `json:",omitempty"`
+
+	// ALPNBackends maps ALPN protocols (e.g., "h2") to alternate backends for
+	// TLS connections on TCP entrypoints. Other protocols use Backend.
+	ALPNBackends map[string]string `json:",omitempty"`
+
+	// Source is where the route came from (empty = unknown).
+	Source Source `json:",omitempty"`
+
 	// ContainerID is the Docker container ID if this route is from Docker.
 	ContainerID string
 
@@ -59,17 +149,230 @@ type Route struct {
 	// (from com.docker.compose.project.working_dir label).
 	ProjectDir string
 
+	// AllowCIDRs restricts access to clients within these networks.
+	// Empty allows all clients (subject to DenyCIDRs).
+	AllowCIDRs []netip.Prefix
+
+	// DenyCIDRs rejects clients within these networks. Takes precedence over AllowCIDRs.
+	DenyCIDRs []netip.Prefix
+
+	// Methods restricts HTTP requests to these methods (uppercase); others
+	// get a 405. Empty allows all methods.
+	Methods []string `json:",omitempty"`
+
+	// BasicAuth maps user names to bcrypt hashes. If set, HTTP requests need
+	// the credentials of one of the users. Kept out of the state file, which
+	// is readable by other users.
+	BasicAuth map[string]string `json:"-"`
+
+	// FollowRedirects is the number of backend redirects followed server-side
+	// before the response is returned to the client (0 = pass redirects through).
+	FollowRedirects int `json:",omitempty"`
+
+	// StickyCookie is the name of the cookie pinning a client to one backend
+	// of a load-balanced route (empty = no sticky sessions).
+	StickyCookie string `json:",omitempty"`
+
+	// RequestHeaders are set on requests before they are proxied, replacing
+	// headers sent by the client. An empty value removes the header.
+	RequestHeaders map[string]string `json:",omitempty"`
+
+	// ResponseHeaders are set on backend responses, replacing headers set by
+	// the backend. An empty value removes the header.
+	ResponseHeaders map[string]string `json:",omitempty"`
+
+	// WebSocket selects how streaming requests are detected (empty = WebSocketAuto).
+	WebSocket WebSocketMode `json:",omitempty"`
+
+	// HTTP selects how the HTTP entrypoint handles requests (empty = HTTPRedirect).
+	HTTP HTTPMode `json:",omitempty"`
+
+	// Timeout bounds how long a request may take before the client gets a
+	// 504 (0 = DefaultRequestTimeout). WebSocket connections are not limited.
+	Timeout time.Duration `json:",omitempty"`
+
+	// Ready indicates the route's certificate was generated and, if a readiness
+	// probe is configured, the backend accepted a connection.
+	Ready bool
+
+	// Disabled routes stay registered but are not proxied (HTTP returns 503).
+	Disabled bool `json:",omitempty"`
+
 	// CreatedAt is when the route was added.
 	CreatedAt time.Time
 }
 
+// replica is one backend of a load-balanced route.
+type replica struct {
+	backend       string
+	containerID   string
+	containerName string
+}
+
+// Origin returns where the route came from. Routes saved before sources were
+// recorded are told apart by their container; others are unknown ("").
+func (r *Route) Origin() Source {
+	if r.Source == "" && r.ContainerID != "" {
+		return SourceDocker
+	}
+	return r.Source
+}
+
+// replicaSet returns a copy of the route's replicas. A single-backend route
+// has one replica.
+func (r *Route) replicaSet() []replica {
+	if len(r.replicas) == 0 {
+		return []replica{{backend: r.Backend, containerID: r.ContainerID, containerName: r.ContainerName}}
+	}
+	return slices.Clone(r.replicas)
+}
+
+// setReplicas updates the route's backends from replicas. The first replica
+// becomes the route's Backend and container.
+func (r *Route) setReplicas(replicas []replica) {
+	r.Backend = replicas[0].backend
+	r.ContainerID = replicas[0].containerID
+	r.ContainerName = replicas[0].containerName
+	if len(replicas) == 1 {
+		r.Backends, r.replicas = nil, nil
+		return
+	}
+	r.replicas = replicas
+	r.Backends = make([]string, len(replicas))
+	for i, rep := range replicas {
+		r.Backends[i] = rep.backend
+	}
+}
+
+// dropReplica removes the backend served by containerID from a load-balanced
+// route and reports whether it had one.
+func (r *Route) dropReplica(containerID string) bool {
+	i := slices.IndexFunc(r.replicas, func(rep replica) bool { return rep.containerID == containerID })
+	if i < 0 {
+		return false
+	}
+	r.setReplicas(slices.Delete(r.replicaSet(), i, i+1))
+	return true
+}
+
+// BackendForALPN returns the backend for the first of the client's offered
+// protocols that has an ALPN backend, along with that protocol.
+// Returns empty strings if none match.
+func (r *Route) BackendForALPN(protocols []string) (backend, protocol string) {
+	for _, proto := range protocols {
+		if backend, ok := r.ALPNBackends[proto]; ok {
+			return backend, proto
+		}
+	}
+	return "", ""
+}
+
 // Errors for route operations.
 var (
 	ErrRouteExists         = errors.New("route already exists")
 	ErrWildcardRouteExists = errors.New("wildcard route already exists for this pattern")
 	ErrRouteNotFound       = errors.New("route not found")
+	ErrInvalidBackend      = errors.New("invalid backend address")
+	ErrRouteShadowed       = errors.New("route shadows or is shadowed by another route")
+	ErrInvalidPathPrefix   = errors.New("invalid path prefix")
+	ErrTooManyRoutes       = errors.New("route limit reached")
 )
 
+// NormalizePathPrefix validates a route path prefix and returns it without
+// trailing slashes. An empty prefix or "/" returns "" (all paths).
+func NormalizePathPrefix(prefix string) (string, error) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		return "", fmt.Errorf("%w %q: must start with /", ErrInvalidPathPrefix, prefix)
+	}
+	if strings.ContainsAny(prefix, "?#*") {
+		return "", fmt.Errorf("%w %q: must be a plain path without query, fragment or wildcard", ErrInvalidPathPrefix, prefix)
+	}
+	return strings.TrimRight(prefix, "/"), nil
+}
+
+// matchPath reports whether path is prefix or below it. An empty prefix
+// matches every path.
+func matchPath(path, prefix string) bool {
+	if prefix == "" || path == prefix {
+		return true
+	}
+	return strings.HasPrefix(path, prefix+"/")
+}
+
+// stripPathPrefix removes prefix from path; the prefix itself and the prefix
+// with a trailing slash become "/". It reports false if path is not below prefix.
+func stripPathPrefix(path, prefix string) (string, bool) {
+	if prefix == "" || !matchPath(path, prefix) {
+		return path, false
+	}
+	return singleJoiningSlash("/", strings.TrimPrefix(path, prefix)), true
+}
+
+// ValidateBackend checks that backend is a host:port address such as
+// "172.18.0.3:3000" or "[fd00::3]:3000". With allowBareHost, a host without
+// port is accepted too (TCP routes can take the port from their entrypoint).
+func ValidateBackend(backend string, allowBareHost bool) error {
+	if backend == "" {
+		return fmt.Errorf("%w: backend is empty", ErrInvalidBackend)
+	}
+
+	host, port, err := net.SplitHostPort(backend)
+	if err != nil {
+		if allowBareHost && !strings.ContainsAny(backend, ":[]") {
+			return nil
+		}
+		var addrErr *net.AddrError
+		if errors.As(err, &addrErr) {
+			return fmt.Errorf("%w %q: %s (want host:port, e.g. 172.18.0.3:3000)", ErrInvalidBackend, backend, addrErr.Err)
+		}
+		return fmt.Errorf("%w %q: %v", ErrInvalidBackend, backend, err)
+	}
+	if host == "" {
+		return fmt.Errorf("%w %q: missing host", ErrInvalidBackend, backend)
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%w %q: port %q must be a number between 1 and 65535", ErrInvalidBackend, backend, port)
+	}
+	return nil
+}
+
+// normalizeRoutePath validates and normalizes the path prefix of route.
+func normalizeRoutePath(route *Route) error {
+	prefix, err := NormalizePathPrefix(route.PathPrefix)
+	if err != nil {
+		return err
+	}
+	if prefix != "" && (isWildcardHost(route.Host) || route.Protocol == ProtocolTCP) {
+		return fmt.Errorf("%w %q: only supported on exact HTTP hosts", ErrInvalidPathPrefix, prefix)
+	}
+	route.PathPrefix = prefix
+	return nil
+}
+
+// validateBackends checks the backend and any ALPN backends of route.
+func validateBackends(route *Route) error {
+	allowBareHost := route.Protocol == ProtocolTCP && route.Entrypoint != ""
+	if err := ValidateBackend(route.Backend, allowBareHost); err != nil {
+		return err
+	}
+	for proto, backend := range route.ALPNBackends {
+		if err := ValidateBackend(backend, allowBareHost); err != nil {
+			return fmt.Errorf("alpn %s: %w", proto, err)
+		}
+	}
+	return nil
+}
+
+// normalizeHost lowercases host and strips a trailing dot, so hosts match
+// case-insensitively and fully qualified names match their route.
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
 // isWildcardHost checks if host is a wildcard pattern (e.g., "*.app.localhost").
 func isWildcardHost(host string) bool {
 	return strings.HasPrefix(host, "*.")
@@ -87,14 +390,65 @@ func matchWildcard(host, pattern string) bool {
 	return strings.HasSuffix(host, "."+pattern)
 }
 
+// tcpKey returns the registry key for a TCP route. TCP routes are scoped to
+// their entrypoint so one host can be served on several entrypoints.
+func tcpKey(host, entrypoint string) string {
+	return host + "@" + strings.ToLower(entrypoint)
+}
+
+// routeKey returns the key of an exact route in Registry.routes. HTTP
+// routes with a path prefix are keyed by host and prefix (e.g., "app.localhost/api").
+func routeKey(route *Route) string {
+	if route.Protocol == ProtocolTCP && route.Entrypoint != "" {
+		return tcpKey(route.Host, route.Entrypoint)
+	}
+	return route.Host + route.PathPrefix
+}
+
+// DefaultMaxRoutes is the route limit of a registry without SetMaxRoutes.
+// It is far above what a development machine needs and only stops runaway
+// label generators or misconfigurations.
+const DefaultMaxRoutes = 10000
+
 // Registry is a thread-safe registry of proxy routes.
 type Registry struct {
 	mu             sync.RWMutex
-	routes         map[string]*Route // exact host -> route
+	routes         map[string]*Route // exact host (TCP: host@entrypoint) -> route
 	wildcardRoutes map[string]*Route // pattern (e.g., "app.localhost") -> route
 
+	// byHost indexes routes by host, in hostRoutes order. Its slices are
+	// replaced rather than modified, so callers may range over them while
+	// routes are removed.
+	byHost map[string][]*Route
+
+	// balancer picks backends for load-balanced routes.
+	balancer *balancer
+
+	// health ejects backends after repeated connection failures.
+	health *healthTracker
+
+	// conns counts reused and dialed backend connections.
+	conns *connCounter
+
 	// onChange is called when routes are added or removed.
 	onChange func()
+
+	// generation counts route changes; it increases with every change.
+	generation uint64
+
+	// notifyMu guards notifying and notified, which serialize onChange calls.
+	notifyMu  sync.Mutex
+	notifying bool
+	notified  uint64 // generation the last onChange call saw
+
+	// logger receives shadowing warnings (optional, defaults to slog.Default)
+	logger *slog.Logger
+
+	// rejectShadowing makes Add fail instead of warn when routes overlap.
+	rejectShadowing bool
+
+	// maxRoutes caps the number of routes (0 = DefaultMaxRoutes).
+	maxRoutes int
 }
 
 // NewRegistry creates a new route registry.
@@ -102,6 +456,10 @@ func NewRegistry() *Registry {
 	return &Registry{
 		routes:         make(map[string]*Route),
 		wildcardRoutes: make(map[string]*Route),
+		byHost:         make(map[string][]*Route),
+		balancer:       newBalancer(),
+		health:         newHealthTracker(),
+		conns:          newConnCounter(),
 	}
 }
 
@@ -112,10 +470,148 @@ func (r *Registry) OnChange(fn func()) {
 	r.onChange = fn
 }
 
+// Generation returns a counter that increases with every route change.
+func (r *Registry) Generation() uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.generation
+}
+
+// notifyChange calls onChange for the current generation. Calls are
+// serialized: while one runs, changes made concurrently do not start another
+// but make the running one call onChange again once it returns, so a slow
+// onChange such as SaveState never sees generations out of order and its
+// last call always sees the latest routes. The caller must not hold r.mu.
+func (r *Registry) notifyChange() {
+	r.notifyMu.Lock()
+	if r.notifying {
+		r.notifyMu.Unlock()
+		return
+	}
+	r.notifying = true
+
+	for {
+		// Read the generation under notifyMu, so a change made after this
+		// check waits for notifying to be cleared and notifies itself
+		r.mu.RLock()
+		generation, onChange := r.generation, r.onChange
+		r.mu.RUnlock()
+		if generation == r.notified || onChange == nil {
+			r.notifying = false
+			r.notifyMu.Unlock()
+			return
+		}
+		r.notified = generation
+		r.notifyMu.Unlock()
+
+		onChange()
+
+		r.notifyMu.Lock()
+	}
+}
+
+// SetLogger sets the logger for registry warnings.
+func (r *Registry) SetLogger(logger *slog.Logger) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.logger = logger
+}
+
+// SetRejectShadowing makes Add reject routes that shadow, or are shadowed by,
+// an existing route instead of only logging a warning.
+func (r *Registry) SetRejectShadowing(reject bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rejectShadowing = reject
+}
+
+// SetMaxRoutes sets the number of routes past which Add rejects new routes
+// with ErrTooManyRoutes. Existing routes are kept if the limit is lowered.
+// A limit of 0 selects DefaultMaxRoutes.
+func (r *Registry) SetMaxRoutes(limit int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.maxRoutes = limit
+}
+
+// routeLimit returns the route limit. Must be called with r.mu held.
+func (r *Registry) routeLimit() int {
+	if r.maxRoutes <= 0 {
+		return DefaultMaxRoutes
+	}
+	return r.maxRoutes
+}
+
+// shadowing describes two routes matching the same hosts; winner takes
+// precedence over loser for them.
+type shadowing struct {
+	winner string
+	loser  string
+}
+
+// findShadowing returns the existing routes that overlap with route, which
+// must already have IsWildcard and Pattern set. Only routes served on the
+// same listener can overlap. Exact routes win over wildcards and more
+// specific wildcards win over broader ones.
+// Must be called with r.mu held.
+func (r *Registry) findShadowing(route *Route) []shadowing {
+	var found []shadowing
+	if !route.IsWildcard {
+		for pattern, wildcard := range r.wildcardRoutes {
+			if sameListener(route, wildcard) && matchWildcard(route.Host, pattern) {
+				found = append(found, shadowing{winner: route.Host, loser: wildcard.Host})
+			}
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, exact := range r.routes {
+			if sameListener(route, exact) && matchWildcard(exact.Host, route.Pattern) && !seen[exact.Host] {
+				seen[exact.Host] = true
+				found = append(found, shadowing{winner: exact.Host, loser: route.Host})
+			}
+		}
+		for pattern, wildcard := range r.wildcardRoutes {
+			switch {
+			case !sameListener(route, wildcard):
+			case matchWildcard(pattern, route.Pattern):
+				found = append(found, shadowing{winner: wildcard.Host, loser: route.Host})
+			case matchWildcard(route.Pattern, pattern):
+				found = append(found, shadowing{winner: route.Host, loser: wildcard.Host})
+			}
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].winner != found[j].winner {
+			return found[i].winner < found[j].winner
+		}
+		return found[i].loser < found[j].loser
+	})
+	return found
+}
+
+// sameListener reports whether a and b are served on the same listener: both
+// on the HTTP and HTTPS entrypoints, or both on the same TCP entrypoint.
+func sameListener(a, b *Route) bool {
+	aTCP, bTCP := a.Protocol == ProtocolTCP, b.Protocol == ProtocolTCP
+	return aTCP == bTCP && (!aTCP || strings.EqualFold(a.Entrypoint, b.Entrypoint))
+}
+
 // Add adds a new route to the registry.
 // Returns ErrRouteExists if an exact route for the host already exists.
 // Returns ErrWildcardRouteExists if a wildcard route for the pattern already exists.
+// Returns an error wrapping ErrInvalidBackend if a backend is not a host:port address.
+// A route overlapping an existing one (e.g., api.app.localhost and
+// *.app.localhost) is logged, or rejected with ErrRouteShadowed if
+// SetRejectShadowing is enabled.
+// Returns an error wrapping ErrTooManyRoutes if the registry is full.
 func (r *Registry) Add(route Route) error {
+	if err := validateBackends(&route); err != nil {
+		return err
+	}
+	if err := normalizeRoutePath(&route); err != nil {
+		return err
+	}
+
 	r.mu.Lock()
 
 	// Set creation time if not provided
@@ -123,6 +619,10 @@ func (r *Registry) Add(route Route) error {
 		route.CreatedAt = time.Now()
 	}
 
+	// Host and entrypoint names are case-insensitive
+	route.Host = normalizeHost(route.Host)
+	route.Entrypoint = strings.ToLower(route.Entrypoint)
+
 	if isWildcardHost(route.Host) {
 		// Handle wildcard route
 		route.IsWildcard = true
@@ -132,32 +632,237 @@ func (r *Registry) Add(route Route) error {
 			r.mu.Unlock()
 			return ErrWildcardRouteExists
 		}
+	} else if _, exists := r.routes[routeKey(&route)]; exists {
+		r.mu.Unlock()
+		return ErrRouteExists
+	}
+
+	if count, limit := len(r.routes)+len(r.wildcardRoutes), r.routeLimit(); count >= limit {
+		logger := r.logger
+		r.mu.Unlock()
+		if logger == nil {
+			logger = slog.Default()
+		}
+		logger.Error("route limit reached, rejecting route - raise proxy.max_routes if this is intended",
+			"host", route.Host,
+			"routes", count,
+			"limit", limit)
+		return fmt.Errorf("%w (%d routes), rejecting %s", ErrTooManyRoutes, limit, route.Host)
+	}
 
+	shadows := r.findShadowing(&route)
+	if len(shadows) > 0 && r.rejectShadowing {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %s takes precedence over %s", ErrRouteShadowed, shadows[0].winner, shadows[0].loser)
+	}
+
+	if route.IsWildcard {
 		r.wildcardRoutes[route.Pattern] = &route
 	} else {
-		// Handle exact route
-		if _, exists := r.routes[route.Host]; exists {
-			r.mu.Unlock()
-			return ErrRouteExists
-		}
+		r.setRoute(&route)
+	}
+
+	r.generation++
+	logger := r.logger
+	r.mu.Unlock()
 
-		r.routes[route.Host] = &route
+	if logger == nil {
+		logger = slog.Default()
+	}
+	for _, s := range shadows {
+		logger.Warn("route overlaps another route, the more specific one takes precedence",
+			"host", route.Host,
+			"winner", s.winner,
+			"shadowed", s.loser)
 	}
 
-	onChange := r.onChange
+	// Call onChange outside the lock to prevent deadlocks
+	r.notifyChange()
+
+	return nil
+}
+
+// AddBackend adds route.Backend, served by route.ContainerID, to the existing
+// HTTP route for route.Host and route.PathPrefix, so requests are balanced
+// across all containers serving the host.
+// Returns ErrRouteNotFound if no HTTP route exists for the host and
+// ErrRouteExists if the route already has a backend of this container or
+// comes from the config or an import.
+func (r *Registry) AddBackend(route Route) error {
+	if err := ValidateBackend(route.Backend, false); err != nil {
+		return err
+	}
+	if err := normalizeRoutePath(&route); err != nil {
+		return err
+	}
+	route.Host = normalizeHost(route.Host)
+
+	r.mu.Lock()
+
+	var existing *Route
+	if isWildcardHost(route.Host) {
+		existing = r.wildcardRoutes[wildcardPattern(route.Host)]
+	} else {
+		existing = r.routes[route.Host+route.PathPrefix]
+	}
+	if existing == nil || existing.Protocol == ProtocolTCP {
+		r.mu.Unlock()
+		return ErrRouteNotFound
+	}
+	// Containers never join the route of the config or an import
+	if existing.Source == SourceConfig || existing.Source == SourceManual {
+		r.mu.Unlock()
+		return fmt.Errorf("%w (%s route)", ErrRouteExists, existing.Source)
+	}
+
+	replicas := existing.replicaSet()
+	if slices.ContainsFunc(replicas, func(rep replica) bool { return rep.containerID == route.ContainerID }) {
+		r.mu.Unlock()
+		return ErrRouteExists
+	}
+
+	existing.setReplicas(append(replicas, replica{
+		backend:       route.Backend,
+		containerID:   route.ContainerID,
+		containerName: route.ContainerName,
+	}))
+
+	r.generation++
 	r.mu.Unlock()
 
 	// Call onChange outside the lock to prevent deadlocks
-	if onChange != nil {
-		onChange()
+	r.notifyChange()
+
+	return nil
+}
+
+// RemoveBackend removes the backend served by containerID from the routes
+// for host. Routes of the container that are not load-balanced are removed
+// entirely; load-balanced routes keep their other backends, and routes of
+// other containers on the same host (e.g., for another path) are kept.
+// Returns ErrRouteNotFound if the host has no route of the container.
+func (r *Registry) RemoveBackend(host, containerID string) error {
+	host = normalizeHost(host)
+	r.mu.Lock()
+
+	var found bool
+	for _, route := range r.hostRoutes(host) {
+		if route.dropReplica(containerID) {
+			found = true
+			continue
+		}
+		if route.ContainerID != containerID || len(route.Backends) > 0 {
+			continue
+		}
+		found = true
+		if route.IsWildcard {
+			delete(r.wildcardRoutes, route.Pattern)
+		} else {
+			r.deleteRoute(route)
+		}
+	}
+	if !found {
+		r.mu.Unlock()
+		return ErrRouteNotFound
 	}
 
+	r.generation++
+	r.mu.Unlock()
+
+	// Call onChange outside the lock to prevent deadlocks
+	r.notifyChange()
+
 	return nil
 }
 
+// SetHealthPolicy sets after how many consecutive connection failures a
+// backend is ejected and how long it is then skipped. Zero values select
+// DefaultFailureThreshold and DefaultEjectCooldown.
+func (r *Registry) SetHealthPolicy(threshold int, cooldown time.Duration) {
+	r.health.setPolicy(threshold, cooldown)
+}
+
+// SelectBackend returns the backend for the next request to route, skipping
+// ejected or unhealthy backends. Requests to load-balanced routes rotate
+// across backends. It reports false if none of the route's backends is available.
+func (r *Registry) SelectBackend(route *Route) (string, bool) {
+	now := time.Now()
+	available := func(backend string) bool {
+		return r.health.available(backend, now)
+	}
+	if len(route.Backends) < 2 {
+		return route.Backend, available(route.Backend)
+	}
+	return r.balancer.pick(route.Host, route.Backends, available)
+}
+
+// PinnedBackend returns the backend of route identified by the sticky
+// session id (see StickyID). It reports false if no backend has that id or
+// the backend is ejected or unhealthy, so the caller selects another one.
+func (r *Registry) PinnedBackend(route *Route, id string) (string, bool) {
+	backends := route.Backends
+	if len(backends) == 0 {
+		backends = []string{route.Backend}
+	}
+	for _, backend := range backends {
+		if StickyID(backend) == id {
+			return backend, r.health.available(backend, time.Now())
+		}
+	}
+	return "", false
+}
+
+// ReportFailure records a failed connection to backend and reports whether
+// the failure ejected it.
+func (r *Registry) ReportFailure(backend string) bool {
+	return r.health.failure(backend, time.Now())
+}
+
+// SetBackendHealthy records the result of an active health check. Unhealthy
+// backends are skipped by SelectBackend until they are reported healthy.
+func (r *Registry) SetBackendHealthy(backend string, healthy bool) {
+	r.health.setHealthy(backend, healthy)
+}
+
+// RecordHealthCheck records the result of an active health check of backend
+// that took latency and failed with err, or passed if err is nil. Like
+// SetBackendHealthy, a failure makes SelectBackend skip the backend.
+func (r *Registry) RecordHealthCheck(backend string, latency time.Duration, err error) {
+	r.health.recordCheck(backend, latency, err, time.Now())
+}
+
+// HealthStats returns the results of the active health checks of backend,
+// or false if it has not been checked.
+func (r *Registry) HealthStats(backend string) (HealthStats, bool) {
+	return r.health.checkStats(backend)
+}
+
+// HealthChecks returns the results of all active health checks by backend.
+func (r *Registry) HealthChecks() map[string]HealthStats {
+	return r.health.allCheckStats()
+}
+
+// ConnStats returns the reused and dialed connections of proxied requests by
+// backend.
+func (r *Registry) ConnStats() map[string]ConnStats {
+	return r.conns.all()
+}
+
+// ForgetHealthCheck removes the active health check state of backend, e.g.
+// once its container is gone, so the address is available again.
+func (r *Registry) ForgetHealthCheck(backend string) {
+	r.health.forgetCheck(backend)
+}
+
+// ReportSuccess records a successful request to backend, clearing its failures.
+func (r *Registry) ReportSuccess(backend string) {
+	r.health.success(backend)
+}
+
 // Remove removes a route from the registry.
 // Returns ErrRouteNotFound if the route doesn't exist.
 func (r *Registry) Remove(host string) error {
+	host = normalizeHost(host)
 	r.mu.Lock()
 
 	if isWildcardHost(host) {
@@ -168,19 +873,80 @@ func (r *Registry) Remove(host string) error {
 		}
 		delete(r.wildcardRoutes, pattern)
 	} else {
-		if _, exists := r.routes[host]; !exists {
+		routes := r.hostRoutes(host)
+		if len(routes) == 0 {
 			r.mu.Unlock()
 			return ErrRouteNotFound
 		}
-		delete(r.routes, host)
+		for _, route := range routes {
+			r.deleteRoute(route)
+		}
 	}
 
-	onChange := r.onChange
+	r.generation++
 	r.mu.Unlock()
 
 	// Call onChange outside the lock to prevent deadlocks
-	if onChange != nil {
-		onChange()
+	r.notifyChange()
+
+	return nil
+}
+
+// SetReady updates the readiness of a route.
+// Returns ErrRouteNotFound if the route doesn't exist.
+func (r *Registry) SetReady(host string, ready bool) error {
+	host = normalizeHost(host)
+	r.mu.Lock()
+
+	routes := r.hostRoutes(host)
+	if len(routes) == 0 {
+		r.mu.Unlock()
+		return ErrRouteNotFound
+	}
+
+	var changed bool
+	for _, route := range routes {
+		changed = changed || route.Ready != ready
+		route.Ready = ready
+	}
+	if changed {
+		r.generation++
+	}
+	r.mu.Unlock()
+
+	// Call onChange outside the lock to prevent deadlocks
+	if changed {
+		r.notifyChange()
+	}
+
+	return nil
+}
+
+// SetEnabled enables or disables a route without removing it.
+// Returns ErrRouteNotFound if the route doesn't exist.
+func (r *Registry) SetEnabled(host string, enabled bool) error {
+	host = normalizeHost(host)
+	r.mu.Lock()
+
+	routes := r.hostRoutes(host)
+	if len(routes) == 0 {
+		r.mu.Unlock()
+		return ErrRouteNotFound
+	}
+
+	var changed bool
+	for _, route := range routes {
+		changed = changed || route.Disabled == enabled
+		route.Disabled = !enabled
+	}
+	if changed {
+		r.generation++
+	}
+	r.mu.Unlock()
+
+	// Call onChange outside the lock to prevent deadlocks
+	if changed {
+		r.notifyChange()
 	}
 
 	return nil
@@ -192,54 +958,123 @@ func (r *Registry) RemoveByContainerID(containerID string) int {
 	r.mu.Lock()
 
 	var removed int
+	var changed bool
 
-	// Remove from exact routes
-	for host, route := range r.routes {
-		if route.ContainerID == containerID {
-			delete(r.routes, host)
+	// Remove from exact routes; load-balanced routes only lose the container's backend
+	for _, route := range r.routes {
+		if route.dropReplica(containerID) {
+			changed = true
+		} else if route.ContainerID == containerID {
+			r.deleteRoute(route)
 			removed++
 		}
 	}
 
 	// Remove from wildcard routes
 	for pattern, route := range r.wildcardRoutes {
-		if route.ContainerID == containerID {
+		if route.dropReplica(containerID) {
+			changed = true
+		} else if route.ContainerID == containerID {
 			delete(r.wildcardRoutes, pattern)
 			removed++
 		}
 	}
 
-	onChange := r.onChange
+	if removed > 0 || changed {
+		r.generation++
+	}
 	r.mu.Unlock()
 
 	// Call onChange outside the lock to prevent deadlocks
-	if removed > 0 && onChange != nil {
-		onChange()
+	if removed > 0 || changed {
+		r.notifyChange()
 	}
 
 	return removed
 }
 
+// hostRoutes returns the routes registered for host: the wildcard route for
+// a wildcard host, otherwise the exact routes (one per path prefix) and the
+// TCP routes on every entrypoint, ordered by entrypoint (HTTP first) and path.
+// The slice must not be modified. Must be called with r.mu held.
+func (r *Registry) hostRoutes(host string) []*Route {
+	if isWildcardHost(host) {
+		if route, exists := r.wildcardRoutes[wildcardPattern(host)]; exists {
+			return []*Route{route}
+		}
+		return nil
+	}
+	return r.byHost[host]
+}
+
+// setRoute stores an exact route, replacing the one with the same key, and
+// updates the host index. Must be called with r.mu held.
+func (r *Registry) setRoute(route *Route) {
+	key := routeKey(route)
+	r.routes[key] = route
+
+	routes := make([]*Route, 0, len(r.byHost[route.Host])+1)
+	for _, existing := range r.byHost[route.Host] {
+		if routeKey(existing) != key {
+			routes = append(routes, existing)
+		}
+	}
+	routes = append(routes, route)
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Entrypoint != routes[j].Entrypoint {
+			return routes[i].Entrypoint < routes[j].Entrypoint
+		}
+		return routes[i].PathPrefix < routes[j].PathPrefix
+	})
+	r.byHost[route.Host] = routes
+}
+
+// deleteRoute removes an exact route and updates the host index.
+// Must be called with r.mu held.
+func (r *Registry) deleteRoute(route *Route) {
+	key := routeKey(route)
+	delete(r.routes, key)
+
+	var routes []*Route
+	for _, existing := range r.byHost[route.Host] {
+		if routeKey(existing) != key {
+			routes = append(routes, existing)
+		}
+	}
+	if len(routes) == 0 {
+		delete(r.byHost, route.Host)
+		return
+	}
+	r.byHost[route.Host] = routes
+}
+
 // findMostSpecificWildcard finds the most specific matching wildcard route.
-// More specific = longer pattern (more domain segments).
+// More specific = longer pattern (more domain segments), so the parent
+// domains of host are tried from the longest.
 // Must be called with r.mu held.
 func (r *Registry) findMostSpecificWildcard(host string) *Route {
-	var bestMatch *Route
-	var bestLen int
-
-	for pattern, route := range r.wildcardRoutes {
-		if matchWildcard(host, pattern) && len(pattern) > bestLen {
-			bestMatch = route
-			bestLen = len(pattern)
+	for i := strings.IndexByte(host, '.'); i >= 0; {
+		if route, exists := r.wildcardRoutes[host[i+1:]]; exists {
+			return route
 		}
+		next := strings.IndexByte(host[i+1:], '.')
+		if next < 0 {
+			break
+		}
+		i += next + 1
 	}
-	return bestMatch
+	return nil
 }
 
 // Lookup finds a route by host.
 // Priority: exact match > most specific wildcard.
+// The host is matched case-insensitively and a trailing dot is ignored, so
+// single-label names such as "app" or "APP." find the route for "app".
+// The route without a path prefix is preferred; use LookupPath for requests.
 // Returns nil if not found.
 func (r *Registry) Lookup(host string) *Route {
+	host = normalizeHost(host)
+
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
@@ -248,6 +1083,10 @@ func (r *Registry) Lookup(host string) *Route {
 		routeCopy := *route
 		return &routeCopy
 	}
+	if routes := r.hostRoutes(host); len(routes) > 0 {
+		routeCopy := *routes[0]
+		return &routeCopy
+	}
 
 	// 2. Try wildcard match (most specific wins)
 	if route := r.findMostSpecificWildcard(host); route != nil {
@@ -258,6 +1097,60 @@ func (r *Registry) Lookup(host string) *Route {
 	return nil
 }
 
+// LookupPath finds the route for an HTTP request to host and path.
+// Priority: the exact route with the longest matching PathPrefix (a route
+// without one matches any path) > most specific wildcard. A host served only
+// on TCP entrypoints returns its first TCP route, as with Lookup.
+// Returns nil if not found.
+func (r *Registry) LookupPath(host, path string) *Route {
+	host = normalizeHost(host)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var best, tcp *Route
+	var hasHTTP bool
+	for _, route := range r.hostRoutes(host) {
+		if route.Protocol == ProtocolTCP && route.Entrypoint != "" {
+			if tcp == nil {
+				tcp = route
+			}
+			continue
+		}
+		hasHTTP = true
+		if matchPath(path, route.PathPrefix) && (best == nil || len(route.PathPrefix) > len(best.PathPrefix)) {
+			best = route
+		}
+	}
+	if best == nil && !hasHTTP {
+		best = tcp
+	}
+	if best == nil {
+		best = r.findMostSpecificWildcard(host)
+	}
+	if best == nil {
+		return nil
+	}
+	routeCopy := *best
+	return &routeCopy
+}
+
+// LookupEntrypoint finds the route for host on a TCP entrypoint.
+// A TCP route registered for this entrypoint wins; otherwise it falls back to Lookup.
+func (r *Registry) LookupEntrypoint(host, entrypoint string) *Route {
+	host = normalizeHost(host)
+
+	r.mu.RLock()
+	route, exists := r.routes[tcpKey(host, entrypoint)]
+	r.mu.RUnlock()
+
+	if exists {
+		routeCopy := *route
+		return &routeCopy
+	}
+	return r.Lookup(host)
+}
+
 // List returns a snapshot of all routes, sorted by host.
 func (r *Registry) List() []Route {
 	r.mu.RLock()
@@ -277,12 +1170,80 @@ func (r *Registry) List() []Route {
 
 	// Sort by host for consistent ordering
 	sort.Slice(routes, func(i, j int) bool {
-		return routes[i].Host < routes[j].Host
+		if routes[i].Host != routes[j].Host {
+			return routes[i].Host < routes[j].Host
+		}
+		if routes[i].Entrypoint != routes[j].Entrypoint {
+			return routes[i].Entrypoint < routes[j].Entrypoint
+		}
+		return routes[i].PathPrefix < routes[j].PathPrefix
 	})
 
 	return routes
 }
 
+// routeIdentity identifies a route for Diff. TCP routes are per entrypoint
+// and HTTP routes per path prefix, so those are part of the host key.
+type routeIdentity struct {
+	key      string
+	backend  string
+	protocol Protocol
+}
+
+// identity returns the identity of route.
+func identity(route *Route) routeIdentity {
+	// Add normalizes hosts, entrypoints and paths, so desired routes may differ in those only
+	prefix, err := NormalizePathPrefix(route.PathPrefix)
+	if err != nil {
+		prefix = route.PathPrefix
+	}
+	key := routeKey(&Route{Host: normalizeHost(route.Host), Protocol: route.Protocol, Entrypoint: strings.ToLower(route.Entrypoint), PathPrefix: prefix})
+	return routeIdentity{key: key, backend: route.Backend, protocol: route.Protocol}
+}
+
+// Diff compares the registry against the desired routes and returns the
+// routes to add and to remove to reach that state. Routes are equal if host,
+// backend and protocol (and the TCP entrypoint or HTTP path prefix) match; other fields are not
+// compared, so unchanged routes keep their state. Both results are sorted
+// like List.
+func (r *Registry) Diff(desired []Route) (toAdd, toRemove []Route) {
+	current := r.List()
+
+	have := make(map[routeIdentity]bool, len(current))
+	for i := range current {
+		have[identity(&current[i])] = true
+	}
+
+	want := make(map[routeIdentity]bool, len(desired))
+	for i := range desired {
+		id := identity(&desired[i])
+		if want[id] {
+			continue
+		}
+		want[id] = true
+		if !have[id] {
+			toAdd = append(toAdd, desired[i])
+		}
+	}
+
+	for i := range current {
+		if !want[identity(&current[i])] {
+			toRemove = append(toRemove, current[i])
+		}
+	}
+
+	sort.Slice(toAdd, func(i, j int) bool {
+		if toAdd[i].Host != toAdd[j].Host {
+			return toAdd[i].Host < toAdd[j].Host
+		}
+		if toAdd[i].Entrypoint != toAdd[j].Entrypoint {
+			return toAdd[i].Entrypoint < toAdd[j].Entrypoint
+		}
+		return toAdd[i].PathPrefix < toAdd[j].PathPrefix
+	})
+	return toAdd, toRemove
+}
+
 // Count returns the number of registered routes.
 func (r *Registry) Count() int {
 	r.mu.RLock()
@@ -296,6 +1257,7 @@ func (r *Registry) GetByEntrypoint(entrypoint string) []*Route {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	entrypoint = strings.ToLower(entrypoint)
 	var result []*Route
 	for _, route := range r.routes {
 		if route.Protocol == ProtocolTCP && route.Entrypoint == entrypoint {
@@ -313,12 +1275,15 @@ func (r *Registry) Clear() {
 	hadRoutes := len(r.routes) > 0 || len(r.wildcardRoutes) > 0
 	r.routes = make(map[string]*Route)
 	r.wildcardRoutes = make(map[string]*Route)
-	onChange := r.onChange
+	r.byHost = make(map[string][]*Route)
+	if hadRoutes {
+		r.generation++
+	}
 	r.mu.Unlock()
 
 	// Call onChange outside the lock to prevent deadlocks
-	if hadRoutes && onChange != nil {
-		onChange()
+	if hadRoutes {
+		r.notifyChange()
 	}
 }
 
@@ -334,26 +1299,8 @@ type RouteState struct {
 
 // SaveState writes the current routes to a state file for IPC with CLI.
 func (r *Registry) SaveState() error {
-	r.mu.RLock()
-	routes := make([]Route, 0, len(r.routes)+len(r.wildcardRoutes))
-
-	// Add exact routes
-	for _, route := range r.routes {
-		routes = append(routes, *route)
-	}
-
-	// Add wildcard routes
-	for _, route := range r.wildcardRoutes {
-		routes = append(routes, *route)
-	}
-	r.mu.RUnlock()
-
-	// Sort for consistent output
-	sort.Slice(routes, func(i, j int) bool {
-		return routes[i].Host < routes[j].Host
-	})
-
-	state := RouteState{Routes: routes}
+	// List sorts the routes, so unchanged routes are saved unchanged
+	state := RouteState{Routes: r.List()}
 
 	data, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
@@ -1,8 +1,20 @@
 package proxy
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/paths"
 )
 
 func TestRegistry_AddAndLookup(t *testing.T) {
@@ -102,6 +114,347 @@ func TestRegistry_RemoveByContainerID(t *testing.T) {
 	}
 }
 
+func TestRegistry_AddBackend(t *testing.T) {
+	newReg := func() *Registry {
+		reg := NewRegistry()
+		reg.Add(Route{Host: "web.localhost", Backend: "172.18.0.2:3000", Protocol: ProtocolHTTP, ContainerID: "web1", ContainerName: "web-1"})
+		reg.Add(Route{Host: "db.localhost", Backend: "172.18.0.5:5432", Protocol: ProtocolTCP, Entrypoint: "postgres", ContainerID: "db1"})
+		reg.Add(Route{Host: "static.localhost", Backend: "127.0.0.1:8080", Protocol: ProtocolHTTP, Source: SourceConfig})
+		return reg
+	}
+
+	t.Run("appends backend of another container", func(t *testing.T) {
+		reg := newReg()
+		if err := reg.AddBackend(Route{Host: "web.localhost", Backend: "172.18.0.3:3000", ContainerID: "web2", ContainerName: "web-2"}); err != nil {
+			t.Fatalf("AddBackend() error = %v", err)
+		}
+
+		route := reg.Lookup("web.localhost")
+		if !slices.Equal(route.Backends, []string{"172.18.0.2:3000", "172.18.0.3:3000"}) {
+			t.Errorf("unexpected backends %v", route.Backends)
+		}
+		if route.Backend != "172.18.0.2:3000" || route.ContainerName != "web-1" {
+			t.Errorf("expected first container to stay primary, got %+v", route)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		route   Route
+		wantErr error
+	}{
+		{name: "unknown host", route: Route{Host: "other.localhost", Backend: "172.18.0.3:3000", ContainerID: "web2"}, wantErr: ErrRouteNotFound},
+		{name: "same container", route: Route{Host: "web.localhost", Backend: "172.18.0.2:3000", ContainerID: "web1"}, wantErr: ErrRouteExists},
+		{name: "TCP route", route: Route{Host: "db.localhost", Backend: "172.18.0.6:5432", ContainerID: "db2"}, wantErr: ErrRouteNotFound},
+		{name: "invalid backend", route: Route{Host: "web.localhost", Backend: "172.18.0.3", ContainerID: "web2"}, wantErr: ErrInvalidBackend},
+		{name: "config route", route: Route{Host: "static.localhost", Backend: "172.18.0.3:3000", ContainerID: "web2"}, wantErr: ErrRouteExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := newReg().AddBackend(tt.route); !errors.Is(err, tt.wantErr) {
+				t.Errorf("AddBackend() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_RemoveBackend(t *testing.T) {
+	reg := NewRegistry()
+	reg.Add(Route{Host: "web.localhost", Backend: "172.18.0.2:3000", Protocol: ProtocolHTTP, ContainerID: "web1"})
+	reg.AddBackend(Route{Host: "web.localhost", Backend: "172.18.0.3:3000", ContainerID: "web2"})
+	reg.AddBackend(Route{Host: "web.localhost", Backend: "172.18.0.4:3000", ContainerID: "web3"})
+
+	if err := reg.RemoveBackend("web.localhost", "web1"); err != nil {
+		t.Fatalf("RemoveBackend() error = %v", err)
+	}
+	route := reg.Lookup("web.localhost")
+	if route.Backend != "172.18.0.3:3000" || route.ContainerID != "web2" {
+		t.Errorf("expected next replica to become primary, got %+v", route)
+	}
+
+	if removed := reg.RemoveByContainerID("web3"); removed != 0 {
+		t.Errorf("expected route to stay while a replica is left, got %d removed", removed)
+	}
+	route = reg.Lookup("web.localhost")
+	if route.Backend != "172.18.0.3:3000" || len(route.Backends) != 0 {
+		t.Errorf("expected single-backend route, got %+v", route)
+	}
+
+	if err := reg.RemoveBackend("web.localhost", "web2"); err != nil {
+		t.Fatalf("RemoveBackend() error = %v", err)
+	}
+	if reg.Lookup("web.localhost") != nil {
+		t.Error("expected route to be removed with its last backend")
+	}
+	if err := reg.RemoveBackend("web.localhost", "web2"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("expected ErrRouteNotFound, got %v", err)
+	}
+}
+
+func TestRegistry_RemoveBackend_KeepsOtherContainers(t *testing.T) {
+	reg := NewRegistry()
+	reg.Add(Route{Host: "app.localhost", Backend: "172.18.0.2:3000", Protocol: ProtocolHTTP, ContainerID: "web"})
+	reg.Add(Route{Host: "app.localhost", PathPrefix: "/api", Backend: "172.18.0.3:8080", Protocol: ProtocolHTTP, ContainerID: "api"})
+
+	if err := reg.RemoveBackend("app.localhost", "api"); err != nil {
+		t.Fatalf("RemoveBackend() error = %v", err)
+	}
+	if route := reg.LookupPath("app.localhost", "/api/users"); route == nil || route.ContainerID != "web" {
+		t.Errorf("expected web route to remain, got %+v", route)
+	}
+	if err := reg.RemoveBackend("app.localhost", "api"); !errors.Is(err, ErrRouteNotFound) {
+		t.Errorf("expected ErrRouteNotFound for container without routes, got %v", err)
+	}
+}
+
+func TestRegistry_SelectBackend(t *testing.T) {
+	reg := NewRegistry()
+	reg.Add(Route{Host: "web.localhost", Backend: "172.18.0.2:3000", Protocol: ProtocolHTTP, ContainerID: "web1"})
+
+	reg.SetHealthPolicy(1, time.Minute)
+
+	route := reg.Lookup("web.localhost")
+	if got, ok := reg.SelectBackend(route); !ok || got != "172.18.0.2:3000" {
+		t.Errorf("expected single backend, got %q, %v", got, ok)
+	}
+
+	reg.AddBackend(Route{Host: "web.localhost", Backend: "172.18.0.3:3000", ContainerID: "web2"})
+	route = reg.Lookup("web.localhost")
+	reg.ReportFailure("172.18.0.2:3000")
+	for i := 0; i < 3; i++ {
+		if got, ok := reg.SelectBackend(route); !ok || got != "172.18.0.3:3000" {
+			t.Errorf("expected healthy backend, got %q, %v", got, ok)
+		}
+	}
+
+	reg.ReportFailure("172.18.0.3:3000")
+	if _, ok := reg.SelectBackend(route); ok {
+		t.Error("expected no backend when all are ejected")
+	}
+
+	reg.ReportSuccess("172.18.0.2:3000")
+	if got, ok := reg.SelectBackend(route); !ok || got != "172.18.0.2:3000" {
+		t.Errorf("expected recovered backend, got %q, %v", got, ok)
+	}
+}
+
+func TestStripPathPrefix(t *testing.T) {
+	tests := []struct {
+		path   string
+		prefix string
+		want   string
+		wantOK bool
+	}{
+		{path: "/api/users", prefix: "/api", want: "/users", wantOK: true},
+		{path: "/api", prefix: "/api", want: "/", wantOK: true},
+		{path: "/api/", prefix: "/api", want: "/", wantOK: true},
+		{path: "/api/users/", prefix: "/api", want: "/users/", wantOK: true},
+		{path: "/apiv2", prefix: "/api", want: "/apiv2"},
+		{path: "/api/users", prefix: "", want: "/api/users"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path+" "+tt.prefix, func(t *testing.T) {
+			got, ok := stripPathPrefix(tt.path, tt.prefix)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("stripPathPrefix(%q, %q) = %q, %v, want %q, %v", tt.path, tt.prefix, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNormalizePathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{prefix: "", want: ""},
+		{prefix: "/", want: ""},
+		{prefix: "/api", want: "/api"},
+		{prefix: "/api/", want: "/api"},
+		{prefix: " /api/v1 ", want: "/api/v1"},
+		{prefix: "api", wantErr: true},
+		{prefix: "/api?x=1", wantErr: true},
+		{prefix: "/api/*", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := NormalizePathPrefix(tt.prefix)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizePathPrefix(%q) error = %v, wantErr %v", tt.prefix, err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPathPrefix) {
+				t.Errorf("expected ErrInvalidPathPrefix, got %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizePathPrefix(%q) = %q, want %q", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_HostCase(t *testing.T) {
+	reg := NewRegistry()
+	routes := []Route{
+		{Host: "App.Localhost.", Backend: "127.0.0.1:1", Protocol: ProtocolHTTP},
+		{Host: "*.Team.localhost", Backend: "127.0.0.1:2", Protocol: ProtocolHTTP},
+		{Host: "DB.localhost", Backend: "127.0.0.1:5432", Protocol: ProtocolTCP, Entrypoint: "postgres"},
+	}
+	for _, route := range routes {
+		if err := reg.Add(route); err != nil {
+			t.Fatalf("Add(%s) error = %v", route.Host, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		find func() *Route
+		want string
+	}{
+		{"lower-case host", func() *Route { return reg.Lookup("app.localhost") }, "127.0.0.1:1"},
+		{"mixed-case host", func() *Route { return reg.Lookup("APP.localhost") }, "127.0.0.1:1"},
+		{"wildcard", func() *Route { return reg.Lookup("api.TEAM.localhost.") }, "127.0.0.1:2"},
+		{"path", func() *Route { return reg.LookupPath("app.LOCALHOST", "/") }, "127.0.0.1:1"},
+		{"entrypoint", func() *Route { return reg.LookupEntrypoint("db.localhost.", "Postgres") }, "127.0.0.1:5432"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := tt.find()
+			if route == nil || route.Backend != tt.want {
+				t.Fatalf("got %+v, want backend %s", route, tt.want)
+			}
+		})
+	}
+
+	if err := reg.Add(Route{Host: "app.localhost", Backend: "127.0.0.1:3", Protocol: ProtocolHTTP}); !errors.Is(err, ErrRouteExists) {
+		t.Errorf("expected hosts differing only in case to conflict, got %v", err)
+	}
+	if err := reg.SetEnabled("APP.localhost", false); err != nil {
+		t.Errorf("SetEnabled() error = %v", err)
+	}
+	if err := reg.Remove("App.Localhost"); err != nil {
+		t.Errorf("Remove() error = %v", err)
+	}
+	if err := reg.Remove("*.team.LOCALHOST"); err != nil {
+		t.Errorf("Remove() wildcard error = %v", err)
+	}
+}
+
+func TestRegistry_LookupPath(t *testing.T) {
+	reg := NewRegistry()
+	reg.Add(Route{Host: "app.localhost", Backend: "127.0.0.1:1", Protocol: ProtocolHTTP})
+	reg.Add(Route{Host: "app.localhost", PathPrefix: "/api", Backend: "127.0.0.1:2", Protocol: ProtocolHTTP})
+	reg.Add(Route{Host: "app.localhost", PathPrefix: "/apiv2/", Backend: "127.0.0.1:3", Protocol: ProtocolHTTP})
+	reg.Add(Route{Host: "app.localhost", PathPrefix: "/api/admin", Backend: "127.0.0.1:4", Protocol: ProtocolHTTP})
+	reg.Add(Route{Host: "docs.localhost", PathPrefix: "/v1", Backend: "127.0.0.1:5", Protocol: ProtocolHTTP})
+	reg.Add(Route{Host: "*.localhost", Backend: "127.0.0.1:6", Protocol: ProtocolHTTP})
+	reg.Add(Route{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: ProtocolTCP, Entrypoint: "postgres"})
+
+	tests := []struct {
+		host string
+		path string
+		want string
+	}{
+		{"app.localhost", "/", "127.0.0.1:1"},
+		{"app.localhost", "/api", "127.0.0.1:2"},
+		{"app.localhost", "/api/users", "127.0.0.1:2"},
+		{"app.localhost", "/apiv2", "127.0.0.1:3"},
+		{"app.localhost", "/apiv2/users", "127.0.0.1:3"},
+		{"app.localhost", "/apiv3", "127.0.0.1:1"},
+		{"app.localhost", "/ap", "127.0.0.1:1"},
+		{"app.localhost", "/api/admin/users", "127.0.0.1:4"},
+		{"APP.localhost.", "/api", "127.0.0.1:2"},
+		// Paths not covered by a host's routes fall back to wildcards
+		{"docs.localhost", "/v1/guide", "127.0.0.1:5"},
+		{"docs.localhost", "/v2", "127.0.0.1:6"},
+		{"other.localhost", "/api", "127.0.0.1:6"},
+		// Hosts served only on TCP entrypoints keep returning their TCP route
+		{"db.localhost", "/", "127.0.0.1:5432"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host+tt.path, func(t *testing.T) {
+			route := reg.LookupPath(tt.host, tt.path)
+			if route == nil {
+				t.Fatalf("expected route for %s%s", tt.host, tt.path)
+			}
+			if route.Backend != tt.want {
+				t.Errorf("LookupPath(%q, %q) = %s, want %s", tt.host, tt.path, route.Backend, tt.want)
+			}
+		})
+	}
+
+	if route := reg.Lookup("app.localhost"); route == nil || route.PathPrefix != "" {
+		t.Errorf("expected Lookup to prefer the route without path prefix, got %+v", route)
+	}
+}
+
+func TestRegistry_LookupPathAfterChanges(t *testing.T) {
+	reg := NewRegistry()
+	reg.Add(Route{Host: "app.localhost", Backend: "127.0.0.1:1", Protocol: ProtocolHTTP})
+	reg.Add(Route{Host: "app.localhost", PathPrefix: "/api", Backend: "127.0.0.1:2", Protocol: ProtocolHTTP, ContainerID: "api"})
+	reg.Add(Route{Host: "*.localhost", Backend: "127.0.0.1:3", Protocol: ProtocolHTTP})
+
+	lookup := func(path string) string {
+		t.Helper()
+		route := reg.LookupPath("app.localhost", path)
+		if route == nil {
+			t.Fatalf("expected route for app.localhost%s", path)
+		}
+		return route.Backend
+	}
+
+	if got := lookup("/api/users"); got != "127.0.0.1:2" {
+		t.Errorf("before removal: got %s, want the /api route", got)
+	}
+
+	reg.RemoveByContainerID("api")
+	if got := lookup("/api/users"); got != "127.0.0.1:1" {
+		t.Errorf("after removing the /api route: got %s, want the host route", got)
+	}
+
+	reg.Add(Route{Host: "app.localhost", PathPrefix: "/api", Backend: "127.0.0.1:4", Protocol: ProtocolHTTP})
+	if got := lookup("/api/users"); got != "127.0.0.1:4" {
+		t.Errorf("after re-adding the /api route: got %s, want it", got)
+	}
+
+	if err := reg.Remove("app.localhost"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if got := lookup("/api/users"); got != "127.0.0.1:3" {
+		t.Errorf("after removing the host: got %s, want the wildcard route", got)
+	}
+}
+
+func TestRegistry_AddPathPrefix(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Add(Route{Host: "app.localhost", PathPrefix: "/api/", Backend: "127.0.0.1:1"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		route   Route
+		wantErr error
+	}{
+		{"same host and prefix", Route{Host: "app.localhost", PathPrefix: "/api", Backend: "127.0.0.1:2"}, ErrRouteExists},
+		{"wildcard host", Route{Host: "*.app.localhost", PathPrefix: "/api", Backend: "127.0.0.1:2"}, ErrInvalidPathPrefix},
+		{"TCP route", Route{Host: "db.localhost", PathPrefix: "/api", Backend: "127.0.0.1:2", Protocol: ProtocolTCP, Entrypoint: "postgres"}, ErrInvalidPathPrefix},
+		{"relative prefix", Route{Host: "app.localhost", PathPrefix: "api", Backend: "127.0.0.1:2"}, ErrInvalidPathPrefix},
+		{"other prefix", Route{Host: "app.localhost", PathPrefix: "/apiv2", Backend: "127.0.0.1:2"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := reg.Add(tt.route); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Add() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestRegistry_List(t *testing.T) {
 	reg := NewRegistry()
 
@@ -127,6 +480,95 @@ func TestRegistry_List(t *testing.T) {
 	}
 }
 
+func TestRegistry_Diff(t *testing.T) {
+	hosts := func(routes []Route) []string {
+		var out []string
+		for _, route := range routes {
+			out = append(out, route.Host+"@"+route.Entrypoint+"="+route.Backend)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name       string
+		current    []Route
+		desired    []Route
+		wantAdd    []string
+		wantRemove []string
+	}{
+		{
+			name:    "adds missing routes",
+			desired: []Route{{Host: "b.localhost", Backend: "127.0.0.1:2"}, {Host: "a.localhost", Backend: "127.0.0.1:1"}},
+			wantAdd: []string{"a.localhost@=127.0.0.1:1", "b.localhost@=127.0.0.1:2"},
+		},
+		{
+			name:       "removes routes no longer desired",
+			current:    []Route{{Host: "a.localhost", Backend: "127.0.0.1:1"}, {Host: "*.b.localhost", Backend: "127.0.0.1:2"}},
+			desired:    []Route{{Host: "a.localhost", Backend: "127.0.0.1:1"}},
+			wantRemove: []string{"*.b.localhost@=127.0.0.1:2"},
+		},
+		{
+			name:    "unchanged routes produce no diff",
+			current: []Route{{Host: "a.localhost", Backend: "127.0.0.1:1", Protocol: ProtocolHTTP}},
+			desired: []Route{{Host: "a.localhost", Backend: "127.0.0.1:1", Protocol: ProtocolHTTP, ContainerName: "renamed"}},
+		},
+		{
+			name:       "changed backend is replaced",
+			current:    []Route{{Host: "a.localhost", Backend: "127.0.0.1:1"}},
+			desired:    []Route{{Host: "a.localhost", Backend: "127.0.0.1:9"}},
+			wantAdd:    []string{"a.localhost@=127.0.0.1:9"},
+			wantRemove: []string{"a.localhost@=127.0.0.1:1"},
+		},
+		{
+			name:       "changed protocol is replaced",
+			current:    []Route{{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: ProtocolHTTP}},
+			desired:    []Route{{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: ProtocolTCP, Entrypoint: "postgres"}},
+			wantAdd:    []string{"db.localhost@postgres=127.0.0.1:5432"},
+			wantRemove: []string{"db.localhost@=127.0.0.1:5432"},
+		},
+		{
+			name: "TCP routes are compared per entrypoint",
+			current: []Route{
+				{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: ProtocolTCP, Entrypoint: "postgres"},
+			},
+			desired: []Route{
+				{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: ProtocolTCP, Entrypoint: "Postgres"},
+				{Host: "db.localhost", Backend: "127.0.0.1:5432", Protocol: ProtocolTCP, Entrypoint: "mysql"},
+			},
+			wantAdd: []string{"db.localhost@mysql=127.0.0.1:5432"},
+		},
+		{
+			name:    "hosts differing in case or trailing dot are unchanged",
+			current: []Route{{Host: "App.localhost", Backend: "127.0.0.1:1"}, {Host: "*.Team.localhost", Backend: "127.0.0.1:2"}},
+			desired: []Route{{Host: "app.LOCALHOST.", Backend: "127.0.0.1:1"}, {Host: "*.team.localhost", Backend: "127.0.0.1:2"}},
+		},
+		{
+			name:    "duplicate desired routes are added once",
+			desired: []Route{{Host: "a.localhost", Backend: "127.0.0.1:1"}, {Host: "a.localhost", Backend: "127.0.0.1:1"}},
+			wantAdd: []string{"a.localhost@=127.0.0.1:1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := NewRegistry()
+			for _, route := range tt.current {
+				if err := reg.Add(route); err != nil {
+					t.Fatalf("failed to add %s: %v", route.Host, err)
+				}
+			}
+
+			toAdd, toRemove := reg.Diff(tt.desired)
+			if got := hosts(toAdd); !slices.Equal(got, tt.wantAdd) {
+				t.Errorf("toAdd = %v, want %v", got, tt.wantAdd)
+			}
+			if got := hosts(toRemove); !slices.Equal(got, tt.wantRemove) {
+				t.Errorf("toRemove = %v, want %v", got, tt.wantRemove)
+			}
+		})
+	}
+}
+
 func TestRegistry_Count(t *testing.T) {
 	reg := NewRegistry()
 
@@ -180,6 +622,318 @@ func TestRegistry_OnChange(t *testing.T) {
 	}
 }
 
+func TestRegistry_OnChangeSerialized(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	reg := NewRegistry()
+
+	var running, overlapped atomic.Bool
+	var seen []uint64
+	reg.OnChange(func() {
+		if running.Swap(true) {
+			overlapped.Store(true)
+		}
+		defer running.Store(false)
+
+		seen = append(seen, reg.Generation())
+		time.Sleep(time.Millisecond) // A slow SaveState
+		if err := reg.SaveState(); err != nil {
+			t.Errorf("SaveState() error = %v", err)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range 10 {
+				host := fmt.Sprintf("app%d-%d.localhost", i, j)
+				reg.Add(Route{Host: host, Backend: "127.0.0.1:3000"})
+				if j%3 == 0 {
+					reg.Remove(host)
+				}
+				if i == 0 && j == 5 {
+					reg.Clear()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if overlapped.Load() {
+		t.Error("expected onChange calls not to overlap")
+	}
+	if !slices.IsSorted(seen) {
+		t.Errorf("expected onChange to see generations in order, got %v", seen)
+	}
+	if last := seen[len(seen)-1]; last != reg.Generation() {
+		t.Errorf("expected last onChange to see generation %d, got %d", reg.Generation(), last)
+	}
+
+	persisted, err := LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	hosts := func(routes []Route) []string {
+		var out []string
+		for _, route := range routes {
+			out = append(out, route.Host)
+		}
+		slices.Sort(out)
+		return out
+	}
+	if got, want := hosts(persisted), hosts(reg.List()); !slices.Equal(got, want) {
+		t.Errorf("persisted routes differ from registry:\ngot  %v\nwant %v", got, want)
+	}
+}
+
+func TestRegistry_SaveStateStable(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	paths.Reset()
+	defer paths.Reset()
+
+	reg := NewRegistry()
+	for _, prefix := range []string{"/c", "/a", "/b", "/e", "/d"} {
+		reg.Add(Route{Host: "app.localhost", PathPrefix: prefix, Backend: "127.0.0.1:3000"})
+	}
+
+	var saved []byte
+	for i := range 10 {
+		if err := reg.SaveState(); err != nil {
+			t.Fatalf("SaveState() error = %v", err)
+		}
+		data, err := os.ReadFile(StateFile())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i > 0 && !bytes.Equal(data, saved) {
+			t.Fatalf("state file changed between saves without route changes:\n%s\n%s", saved, data)
+		}
+		saved = data
+	}
+
+	persisted, err := LoadState()
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	var prefixes []string
+	for _, route := range persisted {
+		prefixes = append(prefixes, route.PathPrefix)
+	}
+	if want := []string{"/a", "/b", "/c", "/d", "/e"}; !slices.Equal(prefixes, want) {
+		t.Errorf("saved path prefixes = %v, want %v", prefixes, want)
+	}
+}
+
+func TestRegistry_SetReady(t *testing.T) {
+	reg := NewRegistry()
+
+	callCount := 0
+	reg.OnChange(func() {
+		callCount++
+	})
+
+	reg.Add(Route{Host: "a.localhost", Backend: "127.0.0.1:1"})
+	reg.Add(Route{Host: "*.b.localhost", Backend: "127.0.0.1:2"})
+
+	if route := reg.Lookup("a.localhost"); route.Ready {
+		t.Error("expected new route to not be ready")
+	}
+
+	if err := reg.SetReady("a.localhost", true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if route := reg.Lookup("a.localhost"); !route.Ready {
+		t.Error("expected route to be ready")
+	}
+	if callCount != 3 {
+		t.Errorf("expected onChange after SetReady, got %d calls", callCount)
+	}
+
+	// Setting the same value again does not trigger onChange
+	reg.SetReady("a.localhost", true)
+	if callCount != 3 {
+		t.Errorf("expected no onChange for unchanged readiness, got %d calls", callCount)
+	}
+
+	if err := reg.SetReady("*.b.localhost", true); err != nil {
+		t.Fatalf("unexpected error for wildcard route: %v", err)
+	}
+	if route := reg.Lookup("api.b.localhost"); !route.Ready {
+		t.Error("expected wildcard route to be ready")
+	}
+
+	if err := reg.SetReady("missing.localhost", true); err != ErrRouteNotFound {
+		t.Errorf("expected ErrRouteNotFound, got %v", err)
+	}
+}
+
+func TestValidateBackend(t *testing.T) {
+	tests := []struct {
+		backend       string
+		allowBareHost bool
+		wantErr       string
+	}{
+		{backend: "172.18.0.3:3000"},
+		{backend: "localhost:8080"},
+		{backend: "[fd00::3]:3000"},
+		{backend: "db", allowBareHost: true},
+		{backend: "", wantErr: "backend is empty"},
+		{backend: ":3000", wantErr: `":3000": missing host`},
+		{backend: "container::3000", wantErr: `"container::3000": too many colons in address`},
+		{backend: "container", wantErr: `"container": missing port in address`},
+		{backend: "container:http", wantErr: `port "http" must be a number`},
+		{backend: "container:70000", wantErr: `port "70000" must be a number`},
+		{backend: "container:", wantErr: `port "" must be a number`},
+		{backend: "fd00::3", allowBareHost: true, wantErr: "too many colons"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			err := ValidateBackend(tt.backend, tt.allowBareHost)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ValidateBackend(%q) error = %v", tt.backend, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidBackend) {
+				t.Fatalf("ValidateBackend(%q) error = %v, want ErrInvalidBackend", tt.backend, err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateBackend(%q) error = %q, want it to contain %q", tt.backend, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistry_Add_InvalidBackend(t *testing.T) {
+	tests := []struct {
+		name  string
+		route Route
+	}{
+		{name: "missing host", route: Route{Host: "app.localhost", Backend: ":3000", Protocol: ProtocolHTTP}},
+		{name: "double colon", route: Route{Host: "app.localhost", Backend: "container::3000", Protocol: ProtocolHTTP}},
+		{name: "HTTP without port", route: Route{Host: "app.localhost", Backend: "container", Protocol: ProtocolHTTP}},
+		{name: "invalid ALPN backend", route: Route{
+			Host: "db.localhost", Backend: "172.18.0.5:5432", Protocol: ProtocolTCP, Entrypoint: "postgres",
+			ALPNBackends: map[string]string{"h2": ":8443"},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg := NewRegistry()
+			err := reg.Add(tt.route)
+			if !errors.Is(err, ErrInvalidBackend) {
+				t.Errorf("Add() error = %v, want ErrInvalidBackend", err)
+			}
+			if reg.Count() != 0 {
+				t.Error("invalid route should not be added")
+			}
+		})
+	}
+
+	t.Run("TCP route may omit port", func(t *testing.T) {
+		reg := NewRegistry()
+		if err := reg.Add(Route{Host: "db.localhost", Backend: "db", Protocol: ProtocolTCP, Entrypoint: "postgres"}); err != nil {
+			t.Errorf("Add() error = %v", err)
+		}
+	})
+}
+
+func TestRegistry_TCPRoutesPerEntrypoint(t *testing.T) {
+	reg := NewRegistry()
+
+	routes := []Route{
+		{Host: "db.localhost", Backend: "172.18.0.5:5432", Protocol: ProtocolTCP, Entrypoint: "postgres"},
+		{Host: "db.localhost", Backend: "172.18.0.5:3306", Protocol: ProtocolTCP, Entrypoint: "mysql"},
+		{Host: "db.localhost", Backend: "172.18.0.5:80", Protocol: ProtocolHTTP},
+	}
+	for _, route := range routes {
+		if err := reg.Add(route); err != nil {
+			t.Fatalf("Add(%s on %q) error = %v", route.Host, route.Entrypoint, err)
+		}
+	}
+	if err := reg.Add(routes[0]); err != ErrRouteExists {
+		t.Errorf("expected ErrRouteExists for duplicate entrypoint, got %v", err)
+	}
+	if reg.Count() != 3 {
+		t.Fatalf("expected 3 routes, got %d", reg.Count())
+	}
+
+	tests := []struct {
+		entrypoint  string
+		wantBackend string
+	}{
+		{entrypoint: "postgres", wantBackend: "172.18.0.5:5432"},
+		{entrypoint: "mysql", wantBackend: "172.18.0.5:3306"},
+		// Entrypoints without their own route fall back to Lookup
+		{entrypoint: "mongo", wantBackend: "172.18.0.5:80"},
+	}
+	for _, tt := range tests {
+		route := reg.LookupEntrypoint("DB.localhost", tt.entrypoint)
+		if route == nil || route.Backend != tt.wantBackend {
+			t.Errorf("LookupEntrypoint(%s) = %+v, want backend %s", tt.entrypoint, route, tt.wantBackend)
+		}
+	}
+
+	if route := reg.Lookup("db.localhost"); route.Protocol != ProtocolHTTP {
+		t.Errorf("expected Lookup to prefer the HTTP route, got %+v", route)
+	}
+
+	// Host-level operations apply to every route of the host
+	if err := reg.SetReady("db.localhost", true); err != nil {
+		t.Fatalf("SetReady() error = %v", err)
+	}
+	if err := reg.SetEnabled("db.localhost", false); err != nil {
+		t.Fatalf("SetEnabled() error = %v", err)
+	}
+	for _, route := range reg.List() {
+		if !route.Ready || !route.Disabled {
+			t.Errorf("expected %s on %q to be ready and disabled, got %+v", route.Host, route.Entrypoint, route)
+		}
+	}
+
+	if err := reg.Remove("db.localhost"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if reg.Count() != 0 {
+		t.Errorf("expected all routes removed, got %d", reg.Count())
+	}
+}
+
+func TestRegistry_EntrypointCasing(t *testing.T) {
+	reg := NewRegistry()
+	if err := reg.Add(Route{Host: "db.localhost", Backend: "172.18.0.5:5432", Protocol: ProtocolTCP, Entrypoint: "Postgres"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	// The same entrypoint with different casing is a duplicate
+	if err := reg.Add(Route{Host: "db.localhost", Backend: "172.18.0.6:5432", Protocol: ProtocolTCP, Entrypoint: "postgres"}); err != ErrRouteExists {
+		t.Errorf("expected ErrRouteExists, got %v", err)
+	}
+
+	if route := reg.LookupEntrypoint("db.localhost", "postgres"); route == nil || route.Entrypoint != "postgres" {
+		t.Errorf("LookupEntrypoint(postgres) = %+v, want route on postgres", route)
+	}
+	if routes := reg.GetByEntrypoint("POSTGRES"); len(routes) != 1 {
+		t.Errorf("GetByEntrypoint(POSTGRES) returned %d routes, want 1", len(routes))
+	}
+}
+
+func TestRegistry_LookupTCPOnlyHost(t *testing.T) {
+	reg := NewRegistry()
+	reg.Add(Route{Host: "db.localhost", Backend: "172.18.0.5:5432", Protocol: ProtocolTCP, Entrypoint: "postgres"})
+
+	if route := reg.Lookup("db.localhost"); route == nil || route.Entrypoint != "postgres" {
+		t.Errorf("expected Lookup to find TCP route, got %+v", route)
+	}
+}
+
 func TestRegistry_ConcurrentAccess(t *testing.T) {
 	reg := NewRegistry()
 
@@ -401,6 +1155,122 @@ func TestRegistry_WildcardDuplicateRejection(t *testing.T) {
 	}
 }
 
+func TestRegistry_ShadowingWarnings(t *testing.T) {
+	tcp := func(entrypoint string) Route { return Route{Protocol: ProtocolTCP, Entrypoint: entrypoint} }
+
+	tests := []struct {
+		name         string
+		existing     []string
+		add          string
+		existingOn   Route // protocol and entrypoint of the existing routes
+		addOn        Route // protocol and entrypoint of the added route
+		wantShadows  bool
+		lookup       string
+		wantLookedUp string
+	}{
+		{name: "exact under wildcard", existing: []string{"*.app.localhost"}, add: "api.app.localhost", wantShadows: true, lookup: "api.app.localhost", wantLookedUp: "api.app.localhost"},
+		{name: "wildcard over exact", existing: []string{"api.app.localhost"}, add: "*.app.localhost", wantShadows: true, lookup: "api.app.localhost", wantLookedUp: "api.app.localhost"},
+		{name: "narrower wildcard", existing: []string{"*.app.localhost"}, add: "*.api.app.localhost", wantShadows: true, lookup: "v1.api.app.localhost", wantLookedUp: "*.api.app.localhost"},
+		{name: "broader wildcard", existing: []string{"*.api.app.localhost"}, add: "*.app.localhost", wantShadows: true, lookup: "v1.api.app.localhost", wantLookedUp: "*.api.app.localhost"},
+		{name: "apex is not shadowed", existing: []string{"*.app.localhost"}, add: "app.localhost", wantShadows: false, lookup: "app.localhost", wantLookedUp: "app.localhost"},
+		{name: "unrelated hosts", existing: []string{"*.other.localhost"}, add: "api.app.localhost", wantShadows: false, lookup: "api.app.localhost", wantLookedUp: "api.app.localhost"},
+		{name: "TCP route under HTTP wildcard", existing: []string{"*.app.localhost"}, add: "db.app.localhost", addOn: tcp("postgres"), wantShadows: false, lookup: "db.app.localhost", wantLookedUp: "db.app.localhost"},
+		{name: "TCP routes on other entrypoints", existing: []string{"*.app.localhost"}, existingOn: tcp("redis"), add: "db.app.localhost", addOn: tcp("postgres"), wantShadows: false, lookup: "db.app.localhost", wantLookedUp: "db.app.localhost"},
+		{name: "TCP routes on the same entrypoint", existing: []string{"*.app.localhost"}, existingOn: tcp("postgres"), add: "db.app.localhost", addOn: tcp("postgres"), wantShadows: true, lookup: "db.app.localhost", wantLookedUp: "db.app.localhost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			reg := NewRegistry()
+			reg.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+			for _, host := range tt.existing {
+				if err := reg.Add(Route{Host: host, Backend: "127.0.0.1:3000", Protocol: tt.existingOn.Protocol, Entrypoint: tt.existingOn.Entrypoint}); err != nil {
+					t.Fatalf("Add(%q) error = %v", host, err)
+				}
+			}
+
+			if err := reg.Add(Route{Host: tt.add, Backend: "127.0.0.1:4000", Protocol: tt.addOn.Protocol, Entrypoint: tt.addOn.Entrypoint}); err != nil {
+				t.Fatalf("Add(%q) error = %v", tt.add, err)
+			}
+
+			if got := strings.Contains(logs.String(), "route overlaps another route"); got != tt.wantShadows {
+				t.Errorf("shadowing warning logged = %v, want %v: %s", got, tt.wantShadows, logs.String())
+			}
+			if route := reg.Lookup(tt.lookup); route == nil || route.Host != tt.wantLookedUp {
+				t.Errorf("Lookup(%q) = %+v, want route %q", tt.lookup, route, tt.wantLookedUp)
+			}
+		})
+	}
+}
+
+func TestRegistry_RejectShadowing(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	reg.SetRejectShadowing(true)
+
+	if err := reg.Add(Route{Host: "*.app.localhost", Backend: "127.0.0.1:3000"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	err := reg.Add(Route{Host: "api.app.localhost", Backend: "127.0.0.1:4000"})
+	if !errors.Is(err, ErrRouteShadowed) {
+		t.Fatalf("expected ErrRouteShadowed, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "api.app.localhost takes precedence over *.app.localhost") {
+		t.Errorf("expected error to explain precedence, got %q", err)
+	}
+	if reg.Lookup("api.app.localhost").Host != "*.app.localhost" {
+		t.Error("expected rejected route not to be registered")
+	}
+
+	// The apex is not covered by the wildcard
+	if err := reg.Add(Route{Host: "app.localhost", Backend: "127.0.0.1:4000"}); err != nil {
+		t.Errorf("expected apex route to be accepted, got %v", err)
+	}
+}
+
+func TestRegistry_MaxRoutes(t *testing.T) {
+	var logs bytes.Buffer
+	reg := NewRegistry()
+	reg.SetLogger(slog.New(slog.NewTextHandler(&logs, nil)))
+	reg.SetMaxRoutes(3)
+
+	for _, host := range []string{"a.localhost", "b.localhost", "*.c.localhost"} {
+		if err := reg.Add(Route{Host: host, Backend: "127.0.0.1:3000"}); err != nil {
+			t.Fatalf("Add(%q) error = %v", host, err)
+		}
+	}
+
+	for _, host := range []string{"d.localhost", "*.e.localhost"} {
+		err := reg.Add(Route{Host: host, Backend: "127.0.0.1:4000"})
+		if !errors.Is(err, ErrTooManyRoutes) {
+			t.Fatalf("Add(%q) expected ErrTooManyRoutes, got %v", host, err)
+		}
+		if reg.Lookup(host) != nil {
+			t.Errorf("expected %s not to be registered", host)
+		}
+	}
+	if !strings.Contains(logs.String(), "route limit reached") || !strings.Contains(logs.String(), "level=ERROR") {
+		t.Errorf("expected rejection to be logged as an error, got %q", logs.String())
+	}
+
+	// Existing routes keep working and their backends can still scale
+	if reg.Count() != 3 || reg.Lookup("a.localhost") == nil || reg.Lookup("x.c.localhost") == nil {
+		t.Error("expected existing routes to be unaffected")
+	}
+	if err := reg.AddBackend(Route{Host: "a.localhost", Backend: "127.0.0.1:3001", ContainerID: "second"}); err != nil {
+		t.Errorf("AddBackend() error = %v", err)
+	}
+
+	// Removing a route frees a slot
+	if err := reg.Remove("b.localhost"); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if err := reg.Add(Route{Host: "d.localhost", Backend: "127.0.0.1:4000"}); err != nil {
+		t.Errorf("expected route to fit after removal, got %v", err)
+	}
+}
+
 func TestRegistry_WildcardMixedWithExact(t *testing.T) {
 	reg := NewRegistry()
 
@@ -528,3 +1398,112 @@ func TestRegistry_WildcardClear(t *testing.T) {
 		t.Error("expected wildcard route to be cleared")
 	}
 }
+
+func TestRoute_BackendForALPN(t *testing.T) {
+	route := Route{
+		Host:    "api.localhost",
+		Backend: "127.0.0.1:8080",
+		ALPNBackends: map[string]string{
+			"h2":       "127.0.0.1:50051",
+			"http/1.1": "127.0.0.1:8081",
+		},
+	}
+
+	tests := []struct {
+		name         string
+		protocols    []string
+		wantBackend  string
+		wantProtocol string
+	}{
+		{name: "first offered match wins", protocols: []string{"h2", "http/1.1"}, wantBackend: "127.0.0.1:50051", wantProtocol: "h2"},
+		{name: "client preference order", protocols: []string{"http/1.1", "h2"}, wantBackend: "127.0.0.1:8081", wantProtocol: "http/1.1"},
+		{name: "skips unmapped protocols", protocols: []string{"spdy/3", "h2"}, wantBackend: "127.0.0.1:50051", wantProtocol: "h2"},
+		{name: "no match", protocols: []string{"spdy/3"}},
+		{name: "no protocols"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend, protocol := route.BackendForALPN(tt.protocols)
+			if backend != tt.wantBackend || protocol != tt.wantProtocol {
+				t.Errorf("expected (%q, %q), got (%q, %q)", tt.wantBackend, tt.wantProtocol, backend, protocol)
+			}
+		})
+	}
+}
+
+func TestRegistry_SetEnabled(t *testing.T) {
+	t.Run("toggles route and notifies on change", func(t *testing.T) {
+		r := NewRegistry()
+		r.Add(Route{Host: "*.app.localhost", Backend: "127.0.0.1:3000"})
+
+		changes := 0
+		r.OnChange(func() { changes++ })
+
+		if err := r.SetEnabled("*.app.localhost", false); err != nil {
+			t.Fatalf("SetEnabled() error = %v", err)
+		}
+		if route := r.Lookup("web.app.localhost"); route == nil || !route.Disabled {
+			t.Errorf("expected disabled route to still be found, got %+v", route)
+		}
+
+		// No change, no notification
+		if err := r.SetEnabled("*.app.localhost", false); err != nil {
+			t.Fatalf("SetEnabled() error = %v", err)
+		}
+
+		if err := r.SetEnabled("*.app.localhost", true); err != nil {
+			t.Fatalf("SetEnabled() error = %v", err)
+		}
+		if route := r.Lookup("web.app.localhost"); route == nil || route.Disabled {
+			t.Errorf("expected enabled route, got %+v", route)
+		}
+
+		if changes != 2 {
+			t.Errorf("expected 2 change notifications, got %d", changes)
+		}
+	})
+
+	t.Run("returns error for unknown route", func(t *testing.T) {
+		r := NewRegistry()
+		if err := r.SetEnabled("missing.localhost", false); err != ErrRouteNotFound {
+			t.Errorf("expected ErrRouteNotFound, got %v", err)
+		}
+	})
+}
+
+func TestRegistry_LookupSingleLabel(t *testing.T) {
+	r := NewRegistry()
+	r.Add(Route{Host: "app", Backend: "127.0.0.1:3000"})
+	r.Add(Route{Host: "*.db", Backend: "127.0.0.1:5432"})
+
+	tests := []struct {
+		host        string
+		wantBackend string
+	}{
+		{host: "app", wantBackend: "127.0.0.1:3000"},
+		{host: "APP", wantBackend: "127.0.0.1:3000"},
+		{host: "app.", wantBackend: "127.0.0.1:3000"},
+		{host: "main.db", wantBackend: "127.0.0.1:5432"},
+		{host: "db", wantBackend: ""}, // Wildcards match subdomains only
+		{host: "apps", wantBackend: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			route := r.Lookup(tt.host)
+			if tt.wantBackend == "" {
+				if route != nil {
+					t.Errorf("expected no route, got %s", route.Backend)
+				}
+				return
+			}
+			if route == nil {
+				t.Fatalf("expected route for %s", tt.host)
+			}
+			if route.Backend != tt.wantBackend {
+				t.Errorf("expected backend %s, got %s", tt.wantBackend, route.Backend)
+			}
+		})
+	}
+}
@@ -22,7 +22,8 @@ const (
 
 // TLS extension types
 const (
-	tlsExtensionSNI = 0x0000
+	tlsExtensionSNI  = 0x0000
+	tlsExtensionALPN = 0x0010
 )
 
 // SNI name types
@@ -38,6 +39,16 @@ var (
 	ErrInvalidClientHello = errors.New("invalid ClientHello message")
 )
 
+// ClientHello holds the fields of a TLS ClientHello used for routing.
+type ClientHello struct {
+	// ServerName is the SNI hostname (empty if the client sent none).
+	ServerName string
+
+	// ALPNProtocols are the application protocols offered by the client,
+	// in the client's preference order (e.g., "h2", "http/1.1").
+	ALPNProtocols []string
+}
+
 // PeekedConn wraps a net.Conn and prepends peeked bytes to reads.
 // This allows replaying the peeked bytes to the backend.
 type PeekedConn struct {
@@ -111,29 +122,32 @@ func ExtractSNI(conn net.Conn) (hostname string, peeked []byte, err error) {
 	}
 
 	// Parse the handshake message
-	hostname, err = parseClientHello(peeked[5:])
+	hello, err := parseClientHello(peeked[5:])
 	if err != nil {
 		// Return peeked bytes even on parse error for passthrough
 		return "", peeked, err
 	}
 
-	return hostname, peeked, nil
+	return hello.ServerName, peeked, nil
 }
 
-// parseClientHello parses a TLS ClientHello message and extracts the SNI.
-func parseClientHello(data []byte) (string, error) {
+// parseClientHello parses a TLS ClientHello message and extracts the SNI
+// and ALPN extensions.
+func parseClientHello(data []byte) (ClientHello, error) {
+	var hello ClientHello
+
 	if len(data) < 4 {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 
 	// Handshake type (1) + Length (3)
 	if data[0] != tlsHandshakeTypeClientHello {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 
 	handshakeLen := int(data[1])<<16 | int(data[2])<<8 | int(data[3])
 	if len(data) < 4+handshakeLen {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 
 	// Move past handshake header
@@ -146,59 +160,59 @@ func parseClientHello(data []byte) (string, error) {
 
 	// Skip version (2 bytes)
 	if pos+2 > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 	pos += 2
 
 	// Skip random (32 bytes)
 	if pos+32 > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 	pos += 32
 
 	// Skip session ID
 	if pos+1 > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 	sessionIDLen := int(data[pos])
 	pos++
 	if pos+sessionIDLen > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 	pos += sessionIDLen
 
 	// Skip cipher suites
 	if pos+2 > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 	cipherSuitesLen := int(binary.BigEndian.Uint16(data[pos : pos+2]))
 	pos += 2
 	if pos+cipherSuitesLen > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 	pos += cipherSuitesLen
 
 	// Skip compression methods
 	if pos+1 > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 	compressionLen := int(data[pos])
 	pos++
 	if pos+compressionLen > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 	pos += compressionLen
 
 	// Check if we have extensions
 	if pos+2 > len(data) {
-		// No extensions, no SNI
-		return "", nil
+		// No extensions, no SNI or ALPN
+		return hello, nil
 	}
 
 	extensionsLen := int(binary.BigEndian.Uint16(data[pos : pos+2]))
 	pos += 2
 	if pos+extensionsLen > len(data) {
-		return "", ErrInvalidClientHello
+		return hello, ErrInvalidClientHello
 	}
 
 	// Parse extensions
@@ -209,18 +223,28 @@ func parseClientHello(data []byte) (string, error) {
 		pos += 4
 
 		if pos+extLen > extensionsEnd {
-			return "", ErrInvalidClientHello
+			return hello, ErrInvalidClientHello
 		}
 
-		if extType == tlsExtensionSNI {
-			return parseSNIExtension(data[pos : pos+extLen])
+		switch extType {
+		case tlsExtensionSNI:
+			serverName, err := parseSNIExtension(data[pos : pos+extLen])
+			if err != nil {
+				return hello, err
+			}
+			hello.ServerName = serverName
+		case tlsExtensionALPN:
+			protocols, err := parseALPNExtension(data[pos : pos+extLen])
+			if err != nil {
+				return hello, err
+			}
+			hello.ALPNProtocols = protocols
 		}
 
 		pos += extLen
 	}
 
-	// No SNI extension found
-	return "", nil
+	return hello, nil
 }
 
 // parseSNIExtension extracts the hostname from an SNI extension.
@@ -257,43 +281,82 @@ func parseSNIExtension(data []byte) (string, error) {
 	return "", nil
 }
 
-// ExtractSNIFromBytes extracts SNI from a TLS ClientHello, given the first byte already peeked.
-// It reads additional bytes from conn as needed and returns all peeked bytes for replay.
-func ExtractSNIFromBytes(firstByte []byte, conn net.Conn) (hostname string, peeked []byte, err error) {
-	reader := bufio.NewReader(conn)
+// parseALPNExtension extracts the protocol names from an ALPN extension.
+func parseALPNExtension(data []byte) ([]string, error) {
+	if len(data) < 2 {
+		return nil, ErrInvalidClientHello
+	}
 
-	// We already have the first byte, read the rest of the header (4 more bytes)
-	headerRest := make([]byte, 4)
-	_, err = io.ReadFull(reader, headerRest)
-	if err != nil {
-		return "", firstByte, fmt.Errorf("reading TLS header: %w", err)
+	// Protocol name list length
+	listLen := int(binary.BigEndian.Uint16(data[0:2]))
+	if len(data) < 2+listLen {
+		return nil, ErrInvalidClientHello
+	}
+
+	var protocols []string
+	pos := 2
+	listEnd := 2 + listLen
+
+	for pos < listEnd {
+		nameLen := int(data[pos])
+		pos++
+
+		if nameLen == 0 || pos+nameLen > listEnd {
+			return nil, ErrInvalidClientHello
+		}
+
+		protocols = append(protocols, string(data[pos:pos+nameLen]))
+		pos += nameLen
 	}
 
-	header := append(firstByte, headerRest...)
+	return protocols, nil
+}
+
+// ExtractSNIFromBytes extracts SNI from a TLS ClientHello, given the bytes already peeked.
+// It reads additional bytes from conn as needed and returns all peeked bytes for replay.
+func ExtractSNIFromBytes(alreadyPeeked []byte, conn net.Conn) (hostname string, peeked []byte, err error) {
+	hello, peeked, err := ExtractClientHelloFromBytes(alreadyPeeked, conn)
+	return hello.ServerName, peeked, err
+}
+
+// ExtractClientHelloFromBytes parses a TLS ClientHello, given the bytes already peeked.
+// It reads additional bytes from conn as needed and returns all peeked bytes for replay.
+func ExtractClientHelloFromBytes(alreadyPeeked []byte, conn net.Conn) (hello ClientHello, peeked []byte, err error) {
+	peeked = append([]byte(nil), alreadyPeeked...)
+
+	// Read the rest of the 5-byte TLS record header if needed
+	if len(peeked) < 5 {
+		headerRest := make([]byte, 5-len(peeked))
+		if _, err := io.ReadFull(conn, headerRest); err != nil {
+			return hello, peeked, fmt.Errorf("reading TLS header: %w", err)
+		}
+		peeked = append(peeked, headerRest...)
+	}
 
 	// Get record length
-	recordLen := int(binary.BigEndian.Uint16(header[3:5]))
+	recordLen := int(binary.BigEndian.Uint16(peeked[3:5]))
 	if recordLen < 4 || recordLen > 16384 {
-		return "", header, ErrInvalidClientHello
+		return hello, peeked, ErrInvalidClientHello
 	}
 
-	// Read the entire TLS record body
-	recordBody := make([]byte, recordLen)
-	_, err = io.ReadFull(reader, recordBody)
-	if err != nil {
-		return "", header, fmt.Errorf("reading TLS record: %w", err)
+	// Read the remainder of the TLS record body. Reading exactly the missing bytes
+	// (rather than through a buffered reader) ensures nothing is consumed that
+	// would not be replayed.
+	if missing := 5 + recordLen - len(peeked); missing > 0 {
+		bodyRest := make([]byte, missing)
+		if _, err := io.ReadFull(conn, bodyRest); err != nil {
+			return hello, peeked, fmt.Errorf("reading TLS record: %w", err)
+		}
+		peeked = append(peeked, bodyRest...)
 	}
 
-	// Combine all peeked bytes
-	peeked = append(header, recordBody...)
-
 	// Parse the handshake message
-	hostname, err = parseClientHello(recordBody)
+	hello, err = parseClientHello(peeked[5 : 5+recordLen])
 	if err != nil {
-		return "", peeked, err
+		return ClientHello{}, peeked, err
 	}
 
-	return hostname, peeked, nil
+	return hello, peeked, nil
 }
 
 // UnderlyingConn returns the underlying connection from a PeekedConn.
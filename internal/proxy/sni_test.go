@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"bytes"
+	"fmt"
 	"io"
 	"net"
 	"testing"
@@ -47,6 +48,11 @@ func (m *mockConn) SetWriteDeadline(t time.Time) error { return nil }
 
 // buildClientHello constructs a minimal TLS ClientHello with SNI
 func buildClientHello(hostname string) []byte {
+	return buildClientHelloWithALPN(hostname, nil)
+}
+
+// buildClientHelloWithALPN constructs a minimal TLS ClientHello with SNI and ALPN
+func buildClientHelloWithALPN(hostname string, protocols []string) []byte {
 	// Build SNI extension
 	var sniExt []byte
 	if hostname != "" {
@@ -74,8 +80,20 @@ func buildClientHello(hostname string) []byte {
 		copy(sniExt[4:], sniData)
 	}
 
+	// Build ALPN extension: list length (2) + (name length (1) + name)...
+	var alpnExt []byte
+	if len(protocols) > 0 {
+		var list []byte
+		for _, proto := range protocols {
+			list = append(list, byte(len(proto)))
+			list = append(list, proto...)
+		}
+		alpnData := append([]byte{byte(len(list) >> 8), byte(len(list))}, list...)
+		alpnExt = append([]byte{0x00, 0x10, byte(len(alpnData) >> 8), byte(len(alpnData))}, alpnData...)
+	}
+
 	// Build extensions block
-	extensions := sniExt
+	extensions := append(sniExt, alpnExt...)
 	extensionsLen := len(extensions)
 
 	// Build ClientHello body
@@ -252,6 +270,35 @@ func TestExtractSNI(t *testing.T) {
 	})
 }
 
+func TestExtractSNIFromBytes(t *testing.T) {
+	// peekConnectionType may hand over 1 byte (after a PostgreSQL SSLRequest) or
+	// up to 8 bytes (direct TLS), so all split points must reassemble the record
+	for _, split := range []int{1, 5, 8} {
+		t.Run(fmt.Sprintf("already peeked %d bytes", split), func(t *testing.T) {
+			clientHello := buildClientHello("db.localhost")
+			trailing := []byte("after-hello")
+			conn := newMockConn(append(append([]byte{}, clientHello[split:]...), trailing...))
+
+			hostname, peeked, err := ExtractSNIFromBytes(clientHello[:split], conn)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hostname != "db.localhost" {
+				t.Errorf("expected hostname 'db.localhost', got '%s'", hostname)
+			}
+			if !bytes.Equal(peeked, clientHello) {
+				t.Error("peeked bytes don't match original ClientHello")
+			}
+
+			// Bytes after the ClientHello must remain unread on the connection
+			rest, _ := io.ReadAll(conn)
+			if !bytes.Equal(rest, trailing) {
+				t.Errorf("expected trailing bytes %q to remain, got %q", trailing, rest)
+			}
+		})
+	}
+}
+
 func TestPeekedConn(t *testing.T) {
 	t.Run("returns peeked bytes first", func(t *testing.T) {
 		peeked := []byte("peeked data")
@@ -347,6 +394,38 @@ func TestParseClientHello(t *testing.T) {
 		}
 	})
 
+	t.Run("parses SNI and ALPN", func(t *testing.T) {
+		record := buildClientHelloWithALPN("grpc.localhost", []string{"h2", "http/1.1"})
+		hello, err := parseClientHello(record[5:])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if hello.ServerName != "grpc.localhost" {
+			t.Errorf("expected server name grpc.localhost, got %q", hello.ServerName)
+		}
+		if len(hello.ALPNProtocols) != 2 || hello.ALPNProtocols[0] != "h2" || hello.ALPNProtocols[1] != "http/1.1" {
+			t.Errorf("expected ALPN [h2 http/1.1], got %v", hello.ALPNProtocols)
+		}
+	})
+
+	t.Run("parses ALPN without SNI", func(t *testing.T) {
+		record := buildClientHelloWithALPN("", []string{"h2"})
+		hello, err := parseClientHello(record[5:])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if hello.ServerName != "" || len(hello.ALPNProtocols) != 1 || hello.ALPNProtocols[0] != "h2" {
+			t.Errorf("unexpected hello: %+v", hello)
+		}
+	})
+
+	t.Run("returns error for malformed ALPN", func(t *testing.T) {
+		_, err := parseALPNExtension([]byte{0x00, 0x03, 0x05, 'h', '2'})
+		if err != ErrInvalidClientHello {
+			t.Errorf("expected ErrInvalidClientHello, got %v", err)
+		}
+	})
+
 	t.Run("returns error for wrong handshake type", func(t *testing.T) {
 		// Type 2 = ServerHello instead of ClientHello
 		data := []byte{0x02, 0x00, 0x00, 0x10}
@@ -9,10 +9,13 @@ import (
 	"io"
 	"log/slog"
 	"net"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/munichmade/devproxy/internal/cert"
+	"github.com/munichmade/devproxy/internal/listen"
+	"github.com/munichmade/devproxy/internal/metrics"
 )
 
 const (
@@ -31,18 +34,45 @@ var (
 	ErrEntrypointClosed = errors.New("entrypoint closed")
 )
 
+// TargetPortMode controls when a TCP entrypoint's target port replaces the
+// route's backend port.
+type TargetPortMode string
+
+const (
+	// TargetPortForce always uses the target port, except for ports paired
+	// with the entrypoint in labels. This is the default.
+	TargetPortForce TargetPortMode = "force"
+
+	// TargetPortDefault uses the target port only if the route's port was
+	// not set in labels.
+	TargetPortDefault TargetPortMode = "default"
+
+	// TargetPortIgnore always uses the route's backend port.
+	TargetPortIgnore TargetPortMode = "ignore"
+)
+
 // TCPEntrypoint handles TCP connections with optional TLS termination.
 type TCPEntrypoint struct {
 	name        string
 	listen      string
 	targetPort  int
+	portMode    TargetPortMode
+	defaultHost string
+	rateLimit   int64
+	backlog     int
 	registry    *Registry
+	tcpRoutes   *TCPRegistry
 	certManager *cert.Manager
+	tickets     *SessionTicketKeys
 	logger      *slog.Logger
 
+	tlsOnce   sync.Once
+	tlsConfig *tls.Config
+
 	listener net.Listener
 	mu       sync.Mutex
 	running  bool
+	conns    map[net.Conn]struct{} // active connections, guarded by mu
 	wg       sync.WaitGroup
 }
 
@@ -51,9 +81,23 @@ type TCPEntrypointConfig struct {
 	Name        string
 	Listen      string
 	TargetPort  int
+	DefaultHost string // Route used when a connection has no SNI (optional)
+	RateLimit   int64  // Bytes per second per connection and direction (0 = unlimited)
+	Backlog     int    // Connections queued until accepted (0 = system default)
 	Registry    *Registry
 	CertManager *cert.Manager
 	Logger      *slog.Logger
+
+	// TargetPortMode controls when TargetPort replaces the route's port
+	// (optional, defaults to TargetPortForce)
+	TargetPortMode TargetPortMode
+
+	// TCPRoutes holds routes keyed by host and entrypoint (optional).
+	// Its routes for this entrypoint take precedence over Registry lookups.
+	TCPRoutes *TCPRegistry
+
+	// SessionTickets enables TLS session resumption (optional)
+	SessionTickets *SessionTicketKeys
 }
 
 // NewTCPEntrypoint creates a new TCP entrypoint.
@@ -64,11 +108,17 @@ func NewTCPEntrypoint(cfg TCPEntrypointConfig) *TCPEntrypoint {
 	}
 
 	return &TCPEntrypoint{
-		name:        cfg.Name,
+		name:        strings.ToLower(cfg.Name),
 		listen:      cfg.Listen,
 		targetPort:  cfg.TargetPort,
+		portMode:    cfg.TargetPortMode,
+		defaultHost: cfg.DefaultHost,
+		rateLimit:   cfg.RateLimit,
+		backlog:     cfg.Backlog,
 		registry:    cfg.Registry,
+		tcpRoutes:   cfg.TCPRoutes,
 		certManager: cfg.CertManager,
+		tickets:     cfg.SessionTickets,
 		logger:      logger.With("entrypoint", cfg.Name),
 	}
 }
@@ -91,7 +141,7 @@ func (e *TCPEntrypoint) Start(ctx context.Context) error {
 
 	// If no listener was provided, create one
 	if e.listener == nil {
-		listener, err := net.Listen("tcp", e.listen)
+		listener, err := listen.TCP(e.listen, e.backlog)
 		if err != nil {
 			e.mu.Unlock()
 			return fmt.Errorf("failed to listen on %s: %w", e.listen, err)
@@ -110,21 +160,11 @@ func (e *TCPEntrypoint) Start(ctx context.Context) error {
 	return nil
 }
 
-// Stop gracefully shuts down the entrypoint.
+// Stop gracefully shuts down the entrypoint: it stops accepting connections
+// and waits for active ones to finish. Connections still open when ctx is
+// done are closed. Stop may be called after Close to drain the connections.
 func (e *TCPEntrypoint) Stop(ctx context.Context) error {
-	e.mu.Lock()
-	if !e.running {
-		e.mu.Unlock()
-		return nil
-	}
-	e.running = false
-	listener := e.listener
-	e.mu.Unlock()
-
-	// Close listener to stop accepting new connections
-	if listener != nil {
-		listener.Close()
-	}
+	e.Close()
 
 	// Wait for active connections to finish (with timeout)
 	done := make(chan struct{})
@@ -137,12 +177,43 @@ func (e *TCPEntrypoint) Stop(ctx context.Context) error {
 	case <-done:
 		e.logger.Info("TCP entrypoint stopped gracefully")
 	case <-ctx.Done():
-		e.logger.Warn("TCP entrypoint shutdown timed out")
+		e.mu.Lock()
+		n := len(e.conns)
+		for conn := range e.conns {
+			conn.Close()
+		}
+		e.mu.Unlock()
+		e.logger.Warn("TCP entrypoint shutdown timed out, closed active connections", "connections", n)
 	}
 
 	return nil
 }
 
+// Close stops accepting connections without waiting for active ones, which
+// keep running until they end. It reports whether the entrypoint was running.
+// This frees the listen address for a replacement entrypoint on reload.
+func (e *TCPEntrypoint) Close() bool {
+	e.mu.Lock()
+	if !e.running {
+		e.mu.Unlock()
+		return false
+	}
+	e.running = false
+	listener := e.listener
+	tlsConfig := e.tlsConfig
+	e.mu.Unlock()
+
+	if tlsConfig != nil && e.tickets != nil {
+		e.tickets.Release(tlsConfig)
+	}
+
+	// Close listener to stop accepting new connections
+	if listener != nil {
+		listener.Close()
+	}
+	return true
+}
+
 // acceptLoop accepts incoming connections.
 func (e *TCPEntrypoint) acceptLoop(ctx context.Context) {
 	for {
@@ -160,9 +231,21 @@ func (e *TCPEntrypoint) acceptLoop(ctx context.Context) {
 			continue
 		}
 
+		e.mu.Lock()
+		if e.conns == nil {
+			e.conns = make(map[net.Conn]struct{})
+		}
+		e.conns[conn] = struct{}{}
+		e.mu.Unlock()
+
 		e.wg.Add(1)
 		go func() {
 			defer e.wg.Done()
+			defer func() {
+				e.mu.Lock()
+				delete(e.conns, conn)
+				e.mu.Unlock()
+			}()
 			e.handleConnection(ctx, conn)
 		}()
 	}
@@ -171,6 +254,7 @@ func (e *TCPEntrypoint) acceptLoop(ctx context.Context) {
 // handleConnection processes a single TCP connection.
 func (e *TCPEntrypoint) handleConnection(ctx context.Context, conn net.Conn) {
 	defer conn.Close()
+	defer metrics.TCPConnectionOpened(e.name)()
 
 	clientAddr := conn.RemoteAddr().String()
 
@@ -183,60 +267,72 @@ func (e *TCPEntrypoint) handleConnection(ctx context.Context, conn net.Conn) {
 
 	var route *Route
 	var serverName string
+	var sniMissing bool
+	var hello ClientHello
 
 	if isTLS {
-		// Extract SNI from TLS ClientHello
-		serverName, peekedBytes, err = ExtractSNIFromBytes(peekedBytes, conn)
+		// Extract SNI and ALPN from TLS ClientHello
+		hello, peekedBytes, err = ExtractClientHelloFromBytes(peekedBytes, conn)
 		if err != nil {
 			e.logger.Error("failed to extract SNI", "client", clientAddr, "error", err)
 			return
 		}
+		serverName = hello.ServerName
 		if serverName == "" {
-			e.logger.Warn("no SNI in ClientHello", "client", clientAddr)
-			return
-		}
-
-		e.logger.Debug("TLS connection received", "client", clientAddr, "sni", serverName)
-
-		// Look up route in registry
-		route = e.registry.Lookup(serverName)
-		if route == nil {
-			e.logger.Warn("no route for SNI", "sni", serverName, "client", clientAddr)
-			return
+			// Clients such as older DB drivers or direct IP connections omit SNI;
+			// fall back to the entrypoint's default route if it is unambiguous
+			route = e.defaultRoute(clientAddr)
+			if route == nil {
+				return
+			}
+			sniMissing = true
+			serverName = route.Host
+			e.logger.Debug("no SNI in ClientHello, using default route", "client", clientAddr, "route", serverName)
+		} else {
+			e.logger.Debug("TLS connection received", "client", clientAddr, "sni", serverName)
+
+			// Look up route in registry
+			route = e.lookupRoute(serverName)
+			if route == nil {
+				e.logger.Warn("no route for SNI", "sni", serverName, "client", clientAddr)
+				return
+			}
 		}
 	} else {
 		// Non-TLS connection - try to find a single route for this entrypoint
-		routes := e.registry.GetByEntrypoint(e.name)
-		if len(routes) == 0 {
-			e.logger.Warn("no routes for entrypoint", "entrypoint", e.name, "client", clientAddr)
-			return
-		}
-		if len(routes) > 1 {
-			// Build list of conflicting hosts for the error message
-			hosts := make([]string, len(routes))
-			for i, r := range routes {
-				hosts[i] = r.Host
-			}
-			e.logger.Warn("multiple routes for non-TLS connection, cannot determine target; "+
-				"use unique entrypoint names (e.g., devproxy.entrypoint=myapp-postgres) or enable TLS",
-				"entrypoint", e.name, "client", clientAddr, "routes", len(routes), "hosts", hosts)
+		route = e.defaultRoute(clientAddr)
+		if route == nil {
 			return
 		}
-		route = routes[0]
 		serverName = route.Host
 		e.logger.Debug("non-TLS connection received", "client", clientAddr, "route", serverName)
 	}
 
-	// Determine backend address
+	if route.Disabled {
+		e.logger.Info("route disabled, dropping connection", "route", route.Host, "client", clientAddr)
+		return
+	}
+
+	// Determine backend address, preferring a backend for the client's ALPN protocols
 	backendAddr := e.getBackendAddr(*route)
+	alpnBackend, alpnProtocol := route.BackendForALPN(hello.ALPNProtocols)
+	if alpnBackend != "" {
+		backendAddr = alpnBackend
+	}
 
 	// Wrap connection to replay peeked bytes
 	peekedConn := NewPeekedConn(conn, peekedBytes)
 
 	if isTLS {
 		// Perform TLS handshake with client using our certificate
-		tlsConfig := &tls.Config{
-			GetCertificate: e.certManager.GetCertificate,
+		tlsConfig := e.serverTLSConfig()
+		if sniMissing {
+			tlsConfig = e.defaultTLSConfig(serverName)
+		}
+		if alpnProtocol != "" {
+			// Negotiate the protocol the backend was chosen for
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.NextProtos = []string{alpnProtocol}
 		}
 
 		tlsConn := tls.Server(peekedConn, tlsConfig)
@@ -254,7 +350,7 @@ func (e *TCPEntrypoint) handleConnection(ctx context.Context, conn net.Conn) {
 		}
 		defer backendConn.Close()
 
-		e.logger.Debug("proxying TLS connection", "sni", serverName, "backend", backendAddr)
+		e.logger.Debug("proxying TLS connection", "sni", serverName, "alpn", alpnProtocol, "backend", backendAddr)
 
 		// Proxy data bidirectionally
 		e.proxyBidirectional(tlsConn, backendConn)
@@ -366,21 +462,152 @@ func (e *TCPEntrypoint) proxyBidirectionalTCP(client, backend net.Conn) {
 	wg.Wait()
 }
 
+// serverTLSConfig returns the TLS config shared by all connections on this entrypoint.
+// Reusing a single config lets clients resume sessions from earlier connections.
+func (e *TCPEntrypoint) serverTLSConfig() *tls.Config {
+	e.tlsOnce.Do(func() {
+		cfg := &tls.Config{
+			GetCertificate: e.certManager.GetCertificate,
+		}
+
+		// Registered under e.mu so Close either sees the config and releases
+		// it, or runs first and it is never registered
+		e.mu.Lock()
+		defer e.mu.Unlock()
+		if e.tickets != nil && e.running {
+			e.tickets.Apply(cfg)
+		}
+		e.tlsConfig = cfg
+	})
+	return e.tlsConfig
+}
+
+// defaultRoute returns the route used when a connection carries no hostname.
+// This is the configured default host if set, otherwise the only route on this
+// entrypoint. The default host must have a TCP route on this entrypoint, so a
+// connection never falls into an HTTP route or another entrypoint's route.
+// Returns nil (and logs why) when no route can be chosen.
+func (e *TCPEntrypoint) defaultRoute(clientAddr string) *Route {
+	if e.defaultHost != "" {
+		route := e.lookupRoute(e.defaultHost)
+		if route == nil || route.Protocol != ProtocolTCP || route.Entrypoint != e.name {
+			e.logger.Warn("no TCP route for default host on this entrypoint", "entrypoint", e.name, "host", e.defaultHost, "client", clientAddr)
+			return nil
+		}
+		return route
+	}
+
+	routes := e.entrypointRoutes()
+	if len(routes) == 0 {
+		e.logger.Warn("no routes for entrypoint", "entrypoint", e.name, "client", clientAddr)
+		return nil
+	}
+	if len(routes) > 1 {
+		// Build list of conflicting hosts for the error message
+		hosts := make([]string, len(routes))
+		for i, r := range routes {
+			hosts[i] = r.Host
+		}
+		e.logger.Warn("multiple routes for connection without hostname, cannot determine target; "+
+			"use unique entrypoint names (e.g., devproxy.entrypoint=myapp-postgres), send SNI, or set default_host",
+			"entrypoint", e.name, "client", clientAddr, "routes", len(routes), "hosts", hosts)
+		return nil
+	}
+	return routes[0]
+}
+
+// lookupRoute finds the route for host on this entrypoint. A TCP registry
+// route for this entrypoint wins over the route registry, which otherwise
+// falls back to the host's route on any entrypoint.
+func (e *TCPEntrypoint) lookupRoute(host string) *Route {
+	if e.tcpRoutes != nil {
+		if tcpRoute, ok := e.tcpRoutes.LookupTCP(host, e.name); ok {
+			route := e.resolveTCPRoute(tcpRoute)
+			if route == nil {
+				e.logger.Debug("ignoring TCP route removed from the registry", "entrypoint", e.name, "host", tcpRoute.Host)
+			}
+			return route
+		}
+	}
+	return e.registry.LookupEntrypoint(host, e.name)
+}
+
+// entrypointRoutes returns all routes bound to this entrypoint, from both the
+// route registry and the TCP registry.
+func (e *TCPEntrypoint) entrypointRoutes() []*Route {
+	routes := e.registry.GetByEntrypoint(e.name)
+	if e.tcpRoutes == nil {
+		return routes
+	}
+
+	known := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		known[route.Host] = true
+	}
+	for _, tcpRoute := range e.tcpRoutes.List(e.name) {
+		if known[tcpRoute.Host] {
+			continue
+		}
+		if route := e.resolveTCPRoute(&tcpRoute); route != nil {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+// resolveTCPRoute returns the registry route matching a TCP registry entry so
+// its state (disabled, ALPN backends) applies. The registry is authoritative:
+// TCP registry entries are only removed when their container stops, so an
+// entry whose route was removed by a config reload, a toggle or the control
+// socket is stale, and nil is returned.
+func (e *TCPEntrypoint) resolveTCPRoute(tcpRoute *TCPRoute) *Route {
+	if route := e.registry.LookupEntrypoint(tcpRoute.Host, e.name); route != nil && route.Protocol == ProtocolTCP && route.Entrypoint == e.name {
+		return route
+	}
+	return nil
+}
+
+// defaultTLSConfig returns a TLS config that presents the certificate for host
+// to clients that did not send SNI.
+func (e *TCPEntrypoint) defaultTLSConfig(host string) *tls.Config {
+	cfg := e.serverTLSConfig().Clone()
+	cfg.GetCertificate = func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+		named := *hello
+		named.ServerName = host
+		return e.certManager.GetCertificate(&named)
+	}
+	return cfg
+}
+
 // getBackendAddr returns the backend address for a route.
 func (e *TCPEntrypoint) getBackendAddr(route Route) string {
-	// If targetPort is configured on the entrypoint, use it instead of route's backend port
-	if e.targetPort > 0 {
-		// Extract host from route.Backend and use entrypoint's targetPort
-		host, _, err := net.SplitHostPort(route.Backend)
-		if err != nil {
-			// Backend might not have a port, use as-is
-			host = route.Backend
-		}
+	if e.targetPort <= 0 {
+		return route.Backend
+	}
+	host, _, err := net.SplitHostPort(route.Backend)
+	if err != nil {
+		// Backend has no port, so the target port is the only one available
+		return fmt.Sprintf("%s:%d", route.Backend, e.targetPort)
+	}
+	if e.useTargetPort(route) {
 		return fmt.Sprintf("%s:%d", host, e.targetPort)
 	}
 	return route.Backend
 }
 
+// useTargetPort reports whether the entrypoint's target port replaces the
+// route's backend port under the entrypoint's target port mode.
+func (e *TCPEntrypoint) useTargetPort(route Route) bool {
+	switch e.portMode {
+	case TargetPortIgnore:
+		return false
+	case TargetPortDefault:
+		return !route.ExplicitPort && !route.PortLabeled
+	default:
+		return !route.ExplicitPort
+	}
+}
+
 // proxyBidirectional copies data between client and backend.
 func (e *TCPEntrypoint) proxyBidirectional(client, backend net.Conn) {
 	var wg sync.WaitGroup
@@ -409,10 +636,15 @@ func (e *TCPEntrypoint) proxyBidirectional(client, backend net.Conn) {
 	wg.Wait()
 }
 
-// copyData copies data from src to dst.
+// copyData copies data from src to dst, throttled to the entrypoint's rate limit if set.
 func (e *TCPEntrypoint) copyData(dst, src net.Conn) {
+	var w io.Writer = dst
+	if e.rateLimit > 0 {
+		w = newRateLimitedWriter(dst, e.rateLimit)
+	}
+
 	buf := make([]byte, tcpCopyBufferSize)
-	_, err := io.CopyBuffer(dst, src, buf)
+	_, err := io.CopyBuffer(w, src, buf)
 	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
 		// Only log unexpected errors
 		if opErr, ok := err.(*net.OpError); ok && opErr.Err.Error() == "use of closed network connection" {
@@ -2,6 +2,7 @@
 package proxy
 
 import (
+	"strings"
 	"sync"
 )
 
@@ -11,9 +12,17 @@ type TCPRoute struct {
 	Backend     string // e.g., "172.18.0.5:5432"
 	Entrypoint  string // e.g., "postgres"
 	ContainerID string // Docker container ID
+
+	// ExplicitPort is set when the backend port was paired with the entrypoint
+	// in labels and must not be replaced by the entrypoint's target port
+	ExplicitPort bool
+
+	// PortLabeled is set when the backend port came from labels
+	PortLabeled bool
 }
 
-// TCPRegistry stores and retrieves TCP routes.
+// TCPRegistry stores and retrieves TCP routes. Hosts and entrypoint names
+// are matched case-insensitively, like the route registry.
 // It is thread-safe for concurrent access.
 type TCPRegistry struct {
 	mu     sync.RWMutex
@@ -33,12 +42,14 @@ func (r *TCPRegistry) Add(route TCPRoute) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	if r.routes[route.Entrypoint] == nil {
-		r.routes[route.Entrypoint] = make(map[string]*TCPRoute)
-	}
-
 	routeCopy := route
-	r.routes[route.Entrypoint][route.Host] = &routeCopy
+	routeCopy.Host = normalizeHost(route.Host)
+	routeCopy.Entrypoint = strings.ToLower(route.Entrypoint)
+
+	if r.routes[routeCopy.Entrypoint] == nil {
+		r.routes[routeCopy.Entrypoint] = make(map[string]*TCPRoute)
+	}
+	r.routes[routeCopy.Entrypoint][routeCopy.Host] = &routeCopy
 }
 
 // Remove removes a TCP route by host and entrypoint.
@@ -47,6 +58,8 @@ func (r *TCPRegistry) Remove(host, entrypoint string) bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	host = normalizeHost(host)
+	entrypoint = strings.ToLower(entrypoint)
 	if hostRoutes, ok := r.routes[entrypoint]; ok {
 		if _, exists := hostRoutes[host]; exists {
 			delete(hostRoutes, host)
@@ -88,6 +101,8 @@ func (r *TCPRegistry) LookupTCP(host, entrypoint string) (*TCPRoute, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	host = normalizeHost(host)
+	entrypoint = strings.ToLower(entrypoint)
 	if hostRoutes, ok := r.routes[entrypoint]; ok {
 		if route, exists := hostRoutes[host]; exists {
 			return route, true
@@ -104,6 +119,7 @@ func (r *TCPRegistry) List(entrypoint string) []TCPRoute {
 
 	var result []TCPRoute
 
+	entrypoint = strings.ToLower(entrypoint)
 	if entrypoint != "" {
 		if hostRoutes, ok := r.routes[entrypoint]; ok {
 			for _, route := range hostRoutes {
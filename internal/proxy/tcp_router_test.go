@@ -341,3 +341,30 @@ func TestTCPRegistry_ConcurrentAccess(t *testing.T) {
 		t.Errorf("expected 1 route after concurrent adds, got %d", registry.Count())
 	}
 }
+
+func TestTCPRegistry_HostNormalization(t *testing.T) {
+	registry := NewTCPRegistry()
+	registry.Add(TCPRoute{Host: "DB.Localhost", Backend: "172.18.0.5:5432", Entrypoint: "postgres"})
+
+	if _, found := registry.LookupTCP("db.localhost.", "postgres"); !found {
+		t.Error("expected lookup to ignore case and trailing dot")
+	}
+	if !registry.Remove("db.LOCALHOST", "postgres") {
+		t.Error("expected remove to ignore case")
+	}
+}
+
+func TestTCPRegistry_EntrypointCasing(t *testing.T) {
+	registry := NewTCPRegistry()
+	registry.Add(TCPRoute{Host: "db.localhost", Backend: "172.18.0.5:5432", Entrypoint: "Postgres"})
+
+	if _, found := registry.LookupTCP("db.localhost", "postgres"); !found {
+		t.Error("expected lookup to ignore entrypoint casing")
+	}
+	if routes := registry.List("POSTGRES"); len(routes) != 1 || routes[0].Entrypoint != "postgres" {
+		t.Errorf("List(POSTGRES) = %+v, want one route on postgres", routes)
+	}
+	if !registry.Remove("db.localhost", "postgres") {
+		t.Error("expected remove to ignore entrypoint casing")
+	}
+}
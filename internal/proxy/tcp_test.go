@@ -15,6 +15,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/munichmade/devproxy/internal/ca"
 	"github.com/munichmade/devproxy/internal/cert"
 )
 
@@ -123,6 +124,41 @@ func TestTCPEntrypoint_StartStop(t *testing.T) {
 		_ = mockCM
 	})
 
+	t.Run("closes connections still open when the context is done", func(t *testing.T) {
+		ep := &TCPEntrypoint{
+			name:     "test",
+			listen:   "127.0.0.1:0",
+			registry: NewRegistry(),
+			logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		if err := ep.Start(context.Background()); err != nil {
+			t.Fatalf("failed to start: %v", err)
+		}
+
+		// The connection sends nothing, so it waits for its first bytes
+		conn, err := net.DialTimeout("tcp", ep.Addr(), time.Second)
+		if err != nil {
+			t.Fatalf("failed to connect: %v", err)
+		}
+		defer conn.Close()
+		time.Sleep(50 * time.Millisecond) // let the entrypoint accept it
+
+		stopCtx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		if err := ep.Stop(stopCtx); err != nil {
+			t.Fatalf("failed to stop: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Stop() took %s, want it to return at the timeout", elapsed)
+		}
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+			t.Errorf("expected the connection to be closed, got %v", err)
+		}
+	})
+
 	t.Run("returns error when already running", func(t *testing.T) {
 		registry := NewRegistry()
 
@@ -197,6 +233,23 @@ func TestTCPEntrypoint_GetBackendAddr(t *testing.T) {
 		}
 	})
 
+	t.Run("keeps explicitly paired port", func(t *testing.T) {
+		ep := &TCPEntrypoint{
+			targetPort: 3000,
+		}
+
+		route := Route{
+			Host:         "test.localhost",
+			Backend:      "container:8080",
+			ExplicitPort: true,
+		}
+
+		addr := ep.getBackendAddr(route)
+		if addr != "container:8080" {
+			t.Errorf("expected 'container:8080', got '%s'", addr)
+		}
+	})
+
 	t.Run("handles backend without port", func(t *testing.T) {
 		ep := &TCPEntrypoint{
 			targetPort: 3000,
@@ -214,6 +267,44 @@ func TestTCPEntrypoint_GetBackendAddr(t *testing.T) {
 	})
 }
 
+func TestTCPEntrypoint_GetBackendAddr_TargetPortMode(t *testing.T) {
+	defaultPort := Route{Host: "db.localhost", Backend: "container:80"}
+	labeledPort := Route{Host: "db.localhost", Backend: "container:5433", PortLabeled: true}
+	pairedPort := Route{Host: "db.localhost", Backend: "container:5434", ExplicitPort: true, PortLabeled: true}
+	noPort := Route{Host: "db.localhost", Backend: "container"}
+
+	tests := []struct {
+		name  string
+		mode  TargetPortMode
+		route Route
+		want  string
+	}{
+		{name: "unset forces target port", mode: "", route: labeledPort, want: "container:5432"},
+		{name: "force replaces default port", mode: TargetPortForce, route: defaultPort, want: "container:5432"},
+		{name: "force replaces labeled port", mode: TargetPortForce, route: labeledPort, want: "container:5432"},
+		{name: "force keeps paired port", mode: TargetPortForce, route: pairedPort, want: "container:5434"},
+		{name: "default replaces default port", mode: TargetPortDefault, route: defaultPort, want: "container:5432"},
+		{name: "default keeps labeled port", mode: TargetPortDefault, route: labeledPort, want: "container:5433"},
+		{name: "default keeps paired port", mode: TargetPortDefault, route: pairedPort, want: "container:5434"},
+		{name: "ignore keeps default port", mode: TargetPortIgnore, route: defaultPort, want: "container:80"},
+		{name: "ignore keeps labeled port", mode: TargetPortIgnore, route: labeledPort, want: "container:5433"},
+		{name: "ignore uses target port without backend port", mode: TargetPortIgnore, route: noPort, want: "container:5432"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ep := NewTCPEntrypoint(TCPEntrypointConfig{
+				Name:           "postgres",
+				TargetPort:     5432,
+				TargetPortMode: tt.mode,
+			})
+			if got := ep.getBackendAddr(tt.route); got != tt.want {
+				t.Errorf("getBackendAddr() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestTCPEntrypoint_Addr(t *testing.T) {
 	t.Run("returns empty when not listening", func(t *testing.T) {
 		ep := &TCPEntrypoint{}
@@ -284,3 +375,402 @@ func TestNewTCPEntrypoint(t *testing.T) {
 		}
 	})
 }
+
+func TestTCPEntrypoint_ServerTLSConfig(t *testing.T) {
+	keys, err := NewSessionTicketKeys(0)
+	if err != nil {
+		t.Fatalf("failed to create session ticket keys: %v", err)
+	}
+
+	ep := NewTCPEntrypoint(TCPEntrypointConfig{
+		Name:           "postgres",
+		Listen:         "127.0.0.1:0",
+		Registry:       NewRegistry(),
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		SessionTickets: keys,
+	})
+	if err := ep.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start entrypoint: %v", err)
+	}
+
+	cfg := ep.serverTLSConfig()
+	if cfg != ep.serverTLSConfig() {
+		t.Error("expected TLS config to be shared across connections")
+	}
+	if cfg.SessionTicketsDisabled {
+		t.Error("expected session tickets to be enabled")
+	}
+	if len(keys.configs) != 1 {
+		t.Errorf("expected config to be registered once, got %d", len(keys.configs))
+	}
+
+	ep.Close()
+	if len(keys.configs) != 0 {
+		t.Errorf("expected config to be released on close, got %d registered", len(keys.configs))
+	}
+}
+
+func TestTCPEntrypoint_NoSNIFallback(t *testing.T) {
+	mgr := setupTestCA(t)
+
+	// Echo backend
+	backend, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to start backend: %v", err)
+	}
+	defer backend.Close()
+	go func() {
+		for {
+			conn, err := backend.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	tests := []struct {
+		name        string
+		hosts       []string
+		other       *Route
+		defaultHost string
+		wantProxied bool
+	}{
+		{name: "single route is used", hosts: []string{"db.localhost"}, wantProxied: true},
+		{name: "multiple routes are ambiguous", hosts: []string{"a.localhost", "b.localhost"}, wantProxied: false},
+		{name: "default host resolves ambiguity", hosts: []string{"a.localhost", "b.localhost"}, defaultHost: "b.localhost", wantProxied: true},
+		{name: "missing default host drops connection", hosts: []string{"db.localhost"}, defaultHost: "other.localhost", wantProxied: false},
+		{name: "no routes drops connection", wantProxied: false},
+		{
+			name:        "HTTP default host drops connection",
+			hosts:       []string{"db.localhost"},
+			other:       &Route{Host: "web.localhost", Protocol: ProtocolHTTP},
+			defaultHost: "web.localhost",
+			wantProxied: false,
+		},
+		{
+			name:        "default host on another entrypoint drops connection",
+			hosts:       []string{"db.localhost"},
+			other:       &Route{Host: "cache.localhost", Protocol: ProtocolTCP, Entrypoint: "redis"},
+			defaultHost: "cache.localhost",
+			wantProxied: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry()
+			for _, host := range tt.hosts {
+				if err := registry.Add(Route{Host: host, Backend: backend.Addr().String(), Protocol: "tcp", Entrypoint: "postgres"}); err != nil {
+					t.Fatalf("failed to add route: %v", err)
+				}
+			}
+			if tt.other != nil {
+				other := *tt.other
+				other.Backend = backend.Addr().String()
+				if err := registry.Add(other); err != nil {
+					t.Fatalf("failed to add route: %v", err)
+				}
+			}
+
+			ep := NewTCPEntrypoint(TCPEntrypointConfig{
+				Name:        "postgres",
+				Listen:      "127.0.0.1:0",
+				DefaultHost: tt.defaultHost,
+				Registry:    registry,
+				CertManager: mgr,
+				Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+			})
+			if err := ep.Start(context.Background()); err != nil {
+				t.Fatalf("failed to start entrypoint: %v", err)
+			}
+			defer ep.Stop(context.Background())
+
+			// Dialing an IP address without ServerName sends no SNI
+			dialer := &net.Dialer{Timeout: 2 * time.Second}
+			conn, err := tls.DialWithDialer(dialer, "tcp", ep.Addr(), &tls.Config{InsecureSkipVerify: true})
+			if !tt.wantProxied {
+				if err == nil {
+					conn.Close()
+					t.Fatal("expected connection to be dropped")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("TLS handshake failed: %v", err)
+			}
+			defer conn.Close()
+
+			if _, err := conn.Write([]byte("ping")); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+			buf := make([]byte, 4)
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			if string(buf) != "ping" {
+				t.Errorf("expected echo 'ping', got %q", buf)
+			}
+		})
+	}
+}
+
+func TestTCPEntrypoint_TLSTermination(t *testing.T) {
+	mgr := setupTestCA(t)
+	rootCA, err := ca.Load()
+	if err != nil {
+		t.Fatalf("failed to load CA: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(rootCA.Certificate)
+
+	// Backends record what they receive and answer with their name
+	startBackend := func(name string) (string, <-chan []byte) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to start backend: %v", err)
+		}
+		t.Cleanup(func() { listener.Close() })
+
+		received := make(chan []byte, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+			buf := make([]byte, 64)
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			n, _ := conn.Read(buf)
+			received <- buf[:n]
+			conn.Write([]byte(name))
+		}()
+		return listener.Addr().String(), received
+	}
+	dbAddr, dbReceived := startBackend("db")
+	cacheAddr, cacheReceived := startBackend("cache")
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "db.example.localhost", Backend: dbAddr, Protocol: ProtocolTCP, Entrypoint: "postgres"})
+	registry.Add(Route{Host: "cache.example.localhost", Backend: cacheAddr, Protocol: ProtocolTCP, Entrypoint: "postgres"})
+
+	ep := NewTCPEntrypoint(TCPEntrypointConfig{
+		Name:        "postgres",
+		Listen:      "127.0.0.1:0",
+		Registry:    registry,
+		CertManager: mgr,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := ep.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start entrypoint: %v", err)
+	}
+	defer ep.Stop(context.Background())
+
+	tests := []struct {
+		host     string
+		received <-chan []byte
+		wantName string
+	}{
+		{host: "db.example.localhost", received: dbReceived, wantName: "db"},
+		{host: "cache.example.localhost", received: cacheReceived, wantName: "cache"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			dialer := &net.Dialer{Timeout: 2 * time.Second}
+			conn, err := tls.DialWithDialer(dialer, "tcp", ep.Addr(), &tls.Config{
+				ServerName: tt.host,
+				RootCAs:    roots,
+			})
+			if err != nil {
+				t.Fatalf("TLS handshake failed: %v", err)
+			}
+			defer conn.Close()
+
+			if cn := conn.ConnectionState().PeerCertificates[0].Subject.CommonName; cn != "*.example.localhost" {
+				t.Errorf("expected certificate for *.example.localhost, got %q", cn)
+			}
+
+			want := "hello " + tt.host
+			if _, err := conn.Write([]byte(want)); err != nil {
+				t.Fatalf("write failed: %v", err)
+			}
+
+			// The backend sees the decrypted bytes
+			select {
+			case got := <-tt.received:
+				if string(got) != want {
+					t.Errorf("backend received %q, want %q", got, want)
+				}
+			case <-time.After(2 * time.Second):
+				t.Fatal("backend received nothing")
+			}
+
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			reply, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			if string(reply) != tt.wantName {
+				t.Errorf("expected reply from %s backend, got %q", tt.wantName, reply)
+			}
+		})
+	}
+}
+
+func TestTCPEntrypoint_ALPNRouting(t *testing.T) {
+	mgr := setupTestCA(t)
+
+	// bannerBackend accepts connections and writes its name
+	bannerBackend := func(name string) net.Listener {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("failed to start backend: %v", err)
+		}
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Write([]byte(name))
+				conn.Close()
+			}
+		}()
+		return ln
+	}
+
+	rest := bannerBackend("rest")
+	defer rest.Close()
+	grpc := bannerBackend("grpc")
+	defer grpc.Close()
+
+	registry := NewRegistry()
+	if err := registry.Add(Route{
+		Host:         "api.localhost",
+		Backend:      rest.Addr().String(),
+		Protocol:     ProtocolTCP,
+		Entrypoint:   "grpc",
+		ALPNBackends: map[string]string{"h2": grpc.Addr().String()},
+	}); err != nil {
+		t.Fatalf("failed to add route: %v", err)
+	}
+
+	ep := NewTCPEntrypoint(TCPEntrypointConfig{
+		Name:        "grpc",
+		Listen:      "127.0.0.1:0",
+		Registry:    registry,
+		CertManager: mgr,
+		Logger:      slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	if err := ep.Start(context.Background()); err != nil {
+		t.Fatalf("failed to start entrypoint: %v", err)
+	}
+	defer ep.Stop(context.Background())
+
+	tests := []struct {
+		name        string
+		nextProtos  []string
+		wantBackend string
+		wantALPN    string
+	}{
+		{name: "h2 goes to ALPN backend", nextProtos: []string{"h2", "http/1.1"}, wantBackend: "grpc", wantALPN: "h2"},
+		{name: "http/1.1 goes to default backend", nextProtos: []string{"http/1.1"}, wantBackend: "rest", wantALPN: ""},
+		{name: "no ALPN goes to default backend", wantBackend: "rest", wantALPN: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dialer := &net.Dialer{Timeout: 2 * time.Second}
+			conn, err := tls.DialWithDialer(dialer, "tcp", ep.Addr(), &tls.Config{
+				ServerName:         "api.localhost",
+				NextProtos:         tt.nextProtos,
+				InsecureSkipVerify: true,
+			})
+			if err != nil {
+				t.Fatalf("TLS handshake failed: %v", err)
+			}
+			defer conn.Close()
+
+			if got := conn.ConnectionState().NegotiatedProtocol; got != tt.wantALPN {
+				t.Errorf("expected negotiated protocol %q, got %q", tt.wantALPN, got)
+			}
+
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			banner, err := io.ReadAll(conn)
+			if err != nil {
+				t.Fatalf("read failed: %v", err)
+			}
+			if string(banner) != tt.wantBackend {
+				t.Errorf("expected backend %q, got %q", tt.wantBackend, banner)
+			}
+		})
+	}
+}
+
+func TestTCPEntrypoint_TCPRegistryLookup(t *testing.T) {
+	registry := NewRegistry()
+	registry.Add(Route{Host: "db.localhost", Backend: "172.18.0.5:80", Protocol: ProtocolHTTP})
+	registry.Add(Route{Host: "cache.localhost", Backend: "172.18.0.6:6379", Protocol: ProtocolTCP, Entrypoint: "redis", Disabled: true})
+	registry.Add(Route{Host: "static.localhost", Backend: "172.18.0.7:6379", Protocol: ProtocolTCP, Entrypoint: "redis"})
+	// Docker sync adds TCP routes to both registries
+	registry.Add(Route{Host: "db.localhost", Backend: "172.18.0.5:6379", Protocol: ProtocolTCP, Entrypoint: "redis", ExplicitPort: true})
+
+	tcpRoutes := NewTCPRegistry()
+	tcpRoutes.Add(TCPRoute{Host: "db.localhost", Backend: "172.18.0.5:6379", Entrypoint: "redis", ExplicitPort: true})
+	tcpRoutes.Add(TCPRoute{Host: "cache.localhost", Backend: "172.18.0.6:6379", Entrypoint: "redis"})
+	tcpRoutes.Add(TCPRoute{Host: "db.localhost", Backend: "172.18.0.5:5432", Entrypoint: "postgres"})
+	// Left behind after its route was removed from the registry
+	tcpRoutes.Add(TCPRoute{Host: "gone.localhost", Backend: "172.18.0.8:6379", Entrypoint: "redis"})
+
+	ep := NewTCPEntrypoint(TCPEntrypointConfig{
+		Name:      "redis",
+		Registry:  registry,
+		TCPRoutes: tcpRoutes,
+	})
+
+	tests := []struct {
+		name         string
+		host         string
+		wantBackend  string
+		wantDisabled bool
+	}{
+		{name: "TCP registry wins over route on other protocol", host: "DB.localhost", wantBackend: "172.18.0.5:6379"},
+		{name: "registry state applies to TCP registry route", host: "cache.localhost", wantBackend: "172.18.0.6:6379", wantDisabled: true},
+		{name: "routes only in registry are found", host: "static.localhost", wantBackend: "172.18.0.7:6379"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route := ep.lookupRoute(tt.host)
+			if route == nil {
+				t.Fatal("expected route")
+			}
+			if route.Backend != tt.wantBackend || route.Disabled != tt.wantDisabled {
+				t.Errorf("lookupRoute(%s) = %+v, want backend %s disabled %v", tt.host, route, tt.wantBackend, tt.wantDisabled)
+			}
+		})
+	}
+
+	t.Run("stale TCP registry route is unrouted", func(t *testing.T) {
+		if route := ep.lookupRoute("gone.localhost"); route != nil {
+			t.Errorf("expected no route for a host removed from the registry, got %+v", route)
+		}
+	})
+
+	t.Run("entrypoint routes include both registries once", func(t *testing.T) {
+		routes := ep.entrypointRoutes()
+		if len(routes) != 3 {
+			t.Fatalf("expected 3 routes, got %d", len(routes))
+		}
+		for _, route := range routes {
+			if route.Host == "db.localhost" && !route.ExplicitPort {
+				t.Errorf("expected TCP registry route to keep its explicit port, got %+v", route)
+			}
+		}
+	})
+}
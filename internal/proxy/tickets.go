@@ -0,0 +1,139 @@
+// Package proxy provides HTTP and TCP proxy functionality including TLS session resumption.
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTicketKeyRotation is how often a new session ticket key is generated.
+	DefaultTicketKeyRotation = 12 * time.Hour
+
+	// maxTicketKeys is the number of keys kept for decrypting older tickets.
+	// With the default rotation, tickets remain valid for up to 36 hours.
+	maxTicketKeys = 3
+)
+
+// SessionTicketKeys manages rotating TLS session ticket keys shared by
+// multiple server configs, so clients can resume sessions across connections.
+// The newest key encrypts new tickets; older keys still decrypt existing ones.
+type SessionTicketKeys struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	keys    [][32]byte
+	configs map[*tls.Config]struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewSessionTicketKeys creates a key set with an initial random key.
+// A non-positive interval uses DefaultTicketKeyRotation.
+func NewSessionTicketKeys(interval time.Duration) (*SessionTicketKeys, error) {
+	if interval <= 0 {
+		interval = DefaultTicketKeyRotation
+	}
+
+	k := &SessionTicketKeys{interval: interval, configs: make(map[*tls.Config]struct{})}
+	if err := k.Rotate(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Apply enables session tickets on the config and registers it to receive
+// rotated keys. Applying the same config again has no further effect.
+func (k *SessionTicketKeys) Apply(cfg *tls.Config) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	cfg.SessionTicketsDisabled = false
+	cfg.SetSessionTicketKeys(k.keys)
+	k.configs[cfg] = struct{}{}
+}
+
+// Release stops pushing rotated keys to cfg, which keeps the keys it has.
+// Servers call it on shutdown so stopped configs are not kept alive.
+func (k *SessionTicketKeys) Release(cfg *tls.Config) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	delete(k.configs, cfg)
+}
+
+// Rotate generates a new key, making it the active key for new tickets,
+// and pushes the updated key set to all registered configs.
+func (k *SessionTicketKeys) Rotate() error {
+	var key [32]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return fmt.Errorf("failed to generate session ticket key: %w", err)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	keys := make([][32]byte, 0, maxTicketKeys)
+	keys = append(keys, key)
+	for _, old := range k.keys {
+		if len(keys) == maxTicketKeys {
+			break
+		}
+		keys = append(keys, old)
+	}
+	k.keys = keys
+
+	for cfg := range k.configs {
+		cfg.SetSessionTicketKeys(k.keys)
+	}
+	return nil
+}
+
+// Start begins rotating keys in the background at the configured interval.
+func (k *SessionTicketKeys) Start() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.stop != nil {
+		return
+	}
+	k.stop = make(chan struct{})
+	k.done = make(chan struct{})
+
+	go k.rotateLoop(k.stop, k.done)
+}
+
+// Stop halts background key rotation.
+func (k *SessionTicketKeys) Stop() {
+	k.mu.Lock()
+	stop, done := k.stop, k.done
+	k.stop, k.done = nil, nil
+	k.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// rotateLoop rotates keys until stop is closed.
+func (k *SessionTicketKeys) rotateLoop(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(k.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// A failed rotation keeps the current keys in use
+			_ = k.Rotate()
+		case <-stop:
+			return
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+)
+
+func TestNewSessionTicketKeys(t *testing.T) {
+	t.Run("uses default interval", func(t *testing.T) {
+		keys, err := NewSessionTicketKeys(0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if keys.interval != DefaultTicketKeyRotation {
+			t.Errorf("expected interval %v, got %v", DefaultTicketKeyRotation, keys.interval)
+		}
+		if len(keys.keys) != 1 {
+			t.Errorf("expected 1 initial key, got %d", len(keys.keys))
+		}
+	})
+
+	t.Run("uses custom interval", func(t *testing.T) {
+		keys, err := NewSessionTicketKeys(time.Minute)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if keys.interval != time.Minute {
+			t.Errorf("expected interval 1m, got %v", keys.interval)
+		}
+	})
+}
+
+func TestSessionTicketKeys_Rotate(t *testing.T) {
+	keys, err := NewSessionTicketKeys(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := &tls.Config{SessionTicketsDisabled: true}
+	keys.Apply(cfg)
+
+	if cfg.SessionTicketsDisabled {
+		t.Error("expected session tickets to be enabled")
+	}
+
+	first := keys.keys[0]
+	for i := 0; i < maxTicketKeys+2; i++ {
+		if err := keys.Rotate(); err != nil {
+			t.Fatalf("rotate failed: %v", err)
+		}
+	}
+
+	if len(keys.keys) != maxTicketKeys {
+		t.Errorf("expected %d keys to be retained, got %d", maxTicketKeys, len(keys.keys))
+	}
+	for _, key := range keys.keys {
+		if key == first {
+			t.Error("expected oldest key to be dropped after rotation")
+		}
+	}
+}
+
+func TestSessionTicketKeys_ApplyRelease(t *testing.T) {
+	keys, err := NewSessionTicketKeys(0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cfg := &tls.Config{}
+	keys.Apply(cfg)
+	keys.Apply(cfg)
+	if len(keys.configs) != 1 {
+		t.Errorf("expected a config applied twice to be registered once, got %d", len(keys.configs))
+	}
+
+	keys.Release(cfg)
+	if len(keys.configs) != 0 {
+		t.Errorf("expected released config to be unregistered, got %d", len(keys.configs))
+	}
+	if err := keys.Rotate(); err != nil {
+		t.Fatalf("rotate failed: %v", err)
+	}
+}
+
+func TestSessionTicketKeys_StartStop(t *testing.T) {
+	keys, err := NewSessionTicketKeys(10 * time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	first := keys.keys[0]
+	keys.Start()
+	keys.Start() // second call is a no-op
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		keys.mu.Lock()
+		rotated := keys.keys[0] != first
+		keys.mu.Unlock()
+		if rotated {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected key to rotate in background")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	keys.Stop()
+	keys.Stop() // second call is a no-op
+}
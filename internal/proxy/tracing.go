@@ -0,0 +1,97 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the instrumentation that creates proxy spans.
+const tracerName = "github.com/munichmade/devproxy/internal/proxy"
+
+// Span attribute keys for proxied requests.
+const (
+	attrHost       = attribute.Key("devproxy.host")
+	attrBackend    = attribute.Key("devproxy.backend")
+	attrStatus     = attribute.Key("http.response.status_code")
+	attrMethod     = attribute.Key("http.request.method")
+	attrPath       = attribute.Key("url.path")
+	attrDurationMS = attribute.Key("devproxy.duration_ms")
+)
+
+// TracingHandler wraps an http.Handler to record a span per proxied request.
+// The trace context is propagated to the backend via the traceparent header.
+type TracingHandler struct {
+	handler    http.Handler
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracingHandler creates a tracing middleware using spans from provider.
+func NewTracingHandler(handler http.Handler, provider trace.TracerProvider) *TracingHandler {
+	return &TracingHandler{
+		handler:    handler,
+		tracer:     provider.Tracer(tracerName),
+		propagator: propagation.TraceContext{},
+	}
+}
+
+// ServeHTTP implements http.Handler.
+func (t *TracingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	// Continue a trace started by the client, if any
+	ctx := t.propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := t.tracer.Start(ctx, r.Method+" "+host,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attrHost.String(host),
+			attrMethod.String(r.Method),
+			attrPath.String(r.URL.Path),
+		),
+	)
+
+	// Inject into a copy of the headers so the backend receives our span as parent
+	r = r.WithContext(ctx)
+	r.Header = r.Header.Clone()
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(r.Header))
+
+	wrapped := &responseRecorder{
+		ResponseWriter: w,
+		statusCode:     http.StatusOK,
+	}
+
+	defer func() {
+		span.SetAttributes(
+			attrStatus.Int(wrapped.statusCode),
+			attrDurationMS.Int64(time.Since(start).Milliseconds()),
+		)
+		if p := recover(); p != nil {
+			span.SetStatus(codes.Error, "response aborted")
+			span.End()
+			panic(p)
+		}
+		if wrapped.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(wrapped.statusCode))
+		}
+		span.End()
+	}()
+
+	t.handler.ServeHTTP(wrapped, r)
+}
+
+// recordBackend adds the selected backend to the request's span, if any.
+func recordBackend(ctx context.Context, backend string) {
+	trace.SpanFromContext(ctx).SetAttributes(attrBackend.String(backend))
+}
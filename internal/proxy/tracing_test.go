@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracingHandler(t *testing.T) {
+	var gotTraceparent string
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotTraceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer backend.Close()
+
+	backendAddr := strings.TrimPrefix(backend.URL, "http://")
+	registry := NewRegistry()
+	registry.Add(Route{Host: "app.localhost", Backend: backendAddr, Protocol: ProtocolHTTP})
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(t.Context())
+
+	handler := NewTracingHandler(NewProxyHandler(registry), provider)
+
+	req := httptest.NewRequest(http.MethodGet, "http://app.localhost/api/items", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected status 201, got %d", rec.Code)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	span := spans[0]
+
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+
+	if got := attrs[attrHost].AsString(); got != "app.localhost" {
+		t.Errorf("expected host attribute app.localhost, got %q", got)
+	}
+	if got := attrs[attrBackend].AsString(); got != backendAddr {
+		t.Errorf("expected backend attribute %s, got %q", backendAddr, got)
+	}
+	if got := attrs[attrStatus].AsInt64(); got != http.StatusCreated {
+		t.Errorf("expected status attribute 201, got %d", got)
+	}
+	if _, ok := attrs[attrDurationMS]; !ok {
+		t.Error("expected duration attribute")
+	}
+
+	wantTraceparent := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"
+	if gotTraceparent != wantTraceparent {
+		t.Errorf("expected backend traceparent %q, got %q", wantTraceparent, gotTraceparent)
+	}
+}
+
+func TestTracingHandler_ContinuesClientTrace(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	defer provider.Shutdown(t.Context())
+
+	handler := NewTracingHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}), provider)
+
+	req := httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	if got := spans[0].SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected span to continue client trace, got trace ID %s", got)
+	}
+	if got := spans[0].Parent.SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("expected parent span 00f067aa0ba902b7, got %s", got)
+	}
+	if spans[0].Status.Code.String() != "Error" {
+		t.Errorf("expected error status for 502, got %s", spans[0].Status.Code)
+	}
+	if req.Header.Get("traceparent") != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Error("expected incoming request headers to be left unchanged")
+	}
+}
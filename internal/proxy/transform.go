@@ -0,0 +1,243 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// DefaultMaxBufferSize is the largest response body, in bytes, buffered for
+// ResponseBodyTransformers unless another limit is set.
+const DefaultMaxBufferSize = 10 << 20
+
+// RequestTransformer modifies a request before it is proxied to the route's
+// backend. Returning an error rejects the request; see Reject.
+type RequestTransformer interface {
+	TransformRequest(req *http.Request, route *Route) error
+}
+
+// ResponseTransformer modifies a backend response before it is sent to the
+// client. Returning an error answers the client with 502 Bad Gateway.
+type ResponseTransformer interface {
+	TransformResponse(resp *http.Response, route *Route) error
+}
+
+// ResponseBodyTransformer rewrites a backend response body, which is
+// buffered in memory for it. Bodies larger than the buffer limit are
+// streamed to the client unmodified instead. Returning an error answers the
+// client with 502 Bad Gateway.
+type ResponseBodyTransformer interface {
+	TransformResponseBody(resp *http.Response, body []byte, route *Route) ([]byte, error)
+}
+
+// RequestTransformerFunc adapts a function to RequestTransformer.
+type RequestTransformerFunc func(req *http.Request, route *Route) error
+
+// TransformRequest calls f(req, route).
+func (f RequestTransformerFunc) TransformRequest(req *http.Request, route *Route) error {
+	return f(req, route)
+}
+
+// ResponseTransformerFunc adapts a function to ResponseTransformer.
+type ResponseTransformerFunc func(resp *http.Response, route *Route) error
+
+// TransformResponse calls f(resp, route).
+func (f ResponseTransformerFunc) TransformResponse(resp *http.Response, route *Route) error {
+	return f(resp, route)
+}
+
+// ResponseBodyTransformerFunc adapts a function to ResponseBodyTransformer.
+type ResponseBodyTransformerFunc func(resp *http.Response, body []byte, route *Route) ([]byte, error)
+
+// TransformResponseBody calls f(resp, body, route).
+func (f ResponseBodyTransformerFunc) TransformResponseBody(resp *http.Response, body []byte, route *Route) ([]byte, error) {
+	return f(resp, body, route)
+}
+
+// StripResponseHeaders returns a response transformer that removes the
+// headers returned by headers from every response. headers is called per
+// response, so config reloads take effect without rebuilding the proxy.
+func StripResponseHeaders(headers func() []string) ResponseTransformer {
+	return ResponseTransformerFunc(func(resp *http.Response, _ *Route) error {
+		for _, header := range headers() {
+			resp.Header.Del(header)
+		}
+		return nil
+	})
+}
+
+// RejectError is returned by a RequestTransformer to answer the client with
+// a specific status instead of proxying the request.
+type RejectError struct {
+	Status  int
+	Message string
+}
+
+// Error implements error.
+func (e *RejectError) Error() string {
+	return e.Message
+}
+
+// Reject returns an error that makes the proxy answer with status and message.
+func Reject(status int, message string) error {
+	return &RejectError{Status: status, Message: message}
+}
+
+// Transformers holds the request and response transformers applied to all
+// HTTP routes, in registration order. It is safe for concurrent use, so
+// transformers can be added while the proxy is serving.
+type Transformers struct {
+	mu        sync.RWMutex
+	requests  []RequestTransformer
+	responses []ResponseTransformer
+	bodies    []ResponseBodyTransformer
+
+	// maxBuffer returns the body buffer limit (optional, defaults to DefaultMaxBufferSize)
+	maxBuffer func() int64
+}
+
+// NewTransformers creates an empty transformer registry.
+func NewTransformers() *Transformers {
+	return &Transformers{}
+}
+
+// AddRequest registers a request transformer.
+func (t *Transformers) AddRequest(transformer RequestTransformer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.requests = append(t.requests, transformer)
+}
+
+// AddResponse registers a response transformer.
+func (t *Transformers) AddResponse(transformer ResponseTransformer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.responses = append(t.responses, transformer)
+}
+
+// AddResponseBody registers a response body transformer. Body transformers
+// run after all response transformers.
+func (t *Transformers) AddResponseBody(transformer ResponseBodyTransformer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.bodies = append(t.bodies, transformer)
+}
+
+// SetMaxBufferSize sets a function returning the largest response body, in
+// bytes, buffered for body transformers. It is called per response, so
+// config reloads take effect; values of 0 or less select DefaultMaxBufferSize.
+func (t *Transformers) SetMaxBufferSize(size func() int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxBuffer = size
+}
+
+// hasResponse reports whether any response or body transformers are registered.
+func (t *Transformers) hasResponse() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return len(t.responses) > 0 || len(t.bodies) > 0
+}
+
+// transformRequest runs the request transformers, stopping at the first error.
+func (t *Transformers) transformRequest(req *http.Request, route *Route) error {
+	t.mu.RLock()
+	requests := t.requests
+	t.mu.RUnlock()
+
+	for _, transformer := range requests {
+		if err := transformer.TransformRequest(req, route); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transformResponse runs the response transformers and then the body
+// transformers, stopping at the first error.
+func (t *Transformers) transformResponse(resp *http.Response, route *Route) error {
+	t.mu.RLock()
+	responses := t.responses
+	bodies := t.bodies
+	maxBuffer := t.maxBuffer
+	t.mu.RUnlock()
+
+	for _, transformer := range responses {
+		if err := transformer.TransformResponse(resp, route); err != nil {
+			return err
+		}
+	}
+	if len(bodies) == 0 {
+		return nil
+	}
+
+	limit := int64(DefaultMaxBufferSize)
+	if maxBuffer != nil {
+		if n := maxBuffer(); n > 0 {
+			limit = n
+		}
+	}
+	return transformBody(resp, route, bodies, limit)
+}
+
+// transformBody buffers the response body and runs the body transformers on
+// it. A body larger than limit is left to stream through unmodified.
+func transformBody(resp *http.Response, route *Route, bodies []ResponseBodyTransformer, limit int64) error {
+	if resp.ContentLength > limit {
+		logBufferSkipped(resp, route, limit)
+		return nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > limit {
+		// Replay what was read, then stream the rest
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		logBufferSkipped(resp, route, limit)
+		return nil
+	}
+	resp.Body.Close()
+
+	for _, transformer := range bodies {
+		if body, err = transformer.TransformResponseBody(resp, body, route); err != nil {
+			return err
+		}
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.TransferEncoding = nil
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// logBufferSkipped logs that a response was too large to transform.
+func logBufferSkipped(resp *http.Response, route *Route, limit int64) {
+	path := ""
+	if resp.Request != nil {
+		path = resp.Request.URL.Path
+	}
+	slog.Warn("response body exceeds buffer limit, streaming it without transforms",
+		"host", route.Host,
+		"path", path,
+		"limit", limit)
+}
+
+// writeTransformError answers a request rejected by a request transformer.
+func writeTransformError(w http.ResponseWriter, err error) {
+	var reject *RejectError
+	if errors.As(err, &reject) {
+		http.Error(w, reject.Message, reject.Status)
+		return
+	}
+	http.Error(w, "request transform failed: "+err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,206 @@
+package proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReverseProxy_Transformers(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Seen-Tenant", r.Header.Get("X-Tenant"))
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:     "app.localhost",
+		Backend:  strings.TrimPrefix(backend.URL, "http://"),
+		Protocol: ProtocolHTTP,
+	})
+
+	transformers := NewTransformers()
+	transformers.AddRequest(RequestTransformerFunc(func(req *http.Request, route *Route) error {
+		if strings.HasPrefix(req.URL.Path, "/admin") {
+			return Reject(http.StatusForbidden, "admin is blocked")
+		}
+		if strings.HasPrefix(req.URL.Path, "/broken") {
+			return errors.New("lookup failed")
+		}
+		req.Header.Set("X-Tenant", "tenant-of-"+route.Host)
+		return nil
+	}))
+	transformers.AddResponse(ResponseTransformerFunc(func(resp *http.Response, route *Route) error {
+		resp.Header.Set("X-Transformed", route.Host)
+		return nil
+	}))
+
+	proxy := NewReverseProxy(registry)
+	proxy.SetTransformers(transformers)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantHeader map[string]string
+	}{
+		{
+			name:       "transforms request and response",
+			path:       "/",
+			wantStatus: http.StatusOK,
+			wantHeader: map[string]string{
+				"X-Seen-Tenant": "tenant-of-app.localhost",
+				"X-Transformed": "app.localhost",
+			},
+		},
+		{name: "rejects with status", path: "/admin/users", wantStatus: http.StatusForbidden},
+		{name: "fails on transform error", path: "/broken", wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "http://app.localhost"+tt.path, nil)
+			rec := httptest.NewRecorder()
+			proxy.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+			for name, want := range tt.wantHeader {
+				if got := rec.Header().Get(name); got != want {
+					t.Errorf("expected %s %q, got %q", name, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestReverseProxy_ResponseTransformerError(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:     "app.localhost",
+		Backend:  strings.TrimPrefix(backend.URL, "http://"),
+		Protocol: ProtocolHTTP,
+	})
+
+	transformers := NewTransformers()
+	transformers.AddResponse(ResponseTransformerFunc(func(resp *http.Response, route *Route) error {
+		return errors.New("bad response")
+	}))
+
+	proxy := NewReverseProxy(registry)
+	proxy.SetTransformers(transformers)
+
+	rec := httptest.NewRecorder()
+	proxy.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "http://app.localhost/", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("expected status %d, got %d", http.StatusBadGateway, rec.Code)
+	}
+}
+
+func TestReverseProxy_ResponseBodyTransformer(t *testing.T) {
+	small := "hello"
+	large := strings.Repeat("x", 64)
+
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := small
+		if strings.HasPrefix(r.URL.Path, "/large") {
+			body = large
+		}
+		if r.URL.Path == "/large/chunked" {
+			// Without Content-Length the limit is only noticed while reading
+			w.Write([]byte(body[:10]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(body[10:]))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer backend.Close()
+
+	registry := NewRegistry()
+	registry.Add(Route{
+		Host:     "app.localhost",
+		Backend:  strings.TrimPrefix(backend.URL, "http://"),
+		Protocol: ProtocolHTTP,
+	})
+
+	transformers := NewTransformers()
+	transformers.SetMaxBufferSize(func() int64 { return 32 })
+	transformers.AddResponseBody(ResponseBodyTransformerFunc(func(resp *http.Response, body []byte, route *Route) ([]byte, error) {
+		return bytes.ToUpper(body), nil
+	}))
+
+	proxy := NewReverseProxy(registry)
+	proxy.SetTransformers(transformers)
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "transforms body within limit", path: "/", want: "HELLO"},
+		{name: "streams body over limit unmodified", path: "/large", want: large},
+		{name: "streams chunked body over limit unmodified", path: "/large/chunked", want: large},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.Host = "app.localhost"
+				proxy.ServeHTTP(w, r)
+			}))
+			defer server.Close()
+
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", resp.StatusCode)
+			}
+			if string(body) != tt.want {
+				t.Errorf("expected body %q, got %q", tt.want, body)
+			}
+			if resp.ContentLength >= 0 && resp.ContentLength != int64(len(body)) {
+				t.Errorf("Content-Length %d does not match body length %d", resp.ContentLength, len(body))
+			}
+		})
+	}
+}
+
+func TestTransformers_DefaultMaxBufferSize(t *testing.T) {
+	transformers := NewTransformers()
+	transformers.SetMaxBufferSize(func() int64 { return 0 })
+
+	var got int
+	transformers.AddResponseBody(ResponseBodyTransformerFunc(func(resp *http.Response, body []byte, route *Route) ([]byte, error) {
+		got = len(body)
+		return body, nil
+	}))
+
+	body := strings.Repeat("x", 1<<20)
+	resp := &http.Response{Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), ContentLength: -1}
+	if err := transformers.transformResponse(resp, &Route{Host: "app.localhost"}); err != nil {
+		t.Fatalf("transformResponse() error = %v", err)
+	}
+	if got != len(body) {
+		t.Errorf("expected body within the default limit to be transformed, got %d bytes", got)
+	}
+}
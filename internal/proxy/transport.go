@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/munichmade/devproxy/internal/metrics"
+)
+
+// DefaultMaxIdleConnsPerHost is the number of idle connections kept open per
+// backend unless configured otherwise, matching net/http.
+const DefaultMaxIdleConnsPerHost = http.DefaultMaxIdleConnsPerHost
+
+// ConnStats counts the backend connections proxied requests were sent on.
+// Many dialed connections compared to reused ones mean the backend gets more
+// concurrent requests than idle connections are kept for it.
+type ConnStats struct {
+	Reused uint64 `json:"reused"` // idle connections taken from the pool
+	Dialed uint64 `json:"dialed"` // newly opened connections
+}
+
+// connCounter tracks ConnStats by backend.
+type connCounter struct {
+	mu       sync.Mutex
+	backends map[string]*ConnStats
+}
+
+func newConnCounter() *connCounter {
+	return &connCounter{backends: make(map[string]*ConnStats)}
+}
+
+// record counts a connection to backend.
+func (c *connCounter) record(backend string, reused bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats, ok := c.backends[backend]
+	if !ok {
+		stats = &ConnStats{}
+		c.backends[backend] = stats
+	}
+	if reused {
+		stats.Reused++
+	} else {
+		stats.Dialed++
+	}
+}
+
+// all returns a copy of the stats of all backends.
+func (c *connCounter) all() map[string]ConnStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	all := make(map[string]ConnStats, len(c.backends))
+	for backend, stats := range c.backends {
+		all[backend] = *stats
+	}
+	return all
+}
+
+// newBackendTransport creates the transport shared by all HTTP routes, which
+// keeps up to maxIdlePerHost idle connections per backend
+// (DefaultMaxIdleConnsPerHost if not positive).
+func newBackendTransport(maxIdlePerHost int) *http.Transport {
+	if maxIdlePerHost <= 0 {
+		maxIdlePerHost = DefaultMaxIdleConnsPerHost
+	}
+	return &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   maxIdlePerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// connStatsTransport counts whether each request to backend reused an idle
+// connection or dialed a new one.
+type connStatsTransport struct {
+	base     http.RoundTripper
+	registry *Registry
+	backend  string
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *connStatsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.registry.conns.record(t.backend, info.Reused)
+			metrics.ObserveBackendConnection(t.backend, info.Reused)
+		},
+	}
+	return t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
@@ -0,0 +1,113 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/munichmade/devproxy/internal/metrics"
+)
+
+func TestReverseProxy_ConnStats(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	backendAddr := strings.TrimPrefix(backend.URL, "http://")
+
+	registry := NewRegistry()
+	registry.Add(Route{Host: "reuse.localhost", Backend: backendAddr, Protocol: ProtocolHTTP})
+	rp := NewReverseProxy(registry)
+
+	const requests = 20
+	var last ConnStats
+	for i := range requests {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = "reuse.localhost"
+		w := httptest.NewRecorder()
+		rp.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i, w.Code)
+		}
+
+		stats := registry.ConnStats()[backendAddr]
+		if stats.Reused+stats.Dialed != uint64(i+1) {
+			t.Fatalf("request %d: expected %d connections counted, got %+v", i, i+1, stats)
+		}
+		if i > 0 && stats.Reused <= last.Reused {
+			t.Errorf("request %d: expected reused connections to climb, got %+v after %+v", i, stats, last)
+		}
+		last = stats
+	}
+
+	// Sequential requests share one connection
+	if last.Dialed != 1 || last.Reused != requests-1 {
+		t.Errorf("expected 1 dialed and %d reused connections, got %+v", requests-1, last)
+	}
+
+	var b strings.Builder
+	metrics.Default.WriteTo(&b)
+	if !strings.Contains(b.String(), `devproxy_backend_connections_total{backend="`+backendAddr+`",reused="true"} 19`) {
+		t.Errorf("expected reused connections in metrics, got:\n%s", b.String())
+	}
+}
+
+func TestReverseProxy_MaxIdleConnsPerHost(t *testing.T) {
+	// The backend holds the requests of a round until all of them arrived, so
+	// each request of a round needs its own connection
+	const concurrent = 6
+	var rounds map[string]*sync.WaitGroup
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		round := rounds[r.Header.Get("X-Round")]
+		round.Done()
+		round.Wait()
+		w.Write([]byte("ok"))
+	}))
+	defer backend.Close()
+	backendAddr := strings.TrimPrefix(backend.URL, "http://")
+
+	tests := []struct {
+		name       string
+		maxIdle    int
+		wantReused uint64
+	}{
+		{name: "default keeps 2 idle connections", maxIdle: 0, wantReused: DefaultMaxIdleConnsPerHost},
+		{name: "raised limit keeps all connections", maxIdle: concurrent, wantReused: concurrent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rounds = map[string]*sync.WaitGroup{"1": {}, "2": {}}
+			rounds["1"].Add(concurrent)
+			rounds["2"].Add(concurrent)
+
+			registry := NewRegistry()
+			registry.Add(Route{Host: "burst.localhost", Backend: backendAddr, Protocol: ProtocolHTTP})
+			rp := NewReverseProxy(registry)
+			rp.SetMaxIdleConnsPerHost(tt.maxIdle)
+
+			// The second round reuses the connections kept idle after the first
+			for _, round := range []string{"1", "2"} {
+				var wg sync.WaitGroup
+				for range concurrent {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						req := httptest.NewRequest(http.MethodGet, "/", nil)
+						req.Host = "burst.localhost"
+						req.Header.Set("X-Round", round)
+						rp.ServeHTTP(httptest.NewRecorder(), req)
+					}()
+				}
+				wg.Wait()
+			}
+
+			stats := registry.ConnStats()[backendAddr]
+			if stats.Reused != tt.wantReused || stats.Dialed != 2*concurrent-tt.wantReused {
+				t.Errorf("expected %d reused connections, got %+v", tt.wantReused, stats)
+			}
+		})
+	}
+}
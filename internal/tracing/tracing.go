@@ -0,0 +1,66 @@
+// Package tracing sets up OpenTelemetry trace export for proxied requests.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+
+	"github.com/munichmade/devproxy/internal/version"
+)
+
+// ServiceName is the service name reported on exported spans.
+const ServiceName = "devproxy"
+
+// TracesPath is the OTLP/HTTP path spans are posted to on a collector
+// endpoint without a path.
+const TracesPath = "/v1/traces"
+
+// NewProvider creates a tracer provider that batches spans and exports them
+// via OTLP/HTTP to endpoint (e.g., "http://localhost:4318"). Spans are posted
+// to TracesPath unless the endpoint has a path of its own.
+// Call Shutdown on the returned provider to flush pending spans.
+func NewProvider(ctx context.Context, endpoint string) (*sdktrace.TracerProvider, error) {
+	endpoint, err := tracesURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	return NewProviderWithExporter(exporter), nil
+}
+
+// tracesURL returns the URL spans are posted to for the collector endpoint.
+// WithEndpointURL posts to the URL's path as is, so a bare collector address
+// would be sent spans at /, which collectors reject.
+func tracesURL(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid OTLP endpoint %q: %w", endpoint, err)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = TracesPath
+	}
+	return u.String(), nil
+}
+
+// NewProviderWithExporter creates a tracer provider that batches spans to exporter.
+func NewProviderWithExporter(exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	res := resource.NewSchemaless(
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceVersion(version.Version),
+	)
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+func TestNewProviderWithExporter(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewProviderWithExporter(exporter)
+	defer provider.Shutdown(t.Context())
+
+	_, span := provider.Tracer("test").Start(t.Context(), "request")
+	span.End()
+
+	if err := provider.ForceFlush(t.Context()); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 exported span, got %d", len(spans))
+	}
+
+	var serviceName string
+	for _, kv := range spans[0].Resource.Attributes() {
+		if kv.Key == semconv.ServiceNameKey {
+			serviceName = kv.Value.AsString()
+		}
+	}
+	if serviceName != ServiceName {
+		t.Errorf("expected service name %q, got %q", ServiceName, serviceName)
+	}
+}
+
+func TestNewProvider_TracesPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		wantPath string
+	}{
+		{name: "collector address", path: "", wantPath: TracesPath},
+		{name: "trailing slash", path: "/", wantPath: TracesPath},
+		{name: "custom path", path: "/otlp/v1/traces", wantPath: "/otlp/v1/traces"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := make(chan string, 1)
+			collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case paths <- r.URL.Path:
+				default:
+				}
+				w.WriteHeader(http.StatusOK)
+			}))
+			defer collector.Close()
+
+			provider, err := NewProvider(t.Context(), collector.URL+tt.path)
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			_, span := provider.Tracer("test").Start(t.Context(), "request")
+			span.End()
+			if err := provider.Shutdown(t.Context()); err != nil {
+				t.Fatalf("shutdown failed: %v", err)
+			}
+
+			select {
+			case path := <-paths:
+				if path != tt.wantPath {
+					t.Errorf("spans posted to %q, want %q", path, tt.wantPath)
+				}
+			default:
+				t.Fatal("expected spans to be exported")
+			}
+		})
+	}
+}
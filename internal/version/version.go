@@ -0,0 +1,41 @@
+// Package version provides build metadata for devproxy.
+package version
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// Build-time variables set via ldflags.
+var (
+	Version   = "dev"
+	Commit    = "unknown"
+	BuildDate = "unknown"
+)
+
+// Info describes the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+}
+
+// Get returns the build metadata and runtime info of the current binary.
+func Get() Info {
+	return Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildDate: BuildDate,
+		GoVersion: runtime.Version(),
+		OS:        runtime.GOOS,
+		Arch:      runtime.GOARCH,
+	}
+}
+
+// Platform returns the OS/architecture pair (e.g., "darwin/arm64").
+func (i Info) Platform() string {
+	return fmt.Sprintf("%s/%s", i.OS, i.Arch)
+}
@@ -0,0 +1,36 @@
+package version
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	t.Run("includes build variables", func(t *testing.T) {
+		origVersion, origCommit, origDate := Version, Commit, BuildDate
+		defer func() { Version, Commit, BuildDate = origVersion, origCommit, origDate }()
+
+		Version, Commit, BuildDate = "1.2.3", "abc1234", "2024-01-02T03:04:05Z"
+
+		info := Get()
+		if info.Version != "1.2.3" {
+			t.Errorf("expected version 1.2.3, got %q", info.Version)
+		}
+		if info.Commit != "abc1234" {
+			t.Errorf("expected commit abc1234, got %q", info.Commit)
+		}
+		if info.BuildDate != "2024-01-02T03:04:05Z" {
+			t.Errorf("expected build date, got %q", info.BuildDate)
+		}
+	})
+
+	t.Run("includes runtime info", func(t *testing.T) {
+		info := Get()
+		if info.GoVersion != runtime.Version() {
+			t.Errorf("expected Go version %q, got %q", runtime.Version(), info.GoVersion)
+		}
+		if info.Platform() != runtime.GOOS+"/"+runtime.GOARCH {
+			t.Errorf("unexpected platform %q", info.Platform())
+		}
+	})
+}